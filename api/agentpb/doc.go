@@ -0,0 +1,9 @@
+// Package agentpb is the gRPC service for controlling a headless
+// agent-desktop from other programs, generated from agent.proto. Use
+// NewAgentClient with the API token in each call's metadata:
+//
+//	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+//	run, err := agentpb.NewAgentClient(conn).SendMessage(ctx, &agentpb.SendMessageRequest{Text: "..."})
+package agentpb
+
+//go:generate protoc --proto_path=../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative api/agentpb/agent.proto
@@ -2,13 +2,57 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"agent-desktop/internal/actions"
 	"agent-desktop/internal/agent"
+	"agent-desktop/internal/bridge"
+	"agent-desktop/internal/bugreport"
+	"agent-desktop/internal/calendar"
 	"agent-desktop/internal/config"
 	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/crash"
+	"agent-desktop/internal/deeplink"
+	"agent-desktop/internal/email"
+	"agent-desktop/internal/hotkey"
+	"agent-desktop/internal/i18n"
+	"agent-desktop/internal/inbox"
+	"agent-desktop/internal/index"
+	"agent-desktop/internal/jobs"
 	"agent-desktop/internal/llm"
+	"agent-desktop/internal/logging"
+	"agent-desktop/internal/mcp"
+	"agent-desktop/internal/memory"
+	"agent-desktop/internal/metrics"
+	"agent-desktop/internal/notify"
+	"agent-desktop/internal/personas"
+	"agent-desktop/internal/plugins"
+	"agent-desktop/internal/reveal"
+	"agent-desktop/internal/runqueue"
+	"agent-desktop/internal/snapshot"
+	"agent-desktop/internal/telemetry"
 	"agent-desktop/internal/tools"
+	"agent-desktop/internal/tracing"
+	"agent-desktop/internal/tray"
+	"agent-desktop/internal/updater"
+	"agent-desktop/internal/webhook"
 
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -18,23 +62,168 @@ type App struct {
 	config *config.Config
 	client *llm.Client
 
+	// emitter delivers events to the frontend, or to API clients when
+	// headless is set and there is no window
+	emitter  func(event string, data ...interface{})
+	headless bool
+
 	// Conversation state
 	convManager *conversation.Manager
+	storePath   string
+	storeCipher *conversation.Cipher // Set once encrypted storage is unlocked
+	storeLock   *conversation.StoreLock
+	readOnly    bool // Another instance owns the store; conversations can be viewed but not changed
+
+	// Retention janitor, started once with the app and stopped on shutdown
+	janitorMu     sync.Mutex
+	janitorCancel context.CancelFunc
+	janitorDone   chan struct{}
 
-	// Agent state
+	// Agent state: the run that has the agent, which is one at a time.
+	// Bindings, queued tasks, and tool approvals reach it from different
+	// goroutines, so agentMu guards it
+	agentMu     sync.Mutex
 	agentCancel context.CancelFunc
 	agentCtx    context.Context
+	agentRun    *conversation.RunHandle // nil for RunAgentTask, which has no conversation
+	agentDone   chan struct{}           // Closed when the run ends
+
+	// Original contents of files the agent changed, for reviewing diffs
+	snapshots *snapshot.Store
+
+	// Files changed by the current run, reported to webhooks
+	runFilesMu sync.Mutex
+	runFiles   []string
+
+	// Tasks queued to run one after another
+	queue *runqueue.Queue
+
+	// Connections to MCP servers whose tools the agent can use
+	mcp *mcp.Manager
+
+	// Tool plugins last loaded from the tools folder
+	pluginsMu sync.Mutex
+	plugins   PluginStatus
+
+	// Tool calls waiting for the user's answer in the window, by ID
+	approvalsMu sync.Mutex
+	approvals   map[string]chan bool
+
+	// Slack and Discord bots that accept tasks, and the settings they were
+	// started with
+	bridgeMu       sync.Mutex
+	bridge         *bridge.Bridge
+	bridgeCancel   context.CancelFunc
+	bridgeSettings config.BridgeSettings
+
+	// Task files from the inbox folder, by the conversation running them
+	inboxMu     sync.Mutex
+	inboxDir    string
+	inboxCancel context.CancelFunc
+	inboxRuns   map[string]*inboxRun
+
+	// Vector index of the workspace folder, kept up to date while it runs
+	indexMu     sync.Mutex
+	index       *index.Index
+	indexCancel context.CancelFunc
+
+	// Embeddings already paid for, shared by the index and conversation search
+	embeddingCache *index.Cache
+
+	// Recent crash reports the user can save
+	crashMu sync.Mutex
+	crashes []*crash.Report
+
+	// Ends the stream of log records to the window, if one is running
+	logStreamMu   sync.Mutex
+	stopLogStream func()
+
+	// System tray icon; quitting is set when the user chose Quit so the
+	// window close isn't turned into minimize-to-tray
+	tray     *tray.Tray
+	quitting bool
+
+	// Window layout restored at startup and saved when the window closes;
+	// nil when the app wasn't started with a saved layout
+	windowMu sync.Mutex
+	window   *config.WindowState
+
+	// Global shortcut that summons the quick-task entry
+	hotkey      *hotkey.Registration
+	hotkeyCombo string
+
+	// Deep links: one that arrives before startup is pending until the app
+	// is ready, and the result of the launch link waits for the frontend
+	linkMu      sync.Mutex
+	linkReady   bool
+	linkPending string
+	launchLink  *DeepLinkRequest
+
+	// Estimated spend for the current day, checked against Limits.MaxCostPerDay
+	spendMu    sync.Mutex
+	spendDay   string
+	spentToday float64
+
+	// When each prompt was last warmed up, keyed by warmKey
+	warmMu sync.Mutex
+	warmed map[string]time.Time
+
+	// Spending alerts: the day and month each was last shown for, and the
+	// alert new runs wait on when pause_on_alert is set
+	budgetMu     sync.Mutex
+	alertedDay   string
+	alertedMonth string
+	budgetPause  *BudgetWarning
+
+	// Usage totals per day and model for the usage dashboard
+	usage *metrics.Store
+
+	// Anonymous feature and crash counts, kept only when telemetry is on
+	telemetry *telemetry.Recorder
+
+	// Facts the agent was asked to remember
+	memories *memory.Store
+
+	// Embeddings of past exchanges for semantic search of conversations
+	exchangeIndex *conversation.SemanticIndex
+
+	// Background LLM calls made after runs, such as titling, one at a time
+	jobs *jobs.Queue
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	return &App{jobs: jobs.New()}
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
+	a.emitter = func(event string, data ...interface{}) {
+		runtime.EventsEmit(ctx, event, data...)
+	}
+	a.initBackend(ctx)
+
+	a.restoreWindowPosition(ctx)
+	a.startTray()
+	a.registerHotkey(a.config.GlobalHotkey)
+
+	if !a.config.DisableUpdateCheck {
+		go a.checkForUpdatesInBackground()
+	}
+
+	runtime.OnFileDrop(ctx, func(x, y int, paths []string) {
+		a.HandleDroppedFiles(paths)
+	})
+
+	a.handleLaunchDeepLink()
+}
+
+// initBackend loads the configuration and starts everything the agent
+// needs that doesn't depend on a window, so the app can also run headless.
+func (a *App) initBackend(ctx context.Context) {
 	a.ctx = ctx
+	crash.SetHandler(a.handleCrash)
 
 	// Load configuration
 	cfg, err := config.Load()
@@ -42,305 +231,3852 @@ func (a *App) startup(ctx context.Context) {
 		cfg = &config.Config{ExecutionTimeout: 60}
 	}
 	a.config = cfg
+	a.configureLogging(cfg)
+	i18n.SetLocale(cfg.Locale)
+	if err != nil {
+		slog.Error("failed to load config, using defaults", "error", err)
+	}
+
+	// Start shell sessions in the configured folder (ignored if it no longer exists)
+	if err := tools.SetDefaultWorkingDir(cfg.DefaultWorkingDir); err != nil {
+		slog.Warn("ignoring default working directory", "error", err)
+	}
+	tools.ResetSession()
+	tools.SetMaxOutputBytes(cfg.Limits.MaxOutputBytesPerTool)
+	tools.SetOutputsDir(config.OutputsDir())
+	tools.SetAuditLog(config.AuditLogPath())
+	tools.SetHistoryLimit(cfg.ShellHistorySize)
+	tools.SetElevation(!cfg.DisableElevation)
+
+	// Export traces of runs if a collector is configured
+	if err := tracing.Configure(cfg.Tracing, version); err != nil {
+		slog.Warn("tracing unavailable", "error", err)
+	}
+
+	// Keep the original contents of files before the agent changes them
+	a.snapshots = snapshot.NewStore(config.SnapshotsDir())
+
+	// Keep usage totals for the dashboard
+	usage, err := metrics.Open(filepath.Join(config.Dir(), "metrics.json"))
+	if err != nil {
+		slog.Warn("starting usage metrics afresh", "error", err)
+	}
+	a.usage = usage
+
+	// Count feature use for telemetry, if the user turned it on
+	recorder, err := telemetry.Open(filepath.Join(config.Dir(), "telemetry.json"))
+	if err != nil {
+		slog.Warn("starting telemetry counts afresh", "error", err)
+	}
+	recorder.SetEnabled(cfg.Telemetry.Active())
+	a.telemetry = recorder
+	go a.sendTelemetry()
+
+	// Let the agent remember facts across conversations
+	memories, err := memory.Open(filepath.Join(config.Dir(), "memories.json"))
+	if err != nil {
+		slog.Warn("starting memories afresh", "error", err)
+	}
+	a.memories = memories
+	if err := memory.Register(memories); err != nil {
+		slog.Warn("memory tools unavailable", "error", err)
+	}
+	agent.SetUserProfileFunc(a.userContext)
+
+	// Keep embeddings of past exchanges so each is embedded once
+	exchangeIndex, err := conversation.OpenSemanticIndex(filepath.Join(config.Dir(), "conversation_embeddings.json"))
+	if err != nil {
+		slog.Warn("embedding past conversations afresh", "error", err)
+	}
+	a.exchangeIndex = exchangeIndex
+	a.embeddingCache = index.OpenCache(filepath.Join(config.IndexDir(), "embeddings"))
+	tools.SetFileChangeHook(a.snapshotFile)
+
+	// Let long-running tools drive progress bars in the UI
+	tools.SetProgressHook(func(p tools.Progress) {
+		a.emit("tool:progress", p)
+	})
 
 	// Initialize LLM client if configured
 	if cfg.IsConfigured() {
 		client, err := llm.NewClient(cfg)
-		if err == nil {
+		if err != nil {
+			slog.Error("failed to create LLM client", "error", err)
+		} else {
 			a.client = client
 		}
 	}
 
-	// Initialize conversation manager
+	// Initialize conversation manager, then clean up old conversations in
+	// the background
 	a.initConversationManager()
-}
+	a.startJanitor()
 
-// initConversationManager initializes or reinitializes the conversation manager.
-func (a *App) initConversationManager() {
-	storePath, err := conversation.GetDefaultStorePath()
-	if err != nil {
-		// Fallback to temp directory if home dir fails
-		storePath = "./conversations"
+	// Pick up edits to config.json made outside the app
+	go a.watchConfig(ctx)
+
+	a.queue = runqueue.New(a.runQueued, queueConcurrency, a.queueChanged)
+
+	// Let the agent read the calendar and mail, and add events and
+	// reminders and send mail the user approves. The app's own tools are
+	// registered before MCP servers and plugins, which can't take their names.
+	if err := calendar.Register(cfg.Calendar); err != nil {
+		slog.Warn("calendar tools unavailable", "error", err)
+	}
+	if err := email.Register(cfg.Email); err != nil {
+		slog.Warn("email tools unavailable", "error", err)
 	}
 
-	store, err := conversation.NewStore(storePath)
-	if err != nil {
-		// Log error but don't fail startup
-		return
+	// Let the agent jump to definitions in the workspace or current folder
+	if err := index.RegisterSymbols(index.NewSymbols(), a.symbolRoot); err != nil {
+		slog.Warn("symbol search unavailable", "error", err)
 	}
 
-	systemPrompt := agent.GetSystemPrompt()
-	a.convManager = conversation.NewManager(store, a.client, systemPrompt)
-}
+	// Accept tasks from chat, asking there before risky tool calls
+	tools.SetApprovalHook(a.approveToolCall)
+	a.startBridge(cfg.Bridges)
 
-// ============================================================================
-// Configuration Methods
-// ============================================================================
+	// Run task files dropped into the inbox folder
+	a.startInbox(cfg.InboxDir)
 
-// GetConfig returns the current configuration
-func (a *App) GetConfig() *config.Config {
-	return a.config
+	// Index the workspace folder for semantic search
+	a.startIndex(cfg.IndexRoot, cfg.IndexChunking)
+
+	// Connect to MCP servers in the background; their tools appear as they connect
+	a.mcp = mcp.NewManager(func(servers []mcp.ServerStatus) {
+		a.emit("mcp:changed", servers)
+	})
+	a.mcp.Apply(cfg.MCPServers)
+
+	// Offer plugins from the tools folder, reloading them as files change
+	go plugins.Watch(ctx, config.ToolsDir(), plugins.DefaultWatchInterval, a.pluginsLoaded)
 }
 
-// SaveConfig saves the configuration
-func (a *App) SaveConfig(cfg *config.Config) error {
-	if err := cfg.Save(); err != nil {
-		return err
+// emit sends an event to the frontend, or to the REST API's event stream
+// when running headless. It does nothing before startup.
+func (a *App) emit(event string, data ...interface{}) {
+	if a.emitter != nil {
+		a.emitter(event, data...)
 	}
-	a.config = cfg
+}
 
-	// Reinitialize client with new config
-	if cfg.IsConfigured() {
-		client, err := llm.NewClient(cfg)
-		if err == nil {
-			a.client = client
-			// Reinitialize conversation manager with the new client
-			a.initConversationManager()
+// shutdown is called when the app is closing.
+func (a *App) shutdown(ctx context.Context) {
+	a.applyUpdate()
+	if a.queue != nil {
+		a.queue.CancelAll()
+	}
+	if a.mcp != nil {
+		a.mcp.Close()
+	}
+	a.stopBridge()
+	a.startInbox("")
+	a.startIndex("", nil)
+	a.tray.Stop()
+	a.hotkey.Unregister()
+	a.StreamLogs("off")
+	a.jobs.Close()
+	a.stopJanitor()
+	if err := a.telemetry.Save(); err != nil {
+		slog.Warn("failed to save telemetry counts", "error", err)
+	}
+	// Save the active conversation and write out the conversation index,
+	// whose changes are batched
+	if a.convManager != nil {
+		if err := a.convManager.Close(); err != nil {
+			slog.Warn("failed to save the active conversation", "error", err)
+		}
+		if closer, ok := a.convManager.GetStore().(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				slog.Warn("failed to close conversation store", "error", err)
+			}
 		}
 	}
-
-	return nil
+	tracing.Shutdown()
 }
 
-// IsConfigured returns true if the app is configured with LLM credentials
-func (a *App) IsConfigured() bool {
-	return a.config != nil && a.config.IsConfigured()
-}
+// beforeClose is called when the window is closed. With minimize-to-tray
+// enabled the window is hidden instead and the app keeps running in the
+// tray; returning true cancels the close.
+func (a *App) beforeClose(ctx context.Context) bool {
+	a.saveWindowState(ctx)
 
-// TestConnection tests the LLM connection
-func (a *App) TestConnection() (bool, string) {
-	if a.config == nil {
-		return false, "No configuration loaded"
+	if a.tray == nil || a.quitting || a.config == nil || !a.config.MinimizeToTray {
+		return false
 	}
-	return llm.TestConnection(a.config)
+	runtime.WindowHide(ctx)
+	return true
 }
 
-// ============================================================================
-// Session Methods
-// ============================================================================
+// restoreWindowPosition moves the window to its saved position if that
+// position is still on the screen.
+func (a *App) restoreWindowPosition(ctx context.Context) {
+	a.windowMu.Lock()
+	defer a.windowMu.Unlock()
+	if a.window == nil || !a.window.Positioned || a.window.Maximised {
+		return
+	}
 
-// GetSessionInfo returns information about the current shell session
-func (a *App) GetSessionInfo() map[string]interface{} {
-	return tools.GetSessionInfo()
+	screens, err := runtime.ScreenGetAll(ctx)
+	if err != nil {
+		return
+	}
+	for _, screen := range screens {
+		// Keep enough of the window on screen to grab its title bar
+		if screen.IsCurrent && a.window.X >= 0 && a.window.Y >= 0 &&
+			a.window.X < screen.Size.Width-100 && a.window.Y < screen.Size.Height-100 {
+			runtime.WindowSetPosition(ctx, a.window.X, a.window.Y)
+			return
+		}
+	}
 }
 
-// ResetSession resets the shell session
-func (a *App) ResetSession() {
-	tools.ResetSession()
+// saveWindowState records the window's size, position, and maximised state
+// for the next launch. A maximised or minimised window keeps its last
+// normal size and position.
+func (a *App) saveWindowState(ctx context.Context) {
+	a.windowMu.Lock()
+	defer a.windowMu.Unlock()
+	if a.window == nil {
+		return
+	}
+
+	a.window.Maximised = runtime.WindowIsMaximised(ctx)
+	if !a.window.Maximised && !runtime.WindowIsMinimised(ctx) {
+		a.window.Width, a.window.Height = runtime.WindowGetSize(ctx)
+		a.window.X, a.window.Y = runtime.WindowGetPosition(ctx)
+		a.window.Positioned = true
+	}
+	if err := config.SaveWindowState(*a.window); err != nil {
+		slog.Warn("failed to save window state", "error", err)
+	}
 }
 
-// ============================================================================
-// Conversation Methods
-// ============================================================================
+// GetWindowLayout returns the saved window and panel layout.
+func (a *App) GetWindowLayout() config.WindowState {
+	defer a.recoverBinding("GetWindowLayout", nil)
 
-// NewConversation creates a new conversation and makes it active.
-func (a *App) NewConversation() *conversation.Conversation {
-	if a.convManager == nil {
-		return nil
+	a.windowMu.Lock()
+	defer a.windowMu.Unlock()
+	if a.window == nil {
+		return config.DefaultWindowState()
 	}
-	return a.convManager.New()
+	return *a.window
 }
 
-// LoadConversation loads an existing conversation by ID.
-func (a *App) LoadConversation(id string) (*conversation.Conversation, error) {
-	if a.convManager == nil {
-		return nil, nil
+// SetPanelWidth saves the width of a resizable panel, such as "sidebar", so
+// it is restored next time.
+func (a *App) SetPanelWidth(panel string, width int) (err error) {
+	defer a.recoverBinding("SetPanelWidth", &err)
+
+	if panel == "" || width < 0 {
+		return errors.New("invalid panel width")
 	}
-	return a.convManager.Load(id)
-}
 
-// ListConversations returns summaries of all saved conversations.
-func (a *App) ListConversations() ([]conversation.Summary, error) {
-	if a.convManager == nil {
-		return nil, nil
+	a.windowMu.Lock()
+	defer a.windowMu.Unlock()
+	if a.window == nil {
+		state := config.LoadWindowState()
+		a.window = &state
 	}
-	return a.convManager.List()
+	if a.window.Panels == nil {
+		a.window.Panels = map[string]int{}
+	}
+	a.window.Panels[panel] = width
+	return config.SaveWindowState(*a.window)
 }
 
-// DeleteConversation removes a conversation by ID.
-func (a *App) DeleteConversation(id string) error {
-	if a.convManager == nil {
-		return nil
-	}
-	return a.convManager.Delete(id)
+// startTray shows the tray icon with its quick actions.
+func (a *App) startTray() {
+	a.tray = tray.Start(tray.Actions{
+		Show: func() {
+			runtime.WindowShow(a.ctx)
+		},
+		NewTask: func() {
+			runtime.WindowShow(a.ctx)
+			if conv := a.NewConversation(); conv != nil {
+				a.emit("tray:new-task", conv.ID)
+			}
+		},
+		StopAgent: a.StopAgent,
+		OpenConversation: func(id string) {
+			runtime.WindowShow(a.ctx)
+			a.emit("tray:open-conversation", id)
+		},
+		Quit: func() {
+			a.quitting = true
+			a.saveWindowState(a.ctx)
+			runtime.Quit(a.ctx)
+		},
+	})
+	a.refreshTray()
 }
 
-// RenameConversation sets a custom title for a conversation.
-func (a *App) RenameConversation(id string, title string) error {
-	if a.convManager == nil {
-		return nil
+// registerHotkey replaces the global quick-task shortcut. An empty combo
+// removes it.
+func (a *App) registerHotkey(combo string) {
+	if combo == a.hotkeyCombo {
+		return
 	}
-
-	// Load the conversation if it's not active
-	active := a.convManager.GetActive()
-	if active == nil || active.ID != id {
-		_, err := a.convManager.Load(id)
-		if err != nil {
-			return err
-		}
+	a.hotkey.Unregister()
+	a.hotkey, a.hotkeyCombo = nil, ""
+	if combo == "" {
+		return
 	}
 
-	return a.convManager.Rename(title)
+	hk, err := hotkey.Parse(combo)
+	if err != nil {
+		slog.Warn("ignoring global shortcut", "shortcut", combo, "error", err)
+		return
+	}
+	reg, err := hotkey.Register(hk, a.summonQuickTask)
+	if err != nil {
+		slog.Warn("failed to register global shortcut", "shortcut", combo, "error", err)
+		return
+	}
+	a.hotkey, a.hotkeyCombo = reg, combo
 }
 
-// GetActiveConversation returns the currently active conversation.
-func (a *App) GetActiveConversation() *conversation.Conversation {
-	if a.convManager == nil {
-		return nil
-	}
-	return a.convManager.GetActive()
+// summonQuickTask raises the window and asks the frontend to focus the
+// message box.
+func (a *App) summonQuickTask() {
+	a.raiseWindow()
+	a.emit("hotkey:quick-task")
 }
 
-// SendMessage sends a message to the active conversation and runs the agent.
-// This is the main method for multi-turn chat.
-func (a *App) SendMessage(message string, taskContext string) {
-	if a.client == nil {
-		runtime.EventsEmit(a.ctx, "agent:error", "LLM not configured")
+// refreshTray updates the tray's list of recent conversations.
+func (a *App) refreshTray() {
+	if a.tray == nil || a.convManager == nil {
 		return
 	}
-
-	if a.convManager == nil {
-		runtime.EventsEmit(a.ctx, "agent:error", "Conversation manager not initialized")
+	page, err := a.convManager.ListPage(0, tray.MaxRecent)
+	if err != nil {
 		return
 	}
-
-	// Ensure we have an active conversation
-	if a.convManager.GetActive() == nil {
-		a.convManager.New()
+	entries := make([]tray.Entry, len(page.Summaries))
+	for i, summary := range page.Summaries {
+		entries[i] = tray.Entry{ID: summary.ID, Title: summary.Title}
 	}
+	a.tray.SetRecent(entries)
+}
 
-	// Cancel any existing agent run
-	if a.agentCancel != nil {
-		a.agentCancel()
+// initConversationManager initializes or reinitializes the conversation manager.
+func (a *App) initConversationManager() {
+	store, err := a.openConversationStore()
+	if err != nil {
+		// Conversations are unavailable, but the rest of the app still works
+		slog.Error("failed to open conversation store", "error", err)
+		return
 	}
 
-	// Create new context for this run
-	a.agentCtx, a.agentCancel = context.WithCancel(context.Background())
-
-	go func() {
-		// Build message content with optional context
-		content := message
-		if taskContext != "" {
-			content = message + "\n\nContext: " + taskContext
+	var previous conversation.Store
+	if a.convManager != nil {
+		// The janitor works on the previous store, which is about to close
+		a.stopJanitor()
+		previous = a.convManager.GetStore()
+		if err := a.convManager.Close(); err != nil {
+			slog.Warn("failed to save the active conversation", "error", err)
 		}
+	}
 
-		// Add user message to conversation
-		if err := a.convManager.AddUserMessage(content); err != nil {
-			runtime.EventsEmit(a.ctx, "agent:error", "Failed to add message: "+err.Error())
-			return
+	systemPrompt := agent.GetSystemPrompt()
+	a.convManager = conversation.NewManager(store, a.client, systemPrompt)
+	a.convManager.SetSystemPromptFunc(agent.GetSystemPrompt)
+	// Tell the UI when the open conversation was changed on another machine
+	// and reloaded; the local version is kept in a conflict copy
+	a.convManager.SetConflictFunc(func(id string, err error) {
+		a.emit("conversation:conflict", id, err.Error())
+	})
+	if a.config != nil {
+		a.convManager.SetContextWindow(a.config.ContextWindow)
+		a.convManager.SetLLMTitles(!a.config.DisableLLMTitles)
+	}
+
+	// Batch saves during runs, journaling messages in between. The journal
+	// isn't encrypted, so encrypted stores save every message instead.
+	if !a.readOnly && a.storeCipher == nil && (a.config == nil || a.config.Encryption == "") {
+		if err := a.convManager.SetJournal(filepath.Join(a.storePath, "journal.jsonl")); err != nil {
+			slog.Warn("saving every message as it is added", "error", err)
 		}
+	}
 
-		// Get messages for the agent
-		messages := a.convManager.GetMessages()
+	if a.readOnly {
+		// Tell the UI why changes can't be saved
+		a.emit("conversations:readonly", conversation.ErrReadOnly.Error())
+	}
 
-		maxSteps := 20
-		if a.config.ExecutionTimeout > 0 {
-			maxSteps = a.config.ExecutionTimeout / 3
-			if maxSteps < 10 {
-				maxSteps = 10
-			}
-			if maxSteps > 50 {
-				maxSteps = 50
-			}
-		}
+	// Release the previous store's resources (e.g. a SQLite handle)
+	if closer, ok := previous.(io.Closer); ok && previous != store {
+		closer.Close()
+	}
+}
 
-		// Run conversation continuation
-		for step := range agent.ContinueConversation(a.agentCtx, a.client, messages, maxSteps) {
-			// Emit step to frontend
-			runtime.EventsEmit(a.ctx, "agent:step", step)
-
-			// Update conversation with new messages if present
-			if step.Messages != nil {
-				// Find and add new messages since last sync
-				currentMsgs := a.convManager.GetMessages()
-				for i := len(currentMsgs); i < len(step.Messages); i++ {
-					msg := step.Messages[i]
-					if msg.Role == "assistant" {
-						a.convManager.AddAssistantMessage(msg)
-					} else if msg.Role == "tool" {
-						a.convManager.AddToolMessage(msg.ToolCallID, msg.Content)
-					}
-				}
-			}
+// startJanitor applies the retention policy and purges expired trash in
+// the background. It runs once per app start; later calls do nothing.
+func (a *App) startJanitor() {
+	a.janitorMu.Lock()
+	defer a.janitorMu.Unlock()
+	if a.janitorDone != nil || a.convManager == nil || a.readOnly || a.ctx == nil {
+		return
+	}
 
-			// Handle completion states
-			if step.Type == agent.StepTypeComplete {
-				// Generate title if this is the first completion
-				go a.convManager.GenerateTitle(context.Background())
-				runtime.EventsEmit(a.ctx, "agent:complete", step.Content)
-				return
-			}
-			if step.Type == agent.StepTypeAssistantMessage {
-				// Conversational response - also triggers title generation
-				go a.convManager.GenerateTitle(context.Background())
-				runtime.EventsEmit(a.ctx, "agent:message", step.Content)
-				return
-			}
-			if step.Type == agent.StepTypeError {
-				runtime.EventsEmit(a.ctx, "agent:error", step.Content)
-				return
-			}
-		}
+	trashRetention := time.Duration(0)
+	if a.config != nil {
+		trashRetention = time.Duration(a.config.TrashRetentionDays) * 24 * time.Hour
+	}
+	manager, policy := a.convManager, a.retentionPolicy()
+	ctx, cancel := context.WithCancel(a.ctx)
+	done := make(chan struct{})
+	a.janitorCancel, a.janitorDone = cancel, done
+	go func() {
+		defer close(done)
+		a.runJanitor(ctx, manager, policy, trashRetention)
 	}()
 }
 
-// ============================================================================
-// Agent Methods (Legacy - kept for backward compatibility)
-// ============================================================================
+// stopJanitor stops the janitor, if it is still running, and waits for it
+// to finish.
+func (a *App) stopJanitor() {
+	a.janitorMu.Lock()
+	defer a.janitorMu.Unlock()
+	if a.janitorCancel == nil {
+		return
+	}
+	a.janitorCancel()
+	<-a.janitorDone
+}
 
-// RunAgentTask starts the agent to complete a task
-// It emits events to the frontend as the agent progresses
-func (a *App) RunAgentTask(task string, taskContext string) {
-	if a.client == nil {
-		runtime.EventsEmit(a.ctx, "agent:error", "LLM not configured")
+// runJanitor applies the retention policy and purges expired trash, unless
+// ctx is canceled first.
+func (a *App) runJanitor(ctx context.Context, manager *conversation.Manager, policy conversation.RetentionPolicy, trashRetention time.Duration) {
+	defer crash.Recover("retention janitor", nil)
+
+	report, err := manager.ApplyRetention(policy)
+	if err != nil {
+		slog.Warn("failed to apply the retention policy", "error", err)
+	}
+	if len(report.Archived) > 0 || len(report.Deleted) > 0 {
+		a.emit("conversations:retention", report)
+	}
+	if ctx.Err() != nil {
 		return
 	}
 
-	// Cancel any existing agent run
-	if a.agentCancel != nil {
-		a.agentCancel()
+	// Permanently remove conversations whose trash retention has expired
+	if _, err := manager.PurgeTrash(trashRetention); err != nil {
+		slog.Warn("failed to purge the trash", "error", err)
 	}
+}
 
-	// Create new context for this run
-	a.agentCtx, a.agentCancel = context.WithCancel(context.Background())
+// retentionPolicy builds the conversation retention policy from the config.
+func (a *App) retentionPolicy() conversation.RetentionPolicy {
+	if a.config == nil {
+		return conversation.RetentionPolicy{}
+	}
+	day := 24 * time.Hour
+	return conversation.RetentionPolicy{
+		ArchiveAfter:  time.Duration(a.config.ArchiveAfterDays) * day,
+		DeleteAfter:   time.Duration(a.config.DeleteAfterDays) * day,
+		MaxStoreBytes: int64(a.config.MaxStoreSizeMB) << 20,
+	}
+}
 
-	go func() {
-		// Reset session for fresh start
-		tools.ResetSession()
+// openConversationStore opens the conversation store selected in the config,
+// with encryption applied. When switching to SQLite for the first time,
+// existing JSON conversations are imported into the new database. If the
+// database can't be opened or the import fails, the JSON store is used for
+// this session and the user is told; the import is tried again next time.
+func (a *App) openConversationStore() (conversation.Store, error) {
+	storePath, err := conversation.GetDefaultStorePath()
+	if err != nil {
+		// Fallback to temp directory if home dir fails
+		slog.Warn("no home directory, storing conversations in the working directory", "error", err)
+		storePath = "./conversations"
+	}
 
-		maxSteps := 20
-		if a.config.ExecutionTimeout > 0 {
-			// Use execution timeout as rough guide for max steps
-			maxSteps = a.config.ExecutionTimeout / 3
-			if maxSteps < 10 {
-				maxSteps = 10
-			}
-			if maxSteps > 50 {
-				maxSteps = 50
+	// Only one instance may write to the store; others get read-only access
+	if a.storeLock == nil || a.storePath != storePath {
+		a.storeLock.Release()
+		a.storeLock = nil
+		lock, err := conversation.AcquireStoreLock(storePath)
+		a.readOnly = errors.Is(err, conversation.ErrStoreLocked)
+		if err == nil {
+			a.storeLock = lock
+		} else if a.readOnly {
+			slog.Info("conversation store is in use by another instance; opening read-only")
+		} else {
+			slog.Warn("failed to lock conversation store", "error", err)
+		}
+	}
+
+	fileStore, err := conversation.NewStore(storePath)
+	if err != nil {
+		return nil, err
+	}
+	a.storePath = storePath
+	fileStore.SetCompression(a.config != nil && a.config.CompressConversations)
+
+	if a.config == nil || a.config.StorageBackend != config.StorageBackendSQLite {
+		a.applyStoreEncryption(fileStore)
+		return a.guardStore(fileStore), nil
+	}
+
+	sqliteStore, err := conversation.NewSQLiteStore(filepath.Join(storePath, "conversations.db"))
+	if err != nil {
+		// Fall back to JSON storage rather than losing conversations
+		slog.Error("failed to open SQLite store, using JSON storage", "error", err)
+		a.emit("conversations:storage_fallback", err.Error())
+		a.applyStoreEncryption(fileStore)
+		return a.guardStore(fileStore), nil
+	}
+
+	// Encrypted JSON conversations are read, and imported ones written, with
+	// the same key
+	a.applyStoreEncryption(fileStore, sqliteStore)
+	if existing, err := sqliteStore.List(); err == nil && len(existing) == 0 && !a.readOnly {
+		n, err := sqliteStore.ImportFrom(fileStore)
+		if err != nil {
+			slog.Error("failed to import conversations into SQLite, using JSON storage", "error", err)
+			a.emit("conversations:storage_fallback", err.Error())
+			sqliteStore.Close()
+			return a.guardStore(fileStore), nil
+		}
+		if n > 0 {
+			slog.Info("imported conversations into SQLite", "count", n)
+		}
+	}
+
+	return a.guardStore(sqliteStore), nil
+}
+
+// guardStore wraps store read-only if another instance owns it.
+func (a *App) guardStore(store conversation.Store) conversation.Store {
+	if a.readOnly {
+		return conversation.NewReadOnlyStore(store)
+	}
+	return store
+}
+
+// applyStoreEncryption enables encryption on the stores according to the
+// config. Keychain keys are fetched automatically; passphrase-derived keys
+// are only available after UnlockConversations. Until a key is available
+// the stores are locked, so nothing is saved in plaintext.
+func (a *App) applyStoreEncryption(stores ...conversation.Store) {
+	if a.config == nil {
+		return
+	}
+
+	switch a.config.Encryption {
+	case config.EncryptionKeychain:
+		if a.storeCipher == nil {
+			c, err := conversation.CipherFromKeychain()
+			if err != nil {
+				slog.Error("failed to read encryption key from keychain; conversations won't be saved", "error", err)
+				a.emit("conversations:locked", err.Error())
 			}
+			a.storeCipher = c
+		}
+	case config.EncryptionPassphrase:
+		// Keep the unlocked cipher across reinitialization
+	default:
+		a.storeCipher = nil
+	}
+	encrypt := a.config.Encryption == config.EncryptionKeychain || a.config.Encryption == config.EncryptionPassphrase
+	for _, store := range stores {
+		encrypted, ok := store.(conversation.EncryptedStore)
+		switch {
+		case !ok:
+		case encrypt && a.storeCipher == nil:
+			encrypted.Lock()
+		default:
+			encrypted.SetCipher(a.storeCipher)
 		}
+	}
+}
 
-		for step := range agent.RunLoop(a.agentCtx, a.client, task, taskContext, maxSteps) {
-			// Emit step to frontend
-			runtime.EventsEmit(a.ctx, "agent:step", step)
+// ============================================================================
+// Configuration Methods
+// ============================================================================
 
-			// Check if complete or error
-			if step.Type == agent.StepTypeComplete {
-				runtime.EventsEmit(a.ctx, "agent:complete", step.Content)
-				return
+// GetConfig returns the configuration as saved in config.json, for the
+// settings UI to edit. Environment variables override some of its values
+// while the app runs; see config.ApplyEnv.
+func (a *App) GetConfig() *config.Config {
+	defer a.recoverBinding("GetConfig", nil)
+
+	if a.config == nil {
+		return nil
+	}
+	return a.config.WithoutEnv()
+}
+
+// SaveConfig saves the configuration and applies it, with any environment
+// variable overrides.
+func (a *App) SaveConfig(cfg *config.Config) (err error) {
+	defer a.recoverBinding("SaveConfig", &err)
+
+	if err := tools.SetDefaultWorkingDir(cfg.DefaultWorkingDir); err != nil {
+		return err
+	}
+	if cfg.GlobalHotkey != "" {
+		if _, err := hotkey.Parse(cfg.GlobalHotkey); err != nil {
+			return err
+		}
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	a.applyConfig(cfg)
+	return nil
+}
+
+// GetLocales returns the languages the app's messages are translated into,
+// for the locale setting.
+func (a *App) GetLocales() []string {
+	defer a.recoverBinding("GetLocales", nil)
+
+	return i18n.Available()
+}
+
+// ValidateConfig checks cfg without saving it and returns every problem found,
+// keyed by JSON field name, so the settings UI can highlight the offending
+// inputs. If the endpoint is well-formed it is also probed for reachability.
+func (a *App) ValidateConfig(cfg *config.Config) []config.FieldError {
+	defer a.recoverBinding("ValidateConfig", nil)
+
+	if cfg == nil {
+		return nil
+	}
+
+	diags := cfg.Diagnose()
+	if cfg.GlobalHotkey != "" {
+		if _, err := hotkey.Parse(cfg.GlobalHotkey); err != nil {
+			diags = append(diags, config.FieldError{Field: "global_hotkey", Message: err.Error(), Severity: config.SeverityError})
+		}
+	}
+	for _, fe := range diags {
+		if fe.Severity == config.SeverityError && (fe.Field == "endpoint" || fe.Field == "ca_cert_path") {
+			return diags
+		}
+	}
+	return append(diags, llm.ProbeEndpoint(context.Background(), cfg)...)
+}
+
+// applyConfig makes cfg the running configuration, rebuilding the LLM client
+// and conversation manager. The active conversation stays open.
+func (a *App) applyConfig(cfg *config.Config) {
+	a.config = cfg
+	a.configureLogging(cfg)
+	i18n.SetLocale(cfg.Locale)
+	tools.SetDefaultWorkingDir(cfg.DefaultWorkingDir)
+	tools.SetMaxOutputBytes(cfg.Limits.MaxOutputBytesPerTool)
+	tools.SetHistoryLimit(cfg.ShellHistorySize)
+	tools.SetElevation(!cfg.DisableElevation)
+	a.telemetry.SetEnabled(cfg.Telemetry.Active())
+	if err := tracing.Configure(cfg.Tracing, version); err != nil {
+		slog.Warn("tracing unavailable", "error", err)
+	}
+	if a.mcp != nil {
+		a.mcp.Apply(cfg.MCPServers)
+	}
+	if err := calendar.Register(cfg.Calendar); err != nil {
+		slog.Warn("calendar tools unavailable", "error", err)
+	}
+	if err := email.Register(cfg.Email); err != nil {
+		slog.Warn("email tools unavailable", "error", err)
+	}
+	if a.ctx != nil && !reflect.DeepEqual(a.bridgeSettings, cfg.Bridges) {
+		a.startBridge(cfg.Bridges)
+	}
+	if a.ctx != nil && a.inboxDir != cfg.InboxDir {
+		a.startInbox(cfg.InboxDir)
+	}
+	if a.ctx != nil && !a.headless {
+		a.registerHotkey(cfg.GlobalHotkey)
+	}
+
+	// Reinitialize client with new config
+	if cfg.IsConfigured() {
+		client, err := llm.NewClient(cfg)
+		if err != nil {
+			slog.Error("failed to create LLM client", "error", err)
+		} else {
+			a.client = client
+
+			var activeID string
+			if a.convManager != nil && a.convManager.GetActive() != nil {
+				activeID = a.convManager.GetActive().ID
 			}
-			if step.Type == agent.StepTypeError {
-				runtime.EventsEmit(a.ctx, "agent:error", step.Content)
-				return
+
+			// Reinitialize conversation manager with the new client
+			a.initConversationManager()
+			if activeID != "" && a.convManager != nil {
+				a.convManager.Load(activeID)
 			}
 		}
-	}()
+	}
+
+	a.indexMu.Lock()
+	x := a.index
+	a.indexMu.Unlock()
+	root := ""
+	if x != nil {
+		root = x.Root()
+	}
+	if a.ctx != nil && root != cfg.IndexRoot {
+		a.startIndex(cfg.IndexRoot, cfg.IndexChunking)
+	} else if x != nil {
+		x.SetEmbedder(a.embedder())
+		x.SetChunking(cfg.IndexChunking)
+	}
 }
 
-// StopAgent stops the currently running agent
-func (a *App) StopAgent() {
-	if a.agentCancel != nil {
-		a.agentCancel()
-		a.agentCancel = nil
+// configureLogging applies the logging, capture, and cassette settings from
+// cfg. A bad setting leaves the previous logger or cassette in place.
+func (a *App) configureLogging(cfg *config.Config) {
+	llm.SetCapture(cfg.CaptureLLMTraffic)
+	if err := llm.SetCassette(cfg.Cassette, cfg.CassetteMode, cfg.Secrets()); err != nil {
+		slog.Error("failed to set cassette", "error", err)
+	}
+	err := logging.Init(logging.Options{
+		Level:         cfg.LogLevel,
+		File:          cfg.LogFilePath(),
+		RetentionDays: cfg.LogRetentionDays,
+	})
+	if err != nil {
+		slog.Error("failed to configure logging", "error", err)
+	}
+}
+
+// GetRecentLogs returns up to the last n log records, oldest first, for
+// viewing in the app. n <= 0 returns every record kept.
+func (a *App) GetRecentLogs(n int) []logging.Entry {
+	defer a.recoverBinding("GetRecentLogs", nil)
+
+	return logging.Recent(n)
+}
+
+// GetCommandAuditLog returns up to the last n commands the agent ran,
+// oldest first, across every conversation and beyond the session history
+// limit. n <= 0 returns every command logged.
+func (a *App) GetCommandAuditLog(n int) (_ []tools.CommandRecord, err error) {
+	defer a.recoverBinding("GetCommandAuditLog", &err)
+
+	return tools.ReadAuditLog(n)
+}
+
+// StreamLogs emits each record logged at or above level from now on as a
+// "logs:append" event carrying a logging.Entry, replacing any stream
+// started before. "off" stops the stream. Records below log_level aren't
+// logged, so they aren't streamed either.
+func (a *App) StreamLogs(level string) (err error) {
+	defer a.recoverBinding("StreamLogs", &err)
+
+	var stop func()
+	if level != "off" {
+		min, err := logging.ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		stop = logging.Subscribe(min, func(e logging.Entry) {
+			a.emit("logs:append", e)
+		})
+	}
+
+	a.logStreamMu.Lock()
+	defer a.logStreamMu.Unlock()
+	if a.stopLogStream != nil {
+		a.stopLogStream()
+	}
+	a.stopLogStream = stop
+	return nil
+}
+
+// GetLLMTrace returns the requests to the provider and their responses
+// captured while capture_llm_traffic is on, oldest first, with keys masked.
+func (a *App) GetLLMTrace() []llm.Exchange {
+	defer a.recoverBinding("GetLLMTrace", nil)
+
+	return llm.Trace()
+}
+
+// GetProviderHealth returns the latency and recent failures of each provider
+// profile used since the app started, healthy providers first.
+func (a *App) GetProviderHealth() []llm.ProviderHealth {
+	defer a.recoverBinding("GetProviderHealth", nil)
+
+	return llm.Health()
+}
+
+// GetTelemetryPreview returns the anonymous usage report that would be
+// sent next, so the user can see exactly what telemetry shares. It is empty
+// while telemetry is off.
+func (a *App) GetTelemetryPreview() telemetry.Report {
+	defer a.recoverBinding("GetTelemetryPreview", nil)
+
+	return a.telemetry.Preview(version)
+}
+
+// sendTelemetry sends the day's usage report if telemetry is on and one is
+// due.
+func (a *App) sendTelemetry() {
+	if a.config == nil || !a.config.Telemetry.Active() || !a.telemetry.Due() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryTimeout)
+	defer cancel()
+	if err := a.telemetry.Send(ctx, a.config.Telemetry.Endpoint, version); err != nil {
+		slog.Debug("telemetry report not sent", "error", err)
+	}
+}
+
+// telemetryTimeout bounds sending a usage report.
+const telemetryTimeout = 30 * time.Second
+
+// ClearLLMTrace forgets the captured requests to the provider.
+func (a *App) ClearLLMTrace() {
+	defer a.recoverBinding("ClearLLMTrace", nil)
+
+	llm.ClearTrace()
+}
+
+// watchConfig reloads the configuration when config.json is edited outside
+// the app and notifies the frontend with a config:changed event.
+func (a *App) watchConfig(ctx context.Context) {
+	config.Watch(ctx, config.DefaultWatchInterval, func(cfg *config.Config) {
+		// Ignore our own saves
+		if a.config != nil && reflect.DeepEqual(*cfg, *a.config) {
+			return
+		}
+		a.applyConfig(cfg)
+		a.emit("config:changed", cfg.WithoutEnv())
+	})
+}
+
+// ExportSettings writes the configuration and settings files, such as the
+// system prompt template, to a bundle at path. The API key is not exported.
+func (a *App) ExportSettings(path string) (err error) {
+	defer a.recoverBinding("ExportSettings", &err)
+
+	if a.config == nil {
+		return errors.New("no configuration loaded")
+	}
+	return config.ExportBundle(path, a.config.WithoutEnv())
+}
+
+// ImportSettings applies a bundle written by ExportSettings. The current API
+// key is kept since bundles don't contain it.
+func (a *App) ImportSettings(path string) (err error) {
+	defer a.recoverBinding("ImportSettings", &err)
+
+	var current *config.Config
+	if a.config != nil {
+		current = a.config.WithoutEnv()
+	}
+	cfg, err := config.ImportBundle(path, current)
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	a.applyConfig(cfg)
+	return nil
+}
+
+// PickFile shows the native open dialog and returns the chosen file, or ""
+// if the user cancelled. Filters restrict the file types offered, e.g.
+// {DisplayName: "Settings bundles (*.json)", Pattern: "*.json"}.
+func (a *App) PickFile(filters []runtime.FileFilter) (_ string, err error) {
+	defer a.recoverBinding("PickFile", &err)
+
+	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:            i18n.T("dialog.choose_file"),
+		DefaultDirectory: dialogDir(),
+		Filters:          filters,
+	})
+}
+
+// PickFolder shows the native folder dialog and returns the chosen folder,
+// or "" if the user cancelled.
+func (a *App) PickFolder() (_ string, err error) {
+	defer a.recoverBinding("PickFolder", &err)
+
+	return runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:                i18n.T("dialog.choose_folder"),
+		DefaultDirectory:     dialogDir(),
+		CanCreateDirectories: true,
+	})
+}
+
+// PickSavePath shows the native save dialog, suggesting defaultName, and
+// returns the chosen path, or "" if the user cancelled.
+func (a *App) PickSavePath(defaultName string, filters []runtime.FileFilter) (_ string, err error) {
+	defer a.recoverBinding("PickSavePath", &err)
+
+	return runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:                i18n.T("dialog.save_as"),
+		DefaultDirectory:     dialogDir(),
+		DefaultFilename:      defaultName,
+		Filters:              filters,
+		CanCreateDirectories: true,
+	})
+}
+
+// CopyToClipboard puts text on the system clipboard. The webview's own
+// clipboard API needs focus and permissions that vary by platform.
+func (a *App) CopyToClipboard(text string) (err error) {
+	defer a.recoverBinding("CopyToClipboard", &err)
+
+	return runtime.ClipboardSetText(a.ctx, text)
+}
+
+// ReadClipboard returns the text on the system clipboard, or "" if it
+// holds no text.
+func (a *App) ReadClipboard() (_ string, err error) {
+	defer a.recoverBinding("ReadClipboard", &err)
+
+	return runtime.ClipboardGetText(a.ctx)
+}
+
+// dialogDir returns the directory file dialogs open in: the agent's current
+// working directory, if it still exists.
+func dialogDir() string {
+	dir := tools.GetSession().GetCWD()
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+// GetLimits returns the configured budget and execution limits.
+func (a *App) GetLimits() config.Limits {
+	defer a.recoverBinding("GetLimits", nil)
+
+	if a.config == nil {
+		return config.Limits{}
+	}
+	return a.config.Limits
+}
+
+// SetLimits saves new budget and execution limits.
+func (a *App) SetLimits(limits config.Limits) (err error) {
+	defer a.recoverBinding("SetLimits", &err)
+
+	if a.config == nil {
+		return errors.New("no configuration loaded")
+	}
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	cfg := *a.config
+	cfg.Limits = limits
+	return a.SaveConfig(&cfg)
+}
+
+// BudgetWarning is a spending alert threshold that estimated spend reached.
+type BudgetWarning struct {
+	Period    string  `json:"period"` // "day" or "month"
+	Spent     float64 `json:"spent"`  // Estimated US dollars spent in the period
+	Threshold float64 `json:"threshold"`
+	Paused    bool    `json:"paused"` // New runs wait until the warning is acknowledged
+}
+
+// GetBudgetWarning returns the spending alert new runs are waiting on, or
+// nil if runs aren't paused.
+func (a *App) GetBudgetWarning() *BudgetWarning {
+	defer a.recoverBinding("GetBudgetWarning", nil)
+
+	a.budgetMu.Lock()
+	defer a.budgetMu.Unlock()
+	return a.budgetPause
+}
+
+// AcknowledgeBudgetWarning lets new runs start again after a spending alert
+// paused them.
+func (a *App) AcknowledgeBudgetWarning() {
+	defer a.recoverBinding("AcknowledgeBudgetWarning", nil)
+
+	a.budgetMu.Lock()
+	defer a.budgetMu.Unlock()
+	a.budgetPause = nil
+}
+
+// checkSpendAlerts shows a notification and emits an "agent:budget-warning"
+// event the first time each day or month that estimated spend reaches the
+// configured alert, pausing new runs if the limits say to.
+func (a *App) checkSpendAlerts() {
+	limits := a.GetLimits()
+	if limits.AlertCostPerDay <= 0 && limits.AlertCostPerMonth <= 0 {
+		return
+	}
+	day, month := a.usage.Spend()
+	today := time.Now().Format("2006-01-02")
+
+	a.budgetMu.Lock()
+	var hits []BudgetWarning
+	if limits.AlertCostPerDay > 0 && day >= limits.AlertCostPerDay && a.alertedDay != today {
+		a.alertedDay = today
+		hits = append(hits, BudgetWarning{Period: "day", Spent: day, Threshold: limits.AlertCostPerDay})
+	}
+	if limits.AlertCostPerMonth > 0 && month >= limits.AlertCostPerMonth && a.alertedMonth != today[:7] {
+		a.alertedMonth = today[:7]
+		hits = append(hits, BudgetWarning{Period: "month", Spent: month, Threshold: limits.AlertCostPerMonth})
+	}
+	for i := range hits {
+		hits[i].Paused = limits.PauseOnAlert
+		if limits.PauseOnAlert {
+			a.budgetPause = &hits[i]
+		}
+	}
+	a.budgetMu.Unlock()
+
+	for _, w := range hits {
+		a.notify(config.NotifyBudget, i18n.T("notify.spending_alert"), i18n.T("notify.spending_alert_"+w.Period, w.Spent, w.Threshold))
+		a.emit("agent:budget-warning", w)
+	}
+}
+
+// UpdateInfo describes the newest release available to this install.
+type UpdateInfo struct {
+	CurrentVersion string `json:"current_version"`
+	Available      bool   `json:"available"`         // A newer release was found
+	Version        string `json:"version,omitempty"` // Version of the newer release
+	Notes          string `json:"notes,omitempty"`   // Its release notes
+	Ready          bool   `json:"ready"`             // Downloaded and verified; installed when the app exits
+}
+
+// GetVersion returns the application version.
+func (a *App) GetVersion() string {
+	defer a.recoverBinding("GetVersion", nil)
+
+	return version
+}
+
+// CheckForUpdates looks for a newer release on the configured channel and,
+// if there is one, downloads and verifies it. The update is installed when
+// the app exits and takes effect on the next launch.
+func (a *App) CheckForUpdates() (_ UpdateInfo, err error) {
+	defer a.recoverBinding("CheckForUpdates", &err)
+
+	info := UpdateInfo{CurrentVersion: version}
+	u := a.newUpdater()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	release, err := u.Check(ctx)
+	if err != nil || release == nil {
+		return info, err
+	}
+	info.Available, info.Version, info.Notes = true, release.Version, release.Notes
+
+	if pending := u.Pending(); pending != nil && pending.Version == release.Version {
+		info.Ready = true
+		return info, nil
+	}
+	if _, err := u.Download(ctx, release); err != nil {
+		return info, err
+	}
+	info.Ready = true
+	return info, nil
+}
+
+// checkForUpdatesInBackground checks for updates at startup and emits an
+// update:ready event when one has been downloaded.
+func (a *App) checkForUpdatesInBackground() {
+	defer crash.Recover("update check", nil)
+
+	info, err := a.CheckForUpdates()
+	if err != nil {
+		slog.Info("update check failed", "error", err)
+		return
+	}
+	if info.Ready {
+		slog.Info("update ready", "version", info.Version)
+		a.emit("update:ready", info)
+	}
+}
+
+// newUpdater returns an updater for the configured channel and feed.
+func (a *App) newUpdater() *updater.Updater {
+	u := &updater.Updater{
+		CurrentVersion: version,
+		Dir:            config.UpdatesDir(),
 	}
+	if a.config != nil {
+		u.Channel = a.config.UpdateChannel
+		u.FeedURL = a.config.UpdateFeedURL
+	}
+	return u
+}
+
+// applyUpdate installs a downloaded update in place of the running
+// executable.
+func (a *App) applyUpdate() {
+	exe, err := os.Executable()
+	if err != nil {
+		slog.Warn("cannot locate executable to update", "error", err)
+		return
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		slog.Warn("cannot locate executable to update", "error", err)
+		return
+	}
+	installed, err := a.newUpdater().Apply(exe)
+	if err != nil {
+		slog.Error("failed to install update", "error", err)
+	} else if installed != "" {
+		slog.Info("installed update", "version", installed)
+	}
+}
+
+// IsConfigured returns true if the app is configured with LLM credentials
+func (a *App) IsConfigured() bool {
+	defer a.recoverBinding("IsConfigured", nil)
+
+	return a.config != nil && a.config.IsConfigured()
+}
+
+// NeedsSetup returns true if the first-run setup wizard should be shown.
+// Users who configured the app before the wizard existed skip it.
+func (a *App) NeedsSetup() bool {
+	defer a.recoverBinding("NeedsSetup", nil)
+
+	return a.config == nil || (!a.config.SetupComplete && !a.config.IsConfigured())
+}
+
+// DetectProviders looks for local LLM servers and returns endpoint
+// suggestions for the setup wizard.
+func (a *App) DetectProviders() []llm.ProviderSuggestion {
+	defer a.recoverBinding("DetectProviders", nil)
+
+	return llm.DetectProviders(context.Background())
+}
+
+// ListModels returns the models served by the endpoint in cfg.
+func (a *App) ListModels(cfg *config.Config) (_ []string, err error) {
+	defer a.recoverBinding("ListModels", &err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return llm.ListModels(ctx, cfg)
+}
+
+// RunSetupProbe checks that cfg connects and that its model can call tools.
+func (a *App) RunSetupProbe(cfg *config.Config) llm.SetupProbeResult {
+	defer a.recoverBinding("RunSetupProbe", nil)
+
+	return llm.ProbeSetup(context.Background(), cfg)
+}
+
+// CompleteSetup saves the configuration chosen in the setup wizard and
+// marks setup as done.
+func (a *App) CompleteSetup(cfg *config.Config) (err error) {
+	defer a.recoverBinding("CompleteSetup", &err)
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	cfg.SetupComplete = true
+	return a.SaveConfig(cfg)
+}
+
+// TestConnection checks the LLM connection step by step (DNS, TLS, HTTP,
+// API key, tool calling, latency) so a failure points at its cause.
+func (a *App) TestConnection() llm.Diagnosis {
+	defer a.recoverBinding("TestConnection", nil)
+
+	if a.config == nil {
+		return llm.Diagnosis{Message: "No configuration loaded"}
+	}
+	return llm.Diagnose(a.ctx, a.config)
+}
+
+// IsConversationStoreReadOnly returns true if another running instance owns
+// the conversation store, so conversations can be viewed but not changed.
+func (a *App) IsConversationStoreReadOnly() bool {
+	defer a.recoverBinding("IsConversationStoreReadOnly", nil)
+
+	return a.readOnly
+}
+
+// IsConversationStoreLocked returns true if conversations are encrypted with
+// a passphrase that has not been entered yet this session.
+func (a *App) IsConversationStoreLocked() bool {
+	defer a.recoverBinding("IsConversationStoreLocked", nil)
+
+	return a.config != nil && a.config.Encryption == config.EncryptionPassphrase && a.storeCipher == nil
+}
+
+// UnlockConversations derives the storage key from a passphrase and unlocks
+// encrypted conversations. The first passphrase entered becomes the
+// passphrase for the store.
+func (a *App) UnlockConversations(passphrase string) (err error) {
+	defer a.recoverBinding("UnlockConversations", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+
+	c, err := conversation.CipherFromPassphrase(a.storePath, passphrase)
+	if err != nil {
+		return err
+	}
+	a.storeCipher = c
+
+	if encrypted, ok := a.convManager.GetStore().(conversation.EncryptedStore); ok {
+		encrypted.SetCipher(c)
+	}
+	return nil
+}
+
+// EncryptExistingConversations rewrites every stored conversation so data
+// saved before encryption was enabled is encrypted too.
+func (a *App) EncryptExistingConversations() (err error) {
+	defer a.recoverBinding("EncryptExistingConversations", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	if a.storeCipher == nil {
+		return errors.New("encryption is not enabled or the store is locked")
+	}
+	return conversation.RewriteAll(a.convManager.GetStore())
+}
+
+// GetSystemPromptTemplate returns the system prompt template used for new
+// conversations. Templates may use {OS}, {CWD}, {DATE}, {USERNAME}, and
+// {OS_INSTRUCTIONS}.
+func (a *App) GetSystemPromptTemplate() string {
+	defer a.recoverBinding("GetSystemPromptTemplate", nil)
+
+	return agent.GetSystemPromptTemplate()
+}
+
+// SaveSystemPromptTemplate saves a custom system prompt template. It applies
+// to conversations created afterwards.
+func (a *App) SaveSystemPromptTemplate(template string) (err error) {
+	defer a.recoverBinding("SaveSystemPromptTemplate", &err)
+
+	return agent.SaveSystemPromptTemplate(template)
+}
+
+// ResetSystemPromptTemplate restores the built-in system prompt template and
+// returns it.
+func (a *App) ResetSystemPromptTemplate() (_ string, err error) {
+	defer a.recoverBinding("ResetSystemPromptTemplate", &err)
+
+	if err := agent.ResetSystemPromptTemplate(); err != nil {
+		return "", err
+	}
+	return agent.DefaultSystemPromptTemplate(), nil
+}
+
+// PreviewSystemPrompt renders a template with the current variable values.
+func (a *App) PreviewSystemPrompt(template string) string {
+	defer a.recoverBinding("PreviewSystemPrompt", nil)
+
+	return agent.RenderSystemPrompt(template)
+}
+
+// ============================================================================
+// Session Methods
+// ============================================================================
+
+// GetSessionInfo returns information about the current shell session
+func (a *App) GetSessionInfo() map[string]interface{} {
+	defer a.recoverBinding("GetSessionInfo", nil)
+
+	return tools.GetSessionInfo()
+}
+
+// ResetSession resets the shell session
+func (a *App) ResetSession() {
+	defer a.recoverBinding("ResetSession", nil)
+
+	tools.ResetSession()
+}
+
+// SessionInspection describes a conversation's shell session for the
+// session inspector. Running commands belong to the live session, which is
+// the open conversation's; other conversations have their working folder
+// and the command history saved with them.
+type SessionInspection struct {
+	tools.SessionInspection
+	ConversationID string `json:"conversation_id,omitempty"`
+	Active         bool   `json:"active"` // The live session belongs to this conversation
+}
+
+// InspectSession returns the working folder, redacted environment, command
+// history with exit codes and durations, and running commands of a
+// conversation's shell session. An empty id inspects the open
+// conversation's session.
+func (a *App) InspectSession(id string) (_ SessionInspection, err error) {
+	defer a.recoverBinding("InspectSession", &err)
+
+	session := tools.GetSession().Inspect()
+
+	var activeID, liveID string
+	if a.convManager != nil {
+		if active := a.convManager.GetActive(); active != nil {
+			activeID = active.ID
+		}
+		liveID = a.convManager.SessionConversation()
+	}
+	if id == "" {
+		id = activeID
+	}
+
+	// The live session is the open conversation's unless a run in another
+	// conversation has it
+	if id == "" || id == liveID {
+		return SessionInspection{SessionInspection: session, ConversationID: id, Active: true}, nil
+	}
+
+	cwd, err := a.conversationFolder(id)
+	if err != nil {
+		return SessionInspection{}, err
+	}
+	conv, err := a.convManager.Get(id)
+	if err != nil {
+		return SessionInspection{}, err
+	}
+	history := append([]tools.CommandRecord{}, conv.SessionHistory...)
+	return SessionInspection{
+		SessionInspection: tools.SessionInspection{CWD: cwd, Env: session.Env, History: history, Running: []tools.RunningCommand{}},
+		ConversationID:    id,
+	}, nil
+}
+
+// ExportSessionHistory writes the open conversation's command history to
+// path: JSON if path ends in .json, otherwise a commented shell log with
+// each command's folder, exit code, and duration.
+func (a *App) ExportSessionHistory(id, path string) (err error) {
+	defer a.recoverBinding("ExportSessionHistory", &err)
+
+	info, err := a.InspectSession(id)
+	if err != nil {
+		return err
+	}
+	if !info.Active {
+		return errors.New("command history is only kept for the open conversation")
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err := json.MarshalIndent(info.History, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0644)
+	}
+
+	var sb strings.Builder
+	for _, rec := range info.History {
+		fmt.Fprintf(&sb, "# %s  %s  exit %d  %s\n%s\n\n",
+			rec.StartedAt.Format(time.RFC3339), rec.CWD, rec.ExitCode,
+			time.Duration(rec.DurationMS)*time.Millisecond, rec.Command)
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// ExportRunAsScript writes a script to path that replays the conversation's
+// tool calls, with the agent's reasoning as comments, so a task can be
+// repeated without the agent. A path ending in .ps1 gets a PowerShell
+// script; anything else gets a bash script.
+func (a *App) ExportRunAsScript(convID, path string) (err error) {
+	defer a.recoverBinding("ExportRunAsScript", &err)
+
+	if a.convManager == nil {
+		return errors.New("conversation manager not initialized")
+	}
+	conv, err := a.convManager.Get(convID)
+	if err != nil {
+		return err
+	}
+
+	dialect, mode := conversation.ScriptBash, os.FileMode(0755)
+	if strings.EqualFold(filepath.Ext(path), ".ps1") {
+		dialect, mode = conversation.ScriptPowerShell, 0644
+	}
+	script, err := conv.Script(dialect)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(script), mode)
+}
+
+// ============================================================================
+// Conversation Methods
+// ============================================================================
+
+// NewConversation creates a new conversation and makes it active.
+func (a *App) NewConversation() *conversation.Conversation {
+	defer a.recoverBinding("NewConversation", nil)
+
+	if a.convManager == nil {
+		return nil
+	}
+	conv := a.convManager.New()
+	a.warmStart()
+	return conv
+}
+
+// LoadConversation loads an existing conversation by ID.
+func (a *App) LoadConversation(id string) (_ *conversation.Conversation, err error) {
+	defer a.recoverBinding("LoadConversation", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	conv, err := a.convManager.Load(id)
+	if err == nil {
+		a.warmStart()
+	}
+	return conv, err
+}
+
+// ForkConversation copies the messages of a conversation up to and including
+// fromMessageIndex into a new conversation and makes the fork active.
+func (a *App) ForkConversation(id string, fromMessageIndex int) (_ *conversation.Conversation, err error) {
+	defer a.recoverBinding("ForkConversation", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	conv, err := a.convManager.Fork(id, fromMessageIndex)
+	if err == nil {
+		a.warmStart()
+	}
+	return conv, err
+}
+
+// OpenConversation loads a conversation and makes it active, returning only
+// its summary. Messages are fetched with GetConversationMessages.
+func (a *App) OpenConversation(id string) (_ conversation.Summary, err error) {
+	defer a.recoverBinding("OpenConversation", &err)
+
+	if a.convManager == nil {
+		return conversation.Summary{}, nil
+	}
+	summary, err := a.convManager.Open(id)
+	if err == nil {
+		a.warmStart()
+	}
+	return summary, err
+}
+
+// GetConversationMessages returns a page of a conversation's messages.
+// A negative offset counts from the end, so (-50, 50) returns the last 50.
+func (a *App) GetConversationMessages(id string, offset int, limit int) (_ conversation.MessagePage, err error) {
+	defer a.recoverBinding("GetConversationMessages", &err)
+
+	if a.convManager == nil {
+		return conversation.MessagePage{}, nil
+	}
+	return a.convManager.GetMessagesPage(id, offset, limit)
+}
+
+// ListConversationsPage returns one page of conversation summaries, most
+// recent first, for incremental loading of the sidebar.
+func (a *App) ListConversationsPage(offset int, limit int) (_ conversation.Page, err error) {
+	defer a.recoverBinding("ListConversationsPage", &err)
+
+	if a.convManager == nil {
+		return conversation.Page{}, nil
+	}
+	return a.convManager.ListPage(offset, limit)
+}
+
+// ListConversations returns summaries of all saved conversations.
+func (a *App) ListConversations() (_ []conversation.Summary, err error) {
+	defer a.recoverBinding("ListConversations", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	return a.convManager.List()
+}
+
+// SearchConversations returns up to limit conversations whose title or
+// messages contain query, most recently updated first. A non-positive limit
+// returns every match.
+func (a *App) SearchConversations(query string, limit int) (_ []conversation.SearchResult, err error) {
+	defer a.recoverBinding("SearchConversations", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	return a.convManager.Search(query, limit)
+}
+
+// DeleteConversation moves a conversation to the trash. It can be restored
+// with RestoreConversation until the trash is emptied or its retention expires.
+func (a *App) DeleteConversation(id string) (err error) {
+	defer a.recoverBinding("DeleteConversation", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.Delete(id)
+}
+
+// PinConversation pins or unpins a conversation. Pinned conversations are
+// never archived or deleted by the retention policy.
+func (a *App) PinConversation(id string, pinned bool) (err error) {
+	defer a.recoverBinding("PinConversation", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.SetPinned(id, pinned)
+}
+
+// ArchiveConversation archives or unarchives a conversation.
+func (a *App) ArchiveConversation(id string, archived bool) (err error) {
+	defer a.recoverBinding("ArchiveConversation", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.SetArchived(id, archived)
+}
+
+// RestoreConversation moves a deleted conversation back out of the trash.
+func (a *App) RestoreConversation(id string) (err error) {
+	defer a.recoverBinding("RestoreConversation", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.Restore(id)
+}
+
+// ListConversationTrash returns deleted conversations that can still be restored.
+func (a *App) ListConversationTrash() (_ []conversation.TrashedSummary, err error) {
+	defer a.recoverBinding("ListConversationTrash", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	return a.convManager.ListTrash()
+}
+
+// EmptyConversationTrash permanently removes every deleted conversation.
+func (a *App) EmptyConversationTrash() (err error) {
+	defer a.recoverBinding("EmptyConversationTrash", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.EmptyTrash()
+}
+
+// RenameConversation sets a custom title for a conversation.
+func (a *App) RenameConversation(id string, title string) (err error) {
+	defer a.recoverBinding("RenameConversation", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+
+	// Load the conversation if it's not active
+	active := a.convManager.GetActive()
+	if active == nil || active.ID != id {
+		_, err := a.convManager.Load(id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return a.convManager.Rename(title)
+}
+
+// TagConversation adds a tag to a conversation.
+func (a *App) TagConversation(id string, tag string) (err error) {
+	defer a.recoverBinding("TagConversation", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.TagConversation(id, tag)
+}
+
+// UntagConversation removes a tag from a conversation.
+func (a *App) UntagConversation(id string, tag string) (err error) {
+	defer a.recoverBinding("UntagConversation", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.UntagConversation(id, tag)
+}
+
+// SetConversationFolder moves a conversation into a folder.
+// An empty folder moves it back to the top level.
+func (a *App) SetConversationFolder(id string, folder string) (err error) {
+	defer a.recoverBinding("SetConversationFolder", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.SetFolder(id, folder)
+}
+
+// ListConversationsByTag returns summaries of conversations with the given tag.
+func (a *App) ListConversationsByTag(tag string) (_ []conversation.Summary, err error) {
+	defer a.recoverBinding("ListConversationsByTag", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	return a.convManager.ListByTag(tag)
+}
+
+// ListConversationsInFolder returns summaries of conversations in the given folder.
+func (a *App) ListConversationsInFolder(folder string) (_ []conversation.Summary, err error) {
+	defer a.recoverBinding("ListConversationsInFolder", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	return a.convManager.ListByFolder(folder)
+}
+
+// ListConversationTags returns all tags in use across conversations.
+func (a *App) ListConversationTags() (_ []string, err error) {
+	defer a.recoverBinding("ListConversationTags", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	return a.convManager.ListTags()
+}
+
+// ListConversationFolders returns all folders in use across conversations.
+func (a *App) ListConversationFolders() (_ []string, err error) {
+	defer a.recoverBinding("ListConversationFolders", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	return a.convManager.ListFolders()
+}
+
+// GetConversationSettings returns the per-conversation settings for a conversation.
+func (a *App) GetConversationSettings(id string) (_ conversation.Settings, err error) {
+	defer a.recoverBinding("GetConversationSettings", &err)
+
+	if a.convManager == nil {
+		return conversation.Settings{}, nil
+	}
+	return a.convManager.GetSettings(id)
+}
+
+// UpdateConversationSettings replaces the per-conversation settings
+// (model, persona, working directory, approval policy, max steps).
+func (a *App) UpdateConversationSettings(id string, settings conversation.Settings) (err error) {
+	defer a.recoverBinding("UpdateConversationSettings", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.UpdateSettings(id, settings)
+}
+
+// RegenerateTitle generates a fresh title for a conversation, replacing
+// its current title.
+func (a *App) RegenerateTitle(id string) (_ string, err error) {
+	defer a.recoverBinding("RegenerateTitle", &err)
+
+	if a.convManager == nil {
+		return "", nil
+	}
+	return a.convManager.RegenerateTitle(context.Background(), id)
+}
+
+// GetUsageStats returns token usage, estimated cost, and run and tool-call
+// counts per day and model for the usage dashboard. usageRange is "today",
+// "all", or a number of days like "7d"; empty means the last 30 days.
+func (a *App) GetUsageStats(usageRange string) (_ metrics.Stats, err error) {
+	defer a.recoverBinding("GetUsageStats", &err)
+
+	days, err := metrics.ParseRange(usageRange)
+	if err != nil {
+		return metrics.Stats{}, err
+	}
+	return a.usage.Stats(days), nil
+}
+
+// ExportUsageReport writes the tokens and estimated cost of each model in
+// each conversation over a usage range (see GetUsageStats) to path, as
+// "csv" or "json", for expense reports.
+func (a *App) ExportUsageReport(usageRange, format, path string) (err error) {
+	defer a.recoverBinding("ExportUsageReport", &err)
+
+	if a.convManager == nil {
+		return errors.New("conversation manager not initialized")
+	}
+	days, err := metrics.ParseRange(usageRange)
+	if err != nil {
+		return err
+	}
+	write := conversation.UsageReport.WriteCSV
+	switch strings.ToLower(format) {
+	case "csv":
+	case "json":
+		write = conversation.UsageReport.WriteJSON
+	default:
+		return fmt.Errorf("unknown report format %q: use csv or json", format)
+	}
+
+	now := time.Now()
+	var from time.Time
+	if days > 0 {
+		y, m, d := now.Date()
+		from = time.Date(y, m, d+1-days, 0, 0, 0, 0, now.Location())
+	}
+	report, err := a.convManager.UsageReport(from, now)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(report, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// GetConversationRuns returns the steps of a conversation's recent agent
+// runs, oldest first, so reopening it can replay them as they appeared
+// live. Each run notes the index of the first message it added.
+func (a *App) GetConversationRuns(id string) (_ []conversation.Run, err error) {
+	defer a.recoverBinding("GetConversationRuns", &err)
+
+	if a.convManager == nil {
+		return nil, errors.New("conversation manager not initialized")
+	}
+	return a.convManager.GetRuns(id)
+}
+
+// GetToolStats returns how many times each tool has run, its median and
+// 95th percentile durations over its recent calls, and how often it
+// failed, slowest tools first.
+func (a *App) GetToolStats() []metrics.ToolStats {
+	defer a.recoverBinding("GetToolStats", nil)
+
+	return a.usage.ToolStats()
+}
+
+// GetConversationStats returns cumulative token, cost, tool-call, and
+// wall-time totals for a conversation.
+func (a *App) GetConversationStats(id string) (_ conversation.Stats, err error) {
+	defer a.recoverBinding("GetConversationStats", &err)
+
+	if a.convManager == nil {
+		return conversation.Stats{}, nil
+	}
+	return a.convManager.GetStats(id)
+}
+
+// HandleDroppedFiles attaches dropped files and folders to the active
+// conversation, starting one if needed, and emits their metadata in a
+// files:dropped event. They are described to the agent with the next message.
+func (a *App) HandleDroppedFiles(paths []string) (_ []conversation.Attachment, err error) {
+	defer a.recoverBinding("HandleDroppedFiles", &err)
+
+	if a.convManager == nil || len(paths) == 0 {
+		return nil, nil
+	}
+	if a.convManager.GetActive() == nil {
+		a.convManager.New()
+	}
+
+	attached, err := a.convManager.Attach(paths)
+	if err != nil {
+		slog.Warn("some dropped files could not be attached", "error", err)
+	}
+	if len(attached) > 0 {
+		a.emit("files:dropped", attached)
+	}
+	return attached, err
+}
+
+// GetAttachments returns the files and folders attached to a conversation.
+func (a *App) GetAttachments(id string) (_ []conversation.Attachment, err error) {
+	defer a.recoverBinding("GetAttachments", &err)
+
+	if a.convManager == nil {
+		return nil, nil
+	}
+	return a.convManager.GetAttachments(id)
+}
+
+// RemoveAttachment removes an attached file or folder from a conversation.
+func (a *App) RemoveAttachment(id, path string) (err error) {
+	defer a.recoverBinding("RemoveAttachment", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.Detach(id, path)
+}
+
+// GetChangedFiles returns the files the agent changed in a conversation
+// with its file tools.
+func (a *App) GetChangedFiles(id string) (_ []snapshot.Entry, err error) {
+	defer a.recoverBinding("GetChangedFiles", &err)
+
+	if a.snapshots == nil {
+		return nil, nil
+	}
+	return a.snapshots.List(id)
+}
+
+// GetFileDiff returns the contents of a file before the agent first changed
+// it in a conversation, its current contents, and a unified diff between
+// them. Files over 1 MB return only the diff, which is cut at 1 MB.
+func (a *App) GetFileDiff(id, path string) (_ *snapshot.FileDiff, err error) {
+	defer a.recoverBinding("GetFileDiff", &err)
+
+	if a.snapshots == nil {
+		return nil, snapshot.ErrNotFound
+	}
+	return a.snapshots.Diff(id, filepath.Clean(path))
+}
+
+// RevealPath opens the system file manager at path, selecting it if it is
+// a file.
+func (a *App) RevealPath(path string) (err error) {
+	defer a.recoverBinding("RevealPath", &err)
+
+	return reveal.Path(tools.ExpandPath(path, tools.GetSession().GetCWD()))
+}
+
+// OpenConversationFolder opens the system file manager in the folder a
+// conversation was working in.
+func (a *App) OpenConversationFolder(id string) (err error) {
+	defer a.recoverBinding("OpenConversationFolder", &err)
+
+	dir, err := a.conversationFolder(id)
+	if err != nil {
+		return err
+	}
+	return reveal.Path(dir)
+}
+
+// conversationFolder returns the folder a conversation was last working in,
+// or its configured working folder.
+func (a *App) conversationFolder(id string) (string, error) {
+	if a.convManager == nil {
+		return "", errors.New("conversation manager not initialized")
+	}
+
+	var candidates []string
+	if active := a.convManager.GetActive(); active != nil && active.ID == id {
+		candidates = append(candidates, tools.GetSession().GetCWD())
+	}
+	conv, err := a.convManager.GetStore().Load(id)
+	if err != nil {
+		return "", err
+	}
+	candidates = append(candidates, conv.SessionCWD, conv.Settings.WorkingDir)
+
+	for _, dir := range candidates {
+		if info, err := os.Stat(dir); dir != "" && err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+	return "", errors.New("the conversation's working folder no longer exists")
+}
+
+// snapshotFile keeps the original contents of a file the agent is about to
+// change, for the conversation of the run.
+func (a *App) snapshotFile(path string) {
+	a.recordRunFile(path)
+	if a.snapshots == nil {
+		return
+	}
+	run, _ := a.runningAgent()
+	if run == nil {
+		return
+	}
+	if err := a.snapshots.Record(run.ID(), path); err != nil {
+		slog.Warn("failed to snapshot file", "path", path, "error", err)
+	}
+}
+
+// GetActiveConversation returns the currently active conversation.
+func (a *App) GetActiveConversation() *conversation.Conversation {
+	defer a.recoverBinding("GetActiveConversation", nil)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.convManager.GetActive()
+}
+
+// SendMessage sends a message to the active conversation and runs the agent.
+// This is the main method for multi-turn chat.
+func (a *App) SendMessage(message string, taskContext string) {
+	defer a.recoverBinding("SendMessage", nil)
+
+	if !a.canRunConversation() {
+		return
+	}
+
+	// Ensure we have an active conversation
+	active := a.convManager.GetActive()
+	if active == nil {
+		active = a.convManager.New()
+	}
+
+	run, ctx, err := a.startAgent(context.Background(), active.ID)
+	if err != nil {
+		a.emit("agent:error", err.Error())
+		return
+	}
+	a.goAgentRun(func() {
+		defer a.endAgent()
+
+		// Build message content with optional context
+		content := message
+		if taskContext != "" {
+			content = message + "\n\nContext: " + taskContext
+		}
+		if attached := conversation.DescribeAttachments(run.TakePendingAttachments()); attached != "" {
+			content += "\n\n" + attached
+		}
+
+		// Pick a provider profile for a new conversation
+		a.selectProfile(run, content)
+
+		// Add user message to conversation
+		if err := run.AddUserMessage(content); err != nil {
+			a.emit("agent:error", "Failed to add message: "+err.Error())
+			return
+		}
+
+		a.runConversation(ctx, run)
+	})
+}
+
+// EditAndRegenerate replaces the user message at messageIndex in the active
+// conversation, discards everything after it, and runs the agent again from
+// the edited message.
+func (a *App) EditAndRegenerate(messageIndex int, content string) (err error) {
+	defer a.recoverBinding("EditAndRegenerate", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.rerunActive(func() error {
+		return a.convManager.EditUserMessage(messageIndex, content)
+	})
+}
+
+// RegenerateFrom discards every message after messageIndex in the active
+// conversation and runs the agent again from that point.
+func (a *App) RegenerateFrom(messageIndex int) (err error) {
+	defer a.recoverBinding("RegenerateFrom", &err)
+
+	if a.convManager == nil {
+		return nil
+	}
+	return a.rerunActive(func() error {
+		return a.convManager.TruncateAfter(messageIndex)
+	})
+}
+
+// rerunActive makes change to the active conversation and runs the agent
+// again from its end. The change is only made if the agent is free, so it
+// never rewrites a conversation a run is writing to.
+func (a *App) rerunActive(change func() error) error {
+	active := a.convManager.GetActive()
+	if active == nil {
+		return change() // Fails without an active conversation
+	}
+	run, ctx, err := a.startAgent(context.Background(), active.ID)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		a.endAgent()
+		return err
+	}
+	if !a.canRunConversation() {
+		a.endAgent()
+		return nil
+	}
+
+	a.goAgentRun(func() {
+		defer a.endAgent()
+		a.runConversation(ctx, run)
+	})
+	return nil
+}
+
+// CompactConversation summarizes the older turns of the active conversation
+// so the LLM sees a compact form while the full history is preserved.
+// Returns false if there was nothing to compact.
+func (a *App) CompactConversation() (compacted bool, err error) {
+	defer a.recoverBinding("CompactConversation", &err)
+
+	if a.convManager == nil {
+		return false, nil
+	}
+	return a.convManager.Compact(context.Background())
+}
+
+// canRunConversation checks that a conversation run can start. It emits an
+// error event and returns false if the app isn't ready.
+func (a *App) canRunConversation() bool {
+	if a.client == nil {
+		a.emit("agent:error", "LLM not configured")
+		return false
+	}
+
+	if a.convManager == nil {
+		a.emit("agent:error", "Conversation manager not initialized")
+		return false
+	}
+	return true
+}
+
+// errAgentRunning is returned when a run is asked for while another has the
+// agent.
+var errAgentRunning = errors.New("the agent is already running; stop it or wait for it to finish")
+
+// startAgent gives the agent to a new run in the conversation with the
+// given ID, or to a RunAgentTask run if convID is empty, and returns the
+// run and its context, derived from parent. It returns errAgentRunning
+// rather than stop a run that has the agent. endAgent ends the run.
+func (a *App) startAgent(parent context.Context, convID string) (*conversation.RunHandle, context.Context, error) {
+	a.agentMu.Lock()
+	defer a.agentMu.Unlock()
+	if a.agentDone != nil {
+		return nil, nil, errAgentRunning
+	}
+
+	var run *conversation.RunHandle
+	if convID != "" {
+		var err error
+		if run, err = a.convManager.BeginRun(convID); err != nil {
+			return nil, nil, err
+		}
+	}
+	a.agentCtx, a.agentCancel = context.WithCancel(parent)
+	a.agentRun = run
+	a.agentDone = make(chan struct{})
+	return run, a.agentCtx, nil
+}
+
+// waitAgent is startAgent for queued tasks, which wait for the run that has
+// the agent to end, or for ctx to be done.
+func (a *App) waitAgent(ctx context.Context, convID string) (*conversation.RunHandle, context.Context, error) {
+	for {
+		run, runCtx, err := a.startAgent(ctx, convID)
+		if !errors.Is(err, errAgentRunning) {
+			return run, runCtx, err
+		}
+
+		a.agentMu.Lock()
+		done := a.agentDone
+		a.agentMu.Unlock()
+		if done == nil {
+			continue
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// endAgent ends the run started by startAgent and frees the agent.
+func (a *App) endAgent() {
+	a.agentMu.Lock()
+	defer a.agentMu.Unlock()
+	if a.agentDone == nil {
+		return
+	}
+	a.agentCancel()
+	if a.agentRun != nil {
+		a.agentRun.End()
+	}
+	close(a.agentDone)
+	a.agentCtx, a.agentCancel, a.agentRun, a.agentDone = nil, nil, nil, nil
+}
+
+// runningAgent returns the run that has the agent and its context. The run
+// is nil for RunAgentTask or when the agent isn't running, and the context
+// is nil when it isn't running.
+func (a *App) runningAgent() (*conversation.RunHandle, context.Context) {
+	a.agentMu.Lock()
+	defer a.agentMu.Unlock()
+	return a.agentRun, a.agentCtx
+}
+
+// runConversation continues the conversation of run with the agent, syncing
+// new messages back into the conversation and emitting events to the frontend.
+// It returns the error that ended the run, if any.
+func (a *App) runConversation(ctx context.Context, run *conversation.RunHandle) error {
+	// Summarize older turns if the conversation is outgrowing the context window
+	if compacted, err := run.CompactIfNeeded(ctx); err == nil && compacted {
+		a.emit("conversation:compacted", run.ID())
+	}
+
+	// Get messages for the agent, with compacted turns replaced by their summary
+	messages := run.LLMMessages()
+	synced := len(messages)
+
+	limits := a.GetLimits()
+	if limits.MaxCostPerDay > 0 && a.addSpend(0) >= limits.MaxCostPerDay {
+		msg := i18n.T("limit.daily_spend", limits.MaxCostPerDay)
+		a.notify(config.NotifyBudget, i18n.T("notify.budget_reached"), msg)
+		a.emit("agent:error", msg)
+		return errors.New(msg)
+	}
+	if a.GetBudgetWarning() != nil {
+		msg := i18n.T("limit.paused_for_alert")
+		a.emit("agent:error", msg)
+		return errors.New(msg)
+	}
+
+	// Apply per-conversation overrides, within the configured step limit
+	client := a.client
+	maxSteps := a.maxSteps()
+	if conv, err := run.Get(); err == nil {
+		client = a.clientForProfile(conv.Settings.Profile).WithModel(conv.Settings.Model)
+		if conv.Settings.MaxSteps > 0 && (limits.MaxSteps == 0 || conv.Settings.MaxSteps < limits.MaxSteps) {
+			maxSteps = conv.Settings.MaxSteps
+		}
+	}
+
+	// Stop the run when a token or spending limit is exceeded
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	runTokens := 0
+	runCost := 0.0
+	limitErr := ""
+	a.takeRunFiles()
+
+	// Track wall time for conversation stats, how the run ended for usage
+	// metrics, and the run's steps for replay
+	start := time.Now()
+	outcome := metrics.RunCancelled
+	a.tray.SetStatus(tray.StatusRunning)
+	run.Start()
+	a.telemetry.Count("run")
+	a.telemetry.Count("provider." + telemetry.ProviderKind(client.GetEndpoint()))
+	defer func() {
+		if err := run.Finish(a.takeRunFiles(), time.Since(start)); err != nil {
+			slog.Warn("failed to save the run", "conversation", run.ID(), "error", err)
+		}
+		if err := a.usage.RecordRun(client.GetModel(), outcome, time.Since(start)); err != nil {
+			slog.Warn("failed to save usage metrics", "error", err)
+		}
+		a.tray.SetStatus(tray.StatusIdle)
+		a.refreshTray()
+		go a.sendTelemetry()
+	}()
+
+	// The task this run works on, for the summary of recent work
+	task := ""
+	if last := len(messages) - 1; last >= 0 && messages[last].Role == "user" {
+		task = messages[last].Content
+	}
+
+	// Give a new task that mentions the indexed folder the passages most
+	// related to it
+	if msg, sources, root := a.retrieveContext(ctx, messages); len(sources) > 0 {
+		last := len(messages) - 1
+		messages = append(messages[:last:last], msg, messages[last])
+		synced = len(messages)
+		step := agent.NewContextStep(0, root, sources)
+		a.emit("agent:step", step)
+		run.RecordStep(step)
+	}
+
+	// Run conversation continuation
+	for step := range agent.ContinueConversation(ctx, client, messages, maxSteps) {
+		// Price usage steps so the window can show what each turn cost
+		if step.Type == agent.StepTypeUsage {
+			runTokens, runCost = priceUsage(step.Usage, client.GetModel(), runTokens, runCost)
+		}
+
+		// Emit step to frontend and keep it for replay
+		a.emit("agent:step", step)
+		run.RecordStep(step)
+
+		// Accumulate conversation stats
+		switch step.Type {
+		case agent.StepTypeUsage:
+			cost := step.Usage.Cost
+			run.RecordUsage(step.Usage.PromptTokens, step.Usage.CompletionTokens, cost)
+			a.usage.RecordUsage(client.GetModel(), step.Usage.PromptTokens, step.Usage.CompletionTokens, cost)
+			a.checkSpendAlerts()
+
+			spent := a.addSpend(cost)
+			if limits.MaxTokensPerRun > 0 && runTokens > limits.MaxTokensPerRun && limitErr == "" {
+				limitErr = i18n.T("limit.tokens_per_run", limits.MaxTokensPerRun)
+				cancel()
+			} else if limits.MaxCostPerDay > 0 && spent >= limits.MaxCostPerDay && limitErr == "" {
+				limitErr = i18n.T("limit.daily_spend", limits.MaxCostPerDay)
+				cancel()
+			} else if warnAt := limits.MaxCostPerDay * budgetWarningFraction; limits.MaxCostPerDay > 0 && spent-cost < warnAt && spent >= warnAt {
+				a.notify(config.NotifyBudget, i18n.T("notify.budget_warning"),
+					i18n.T("notify.budget_warning_body", spent, limits.MaxCostPerDay))
+			}
+		case agent.StepTypeToolResult:
+			failed := step.ToolResult != nil && !step.ToolResult.Success
+			run.RecordToolCall(failed)
+			a.usage.RecordToolCall(client.GetModel(), step.ToolName, failed, time.Duration(step.DurationMs)*time.Millisecond)
+			if tools.IsBuiltinTool(step.ToolName) {
+				a.telemetry.Count("tool." + step.ToolName)
+			} else {
+				a.telemetry.Count("tool.external")
+			}
+		}
+
+		// Update conversation with new messages if present
+		if step.Messages != nil {
+			// Find and add new messages since last sync
+			for ; synced < len(step.Messages); synced++ {
+				msg := step.Messages[synced]
+				if msg.Role == "assistant" {
+					run.AddAssistantMessage(withModelMeta(msg, client.GetModel()))
+				} else if msg.Role == "tool" {
+					run.AddToolMessage(msg)
+				}
+			}
+		}
+
+		// Handle completion states
+		if step.Type == agent.StepTypeComplete {
+			// Generate title if this is the first completion
+			a.queueMaintenance(run, task, step.Content)
+			a.notify(config.NotifyComplete, i18n.T("notify.task_complete"), step.Content)
+			outcome = metrics.RunSucceeded
+			a.reportRun(run, config.WebhookComplete, step.Content, runCost, runTokens, start)
+			a.emit("agent:complete", step.Content)
+			return nil
+		}
+		if step.Type == agent.StepTypeAssistantMessage {
+			// Conversational response - also triggers title generation
+			a.queueMaintenance(run, task, step.Content)
+			a.notify(config.NotifyComplete, i18n.T("notify.agent_replied"), step.Content)
+			outcome = metrics.RunSucceeded
+			a.reportRun(run, config.WebhookComplete, step.Content, runCost, runTokens, start)
+			a.emit("agent:message", step.Content)
+			return nil
+		}
+		if step.Type == agent.StepTypeError {
+			if limitErr != "" {
+				outcome = metrics.RunFailed
+				step.Content = limitErr
+				a.notify(config.NotifyBudget, i18n.T("notify.limit_stopped"), limitErr)
+				a.reportRun(run, config.WebhookError, limitErr, runCost, runTokens, start)
+			} else if ctx.Err() == nil {
+				outcome = metrics.RunFailed
+				a.notify(config.NotifyError, i18n.T("notify.agent_error"), step.Content)
+				a.reportRun(run, config.WebhookError, step.Content, runCost, runTokens, start)
+			}
+			a.emit("agent:error", step.Content)
+			return errors.New(step.Content)
+		}
+	}
+	return ctx.Err()
+}
+
+// selectProfile records the provider profile chosen by the config's profile
+// rules on the conversation of run, if it has no messages yet and no
+// profile was set explicitly.
+func (a *App) selectProfile(run *conversation.RunHandle, firstMessage string) {
+	conv, err := run.Get()
+	if err != nil || a.config == nil || conv.Settings.Profile != "" {
+		return
+	}
+	for _, msg := range conv.Messages {
+		if msg.Role == "user" {
+			return
+		}
+	}
+
+	name := a.config.MatchProfile(conv.Settings.SystemPrompt, firstMessage)
+	if name == "" {
+		return
+	}
+	settings := conv.Settings
+	settings.Profile = name
+	if err := a.convManager.UpdateSettings(conv.ID, settings); err != nil {
+		slog.Warn("failed to record conversation profile", "profile", name, "error", err)
+		return
+	}
+	slog.Info("selected profile for conversation", "conversation", conv.ID, "profile", name)
+}
+
+// clientForProfile returns an LLM client for the named profile, falling back
+// to the default client if the profile is unset or unusable.
+func (a *App) clientForProfile(name string) *llm.Client {
+	if name == "" || a.config == nil {
+		return a.client
+	}
+	cfg, err := a.config.ForProfile(name)
+	if err != nil {
+		slog.Warn("ignoring conversation profile", "profile", name, "error", err)
+		return a.client
+	}
+	client, err := llm.NewClient(cfg)
+	if err != nil {
+		slog.Warn("ignoring conversation profile", "profile", name, "error", err)
+		return a.client
+	}
+	return client.WithProfile(name)
+}
+
+// warmInterval is how long after a prompt is warmed up that opening a
+// conversation with the same prompt doesn't warm it again.
+const warmInterval = time.Minute
+
+// warmTimeout bounds a warm-up request.
+const warmTimeout = 30 * time.Second
+
+// warmStart warms up the endpoint for the active conversation in the
+// background, if warm_start is on: see llm.Client.Warm. Its cost counts
+// towards the daily spend.
+func (a *App) warmStart() {
+	if a.client == nil || a.config == nil || !a.config.WarmStart || a.convManager == nil {
+		return
+	}
+	active := a.convManager.GetActive()
+	if active == nil {
+		return
+	}
+	client := a.clientForProfile(active.Settings.Profile).WithModel(active.Settings.Model)
+	var prefix []llm.Message
+	for _, msg := range a.convManager.GetLLMMessages() {
+		if msg.Role != "system" {
+			break
+		}
+		prefix = append(prefix, msg)
+	}
+	if !a.claimWarm(warmKey(client, prefix)) {
+		return
+	}
+
+	go func() {
+		defer crash.Recover("warm start", nil)
+		ctx, cancel := context.WithTimeout(context.Background(), warmTimeout)
+		defer cancel()
+		usage, err := client.Warm(ctx, prefix, tools.GetToolDefinitions())
+		if err != nil {
+			slog.Debug("failed to warm up endpoint", "endpoint", client.GetEndpoint(), "error", err)
+			return
+		}
+		if usage != nil {
+			a.addSpend(llm.EstimateCost(client.GetModel(), usage.PromptTokens, usage.CompletionTokens))
+		}
+	}()
+}
+
+// claimWarm reports whether the prompt with the given key is due to be
+// warmed up, recording that it is being warmed if so.
+func (a *App) claimWarm(key string) bool {
+	a.warmMu.Lock()
+	defer a.warmMu.Unlock()
+	now := time.Now()
+	if last, ok := a.warmed[key]; ok && now.Sub(last) < warmInterval {
+		return false
+	}
+	if a.warmed == nil {
+		a.warmed = make(map[string]time.Time)
+	}
+	for k, last := range a.warmed {
+		if now.Sub(last) >= warmInterval {
+			delete(a.warmed, k)
+		}
+	}
+	a.warmed[key] = now
+	return true
+}
+
+// warmKey identifies what a warm-up request warms: the endpoint, the model,
+// and the prompt it starts with.
+func warmKey(client *llm.Client, prefix []llm.Message) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s", client.GetEndpoint(), client.GetModel())
+	for _, msg := range prefix {
+		fmt.Fprintf(h, "\x00%s", msg.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// budgetWarningFraction is the share of the daily spending limit at which a
+// budget warning notification is shown.
+const budgetWarningFraction = 0.8
+
+// notify shows a desktop notification for an agent lifecycle event unless
+// the event is turned off in the notification settings. Approval requests
+// use config.NotifyApproval.
+func (a *App) notify(event, title, body string) {
+	if a.config == nil || !a.config.Notifications.Enabled(event) {
+		return
+	}
+	go func() {
+		if err := notify.Send(notify.Notification{Title: title, Body: body}); err != nil {
+			slog.Debug("failed to show notification", "event", event, "error", err)
+		}
+	}()
+}
+
+// reportRun reports the end of run to the configured webhooks and, if its
+// conversation was started from chat, to its thread.
+func (a *App) reportRun(run *conversation.RunHandle, event, summary string, cost float64, tokens int, start time.Time) {
+	files := a.runFilesSoFar()
+	conv, _ := run.Get()
+	a.sendWebhooks(conv, event, summary, files, cost, tokens, start)
+	convID := run.ID()
+
+	a.bridgeMu.Lock()
+	b := a.bridge
+	a.bridgeMu.Unlock()
+	if b != nil {
+		b.RunFinished(convID, event == config.WebhookError, summary)
+	}
+	a.recordInboxOutcome(convID, summary, files)
+}
+
+// sendWebhooks reports the end of a run on conv, which changed files, to
+// the configured webhooks. conv is nil if the run's conversation is gone.
+// Deliveries happen in the background.
+func (a *App) sendWebhooks(conv *conversation.Conversation, event, summary string, files []string, cost float64, tokens int, start time.Time) {
+	if a.config == nil || len(a.config.Webhooks) == 0 {
+		return
+	}
+
+	p := webhook.Payload{
+		Event:         event,
+		Summary:       summary,
+		FilesModified: files,
+		Cost:          cost,
+		Tokens:        tokens,
+		DurationMS:    time.Since(start).Milliseconds(),
+	}
+	if conv != nil {
+		p.ConversationID, p.Title = conv.ID, conv.Title
+	}
+	webhook.Send(a.config.Webhooks, p, func(hook config.Webhook, err error) {
+		// The URL itself may be a secret, as with Slack webhooks
+		host := hook.URL
+		if u, perr := url.Parse(hook.URL); perr == nil {
+			host = u.Host
+		}
+		slog.Warn("webhook delivery failed", "host", host, "event", event, "error", err)
+	})
+}
+
+// recordRunFile adds path to the files changed by the current run.
+func (a *App) recordRunFile(path string) {
+	a.runFilesMu.Lock()
+	defer a.runFilesMu.Unlock()
+	if !slices.Contains(a.runFiles, path) {
+		a.runFiles = append(a.runFiles, path)
+	}
+}
+
+// runFilesSoFar returns the files changed by the current run so far.
+func (a *App) runFilesSoFar() []string {
+	a.runFilesMu.Lock()
+	defer a.runFilesMu.Unlock()
+	return slices.Clone(a.runFiles)
+}
+
+// takeRunFiles returns the files changed by the current run and starts a
+// new list.
+func (a *App) takeRunFiles() []string {
+	a.runFilesMu.Lock()
+	defer a.runFilesMu.Unlock()
+	files := a.runFiles
+	a.runFiles = nil
+	return files
+}
+
+// SendTestNotification shows a sample notification so users can check that
+// notifications appear.
+func (a *App) SendTestNotification() (err error) {
+	defer a.recoverBinding("SendTestNotification", &err)
+
+	return notify.Send(notify.Notification{Title: notify.AppName, Body: i18n.T("notify.test_body")})
+}
+
+// addSpend adds cost to today's estimated spend and returns the total.
+// The total starts over each calendar day.
+func (a *App) addSpend(cost float64) float64 {
+	a.spendMu.Lock()
+	defer a.spendMu.Unlock()
+	today := time.Now().Format("2006-01-02")
+	if a.spendDay != today {
+		a.spendDay, a.spentToday = today, 0
+	}
+	a.spentToday += cost
+	return a.spentToday
+}
+
+// priceUsage fills in the estimated cost of a usage step's model call and
+// the run's totals with it added, given the totals before it, and returns
+// the new totals.
+func priceUsage(usage *agent.TokenUsage, model string, runTokens int, runCost float64) (int, float64) {
+	usage.Cost = llm.EstimateCost(model, usage.PromptTokens, usage.CompletionTokens)
+	usage.RunTokens = runTokens + usage.PromptTokens + usage.CompletionTokens
+	usage.RunCost = runCost + usage.Cost
+	return usage.RunTokens, usage.RunCost
+}
+
+// withModelMeta returns msg with the model that produced it and the estimated
+// cost of its LLM call recorded in its metadata.
+func withModelMeta(msg llm.Message, model string) llm.Message {
+	meta := llm.MessageMeta{}
+	if msg.Meta != nil {
+		meta = *msg.Meta
+	}
+	meta.Model = model
+	if meta.Usage != nil {
+		meta.Cost = llm.EstimateCost(model, meta.Usage.PromptTokens, meta.Usage.CompletionTokens)
+	}
+	msg.Meta = &meta
+	return msg
+}
+
+// maxSteps returns the configured step limit, or derives the agent step
+// budget from the execution timeout.
+func (a *App) maxSteps() int {
+	if limits := a.GetLimits(); limits.MaxSteps > 0 {
+		return limits.MaxSteps
+	}
+
+	maxSteps := 20
+	if a.config != nil && a.config.ExecutionTimeout > 0 {
+		// Use execution timeout as rough guide for max steps
+		maxSteps = a.config.ExecutionTimeout / 3
+		if maxSteps < 10 {
+			maxSteps = 10
+		}
+		if maxSteps > 50 {
+			maxSteps = 50
+		}
+	}
+	return maxSteps
+}
+
+// ============================================================================
+// Agent Methods (Legacy - kept for backward compatibility)
+// ============================================================================
+
+// RunAgentTask starts the agent to complete a task
+// It emits events to the frontend as the agent progresses
+func (a *App) RunAgentTask(task string, taskContext string) {
+	defer a.recoverBinding("RunAgentTask", nil)
+
+	if a.client == nil {
+		a.emit("agent:error", "LLM not configured")
+		return
+	}
+
+	_, ctx, err := a.startAgent(context.Background(), "")
+	if err != nil {
+		a.emit("agent:error", err.Error())
+		return
+	}
+
+	a.goAgentRun(func() {
+		defer a.endAgent()
+		a.tray.SetStatus(tray.StatusRunning)
+		defer a.tray.SetStatus(tray.StatusIdle)
+
+		// Reset session for fresh start
+		tools.ResetSession()
+
+		runTokens, runCost := 0, 0.0
+		for step := range agent.RunLoop(ctx, a.client, task, taskContext, a.maxSteps()) {
+			if step.Type == agent.StepTypeUsage {
+				runTokens, runCost = priceUsage(step.Usage, a.client.GetModel(), runTokens, runCost)
+			}
+
+			// Emit step to frontend
+			a.emit("agent:step", step)
+
+			// Check if complete or error
+			if step.Type == agent.StepTypeComplete {
+				a.notify(config.NotifyComplete, i18n.T("notify.task_complete"), step.Content)
+				a.emit("agent:complete", step.Content)
+				return
+			}
+			if step.Type == agent.StepTypeError {
+				if ctx.Err() == nil {
+					a.notify(config.NotifyError, i18n.T("notify.agent_error"), step.Content)
+				}
+				a.emit("agent:error", step.Content)
+				return
+			}
+		}
+	})
+}
+
+// StopAgent stops the currently running agent
+func (a *App) StopAgent() {
+	defer a.recoverBinding("StopAgent", nil)
+
+	a.agentMu.Lock()
+	defer a.agentMu.Unlock()
+	if a.agentCancel != nil {
+		a.agentCancel()
+	}
+}
+
+// ============================================================================
+// Run Queue Methods
+// ============================================================================
+
+// queueConcurrency is how many queued tasks run at once. Runs share the
+// agent and the shell session, so queued tasks run one at a time, each
+// waiting for the agent to be free.
+const queueConcurrency = 1
+
+// EnqueueTask adds a task to the run queue. conversationID continues an
+// existing conversation; empty starts a new conversation for the task.
+// The task waits for the agent rather than stop a run, and doesn't open
+// its conversation in the window. Queue changes are emitted in
+// queue:changed events.
+func (a *App) EnqueueTask(task, conversationID string) (_ runqueue.Item, err error) {
+	defer a.recoverBinding("EnqueueTask", &err)
+
+	if a.queue == nil {
+		return runqueue.Item{}, errors.New("run queue not started")
+	}
+	if strings.TrimSpace(task) == "" {
+		return runqueue.Item{}, errors.New("task is empty")
+	}
+	return a.queue.Enqueue(task, conversationID), nil
+}
+
+// GetQueue returns the queued, running, and finished tasks in order.
+func (a *App) GetQueue() []runqueue.Item {
+	defer a.recoverBinding("GetQueue", nil)
+
+	if a.queue == nil {
+		return nil
+	}
+	return a.queue.Items()
+}
+
+// MoveQueueItem moves a waiting task to position index among the waiting
+// tasks.
+func (a *App) MoveQueueItem(id string, index int) (err error) {
+	defer a.recoverBinding("MoveQueueItem", &err)
+
+	if a.queue == nil {
+		return nil
+	}
+	return a.queue.Move(id, index)
+}
+
+// CancelQueueItem removes a waiting task from the queue or stops a running
+// one.
+func (a *App) CancelQueueItem(id string) (err error) {
+	defer a.recoverBinding("CancelQueueItem", &err)
+
+	if a.queue == nil {
+		return nil
+	}
+	return a.queue.Cancel(id)
+}
+
+// ClearFinishedQueueItems removes finished tasks from the queue.
+func (a *App) ClearFinishedQueueItems() {
+	defer a.recoverBinding("ClearFinishedQueueItems", nil)
+
+	if a.queue == nil {
+		return
+	}
+	a.queue.ClearFinished()
+}
+
+// SetQueuePaused stops or resumes starting queued tasks. A running task is
+// not affected.
+func (a *App) SetQueuePaused(paused bool) {
+	defer a.recoverBinding("SetQueuePaused", nil)
+
+	if a.queue == nil {
+		return
+	}
+	a.queue.SetPaused(paused)
+}
+
+// runQueued runs a queued task as a new message in its conversation, which
+// the window may or may not show. It waits for a run that has the agent to
+// end rather than stop it, and StopAgent stops the task like any run.
+func (a *App) runQueued(ctx context.Context, item runqueue.Item) (err error) {
+	defer crash.Recover("queued task", func(r *crash.Report) {
+		a.tray.SetStatus(tray.StatusIdle)
+		err = errors.New(r.Message())
+	})
+
+	if a.client == nil {
+		return errors.New("LLM not configured")
+	}
+	if a.convManager == nil {
+		return errors.New("conversation manager not initialized")
+	}
+
+	convID := item.ConversationID
+	if convID == "" {
+		conv, err := a.convManager.Create()
+		if err != nil {
+			return err
+		}
+		convID = conv.ID
+		a.queue.SetConversation(item.ID, convID)
+	}
+
+	run, runCtx, err := a.waitAgent(ctx, convID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return context.Canceled
+		}
+		return err
+	}
+	defer a.endAgent()
+
+	a.selectProfile(run, item.Task)
+	if err := run.AddUserMessage(item.Task); err != nil {
+		return err
+	}
+	err = a.runConversation(runCtx, run)
+	if runCtx.Err() != nil {
+		return context.Canceled
+	}
+	return err
+}
+
+// queueChanged emits the queue to the frontend and records the results of
+// finished inbox tasks.
+func (a *App) queueChanged(items []runqueue.Item) {
+	a.finishInboxRuns(items)
+	a.emit("queue:changed", items)
+}
+
+// ============================================================================
+// Crash Report Methods
+// ============================================================================
+
+// maxCrashReports is how many recent crash reports are kept for the user to
+// save.
+const maxCrashReports = 20
+
+// goAgentRun starts an agent run in a goroutine. A panic ends the run with
+// an agent:error event instead of exiting the app.
+func (a *App) goAgentRun(run func()) {
+	go func() {
+		defer crash.Recover("agent run", func(r *crash.Report) {
+			a.tray.SetStatus(tray.StatusIdle)
+			a.emit("agent:error", r.Message())
+		})
+		run()
+	}()
+}
+
+// recoverBinding reports a panic in a binding as a crash and returns it to
+// the frontend as *err, or as the binding's zero values if err is nil.
+// Wails recovers binding panics itself but logs only the panic value and
+// never answers the call. Every exported method is a binding, so each one
+// starts with it. It must be called directly in a defer statement.
+func (a *App) recoverBinding(name string, err *error) {
+	if v := recover(); v != nil {
+		r := crash.Capture(name, v)
+		if err != nil {
+			*err = errors.New(r.Message())
+		}
+	}
+}
+
+// handleCrash keeps a crash report for the user and emits an app:crash
+// event so the frontend can offer to save it.
+func (a *App) handleCrash(r *crash.Report) {
+	r.AppVersion = version
+
+	a.crashMu.Lock()
+	a.crashes = append(a.crashes, r)
+	if len(a.crashes) > maxCrashReports {
+		a.crashes = a.crashes[len(a.crashes)-maxCrashReports:]
+	}
+	a.crashMu.Unlock()
+
+	a.telemetry.RecordCrash(r)
+	a.emit("app:crash", r)
+}
+
+// GetCrashReports returns the crash reports recorded since the app started,
+// oldest first.
+func (a *App) GetCrashReports() []crash.Report {
+	defer a.recoverBinding("GetCrashReports", nil)
+
+	a.crashMu.Lock()
+	defer a.crashMu.Unlock()
+
+	reports := make([]crash.Report, len(a.crashes))
+	for i, r := range a.crashes {
+		reports[i] = *r
+	}
+	return reports
+}
+
+// SaveCrashReport writes the crash report with the given ID to path so it
+// can be attached to a bug report.
+func (a *App) SaveCrashReport(id, path string) (err error) {
+	defer a.recoverBinding("SaveCrashReport", &err)
+
+	a.crashMu.Lock()
+	defer a.crashMu.Unlock()
+
+	for _, r := range a.crashes {
+		if r.ID == id {
+			return r.Save(path)
+		}
+	}
+	return fmt.Errorf("crash report %s not found", id)
+}
+
+// ExportDebugBundle writes a zip to path for a bug report about a
+// conversation: the conversation and its step log, the config with keys
+// redacted, the OS, the recent logs, and any captured requests to the
+// provider, with secrets scrubbed from all of them.
+func (a *App) ExportDebugBundle(convID, path string) (err error) {
+	defer a.recoverBinding("ExportDebugBundle", &err)
+
+	if a.convManager == nil {
+		return errors.New("conversation manager not initialized")
+	}
+	conv, err := a.convManager.Get(convID)
+	if err != nil {
+		return err
+	}
+	return bugreport.Write(path, bugreport.Contents{
+		Conversation: conv,
+		Config:       a.config,
+		Logs:         logging.Recent(0),
+		Trace:        llm.Trace(),
+		AppVersion:   version,
+	})
+}
+
+// ============================================================================
+// MCP Methods
+// ============================================================================
+
+// GetMCPServers returns the connection status of each configured MCP
+// server and the tools it provides. Changes are emitted in mcp:changed
+// events.
+func (a *App) GetMCPServers() []mcp.ServerStatus {
+	defer a.recoverBinding("GetMCPServers", nil)
+
+	if a.mcp == nil {
+		return nil
+	}
+	return a.mcp.Status()
+}
+
+// ReconnectMCPServer restarts the connection to an MCP server, e.g. after
+// it crashed or its tools changed.
+func (a *App) ReconnectMCPServer(name string) (err error) {
+	defer a.recoverBinding("ReconnectMCPServer", &err)
+
+	if a.mcp == nil {
+		return errors.New("MCP servers not started")
+	}
+	return a.mcp.Reconnect(name)
+}
+
+// ============================================================================
+// Plugin Methods
+// ============================================================================
+
+// PluginStatus lists the tool plugins in the tools folder and the
+// manifests that couldn't be loaded.
+type PluginStatus struct {
+	Dir     string           `json:"dir"`
+	Plugins []plugins.Plugin `json:"plugins"`
+	Errors  []string         `json:"errors"`
+}
+
+// GetPlugins returns the loaded tool plugins and load errors. Changes are
+// emitted in plugins:changed events.
+func (a *App) GetPlugins() PluginStatus {
+	defer a.recoverBinding("GetPlugins", nil)
+
+	a.pluginsMu.Lock()
+	defer a.pluginsMu.Unlock()
+	status := a.plugins
+	status.Dir = config.ToolsDir()
+	return status
+}
+
+// OpenPluginsFolder opens the tools folder in the file manager, creating it
+// if needed.
+func (a *App) OpenPluginsFolder() (err error) {
+	defer a.recoverBinding("OpenPluginsFolder", &err)
+
+	dir := config.ToolsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return reveal.Path(dir)
+}
+
+// pluginsLoaded records the result of loading the tools folder.
+func (a *App) pluginsLoaded(loaded []plugins.Plugin, errs []error) {
+	status := PluginStatus{Dir: config.ToolsDir(), Plugins: loaded, Errors: []string{}}
+	if status.Plugins == nil {
+		status.Plugins = []plugins.Plugin{}
+	}
+	for _, err := range errs {
+		slog.Warn("failed to load tool plugin", "error", err)
+		status.Errors = append(status.Errors, err.Error())
+	}
+
+	a.pluginsMu.Lock()
+	a.plugins = status
+	a.pluginsMu.Unlock()
+	a.emit("plugins:changed", status)
+}
+
+// ============================================================================
+// Chat Bridge Methods
+// ============================================================================
+
+// bridgeApprovalTimeout is how long a tool call waits for someone to
+// answer its approval request in chat before it is denied.
+const bridgeApprovalTimeout = 30 * time.Minute
+
+// startBridge connects the enabled chat bots, replacing any running ones.
+func (a *App) startBridge(settings config.BridgeSettings) {
+	a.stopBridge()
+
+	a.bridgeMu.Lock()
+	defer a.bridgeMu.Unlock()
+	a.bridgeSettings = settings
+	if !settings.Slack.Enabled() && !settings.Discord.Enabled() {
+		return
+	}
+
+	b := bridge.New(apiBackend{a}, filepath.Join(config.Dir(), "bridge_threads.json"))
+	if s := settings.Slack; s.Enabled() {
+		b.Add(bridge.NewSlack(s.BotToken, s.AppToken), s.Users, s.Channels)
+	}
+	if d := settings.Discord; d.Enabled() {
+		b.Add(bridge.NewDiscord(d.BotToken), d.Users, d.Channels)
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.bridge, a.bridgeCancel = b, cancel
+	go b.Run(ctx)
+}
+
+// stopBridge disconnects the chat bots.
+func (a *App) stopBridge() {
+	a.bridgeMu.Lock()
+	defer a.bridgeMu.Unlock()
+	if a.bridgeCancel != nil {
+		a.bridgeCancel()
+	}
+	a.bridge, a.bridgeCancel = nil, nil
+}
+
+// approveToolCall is the tools approval hook. Tool calls in conversations
+// started from chat follow the conversation's approval policy, or the
+// bridge's, and wait for an answer in the chat thread when it asks. Other
+// conversations ask in the window when their own policy asks, which by
+// default it doesn't, and for tools that always need approval, such as
+// send_email.
+func (a *App) approveToolCall(callID, name string, args map[string]interface{}) (bool, string) {
+	required := tools.RequiresApproval(name)
+
+	a.bridgeMu.Lock()
+	b := a.bridge
+	policy := a.bridgeSettings.ApprovalPolicy
+	a.bridgeMu.Unlock()
+	// The run's conversation, not the one the window shows
+	var active *conversation.Conversation
+	run, ctx := a.runningAgent()
+	if run != nil {
+		active, _ = run.Get()
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if b != nil && active != nil && b.Owns(active.ID) {
+		if active.Settings.ApprovalPolicy != "" {
+			policy = active.Settings.ApprovalPolicy
+		} else if policy == "" {
+			policy = conversation.ApprovalPolicyDestructive
+		}
+		if !required && !conversation.NeedsApproval(policy, tools.IsDestructive(name, args)) {
+			return true, ""
+		}
+		slog.Info("waiting for approval in chat", "conversation", active.ID, "tool", name)
+		return a.awaitApproval(ctx, name, args, func(ctx context.Context, description string) (bool, error) {
+			return b.RequestApproval(ctx, active.ID, description)
+		})
+	}
+
+	policy = ""
+	if active != nil {
+		policy = active.Settings.ApprovalPolicy
+	}
+	if !required && !conversation.NeedsApproval(policy, tools.IsDestructive(name, args)) {
+		return true, ""
+	}
+	if a.headless || a.emitter == nil {
+		return false, name + " needs the user's approval, and there is no window to ask in"
+	}
+	conversationID := ""
+	if active != nil {
+		conversationID = active.ID
+	}
+	slog.Info("waiting for approval in the window", "conversation", conversationID, "tool", name)
+	return a.awaitApproval(ctx, name, args, func(ctx context.Context, description string) (bool, error) {
+		return a.requestWindowApproval(ctx, callID, conversationID, name, description)
+	})
+}
+
+// awaitApproval notifies the user of a tool call and waits for ask to
+// return their answer, denying the call after bridgeApprovalTimeout or when
+// ctx, the run's, is done.
+func (a *App) awaitApproval(ctx context.Context, name string, args map[string]interface{}, ask func(ctx context.Context, description string) (bool, error)) (bool, string) {
+	ctx, cancel := context.WithTimeout(ctx, bridgeApprovalTimeout)
+	defer cancel()
+
+	a.tray.SetStatus(tray.StatusAwaitingApproval)
+	defer a.tray.SetStatus(tray.StatusRunning)
+	description := describeToolCall(name, args)
+	a.notify(config.NotifyApproval, i18n.T("notify.approval_needed"), description)
+
+	approved, err := ask(ctx, description)
+	switch {
+	case err != nil:
+		return false, "Not approved: " + err.Error()
+	case !approved:
+		return false, "The user denied this tool call"
+	}
+	return true, ""
+}
+
+// ToolApproval is a tool call waiting for the user to approve it in the
+// window, sent to the frontend in the "tool:approval" event.
+type ToolApproval struct {
+	ID             string `json:"id"`
+	ConversationID string `json:"conversation_id"`
+	Tool           string `json:"tool"`
+	Description    string `json:"description"`
+}
+
+// requestWindowApproval asks the frontend to approve a tool call and waits
+// for AnswerToolApproval or ctx to end.
+func (a *App) requestWindowApproval(ctx context.Context, callID, conversationID, name, description string) (bool, error) {
+	if callID == "" {
+		callID = uuid.NewString()
+	}
+	answer := make(chan bool, 1)
+	a.approvalsMu.Lock()
+	if a.approvals == nil {
+		a.approvals = map[string]chan bool{}
+	}
+	a.approvals[callID] = answer
+	a.approvalsMu.Unlock()
+	defer func() {
+		a.approvalsMu.Lock()
+		delete(a.approvals, callID)
+		a.approvalsMu.Unlock()
+		a.emit("tool:approval_done", callID)
+	}()
+
+	a.emit("tool:approval", ToolApproval{ID: callID, ConversationID: conversationID, Tool: name, Description: description})
+	select {
+	case approved := <-answer:
+		return approved, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return false, errors.New("no answer in time")
+		}
+		return false, errors.New("the run was stopped")
+	}
+}
+
+// AnswerToolApproval approves or denies a tool call the window was asked
+// about in a "tool:approval" event.
+func (a *App) AnswerToolApproval(id string, approved bool) (err error) {
+	defer a.recoverBinding("AnswerToolApproval", &err)
+
+	a.approvalsMu.Lock()
+	answer, ok := a.approvals[id]
+	delete(a.approvals, id)
+	a.approvalsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no tool call is waiting for approval: %s", id)
+	}
+	answer <- approved
+	return nil
+}
+
+// describeToolCall formats a tool call for an approval request.
+func describeToolCall(name string, args map[string]interface{}) string {
+	if command, ok := args["command"].(string); ok && name == "run_command" {
+		return fmt.Sprintf("The agent wants to run:\n```\n%s\n```", command)
+	}
+	data, _ := json.MarshalIndent(args, "", "  ")
+	return fmt.Sprintf("The agent wants to call %s:\n```\n%s\n```", name, data)
+}
+
+// ============================================================================
+// Task Inbox Methods
+// ============================================================================
+
+// inboxRun is a task file being run.
+type inboxRun struct {
+	task    inbox.Task
+	started time.Time
+	summary string
+	files   []string
+}
+
+// startInbox watches dir for task files, replacing any folder watched
+// before. An empty dir stops watching.
+func (a *App) startInbox(dir string) {
+	a.inboxMu.Lock()
+	defer a.inboxMu.Unlock()
+	if a.inboxCancel != nil {
+		a.inboxCancel()
+		a.inboxCancel = nil
+	}
+	a.inboxDir = dir
+	if dir == "" || a.ctx == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.inboxCancel = cancel
+	slog.Info("watching task inbox", "dir", dir)
+	go inbox.Watch(ctx, dir, inbox.DefaultWatchInterval, a.startInboxTask, func(err error) {
+		slog.Warn("skipping inbox task", "error", err)
+	})
+}
+
+// startInboxTask queues a task file in a new conversation that works in
+// the task's folder, leaving the window and its run alone.
+func (a *App) startInboxTask(task inbox.Task) {
+	started := time.Now()
+	fail := func(err error) {
+		slog.Warn("failed to start inbox task", "path", task.Path, "error", err)
+		inbox.WriteResult(task, inbox.Result{Status: inbox.StatusFailed, Error: err.Error(), Started: started, Finished: time.Now()})
+	}
+	if a.convManager == nil {
+		fail(errors.New("conversation manager not initialized"))
+		return
+	}
+	if a.readOnly {
+		fail(conversation.ErrReadOnly)
+		return
+	}
+
+	conv, err := a.convManager.Create()
+	if err != nil {
+		fail(err)
+		return
+	}
+	settings := conv.Settings
+	settings.WorkingDir = task.WorkingDir
+	if err := a.convManager.UpdateSettings(conv.ID, settings); err != nil {
+		fail(err)
+		return
+	}
+
+	// Record the task before queueing it so the run can't finish first
+	a.inboxMu.Lock()
+	if a.inboxRuns == nil {
+		a.inboxRuns = map[string]*inboxRun{}
+	}
+	a.inboxRuns[conv.ID] = &inboxRun{task: task, started: started}
+	a.inboxMu.Unlock()
+
+	item, err := a.EnqueueTask(task.Prompt(), conv.ID)
+	if err != nil {
+		a.inboxMu.Lock()
+		delete(a.inboxRuns, conv.ID)
+		a.inboxMu.Unlock()
+		fail(err)
+		return
+	}
+	inbox.WriteResult(task, inbox.Result{Status: inbox.StatusRunning, ConversationID: conv.ID, Started: started})
+	slog.Info("queued inbox task", "path", task.Path, "run", item.ID)
+}
+
+// recordInboxOutcome keeps the final message and changed files of a run in
+// an inbox task's conversation for its result file.
+func (a *App) recordInboxOutcome(convID, summary string, files []string) {
+	a.inboxMu.Lock()
+	defer a.inboxMu.Unlock()
+	if run, ok := a.inboxRuns[convID]; ok {
+		run.summary = summary
+		run.files = append(run.files, files...)
+	}
+}
+
+// finishInboxRuns writes the result files of inbox tasks whose runs have
+// finished.
+func (a *App) finishInboxRuns(items []runqueue.Item) {
+	a.inboxMu.Lock()
+	defer a.inboxMu.Unlock()
+	for _, item := range items {
+		run, ok := a.inboxRuns[item.ConversationID]
+		if !ok || !item.Finished() {
+			continue
+		}
+		delete(a.inboxRuns, item.ConversationID)
+
+		result := inbox.Result{
+			Status:         inbox.StatusDone,
+			ConversationID: item.ConversationID,
+			Summary:        run.summary,
+			FilesModified:  run.files,
+			Started:        run.started,
+			Finished:       item.FinishedAt,
+		}
+		switch item.Status {
+		case runqueue.StatusFailed:
+			result.Status, result.Error = inbox.StatusFailed, item.Error
+		case runqueue.StatusCancelled:
+			result.Status = inbox.StatusCancelled
+		}
+		if err := inbox.WriteResult(run.task, result); err != nil {
+			slog.Warn("failed to write inbox result", "path", run.task.Path, "error", err)
+		}
+	}
+}
+
+// startIndex starts keeping the vector index of root up to date, with files
+// split into chunks as chunking says, and offers the agent semantic_search
+// over it, replacing the index of any other folder. An empty root stops
+// indexing.
+func (a *App) startIndex(root string, chunking map[string]string) {
+	a.indexMu.Lock()
+	defer a.indexMu.Unlock()
+	if a.indexCancel != nil {
+		a.indexCancel()
+		a.indexCancel = nil
+	}
+	a.index = nil
+	if root == "" || a.ctx == nil {
+		index.Register(nil)
+		return
+	}
+
+	x, err := index.Open(root, filepath.Join(config.IndexDir(), index.FileName(root)), a.embedder())
+	if err != nil {
+		slog.Warn("rebuilding workspace index", "root", root, "error", err)
+	}
+	x.SetChunking(chunking)
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.index, a.indexCancel = x, cancel
+	if err := index.Register(x); err != nil {
+		slog.Warn("semantic search unavailable", "error", err)
+	}
+	slog.Info("indexing workspace", "root", root)
+	go x.Watch(ctx, index.DefaultWatchInterval, func(st index.Status) {
+		a.emit("index:updated", st)
+	}, func(err error) {
+		slog.Warn("failed to update workspace index", "root", root, "error", err)
+	})
+}
+
+// symbolRoot returns the folder find_symbol looks in by default: the
+// indexed workspace folder, or else the shell session's directory.
+func (a *App) symbolRoot() string {
+	a.indexMu.Lock()
+	x := a.index
+	a.indexMu.Unlock()
+	if x != nil {
+		return x.Root()
+	}
+	return tools.GetSession().GetCWD()
+}
+
+const (
+	autoContextChunks  = 5                // Passages added to a task that mentions the indexed folder
+	autoContextTimeout = 15 * time.Second // Bounds the search, so a slow provider doesn't hold up the task
+)
+
+// retrieveContext returns a message with the indexed passages most related
+// to the task ending messages, if it is a new task that mentions the
+// indexed folder, along with the passages' locations and the folder. It
+// returns no sources when there is nothing to add.
+func (a *App) retrieveContext(ctx context.Context, messages []llm.Message) (llm.Message, []string, string) {
+	a.indexMu.Lock()
+	x := a.index
+	a.indexMu.Unlock()
+	if x == nil || a.config == nil || a.config.DisableAutoContext || len(messages) == 0 {
+		return llm.Message{}, nil, ""
+	}
+	task := messages[len(messages)-1]
+	if task.Role != "user" || !x.Mentions(task.Content) {
+		return llm.Message{}, nil, ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, autoContextTimeout)
+	defer cancel()
+	text, sources, err := x.Context(ctx, task.Content, autoContextChunks)
+	if err != nil {
+		slog.Warn("failed to retrieve context for the task", "root", x.Root(), "error", err)
+		return llm.Message{}, nil, ""
+	}
+	return llm.Message{Role: "user", Content: text}, sources, x.Root()
+}
+
+// embedder returns the client used to embed text, answering from the
+// embedding cache where it can, or nil if the LLM isn't configured.
+func (a *App) embedder() index.Embedder {
+	if a.client == nil {
+		return nil
+	}
+	return a.embeddingCache.Wrap(a.client)
+}
+
+// GetIndexStatus returns the size of the workspace index and when it was
+// last updated. The root is empty when no folder is indexed.
+func (a *App) GetIndexStatus() index.Status {
+	defer a.recoverBinding("GetIndexStatus", nil)
+
+	a.indexMu.Lock()
+	x := a.index
+	a.indexMu.Unlock()
+	if x == nil {
+		return index.Status{}
+	}
+	return x.Status()
+}
+
+// ReindexWorkspace brings the workspace index up to date now instead of
+// waiting for the next check for changed files.
+func (a *App) ReindexWorkspace() (err error) {
+	defer a.recoverBinding("ReindexWorkspace", &err)
+
+	a.indexMu.Lock()
+	x := a.index
+	a.indexMu.Unlock()
+	if x == nil {
+		return errors.New("no workspace folder is indexed")
+	}
+	changed, err := x.Update(a.ctx)
+	if changed {
+		a.emit("index:updated", x.Status())
+	}
+	return err
+}
+
+// semanticSearchResults is how many exchanges SemanticSearchConversations returns.
+const semanticSearchResults = 10
+
+// SemanticSearchConversations finds the past exchanges, in any conversation,
+// closest in meaning to query, such as "the command that fixed the printer".
+// Exchanges not searched before are embedded first, which can take a while
+// the first time.
+func (a *App) SemanticSearchConversations(query string) (_ []conversation.ExchangeMatch, err error) {
+	defer a.recoverBinding("SemanticSearchConversations", &err)
+
+	if a.convManager == nil {
+		return nil, errors.New("conversation manager not initialized")
+	}
+	if strings.TrimSpace(query) == "" {
+		return []conversation.ExchangeMatch{}, nil
+	}
+	return a.convManager.SemanticSearch(a.ctx, a.exchangeIndex, a.embedder(), query, semanticSearchResults)
+}
+
+// ListMemories returns the facts the agent was asked to remember, oldest
+// first, so the user can review them.
+func (a *App) ListMemories() []memory.Memory {
+	defer a.recoverBinding("ListMemories", nil)
+
+	return a.memories.List()
+}
+
+// DeleteMemory forgets a remembered fact.
+func (a *App) DeleteMemory(id string) (err error) {
+	defer a.recoverBinding("DeleteMemory", &err)
+
+	return a.memories.Delete(id)
+}
+
+// userContext returns what new conversations are told about the user: their
+// profile and, if it is turned on, the summary of their recent work.
+func (a *App) userContext() string {
+	var parts []string
+	if profile := a.memories.Profile().Render(); profile != "" {
+		parts = append(parts, profile)
+	}
+	if a.config != nil && a.config.RollingSummary {
+		if summary := a.memories.Summary().Render(); summary != "" {
+			parts = append(parts, summary)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// maintenanceTimeout bounds each LLM call made in the background after a
+// run, such as titling the conversation.
+const maintenanceTimeout = time.Minute
+
+// queueMaintenance queues titling the conversation of run and updating the
+// summary of recent work after it. They run one at a time in the
+// background, and a conversation waiting to be titled is titled once.
+func (a *App) queueMaintenance(run *conversation.RunHandle, task, result string) {
+	manager := a.convManager
+	if title := run.PrepareTitle(); title != nil {
+		a.jobs.Submit("title:"+title.ConversationID, func(ctx context.Context) {
+			ctx, cancel := context.WithTimeout(ctx, maintenanceTimeout)
+			defer cancel()
+			if err := manager.CompleteTitle(ctx, title); err != nil {
+				slog.Warn("failed to title conversation", "conversation", title.ConversationID, "error", err)
+			}
+		})
+	}
+	a.jobs.Submit("", func(ctx context.Context) { a.updateSummary(ctx, task, result) })
+}
+
+// updateSummary folds a finished run into the summary of recent work, if
+// it is turned on.
+func (a *App) updateSummary(ctx context.Context, task, result string) {
+	if a.config == nil || !a.config.RollingSummary || a.client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, maintenanceTimeout)
+	defer cancel()
+	if err := a.memories.UpdateSummary(ctx, a.client, task, result); err != nil {
+		slog.Warn("failed to update the summary of recent work", "error", err)
+	}
+}
+
+// GetRecentWorkSummary returns the summary of recent work shown to new
+// conversations when rolling_summary is on.
+func (a *App) GetRecentWorkSummary() memory.Summary {
+	defer a.recoverBinding("GetRecentWorkSummary", nil)
+
+	return a.memories.Summary()
+}
+
+// SaveRecentWorkSummary replaces the summary of recent work, e.g. after the
+// user corrects it. An empty text clears it.
+func (a *App) SaveRecentWorkSummary(text string) (err error) {
+	defer a.recoverBinding("SaveRecentWorkSummary", &err)
+
+	return a.memories.SetSummary(text)
+}
+
+// GetUserProfile returns the user's preferences shown to the agent at the
+// start of every conversation.
+func (a *App) GetUserProfile() memory.Profile {
+	defer a.recoverBinding("GetUserProfile", nil)
+
+	return a.memories.Profile()
+}
+
+// SaveUserProfile replaces the user's preferences after editing them in
+// settings.
+func (a *App) SaveUserProfile(p memory.Profile) (err error) {
+	defer a.recoverBinding("SaveUserProfile", &err)
+
+	return a.memories.SetProfile(p)
+}
+
+// ============================================================================
+// Persona Methods
+// ============================================================================
+
+// ListPersonas returns the personas in the user's library.
+func (a *App) ListPersonas() (_ []personas.Persona, err error) {
+	defer a.recoverBinding("ListPersonas", &err)
+
+	return personas.Load()
+}
+
+// ImportPersonas adds the agent definitions in a file, such as an OpenAI
+// assistant or GPT export or a YAML persona file, to the persona library
+// and returns the imported personas. Personas with the name of one already
+// in the library replace it.
+func (a *App) ImportPersonas(path string) (_ []personas.Persona, err error) {
+	defer a.recoverBinding("ImportPersonas", &err)
+
+	imported, err := personas.ImportFile(path)
+	if err != nil {
+		return nil, err
+	}
+	list, err := personas.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := personas.Save(personas.Merge(list, imported)); err != nil {
+		return nil, err
+	}
+	slog.Info("imported personas", "path", path, "count", len(imported))
+	return imported, nil
+}
+
+// SavePersona adds a persona to the library, replacing any with the same
+// name.
+func (a *App) SavePersona(p personas.Persona) (err error) {
+	defer a.recoverBinding("SavePersona", &err)
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	list, err := personas.Load()
+	if err != nil {
+		return err
+	}
+	return personas.Save(personas.Merge(list, []personas.Persona{p}))
+}
+
+// DeletePersona removes a persona from the library. Conversations already
+// using it keep its prompt.
+func (a *App) DeletePersona(name string) (err error) {
+	defer a.recoverBinding("DeletePersona", &err)
+
+	list, err := personas.Load()
+	if err != nil {
+		return err
+	}
+	if list, err = personas.Remove(list, name); err != nil {
+		return err
+	}
+	return personas.Save(list)
+}
+
+// UsePersona sets a conversation's system prompt, and model if the persona
+// has one, from a persona in the library.
+func (a *App) UsePersona(conversationID, name string) (err error) {
+	defer a.recoverBinding("UsePersona", &err)
+
+	list, err := personas.Load()
+	if err != nil {
+		return err
+	}
+	p, ok := personas.Find(list, name)
+	if !ok {
+		return fmt.Errorf("unknown persona: %s", name)
+	}
+	return a.updateActiveSettings(conversationID, func(s *conversation.Settings) {
+		s.SystemPrompt = p.Prompt
+		if p.Model != "" {
+			s.Model = p.Model
+		}
+	})
+}
+
+// ============================================================================
+// Command Palette Methods
+// ============================================================================
+
+// ListActions returns the actions the command palette can offer, with
+// actions that can't run right now disabled and the reason why. The list
+// reflects the current state, so the frontend should fetch it each time the
+// palette opens.
+func (a *App) ListActions() []actions.Action {
+	defer a.recoverBinding("ListActions", nil)
+
+	return a.actionRegistry().List()
+}
+
+// RunAction runs a backend action from ListActions with the arguments the
+// palette collected for its params. Frontend actions are handled by the UI
+// and return an error here.
+func (a *App) RunAction(id string, args map[string]string) (err error) {
+	defer a.recoverBinding("RunAction", &err)
+	return a.actionRegistry().Run(id, args)
+}
+
+// actionRegistry builds the action catalog from the app's current state.
+func (a *App) actionRegistry() *actions.Registry {
+	r := actions.NewRegistry()
+	none := func(fn func()) actions.Handler {
+		return func(map[string]string) error { fn(); return nil }
+	}
+
+	var activeID string
+	if a.convManager != nil {
+		if active := a.convManager.GetActive(); active != nil {
+			activeID = active.ID
+		}
+	}
+
+	// Conversations
+	r.Add(actions.Action{
+		ID: "conversation.new", Title: "New conversation", Category: "Conversation",
+		Shortcut: "CmdOrCtrl+N",
+	}, none(func() { a.NewConversation() }))
+	r.Add(actions.Action{
+		ID: "conversation.rename", Title: "Rename conversation", Category: "Conversation",
+		Params: []actions.Param{{Name: "title", Label: "Title", Required: true}},
+	}, func(args map[string]string) error {
+		return a.RenameConversation(activeID, args["title"])
+	})
+	r.Add(actions.Action{
+		ID: "conversation.regenerate_title", Title: "Regenerate conversation title", Category: "Conversation",
+	}, func(map[string]string) error {
+		_, err := a.RegenerateTitle(activeID)
+		return err
+	})
+	r.Add(actions.Action{
+		ID: "conversation.compact", Title: "Compact conversation", Category: "Conversation",
+		Description: "Summarize older messages to free up context",
+	}, func(map[string]string) error {
+		_, err := a.CompactConversation()
+		return err
+	})
+	r.Add(actions.Action{
+		ID: "conversation.set_persona", Title: "Switch persona", Category: "Conversation",
+		Description: "Replace the system prompt for this conversation; leave empty for the default",
+		Params:      []actions.Param{{Name: "persona", Label: "Persona"}},
+	}, func(args map[string]string) error {
+		return a.updateActiveSettings(activeID, func(s *conversation.Settings) { s.SystemPrompt = args["persona"] })
+	})
+	library, _ := personas.Load()
+	var personaActions []string
+	for _, p := range library {
+		name := p.Name
+		personaActions = append(personaActions, "conversation.use_persona:"+name)
+		r.Add(actions.Action{
+			ID: "conversation.use_persona:" + name, Title: "Use persona: " + name, Category: "Conversation",
+			Description: p.Description,
+		}, func(map[string]string) error {
+			return a.UsePersona(activeID, name)
+		})
+	}
+	for _, p := range a.configProfiles() {
+		name := p.Name
+		r.Add(actions.Action{
+			ID: "conversation.use_profile:" + name, Title: "Use profile: " + name, Category: "Conversation",
+		}, func(map[string]string) error {
+			return a.updateActiveSettings(activeID, func(s *conversation.Settings) { s.Profile = name })
+		})
+	}
+	r.Add(actions.Action{
+		ID: "conversation.open_folder", Title: "Open conversation folder", Category: "Conversation",
+	}, func(map[string]string) error {
+		return a.OpenConversationFolder(activeID)
+	})
+	r.Add(actions.Action{
+		ID: "conversations.empty_trash", Title: "Empty conversation trash", Category: "Conversation",
+	}, func(map[string]string) error {
+		return a.EmptyConversationTrash()
+	})
+
+	// Agent and queue
+	r.Add(actions.Action{
+		ID: "agent.stop", Title: "Stop agent", Category: "Agent", Shortcut: "Escape",
+	}, none(a.StopAgent))
+	paused := a.queue != nil && a.queue.Paused()
+	if paused {
+		r.Add(actions.Action{ID: "queue.resume", Title: "Resume run queue", Category: "Agent"}, none(func() { a.SetQueuePaused(false) }))
+	} else {
+		r.Add(actions.Action{ID: "queue.pause", Title: "Pause run queue", Category: "Agent"}, none(func() { a.SetQueuePaused(true) }))
+	}
+	r.Add(actions.Action{
+		ID: "queue.clear_finished", Title: "Clear finished queued tasks", Category: "Agent",
+	}, none(a.ClearFinishedQueueItems))
+	r.Add(actions.Action{
+		ID: "session.reset", Title: "Reset shell session", Category: "Agent",
+		Description: "Start a fresh shell in the original working directory",
+	}, none(a.ResetSession))
+
+	// App
+	r.AddFrontend(actions.Action{
+		ID: "settings.open", Title: "Open settings", Category: "App", Shortcut: "CmdOrCtrl+,",
+	})
+	if a.IsConversationStoreLocked() {
+		r.AddFrontend(actions.Action{
+			ID: "conversations.unlock", Title: "Unlock conversations", Category: "App",
+			Params: []actions.Param{{Name: "passphrase", Label: "Passphrase", Required: true}},
+		})
+	}
+	r.Add(actions.Action{
+		ID: "updates.check", Title: "Check for updates", Category: "App",
+	}, func(map[string]string) error {
+		_, err := a.CheckForUpdates()
+		return err
+	})
+	r.Add(actions.Action{
+		ID: "notifications.test", Title: "Send test notification", Category: "App",
+	}, func(map[string]string) error {
+		return a.SendTestNotification()
+	})
+
+	// Disable what can't run right now
+	if activeID == "" {
+		r.Disable("No conversation is open",
+			"conversation.rename", "conversation.regenerate_title", "conversation.compact",
+			"conversation.set_persona", "conversation.open_folder")
+		for _, p := range a.configProfiles() {
+			r.Disable("No conversation is open", "conversation.use_profile:"+p.Name)
+		}
+		r.Disable("No conversation is open", personaActions...)
+	}
+	if _, ctx := a.runningAgent(); ctx == nil {
+		r.Disable("The agent is not running", "agent.stop")
+	}
+	if a.queue == nil {
+		r.Disable("The run queue is not started", "queue.pause", "queue.resume", "queue.clear_finished")
+	}
+	if a.readOnly {
+		reason := "Another instance owns the conversation store"
+		r.Disable(reason,
+			"conversation.new", "conversation.rename", "conversation.regenerate_title", "conversation.compact",
+			"conversation.set_persona", "conversations.empty_trash")
+		for _, p := range a.configProfiles() {
+			r.Disable(reason, "conversation.use_profile:"+p.Name)
+		}
+		r.Disable(reason, personaActions...)
+	}
+	if a.convManager == nil {
+		r.Disable("Conversations are not available", "conversation.new", "conversations.empty_trash")
+	}
+	return r
+}
+
+// configProfiles returns the provider profiles from the config, if any.
+func (a *App) configProfiles() []config.Profile {
+	if a.config == nil {
+		return nil
+	}
+	return a.config.Profiles
+}
+
+// updateActiveSettings changes one field of a conversation's settings.
+func (a *App) updateActiveSettings(id string, change func(*conversation.Settings)) error {
+	settings, err := a.GetConversationSettings(id)
+	if err != nil {
+		return err
+	}
+	change(&settings)
+	return a.UpdateConversationSettings(id, settings)
+}
+
+// ============================================================================
+// Deep Link Methods
+// ============================================================================
+
+// DeepLinkRequest tells the frontend which conversation a deep link opened
+// and the task, if any, to fill in its message box.
+type DeepLinkRequest struct {
+	ConversationID string `json:"conversation_id"`
+	Text           string `json:"text,omitempty"`
+}
+
+// openDeepLink handles an agent-desktop:// link: it opens or creates the
+// conversation, raises the window, and emits deeplink:open, or
+// deeplink:error if the link can't be followed. Links that arrive before
+// startup are handled once the app has started.
+func (a *App) openDeepLink(raw string) {
+	a.linkMu.Lock()
+	if !a.linkReady {
+		a.linkPending = raw
+		a.linkMu.Unlock()
+		return
+	}
+	a.linkMu.Unlock()
+
+	req, err := a.resolveDeepLink(raw)
+	a.raiseWindow()
+	if err != nil {
+		slog.Warn("ignoring deep link", "link", raw, "error", err)
+		a.emit("deeplink:error", err.Error())
+		return
+	}
+	a.emit("deeplink:open", req)
+}
+
+// handleLaunchDeepLink follows a link the app was launched with. The
+// frontend isn't listening for events yet, so the result is kept for
+// TakeLaunchDeepLink.
+func (a *App) handleLaunchDeepLink() {
+	a.linkMu.Lock()
+	a.linkReady = true
+	raw := a.linkPending
+	a.linkPending = ""
+	a.linkMu.Unlock()
+	if raw == "" {
+		return
+	}
+
+	req, err := a.resolveDeepLink(raw)
+	if err != nil {
+		slog.Warn("ignoring deep link", "link", raw, "error", err)
+		return
+	}
+	a.linkMu.Lock()
+	a.launchLink = &req
+	a.linkMu.Unlock()
+}
+
+// TakeLaunchDeepLink returns the deep link the app was launched with, once;
+// nil if there was none.
+func (a *App) TakeLaunchDeepLink() *DeepLinkRequest {
+	defer a.recoverBinding("TakeLaunchDeepLink", nil)
+
+	a.linkMu.Lock()
+	defer a.linkMu.Unlock()
+	req := a.launchLink
+	a.launchLink = nil
+	return req
+}
+
+// resolveDeepLink opens or creates the conversation a link refers to and
+// makes it active.
+func (a *App) resolveDeepLink(raw string) (DeepLinkRequest, error) {
+	link, err := deeplink.Parse(raw)
+	if err != nil {
+		return DeepLinkRequest{}, err
+	}
+	if a.convManager == nil {
+		return DeepLinkRequest{}, errors.New("conversations are not available")
+	}
+
+	var conv *conversation.Conversation
+	if link.ConversationID != "" {
+		if conv, err = a.convManager.Load(link.ConversationID); err != nil {
+			return DeepLinkRequest{}, fmt.Errorf("failed to open conversation %s: %w", link.ConversationID, err)
+		}
+	} else {
+		conv = a.convManager.New()
+	}
+	return DeepLinkRequest{ConversationID: conv.ID, Text: link.Text}, nil
+}
+
+// onSecondInstance is called when the app is launched again while running.
+// The new launch exits; this instance follows its deep link, if any, or
+// just comes to the front.
+func (a *App) onSecondInstance(data options.SecondInstanceData) {
+	if link, ok := deeplink.FromArgs(data.Args); ok {
+		a.openDeepLink(link)
+		return
+	}
+	a.raiseWindow()
+}
+
+// raiseWindow shows the window, restoring it from the tray or the dock.
+func (a *App) raiseWindow() {
+	runtime.WindowUnminimise(a.ctx)
+	runtime.WindowShow(a.ctx)
 }
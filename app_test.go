@@ -1,15 +1,41 @@
 package main
 
 import (
+	"archive/zip"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"agent-desktop/internal/actions"
+	"agent-desktop/internal/agent"
+	"agent-desktop/internal/bridge"
 	"agent-desktop/internal/config"
 	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/inbox"
+	"agent-desktop/internal/index"
 	"agent-desktop/internal/llm"
+	"agent-desktop/internal/logging"
+	"agent-desktop/internal/mcp"
+	"agent-desktop/internal/memory"
+	"agent-desktop/internal/metrics"
+	"agent-desktop/internal/plugins"
+	"agent-desktop/internal/runqueue"
+	"agent-desktop/internal/snapshot"
+	"agent-desktop/internal/telemetry"
 	"agent-desktop/internal/tools"
+	"agent-desktop/internal/webhook"
 )
 
 // MockLLMClient implements the Client interface for testing
@@ -52,6 +78,28 @@ func setupTestApp(t *testing.T) (*App, func()) {
 	return app, cleanup
 }
 
+// runActive runs the agent in app's active conversation as SendMessage does
+// once the user's message is added.
+func runActive(t *testing.T, app *App) error {
+	t.Helper()
+	run, ctx, err := app.startAgent(context.Background(), app.convManager.GetActive().ID)
+	if err != nil {
+		t.Fatalf("startAgent failed: %v", err)
+	}
+	defer app.endAgent()
+	return app.runConversation(ctx, run)
+}
+
+// startRun gives the agent to a run in the conversation with the given ID,
+// since tools are only called in runs, until the test ends.
+func startRun(t *testing.T, app *App, convID string) {
+	t.Helper()
+	if _, _, err := app.startAgent(context.Background(), convID); err != nil {
+		t.Fatalf("startAgent failed: %v", err)
+	}
+	t.Cleanup(app.endAgent)
+}
+
 func TestApp_NewConversation(t *testing.T) {
 	app, cleanup := setupTestApp(t)
 	defer cleanup()
@@ -157,6 +205,23 @@ func TestApp_RenameConversation(t *testing.T) {
 	}
 }
 
+func TestApp_SearchConversations(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	conv := app.NewConversation()
+	app.convManager.AddUserMessage("Summarize the lease agreement")
+	app.NewConversation()
+
+	results, err := app.SearchConversations("LEASE", 10)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != conv.ID {
+		t.Errorf("Expected only the lease conversation, got %+v", results)
+	}
+}
+
 func TestApp_GetActiveConversation_ReturnsNilWhenNone(t *testing.T) {
 	app, cleanup := setupTestApp(t)
 	defer cleanup()
@@ -174,3 +239,1818 @@ func TestApp_GetActiveConversation_ReturnsNilWhenNone(t *testing.T) {
 		t.Error("Expected nil when no active conversation")
 	}
 }
+
+func TestApp_TagConversation(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	conv := app.NewConversation()
+
+	if err := app.TagConversation(conv.ID, "ideas"); err != nil {
+		t.Fatalf("Failed to tag: %v", err)
+	}
+	if err := app.SetConversationFolder(conv.ID, "drafts"); err != nil {
+		t.Fatalf("Failed to set folder: %v", err)
+	}
+
+	tagged, err := app.ListConversationsByTag("ideas")
+	if err != nil {
+		t.Fatalf("Failed to list by tag: %v", err)
+	}
+	if len(tagged) != 1 || tagged[0].ID != conv.ID {
+		t.Errorf("Expected tagged conversation, got %+v", tagged)
+	}
+
+	inFolder, _ := app.ListConversationsInFolder("drafts")
+	if len(inFolder) != 1 {
+		t.Errorf("Expected 1 conversation in folder, got %d", len(inFolder))
+	}
+}
+
+func TestApp_EditAndRegenerate_RejectsNonUserMessage(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	app.NewConversation()
+
+	// Index 0 is the system prompt, which cannot be edited
+	if err := app.EditAndRegenerate(0, "New content"); err == nil {
+		t.Error("Expected error editing the system prompt")
+	}
+
+	if err := app.RegenerateFrom(10); err == nil {
+		t.Error("Expected error regenerating from an out-of-range index")
+	}
+}
+
+func TestApp_UnlockConversations(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	app.config.Encryption = config.EncryptionPassphrase
+	app.storePath = t.TempDir()
+	app.applyStoreEncryption(app.convManager.GetStore())
+
+	if !app.IsConversationStoreLocked() {
+		t.Error("Expected store to be locked before passphrase is entered")
+	}
+	if err := app.EncryptExistingConversations(); err == nil {
+		t.Error("Expected error encrypting while locked")
+	}
+
+	// Nothing is saved in plaintext before the passphrase is entered
+	conv := app.NewConversation()
+	if _, err := app.convManager.GetStore().Load(conv.ID); err == nil {
+		t.Error("Expected the new conversation not to be saved while locked")
+	}
+	if err := app.convManager.Save(); !errors.Is(err, conversation.ErrLocked) {
+		t.Errorf("Save while locked error = %v, want ErrLocked", err)
+	}
+
+	if err := app.UnlockConversations("passphrase"); err != nil {
+		t.Fatalf("UnlockConversations failed: %v", err)
+	}
+	if app.IsConversationStoreLocked() {
+		t.Error("Expected store to be unlocked")
+	}
+	if err := app.convManager.Save(); err != nil {
+		t.Errorf("Save after unlocking failed: %v", err)
+	}
+
+	if err := app.UnlockConversations("other"); err == nil {
+		t.Error("Expected error for a different passphrase")
+	}
+}
+
+func TestApp_RestoreConversation(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	conv := app.NewConversation()
+	if err := app.DeleteConversation(conv.ID); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+
+	if err := app.RestoreConversation(conv.ID); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	if _, err := app.LoadConversation(conv.ID); err != nil {
+		t.Errorf("Restored conversation should be loadable: %v", err)
+	}
+
+	app.DeleteConversation(conv.ID)
+	if err := app.EmptyConversationTrash(); err != nil {
+		t.Fatalf("Failed to empty trash: %v", err)
+	}
+	if err := app.RestoreConversation(conv.ID); err == nil {
+		t.Error("Expected restore to fail after emptying the trash")
+	}
+}
+
+func TestApp_Janitor(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+	app.config.DeleteAfterDays = 30
+
+	store := app.convManager.GetStore()
+	old := conversation.New()
+	old.UpdatedAt = time.Now().Add(-60 * 24 * time.Hour)
+	if err := store.Save(old); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	app.startJanitor()
+	done := app.janitorDone
+	app.stopJanitor()
+	select {
+	case <-done:
+	default:
+		t.Fatal("Expected stopJanitor to wait for the janitor")
+	}
+	if _, err := store.Load(old.ID); err == nil {
+		t.Error("Expected the janitor to trash the old conversation")
+	}
+
+	// The janitor runs once per start, however often the config is saved
+	app.startJanitor()
+	if app.janitorDone != done {
+		t.Error("Expected a second startJanitor to do nothing")
+	}
+}
+
+func TestApp_ValidateConfig(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	diags := app.ValidateConfig(&config.Config{Endpoint: "localhost:1234", Model: "gpt-4o"})
+
+	fields := make(map[string]string)
+	for _, fe := range diags {
+		fields[fe.Field] = fe.Severity
+	}
+	if fields["api_key"] != config.SeverityError {
+		t.Errorf("expected api_key error, got %+v", diags)
+	}
+	if fields["endpoint"] != config.SeverityError {
+		t.Errorf("expected endpoint error, got %+v", diags)
+	}
+
+	if diags := app.ValidateConfig(nil); diags != nil {
+		t.Errorf("ValidateConfig(nil) = %+v, want nil", diags)
+	}
+}
+
+func TestApp_NeedsSetup(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if !app.NeedsSetup() {
+		t.Error("expected unconfigured app to need setup")
+	}
+
+	app.config = &config.Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o"}
+	if app.NeedsSetup() {
+		t.Error("expected configured app to skip setup")
+	}
+}
+
+func TestApp_Limits(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if got := app.maxSteps(); got != 20 {
+		t.Errorf("maxSteps() = %d, want 20 derived from the timeout", got)
+	}
+
+	app.config.Limits = config.Limits{MaxSteps: 7}
+	if got := app.maxSteps(); got != 7 {
+		t.Errorf("maxSteps() = %d, want the configured 7", got)
+	}
+
+	if err := app.SetLimits(config.Limits{MaxTokensPerRun: -1}); err == nil {
+		t.Error("expected SetLimits to reject negative limits")
+	}
+	if app.GetLimits().MaxSteps != 7 {
+		t.Error("rejected limits should not be applied")
+	}
+}
+
+func TestApp_GetConfig_WithoutEnv(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	t.Setenv(config.EnvAPIKey, "env-key")
+	app.config = &config.Config{APIKey: "file-key"}
+	app.config.ApplyEnv()
+
+	if cfg := app.GetConfig(); cfg.APIKey != "file-key" {
+		t.Errorf("GetConfig().APIKey = %q, want the file's key for the settings UI", cfg.APIKey)
+	}
+	if app.config.APIKey != "env-key" {
+		t.Errorf("running APIKey = %q, want the environment's", app.config.APIKey)
+	}
+}
+
+func TestApp_AddSpend_ResetsDaily(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	app.addSpend(1.5)
+	if got := app.addSpend(0.5); got != 2.0 {
+		t.Errorf("addSpend() total = %v, want 2.0", got)
+	}
+
+	app.spendDay = "2000-01-01"
+	if got := app.addSpend(0.25); got != 0.25 {
+		t.Errorf("addSpend() after day change = %v, want 0.25", got)
+	}
+}
+
+func TestApp_SelectProfile(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	app.config.Profiles = []config.Profile{{Name: "coding", Model: "deepseek-chat"}}
+	app.config.ProfileRules = []config.ProfileRule{{Profile: "coding", Keywords: []string{"bug"}}}
+
+	conv := app.NewConversation()
+	run, err := app.convManager.BeginRun(conv.ID)
+	if err != nil {
+		t.Fatalf("BeginRun() error = %v", err)
+	}
+	app.selectProfile(run, "Please fix this bug")
+	run.End()
+
+	settings, err := app.GetConversationSettings(conv.ID)
+	if err != nil {
+		t.Fatalf("GetConversationSettings() error = %v", err)
+	}
+	if settings.Profile != "coding" {
+		t.Errorf("Profile = %q, want coding", settings.Profile)
+	}
+
+	// Later messages don't switch the profile of an existing conversation
+	other := app.NewConversation()
+	app.convManager.AddUserMessage("hello")
+	run, _ = app.convManager.BeginRun(other.ID)
+	app.selectProfile(run, "another bug")
+	run.End()
+	if settings, _ := app.GetConversationSettings(other.ID); settings.Profile != "" {
+		t.Errorf("Profile = %q, want none after the first message", settings.Profile)
+	}
+}
+
+func TestApp_BeforeClose_ClosesWithoutTray(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	app.config.MinimizeToTray = true
+	if app.beforeClose(app.ctx) {
+		t.Error("expected the window to close when no tray is running")
+	}
+}
+
+func TestApp_ValidateConfig_GlobalHotkey(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	diags := app.ValidateConfig(&config.Config{Endpoint: "not a url", GlobalHotkey: "Space"})
+	for _, fe := range diags {
+		if fe.Field == "global_hotkey" && fe.Severity == config.SeverityError {
+			return
+		}
+	}
+	t.Errorf("expected a global_hotkey error, got %+v", diags)
+}
+
+func TestDialogDir(t *testing.T) {
+	session := tools.GetSession()
+	original := session.GetCWD()
+	defer session.SetCWD(original)
+
+	dir := t.TempDir()
+	if err := session.SetCWD(dir); err != nil {
+		t.Fatalf("SetCWD failed: %v", err)
+	}
+	if got := dialogDir(); got != dir {
+		t.Errorf("Expected dialogs to open in %s, got %s", dir, got)
+	}
+}
+
+func TestApp_Queue(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if _, err := app.EnqueueTask("task", ""); err == nil {
+		t.Error("Expected error before the queue is started")
+	}
+
+	// Without an LLM client queued tasks fail instead of running
+	app.queue = runqueue.New(app.runQueued, queueConcurrency, nil)
+	if _, err := app.EnqueueTask("   ", ""); err == nil {
+		t.Error("Expected error for an empty task")
+	}
+	item, err := app.EnqueueTask("list files", "")
+	if err != nil {
+		t.Fatalf("EnqueueTask failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		items := app.GetQueue()
+		if len(items) == 1 && items[0].ID == item.ID && items[0].Status == runqueue.StatusFailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected queued task to fail, got %+v", items)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	app.ClearFinishedQueueItems()
+	if items := app.GetQueue(); len(items) != 0 {
+		t.Errorf("Expected empty queue, got %+v", items)
+	}
+}
+
+// blockingLLM starts a provider that answers "Hello" once release is
+// closed, and sends on started as each request arrives.
+func blockingLLM(t *testing.T, app *App) (started chan struct{}, release chan struct{}) {
+	started, release = make(chan struct{}, 4), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hello"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+	return started, release
+}
+
+func TestApp_RunKeepsItsConversation(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+	started, release := blockingLLM(t, app)
+
+	events := make(chan string, 8)
+	app.emitter = func(event string, data ...interface{}) {
+		if event == "agent:error" || event == "agent:message" {
+			events <- fmt.Sprint(event, data)
+		}
+	}
+
+	first := app.NewConversation()
+	app.SendMessage("Hi", "")
+	<-started
+
+	// A second message waits for the run instead of stopping it
+	app.SendMessage("Are you there?", "")
+	if event := <-events; !strings.Contains(event, errAgentRunning.Error()) {
+		t.Errorf("event = %q, want the agent reported busy", event)
+	}
+	if err := app.RegenerateFrom(1); !errors.Is(err, errAgentRunning) {
+		t.Errorf("RegenerateFrom() error = %v, want errAgentRunning", err)
+	}
+
+	// The user opens another conversation while the run goes on
+	second := app.NewConversation()
+	close(release)
+	if event := <-events; !strings.HasPrefix(event, "agent:message") {
+		t.Fatalf("event = %q, want the run's reply", event)
+	}
+
+	conv, err := app.convManager.Get(first.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if n := len(conv.Messages); n != 3 || conv.Messages[1].Content != "Hi" || conv.Messages[2].Content != "Hello" {
+		t.Errorf("run's conversation messages = %+v", conv.Messages)
+	}
+	if active := app.GetActiveConversation(); active.ID != second.ID || len(active.Messages) != len(second.Messages) {
+		t.Errorf("active conversation = %s with %d messages, want %s untouched", active.ID, len(active.Messages), second.ID)
+	}
+}
+
+func TestApp_ExternalTasksLeaveTheWindowAlone(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+	defer tools.ResetSession()
+	started, release := blockingLLM(t, app)
+	app.queue = runqueue.New(app.runQueued, queueConcurrency, app.queueChanged)
+
+	events := make(chan string, 8)
+	app.emitter = func(event string, data ...interface{}) {
+		if event == "agent:error" || event == "agent:message" {
+			events <- event
+		}
+	}
+
+	window := app.NewConversation()
+	app.SendMessage("Hi", "")
+	<-started
+
+	// A message over the API and a task file arrive during the user's run
+	backend := apiBackend{app}
+	conv, err := backend.NewConversation()
+	if err != nil {
+		t.Fatalf("NewConversation failed: %v", err)
+	}
+	if _, err := backend.SendMessage(conv.ID, "list files"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "task.md")
+	os.WriteFile(path, []byte("tidy up"), 0644)
+	task, err := inbox.Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	app.startInboxTask(task)
+
+	// They wait for the user's run, which ends with its reply
+	close(release)
+	if event := <-events; event != "agent:message" {
+		t.Fatalf("event = %q, want the user's run to finish", event)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		items := app.GetQueue()
+		if len(items) == 2 && items[0].Finished() && items[1].Finished() {
+			for _, item := range items {
+				if item.Status != runqueue.StatusDone {
+					t.Errorf("queued task = %+v, want done", item)
+				}
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected both tasks to finish, got %+v", items)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if active := app.GetActiveConversation(); active.ID != window.ID {
+		t.Errorf("active conversation = %s, want the window's %s", active.ID, window.ID)
+	}
+	got, _ := app.convManager.Get(window.ID)
+	if len(got.Messages) != 3 || got.Messages[2].Content != "Hello" {
+		t.Errorf("window conversation messages = %+v", got.Messages)
+	}
+	got, _ = app.convManager.Get(conv.ID)
+	if len(got.Messages) != 3 || got.Messages[1].Content != "list files" || got.Messages[2].Content != "Hello" {
+		t.Errorf("API conversation messages = %+v", got.Messages)
+	}
+}
+
+func TestApp_InboxTask(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "task.md")
+	os.WriteFile(path, []byte("list files"), 0644)
+	task, err := inbox.Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	// Without an LLM client the run fails, and the result file says so
+	app.queue = runqueue.New(app.runQueued, queueConcurrency, app.queueChanged)
+	app.startInboxTask(task)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, _ := os.ReadFile(inbox.ResultPath(path))
+		if strings.Contains(string(data), "Status: failed") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a failed result, got %q", data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	items := app.GetQueue()
+	if len(items) != 1 {
+		t.Fatalf("Expected one run, got %+v", items)
+	}
+	conv, err := app.convManager.Get(items[0].ConversationID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if conv.Settings.WorkingDir != dir {
+		t.Errorf("Expected the conversation to work in %s, got %q", dir, conv.Settings.WorkingDir)
+	}
+	if len(app.inboxRuns) != 0 {
+		t.Errorf("Expected no pending inbox runs, got %d", len(app.inboxRuns))
+	}
+}
+
+func TestApp_CheckForUpdates_UpToDate(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"releases": [{"version": "` + version + `", "channel": "stable"}]}`))
+	}))
+	defer server.Close()
+	app.config.UpdateFeedURL = server.URL
+
+	info, err := app.CheckForUpdates()
+	if err != nil {
+		t.Fatalf("CheckForUpdates failed: %v", err)
+	}
+	if info.Available || info.CurrentVersion != version {
+		t.Errorf("Expected no update for %s, got %+v", version, info)
+	}
+}
+
+func TestApp_GetUsageStats(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hello"}}],
+			"usage": {"prompt_tokens": 1000, "completion_tokens": 100, "total_tokens": 1100}}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+	app.usage, _ = metrics.Open(filepath.Join(t.TempDir(), "metrics.json"))
+
+	app.convManager.New()
+	app.convManager.AddUserMessage("Hi")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+
+	stats, err := app.GetUsageStats("7d")
+	if err != nil {
+		t.Fatalf("GetUsageStats failed: %v", err)
+	}
+	total := stats.Total
+	if total.TotalTokens != 1100 || total.LLMCalls != 1 || total.Runs != 1 || total.SucceededRuns != 1 || total.Cost <= 0 {
+		t.Errorf("total = %+v", total)
+	}
+	if len(stats.Days) != 7 || len(stats.Models) != 1 || stats.Models[0].Model != "gpt-4o" {
+		t.Errorf("stats = %+v", stats)
+	}
+	if _, err := app.GetUsageStats("fortnight"); err == nil {
+		t.Error("expected an error for an unknown range")
+	}
+}
+
+func TestApp_UsageStepsCarryCost(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_current_directory", "arguments": "{}"}}]}}],
+				"usage": {"prompt_tokens": 1000, "completion_tokens": 100, "total_tokens": 1100}}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Done"}}],
+			"usage": {"prompt_tokens": 2000, "completion_tokens": 50, "total_tokens": 2050}}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+	var usage []agent.TokenUsage
+	app.emitter = func(event string, data ...interface{}) {
+		if step, ok := data[0].(agent.Step); ok && event == "agent:step" && step.Type == agent.StepTypeUsage {
+			usage = append(usage, *step.Usage)
+		}
+	}
+
+	app.convManager.New()
+	app.convManager.AddUserMessage("Where am I?")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("usage steps = %+v", usage)
+	}
+	first, second := usage[0], usage[1]
+	if first.Cost != llm.EstimateCost("gpt-4o", 1000, 100) || first.RunTokens != 1100 || first.RunCost != first.Cost {
+		t.Errorf("first usage step = %+v", first)
+	}
+	if second.Cost != llm.EstimateCost("gpt-4o", 2000, 50) || second.TotalTokens != 2050 || second.RunTokens != 3150 || second.RunCost != first.Cost+second.Cost {
+		t.Errorf("second usage step = %+v", second)
+	}
+}
+
+func TestApp_ExportUsageReport(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hello"}}],
+			"usage": {"prompt_tokens": 1000, "completion_tokens": 100, "total_tokens": 1100}}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+
+	conv := app.convManager.New()
+	app.convManager.AddUserMessage("Hi")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "usage.csv")
+	if err := app.ExportUsageReport("today", "csv", path); err != nil {
+		t.Fatalf("ExportUsageReport failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], conv.ID+",") || !strings.Contains(lines[1], ",gpt-4o,") || !strings.HasPrefix(lines[2], ",Total,") {
+		t.Errorf("CSV report = %s", data)
+	}
+
+	if err := app.ExportUsageReport("today", "xlsx", path); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if err := app.ExportUsageReport("fortnight", "json", path); err == nil {
+		t.Error("expected an error for an unknown range")
+	}
+}
+
+func TestApp_SpendingAlerts(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hello"}}],
+			"usage": {"prompt_tokens": 1000, "completion_tokens": 100, "total_tokens": 1100}}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+	app.usage, _ = metrics.Open(filepath.Join(t.TempDir(), "metrics.json"))
+	app.config.Limits = config.Limits{AlertCostPerDay: 0.001, AlertCostPerMonth: 1000, PauseOnAlert: true}
+	var warnings []BudgetWarning
+	app.emitter = func(event string, data ...interface{}) {
+		if event == "agent:budget-warning" {
+			warnings = append(warnings, data[0].(BudgetWarning))
+		}
+	}
+
+	app.convManager.New()
+	app.convManager.AddUserMessage("Hi")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Period != "day" || warnings[0].Threshold != 0.001 || !warnings[0].Paused {
+		t.Fatalf("budget warnings = %+v", warnings)
+	}
+	if w := app.GetBudgetWarning(); w == nil || w.Period != "day" {
+		t.Errorf("GetBudgetWarning() = %+v", w)
+	}
+
+	// New runs wait for the warning to be acknowledged
+	app.convManager.AddUserMessage("Again")
+	if err := runActive(t, app); err == nil {
+		t.Error("expected the run to be paused")
+	}
+	app.AcknowledgeBudgetWarning()
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation after acknowledging failed: %v", err)
+	}
+	if len(warnings) != 1 || app.GetBudgetWarning() != nil {
+		t.Errorf("expected the daily alert only once, got %+v", warnings)
+	}
+}
+
+func TestApp_GetToolStats(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_current_directory", "arguments": "{}"}}]}}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Done"}}]}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+	app.usage, _ = metrics.Open(filepath.Join(t.TempDir(), "metrics.json"))
+
+	app.convManager.New()
+	app.convManager.AddUserMessage("Where am I?")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+
+	stats := app.GetToolStats()
+	if len(stats) != 1 || stats[0].Tool != "get_current_directory" || stats[0].Calls != 1 || stats[0].Failures != 0 {
+		t.Errorf("tool stats = %+v", stats)
+	}
+}
+
+func TestApp_GetTelemetryPreview(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_current_directory", "arguments": "{}"}}]}}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Done"}}]}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+	app.telemetry, _ = telemetry.Open(filepath.Join(t.TempDir(), "telemetry.json"))
+
+	app.convManager.New()
+	app.convManager.AddUserMessage("Where am I?")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+	if p := app.GetTelemetryPreview(); len(p.Features) != 0 {
+		t.Errorf("counted with telemetry off: %+v", p.Features)
+	}
+
+	app.telemetry.SetEnabled(true)
+	calls = 0
+	app.convManager.AddUserMessage("And now?")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+	features := app.GetTelemetryPreview().Features
+	if features["run"] != 1 || features["provider.local"] != 1 || features["tool.get_current_directory"] != 1 {
+		t.Errorf("features = %+v", features)
+	}
+}
+
+func TestApp_GetConversationRuns(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hello"}}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+
+	conv := app.convManager.New()
+	app.convManager.AddUserMessage("Hi")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+
+	runs, err := app.GetConversationRuns(conv.ID)
+	if err != nil {
+		t.Fatalf("GetConversationRuns failed: %v", err)
+	}
+	if len(runs) != 1 || runs[0].EndedAt.IsZero() {
+		t.Fatalf("runs = %+v, want one finished run", runs)
+	}
+	var types []string
+	for _, step := range runs[0].Steps {
+		types = append(types, step.Type)
+	}
+	if got := strings.Join(types, ","); got != "usage,assistant_message" {
+		t.Errorf("step types = %s", got)
+	}
+}
+
+func TestApp_RecoverBinding(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	err := func() (err error) {
+		defer app.recoverBinding("TestBinding", &err)
+		panic("boom")
+	}()
+	if err == nil || !strings.Contains(err.Error(), "TestBinding") {
+		t.Errorf("Expected the panic to be returned as an error, got %v", err)
+	}
+
+	if err := app.SaveCrashReport("missing", filepath.Join(t.TempDir(), "crash.json")); err == nil {
+		t.Error("Expected error for an unknown crash report")
+	}
+
+	// Bindings without an error result return their zero values
+	names := func() []string {
+		defer app.recoverBinding("TestBinding", nil)
+		panic("boom")
+	}()
+	if names != nil {
+		t.Errorf("Expected the zero value, got %v", names)
+	}
+}
+
+func TestApp_BindingsRecover(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "app.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || !fn.Name.IsExported() {
+			continue
+		}
+		if star, ok := fn.Recv.List[0].Type.(*ast.StarExpr); !ok || star.X.(*ast.Ident).Name != "App" {
+			continue
+		}
+		recovers := false
+		if len(fn.Body.List) > 0 {
+			if d, ok := fn.Body.List[0].(*ast.DeferStmt); ok {
+				if sel, ok := d.Call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "recoverBinding" {
+					recovers = true
+				}
+			}
+		}
+		if !recovers {
+			t.Errorf("binding %s doesn't start with defer a.recoverBinding", fn.Name.Name)
+		}
+	}
+}
+
+func TestApp_GetFileDiff(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+	app.snapshots = snapshot.NewStore(t.TempDir())
+	tools.SetFileChangeHook(app.snapshotFile)
+	defer tools.SetFileChangeHook(nil)
+
+	conv := app.convManager.New()
+	startRun(t, app, conv.ID)
+	path := filepath.Join(t.TempDir(), "main.go")
+	os.WriteFile(path, []byte("package main\n"), 0644)
+
+	if result := tools.WriteFile(path, "package app\n", false); !result.Success {
+		t.Fatalf("WriteFile failed: %s", result.Error)
+	}
+
+	files, err := app.GetChangedFiles(conv.ID)
+	if err != nil || len(files) != 1 || files[0].Path != path {
+		t.Fatalf("Expected %s to be listed as changed, got %+v, %v", path, files, err)
+	}
+	d, err := app.GetFileDiff(conv.ID, path)
+	if err != nil {
+		t.Fatalf("GetFileDiff failed: %v", err)
+	}
+	if !strings.Contains(d.Unified, "-package main\n+package app\n") {
+		t.Errorf("Unexpected diff:\n%s", d.Unified)
+	}
+}
+
+func TestApp_ConversationFolder(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	conv := app.convManager.New()
+	settings := conv.Settings
+	settings.WorkingDir = dir
+	if err := app.convManager.UpdateSettings(conv.ID, settings); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+	other := app.convManager.New()
+
+	got, err := app.conversationFolder(conv.ID)
+	if err != nil {
+		t.Fatalf("conversationFolder failed: %v", err)
+	}
+	if got != dir {
+		t.Errorf("Expected %s, got %s", dir, got)
+	}
+
+	// The active conversation uses the session's current directory
+	if got, err := app.conversationFolder(other.ID); err != nil || got != tools.GetSession().GetCWD() {
+		t.Errorf("Expected the session directory, got %q, %v", got, err)
+	}
+}
+
+func TestApp_Actions(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+	app.config.Profiles = []config.Profile{{Name: "local"}}
+
+	find := func(id string) actions.Action {
+		for _, a := range app.ListActions() {
+			if a.ID == id {
+				return a
+			}
+		}
+		t.Fatalf("action %s not listed", id)
+		return actions.Action{}
+	}
+
+	if find("conversation.rename").Enabled {
+		t.Error("Expected rename to be disabled with no open conversation")
+	}
+	if find("agent.stop").Enabled {
+		t.Error("Expected stop to be disabled when the agent isn't running")
+	}
+	if a := find("settings.open"); a.Kind != actions.KindFrontend {
+		t.Errorf("Expected settings.open to be a frontend action, got %s", a.Kind)
+	}
+
+	if err := app.RunAction("conversation.new", nil); err != nil {
+		t.Fatalf("RunAction(conversation.new) failed: %v", err)
+	}
+	conv := app.convManager.GetActive()
+	if conv == nil {
+		t.Fatal("Expected a new active conversation")
+	}
+
+	if err := app.RunAction("conversation.rename", map[string]string{"title": "Palette"}); err != nil {
+		t.Fatalf("RunAction(conversation.rename) failed: %v", err)
+	}
+	if err := app.RunAction("conversation.set_persona", map[string]string{"persona": "You are terse."}); err != nil {
+		t.Fatalf("RunAction(conversation.set_persona) failed: %v", err)
+	}
+	if err := app.RunAction("conversation.use_profile:local", nil); err != nil {
+		t.Fatalf("RunAction(conversation.use_profile) failed: %v", err)
+	}
+	settings, _ := app.GetConversationSettings(conv.ID)
+	conv = app.convManager.GetActive()
+	if conv.Title != "Palette" || settings.SystemPrompt != "You are terse." || settings.Profile != "local" {
+		t.Errorf("Unexpected conversation after actions: title %q, settings %+v", conv.Title, settings)
+	}
+
+	app.readOnly = true
+	if find("conversation.new").Enabled {
+		t.Error("Expected new conversation to be disabled in a read-only store")
+	}
+}
+
+func TestApp_ResolveDeepLink(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	req, err := app.resolveDeepLink("agent-desktop://task?text=tidy%20up")
+	if err != nil {
+		t.Fatalf("resolveDeepLink failed: %v", err)
+	}
+	active := app.convManager.GetActive()
+	if active == nil || req.ConversationID != active.ID || req.Text != "tidy up" {
+		t.Fatalf("Expected a new active conversation with the task, got %+v", req)
+	}
+	if err := app.convManager.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	app.convManager.New()
+	req, err = app.resolveDeepLink("agent-desktop://conversation/" + active.ID)
+	if err != nil || req.ConversationID != active.ID || app.convManager.GetActive().ID != active.ID {
+		t.Errorf("Expected the linked conversation to be opened, got %+v, %v", req, err)
+	}
+
+	if _, err := app.resolveDeepLink("agent-desktop://conversation/missing"); err == nil {
+		t.Error("Expected an error for a missing conversation")
+	}
+}
+
+func TestApp_LaunchDeepLink(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	// Before startup the link is kept, then followed once the app is ready
+	app.openDeepLink("agent-desktop://new")
+	if app.TakeLaunchDeepLink() != nil {
+		t.Fatal("Expected no launch link before startup")
+	}
+	app.handleLaunchDeepLink()
+	req := app.TakeLaunchDeepLink()
+	if req == nil || req.ConversationID == "" {
+		t.Fatalf("Expected the launch link to open a conversation, got %+v", req)
+	}
+	if app.TakeLaunchDeepLink() != nil {
+		t.Error("Expected the launch link to be taken only once")
+	}
+}
+
+func TestApp_InspectSession(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	first := app.convManager.New()
+	settings := first.Settings
+	settings.WorkingDir = dir
+	if err := app.convManager.UpdateSettings(first.ID, settings); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+	active := app.convManager.New()
+	tools.GetSession().RecordCommand("echo hi", 0)
+
+	info, err := app.InspectSession("")
+	if err != nil || !info.Active || info.ConversationID != active.ID || len(info.History) != 1 {
+		t.Fatalf("Unexpected inspection of the open conversation: %+v, %v", info, err)
+	}
+
+	other, err := app.InspectSession(first.ID)
+	if err != nil || other.Active || other.CWD != dir || len(other.History) != 0 {
+		t.Errorf("Unexpected inspection of another conversation: %+v, %v", other, err)
+	}
+
+	out := filepath.Join(t.TempDir(), "history.sh")
+	if err := app.ExportSessionHistory(active.ID, out); err != nil {
+		t.Fatalf("ExportSessionHistory failed: %v", err)
+	}
+	data, _ := os.ReadFile(out)
+	if !strings.Contains(string(data), "echo hi") || !strings.Contains(string(data), "exit 0") {
+		t.Errorf("Unexpected export: %q", data)
+	}
+
+	if err := app.ExportSessionHistory(first.ID, out); err == nil {
+		t.Error("Expected an error exporting a conversation that isn't open")
+	}
+}
+
+func TestApp_InspectSession_SavedHistory(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+	defer tools.ResetSession()
+
+	first := app.convManager.New()
+	app.convManager.AddUserMessage("build it")
+	tools.GetSession().RecordCommand("make", 2)
+	app.convManager.AddToolMessage(llm.Message{ToolCallID: "call_1", Content: "make failed"})
+	app.convManager.New()
+
+	info, err := app.InspectSession(first.ID)
+	if err != nil || info.Active || len(info.History) != 1 || info.History[0].Command != "make" || info.History[0].ExitCode != 2 {
+		t.Errorf("Expected the saved history of a closed conversation: %+v, %v", info, err)
+	}
+}
+
+func TestApp_TestConnection_NoConfig(t *testing.T) {
+	app := &App{ctx: context.Background()}
+	d := app.TestConnection()
+	if d.Success || d.Message != "No configuration loaded" {
+		t.Errorf("TestConnection() = %+v, want a failure for missing config", d)
+	}
+}
+
+func TestApp_MCPServers(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if servers := app.GetMCPServers(); servers != nil {
+		t.Errorf("GetMCPServers() before startup = %v, want nil", servers)
+	}
+	if err := app.ReconnectMCPServer("files"); err == nil {
+		t.Error("expected an error before startup")
+	}
+
+	var events []string
+	app.emitter = func(event string, data ...interface{}) { events = append(events, event) }
+	app.mcp = mcp.NewManager(func([]mcp.ServerStatus) { app.emit("mcp:changed") })
+	defer app.mcp.Close()
+	app.mcp.Apply([]config.MCPServer{{Name: "files", Command: "unused", Disabled: true}})
+
+	servers := app.GetMCPServers()
+	if len(servers) != 1 || servers[0].State != mcp.StateDisabled {
+		t.Errorf("GetMCPServers() = %+v, want the disabled server", servers)
+	}
+	if len(events) == 0 || events[0] != "mcp:changed" {
+		t.Errorf("events = %v, want mcp:changed", events)
+	}
+	if err := app.ReconnectMCPServer("missing"); err == nil {
+		t.Error("expected an error for an unknown server")
+	}
+}
+
+func TestApp_SendWebhooks(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+	tools.SetFileChangeHook(app.snapshotFile)
+	defer tools.SetFileChangeHook(nil)
+
+	payloads := make(chan webhook.Payload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhook.Payload
+		json.NewDecoder(r.Body).Decode(&p)
+		payloads <- p
+	}))
+	defer server.Close()
+	app.config.Webhooks = []config.Webhook{{URL: server.URL, Events: []string{config.WebhookComplete}}}
+
+	conv := app.convManager.New()
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if result := tools.WriteFile(path, "hello\n", false); !result.Success {
+		t.Fatalf("WriteFile failed: %s", result.Error)
+	}
+
+	app.sendWebhooks(conv, config.WebhookComplete, "Done", app.takeRunFiles(), 0.02, 1500, time.Now())
+	select {
+	case p := <-payloads:
+		if p.Event != config.WebhookComplete || p.ConversationID != conv.ID || p.Cost != 0.02 || p.Tokens != 1500 {
+			t.Errorf("payload = %+v", p)
+		}
+		if len(p.FilesModified) != 1 || p.FilesModified[0] != path {
+			t.Errorf("files = %v, want %s", p.FilesModified, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the webhook")
+	}
+
+	// The webhook only wants completions
+	app.sendWebhooks(conv, config.WebhookError, "boom", nil, 0, 0, time.Now())
+	select {
+	case p := <-payloads:
+		t.Errorf("unexpected payload %+v", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// fakeChat is a chat platform that answers every approval request.
+type fakeChat struct {
+	b       *bridge.Bridge
+	approve bool
+	asked   []string
+}
+
+func (c *fakeChat) Name() string                                                 { return "fake" }
+func (c *fakeChat) Run(ctx context.Context, h bridge.Handler) error              { return nil }
+func (c *fakeChat) Post(ctx context.Context, channel, thread, text string) error { return nil }
+
+func (c *fakeChat) AskApproval(ctx context.Context, channel, thread, requestID, text string) error {
+	c.asked = append(c.asked, text)
+	go c.b.HandleApproval("fake", requestID, "U1", c.approve)
+	return nil
+}
+
+func TestApp_ApproveToolCall(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	conv := app.convManager.New()
+	startRun(t, app, conv.ID)
+	command := map[string]interface{}{"command": "rm -rf build"}
+	if ok, _ := app.approveToolCall("1", "run_command", command); !ok {
+		t.Error("tool call refused without a chat bridge")
+	}
+
+	// Start the bridge with the conversation already mapped to a thread
+	path := filepath.Join(t.TempDir(), "bridge_threads.json")
+	os.WriteFile(path, []byte(`{"`+conv.ID+`": {"platform": "fake", "channel": "D1"}}`), 0600)
+	app.bridge = bridge.New(apiBackend{app}, path)
+	chat := &fakeChat{b: app.bridge}
+	app.bridge.Add(chat, []string{"U1"}, nil)
+
+	if ok, _ := app.approveToolCall("1", "list_directory", map[string]interface{}{"path": "."}); !ok || len(chat.asked) != 0 {
+		t.Error("a read-only call needed approval under the default policy")
+	}
+	if ok, reason := app.approveToolCall("2", "run_command", command); ok || reason == "" {
+		t.Errorf("approveToolCall = %v, %q, want a denial", ok, reason)
+	}
+	if len(chat.asked) != 1 || !strings.Contains(chat.asked[0], "rm -rf build") {
+		t.Errorf("approval requests = %q", chat.asked)
+	}
+
+	chat.approve = true
+	if ok, _ := app.approveToolCall("3", "run_command", command); !ok {
+		t.Error("approved tool call was refused")
+	}
+
+	// The conversation's own policy wins over the bridge's
+	settings := conv.Settings
+	settings.ApprovalPolicy = conversation.ApprovalPolicyAuto
+	app.convManager.UpdateSettings(conv.ID, settings)
+	chat.approve = false
+	if ok, _ := app.approveToolCall("4", "run_command", command); !ok {
+		t.Error("tool call refused under the auto policy")
+	}
+}
+
+func TestApp_ApproveToolCall_Window(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	tools.SetExternalTools("test", []tools.ExternalTool{{
+		Definition:      tools.ToolDefinition{Type: "function", Function: tools.ToolFunction{Name: "send_note"}},
+		RequireApproval: true,
+	}})
+	defer tools.SetExternalTools("test", nil)
+	conv := app.convManager.New()
+	startRun(t, app, conv.ID)
+	args := map[string]interface{}{"to": "me@example.com"}
+
+	if ok, reason := app.approveToolCall("1", "send_note", args); ok || !strings.Contains(reason, "no window") {
+		t.Errorf("approveToolCall without a window = %v, %q", ok, reason)
+	}
+
+	requests := make(chan ToolApproval, 1)
+	app.emitter = func(event string, data ...interface{}) {
+		if event == "tool:approval" {
+			requests <- data[0].(ToolApproval)
+		}
+	}
+	if ok, _ := app.approveToolCall("2", "list_directory", map[string]interface{}{"path": "."}); !ok {
+		t.Error("an ordinary tool call needed approval")
+	}
+
+	for _, approve := range []bool{true, false} {
+		done := make(chan bool)
+		go func() {
+			ok, _ := app.approveToolCall("3", "send_note", args)
+			done <- ok
+		}()
+		req := <-requests
+		if req.ID != "3" || req.ConversationID != conv.ID || req.Tool != "send_note" || !strings.Contains(req.Description, "me@example.com") {
+			t.Errorf("approval request = %+v", req)
+		}
+		if err := app.AnswerToolApproval(req.ID, approve); err != nil {
+			t.Fatalf("AnswerToolApproval failed: %v", err)
+		}
+		if ok := <-done; ok != approve {
+			t.Errorf("approveToolCall = %v after answering %v", ok, approve)
+		}
+	}
+	if err := app.AnswerToolApproval("3", true); err == nil {
+		t.Error("answering a finished approval should fail")
+	}
+
+	// The conversation's approval policy applies in the window too
+	settings := conv.Settings
+	settings.ApprovalPolicy = conversation.ApprovalPolicyDestructive
+	app.convManager.UpdateSettings(conv.ID, settings)
+	if ok, _ := app.approveToolCall("4", "list_directory", map[string]interface{}{"path": "."}); !ok {
+		t.Error("a read-only call needed approval under the destructive policy")
+	}
+	done := make(chan bool)
+	go func() {
+		ok, _ := app.approveToolCall("5", "run_command", map[string]interface{}{"command": "rm -rf build"})
+		done <- ok
+	}()
+	if req := <-requests; req.ID != "5" || req.Tool != "run_command" {
+		t.Errorf("approval request = %+v", req)
+	}
+	app.AnswerToolApproval("5", false)
+	if <-done {
+		t.Error("a denied destructive call ran")
+	}
+}
+
+func TestApp_GetRecentLogs(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if err := logging.Init(logging.Options{}); err != nil {
+		t.Fatalf("logging.Init failed: %v", err)
+	}
+	logging.For("tools").Warn("something to see", "tool", "read_file")
+	logs := app.GetRecentLogs(1)
+	if len(logs) != 1 || logs[0].Message != "something to see" || logs[0].Component != "tools" || logs[0].Attrs["tool"] != "read_file" {
+		t.Errorf("GetRecentLogs(1) = %+v", logs)
+	}
+}
+
+func TestApp_StreamLogs(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if err := logging.Init(logging.Options{}); err != nil {
+		t.Fatalf("logging.Init failed: %v", err)
+	}
+	streamed := make(chan logging.Entry, 10)
+	app.emitter = func(event string, data ...interface{}) {
+		if event == "logs:append" {
+			streamed <- data[0].(logging.Entry)
+		}
+	}
+
+	if err := app.StreamLogs("loud"); err == nil {
+		t.Error("StreamLogs accepted an unknown level")
+	}
+	if err := app.StreamLogs("warn"); err != nil {
+		t.Fatalf("StreamLogs failed: %v", err)
+	}
+	logging.For("tools").Info("too quiet")
+	logging.For("tools").Warn("worth seeing")
+	select {
+	case e := <-streamed:
+		if e.Message != "worth seeing" || e.Level != "WARN" {
+			t.Errorf("streamed %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no logs:append event")
+	}
+
+	if err := app.StreamLogs("off"); err != nil {
+		t.Fatalf("StreamLogs(off) failed: %v", err)
+	}
+	logging.For("tools").Error("after stopping")
+	select {
+	case e := <-streamed:
+		t.Errorf("streamed %+v after stopping", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestApp_GetPlugins(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if status := app.GetPlugins(); len(status.Plugins) != 0 || status.Dir != config.ToolsDir() {
+		t.Errorf("GetPlugins() before loading = %+v", status)
+	}
+
+	loaded := []plugins.Plugin{{Manifest: plugins.Manifest{Name: "weather", Description: "Get the weather"}}}
+	app.pluginsLoaded(loaded, []error{errors.New("broken.json: invalid manifest")})
+
+	status := app.GetPlugins()
+	if len(status.Plugins) != 1 || status.Plugins[0].Name != "weather" {
+		t.Errorf("plugins = %+v, want weather", status.Plugins)
+	}
+	if len(status.Errors) != 1 || !strings.Contains(status.Errors[0], "broken.json") {
+		t.Errorf("errors = %v, want the load error", status.Errors)
+	}
+}
+
+func TestApp_ExportRunAsScript(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	conv := app.convManager.New()
+	app.convManager.AddAssistantMessage(llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{
+		{ID: "1", Name: "run_command", Arguments: `{"command": "echo hi"}`},
+	}})
+
+	dir := t.TempDir()
+	for _, name := range []string{"replay.sh", "replay.ps1"} {
+		path := filepath.Join(dir, name)
+		if err := app.ExportRunAsScript(conv.ID, path); err != nil {
+			t.Fatalf("ExportRunAsScript(%s) failed: %v", name, err)
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), "echo hi") {
+			t.Errorf("%s = %q, want the command", name, data)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "replay.ps1")); !strings.Contains(string(data), "cmd /c 'echo hi'") {
+		t.Errorf("replay.ps1 = %q, want PowerShell", data)
+	}
+
+	if err := app.ExportRunAsScript("missing", filepath.Join(dir, "x.sh")); err == nil {
+		t.Error("expected an error for an unknown conversation")
+	}
+}
+
+func TestApp_GetProviderHealth(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"message": "bad key"}}`))
+	}))
+	defer server.Close()
+	app.config.Profiles = []config.Profile{{Name: "health-check", Endpoint: server.URL, APIKey: "key", Model: "gpt-4o"}}
+
+	client := app.clientForProfile("health-check")
+	if _, err := client.ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "hi"}}, nil); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	for _, h := range app.GetProviderHealth() {
+		if h.Profile == "health-check" {
+			if h.Endpoint != server.URL || h.Failures != 1 || h.LastErrorKind != llm.ErrorAuth {
+				t.Errorf("provider health = %+v", h)
+			}
+			return
+		}
+	}
+	t.Errorf("profile missing from %+v", app.GetProviderHealth())
+}
+
+func TestApp_GetLLMTrace(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hello"}}]}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "sk-trace-test-5678", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+
+	app.ClearLLMTrace()
+	llm.SetCapture(true)
+	defer llm.SetCapture(false)
+	defer app.ClearLLMTrace()
+
+	app.convManager.New()
+	app.convManager.AddUserMessage("Hi")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+	trace := app.GetLLMTrace()
+	if len(trace) != 1 || !strings.Contains(trace[0].RequestBody, "Hi") || trace[0].Status != 200 {
+		t.Fatalf("trace = %+v", trace)
+	}
+	if strings.Contains(trace[0].RequestHeaders["Authorization"], "sk-trace-test") {
+		t.Error("expected the API key to be masked")
+	}
+}
+
+func TestApp_ExportDebugBundle(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+	app.config.APIKey = "sk-debug-bundle-key"
+
+	conv := app.convManager.New()
+	app.convManager.AddUserMessage("my key is sk-debug-bundle-key")
+
+	path := filepath.Join(t.TempDir(), "debug.zip")
+	if err := app.ExportDebugBundle(conv.ID, path); err != nil {
+		t.Fatalf("ExportDebugBundle failed: %v", err)
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if strings.Contains(string(data), "sk-debug-bundle-key") {
+			t.Errorf("%s contains the API key", f.Name)
+		}
+	}
+	if len(zr.File) == 0 {
+		t.Error("expected files in the bundle")
+	}
+
+	if err := app.ExportDebugBundle("missing", path); err == nil {
+		t.Error("expected an error for an unknown conversation")
+	}
+}
+
+func TestApp_ReindexWorkspace(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if err := app.ReindexWorkspace(); err == nil {
+		t.Error("expected an error with no workspace indexed")
+	}
+	if st := app.GetIndexStatus(); st.Root != "" {
+		t.Errorf("status with no workspace = %+v", st)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"index": 0, "embedding": [1, 0]}]}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "notes.md"), []byte("# Notes\n"), 0644)
+	app.index, _ = index.Open(root, filepath.Join(t.TempDir(), "index.json"), app.embedder())
+
+	var events []string
+	app.emitter = func(event string, data ...interface{}) { events = append(events, event) }
+	if err := app.ReindexWorkspace(); err != nil {
+		t.Fatalf("ReindexWorkspace failed: %v", err)
+	}
+	if st := app.GetIndexStatus(); st.Root != root || st.Files != 1 || st.Chunks != 1 {
+		t.Errorf("status = %+v", st)
+	}
+	if len(events) != 1 || events[0] != "index:updated" {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestApp_Memories(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if list := app.ListMemories(); len(list) != 0 {
+		t.Errorf("memories without a store = %+v", list)
+	}
+	if err := app.DeleteMemory("missing"); err == nil {
+		t.Error("expected an error without a store")
+	}
+
+	app.memories, _ = memory.Open(filepath.Join(t.TempDir(), "memories.json"))
+	m, err := app.memories.Add("The user's editor is Helix", memory.ScopeGlobal, "")
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if list := app.ListMemories(); len(list) != 1 || list[0].Fact != m.Fact {
+		t.Errorf("ListMemories() = %+v", list)
+	}
+	if err := app.DeleteMemory(m.ID); err != nil {
+		t.Fatalf("DeleteMemory failed: %v", err)
+	}
+	if list := app.ListMemories(); len(list) != 0 {
+		t.Errorf("ListMemories() after deleting = %+v", list)
+	}
+}
+
+func TestApp_UserProfile(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if err := app.SaveUserProfile(memory.Profile{Shell: "fish"}); err == nil {
+		t.Error("expected an error without a store")
+	}
+
+	app.memories, _ = memory.Open(filepath.Join(t.TempDir(), "memories.json"))
+	if err := app.SaveUserProfile(memory.Profile{Shell: " fish ", Directories: []string{"~/src", "", "~/src"}}); err != nil {
+		t.Fatalf("SaveUserProfile failed: %v", err)
+	}
+	p := app.GetUserProfile()
+	if p.Shell != "fish" || len(p.Directories) != 1 {
+		t.Errorf("GetUserProfile() = %+v", p)
+	}
+}
+
+func TestApp_RollingSummary(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		prompt = string(body)
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "- Moving the blog to Hugo; theme done, redirects next"}}]}`))
+	}))
+	defer server.Close()
+	app.client, _ = llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	app.memories, _ = memory.Open(filepath.Join(t.TempDir(), "memories.json"))
+	app.memories.SetProfile(memory.Profile{Shell: "fish"})
+
+	// Off by default
+	app.updateSummary(context.Background(), "Port the blog theme to Hugo", "Done")
+	if prompt != "" || strings.Contains(app.userContext(), "LATELY") {
+		t.Error("expected no summary unless rolling_summary is on")
+	}
+
+	app.config.RollingSummary = true
+	app.updateSummary(context.Background(), "Port the blog theme to Hugo", "Done")
+	if !strings.Contains(prompt, "Port the blog theme to Hugo") {
+		t.Errorf("summary prompt = %s", prompt)
+	}
+	if got := app.userContext(); !strings.Contains(got, "Preferred shell: fish") || !strings.Contains(got, "redirects next") {
+		t.Errorf("userContext() = %q", got)
+	}
+
+	if err := app.SaveRecentWorkSummary(""); err != nil {
+		t.Fatalf("SaveRecentWorkSummary failed: %v", err)
+	}
+	if summary := app.GetRecentWorkSummary(); summary.Text != "" || strings.Contains(app.userContext(), "LATELY") {
+		t.Errorf("summary after clearing = %+v", summary)
+	}
+}
+
+func TestApp_SemanticSearchConversations(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	if _, err := app.SemanticSearchConversations("printer"); err == nil {
+		t.Error("expected an error without an LLM client")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var data []map[string]interface{}
+		for i, text := range req.Input {
+			v := []float32{0.1, 0.1}
+			if strings.Contains(strings.ToLower(text), "printer") {
+				v[0] = 1
+			}
+			data = append(data, map[string]interface{}{"index": i, "embedding": v})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+
+	conv := app.convManager.New()
+	app.convManager.AddUserMessage("Fix the printer")
+	app.convManager.AddUserMessage("Plan lunch")
+
+	matches, err := app.SemanticSearchConversations("printer trouble")
+	if err != nil {
+		t.Fatalf("SemanticSearchConversations failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].ConversationID != conv.ID || matches[0].Question != "Fix the printer" {
+		t.Errorf("matches = %+v", matches)
+	}
+}
+
+func TestApp_AutoContext(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	var chats [][]llm.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/embeddings") {
+			var req struct {
+				Input []string `json:"input"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			var data []map[string]interface{}
+			for i, text := range req.Input {
+				v := []float32{0.1, 0.1}
+				if strings.Contains(strings.ToLower(text), "retry") {
+					v[0] = 1
+				}
+				data = append(data, map[string]interface{}{"index": i, "embedding": v})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+			return
+		}
+		var req struct {
+			Messages []llm.Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		chats = append(chats, req.Messages)
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Done"}}]}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+
+	root := filepath.Join(t.TempDir(), "payments")
+	os.MkdirAll(root, 0755)
+	os.WriteFile(filepath.Join(root, "retry.go"), []byte("// retry with backoff\n"), 0644)
+	os.WriteFile(filepath.Join(root, "ui.go"), []byte("// buttons\n"), 0644)
+	app.index, _ = index.Open(root, "", app.embedder())
+	app.index.Update(context.Background())
+
+	var steps []agent.Step
+	app.emitter = func(event string, data ...interface{}) {
+		if step, ok := data[0].(agent.Step); ok && event == "agent:step" {
+			steps = append(steps, step)
+		}
+	}
+
+	app.convManager.New()
+	app.convManager.AddUserMessage("Make the retry in payments wait longer")
+	if err := runActive(t, app); err != nil {
+		t.Fatalf("runConversation failed: %v", err)
+	}
+	sent := chats[0]
+	if len(sent) != 3 || !strings.Contains(sent[1].Content, "retry with backoff") || sent[2].Content != "Make the retry in payments wait longer" {
+		t.Errorf("messages sent = %+v", sent)
+	}
+	if len(steps) == 0 || steps[0].Type != agent.StepTypeContext || !strings.Contains(steps[0].Content, "retry.go:1-1") {
+		t.Errorf("first step = %+v", steps)
+	}
+	// The context is only for this request, not saved with the conversation
+	if msgs := app.convManager.GetMessages(); len(msgs) != 3 || msgs[1].Role != "user" || msgs[1].Content != "Make the retry in payments wait longer" {
+		t.Errorf("saved messages = %+v", msgs)
+	}
+
+	// Tasks that don't mention the folder, or with auto context off, get none
+	app.convManager.AddUserMessage("What's 2+2?")
+	runActive(t, app)
+	app.config.DisableAutoContext = true
+	app.convManager.AddUserMessage("Now look at payments again")
+	runActive(t, app)
+	if len(chats) != 3 {
+		t.Fatalf("made %d chat requests, want 3", len(chats))
+	}
+	for _, sent := range chats[1:] {
+		for _, msg := range sent {
+			if strings.Contains(msg.Content, "found by semantic search") {
+				t.Errorf("context added to %+v", sent)
+			}
+		}
+	}
+}
+
+func TestApp_QueueMaintenance(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	calls := 0
+	release := make(chan struct{})
+	manager := conversation.NewManager(app.convManager.GetStore(), &MockLLMClient{
+		ChatCompletionFunc: func(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error) {
+			<-release
+			calls++
+			return &llm.Response{Content: "Cleaning Downloads"}, nil
+		},
+	}, "Test system prompt")
+	app.convManager = manager
+	conv := manager.New()
+	manager.AddUserMessage("Clean up my downloads folder")
+	run, err := manager.BeginRun(conv.ID)
+	if err != nil {
+		t.Fatalf("BeginRun failed: %v", err)
+	}
+	defer run.End()
+
+	// Runs ending in quick succession title the conversation once
+	app.jobs.Submit("", func(context.Context) { <-release })
+	app.queueMaintenance(run, "Clean up my downloads folder", "Done")
+	app.queueMaintenance(run, "Clean up my downloads folder", "Done again")
+	close(release)
+	app.jobs.Wait()
+
+	if calls != 1 {
+		t.Errorf("LLM asked %d times, want once", calls)
+	}
+	if title := manager.GetActive().Title; title != "Cleaning Downloads" {
+		t.Errorf("title = %q", title)
+	}
+}
+
+func TestApp_WarmStart(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	requests := make(chan []byte, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- body
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hi"}}]}`))
+	}))
+	defer server.Close()
+	client, err := llm.NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", ExecutionTimeout: 60})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	app.client = client
+
+	// Off by default
+	app.NewConversation()
+	app.config.WarmStart = true
+	conv := app.NewConversation()
+
+	select {
+	case body := <-requests:
+		if !strings.Contains(string(body), `"max_tokens":1`) {
+			t.Errorf("warm-up request = %s", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no warm-up request was sent")
+	}
+
+	// Opening a conversation with the same prompt right after doesn't warm again
+	if _, err := app.OpenConversation(conv.ID); err != nil {
+		t.Fatal(err)
+	}
+	app.warmMu.Lock()
+	warmed := len(app.warmed)
+	app.warmMu.Unlock()
+	if warmed != 1 || len(requests) != 0 {
+		t.Errorf("warmed %d prompts with %d more requests, want 1 and none", warmed, len(requests))
+	}
+}
@@ -286,7 +286,10 @@ function App() {
 
   const handleTestConnection = useCallback(async () => {
     try {
-      const result = await TestConnection();
+      const result: any = await TestConnection();
+      if (result && typeof result === 'object' && 'success' in result) {
+        return { success: Boolean(result.success), message: String(result.message) };
+      }
       if (Array.isArray(result)) {
         return { success: result[0] as boolean, message: result[1] as string };
       }
@@ -0,0 +1,112 @@
+// Package actions describes the commands the app offers in its command
+// palette. The backend builds a Registry from its current state, so the
+// palette lists exactly what can be done right now and runs backend
+// actions through the same catalog.
+package actions
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind says where an action runs.
+type Kind string
+
+// Action kinds.
+const (
+	KindBackend  Kind = "backend"  // Run with App.RunAction
+	KindFrontend Kind = "frontend" // Handled by the UI itself, e.g. opening a dialog
+)
+
+// ErrUnknown is returned when running an action that isn't in the catalog.
+var ErrUnknown = errors.New("unknown action")
+
+// Param is an argument the palette asks for before running an action.
+type Param struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// Action is a palette entry.
+type Action struct {
+	ID             string  `json:"id"` // Stable identifier, e.g. "conversation.new"
+	Title          string  `json:"title"`
+	Category       string  `json:"category"`
+	Description    string  `json:"description,omitempty"`
+	Shortcut       string  `json:"shortcut,omitempty"` // Suggested key binding, e.g. "Ctrl+N"
+	Kind           Kind    `json:"kind"`
+	Params         []Param `json:"params,omitempty"`
+	Enabled        bool    `json:"enabled"`
+	DisabledReason string  `json:"disabled_reason,omitempty"`
+}
+
+// Handler runs a backend action with the arguments collected for its
+// params.
+type Handler func(args map[string]string) error
+
+// Registry is the catalog of actions available at one moment.
+type Registry struct {
+	actions  []Action
+	handlers map[string]Handler
+}
+
+// NewRegistry creates an empty catalog.
+func NewRegistry() *Registry {
+	return &Registry{handlers: map[string]Handler{}}
+}
+
+// Add adds a backend action run by h. It is enabled unless Disable is
+// called for it.
+func (r *Registry) Add(a Action, h Handler) {
+	a.Kind, a.Enabled = KindBackend, true
+	r.actions = append(r.actions, a)
+	r.handlers[a.ID] = h
+}
+
+// AddFrontend adds an action the UI handles itself.
+func (r *Registry) AddFrontend(a Action) {
+	a.Kind, a.Enabled = KindFrontend, true
+	r.actions = append(r.actions, a)
+}
+
+// Disable marks the actions with the given IDs as unavailable, with a
+// reason shown in the palette.
+func (r *Registry) Disable(reason string, ids ...string) {
+	for i := range r.actions {
+		for _, id := range ids {
+			if r.actions[i].ID == id {
+				r.actions[i].Enabled = false
+				r.actions[i].DisabledReason = reason
+			}
+		}
+	}
+}
+
+// List returns the catalog in the order actions were added.
+func (r *Registry) List() []Action {
+	return append([]Action(nil), r.actions...)
+}
+
+// Run runs a backend action after checking that it is enabled and that its
+// required params are present.
+func (r *Registry) Run(id string, args map[string]string) error {
+	for _, a := range r.actions {
+		if a.ID != id {
+			continue
+		}
+		if a.Kind != KindBackend {
+			return fmt.Errorf("action %s is handled by the UI", id)
+		}
+		if !a.Enabled {
+			return fmt.Errorf("action %s is unavailable: %s", id, a.DisabledReason)
+		}
+		for _, p := range a.Params {
+			if p.Required && args[p.Name] == "" {
+				return fmt.Errorf("action %s requires %s", id, p.Name)
+			}
+		}
+		return r.handlers[id](args)
+	}
+	return fmt.Errorf("%w: %s", ErrUnknown, id)
+}
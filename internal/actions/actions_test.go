@@ -0,0 +1,52 @@
+package actions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistry_Run(t *testing.T) {
+	r := NewRegistry()
+	var got string
+	r.Add(Action{ID: "conversation.rename", Params: []Param{{Name: "title", Required: true}}}, func(args map[string]string) error {
+		got = args["title"]
+		return nil
+	})
+	r.Add(Action{ID: "agent.stop"}, func(map[string]string) error { return nil })
+	r.AddFrontend(Action{ID: "settings.open"})
+	r.Disable("The agent is not running", "agent.stop")
+
+	if err := r.Run("conversation.rename", map[string]string{"title": "Release notes"}); err != nil || got != "Release notes" {
+		t.Errorf("Run() = %v, title %q", err, got)
+	}
+	if err := r.Run("conversation.rename", nil); err == nil {
+		t.Error("expected an error for a missing required param")
+	}
+	if err := r.Run("agent.stop", nil); err == nil {
+		t.Error("expected an error for a disabled action")
+	}
+	if err := r.Run("settings.open", nil); err == nil {
+		t.Error("expected an error for a frontend action")
+	}
+	if err := r.Run("missing", nil); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Run(missing) = %v, want ErrUnknown", err)
+	}
+}
+
+func TestRegistry_List(t *testing.T) {
+	r := NewRegistry()
+	r.Add(Action{ID: "a"}, func(map[string]string) error { return nil })
+	r.AddFrontend(Action{ID: "b"})
+	r.Disable("no", "a")
+
+	list := r.List()
+	if len(list) != 2 || list[0].ID != "a" || list[1].ID != "b" {
+		t.Fatalf("List() = %+v, want a then b", list)
+	}
+	if list[0].Enabled || list[0].DisabledReason != "no" || list[0].Kind != KindBackend {
+		t.Errorf("unexpected backend action: %+v", list[0])
+	}
+	if !list[1].Enabled || list[1].Kind != KindFrontend {
+		t.Errorf("unexpected frontend action: %+v", list[1])
+	}
+}
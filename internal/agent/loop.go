@@ -4,11 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"time"
 
+	"agent-desktop/internal/crash"
+	"agent-desktop/internal/i18n"
 	"agent-desktop/internal/llm"
+	"agent-desktop/internal/logging"
 	"agent-desktop/internal/tools"
+	"agent-desktop/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// logger records how runs go.
+var logger = logging.For("agent")
+
 // Client interface for the LLM client (allows mocking in tests)
 type Client interface {
 	ChatCompletion(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error)
@@ -21,6 +32,15 @@ func RunLoop(ctx context.Context, client Client, task string, taskContext string
 
 	go func() {
 		defer close(steps)
+		stepNumber := 0
+		defer crash.Recover("agent loop", func(r *crash.Report) {
+			steps <- NewErrorStep(stepNumber, i18n.T("agent.error", r.Message()))
+		})
+		ctx, span := tracing.Start(ctx, "agent.run", attribute.Int("agent.max_steps", maxSteps))
+		defer func() {
+			span.SetAttributes(attribute.Int("agent.steps", stepNumber))
+			span.End()
+		}()
 
 		// Reset session for fresh start
 		tools.ResetSession()
@@ -32,7 +52,6 @@ func RunLoop(ctx context.Context, client Client, task string, taskContext string
 		}
 
 		toolDefs := tools.GetToolDefinitions()
-		stepNumber := 0
 		consecutiveTextResponses := 0
 		maxTextResponses := 2
 
@@ -42,7 +61,7 @@ func RunLoop(ctx context.Context, client Client, task string, taskContext string
 			// Check context cancellation
 			select {
 			case <-ctx.Done():
-				steps <- NewErrorStep(stepNumber, "Task cancelled")
+				steps <- failRun(span, NewErrorStep(stepNumber, i18n.T("agent.cancelled")))
 				return
 			default:
 			}
@@ -50,7 +69,8 @@ func RunLoop(ctx context.Context, client Client, task string, taskContext string
 			// Call LLM
 			resp, err := client.ChatCompletion(ctx, messages, toolDefs)
 			if err != nil {
-				steps <- NewErrorStep(stepNumber, "Error: "+err.Error())
+				logger.Error("model request failed", "step", stepNumber, "error", err)
+				steps <- failRun(span, NewModelErrorStep(stepNumber, err))
 				return
 			}
 
@@ -92,14 +112,17 @@ func RunLoop(ctx context.Context, client Client, task string, taskContext string
 					// Parse tool arguments
 					var toolArgs map[string]interface{}
 					if err := json.Unmarshal([]byte(tc.Arguments), &toolArgs); err != nil {
+						logger.Warn("model sent malformed tool arguments", "tool", tc.Name, "error", err)
 						toolArgs = make(map[string]interface{})
 					}
 
 					// Emit tool call step
-					steps <- NewToolCallStep(stepNumber, tc.Name, toolArgs)
+					callStep := NewToolCallStep(stepNumber, tc.Name, toolArgs)
+					callStep.ToolCallID = tc.ID
+					steps <- callStep
 
 					// Execute the tool
-					result := tools.ExecuteTool(tc.Name, toolArgs)
+					result, took := executeTool(ctx, stepNumber, tc, toolArgs)
 
 					// Add tool result to messages
 					resultContent := result.Output
@@ -113,7 +136,10 @@ func RunLoop(ctx context.Context, client Client, task string, taskContext string
 					})
 
 					// Emit tool result step
-					steps <- NewToolResultStep(stepNumber, tc.Name, &result)
+					resultStep := NewToolResultStep(stepNumber, tc.Name, &result)
+					resultStep.ToolCallID = tc.ID
+					resultStep.DurationMs = took.Milliseconds()
+					steps <- resultStep
 
 					// Check if task_complete was called
 					if tc.Name == "task_complete" {
@@ -149,14 +175,14 @@ func RunLoop(ctx context.Context, client Client, task string, taskContext string
 					})
 				} else {
 					// Empty response - something went wrong
-					steps <- NewErrorStep(stepNumber, "Received empty response from model")
+					steps <- failRun(span, NewErrorStep(stepNumber, i18n.T("agent.empty_response")))
 					return
 				}
 			}
 		}
 
 	// Max steps reached
-	steps <- NewErrorStep(stepNumber, "Maximum steps reached without completing the task")
+	steps <- failRun(span, NewErrorStep(stepNumber, i18n.T("agent.max_steps_incomplete")))
 	}()
 
 	return steps
@@ -174,13 +200,21 @@ func ContinueConversation(ctx context.Context, client Client, messages []llm.Mes
 
 	go func() {
 		defer close(steps)
+		stepNumber := 0
+		defer crash.Recover("agent loop", func(r *crash.Report) {
+			steps <- NewErrorStep(stepNumber, i18n.T("agent.error", r.Message()))
+		})
+		ctx, span := tracing.Start(ctx, "agent.run", attribute.Int("agent.max_steps", maxSteps))
+		defer func() {
+			span.SetAttributes(attribute.Int("agent.steps", stepNumber))
+			span.End()
+		}()
 
 		// Make a copy of messages to avoid mutating the input
 		msgs := make([]llm.Message, len(messages))
 		copy(msgs, messages)
 
 		toolDefs := tools.GetToolDefinitions()
-		stepNumber := 0
 
 		for stepNumber < maxSteps {
 			stepNumber++
@@ -188,7 +222,7 @@ func ContinueConversation(ctx context.Context, client Client, messages []llm.Mes
 			// Check context cancellation
 			select {
 			case <-ctx.Done():
-				steps <- NewErrorStep(stepNumber, "Task cancelled")
+				steps <- failRun(span, NewErrorStep(stepNumber, i18n.T("agent.cancelled")))
 				return
 			default:
 			}
@@ -196,7 +230,8 @@ func ContinueConversation(ctx context.Context, client Client, messages []llm.Mes
 			// Call LLM
 			resp, err := client.ChatCompletion(ctx, msgs, toolDefs)
 			if err != nil {
-				steps <- NewErrorStep(stepNumber, "Error: "+err.Error())
+				logger.Error("model request failed", "step", stepNumber, "error", err)
+				steps <- failRun(span, NewModelErrorStep(stepNumber, err))
 				return
 			}
 
@@ -216,6 +251,7 @@ func ContinueConversation(ctx context.Context, client Client, messages []llm.Mes
 					Role:    "assistant",
 					Content: resp.Content,
 					ToolCalls: make([]llm.ToolCall, len(resp.ToolCalls)),
+					Meta:    newMessageMeta(stepNumber, resp.Usage),
 				}
 				for i, tc := range resp.ToolCalls {
 					assistantMsg.ToolCalls[i] = llm.ToolCall{
@@ -236,14 +272,17 @@ func ContinueConversation(ctx context.Context, client Client, messages []llm.Mes
 					// Parse tool arguments
 					var toolArgs map[string]interface{}
 					if err := json.Unmarshal([]byte(tc.Arguments), &toolArgs); err != nil {
+						logger.Warn("model sent malformed tool arguments", "tool", tc.Name, "error", err)
 						toolArgs = make(map[string]interface{})
 					}
 
 					// Emit tool call step
-					steps <- NewToolCallStep(stepNumber, tc.Name, toolArgs)
+					callStep := NewToolCallStep(stepNumber, tc.Name, toolArgs)
+					callStep.ToolCallID = tc.ID
+					steps <- callStep
 
 					// Execute the tool
-					result := tools.ExecuteTool(tc.Name, toolArgs)
+					result, took := executeTool(ctx, stepNumber, tc, toolArgs)
 
 					// Add tool result to messages
 					resultContent := result.Output
@@ -254,10 +293,13 @@ func ContinueConversation(ctx context.Context, client Client, messages []llm.Mes
 						Role:       "tool",
 						Content:    resultContent,
 						ToolCallID: tc.ID,
+						Meta:       newMessageMeta(stepNumber, nil),
 					})
 
 					// Emit tool result step with updated messages
 					toolResultStep := NewToolResultStep(stepNumber, tc.Name, &result)
+					toolResultStep.ToolCallID = tc.ID
+					toolResultStep.DurationMs = took.Milliseconds()
 					toolResultStep.Messages = msgs
 					steps <- toolResultStep
 
@@ -276,6 +318,7 @@ func ContinueConversation(ctx context.Context, client Client, messages []llm.Mes
 					msgs = append(msgs, llm.Message{
 						Role:    "assistant",
 						Content: resp.Content,
+						Meta:    newMessageMeta(stepNumber, resp.Usage),
 					})
 
 					// In conversation mode, text responses are just messages, not completions
@@ -284,17 +327,53 @@ func ContinueConversation(ctx context.Context, client Client, messages []llm.Mes
 					return
 				} else {
 					// Empty response
-					steps <- NewErrorStep(stepNumber, "Received empty response from model")
+					steps <- failRun(span, NewErrorStep(stepNumber, i18n.T("agent.empty_response")))
 					return
 				}
 			}
 		}
 
 		// Max steps reached
-		errorStep := NewErrorStep(stepNumber, "Maximum steps reached")
+		errorStep := NewErrorStep(stepNumber, i18n.T("agent.max_steps"))
 		errorStep.Messages = msgs
-		steps <- errorStep
+		steps <- failRun(span, errorStep)
 	}()
 
 	return steps
 }
+
+// executeTool runs a tool call in a span of its own and returns its result
+// and how long it took.
+func executeTool(ctx context.Context, stepNumber int, tc llm.ToolCall, args map[string]interface{}) (tools.ToolResult, time.Duration) {
+	_, span := tracing.Start(ctx, "tool "+tc.Name,
+		attribute.String("tool.name", tc.Name),
+		attribute.String("tool.call_id", tc.ID),
+		attribute.Int("agent.step", stepNumber),
+	)
+	defer span.End()
+
+	start := time.Now()
+	result := tools.ExecuteToolCall(tc.ID, tc.Name, args)
+	took := time.Since(start)
+	span.SetAttributes(attribute.Bool("tool.success", result.Success), attribute.Int("tool.output_bytes", len(result.Output)))
+	if !result.Success {
+		tracing.Fail(span, result.Error)
+	}
+	return result, took
+}
+
+// failRun marks the run's span as failed with the error step that ends it.
+func failRun(span trace.Span, step Step) Step {
+	tracing.Fail(span, step.Content)
+	return step
+}
+
+// newMessageMeta stamps a message produced at the given step with the
+// current time and the usage of the LLM call that produced it, if any.
+func newMessageMeta(stepNumber int, usage *llm.TokenUsage) *llm.MessageMeta {
+	return &llm.MessageMeta{
+		Timestamp: time.Now(),
+		Usage:     usage,
+		Step:      stepNumber,
+	}
+}
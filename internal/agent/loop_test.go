@@ -2,11 +2,21 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"agent-desktop/internal/llm"
+	"agent-desktop/internal/llmtest"
 	"agent-desktop/internal/tools"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // mockClient is a mock LLM client for testing
@@ -447,3 +457,157 @@ func TestContinueConversation_ToolCallsWork(t *testing.T) {
 		t.Error("Should emit tool_result step")
 	}
 }
+
+func TestContinueConversation_RecordsMessageMeta(t *testing.T) {
+	client := &mockClient{
+		responses: []mockResponse{
+			{
+				toolCalls: []llm.ToolCall{
+					{ID: "call_1", Name: "get_current_directory", Arguments: `{}`},
+				},
+			},
+			{content: "You are in your home directory."},
+		},
+	}
+
+	tools.ResetSession()
+	existingMessages := []llm.Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "Where am I?"},
+	}
+
+	var finalMessages []llm.Message
+	for step := range ContinueConversation(context.Background(), client, existingMessages, 20) {
+		if step.Messages != nil {
+			finalMessages = step.Messages
+		}
+	}
+
+	if len(finalMessages) != 5 {
+		t.Fatalf("Expected 5 messages, got %d", len(finalMessages))
+	}
+	for i, msg := range finalMessages[2:] {
+		if msg.Meta == nil || msg.Meta.Timestamp.IsZero() {
+			t.Fatalf("Expected message %d to have a timestamp", i+2)
+		}
+	}
+
+	toolCallMsg, toolMsg, replyMsg := finalMessages[2], finalMessages[3], finalMessages[4]
+	if toolCallMsg.Meta.Step != 1 || toolMsg.Meta.Step != 1 || replyMsg.Meta.Step != 2 {
+		t.Errorf("Unexpected step linkage: %d, %d, %d", toolCallMsg.Meta.Step, toolMsg.Meta.Step, replyMsg.Meta.Step)
+	}
+	if replyMsg.Meta.Usage == nil || replyMsg.Meta.Usage.TotalTokens != 15 {
+		t.Errorf("Expected reply to record its usage, got %+v", replyMsg.Meta.Usage)
+	}
+	if toolMsg.Meta.Usage != nil {
+		t.Error("Expected tool result to carry no usage")
+	}
+}
+
+// panickingClient panics on every call, like a buggy tool or client would.
+type panickingClient struct{}
+
+func (panickingClient) ChatCompletion(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error) {
+	panic("boom")
+}
+
+func TestContinueConversation_RecoversPanic(t *testing.T) {
+	messages := []llm.Message{{Role: "user", Content: "Hello"}}
+
+	var steps []Step
+	for step := range ContinueConversation(context.Background(), panickingClient{}, messages, 5) {
+		steps = append(steps, step)
+	}
+
+	if len(steps) == 0 {
+		t.Fatal("Expected an error step")
+	}
+	last := steps[len(steps)-1]
+	if last.Type != StepTypeError || !strings.Contains(last.Content, "boom") {
+		t.Errorf("Expected error step mentioning the panic, got %+v", last)
+	}
+}
+
+func TestRunLoop_TracesRunAndTools(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	client := &mockClient{
+		responses: []mockResponse{
+			{toolCalls: []llm.ToolCall{{ID: "call_1", Name: "get_current_directory", Arguments: `{}`}}},
+			{toolCalls: []llm.ToolCall{{ID: "call_2", Name: "task_complete", Arguments: `{"summary": "Done"}`}}},
+		},
+	}
+	tools.ResetSession()
+	for range RunLoop(context.Background(), client, "Get current directory", "", 20) {
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	run, tool := spans["agent.run"], spans["tool get_current_directory"]
+	if run == nil || tool == nil {
+		t.Fatalf("spans = %v, want agent.run and tool get_current_directory", spans)
+	}
+	if tool.Parent().SpanID() != run.SpanContext().SpanID() {
+		t.Error("expected the tool span to be a child of the run span")
+	}
+	if run.Status().Code == codes.Error {
+		t.Errorf("run status = %+v, want it not failed", run.Status())
+	}
+}
+
+func TestRunLoop_TracesFailedRun(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	client := &mockClient{responses: []mockResponse{{err: errors.New("connection refused")}}}
+	for range RunLoop(context.Background(), client, "Task", "", 20) {
+	}
+
+	ended := recorder.Ended()
+	if len(ended) != 1 || ended[0].Name() != "agent.run" {
+		t.Fatalf("expected one agent.run span, got %d", len(ended))
+	}
+	if ended[0].Status().Code != codes.Error {
+		t.Errorf("run status = %+v, want failed", ended[0].Status())
+	}
+}
+
+func TestRunLoop_MockServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	server := llmtest.NewServer(
+		llmtest.Call("write_file", map[string]any{"path": path, "content": "hello"}),
+		llmtest.Call("task_complete", map[string]any{"summary": "Wrote hello.txt"}),
+	)
+	defer server.Close()
+	client, err := llm.NewClient(server.Config())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	tools.ResetSession()
+	var complete *Step
+	for step := range RunLoop(context.Background(), client, "Write hello to hello.txt", "", 5) {
+		if step.Type == StepTypeComplete {
+			complete = &step
+		}
+	}
+
+	if complete == nil || !strings.Contains(complete.Content, "Wrote hello.txt") {
+		t.Fatalf("complete step = %+v", complete)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "hello" {
+		t.Errorf("file = %q, want the agent to have written it", data)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("model called %d times, want 2", len(requests))
+	}
+	if last := requests[1].LastMessage(); last.Role != "tool" || last.ToolCallID != "call_1" || !strings.Contains(last.Content, "Wrote") {
+		t.Errorf("second request ends with %+v, want the write_file result", last)
+	}
+}
@@ -1,10 +1,33 @@
 package agent
 
 import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
 )
 
+// systemPromptFile returns the path of the user's system prompt template.
+// It is a variable so tests can redirect it.
+var systemPromptFile = func() string {
+	return filepath.Join(config.Dir(), config.SystemPromptFile)
+}
+
+// userProfile returns what the system prompt should say about the user.
+var userProfile func() string
+
+// SetUserProfileFunc sets the source of the {PROFILE} template variable. A
+// nil fn leaves it empty.
+func SetUserProfileFunc(fn func() string) {
+	userProfile = fn
+}
+
 // GetOSInstructions returns OS-specific instructions for the system prompt.
 func GetOSInstructions() string {
 	switch runtime.GOOS {
@@ -17,7 +40,21 @@ func GetOSInstructions() string {
 	}
 }
 
-// systemPromptTemplate is the template for the system prompt.
+// osName returns a human-readable name for the current OS.
+func osName() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "macOS"
+	case "windows":
+		return "Windows"
+	default:
+		return "Linux"
+	}
+}
+
+// systemPromptTemplate is the default template for the system prompt.
+// Templates may use {OS_INSTRUCTIONS}, {OS}, {CWD}, {DATE}, {USERNAME}, and
+// {PROFILE}; the profile goes at the end of templates that leave it out.
 const systemPromptTemplate = `You are an AI assistant that helps users accomplish tasks by executing commands and managing files.
 
 You have access to the following tools:
@@ -30,6 +67,7 @@ You have access to the following tools:
 - delete_file: Delete a file (requires confirm=True)
 - copy_file: Copy a file to a new location
 - move_file: Move or rename a file
+- copy_files / move_files: Copy or move many files in one call, by glob pattern or as source and destination pairs
 - task_complete: Signal that the task is finished
 
 CRITICAL RULES:
@@ -39,7 +77,7 @@ CRITICAL RULES:
 4. Break complex tasks into smaller steps
 5. If a command fails, try to understand why and fix it
 6. Be careful with destructive operations - list files before deleting
-7. Prefer using delete_file, copy_file, move_file over shell commands when possible
+7. Prefer using delete_file, copy_file, move_file over shell commands when possible, and copy_files or move_files over one call per file
 8. Always set confirm=True when calling delete_file after verifying the file to delete
 
 {OS_INSTRUCTIONS}
@@ -49,9 +87,82 @@ WORKFLOW:
 2. Call appropriate tools to complete it
 3. Once done, ALWAYS call task_complete with a summary`
 
-// GetSystemPrompt returns the complete system prompt with OS-specific instructions.
+// GetSystemPrompt returns the complete system prompt, rendered from the
+// user's template if one has been saved and from the default otherwise.
 func GetSystemPrompt() string {
-	return strings.Replace(systemPromptTemplate, "{OS_INSTRUCTIONS}", GetOSInstructions(), 1)
+	return RenderSystemPrompt(GetSystemPromptTemplate())
+}
+
+// RenderSystemPrompt fills in the template variables of a system prompt.
+func RenderSystemPrompt(template string) string {
+	profile := ""
+	if userProfile != nil {
+		profile = userProfile()
+	}
+	if profile != "" && !strings.Contains(template, "{PROFILE}") {
+		template += "\n\n{PROFILE}"
+	}
+	return strings.NewReplacer(
+		"{OS_INSTRUCTIONS}", GetOSInstructions(),
+		"{OS}", osName(),
+		"{CWD}", tools.GetSession().GetCWD(),
+		"{DATE}", time.Now().Format("Monday, January 2, 2006"),
+		"{USERNAME}", currentUsername(),
+		"{PROFILE}", profile,
+	).Replace(template)
+}
+
+// DefaultSystemPromptTemplate returns the built-in system prompt template.
+func DefaultSystemPromptTemplate() string {
+	return systemPromptTemplate
+}
+
+// GetSystemPromptTemplate returns the user's system prompt template, or the
+// built-in template if none has been saved or it can't be read.
+func GetSystemPromptTemplate() string {
+	data, err := os.ReadFile(systemPromptFile())
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return systemPromptTemplate
+	}
+	return string(data)
+}
+
+// SaveSystemPromptTemplate saves a custom system prompt template used for
+// new conversations.
+func SaveSystemPromptTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return errors.New("system prompt template is empty")
+	}
+
+	path := systemPromptFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(template), 0644)
+}
+
+// ResetSystemPromptTemplate removes the custom template, restoring the built-in one.
+func ResetSystemPromptTemplate() error {
+	if err := os.Remove(systemPromptFile()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// currentUsername returns the name of the user running the app.
+func currentUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		// Windows usernames include the domain (DOMAIN\user)
+		name := u.Username
+		if i := strings.LastIndex(name, "\\"); i >= 0 {
+			name = name[i+1:]
+		}
+		return name
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
 }
 
 // BuildUserMessage builds the user message from task and context.
@@ -1,9 +1,13 @@
 package agent
 
 import (
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"agent-desktop/internal/tools"
 )
 
 func TestGetOSInstructions_Windows(t *testing.T) {
@@ -115,3 +119,72 @@ func TestGetSystemPrompt_NotEmpty(t *testing.T) {
 		t.Error("System prompt seems too short")
 	}
 }
+
+// useTempPromptFile points the system prompt template at a temp file.
+func useTempPromptFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "system_prompt.md")
+	original := systemPromptFile
+	systemPromptFile = func() string { return path }
+	t.Cleanup(func() { systemPromptFile = original })
+	return path
+}
+
+func TestRenderSystemPrompt_Variables(t *testing.T) {
+	tools.ResetSession()
+	prompt := RenderSystemPrompt("os={OS} cwd={CWD} date={DATE} user={USERNAME}")
+
+	if strings.Contains(prompt, "{") {
+		t.Errorf("Expected all variables to be replaced, got %q", prompt)
+	}
+	if !strings.Contains(prompt, "cwd="+tools.GetSession().GetCWD()) {
+		t.Errorf("Expected prompt to contain the session directory, got %q", prompt)
+	}
+	if !strings.Contains(prompt, time.Now().Format("2006")) {
+		t.Errorf("Expected prompt to contain the date, got %q", prompt)
+	}
+}
+
+func TestRenderSystemPrompt_Profile(t *testing.T) {
+	SetUserProfileFunc(func() string { return "ABOUT THE USER:\n- Preferred shell: fish" })
+	defer SetUserProfileFunc(nil)
+
+	if prompt := RenderSystemPrompt("Be brief."); prompt != "Be brief.\n\nABOUT THE USER:\n- Preferred shell: fish" {
+		t.Errorf("Expected the profile at the end, got %q", prompt)
+	}
+	if prompt := RenderSystemPrompt("{PROFILE}\nBe brief."); !strings.HasPrefix(prompt, "ABOUT THE USER:") || strings.Count(prompt, "fish") != 1 {
+		t.Errorf("Expected the profile in place of {PROFILE}, got %q", prompt)
+	}
+
+	SetUserProfileFunc(func() string { return "" })
+	if prompt := RenderSystemPrompt("Be brief.{PROFILE}"); prompt != "Be brief." {
+		t.Errorf("Expected no profile, got %q", prompt)
+	}
+}
+
+func TestSystemPromptTemplate_SaveAndReset(t *testing.T) {
+	useTempPromptFile(t)
+
+	if GetSystemPromptTemplate() != DefaultSystemPromptTemplate() {
+		t.Fatal("Expected the default template when none is saved")
+	}
+
+	custom := DefaultSystemPromptTemplate() + "\n\nHOUSE RULES: never touch {USERNAME}'s dotfiles."
+	if err := SaveSystemPromptTemplate(custom); err != nil {
+		t.Fatalf("SaveSystemPromptTemplate failed: %v", err)
+	}
+	if !strings.Contains(GetSystemPrompt(), "HOUSE RULES") {
+		t.Error("Expected the system prompt to use the saved template")
+	}
+
+	if err := SaveSystemPromptTemplate("   "); err == nil {
+		t.Error("Expected an empty template to be rejected")
+	}
+
+	if err := ResetSystemPromptTemplate(); err != nil {
+		t.Fatalf("ResetSystemPromptTemplate failed: %v", err)
+	}
+	if strings.Contains(GetSystemPrompt(), "HOUSE RULES") {
+		t.Error("Expected reset to restore the default template")
+	}
+}
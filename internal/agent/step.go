@@ -2,6 +2,9 @@
 package agent
 
 import (
+	"strings"
+
+	"agent-desktop/internal/i18n"
 	"agent-desktop/internal/llm"
 	"agent-desktop/internal/tools"
 )
@@ -15,25 +18,35 @@ const (
 	StepTypeError            = "error"
 	StepTypeUsage            = "usage"
 	StepTypeAssistantMessage = "assistant_message" // Conversational response (not task completion)
+	StepTypeContext          = "context"           // Context added to the task before the first model call
 )
 
 // Step represents a single step in the agent's execution.
 type Step struct {
 	StepNumber int                    `json:"step_number"`
-	Type       string                 `json:"type"` // thinking, tool_call, tool_result, complete, error, usage, assistant_message
+	Type       string                 `json:"type"` // thinking, tool_call, tool_result, complete, error, usage, assistant_message, context
 	Content    string                 `json:"content"`
 	ToolName   string                 `json:"tool_name,omitempty"`
+	ToolCallID string                 `json:"tool_call_id,omitempty"` // Matches tool:progress events to the call
 	ToolArgs   map[string]interface{} `json:"tool_args,omitempty"`
 	ToolResult *tools.ToolResult      `json:"tool_result,omitempty"`
+	DurationMs int64                  `json:"duration_ms,omitempty"` // How long the tool took, on tool results
+	ErrorKind  string                 `json:"error_kind,omitempty"`  // Why a model request or tool call failed, e.g. "rate_limit" or "tool_timeout"
+	Hint       string                 `json:"hint,omitempty"`        // What the user can do about the failure
 	Usage      *TokenUsage            `json:"usage,omitempty"`
 	Messages   []llm.Message          `json:"messages,omitempty"` // Updated conversation messages (for multi-turn)
 }
 
-// TokenUsage represents token usage information for a step.
+// TokenUsage is the token usage of the model call a usage step reports:
+// what it added since the previous usage step, not a running total. The
+// app fills in the costs, since it knows the model's pricing.
 type TokenUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost,omitempty"`       // Estimated US dollars for this step
+	RunTokens        int     `json:"run_tokens,omitempty"` // Tokens used by the run so far, this step included
+	RunCost          float64 `json:"run_cost,omitempty"`   // Estimated US dollars spent by the run so far
 }
 
 // NewThinkingStep creates a new thinking step.
@@ -50,7 +63,7 @@ func NewToolCallStep(stepNumber int, toolName string, toolArgs map[string]interf
 	return Step{
 		StepNumber: stepNumber,
 		Type:       StepTypeToolCall,
-		Content:    "Calling " + toolName,
+		Content:    i18n.T("agent.calling_tool", toolName),
 		ToolName:   toolName,
 		ToolArgs:   toolArgs,
 	}
@@ -61,9 +74,9 @@ func NewToolResultStep(stepNumber int, toolName string, result *tools.ToolResult
 	content := result.Output
 	if result.Error != "" {
 		if content != "" {
-			content += "\n\nError: " + result.Error
+			content += "\n\n" + i18n.T("agent.error", result.Error)
 		} else {
-			content = "Error: " + result.Error
+			content = i18n.T("agent.error", result.Error)
 		}
 	}
 
@@ -73,6 +86,8 @@ func NewToolResultStep(stepNumber int, toolName string, result *tools.ToolResult
 		Content:    content,
 		ToolName:   toolName,
 		ToolResult: result,
+		ErrorKind:  result.Kind,
+		Hint:       errorHint(result.Kind),
 	}
 }
 
@@ -94,6 +109,28 @@ func NewErrorStep(stepNumber int, content string) Step {
 	}
 }
 
+// NewModelErrorStep creates an error step for a failed model request,
+// classified so the UI can suggest a fix.
+func NewModelErrorStep(stepNumber int, err error) Step {
+	step := NewErrorStep(stepNumber, i18n.T("agent.error", err.Error()))
+	step.ErrorKind = string(llm.Classify(err))
+	step.Hint = errorHint(step.ErrorKind)
+	return step
+}
+
+// errorHint returns the fix suggested for a kind of failure, or "" if there
+// is none.
+func errorHint(kind string) string {
+	if kind == "" || kind == string(llm.ErrorUnknown) {
+		return ""
+	}
+	key := "error_hint." + kind
+	if hint := i18n.T(key); hint != key {
+		return hint
+	}
+	return ""
+}
+
 // NewUsageStep creates a new usage step.
 func NewUsageStep(stepNumber int, usage *TokenUsage) Step {
 	return Step{
@@ -104,6 +141,16 @@ func NewUsageStep(stepNumber int, usage *TokenUsage) Step {
 	}
 }
 
+// NewContextStep creates a step disclosing the passages of files added to
+// the task as context, given as path:start-end.
+func NewContextStep(stepNumber int, root string, sources []string) Step {
+	return Step{
+		StepNumber: stepNumber,
+		Type:       StepTypeContext,
+		Content:    i18n.T("agent.added_context", len(sources), root, strings.Join(sources, ", ")),
+	}
+}
+
 // NewAssistantMessageStep creates a step for a conversational assistant response.
 // This is used in multi-turn conversations where the assistant responds without
 // completing a task. It includes the updated messages for the conversation.
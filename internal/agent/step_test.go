@@ -1,8 +1,12 @@
 package agent
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
+	"agent-desktop/internal/llm"
 	"agent-desktop/internal/tools"
 )
 
@@ -93,6 +97,31 @@ func TestStep_Error(t *testing.T) {
 	}
 }
 
+func TestStep_ModelError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &llm.Error{Kind: llm.ErrorRateLimit, StatusCode: 429, Message: "slow down"})
+	step := NewModelErrorStep(3, err)
+	if step.Type != StepTypeError || !strings.Contains(step.Content, "slow down") {
+		t.Errorf("step = %+v", step)
+	}
+	if step.ErrorKind != string(llm.ErrorRateLimit) || step.Hint == "" {
+		t.Errorf("kind = %q, hint = %q, want a rate limit with a hint", step.ErrorKind, step.Hint)
+	}
+
+	if step := NewModelErrorStep(3, errors.New("odd failure")); step.ErrorKind != string(llm.ErrorUnknown) || step.Hint != "" {
+		t.Errorf("unclassified error got kind %q, hint %q", step.ErrorKind, step.Hint)
+	}
+}
+
+func TestStep_ToolResultKind(t *testing.T) {
+	step := NewToolResultStep(1, "run_command", &tools.ToolResult{Success: false, Error: "timed out", Kind: tools.KindTimeout})
+	if step.ErrorKind != tools.KindTimeout || step.Hint == "" {
+		t.Errorf("kind = %q, hint = %q", step.ErrorKind, step.Hint)
+	}
+	if step := NewToolResultStep(1, "read_file", &tools.ToolResult{Success: true}); step.ErrorKind != "" || step.Hint != "" {
+		t.Errorf("successful result got kind %q, hint %q", step.ErrorKind, step.Hint)
+	}
+}
+
 func TestStep_Usage(t *testing.T) {
 	step := Step{
 		StepNumber: 1,
@@ -0,0 +1,325 @@
+// Package bridge lets allowed users give the desktop agent tasks from chat
+// services such as Slack and Discord. Each chat thread (or channel, where
+// the service has no threads) is mapped to a conversation; the agent's
+// final answer is posted back to it, and tool calls that need approval are
+// posted with Approve and Deny buttons.
+package bridge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/runqueue"
+)
+
+// Message is a chat message that may be a task for the agent.
+type Message struct {
+	Channel string
+	Thread  string // The thread the reply goes to; "" on services without threads
+	User    string
+	Text    string // With mentions of the bot removed
+	Direct  bool   // Sent in a direct message or mentioning the bot
+}
+
+// Platform is a connection to a chat service.
+type Platform interface {
+	Name() string
+	// Run connects and delivers messages and button clicks to h until ctx
+	// is cancelled, reconnecting when the connection drops.
+	Run(ctx context.Context, h Handler) error
+	// Post sends text to a thread, or to the channel if thread is "".
+	Post(ctx context.Context, channel, thread, text string) error
+	// AskApproval posts text with Approve and Deny buttons that answer
+	// requestID through Handler.HandleApproval.
+	AskApproval(ctx context.Context, channel, thread, requestID, text string) error
+}
+
+// Handler receives what users do in chat.
+type Handler interface {
+	HandleMessage(platform string, msg Message)
+	// HandleApproval records a click on an approval button. It returns the
+	// text to replace the request with, or false if the click is ignored.
+	HandleApproval(platform, requestID, user string, approved bool) (string, bool)
+}
+
+// Backend is what the bridge drives: conversations and the run queue.
+type Backend interface {
+	NewConversation() (*conversation.Conversation, error)
+	GetConversation(id string) (*conversation.Conversation, error)
+	SendMessage(conversationID, text string) (runqueue.Item, error)
+}
+
+// ErrNoThread is returned when asking for approval in a conversation that
+// wasn't started from chat.
+var ErrNoThread = errors.New("conversation has no chat thread")
+
+// link is a connected platform and who may use it.
+type link struct {
+	platform Platform
+	users    []string
+	channels []string // Empty allows every channel
+}
+
+// thread identifies where a conversation lives in chat.
+type thread struct {
+	Platform string `json:"platform"`
+	Channel  string `json:"channel"`
+	Thread   string `json:"thread,omitempty"`
+}
+
+func (t thread) key() string {
+	return t.Platform + "|" + t.Channel + "|" + t.Thread
+}
+
+// approval is a tool call waiting for a click.
+type approval struct {
+	platform string
+	answer   chan bool
+}
+
+// Bridge connects platforms to the agent.
+type Bridge struct {
+	backend Backend
+	path    string // File the thread to conversation map is kept in
+
+	mu      sync.Mutex
+	links   map[string]*link
+	threads map[string]string // thread key -> conversation ID
+	convs   map[string]thread // conversation ID -> thread
+	pending map[string]*approval
+}
+
+// New creates a bridge that keeps its thread map in path.
+func New(backend Backend, path string) *Bridge {
+	b := &Bridge{
+		backend: backend,
+		path:    path,
+		links:   map[string]*link{},
+		threads: map[string]string{},
+		convs:   map[string]thread{},
+		pending: map[string]*approval{},
+	}
+	b.load()
+	return b
+}
+
+// Add connects a platform when the bridge runs. Only users in users may
+// give tasks, and only in channels, if any are listed.
+func (b *Bridge) Add(p Platform, users, channels []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.links[p.Name()] = &link{platform: p, users: users, channels: channels}
+}
+
+// Run runs every platform until ctx is cancelled.
+func (b *Bridge) Run(ctx context.Context) {
+	b.mu.Lock()
+	links := make([]*link, 0, len(b.links))
+	for _, l := range b.links {
+		links = append(links, l)
+	}
+	b.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, l := range links {
+		wg.Add(1)
+		go func(p Platform) {
+			defer wg.Done()
+			slog.Info("starting chat bridge", "platform", p.Name())
+			if err := p.Run(ctx, b); err != nil && ctx.Err() == nil {
+				slog.Error("chat bridge stopped", "platform", p.Name(), "error", err)
+			}
+		}(l.platform)
+	}
+	wg.Wait()
+}
+
+// HandleMessage queues a message from an allowed user as a task in its
+// thread's conversation. In channels, the bot must be mentioned to start a
+// thread; replies in a thread it already owns need no mention.
+func (b *Bridge) HandleMessage(platform string, msg Message) {
+	text := strings.TrimSpace(msg.Text)
+	b.mu.Lock()
+	l := b.links[platform]
+	t := thread{Platform: platform, Channel: msg.Channel, Thread: msg.Thread}
+	convID, known := b.threads[t.key()]
+	b.mu.Unlock()
+
+	switch {
+	case l == nil || text == "":
+		return
+	case !known && !msg.Direct:
+		return
+	case !slices.Contains(l.users, msg.User):
+		slog.Warn("ignoring chat message from a user who isn't allowed", "platform", platform, "user", msg.User)
+		return
+	case len(l.channels) > 0 && !slices.Contains(l.channels, msg.Channel):
+		return
+	}
+
+	ctx := context.Background()
+	if known {
+		if _, err := b.backend.GetConversation(convID); errors.Is(err, conversation.ErrNotFound) {
+			known = false // Deleted in the app; start over
+		}
+	}
+	if !known {
+		conv, err := b.backend.NewConversation()
+		if err != nil {
+			b.post(ctx, l.platform, t, "Couldn't start a conversation: "+err.Error())
+			return
+		}
+		convID = conv.ID
+		b.remember(t, convID)
+	}
+
+	if _, err := b.backend.SendMessage(convID, text); err != nil {
+		b.post(ctx, l.platform, t, "Couldn't queue the task: "+err.Error())
+		return
+	}
+	b.post(ctx, l.platform, t, "On it. I'll reply here when I'm done.")
+}
+
+// RunFinished posts the end of a run to the conversation's thread, if it
+// was started from chat.
+func (b *Bridge) RunFinished(convID string, failed bool, text string) {
+	b.mu.Lock()
+	t, ok := b.convs[convID]
+	l := b.links[t.Platform]
+	b.mu.Unlock()
+	if !ok || l == nil {
+		return
+	}
+	if failed {
+		text = "The task failed: " + text
+	}
+	b.post(context.Background(), l.platform, t, text)
+}
+
+// Owns reports whether the conversation was started from chat.
+func (b *Bridge) Owns(convID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.convs[convID]
+	return ok && b.links[t.Platform] != nil
+}
+
+// RequestApproval posts description with Approve and Deny buttons to the
+// conversation's thread and waits for an allowed user to click one, or for
+// ctx to end.
+func (b *Bridge) RequestApproval(ctx context.Context, convID, description string) (bool, error) {
+	b.mu.Lock()
+	t, ok := b.convs[convID]
+	l := b.links[t.Platform]
+	b.mu.Unlock()
+	if !ok || l == nil {
+		return false, ErrNoThread
+	}
+
+	id := newRequestID()
+	a := &approval{platform: t.Platform, answer: make(chan bool, 1)}
+	b.mu.Lock()
+	b.pending[id] = a
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, id)
+		b.mu.Unlock()
+	}()
+
+	if err := l.platform.AskApproval(ctx, t.Channel, t.Thread, id, description); err != nil {
+		return false, err
+	}
+	select {
+	case approved := <-a.answer:
+		return approved, nil
+	case <-ctx.Done():
+		b.post(context.Background(), l.platform, t, "No answer in time, so the action was not run.")
+		return false, ctx.Err()
+	}
+}
+
+// HandleApproval answers a pending approval request if user is allowed to.
+func (b *Bridge) HandleApproval(platform, requestID, user string, approved bool) (string, bool) {
+	b.mu.Lock()
+	a, ok := b.pending[requestID]
+	l := b.links[platform]
+	b.mu.Unlock()
+	if !ok || a.platform != platform || l == nil || !slices.Contains(l.users, user) {
+		return "", false
+	}
+
+	select {
+	case a.answer <- approved:
+	default:
+		return "", false // Already answered
+	}
+	if approved {
+		return fmt.Sprintf("Approved by <@%s>", user), true
+	}
+	return fmt.Sprintf("Denied by <@%s>", user), true
+}
+
+// post sends text to a thread, logging failures.
+func (b *Bridge) post(ctx context.Context, p Platform, t thread, text string) {
+	if err := p.Post(ctx, t.Channel, t.Thread, text); err != nil {
+		slog.Warn("failed to post to chat", "platform", t.Platform, "channel", t.Channel, "error", err)
+	}
+}
+
+// remember maps a thread to a conversation and saves the map.
+func (b *Bridge) remember(t thread, convID string) {
+	b.mu.Lock()
+	b.threads[t.key()] = convID
+	b.convs[convID] = t
+	entries := make(map[string]thread, len(b.convs))
+	for id, t := range b.convs {
+		entries[id] = t
+	}
+	b.mu.Unlock()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(b.path), 0755); err == nil {
+			err = os.WriteFile(b.path, data, 0600)
+		}
+	}
+	if err != nil {
+		slog.Warn("failed to save chat threads", "error", err)
+	}
+}
+
+// load reads the thread map saved by earlier runs.
+func (b *Bridge) load() {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return
+	}
+	var entries map[string]thread
+	if err := json.Unmarshal(data, &entries); err != nil {
+		slog.Warn("ignoring unreadable chat threads file", "error", err)
+		return
+	}
+	for convID, t := range entries {
+		b.threads[t.key()] = convID
+		b.convs[convID] = t
+	}
+}
+
+// newRequestID returns a random approval request ID.
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,207 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/runqueue"
+)
+
+// fakeBackend keeps conversations in memory and records queued tasks.
+type fakeBackend struct {
+	mu            sync.Mutex
+	conversations map[string]*conversation.Conversation
+	tasks         []runqueue.Item
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{conversations: map[string]*conversation.Conversation{}}
+}
+
+func (b *fakeBackend) NewConversation() (*conversation.Conversation, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	conv := conversation.New()
+	b.conversations[conv.ID] = conv
+	return conv, nil
+}
+
+func (b *fakeBackend) GetConversation(id string) (*conversation.Conversation, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	conv, ok := b.conversations[id]
+	if !ok {
+		return nil, conversation.ErrNotFound
+	}
+	return conv, nil
+}
+
+func (b *fakeBackend) SendMessage(conversationID, text string) (runqueue.Item, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	item := runqueue.Item{Task: text, ConversationID: conversationID}
+	b.tasks = append(b.tasks, item)
+	return item, nil
+}
+
+// post is a message a fakePlatform was asked to send.
+type post struct {
+	channel, thread, requestID, text string
+}
+
+// fakePlatform records what the bridge posts.
+type fakePlatform struct {
+	posts chan post
+}
+
+func newFakePlatform() *fakePlatform {
+	return &fakePlatform{posts: make(chan post, 16)}
+}
+
+func (p *fakePlatform) Name() string { return "fake" }
+
+func (p *fakePlatform) Run(ctx context.Context, h Handler) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (p *fakePlatform) Post(ctx context.Context, channel, thread, text string) error {
+	p.posts <- post{channel: channel, thread: thread, text: text}
+	return nil
+}
+
+func (p *fakePlatform) AskApproval(ctx context.Context, channel, thread, requestID, text string) error {
+	p.posts <- post{channel: channel, thread: thread, requestID: requestID, text: text}
+	return nil
+}
+
+func (p *fakePlatform) next(t *testing.T) post {
+	t.Helper()
+	select {
+	case got := <-p.posts:
+		return got
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a post")
+		return post{}
+	}
+}
+
+func TestBridge_HandleMessage(t *testing.T) {
+	backend := newFakeBackend()
+	platform := newFakePlatform()
+	path := filepath.Join(t.TempDir(), "bridge.json")
+	b := New(backend, path)
+	b.Add(platform, []string{"U1"}, []string{"C1"})
+
+	// Ignored: not mentioned, not allowed, wrong channel
+	b.HandleMessage("fake", Message{Channel: "C1", Thread: "T1", User: "U1", Text: "chatter"})
+	b.HandleMessage("fake", Message{Channel: "C1", Thread: "T1", User: "U2", Text: "do it", Direct: true})
+	b.HandleMessage("fake", Message{Channel: "C2", Thread: "T1", User: "U1", Text: "do it", Direct: true})
+	if len(backend.tasks) != 0 {
+		t.Fatalf("tasks = %+v, want none", backend.tasks)
+	}
+
+	b.HandleMessage("fake", Message{Channel: "C1", Thread: "T1", User: "U1", Text: " clean up ", Direct: true})
+	if got := platform.next(t); got.channel != "C1" || got.thread != "T1" {
+		t.Errorf("acknowledgement = %+v, want it in the thread", got)
+	}
+	// A reply in the thread continues the conversation without a mention
+	b.HandleMessage("fake", Message{Channel: "C1", Thread: "T1", User: "U1", Text: "and the logs"})
+	platform.next(t)
+	if len(backend.tasks) != 2 || backend.tasks[0].Task != "clean up" || backend.tasks[1].ConversationID != backend.tasks[0].ConversationID {
+		t.Fatalf("tasks = %+v, want two in one conversation", backend.tasks)
+	}
+
+	convID := backend.tasks[0].ConversationID
+	if !b.Owns(convID) {
+		t.Error("Owns = false for a conversation started from chat")
+	}
+	b.RunFinished(convID, false, "All clean")
+	if got := platform.next(t); got.thread != "T1" || got.text != "All clean" {
+		t.Errorf("result post = %+v", got)
+	}
+
+	// The thread map survives a restart
+	restarted := New(backend, path)
+	restarted.Add(platform, []string{"U1"}, nil)
+	if !restarted.Owns(convID) {
+		t.Error("thread map was not reloaded")
+	}
+}
+
+func TestBridge_HandleMessage_DeletedConversation(t *testing.T) {
+	backend := newFakeBackend()
+	platform := newFakePlatform()
+	b := New(backend, filepath.Join(t.TempDir(), "bridge.json"))
+	b.Add(platform, []string{"U1"}, nil)
+
+	b.HandleMessage("fake", Message{Channel: "D1", User: "U1", Text: "first", Direct: true})
+	platform.next(t)
+	delete(backend.conversations, backend.tasks[0].ConversationID)
+
+	b.HandleMessage("fake", Message{Channel: "D1", User: "U1", Text: "second"})
+	platform.next(t)
+	if len(backend.tasks) != 2 || backend.tasks[1].ConversationID == backend.tasks[0].ConversationID {
+		t.Errorf("tasks = %+v, want a new conversation after the old one was deleted", backend.tasks)
+	}
+}
+
+func TestBridge_RequestApproval(t *testing.T) {
+	backend := newFakeBackend()
+	platform := newFakePlatform()
+	b := New(backend, filepath.Join(t.TempDir(), "bridge.json"))
+	b.Add(platform, []string{"U1"}, nil)
+
+	if _, err := b.RequestApproval(context.Background(), "unknown", "rm -rf build"); !errors.Is(err, ErrNoThread) {
+		t.Errorf("RequestApproval(unknown) error = %v, want ErrNoThread", err)
+	}
+
+	b.HandleMessage("fake", Message{Channel: "D1", User: "U1", Text: "build it", Direct: true})
+	platform.next(t)
+	convID := backend.tasks[0].ConversationID
+
+	result := make(chan bool)
+	go func() {
+		approved, _ := b.RequestApproval(context.Background(), convID, "rm -rf build")
+		result <- approved
+	}()
+	ask := platform.next(t)
+	if ask.requestID == "" || ask.text != "rm -rf build" {
+		t.Fatalf("approval request = %+v", ask)
+	}
+
+	if _, ok := b.HandleApproval("fake", ask.requestID, "U2", true); ok {
+		t.Error("a user who isn't allowed answered the request")
+	}
+	if text, ok := b.HandleApproval("fake", ask.requestID, "U1", true); !ok || text != "Approved by <@U1>" {
+		t.Errorf("HandleApproval = %q, %v", text, ok)
+	}
+	if approved := <-result; !approved {
+		t.Error("RequestApproval = false after approval")
+	}
+
+	// No answer before the deadline denies
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if approved, err := b.RequestApproval(ctx, convID, "rm -rf dist"); approved || err == nil {
+		t.Errorf("RequestApproval = %v, %v, want a denial on timeout", approved, err)
+	}
+	platform.next(t)
+	if got := platform.next(t); got.text != "No answer in time, so the action was not run." {
+		t.Errorf("timeout post = %+v", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("héllo", 4); got != "h…" {
+		t.Errorf("truncate = %q", got)
+	}
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate = %q", got)
+	}
+}
@@ -0,0 +1,311 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// discordAPI is Discord's REST API base URL; tests point it at a fake server.
+var discordAPI = "https://discord.com/api/v10"
+
+// discordMaxText keeps posts under Discord's 2000 character limit.
+const discordMaxText = 1900
+
+// Gateway opcodes and intents used by the bridge.
+const (
+	discordDispatch       = 0
+	discordHeartbeat      = 1
+	discordIdentify       = 2
+	discordReconnect      = 7
+	discordInvalidSession = 9
+	discordHello          = 10
+
+	// GUILD_MESSAGES | DIRECT_MESSAGES | MESSAGE_CONTENT
+	discordIntents = 1<<9 | 1<<12 | 1<<15
+)
+
+// Discord connects to the Gateway with a bot token. The bot needs the
+// Message Content intent enabled in the developer portal. Discord has no
+// threads in the bridge's sense, so each channel or DM is one conversation.
+type Discord struct {
+	token  string
+	client *http.Client
+
+	mu    sync.Mutex
+	botID string // The bot's user ID, from READY
+}
+
+// NewDiscord creates a Discord platform.
+func NewDiscord(token string) *Discord {
+	return &Discord{token: token, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name returns "discord".
+func (d *Discord) Name() string {
+	return "discord"
+}
+
+// Run keeps a Gateway connection open until ctx is cancelled.
+func (d *Discord) Run(ctx context.Context, h Handler) error {
+	return reconnect(ctx, d.Name(), func() error { return d.connect(ctx, h) })
+}
+
+// discordPayload is a Gateway message.
+type discordPayload struct {
+	Op   int             `json:"op"`
+	D    json.RawMessage `json:"d,omitempty"`
+	S    *int64          `json:"s,omitempty"`
+	Type string          `json:"t,omitempty"`
+}
+
+// connect runs one Gateway session until it drops.
+func (d *Discord) connect(ctx context.Context, h Handler) error {
+	var gateway struct {
+		URL string `json:"url"`
+	}
+	if err := d.rest(ctx, http.MethodGet, "/gateway/bot", nil, &gateway); err != nil {
+		return err
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, gateway.URL+"?v=10&encoding=json", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var writeMu sync.Mutex
+	send := func(op int, data interface{}) error {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(discordPayload{Op: op, D: raw})
+	}
+
+	var hello discordPayload
+	if err := conn.ReadJSON(&hello); err != nil {
+		return err
+	}
+	var helloData struct {
+		Interval int `json:"heartbeat_interval"`
+	}
+	if hello.Op != discordHello || json.Unmarshal(hello.D, &helloData) != nil || helloData.Interval <= 0 {
+		return fmt.Errorf("discord: expected hello, got op %d", hello.Op)
+	}
+
+	var seqMu sync.Mutex
+	var seq *int64
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(time.Duration(helloData.Interval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				seqMu.Lock()
+				s := seq
+				seqMu.Unlock()
+				if send(discordHeartbeat, s) != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	err = send(discordIdentify, map[string]interface{}{
+		"token":   d.token,
+		"intents": discordIntents,
+		"properties": map[string]string{
+			"os":      runtime.GOOS,
+			"browser": "agent-desktop",
+			"device":  "agent-desktop",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		var p discordPayload
+		if err := conn.ReadJSON(&p); err != nil {
+			return err
+		}
+		switch p.Op {
+		case discordHeartbeat:
+			seqMu.Lock()
+			s := seq
+			seqMu.Unlock()
+			send(discordHeartbeat, s)
+		case discordReconnect, discordInvalidSession:
+			return fmt.Errorf("discord asked to reconnect (op %d)", p.Op)
+		case discordDispatch:
+			if p.S != nil {
+				seqMu.Lock()
+				seq = p.S
+				seqMu.Unlock()
+			}
+			d.dispatch(ctx, p.Type, p.D, h)
+		}
+	}
+}
+
+// discordUser is a user in Gateway events.
+type discordUser struct {
+	ID  string `json:"id"`
+	Bot bool   `json:"bot"`
+}
+
+// dispatch handles a Gateway event.
+func (d *Discord) dispatch(ctx context.Context, event string, data json.RawMessage, h Handler) {
+	switch event {
+	case "READY":
+		var ready struct {
+			User discordUser `json:"user"`
+		}
+		if json.Unmarshal(data, &ready) == nil {
+			d.mu.Lock()
+			d.botID = ready.User.ID
+			d.mu.Unlock()
+		}
+
+	case "MESSAGE_CREATE":
+		var m struct {
+			ChannelID string        `json:"channel_id"`
+			GuildID   string        `json:"guild_id"`
+			Author    discordUser   `json:"author"`
+			Content   string        `json:"content"`
+			Mentions  []discordUser `json:"mentions"`
+		}
+		if json.Unmarshal(data, &m) != nil || m.Author.Bot {
+			return
+		}
+		d.mu.Lock()
+		botID := d.botID
+		d.mu.Unlock()
+		mentioned := slices.ContainsFunc(m.Mentions, func(u discordUser) bool { return u.ID == botID })
+		go h.HandleMessage(d.Name(), Message{
+			Channel: m.ChannelID,
+			User:    m.Author.ID,
+			Text:    mentionPattern.ReplaceAllString(m.Content, ""),
+			Direct:  m.GuildID == "" || mentioned,
+		})
+
+	case "INTERACTION_CREATE":
+		go d.handleInteraction(ctx, data, h)
+	}
+}
+
+// handleInteraction answers a click on an approval button and replaces the
+// buttons with the outcome.
+func (d *Discord) handleInteraction(ctx context.Context, data json.RawMessage, h Handler) {
+	var i struct {
+		ID     string `json:"id"`
+		Token  string `json:"token"`
+		Type   int    `json:"type"`
+		Member struct {
+			User discordUser `json:"user"`
+		} `json:"member"`
+		User discordUser `json:"user"`
+		Data struct {
+			CustomID string `json:"custom_id"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(data, &i) != nil || i.Type != 3 { // MESSAGE_COMPONENT
+		return
+	}
+	action, requestID, ok := strings.Cut(i.Data.CustomID, ":")
+	if !ok || (action != "approve" && action != "deny") {
+		return
+	}
+	user := i.User.ID
+	if user == "" {
+		user = i.Member.User.ID // In a server
+	}
+
+	text, ok := h.HandleApproval(d.Name(), requestID, user, action == "approve")
+	var response interface{}
+	if ok {
+		// UPDATE_MESSAGE
+		response = map[string]interface{}{"type": 7, "data": map[string]interface{}{"content": text, "components": []interface{}{}}}
+	} else {
+		// CHANNEL_MESSAGE_WITH_SOURCE, visible only to the clicker
+		response = map[string]interface{}{"type": 4, "data": map[string]interface{}{"content": "You can't answer this request.", "flags": 64}}
+	}
+	// Errors aren't logged: the callback URL holds the interaction's token
+	d.rest(ctx, http.MethodPost, fmt.Sprintf("/interactions/%s/%s/callback", i.ID, i.Token), response, nil)
+}
+
+// Post sends text to a channel. thread is unused.
+func (d *Discord) Post(ctx context.Context, channel, thread, text string) error {
+	return d.rest(ctx, http.MethodPost, "/channels/"+channel+"/messages", map[string]interface{}{
+		"content": truncate(text, discordMaxText),
+	}, nil)
+}
+
+// AskApproval posts text with Approve and Deny buttons.
+func (d *Discord) AskApproval(ctx context.Context, channel, thread, requestID, text string) error {
+	button := func(label, action string, style int) map[string]interface{} {
+		return map[string]interface{}{"type": 2, "label": label, "style": style, "custom_id": action + ":" + requestID}
+	}
+	return d.rest(ctx, http.MethodPost, "/channels/"+channel+"/messages", map[string]interface{}{
+		"content": truncate(text, discordMaxText),
+		"components": []interface{}{
+			map[string]interface{}{"type": 1, "components": []interface{}{
+				button("Approve", "approve", 3), // Success
+				button("Deny", "deny", 4),       // Danger
+			}},
+		},
+	}, nil)
+}
+
+// rest calls the REST API and decodes the reply into out, if given.
+func (d *Discord) rest(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, discordAPI+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+d.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("discord %s %s: HTTP %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDiscord(t *testing.T) {
+	identify := make(chan map[string]interface{}, 1)
+	calls := make(chan map[string]interface{}, 4)
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/gateway/bot":
+			if r.Header.Get("Authorization") != "Bot token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"url":"ws` + strings.TrimPrefix("http://"+r.Host, "http") + `/gateway"}`))
+		case r.URL.Path == "/gateway":
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.WriteJSON(map[string]interface{}{"op": 10, "d": map[string]int{"heartbeat_interval": 45000}})
+			var p discordPayload
+			if conn.ReadJSON(&p) != nil {
+				return
+			}
+			var d map[string]interface{}
+			json.Unmarshal(p.D, &d)
+			identify <- d
+			conn.WriteJSON(map[string]interface{}{"op": 0, "s": 1, "t": "READY", "d": map[string]interface{}{"user": map[string]string{"id": "BOT"}}})
+			conn.WriteJSON(map[string]interface{}{"op": 0, "s": 2, "t": "MESSAGE_CREATE", "d": map[string]interface{}{
+				"channel_id": "C1", "guild_id": "G1", "content": "<@BOT> run the tests",
+				"author": map[string]string{"id": "U1"}, "mentions": []map[string]string{{"id": "BOT"}},
+			}})
+			conn.WriteJSON(map[string]interface{}{"op": 0, "s": 3, "t": "INTERACTION_CREATE", "d": map[string]interface{}{
+				"id": "I1", "token": "itoken", "type": 3,
+				"member": map[string]interface{}{"user": map[string]string{"id": "U1"}},
+				"data":   map[string]string{"custom_id": "approve:req1"},
+			}})
+			for conn.ReadJSON(&p) == nil {
+			}
+		default:
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			body["path"] = r.URL.Path
+			calls <- body
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+	defer func(old string) { discordAPI = old }(discordAPI)
+	discordAPI = srv.URL
+
+	d := NewDiscord("token")
+	h := newRecordingHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx, h)
+
+	if id := receive(t, identify); id["token"] != "token" || id["intents"] != float64(discordIntents) {
+		t.Errorf("identify = %+v", id)
+	}
+	msg := receive(t, h.messages)
+	if msg.Channel != "C1" || msg.Thread != "" || msg.User != "U1" || msg.Text != "run the tests" || !msg.Direct {
+		t.Errorf("message = %+v", msg)
+	}
+	if id := receive(t, h.approvals); id != "req1" {
+		t.Errorf("approval request = %q", id)
+	}
+	callback := receive(t, calls)
+	data, _ := callback["data"].(map[string]interface{})
+	if callback["path"] != "/interactions/I1/itoken/callback" || callback["type"] != float64(7) || data["content"] != "Approved by <@U1>" {
+		t.Errorf("callback = %+v", callback)
+	}
+
+	if err := d.Post(ctx, "C1", "", strings.Repeat("x", 3000)); err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	posted := receive(t, calls)
+	if content, _ := posted["content"].(string); posted["path"] != "/channels/C1/messages" || len(content) > discordMaxText {
+		t.Errorf("post = %s (%d bytes)", posted["path"], len(content))
+	}
+}
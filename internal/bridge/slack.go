@@ -0,0 +1,292 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// slackAPI is Slack's Web API base URL; tests point it at a fake server.
+var slackAPI = "https://slack.com/api"
+
+// slackMaxText keeps posts under Slack's message length limit.
+const slackMaxText = 3900
+
+// mentionPattern matches the bot mentions a message starts with.
+var mentionPattern = regexp.MustCompile(`^\s*(<@[A-Za-z0-9]+>\s*)+`)
+
+// Slack connects through Socket Mode, so the app needs no public URL. It
+// needs a bot token (xoxb-) with chat:write and the message history scopes,
+// and an app-level token (xapp-) with connections:write.
+type Slack struct {
+	botToken string
+	appToken string
+	client   *http.Client
+
+	mu    sync.Mutex
+	botID string // The bot's user ID, from auth.test
+}
+
+// NewSlack creates a Slack platform.
+func NewSlack(botToken, appToken string) *Slack {
+	return &Slack{botToken: botToken, appToken: appToken, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name returns "slack".
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// Run keeps a Socket Mode connection open until ctx is cancelled.
+func (s *Slack) Run(ctx context.Context, h Handler) error {
+	return reconnect(ctx, s.Name(), func() error { return s.connect(ctx, h) })
+}
+
+// slackEnvelope is a Socket Mode message.
+type slackEnvelope struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// connect runs one Socket Mode connection until it drops.
+func (s *Slack) connect(ctx context.Context, h Handler) error {
+	var auth struct {
+		UserID string `json:"user_id"`
+	}
+	if err := s.call(ctx, s.botToken, "auth.test", nil, &auth); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.botID = auth.UserID
+	s.mu.Unlock()
+
+	var open struct {
+		URL string `json:"url"`
+	}
+	if err := s.call(ctx, s.appToken, "apps.connections.open", nil, &open); err != nil {
+		return err
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, open.URL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		var env slackEnvelope
+		if err := conn.ReadJSON(&env); err != nil {
+			return err
+		}
+		if env.EnvelopeID != "" {
+			if err := conn.WriteJSON(map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+				return err
+			}
+		}
+		switch env.Type {
+		case "disconnect":
+			return fmt.Errorf("slack asked to reconnect")
+		case "events_api":
+			s.handleEvent(env.Payload, h)
+		case "interactive":
+			go s.handleInteraction(ctx, env.Payload, h)
+		}
+	}
+}
+
+// handleEvent passes on messages from people.
+func (s *Slack) handleEvent(payload json.RawMessage, h Handler) {
+	var p struct {
+		Event struct {
+			Type        string `json:"type"`
+			Subtype     string `json:"subtype"`
+			BotID       string `json:"bot_id"`
+			User        string `json:"user"`
+			Text        string `json:"text"`
+			Channel     string `json:"channel"`
+			ChannelType string `json:"channel_type"`
+			TS          string `json:"ts"`
+			ThreadTS    string `json:"thread_ts"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return
+	}
+	e := p.Event
+	if e.Type != "message" || e.Subtype != "" || e.BotID != "" {
+		return
+	}
+
+	s.mu.Lock()
+	botID := s.botID
+	s.mu.Unlock()
+	msg := Message{
+		Channel: e.Channel,
+		Thread:  e.ThreadTS,
+		User:    e.User,
+		Text:    mentionPattern.ReplaceAllString(e.Text, ""),
+		Direct:  e.ChannelType == "im" || (botID != "" && strings.Contains(e.Text, "<@"+botID+">")),
+	}
+	if msg.Thread == "" {
+		msg.Thread = e.TS // Start a thread on the message
+	}
+	go h.HandleMessage(s.Name(), msg)
+}
+
+// handleInteraction answers a click on an approval button and replaces the
+// buttons with the outcome.
+func (s *Slack) handleInteraction(ctx context.Context, payload json.RawMessage, h Handler) {
+	var p struct {
+		Type string `json:"type"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+		Message struct {
+			TS string `json:"ts"`
+		} `json:"message"`
+		Actions []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil || p.Type != "block_actions" || len(p.Actions) == 0 {
+		return
+	}
+	action := p.Actions[0]
+	if action.ActionID != "approve" && action.ActionID != "deny" {
+		return
+	}
+	text, ok := h.HandleApproval(s.Name(), action.Value, p.User.ID, action.ActionID == "approve")
+	if !ok {
+		return
+	}
+	body := map[string]interface{}{"channel": p.Channel.ID, "ts": p.Message.TS, "text": text, "blocks": []interface{}{}}
+	if err := s.call(ctx, s.botToken, "chat.update", body, nil); err != nil {
+		slog.Warn("failed to update Slack approval message", "error", err)
+	}
+}
+
+// Post sends text in a thread.
+func (s *Slack) Post(ctx context.Context, channel, thread, text string) error {
+	return s.call(ctx, s.botToken, "chat.postMessage", map[string]interface{}{
+		"channel":   channel,
+		"thread_ts": thread,
+		"text":      truncate(text, slackMaxText),
+	}, nil)
+}
+
+// AskApproval posts text with Approve and Deny buttons.
+func (s *Slack) AskApproval(ctx context.Context, channel, thread, requestID, text string) error {
+	text = truncate(text, slackMaxText)
+	button := func(label, actionID, style string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":      "button",
+			"text":      map[string]string{"type": "plain_text", "text": label},
+			"action_id": actionID,
+			"value":     requestID,
+			"style":     style,
+		}
+	}
+	return s.call(ctx, s.botToken, "chat.postMessage", map[string]interface{}{
+		"channel":   channel,
+		"thread_ts": thread,
+		"text":      text,
+		"blocks": []interface{}{
+			map[string]interface{}{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}},
+			map[string]interface{}{"type": "actions", "elements": []interface{}{
+				button("Approve", "approve", "primary"),
+				button("Deny", "deny", "danger"),
+			}},
+		},
+	}, nil)
+}
+
+// call calls a Web API method with a JSON body and decodes the reply into
+// out, if given.
+func (s *Slack) call(ctx context.Context, token, method string, body interface{}, out interface{}) error {
+	if body == nil {
+		body = struct{}{}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slackAPI+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("slack %s: HTTP %d", method, resp.StatusCode)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	json.Unmarshal(raw, &status)
+	if !status.OK {
+		return fmt.Errorf("slack %s: %s", method, status.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(raw, out)
+	}
+	return nil
+}
+
+// reconnect runs connect until ctx is cancelled, waiting longer after each
+// failure in a row.
+func reconnect(ctx context.Context, platform string, connect func() error) error {
+	delay := time.Second
+	for {
+		start := time.Now()
+		err := connect()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(start) > time.Minute {
+			delay = time.Second // It was up for a while; this is a fresh failure
+		}
+		slog.Warn("chat bridge disconnected", "platform", platform, "error", err, "retry_in", delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, time.Minute)
+	}
+}
+
+// truncate shortens text to at most n bytes, on a rune boundary.
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	cut := n - len("…")
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
@@ -0,0 +1,128 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// recordingHandler records what a platform delivers.
+type recordingHandler struct {
+	messages  chan Message
+	approvals chan string
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{messages: make(chan Message, 4), approvals: make(chan string, 4)}
+}
+
+func (h *recordingHandler) HandleMessage(platform string, msg Message) {
+	h.messages <- msg
+}
+
+func (h *recordingHandler) HandleApproval(platform, requestID, user string, approved bool) (string, bool) {
+	h.approvals <- requestID
+	if approved {
+		return "Approved by <@" + user + ">", true
+	}
+	return "Denied by <@" + user + ">", true
+}
+
+// receive waits for a value from ch.
+func receive[T any](t *testing.T, ch chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		var zero T
+		return zero
+	}
+}
+
+func TestSlack(t *testing.T) {
+	acks := make(chan string, 4)
+	calls := make(chan map[string]interface{}, 4)
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth.test":
+			w.Write([]byte(`{"ok":true,"user_id":"UBOT"}`))
+		case "/apps.connections.open":
+			if r.Header.Get("Authorization") != "Bearer xapp-test" {
+				w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true,"url":"ws` + strings.TrimPrefix("http://"+r.Host, "http") + `/socket"}`))
+		case "/chat.postMessage", "/chat.update":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			body["method"] = strings.TrimPrefix(r.URL.Path, "/")
+			calls <- body
+			w.Write([]byte(`{"ok":true}`))
+		case "/socket":
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.WriteJSON(map[string]interface{}{"type": "hello"})
+			conn.WriteJSON(map[string]interface{}{"envelope_id": "e1", "type": "events_api", "payload": map[string]interface{}{
+				"event": map[string]string{"type": "message", "user": "U1", "text": "<@UBOT> tidy up", "channel": "C1", "ts": "1.0"},
+			}})
+			conn.WriteJSON(map[string]interface{}{"envelope_id": "e2", "type": "interactive", "payload": map[string]interface{}{
+				"type":    "block_actions",
+				"user":    map[string]string{"id": "U1"},
+				"channel": map[string]string{"id": "C1"},
+				"message": map[string]string{"ts": "2.0"},
+				"actions": []map[string]string{{"action_id": "deny", "value": "req1"}},
+			}})
+			for {
+				var ack map[string]string
+				if conn.ReadJSON(&ack) != nil {
+					return
+				}
+				acks <- ack["envelope_id"]
+			}
+		}
+	}))
+	defer srv.Close()
+	defer func(old string) { slackAPI = old }(slackAPI)
+	slackAPI = srv.URL
+
+	s := NewSlack("xoxb-test", "xapp-test")
+	h := newRecordingHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx, h)
+
+	msg := receive(t, h.messages)
+	if msg.Channel != "C1" || msg.Thread != "1.0" || msg.User != "U1" || msg.Text != "tidy up" || !msg.Direct {
+		t.Errorf("message = %+v", msg)
+	}
+	if id := receive(t, h.approvals); id != "req1" {
+		t.Errorf("approval request = %q", id)
+	}
+	if update := receive(t, calls); update["method"] != "chat.update" || update["text"] != "Denied by <@U1>" || update["ts"] != "2.0" {
+		t.Errorf("update = %+v", update)
+	}
+	if a, b := receive(t, acks), receive(t, acks); a != "e1" || b != "e2" {
+		t.Errorf("acks = %q, %q", a, b)
+	}
+
+	if err := s.AskApproval(ctx, "C1", "1.0", "req2", "run `make`?"); err != nil {
+		t.Fatalf("AskApproval failed: %v", err)
+	}
+	ask := receive(t, calls)
+	blocks, _ := json.Marshal(ask["blocks"])
+	if ask["thread_ts"] != "1.0" || !strings.Contains(string(blocks), `"value":"req2"`) {
+		t.Errorf("approval post = %+v", ask)
+	}
+}
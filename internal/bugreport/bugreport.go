@@ -0,0 +1,262 @@
+// Package bugreport writes debug bundles: a zip with everything needed to
+// look into a conversation that went wrong, with secrets scrubbed so the
+// bundle can be attached to a public bug report.
+package bugreport
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	goruntime "runtime"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/logging"
+)
+
+// Contents is what goes into a bundle.
+type Contents struct {
+	Conversation *conversation.Conversation
+	Config       *config.Config
+	Logs         []logging.Entry
+	Trace        []llm.Exchange // Captured requests to the provider, if any
+	AppVersion   string
+}
+
+// System describes the machine the bundle was made on.
+type System struct {
+	AppVersion string    `json:"app_version"`
+	GoVersion  string    `json:"go_version"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	CPUs       int       `json:"cpus"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Step is an entry in a bundle's step log: a message, tool call, or tool
+// result, in the order the agent produced them.
+type Step struct {
+	Step      int       `json:"step,omitempty"`
+	Time      time.Time `json:"time,omitempty"`
+	Type      string    `json:"type"` // user, assistant, tool_call, tool_result, or system
+	Model     string    `json:"model,omitempty"`
+	Tool      string    `json:"tool,omitempty"`
+	CallID    string    `json:"call_id,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Tokens    int       `json:"tokens,omitempty"`
+	Cost      float64   `json:"cost,omitempty"`
+	ElapsedMs int64     `json:"elapsed_ms,omitempty"` // Since the previous timed entry
+}
+
+// Write writes a debug bundle to path holding the conversation as JSON and
+// as a Markdown transcript, its step log, the redacted config, the system
+// it ran on, the recent logs, and any captured requests to the provider.
+// Secrets are scrubbed from all of them.
+func Write(path string, c Contents) error {
+	if c.Conversation == nil {
+		return fmt.Errorf("no conversation to export")
+	}
+	cfg := c.Config
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	s := newScrubber(cfg.Secrets())
+	conv := s.conversation(c.Conversation)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+	files := []bundleFile{
+		{"conversation.json", conv},
+		{"transcript.md", conv.Markdown()},
+		{"steps.json", StepLog(conv)},
+		{"config.json", cfg.Redacted()},
+		{"system.json", System{
+			AppVersion: c.AppVersion,
+			GoVersion:  goruntime.Version(),
+			OS:         goruntime.GOOS,
+			Arch:       goruntime.GOARCH,
+			CPUs:       goruntime.NumCPU(),
+			CreatedAt:  time.Now(),
+		}},
+		{"logs.jsonl", s.logs(c.Logs)},
+	}
+	if len(c.Trace) > 0 {
+		files = append(files, bundleFile{"llm_trace.json", s.trace(c.Trace)})
+	}
+	for _, file := range files {
+		if err = add(zw, file.name, file.data); err != nil {
+			break
+		}
+	}
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write debug bundle: %w", err)
+	}
+	return nil
+}
+
+// bundleFile is a file in a bundle.
+type bundleFile struct {
+	name string
+	data interface{}
+}
+
+// add writes a file to the zip: strings as they are, anything else as
+// indented JSON.
+func add(zw *zip.Writer, name string, data interface{}) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if text, ok := data.(string); ok {
+		_, err = w.Write([]byte(text))
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// StepLog lists a conversation's messages, tool calls, and tool results in
+// order, with the step, model, usage, and time recorded for each.
+func StepLog(conv *conversation.Conversation) []Step {
+	steps := []Step{}
+	var last time.Time
+	for _, msg := range conv.Messages {
+		base := Step{}
+		if m := msg.Meta; m != nil {
+			base.Step, base.Time, base.Model, base.Cost = m.Step, m.Timestamp, m.Model, m.Cost
+			if m.Usage != nil {
+				base.Tokens = m.Usage.TotalTokens
+			}
+			if !last.IsZero() && !m.Timestamp.IsZero() {
+				base.ElapsedMs = m.Timestamp.Sub(last).Milliseconds()
+			}
+			if !m.Timestamp.IsZero() {
+				last = m.Timestamp
+			}
+		}
+
+		switch msg.Role {
+		case "tool":
+			base.Type, base.CallID, base.Content = "tool_result", msg.ToolCallID, msg.Content
+			base.Tool = toolName(conv.Messages, msg.ToolCallID)
+			steps = append(steps, base)
+		case "assistant":
+			if msg.Content != "" || len(msg.ToolCalls) == 0 {
+				entry := base
+				entry.Type, entry.Content = "assistant", msg.Content
+				steps = append(steps, entry)
+				base.Tokens, base.Cost, base.ElapsedMs = 0, 0, 0 // Counted once per message
+			}
+			for _, tc := range msg.ToolCalls {
+				entry := base
+				entry.Type, entry.Tool, entry.CallID, entry.Content = "tool_call", tc.Name, tc.ID, tc.Arguments
+				steps = append(steps, entry)
+				base.Tokens, base.Cost, base.ElapsedMs = 0, 0, 0
+			}
+		default:
+			base.Type, base.Content = msg.Role, msg.Content
+			steps = append(steps, base)
+		}
+	}
+	return steps
+}
+
+// toolName finds the name of the tool call a result answers.
+func toolName(messages []llm.Message, callID string) string {
+	for _, msg := range messages {
+		for _, tc := range msg.ToolCalls {
+			if tc.ID == callID {
+				return tc.Name
+			}
+		}
+	}
+	return ""
+}
+
+// scrubber replaces secrets in bundle contents with config.RedactedSecret.
+type scrubber struct {
+	*config.Scrubber
+}
+
+func newScrubber(secrets []string) *scrubber {
+	return &scrubber{config.NewScrubber(secrets)}
+}
+
+func (s *scrubber) text(text string) string {
+	return s.Scrub(text)
+}
+
+// conversation returns a copy of conv with secrets scrubbed from its
+// messages, tool calls, and summary.
+func (s *scrubber) conversation(conv *conversation.Conversation) *conversation.Conversation {
+	c := *conv
+	c.Title = s.text(conv.Title)
+	c.Messages = make([]llm.Message, len(conv.Messages))
+	for i, msg := range conv.Messages {
+		msg.Content = s.text(msg.Content)
+		if msg.ToolCalls != nil {
+			calls := make([]llm.ToolCall, len(msg.ToolCalls))
+			for j, tc := range msg.ToolCalls {
+				tc.Arguments = s.text(tc.Arguments)
+				calls[j] = tc
+			}
+			msg.ToolCalls = calls
+		}
+		c.Messages[i] = msg
+	}
+	if conv.Compaction != nil {
+		compaction := *conv.Compaction
+		compaction.Summary = s.text(compaction.Summary)
+		c.Compaction = &compaction
+	}
+	return &c
+}
+
+// trace returns a copy of captured exchanges with secrets scrubbed from
+// their bodies.
+func (s *scrubber) trace(exchanges []llm.Exchange) []llm.Exchange {
+	out := make([]llm.Exchange, len(exchanges))
+	for i, e := range exchanges {
+		e.RequestBody = s.text(e.RequestBody)
+		e.ResponseBody = s.text(e.ResponseBody)
+		out[i] = e
+	}
+	return out
+}
+
+// logs renders log entries as JSON lines with secrets scrubbed.
+func (s *scrubber) logs(entries []logging.Entry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		e.Message = s.text(e.Message)
+		if e.Attrs != nil {
+			attrs := make(map[string]string, len(e.Attrs))
+			for k, v := range e.Attrs {
+				attrs[k] = s.text(v)
+			}
+			e.Attrs = attrs
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,154 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+// maxCalDAVResponse bounds the size of a CalDAV response.
+const maxCalDAVResponse = 16 << 20
+
+// calDAV is a calendar on a CalDAV server (RFC 4791).
+type calDAV struct {
+	url      string // Collection events are read from and added to
+	tasksURL string // Collection reminders are added to
+	username string
+	password string
+	client   *http.Client
+}
+
+func newCalDAV(s config.CalendarSettings) (*calDAV, error) {
+	if s.URL == "" {
+		return nil, errors.New("the CalDAV calendar needs a url")
+	}
+	c := &calDAV{
+		url:      strings.TrimSuffix(s.URL, "/") + "/",
+		tasksURL: strings.TrimSuffix(s.TasksURL, "/") + "/",
+		username: s.Username,
+		password: s.Password,
+		client:   &http.Client{Timeout: callTimeout},
+	}
+	if s.TasksURL == "" {
+		c.tasksURL = c.url
+	}
+	return c, nil
+}
+
+// calendarQuery asks for the events in a time range, with recurring events
+// expanded into their occurrences.
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <C:calendar-data>
+      <C:expand start="%[1]s" end="%[2]s"/>
+    </C:calendar-data>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="%[1]s" end="%[2]s"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+
+// multistatus is a WebDAV REPORT response.
+type multistatus struct {
+	Responses []struct {
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				CalendarData string `xml:"calendar-data"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func (c *calDAV) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	body := fmt.Sprintf(calendarQuery, from.UTC().Format(icalUTC), to.UTC().Format(icalUTC))
+	resp, err := c.do(ctx, "REPORT", c.url, "application/xml; charset=utf-8", body, map[string]string{"Depth": "1"})
+	if err != nil {
+		return nil, err
+	}
+
+	var ms multistatus
+	if err := xml.Unmarshal(resp, &ms); err != nil {
+		return nil, fmt.Errorf("unexpected response from the CalDAV server: %w", err)
+	}
+	var events []Event
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			if ps.Prop.CalendarData == "" || (ps.Status != "" && !strings.Contains(ps.Status, " 200 ")) {
+				continue
+			}
+			for _, e := range parseEvents(ps.Prop.CalendarData, from.Location()) {
+				// Servers that don't expand recurrences return the first occurrence
+				if e.End.After(from) && e.Start.Before(to) {
+					events = append(events, e)
+				}
+			}
+		}
+	}
+	return events, nil
+}
+
+func (c *calDAV) CreateEvent(ctx context.Context, e Event) error {
+	uid := newUID()
+	return c.put(ctx, c.url, uid, encodeEvent(e, uid, now()))
+}
+
+func (c *calDAV) CreateReminder(ctx context.Context, r Reminder) error {
+	uid := newUID()
+	return c.put(ctx, c.tasksURL, uid, encodeTodo(r, uid, now()))
+}
+
+// put uploads a new calendar object to a collection.
+func (c *calDAV) put(ctx context.Context, collection, uid, data string) error {
+	_, err := c.do(ctx, http.MethodPut, collection+uid+".ics", "text/calendar; charset=utf-8", data, map[string]string{"If-None-Match": "*"})
+	return err
+}
+
+// do sends a request and returns the response body, or an error for a
+// non-2xx status.
+func (c *calDAV) do(ctx context.Context, method, url, contentType, body string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader([]byte(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCalDAVResponse))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("the CalDAV server refused access (%s); check the username and password", resp.Status)
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("no calendar at %s", url)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("the CalDAV server returned %s", resp.Status)
+	}
+	return data, nil
+}
@@ -0,0 +1,86 @@
+package calendar
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+const reportResponse = `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+  <d:response>
+    <d:href>/cal/standup.ics</d:href>
+    <d:propstat>
+      <d:prop><cal:calendar-data>BEGIN:VCALENDAR&#13;
+BEGIN:VEVENT&#13;
+SUMMARY:Standup&#13;
+DTSTART:20250311T093000Z&#13;
+DTEND:20250311T094500Z&#13;
+END:VEVENT&#13;
+END:VCALENDAR&#13;
+</cal:calendar-data></d:prop>
+      <d:status>HTTP/1.1 200 OK</d:status>
+    </d:propstat>
+  </d:response>
+</d:multistatus>`
+
+func TestCalDAV(t *testing.T) {
+	var puts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "me" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == "REPORT" && r.URL.Path == "/cal/":
+			if r.Header.Get("Depth") != "1" || !strings.Contains(string(body), `<C:time-range start="20250310T090000Z" end="20250317T090000Z"/>`) {
+				t.Errorf("unexpected query: %s", body)
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, reportResponse)
+		case r.Method == http.MethodPut && r.Header.Get("If-None-Match") == "*" && strings.HasSuffix(r.URL.Path, ".ics"):
+			puts = append(puts, r.URL.Path+"\n"+string(body))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := newCalDAV(config.CalendarSettings{URL: server.URL + "/cal", TasksURL: server.URL + "/tasks/", Username: "me", Password: "secret"})
+	if err != nil {
+		t.Fatalf("newCalDAV failed: %v", err)
+	}
+	ctx := context.Background()
+	from := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	events, err := c.Events(ctx, from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Title != "Standup" || !events[0].Start.Equal(time.Date(2025, 3, 11, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("events = %+v", events)
+	}
+
+	if err := c.CreateEvent(ctx, Event{Title: "Lunch", Start: from, End: from.Add(time.Hour)}); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	if err := c.CreateReminder(ctx, Reminder{Title: "Stretch", Due: from}); err != nil {
+		t.Fatalf("CreateReminder failed: %v", err)
+	}
+	if len(puts) != 2 || !strings.HasPrefix(puts[0], "/cal/") || !strings.Contains(puts[0], "SUMMARY:Lunch") ||
+		!strings.HasPrefix(puts[1], "/tasks/") || !strings.Contains(puts[1], "BEGIN:VTODO") {
+		t.Errorf("puts = %q", puts)
+	}
+
+	c.password = "wrong"
+	if _, err := c.Events(ctx, from, from.AddDate(0, 0, 1)); err == nil || !strings.Contains(err.Error(), "username and password") {
+		t.Errorf("Events with a wrong password error = %v", err)
+	}
+}
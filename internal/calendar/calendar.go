@@ -0,0 +1,343 @@
+// Package calendar gives the agent tools to list upcoming events and to
+// create events and reminders, in a CalDAV calendar or, on macOS, in the
+// Calendar and Reminders apps.
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+)
+
+// Source is the tools package source calendar tools are registered under.
+const Source = "calendar"
+
+// callTimeout bounds a single calendar tool call.
+const callTimeout = 60 * time.Second
+
+// maxDays bounds how far ahead list_calendar_events looks.
+const maxDays = 366
+
+// Event is a calendar event.
+type Event struct {
+	Title    string
+	Start    time.Time
+	End      time.Time
+	AllDay   bool
+	Location string
+	Notes    string
+	Calendar string // Name of the calendar the event is in, if known
+}
+
+// Reminder is a task with a due time at which the user is reminded.
+type Reminder struct {
+	Title string
+	Due   time.Time
+	Notes string
+}
+
+// Provider reads and writes a calendar.
+type Provider interface {
+	// Events returns the events overlapping [from, to).
+	Events(ctx context.Context, from, to time.Time) ([]Event, error)
+	CreateEvent(ctx context.Context, e Event) error
+	CreateReminder(ctx context.Context, r Reminder) error
+}
+
+// now returns the current time. It is a variable so tests can fix it.
+var now = time.Now
+
+// New returns the provider the settings choose, or nil if calendar tools
+// are off.
+func New(s config.CalendarSettings) (Provider, error) {
+	switch s.Provider {
+	case "":
+		return nil, nil
+	case config.CalendarProviderCalDAV:
+		return newCalDAV(s)
+	case config.CalendarProviderSystem:
+		return newSystem(s)
+	}
+	return nil, fmt.Errorf("unknown calendar provider: %s", s.Provider)
+}
+
+// Register makes the calendar tools for the settings' provider available
+// to the agent, replacing any registered before. Turning the calendar off
+// removes them, and so does an error. Tools named like another source's
+// are left out and reported.
+func Register(s config.CalendarSettings) error {
+	p, err := New(s)
+	if err != nil || p == nil {
+		tools.SetExternalTools(Source, nil)
+		return err
+	}
+	return tools.SetExternalTools(Source, Tools(p))
+}
+
+// Tools returns the calendar tools backed by p.
+func Tools(p Provider) []tools.ExternalTool {
+	return []tools.ExternalTool{
+		{
+			Definition: definition("list_calendar_events",
+				"List the user's calendar events in the coming days, with the current time for reference.",
+				map[string]interface{}{
+					"days": map[string]interface{}{
+						"type":        "integer",
+						"description": "How many days ahead to look. Default is 7.",
+						"default":     7,
+					},
+					"from": map[string]interface{}{
+						"type":        "string",
+						"description": "Start of the range in local time, as 'YYYY-MM-DD' or 'YYYY-MM-DD HH:MM'. Default is now.",
+					},
+				}),
+			Execute: func(args map[string]interface{}) tools.ToolResult {
+				return listEvents(p, args)
+			},
+		},
+		{
+			Definition: definition("create_calendar_event",
+				"Add an event to the user's calendar.",
+				map[string]interface{}{
+					"title": map[string]interface{}{"type": "string", "description": "Title of the event"},
+					"start": map[string]interface{}{
+						"type":        "string",
+						"description": "When the event starts in local time, as 'YYYY-MM-DD HH:MM', 'YYYY-MM-DD' for an all-day event, RFC 3339, or relative like 'in 2h' or 'in 30 minutes'",
+					},
+					"end": map[string]interface{}{"type": "string", "description": "When the event ends, in the same formats. Overrides duration_minutes."},
+					"duration_minutes": map[string]interface{}{
+						"type":        "integer",
+						"description": "Length of the event. Default is 60, or one day for all-day events.",
+					},
+					"all_day":  map[string]interface{}{"type": "boolean", "description": "Whether the event lasts all day"},
+					"location": map[string]interface{}{"type": "string"},
+					"notes":    map[string]interface{}{"type": "string"},
+				}, "title", "start"),
+			Execute: func(args map[string]interface{}) tools.ToolResult {
+				return createEvent(p, args)
+			},
+		},
+		{
+			Definition: definition("create_reminder",
+				"Create a reminder that alerts the user at a given time, such as when a long task should be done.",
+				map[string]interface{}{
+					"title": map[string]interface{}{"type": "string", "description": "What to remind the user of"},
+					"due": map[string]interface{}{
+						"type":        "string",
+						"description": "When to remind the user, in local time as 'YYYY-MM-DD HH:MM', RFC 3339, or relative like 'in 45m'",
+					},
+					"notes": map[string]interface{}{"type": "string"},
+				}, "title", "due"),
+			Execute: func(args map[string]interface{}) tools.ToolResult {
+				return createReminder(p, args)
+			},
+		},
+	}
+}
+
+func definition(name, description string, properties map[string]interface{}, required ...string) tools.ToolDefinition {
+	if required == nil {
+		required = []string{}
+	}
+	return tools.ToolDefinition{
+		Type: "function",
+		Function: tools.ToolFunction{
+			Name:        name,
+			Description: description,
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": properties,
+				"required":   required,
+			},
+		},
+	}
+}
+
+func listEvents(p Provider, args map[string]interface{}) tools.ToolResult {
+	current := now()
+	from := current
+	if s, _ := args["from"].(string); s != "" {
+		t, _, err := ParseTime(s, current)
+		if err != nil {
+			return tools.ToolResult{Success: false, Error: err.Error()}
+		}
+		from = t
+	}
+	days := intArg(args, "days", 7)
+	if days < 1 || days > maxDays {
+		return tools.ToolResult{Success: false, Error: fmt.Sprintf("days must be between 1 and %d", maxDays)}
+	}
+	to := from.AddDate(0, 0, days)
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	events, err := p.Events(ctx, from, to)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: "failed to read the calendar: " + err.Error()}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Now: %s\n", current.Format("Mon Jan 2, 2006 15:04 MST"))
+	if len(events) == 0 {
+		fmt.Fprintf(&b, "No events from %s to %s.", from.Format("Mon Jan 2 15:04"), to.Format("Mon Jan 2 15:04"))
+		return tools.ToolResult{Success: true, Output: b.String()}
+	}
+	for _, e := range events {
+		fmt.Fprintf(&b, "- %s  %s", formatSpan(e), e.Title)
+		var details []string
+		if e.Calendar != "" {
+			details = append(details, e.Calendar)
+		}
+		if e.Location != "" {
+			details = append(details, "at "+e.Location)
+		}
+		if len(details) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return tools.ToolResult{Success: true, Output: strings.TrimRight(b.String(), "\n")}
+}
+
+func createEvent(p Provider, args map[string]interface{}) tools.ToolResult {
+	title, _ := args["title"].(string)
+	startArg, _ := args["start"].(string)
+	if strings.TrimSpace(title) == "" || startArg == "" {
+		return tools.ToolResult{Success: false, Error: "create_calendar_event needs a title and a start"}
+	}
+	current := now()
+	start, dateOnly, err := ParseTime(startArg, current)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: err.Error()}
+	}
+
+	e := Event{Title: strings.TrimSpace(title), Start: start}
+	e.AllDay, _ = args["all_day"].(bool)
+	e.AllDay = e.AllDay || dateOnly
+	e.Location, _ = args["location"].(string)
+	e.Notes, _ = args["notes"].(string)
+	if e.AllDay {
+		e.Start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	}
+
+	if endArg, _ := args["end"].(string); endArg != "" {
+		if e.End, _, err = ParseTime(endArg, current); err != nil {
+			return tools.ToolResult{Success: false, Error: err.Error()}
+		}
+		if e.AllDay {
+			// All-day events end at the start of the day after the last one
+			e.End = time.Date(e.End.Year(), e.End.Month(), e.End.Day()+1, 0, 0, 0, 0, e.End.Location())
+		}
+	} else if e.AllDay {
+		e.End = e.Start.AddDate(0, 0, max(intArg(args, "duration_minutes", 0)/(24*60), 1))
+	} else {
+		e.End = e.Start.Add(time.Duration(intArg(args, "duration_minutes", 60)) * time.Minute)
+	}
+	if !e.End.After(e.Start) {
+		return tools.ToolResult{Success: false, Error: "the event must end after it starts"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	if err := p.CreateEvent(ctx, e); err != nil {
+		return tools.ToolResult{Success: false, Error: "failed to create the event: " + err.Error()}
+	}
+	return tools.ToolResult{Success: true, Output: fmt.Sprintf("Created event %q: %s", e.Title, formatSpan(e))}
+}
+
+func createReminder(p Provider, args map[string]interface{}) tools.ToolResult {
+	title, _ := args["title"].(string)
+	dueArg, _ := args["due"].(string)
+	if strings.TrimSpace(title) == "" || dueArg == "" {
+		return tools.ToolResult{Success: false, Error: "create_reminder needs a title and a due time"}
+	}
+	due, _, err := ParseTime(dueArg, now())
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: err.Error()}
+	}
+	r := Reminder{Title: strings.TrimSpace(title), Due: due}
+	r.Notes, _ = args["notes"].(string)
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	if err := p.CreateReminder(ctx, r); err != nil {
+		return tools.ToolResult{Success: false, Error: "failed to create the reminder: " + err.Error()}
+	}
+	return tools.ToolResult{Success: true, Output: fmt.Sprintf("Created reminder %q for %s", r.Title, due.Format("Mon Jan 2, 2006 15:04"))}
+}
+
+// formatSpan describes when an event happens.
+func formatSpan(e Event) string {
+	if e.AllDay {
+		last := e.End.AddDate(0, 0, -1)
+		if !last.After(e.Start) {
+			return e.Start.Format("Mon Jan 2") + " (all day)"
+		}
+		return e.Start.Format("Mon Jan 2") + " to " + last.Format("Mon Jan 2") + " (all day)"
+	}
+	if e.Start.Format("2006-01-02") == e.End.Format("2006-01-02") {
+		return e.Start.Format("Mon Jan 2 15:04") + "-" + e.End.Format("15:04")
+	}
+	return e.Start.Format("Mon Jan 2 15:04") + " to " + e.End.Format("Mon Jan 2 15:04")
+}
+
+// relativeTime matches times like "in 30 minutes" or "in 2h".
+var relativeTime = regexp.MustCompile(`^in\s+(\d+)\s*(m|min|mins|minutes?|h|hr|hrs|hours?|d|days?)$`)
+
+// timeLayouts are the absolute time formats ParseTime accepts, in local
+// time unless they carry a zone.
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// ParseTime parses a time given to a calendar tool: a local date and time,
+// a date alone (dateOnly is then true), RFC 3339, or a time relative to
+// ref such as "in 45m" or "in 2 hours".
+func ParseTime(s string, ref time.Time) (t time.Time, dateOnly bool, err error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+	if m := relativeTime.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := time.Minute
+		switch m[2][0] {
+		case 'h':
+			unit = time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		}
+		return ref.Add(time.Duration(n) * unit), false, nil
+	}
+	if rest, ok := strings.CutPrefix(lower, "in "); ok {
+		if d, err := time.ParseDuration(strings.ReplaceAll(rest, " ", "")); err == nil && d > 0 {
+			return ref.Add(d), false, nil
+		}
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, ref.Location()); err == nil {
+			return t, false, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, ref.Location()); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, errors.New("unrecognized time " + strconv.Quote(s) + "; use 'YYYY-MM-DD HH:MM' or 'in 30m'")
+}
+
+// intArg returns an integer argument, which arrives as a float64 from JSON.
+func intArg(args map[string]interface{}, name string, def int) int {
+	switch v := args[name].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return def
+}
@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+)
+
+// fakeProvider keeps events and reminders in memory.
+type fakeProvider struct {
+	events    []Event
+	reminders []Reminder
+	from, to  time.Time
+}
+
+func (f *fakeProvider) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	f.from, f.to = from, to
+	return f.events, nil
+}
+
+func (f *fakeProvider) CreateEvent(ctx context.Context, e Event) error {
+	f.events = append(f.events, e)
+	return nil
+}
+
+func (f *fakeProvider) CreateReminder(ctx context.Context, r Reminder) error {
+	f.reminders = append(f.reminders, r)
+	return nil
+}
+
+// fixNow sets the current time for the test.
+func fixNow(t *testing.T, current time.Time) {
+	orig := now
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = orig })
+}
+
+func TestParseTime(t *testing.T) {
+	ref := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in       string
+		want     time.Time
+		dateOnly bool
+	}{
+		{"2025-03-11 14:30", time.Date(2025, 3, 11, 14, 30, 0, 0, time.UTC), false},
+		{"2025-03-11T14:30:15", time.Date(2025, 3, 11, 14, 30, 15, 0, time.UTC), false},
+		{"2025-03-11T14:30:00+01:00", time.Date(2025, 3, 11, 13, 30, 0, 0, time.UTC), false},
+		{"2025-03-12", time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC), true},
+		{"in 45m", ref.Add(45 * time.Minute), false},
+		{"In 2 hours", ref.Add(2 * time.Hour), false},
+		{"in 1 day", ref.Add(24 * time.Hour), false},
+		{"in 1h30m", ref.Add(90 * time.Minute), false},
+	}
+	for _, tt := range tests {
+		got, dateOnly, err := ParseTime(tt.in, ref)
+		if err != nil || !got.Equal(tt.want) || dateOnly != tt.dateOnly {
+			t.Errorf("ParseTime(%q) = %v, %v, %v; want %v, %v", tt.in, got, dateOnly, err, tt.want, tt.dateOnly)
+		}
+	}
+	for _, in := range []string{"tomorrow", "in -5m", "03/11/2025"} {
+		if _, _, err := ParseTime(in, ref); err == nil {
+			t.Errorf("ParseTime(%q) should fail", in)
+		}
+	}
+}
+
+func TestTools(t *testing.T) {
+	fixNow(t, time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local))
+	p := &fakeProvider{}
+	run := func(name string, args map[string]interface{}) tools.ToolResult {
+		for _, tool := range Tools(p) {
+			if tool.Definition.Function.Name == name {
+				return tool.Execute(args)
+			}
+		}
+		t.Fatalf("no tool %s", name)
+		return tools.ToolResult{}
+	}
+
+	result := run("create_calendar_event", map[string]interface{}{"title": "Standup", "start": "2025-03-11 09:30", "duration_minutes": float64(15), "location": "Room 4"})
+	if !result.Success || !strings.Contains(result.Output, "Tue Mar 11 09:30-09:45") {
+		t.Fatalf("create_calendar_event = %+v", result)
+	}
+	result = run("create_calendar_event", map[string]interface{}{"title": "Offsite", "start": "2025-03-12", "end": "2025-03-13"})
+	if !result.Success || !p.events[1].AllDay || !p.events[1].End.Equal(time.Date(2025, 3, 14, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("all-day event = %+v, %+v", result, p.events[1])
+	}
+	if result := run("create_calendar_event", map[string]interface{}{"title": "Backwards", "start": "2025-03-12 10:00", "end": "2025-03-12 09:00"}); result.Success {
+		t.Error("expected an error for an event ending before it starts")
+	}
+
+	result = run("create_reminder", map[string]interface{}{"title": "Check the build", "due": "in 30m"})
+	if !result.Success || len(p.reminders) != 1 || !p.reminders[0].Due.Equal(now().Add(30*time.Minute)) {
+		t.Fatalf("create_reminder = %+v, %+v", result, p.reminders)
+	}
+	if result := run("create_reminder", map[string]interface{}{"title": "No time"}); result.Success {
+		t.Error("expected an error for a reminder without a due time")
+	}
+
+	p.events[0].Calendar = "Work"
+	result = run("list_calendar_events", map[string]interface{}{"days": float64(3)})
+	if !result.Success || !p.to.Equal(now().AddDate(0, 0, 3)) {
+		t.Fatalf("list_calendar_events = %+v, range to %v", result, p.to)
+	}
+	want := "Now: Mon Mar 10, 2025 09:00"
+	if !strings.HasPrefix(result.Output, want) ||
+		!strings.Contains(result.Output, "- Tue Mar 11 09:30-09:45  Standup (Work, at Room 4)\n- Wed Mar 12 to Thu Mar 13 (all day)  Offsite") {
+		t.Errorf("list output:\n%s", result.Output)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer tools.SetExternalTools(Source, nil)
+
+	has := func(name string) bool {
+		for _, def := range tools.GetToolDefinitions() {
+			if def.Function.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	if err := Register(config.CalendarSettings{Provider: config.CalendarProviderCalDAV, URL: "https://dav.example.com/cal/"}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if !has("create_reminder") {
+		t.Error("calendar tools not registered")
+	}
+	if err := Register(config.CalendarSettings{Provider: "outlook"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+	if has("create_reminder") {
+		t.Error("calendar tools still registered after an error")
+	}
+}
@@ -0,0 +1,235 @@
+package calendar
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// iCalendar (RFC 5545) date and time formats.
+const (
+	icalUTC   = "20060102T150405Z"
+	icalLocal = "20060102T150405"
+	icalDate  = "20060102"
+)
+
+// encodeEvent returns a VCALENDAR holding e as a VEVENT with the given UID.
+func encodeEvent(e Event, uid string, stamp time.Time) string {
+	var b icalWriter
+	b.begin(uid, stamp, "VEVENT")
+	if e.AllDay {
+		b.line("DTSTART;VALUE=DATE:" + e.Start.Format(icalDate))
+		b.line("DTEND;VALUE=DATE:" + e.End.Format(icalDate))
+	} else {
+		b.line("DTSTART:" + e.Start.UTC().Format(icalUTC))
+		b.line("DTEND:" + e.End.UTC().Format(icalUTC))
+	}
+	b.text("SUMMARY", e.Title)
+	b.text("LOCATION", e.Location)
+	b.text("DESCRIPTION", e.Notes)
+	b.end("VEVENT")
+	return b.String()
+}
+
+// encodeTodo returns a VCALENDAR holding r as a VTODO with the given UID
+// and an alarm at its due time.
+func encodeTodo(r Reminder, uid string, stamp time.Time) string {
+	var b icalWriter
+	b.begin(uid, stamp, "VTODO")
+	b.line("DUE:" + r.Due.UTC().Format(icalUTC))
+	b.text("SUMMARY", r.Title)
+	b.text("DESCRIPTION", r.Notes)
+	b.line("BEGIN:VALARM")
+	b.line("ACTION:DISPLAY")
+	b.text("DESCRIPTION", r.Title)
+	b.line("TRIGGER;RELATED=END:PT0S")
+	b.line("END:VALARM")
+	b.end("VTODO")
+	return b.String()
+}
+
+// icalWriter builds iCalendar text with CRLF line endings and long lines
+// folded.
+type icalWriter struct {
+	strings.Builder
+}
+
+func (w *icalWriter) begin(uid string, stamp time.Time, component string) {
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:-//Agent Desktop//Calendar Tools//EN")
+	w.line("BEGIN:" + component)
+	w.line("UID:" + uid)
+	w.line("DTSTAMP:" + stamp.UTC().Format(icalUTC))
+}
+
+func (w *icalWriter) end(component string) {
+	w.line("END:" + component)
+	w.line("END:VCALENDAR")
+}
+
+// text writes a text property, escaped, if value isn't empty.
+func (w *icalWriter) text(name, value string) {
+	if value == "" {
+		return
+	}
+	value = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+	w.line(name + ":" + value)
+}
+
+// line writes a content line, folding it at 75 bytes without splitting a
+// UTF-8 sequence.
+func (w *icalWriter) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	w.WriteString(s + "\r\n")
+}
+
+// property is a content line of an iCalendar object.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseLines unfolds iCalendar text into its content lines.
+func parseLines(data string) []property {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.NewReplacer("\n ", "", "\n\t", "").Replace(data)
+
+	var props []property
+	for _, line := range strings.Split(data, "\n") {
+		head, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(head, ";")
+		p := property{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: value}
+		for _, param := range parts[1:] {
+			if k, v, ok := strings.Cut(param, "="); ok {
+				p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+			}
+		}
+		props = append(props, p)
+	}
+	return props
+}
+
+// parseEvents returns the VEVENTs in iCalendar text. Times without a zone
+// are taken to be in loc.
+func parseEvents(data string, loc *time.Location) []Event {
+	var events []Event
+	var current *Event
+	var duration time.Duration
+	depth := 0 // Nesting inside the VEVENT, e.g. in a VALARM
+	for _, p := range parseLines(data) {
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			current, duration, depth = &Event{}, 0, 0
+		case current == nil:
+		case p.name == "BEGIN":
+			depth++
+		case p.name == "END" && depth > 0:
+			depth--
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			if current.End.IsZero() {
+				current.End = current.Start.Add(duration)
+				if duration == 0 && current.AllDay {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+			}
+			if !current.Start.IsZero() {
+				events = append(events, *current)
+			}
+			current = nil
+		case depth > 0:
+		case p.name == "SUMMARY":
+			current.Title = unescapeText(p.value)
+		case p.name == "LOCATION":
+			current.Location = unescapeText(p.value)
+		case p.name == "DESCRIPTION":
+			current.Notes = unescapeText(p.value)
+		case p.name == "DTSTART":
+			current.Start, current.AllDay = parseDateTime(p, loc)
+		case p.name == "DTEND":
+			current.End, _ = parseDateTime(p, loc)
+		case p.name == "DURATION":
+			duration = parseDuration(p.value)
+		}
+	}
+	return events
+}
+
+// parseDateTime parses a DATE or DATE-TIME property, reporting whether it
+// was a date.
+func parseDateTime(p property, loc *time.Location) (time.Time, bool) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len(icalDate) {
+		t, _ := time.ParseInLocation(icalDate, p.value, loc)
+		return t, true
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		t, _ := time.Parse(icalUTC, p.value)
+		return t.In(loc), false
+	}
+	zone := loc
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			zone = l
+		}
+	}
+	t, _ := time.ParseInLocation(icalLocal, p.value, zone)
+	return t.In(loc), false
+}
+
+// icalDuration matches durations like P1D, PT1H30M, or P2W.
+var icalDuration = regexp.MustCompile(`^[+-]?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses an iCalendar duration, returning 0 if it's invalid.
+func parseDuration(s string) time.Duration {
+	m := icalDuration.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		n, _ := strconv.Atoi(m[i+1])
+		d += time.Duration(n) * unit
+	}
+	return d
+}
+
+// unescapeText reverses the escaping of an iCalendar text value.
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// newUID returns a unique ID for a new calendar object.
+func newUID() string {
+	return uuid.NewString() + "@agent-desktop"
+}
@@ -0,0 +1,87 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeEvent(t *testing.T) {
+	stamp := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	e := Event{
+		Title: "Review, plan; ship",
+		Start: time.Date(2025, 3, 11, 14, 0, 0, 0, time.UTC),
+		End:   time.Date(2025, 3, 11, 15, 0, 0, 0, time.UTC),
+		Notes: "Line one\nLine two " + strings.Repeat("ü", 40),
+	}
+	data := encodeEvent(e, "uid-1", stamp)
+	for _, want := range []string{
+		"BEGIN:VEVENT\r\nUID:uid-1\r\nDTSTAMP:20250310T090000Z\r\n",
+		"DTSTART:20250311T140000Z\r\nDTEND:20250311T150000Z\r\n",
+		`SUMMARY:Review\, plan\; ship` + "\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("event is missing %q:\n%s", want, data)
+		}
+	}
+	for _, line := range strings.Split(data, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line not folded: %q", line)
+		}
+	}
+
+	// The event parses back to what was written
+	events := parseEvents(data, time.UTC)
+	if len(events) != 1 || events[0].Title != e.Title || events[0].Notes != e.Notes || !events[0].Start.Equal(e.Start) || !events[0].End.Equal(e.End) {
+		t.Errorf("parsed = %+v", events)
+	}
+}
+
+func TestEncodeTodo(t *testing.T) {
+	data := encodeTodo(Reminder{Title: "Check the build", Due: time.Date(2025, 3, 10, 10, 30, 0, 0, time.UTC)}, "uid-2", time.Now())
+	for _, want := range []string{"BEGIN:VTODO\r\n", "DUE:20250310T103000Z\r\n", "SUMMARY:Check the build\r\n", "TRIGGER;RELATED=END:PT0S\r\n"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("todo is missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"SUMMARY:Dentist",
+		"DTSTART;TZID=Europe/Berlin:20250311T083000",
+		"DURATION:PT45M",
+		"LOCATION:Main St\\, 4",
+		"BEGIN:VALARM",
+		"DESCRIPTION:Not the event's description",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Holi",
+		" day",
+		"DTSTART;VALUE=DATE:20250314",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events := parseEvents(data, time.UTC)
+	if len(events) != 2 {
+		t.Fatalf("events = %+v", events)
+	}
+	dentist := events[0]
+	start := time.Date(2025, 3, 11, 8, 30, 0, 0, berlin)
+	if !dentist.Start.Equal(start) || !dentist.End.Equal(start.Add(45*time.Minute)) || dentist.Location != "Main St, 4" || dentist.Notes != "" {
+		t.Errorf("dentist = %+v", dentist)
+	}
+	holiday := events[1]
+	if holiday.Title != "Holiday" || !holiday.AllDay || !holiday.End.Equal(time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("holiday = %+v", holiday)
+	}
+}
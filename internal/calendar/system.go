@@ -0,0 +1,160 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+// system is the macOS Calendar and Reminders apps, driven with AppleScript.
+// Times are passed to scripts as seconds from the script's current date so
+// they need no date parsing, which depends on the user's locale.
+type system struct {
+	calendar string // Calendar for new events (empty = the first writable one)
+	list     string // Reminders list (empty = the default list)
+}
+
+// runScript runs an AppleScript with arguments and returns what it prints.
+// It is a variable so tests can capture scripts instead of running them.
+var runScript = func(ctx context.Context, script string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "osascript", append([]string{"-"}, args...)...)
+	cmd.Stdin = strings.NewReader(script)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func newSystem(s config.CalendarSettings) (*system, error) {
+	if runtime.GOOS != "darwin" {
+		return nil, errors.New("the system calendar is only supported on macOS; use a CalDAV calendar")
+	}
+	return &system{calendar: s.Calendar, list: s.ReminderList}, nil
+}
+
+// Field and record separators in the event list a script returns.
+const (
+	fieldSep  = "\x1f"
+	recordSep = "\x1e"
+)
+
+// listEventsScript prints the events overlapping a range as records of the
+// calendar, title, start and end offsets, all-day flag, and location.
+// Calendar only matches the first occurrence of a recurring event.
+const listEventsScript = `on run argv
+	set baseDate to current date
+	set fromDate to baseDate + (item 1 of argv as integer)
+	set toDate to baseDate + (item 2 of argv as integer)
+	set fs to character id 31
+	set rs to character id 30
+	set out to ""
+	tell application "Calendar"
+		repeat with c in calendars
+			set calName to name of c
+			repeat with e in (every event of c whose start date < toDate and end date > fromDate)
+				set t to summary of e
+				if t is missing value then set t to ""
+				set loc to location of e
+				if loc is missing value then set loc to ""
+				set out to out & calName & fs & t & fs & ((start date of e) - baseDate) & fs & ((end date of e) - baseDate) & fs & (allday event of e) & fs & loc & rs
+			end repeat
+		end repeat
+	end tell
+	return out
+end run`
+
+// createEventScript adds an event from its title, start and end offsets,
+// location, notes, calendar name, and all-day flag.
+const createEventScript = `on run argv
+	set baseDate to current date
+	set startDate to baseDate + (item 2 of argv as integer)
+	set endDate to baseDate + (item 3 of argv as integer)
+	tell application "Calendar"
+		if item 6 of argv is "" then
+			set c to first calendar whose writable is true
+		else
+			set c to calendar (item 6 of argv)
+		end if
+		make new event at end of events of c with properties {summary:item 1 of argv, start date:startDate, end date:endDate, location:item 4 of argv, description:item 5 of argv, allday event:(item 7 of argv is "true")}
+	end tell
+end run`
+
+// createReminderScript adds a reminder from its title, due offset, notes,
+// and list name.
+const createReminderScript = `on run argv
+	set dueDate to (current date) + (item 2 of argv as integer)
+	tell application "Reminders"
+		if item 4 of argv is "" then
+			set l to default list
+		else
+			set l to list (item 4 of argv)
+		end if
+		make new reminder at end of reminders of l with properties {name:item 1 of argv, body:item 3 of argv, due date:dueDate, remind me date:dueDate}
+	end tell
+end run`
+
+// offset returns t as whole seconds from base, for a script argument.
+func offset(t, base time.Time) string {
+	return strconv.FormatInt(int64(t.Sub(base).Round(time.Second)/time.Second), 10)
+}
+
+func (s *system) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	base := now()
+	out, err := runScript(ctx, listEventsScript, offset(from, base), offset(to, base))
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, record := range strings.Split(strings.TrimRight(out, "\r\n"), recordSep) {
+		fields := strings.Split(record, fieldSep)
+		if len(fields) != 6 {
+			continue
+		}
+		start, err1 := strconv.ParseFloat(fields[2], 64)
+		end, err2 := strconv.ParseFloat(fields[3], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		events = append(events, Event{
+			Calendar: fields[0],
+			Title:    fields[1],
+			Start:    base.Add(time.Duration(start) * time.Second).Truncate(time.Second),
+			End:      base.Add(time.Duration(end) * time.Second).Truncate(time.Second),
+			AllDay:   fields[4] == "true",
+			Location: fields[5],
+		})
+	}
+	return events, nil
+}
+
+func (s *system) CreateEvent(ctx context.Context, e Event) error {
+	base := now()
+	_, err := runScript(ctx, createEventScript,
+		e.Title, offset(e.Start, base), offset(e.End, base), e.Location, e.Notes, s.calendar, strconv.FormatBool(e.AllDay))
+	if err != nil {
+		return fmt.Errorf("Calendar: %w", err)
+	}
+	return nil
+}
+
+func (s *system) CreateReminder(ctx context.Context, r Reminder) error {
+	_, err := runScript(ctx, createReminderScript, r.Title, offset(r.Due, now()), r.Notes, s.list)
+	if err != nil {
+		return fmt.Errorf("Reminders: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package calendar
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSystem(t *testing.T) {
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+	fixNow(t, base)
+
+	var calls [][]string
+	orig := runScript
+	runScript = func(ctx context.Context, script string, args ...string) (string, error) {
+		calls = append(calls, append([]string{script}, args...))
+		if script == listEventsScript {
+			return "Work\x1fStandup\x1f5400\x1f6300\x1ffalse\x1fRoom 4\x1e" +
+				"Home\x1fHoliday\x1f-32400\x1f54000\x1ftrue\x1f\x1e\n", nil
+		}
+		return "", nil
+	}
+	defer func() { runScript = orig }()
+
+	s := &system{calendar: "Work", list: "Errands"}
+	ctx := context.Background()
+	events, err := s.Events(ctx, base, base.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+	if len(calls) != 1 || calls[0][1] != "0" || calls[0][2] != "86400" {
+		t.Errorf("list arguments = %q", calls[0][1:])
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %+v", events)
+	}
+	if e := events[0]; e.Calendar != "Work" || e.Title != "Standup" || !e.Start.Equal(base.Add(90*time.Minute)) || !e.End.Equal(base.Add(105*time.Minute)) || e.Location != "Room 4" {
+		t.Errorf("first event = %+v", e)
+	}
+	if e := events[1]; !e.AllDay || !e.Start.Equal(base.Add(-9*time.Hour)) {
+		t.Errorf("second event = %+v", e)
+	}
+
+	if err := s.CreateEvent(ctx, Event{Title: "Lunch", Start: base.Add(3 * time.Hour), End: base.Add(4 * time.Hour)}); err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	if got := strings.Join(calls[1][1:], "|"); got != "Lunch|10800|14400|||Work|false" {
+		t.Errorf("event arguments = %s", got)
+	}
+	if err := s.CreateReminder(ctx, Reminder{Title: "Stretch", Due: base.Add(30 * time.Minute), Notes: "Stand up"}); err != nil {
+		t.Fatalf("CreateReminder failed: %v", err)
+	}
+	if got := strings.Join(calls[2][1:], "|"); calls[2][0] != createReminderScript || got != "Stretch|1800|Stand up|Errands" {
+		t.Errorf("reminder arguments = %s", got)
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import "strings"
+
+// BridgeSettings connects chat bots that let allowed users give the agent
+// tasks remotely. Each chat thread or channel becomes a conversation.
+type BridgeSettings struct {
+	Slack   *SlackBridge   `json:"slack,omitempty"`
+	Discord *DiscordBridge `json:"discord,omitempty"`
+
+	// ApprovalPolicy applies to conversations started from chat that don't
+	// set their own: "auto", "destructive" (the default), or "always".
+	// Tool calls that need approval are posted with Approve and Deny buttons.
+	ApprovalPolicy string `json:"approval_policy,omitempty"`
+}
+
+// SlackBridge is a Slack app connected over Socket Mode, so no public URL
+// is needed.
+type SlackBridge struct {
+	BotToken string   `json:"bot_token"`          // xoxb-...
+	AppToken string   `json:"app_token"`          // xapp-..., with the connections:write scope
+	Users    []string `json:"users"`              // IDs of the users allowed to give tasks
+	Channels []string `json:"channels,omitempty"` // Channel IDs to listen in (empty = every channel the bot is in)
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// DiscordBridge is a Discord bot connected to the gateway.
+type DiscordBridge struct {
+	BotToken string   `json:"bot_token"`
+	Users    []string `json:"users"`              // IDs of the users allowed to give tasks
+	Channels []string `json:"channels,omitempty"` // Channel IDs to listen in (empty = every channel the bot can read)
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// Enabled reports whether the Slack bridge should connect.
+func (s *SlackBridge) Enabled() bool {
+	return s != nil && !s.Disabled
+}
+
+// Enabled reports whether the Discord bridge should connect.
+func (d *DiscordBridge) Enabled() bool {
+	return d != nil && !d.Disabled
+}
+
+func (c *Config) diagnoseBridges(d *diagnostics) {
+	b := c.Bridges
+	switch b.ApprovalPolicy {
+	case "", "auto", "destructive", "always":
+	default:
+		d.fail("bridges.approval_policy", "unknown approval_policy: "+b.ApprovalPolicy)
+	}
+	if b.ApprovalPolicy == "auto" && (b.Slack.Enabled() || b.Discord.Enabled()) {
+		d.warn("bridges.approval_policy", "tool calls from chat run without approval")
+	}
+
+	if b.Slack.Enabled() {
+		if !strings.HasPrefix(b.Slack.BotToken, "xoxb-") {
+			d.fail("bridges.slack.bot_token", "Slack bot_token must be a bot token (xoxb-...)")
+		}
+		if !strings.HasPrefix(b.Slack.AppToken, "xapp-") {
+			d.fail("bridges.slack.app_token", "Slack app_token must be an app-level token (xapp-...)")
+		}
+		if len(b.Slack.Users) == 0 {
+			d.fail("bridges.slack.users", "list the Slack user IDs allowed to give the agent tasks")
+		}
+	}
+	if b.Discord.Enabled() {
+		if b.Discord.BotToken == "" {
+			d.fail("bridges.discord.bot_token", "Discord bot_token is required")
+		}
+		if len(b.Discord.Users) == 0 {
+			d.fail("bridges.discord.users", "list the Discord user IDs allowed to give the agent tasks")
+		}
+	}
+}
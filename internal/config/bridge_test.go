@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Diagnose_Bridges(t *testing.T) {
+	tests := []struct {
+		name    string
+		bridges BridgeSettings
+		field   string // Field of the expected problem, "" for none
+	}{
+		{"none", BridgeSettings{}, ""},
+		{"slack", BridgeSettings{Slack: &SlackBridge{BotToken: "xoxb-1", AppToken: "xapp-1", Users: []string{"U1"}}}, ""},
+		{"discord", BridgeSettings{Discord: &DiscordBridge{BotToken: "token", Users: []string{"42"}}}, ""},
+		{"disabled", BridgeSettings{Slack: &SlackBridge{Disabled: true}}, ""},
+		{"slack app token", BridgeSettings{Slack: &SlackBridge{BotToken: "xoxb-1", AppToken: "xoxb-2", Users: []string{"U1"}}}, "bridges.slack.app_token"},
+		{"slack users", BridgeSettings{Slack: &SlackBridge{BotToken: "xoxb-1", AppToken: "xapp-1"}}, "bridges.slack.users"},
+		{"discord token", BridgeSettings{Discord: &DiscordBridge{Users: []string{"42"}}}, "bridges.discord.bot_token"},
+		{"policy", BridgeSettings{ApprovalPolicy: "never"}, "bridges.approval_policy"},
+		{"auto approval", BridgeSettings{ApprovalPolicy: "auto", Discord: &DiscordBridge{BotToken: "token", Users: []string{"42"}}}, "bridges.approval_policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", Bridges: tt.bridges}
+
+			var got []string
+			for _, fe := range cfg.Diagnose() {
+				if strings.HasPrefix(fe.Field, "bridges") {
+					got = append(got, fe.Field)
+				}
+			}
+			if tt.field == "" && len(got) > 0 {
+				t.Errorf("unexpected problems: %v", got)
+			}
+			if tt.field != "" && (len(got) != 1 || got[0] != tt.field) {
+				t.Errorf("problems = %v, want %s", got, tt.field)
+			}
+		})
+	}
+}
+
+func TestBundle_LeavesOutBotTokens(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	cfg := profileConfig()
+	cfg.Bridges.Slack = &SlackBridge{BotToken: "xoxb-secret", AppToken: "xapp-secret", Users: []string{"U1"}}
+	cfg.Bridges.Discord = &DiscordBridge{BotToken: "discord-secret", Users: []string{"42"}}
+	bundlePath := filepath.Join(t.TempDir(), "settings.json")
+	if err := ExportBundle(bundlePath, cfg); err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(bundlePath)
+	if strings.Contains(string(data), "secret") {
+		t.Error("expected bot tokens to be left out of the bundle")
+	}
+	if !strings.Contains(string(data), "U1") {
+		t.Error("expected the allowed users to be exported")
+	}
+
+	imported, err := ImportBundle(bundlePath, cfg)
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if imported.Bridges.Slack.AppToken != "xapp-secret" || imported.Bridges.Discord.BotToken != "discord-secret" {
+		t.Errorf("bridges = %+v, %+v; want the local tokens kept", imported.Bridges.Slack, imported.Bridges.Discord)
+	}
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SystemPromptFile is the name of the system prompt template in the config directory.
+const SystemPromptFile = "system_prompt.md"
+
+// PersonasFile is the name of the persona library in the config directory.
+const PersonasFile = "personas.json"
+
+// BundleVersion is the format version of settings bundles written by ExportBundle.
+const BundleVersion = 1
+
+// bundleFiles are the settings files in the config directory copied into bundles.
+var bundleFiles = []string{SystemPromptFile, PersonasFile}
+
+// Bundle is a portable snapshot of the user's settings for setting up
+// another machine. Secrets such as the API key are never included.
+type Bundle struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Config     Config            `json:"config"`
+	Files      map[string]string `json:"files,omitempty"` // Settings file name -> contents
+}
+
+// ExportBundle writes cfg and the settings files in the config directory to
+// a bundle at path. API keys, including those of profiles, webhook
+// secrets, bot tokens, and the calendar and email passwords are left out.
+func ExportBundle(path string, cfg *Config) error {
+	bundle := Bundle{
+		Version:    BundleVersion,
+		ExportedAt: time.Now(),
+		Config:     *cfg,
+		Files:      map[string]string{},
+	}
+	bundle.Config.APIKey = ""
+	bundle.Config.Profiles = make([]Profile, len(cfg.Profiles))
+	for i, p := range cfg.Profiles {
+		p.APIKey = ""
+		bundle.Config.Profiles[i] = p
+	}
+	bundle.Config.Webhooks = make([]Webhook, len(cfg.Webhooks))
+	for i, w := range cfg.Webhooks {
+		w.Secret = ""
+		bundle.Config.Webhooks[i] = w
+	}
+	if s := cfg.Bridges.Slack; s != nil {
+		bundle.Config.Bridges.Slack = &SlackBridge{Users: s.Users, Channels: s.Channels, Disabled: s.Disabled}
+	}
+	if dc := cfg.Bridges.Discord; dc != nil {
+		bundle.Config.Bridges.Discord = &DiscordBridge{Users: dc.Users, Channels: dc.Channels, Disabled: dc.Disabled}
+	}
+	bundle.Config.Calendar.Password = ""
+	bundle.Config.Email.Password = ""
+	bundle.Config.Tracing.Headers = nil // They usually hold a collector's API key
+	bundle.Config.Version = CurrentVersion
+
+	for _, name := range bundleFiles {
+		data, err := os.ReadFile(filepath.Join(configDir, name))
+		if err == nil {
+			bundle.Files[name] = string(data)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ImportBundle reads a bundle from path, writes its settings files to the
+// config directory, and returns its configuration merged with current:
+// secrets that bundles don't carry are kept from current. The returned
+// config is saved.
+func ImportBundle(path string, current *Config) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("not a settings bundle: %w", err)
+	}
+	if bundle.Version == 0 {
+		return nil, errors.New("not a settings bundle: missing version")
+	}
+	if bundle.Version > BundleVersion {
+		return nil, fmt.Errorf("settings bundle version %d is newer than this app supports (%d)", bundle.Version, BundleVersion)
+	}
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, err
+	}
+	for _, name := range bundleFiles {
+		contents, ok := bundle.Files[name]
+		if !ok {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(configDir, name), []byte(contents), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	cfg := bundle.Config
+	if current != nil {
+		if cfg.APIKey == "" {
+			cfg.APIKey = current.APIKey
+		}
+		for i, p := range cfg.Profiles {
+			if existing, ok := current.FindProfile(p.Name); ok && p.APIKey == "" {
+				cfg.Profiles[i].APIKey = existing.APIKey
+			}
+		}
+		for i, w := range cfg.Webhooks {
+			for _, existing := range current.Webhooks {
+				if w.Secret == "" && existing.URL == w.URL {
+					cfg.Webhooks[i].Secret = existing.Secret
+				}
+			}
+		}
+		if s, cur := cfg.Bridges.Slack, current.Bridges.Slack; s != nil && cur != nil && s.BotToken == "" {
+			s.BotToken, s.AppToken = cur.BotToken, cur.AppToken
+		}
+		if dc, cur := cfg.Bridges.Discord, current.Bridges.Discord; dc != nil && cur != nil && dc.BotToken == "" {
+			dc.BotToken = cur.BotToken
+		}
+		if cfg.Calendar.Password == "" && cfg.Calendar.Username == current.Calendar.Username {
+			cfg.Calendar.Password = current.Calendar.Password
+		}
+		if cfg.Email.Password == "" && cfg.Email.Login() == current.Email.Login() {
+			cfg.Email.Password = current.Email.Password
+		}
+		if cfg.Tracing.Headers == nil && cfg.Tracing.Endpoint == current.Tracing.Endpoint {
+			cfg.Tracing.Headers = current.Tracing.Headers
+		}
+	}
+	if err := cfg.Save(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
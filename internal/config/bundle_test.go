@@ -0,0 +1,216 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundle_ExportImportRoundTrip(t *testing.T) {
+	tmpDir, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	promptPath := filepath.Join(tmpDir, SystemPromptFile)
+	os.WriteFile(promptPath, []byte("House rules for {USERNAME}"), 0644)
+
+	cfg := &Config{
+		APIKey:           "sk-secret",
+		Endpoint:         "http://localhost:1234/v1",
+		Model:            "local-model",
+		ExecutionTimeout: 90,
+		StorageBackend:   StorageBackendSQLite,
+	}
+	bundlePath := filepath.Join(t.TempDir(), "settings.json")
+	if err := ExportBundle(bundlePath, cfg); err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(bundlePath)
+	if strings.Contains(string(data), "sk-secret") {
+		t.Error("expected the API key to be left out of the bundle")
+	}
+
+	// Import on a "new machine" that already has its own key
+	os.Remove(promptPath)
+	imported, err := ImportBundle(bundlePath, &Config{APIKey: "sk-local"})
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+
+	if imported.APIKey != "sk-local" {
+		t.Errorf("expected local API key to be kept, got %q", imported.APIKey)
+	}
+	if imported.Model != "local-model" || imported.ExecutionTimeout != 90 || imported.StorageBackend != StorageBackendSQLite {
+		t.Errorf("unexpected imported config: %+v", imported)
+	}
+	prompt, err := os.ReadFile(promptPath)
+	if err != nil || string(prompt) != "House rules for {USERNAME}" {
+		t.Errorf("expected system prompt template to be restored, got %q (%v)", prompt, err)
+	}
+
+	saved, err := Load()
+	if err != nil || saved.Model != "local-model" {
+		t.Errorf("expected imported config to be saved, got %+v (%v)", saved, err)
+	}
+}
+
+func TestImportBundle_RejectsInvalid(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	cases := map[string]string{
+		"not-json.json": "hello",
+		"no-version":    `{"config": {}}`,
+		"future.json":   `{"version": 99, "config": {}}`,
+	}
+	for name, contents := range cases {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(contents), 0644)
+		if _, err := ImportBundle(path, nil); err == nil {
+			t.Errorf("%s: expected import to fail", name)
+		}
+	}
+}
+
+func TestBundle_LeavesOutProfileKeys(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	cfg := profileConfig()
+	bundlePath := filepath.Join(t.TempDir(), "settings.json")
+	if err := ExportBundle(bundlePath, cfg); err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(bundlePath)
+	if strings.Contains(string(data), "ds-key") {
+		t.Error("expected profile API keys to be left out of the bundle")
+	}
+	if cfg.Profiles[0].APIKey != "ds-key" {
+		t.Error("ExportBundle must not modify the config")
+	}
+
+	imported, err := ImportBundle(bundlePath, profileConfig())
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if p, _ := imported.FindProfile("coding"); p.APIKey != "ds-key" {
+		t.Errorf("expected local profile key to be kept, got %q", p.APIKey)
+	}
+}
+
+func TestBundle_LeavesOutWebhookSecrets(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	cfg := profileConfig()
+	cfg.Webhooks = []Webhook{{URL: "https://hooks.example.com/agent", Secret: "hook-secret"}}
+	bundlePath := filepath.Join(t.TempDir(), "settings.json")
+	if err := ExportBundle(bundlePath, cfg); err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(bundlePath)
+	if strings.Contains(string(data), "hook-secret") {
+		t.Error("expected webhook secrets to be left out of the bundle")
+	}
+	if cfg.Webhooks[0].Secret != "hook-secret" {
+		t.Error("ExportBundle must not modify the config")
+	}
+
+	imported, err := ImportBundle(bundlePath, cfg)
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if len(imported.Webhooks) != 1 || imported.Webhooks[0].Secret != "hook-secret" {
+		t.Errorf("webhooks = %+v, want the local secret kept", imported.Webhooks)
+	}
+}
+
+func TestBundle_LeavesOutCalendarPassword(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	cfg := profileConfig()
+	cfg.Calendar = CalendarSettings{Provider: CalendarProviderCalDAV, URL: "https://dav.example.com/cal/", Username: "me", Password: "cal-secret"}
+	bundlePath := filepath.Join(t.TempDir(), "settings.json")
+	if err := ExportBundle(bundlePath, cfg); err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+	if data, _ := os.ReadFile(bundlePath); strings.Contains(string(data), "cal-secret") {
+		t.Error("expected the calendar password to be left out of the bundle")
+	}
+
+	imported, err := ImportBundle(bundlePath, cfg)
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if imported.Calendar.Password != "cal-secret" {
+		t.Errorf("calendar = %+v, want the local password kept", imported.Calendar)
+	}
+}
+
+func TestBundle_LeavesOutEmailPassword(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	cfg := profileConfig()
+	cfg.Email = EmailSettings{Address: "me@example.com", Password: "mail-secret", SMTPServer: "smtp.example.com:465"}
+	bundlePath := filepath.Join(t.TempDir(), "settings.json")
+	if err := ExportBundle(bundlePath, cfg); err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+	if data, _ := os.ReadFile(bundlePath); strings.Contains(string(data), "mail-secret") {
+		t.Error("expected the email password to be left out of the bundle")
+	}
+
+	imported, err := ImportBundle(bundlePath, cfg)
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if imported.Email.Password != "mail-secret" {
+		t.Errorf("email = %+v, want the local password kept", imported.Email)
+	}
+
+	// A bundle for another account doesn't get this one's password
+	other := *cfg
+	other.Email.Address = "someone@example.com"
+	if imported, _ := ImportBundle(bundlePath, &other); imported.Email.Password != "" {
+		t.Error("expected no password for a different login")
+	}
+}
+
+func TestBundle_LeavesOutTracingHeaders(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	cfg := profileConfig()
+	cfg.Tracing = TracingSettings{Endpoint: "https://otlp.example.com", Headers: map[string]string{"x-api-key": "trace-secret"}}
+	bundlePath := filepath.Join(t.TempDir(), "settings.json")
+	if err := ExportBundle(bundlePath, cfg); err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+	if data, _ := os.ReadFile(bundlePath); strings.Contains(string(data), "trace-secret") {
+		t.Error("expected the tracing headers to be left out of the bundle")
+	}
+	if cfg.Tracing.Headers["x-api-key"] != "trace-secret" {
+		t.Error("exporting changed the config's own headers")
+	}
+
+	imported, err := ImportBundle(bundlePath, cfg)
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if imported.Tracing.Headers["x-api-key"] != "trace-secret" {
+		t.Errorf("tracing = %+v, want the local headers kept", imported.Tracing)
+	}
+
+	// A bundle for another collector doesn't get this one's headers
+	other := *cfg
+	other.Tracing.Endpoint = "https://other.example.com"
+	if imported, _ := ImportBundle(bundlePath, &other); imported.Tracing.Headers != nil {
+		t.Error("expected no headers for a different collector")
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"net/url"
+	"runtime"
+)
+
+// Calendar providers accepted in CalendarSettings.Provider.
+const (
+	CalendarProviderCalDAV = "caldav" // Any CalDAV server, e.g. Nextcloud, Fastmail, or iCloud
+	CalendarProviderSystem = "system" // The macOS Calendar and Reminders apps
+)
+
+// CalendarSettings gives the agent tools to list upcoming events and
+// create events and reminders.
+type CalendarSettings struct {
+	Provider string `json:"provider,omitempty"` // "caldav", "system", or "" (no calendar tools)
+
+	// CalDAV settings
+	URL      string `json:"url,omitempty"`       // Calendar collection events are read from and added to
+	TasksURL string `json:"tasks_url,omitempty"` // Collection reminders are added to as tasks (empty = url)
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"` // An app password where the server offers them
+
+	// System settings
+	Calendar     string `json:"calendar,omitempty"`      // Calendar new events go in (empty = the first writable one)
+	ReminderList string `json:"reminder_list,omitempty"` // Reminders list (empty = the default list)
+}
+
+func (c *Config) diagnoseCalendar(d *diagnostics) {
+	cal := c.Calendar
+	switch cal.Provider {
+	case "":
+	case CalendarProviderCalDAV:
+		check := func(field, raw string) {
+			u, err := url.Parse(raw)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				d.fail(field, "CalDAV "+field+" must be the http(s) URL of a calendar collection")
+			} else if u.Scheme == "http" && cal.Password != "" {
+				d.warn(field, "the CalDAV password is sent unencrypted over http")
+			}
+		}
+		check("calendar.url", cal.URL)
+		if cal.TasksURL != "" {
+			check("calendar.tasks_url", cal.TasksURL)
+		}
+	case CalendarProviderSystem:
+		if runtime.GOOS != "darwin" {
+			d.fail("calendar.provider", "the system calendar is only supported on macOS; use caldav")
+		}
+	default:
+		d.fail("calendar.provider", "unknown calendar provider: "+cal.Provider)
+	}
+}
@@ -5,7 +5,6 @@ package config
 
 import (
 	"encoding/json"
-	"errors"
 	"os"
 	"path/filepath"
 )
@@ -29,13 +28,219 @@ func init() {
 // - OpenRouter (https://openrouter.ai/api/v1)
 // - Any other OpenAI-compatible API
 type Config struct {
+	// Version is the schema version of the file; see CurrentVersion
+	Version int `json:"version"`
+
+	// SetupComplete is set once the first-run setup wizard has finished
+	SetupComplete bool `json:"setup_complete,omitempty"`
+
 	// LLM API settings
 	APIKey   string `json:"api_key"`
 	Endpoint string `json:"endpoint"`   // Base URL (e.g., https://api.openai.com/v1)
 	Model    string `json:"model"`      // Model name (e.g., gpt-4o, deepseek-chat)
 
+	// Embeddings for semantic search, from the same endpoint
+	EmbeddingModel string `json:"embedding_model,omitempty"` // Empty = text-embedding-3-small, or nomic-embed-text on Ollama
+	EmbeddingAPI   string `json:"embedding_api,omitempty"`   // "openai" or "ollama" (empty = ollama on port 11434, else openai)
+
+	// Named provider profiles, picked for new conversations by ProfileRules
+	Profiles     []Profile     `json:"profiles,omitempty"`
+	ProfileRules []ProfileRule `json:"profile_rules,omitempty"`
+
+	// TLS settings for self-hosted endpoints
+	CACertPath         string `json:"ca_cert_path,omitempty"`         // PEM file of extra CA certificates to trust
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"` // Disable certificate verification (testing only)
+
+	// WarmStart sends a one-token request when a conversation is opened, so
+	// the connection is up and the prompt cached before its first turn
+	WarmStart bool `json:"warm_start,omitempty"`
+
 	// Execution settings
-	ExecutionTimeout int `json:"execution_timeout"`
+	ExecutionTimeout  int    `json:"execution_timeout"`
+	ContextWindow     int    `json:"context_window,omitempty"`      // Model context size in tokens (0 = default)
+	DefaultWorkingDir string `json:"default_working_dir,omitempty"` // Directory conversations start in (empty = home)
+	ShellHistorySize  int    `json:"shell_history_size,omitempty"`  // Commands kept in a session's history (0 = 1000)
+
+	// Budget and execution limits
+	Limits Limits `json:"limits"`
+
+	// Storage settings
+	StorageBackend     string `json:"storage_backend,omitempty"`      // "json" (default) or "sqlite"
+	TrashRetentionDays int    `json:"trash_retention_days,omitempty"` // Days deleted conversations are kept (0 = 30)
+	// CompressConversations gzips conversation files and keeps large tool
+	// outputs once in a blobs folder (JSON storage only)
+	CompressConversations bool `json:"compress_conversations,omitempty"`
+
+	// Retention settings (0 = disabled); pinned conversations are exempt
+	ArchiveAfterDays int `json:"archive_after_days,omitempty"` // Archive conversations idle this many days
+	DeleteAfterDays  int `json:"delete_after_days,omitempty"`  // Trash conversations idle this many days
+	MaxStoreSizeMB   int `json:"max_store_size_mb,omitempty"`  // Trash the oldest conversations beyond this size
+
+	// Window settings
+	MinimizeToTray bool   `json:"minimize_to_tray,omitempty"` // Closing the window keeps the app running in the tray
+	GlobalHotkey   string `json:"global_hotkey,omitempty"`    // Shortcut that summons the quick-task entry, e.g. "Ctrl+Shift+Space"
+
+	// DisableElevation stops installer commands that need administrator
+	// rights from being run through the system's administrator prompt
+	DisableElevation bool `json:"disable_elevation,omitempty"`
+
+	// Model Context Protocol servers whose tools the agent can use
+	MCPServers []MCPServer `json:"mcp_servers,omitempty"`
+
+	// Language of messages produced by the app, e.g. "de" (empty = system locale)
+	Locale string `json:"locale,omitempty"`
+
+	// Desktop notification settings
+	Notifications NotificationSettings `json:"notifications"`
+
+	// URLs notified when agent runs end
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+
+	// Chat bots that accept tasks from Slack or Discord
+	Bridges BridgeSettings `json:"bridges"`
+
+	// Calendar the agent can read and add events and reminders to
+	Calendar CalendarSettings `json:"calendar"`
+
+	// Mailbox the agent can search and send mail from, with approval
+	Email EmailSettings `json:"email"`
+
+	// OpenTelemetry traces of agent runs, exported to an OTLP collector
+	Tracing TracingSettings `json:"tracing"`
+
+	// Anonymous feature usage and crash counts, sent only when turned on
+	Telemetry TelemetrySettings `json:"telemetry"`
+
+	// Folder watched for task.md and task.json files that start runs (empty = off)
+	InboxDir string `json:"inbox_dir,omitempty"`
+
+	// Workspace folder kept in a vector index for semantic search (empty = off)
+	IndexRoot string `json:"index_root,omitempty"`
+
+	// How indexed files are split into chunks, by extension (e.g. ".md") with
+	// "*" for other files (empty = by definition for source code, by
+	// paragraph for prose, by lines otherwise)
+	IndexChunking map[string]string `json:"index_chunking,omitempty"`
+
+	// Don't add indexed passages to tasks that mention the indexed folder
+	DisableAutoContext bool `json:"disable_auto_context,omitempty"`
+
+	// Keep a summary of recent work, updated after each run, and show it to
+	// new conversations
+	RollingSummary bool `json:"rolling_summary,omitempty"`
+
+	// Logging settings
+	LogLevel         string `json:"log_level,omitempty"`          // "debug", "info" (default), "warn", or "error"
+	LogFile          string `json:"log_file,omitempty"`           // Log file path (empty = logs/agent-desktop.log in the config directory)
+	LogRetentionDays int    `json:"log_retention_days,omitempty"` // Days old log files are kept (0 = 7)
+
+	// CaptureLLMTraffic keeps the full bodies of recent requests to the
+	// provider, with keys masked, for diagnosing provider bugs
+	CaptureLLMTraffic bool `json:"capture_llm_traffic,omitempty"`
+
+	// Cassette records exchanges with the provider to a file, scrubbed of
+	// secrets, or replays them from it without contacting the provider, for
+	// deterministic tests and offline demos
+	Cassette     string `json:"cassette,omitempty"`      // Cassette file path (empty = off)
+	CassetteMode string `json:"cassette_mode,omitempty"` // "replay" (default) or "record"
+
+	// Update settings
+	UpdateChannel      string `json:"update_channel,omitempty"`       // "stable" (default) or "beta"
+	UpdateFeedURL      string `json:"update_feed_url,omitempty"`      // Release feed (empty = the project's feed)
+	DisableUpdateCheck bool   `json:"disable_update_check,omitempty"` // Don't check for updates at startup
+
+	// Privacy settings
+	DisableLLMTitles bool   `json:"disable_llm_titles,omitempty"` // Title conversations locally instead of asking the LLM
+	Encryption       string `json:"encryption,omitempty"`         // "" (off), "keychain", or "passphrase"
+
+	// env records the values replaced by ApplyEnv, which Save writes instead
+	env []envOverride
+}
+
+// Cassette modes accepted in Config.CassetteMode.
+const (
+	CassetteReplay = "replay" // Answer requests from the cassette
+	CassetteRecord = "record" // Send requests and save the exchanges
+)
+
+// Storage backend names accepted in Config.StorageBackend.
+const (
+	StorageBackendJSON   = "json"
+	StorageBackendSQLite = "sqlite"
+)
+
+// Embedding APIs accepted in Config.EmbeddingAPI.
+const (
+	EmbeddingAPIOpenAI = "openai" // POST {endpoint}/embeddings
+	EmbeddingAPIOllama = "ollama" // Ollama's native POST /api/embed
+)
+
+// Chunking strategies accepted in Config.IndexChunking.
+const (
+	ChunkLines      = "lines"      // Runs of up to 60 lines
+	ChunkTokens     = "tokens"     // Runs of about 512 tokens
+	ChunkParagraphs = "paragraphs" // Paragraphs separated by blank lines, short ones merged
+	ChunkCode       = "code"       // One function, type, or class per chunk
+)
+
+// Encryption modes accepted in Config.Encryption.
+const (
+	EncryptionKeychain   = "keychain"   // Key generated and stored in the OS keychain
+	EncryptionPassphrase = "passphrase" // Key derived from a passphrase entered each session
+)
+
+// Update channels accepted in Config.UpdateChannel.
+const (
+	UpdateChannelStable = "stable"
+	UpdateChannelBeta   = "beta"
+)
+
+// Dir returns the directory holding config.json and other user settings files.
+func Dir() string {
+	return configDir
+}
+
+// LogFilePath returns the configured log file, or the default location in
+// the config directory.
+func (c *Config) LogFilePath() string {
+	if c.LogFile != "" {
+		return c.LogFile
+	}
+	return filepath.Join(configDir, "logs", "agent-desktop.log")
+}
+
+// UpdatesDir returns the directory downloaded updates are staged in.
+func UpdatesDir() string {
+	return filepath.Join(configDir, "updates")
+}
+
+// SnapshotsDir returns the directory holding the original contents of
+// files changed by the agent.
+func SnapshotsDir() string {
+	return filepath.Join(configDir, "snapshots")
+}
+
+// IndexDir returns the directory holding the vector indexes of workspace
+// folders.
+func IndexDir() string {
+	return filepath.Join(configDir, "index")
+}
+
+// OutputsDir returns the directory holding full tool outputs that were
+// too large or too binary to show the model.
+func OutputsDir() string {
+	return filepath.Join(configDir, "outputs")
+}
+
+// AuditLogPath returns the file every command the agent runs is logged in.
+func AuditLogPath() string {
+	return filepath.Join(configDir, "logs", "commands.jsonl")
+}
+
+// ToolsDir returns the directory holding tool plugins: executables with a
+// JSON manifest that the agent can call.
+func ToolsDir() string {
+	return filepath.Join(configDir, "tools")
 }
 
 // getConfigPath returns the full path to the config file.
@@ -45,26 +250,32 @@ func getConfigPath() string {
 
 // Load loads the configuration from disk.
 // If the config file doesn't exist, it returns a default configuration.
+// Environment variables override file values; see ApplyEnv.
 func Load() (*Config, error) {
 	configPath := getConfigPath()
 
+	var cfg Config
 	data, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Return default config if file doesn't exist
-			return &Config{
-				Endpoint:         "https://api.openai.com/v1",
-				ExecutionTimeout: 60,
-			}, nil
-		}
+	if err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
+	if err == nil {
+		upgraded, version, migrated, err := migrate(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(upgraded, &cfg); err != nil {
+			return nil, err
+		}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, err
+		// Persist the upgrade, keeping a copy of the original file
+		if migrated && backupConfig(configPath, data, version) == nil {
+			cfg.Save()
+		}
 	}
 
+	cfg.ApplyEnv()
+
 	// Ensure default timeout if not set
 	if cfg.ExecutionTimeout == 0 {
 		cfg.ExecutionTimeout = 60
@@ -78,34 +289,27 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
-// Save saves the configuration to disk.
-// It creates the config directory if it doesn't exist.
+// Save saves the configuration to disk, readable only by the user since it
+// holds the API key. Values from environment variables aren't saved; the
+// file keeps its own. It creates the config directory if it doesn't exist.
 func (c *Config) Save() error {
 	// Create config directory if it doesn't exist
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return err
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	c.Version = CurrentVersion
+	data, err := json.MarshalIndent(c.WithoutEnv(), "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(getConfigPath(), data, 0644)
-}
-
-// Validate checks if the configuration has all required fields.
-func (c *Config) Validate() error {
-	if c.APIKey == "" {
-		return errors.New("api_key is required")
-	}
-	if c.Endpoint == "" {
-		return errors.New("endpoint is required")
-	}
-	if c.Model == "" {
-		return errors.New("model is required")
+	path := getConfigPath()
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
 	}
-	return nil
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
 }
 
 // IsConfigured returns true if all required fields are set.
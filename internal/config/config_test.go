@@ -19,6 +19,11 @@ func setupTestConfigDir(t *testing.T) (string, func()) {
 	originalConfigDir := configDir
 	configDir = tmpDir
 
+	// Keep the environment from overriding test configs
+	for _, name := range envOverrides {
+		t.Setenv(name, "")
+	}
+
 	cleanup := func() {
 		configDir = originalConfigDir
 		os.RemoveAll(tmpDir)
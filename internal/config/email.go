@@ -0,0 +1,76 @@
+package config
+
+import (
+	"net"
+	"net/mail"
+	"strings"
+)
+
+// EmailSettings gives the agent tools to search a mailbox over IMAP and to
+// send mail over SMTP. Every message sent waits for the user's approval.
+type EmailSettings struct {
+	Address    string `json:"address,omitempty"`     // The user's address: the sender, and the default recipient
+	Username   string `json:"username,omitempty"`    // Login for both servers (empty = address)
+	Password   string `json:"password,omitempty"`    // An app password where the provider offers them
+	IMAPServer string `json:"imap_server,omitempty"` // host:port for search_email; 993 uses TLS, other ports STARTTLS (empty = no search_email)
+	SMTPServer string `json:"smtp_server,omitempty"` // host:port for send_email; 465 uses TLS, other ports STARTTLS (empty = no send_email)
+
+	// Recipients send_email may write to: addresses, or domains written as
+	// "@example.com". The user's own address is always allowed, and is the
+	// only one allowed when this is empty.
+	AllowedRecipients []string `json:"allowed_recipients,omitempty"`
+}
+
+// Enabled reports whether any email tools are configured.
+func (e EmailSettings) Enabled() bool {
+	return e.Address != "" && (e.IMAPServer != "" || e.SMTPServer != "")
+}
+
+// Login returns the username for both servers.
+func (e EmailSettings) Login() string {
+	if e.Username != "" {
+		return e.Username
+	}
+	return e.Address
+}
+
+// AllowsRecipient reports whether send_email may write to addr.
+func (e EmailSettings) AllowsRecipient(addr string) bool {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	if addr == "" {
+		return false
+	}
+	if strings.EqualFold(addr, e.Address) {
+		return true
+	}
+	for _, allowed := range e.AllowedRecipients {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == addr || (strings.HasPrefix(allowed, "@") && strings.HasSuffix(addr, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) diagnoseEmail(d *diagnostics) {
+	e := c.Email
+	if e.Address == "" && e.IMAPServer == "" && e.SMTPServer == "" {
+		return
+	}
+	if _, err := mail.ParseAddress(e.Address); err != nil {
+		d.fail("email.address", "email address is missing or invalid")
+	}
+	checkServer := func(field, server, example string) {
+		if host, port, err := net.SplitHostPort(server); server != "" && (err != nil || host == "" || port == "") {
+			d.fail(field, "server must be host:port, e.g. "+example)
+		}
+	}
+	checkServer("email.imap_server", e.IMAPServer, "imap.example.com:993")
+	checkServer("email.smtp_server", e.SMTPServer, "smtp.example.com:465")
+	if e.IMAPServer == "" && e.SMTPServer == "" {
+		d.warn("email.address", "set imap_server or smtp_server to use the email tools")
+	}
+	if e.Password == "" {
+		d.warn("email.password", "email servers usually need a password")
+	}
+}
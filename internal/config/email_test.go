@@ -0,0 +1,23 @@
+package config
+
+import "testing"
+
+func TestEmailSettings_AllowsRecipient(t *testing.T) {
+	e := EmailSettings{Address: "Me@Example.com", AllowedRecipients: []string{"ann@example.com", "@team.example.org"}}
+	tests := map[string]bool{
+		"me@example.com":           true,
+		"ANN@example.com":          true,
+		"joe@team.example.org":     true,
+		"joe@evilteam.example.org": false,
+		"bob@example.com":          false,
+		"":                         false,
+	}
+	for addr, want := range tests {
+		if got := e.AllowsRecipient(addr); got != want {
+			t.Errorf("AllowsRecipient(%q) = %v, want %v", addr, got, want)
+		}
+	}
+	if (EmailSettings{Address: "me@example.com"}).Login() != "me@example.com" {
+		t.Error("Login should default to the address")
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// Environment variables that override values from config.json. They are
+// applied by Load with this precedence, highest first:
+//
+//  1. LLM_* variables (and AGENT_DESKTOP_* for app settings)
+//  2. OPENAI_* variables, for compatibility with other OpenAI tooling
+//  3. config.json
+//  4. built-in defaults
+//
+// Empty variables are ignored, as are numeric variables that don't parse.
+const (
+	EnvEndpoint         = "LLM_ENDPOINT"
+	EnvAPIKey           = "LLM_API_KEY"
+	EnvModel            = "LLM_MODEL"
+	EnvExecutionTimeout = "AGENT_DESKTOP_EXECUTION_TIMEOUT" // Seconds
+	EnvContextWindow    = "AGENT_DESKTOP_CONTEXT_WINDOW"    // Tokens
+	EnvStorageBackend   = "AGENT_DESKTOP_STORAGE_BACKEND"   // "json" or "sqlite"
+	EnvEncryption       = "AGENT_DESKTOP_ENCRYPTION"        // "", "keychain", or "passphrase"
+	EnvCassette         = "AGENT_DESKTOP_CASSETTE"          // Cassette file path
+	EnvCassetteMode     = "AGENT_DESKTOP_CASSETTE_MODE"     // "replay" or "record"
+
+	envOpenAIEndpoint = "OPENAI_API_BASE"
+	envOpenAIAPIKey   = "OPENAI_API_KEY"
+	envOpenAIModel    = "OPENAI_MODEL"
+)
+
+// envOverrides lists every variable consulted by ApplyEnv.
+var envOverrides = []string{
+	EnvEndpoint, EnvAPIKey, EnvModel,
+	EnvExecutionTimeout, EnvContextWindow, EnvStorageBackend, EnvEncryption,
+	EnvCassette, EnvCassetteMode,
+	envOpenAIEndpoint, envOpenAIAPIKey, envOpenAIModel,
+}
+
+// envOverride is an environment variable applied by ApplyEnv and the
+// config file's value it replaced, a string or an int.
+type envOverride struct {
+	name string
+	file any
+}
+
+// envStringFields and envIntFields map each variable to the field it sets.
+var (
+	envStringFields = map[string]func(*Config) *string{
+		EnvEndpoint:       func(c *Config) *string { return &c.Endpoint },
+		envOpenAIEndpoint: func(c *Config) *string { return &c.Endpoint },
+		EnvAPIKey:         func(c *Config) *string { return &c.APIKey },
+		envOpenAIAPIKey:   func(c *Config) *string { return &c.APIKey },
+		EnvModel:          func(c *Config) *string { return &c.Model },
+		envOpenAIModel:    func(c *Config) *string { return &c.Model },
+		EnvStorageBackend: func(c *Config) *string { return &c.StorageBackend },
+		EnvEncryption:     func(c *Config) *string { return &c.Encryption },
+		EnvCassette:       func(c *Config) *string { return &c.Cassette },
+		EnvCassetteMode:   func(c *Config) *string { return &c.CassetteMode },
+	}
+	envIntFields = map[string]func(*Config) *int{
+		EnvExecutionTimeout: func(c *Config) *int { return &c.ExecutionTimeout },
+		EnvContextWindow:    func(c *Config) *int { return &c.ContextWindow },
+	}
+)
+
+// ApplyEnv overrides configuration values with any set environment variables.
+// It returns the names of the variables that were applied. The values they
+// replaced are remembered, so Save writes those instead and values that only
+// came from the environment, such as an API key, never reach config.json.
+// Applying again first puts the replaced values back.
+func (c *Config) ApplyEnv() []string {
+	c.restoreEnv(c)
+	c.env = nil
+
+	var applied []string
+	setString := func(names ...string) {
+		for _, name := range names {
+			if v := os.Getenv(name); v != "" {
+				dst := envStringFields[name](c)
+				c.env = append(c.env, envOverride{name: name, file: *dst})
+				*dst = v
+				applied = append(applied, name)
+				return
+			}
+		}
+	}
+	setInt := func(name string) {
+		if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+			dst := envIntFields[name](c)
+			c.env = append(c.env, envOverride{name: name, file: *dst})
+			*dst = n
+			applied = append(applied, name)
+		}
+	}
+
+	setString(EnvEndpoint, envOpenAIEndpoint)
+	setString(EnvAPIKey, envOpenAIAPIKey)
+	setString(EnvModel, envOpenAIModel)
+	setInt(EnvExecutionTimeout)
+	setInt(EnvContextWindow)
+	setString(EnvStorageBackend)
+	setString(EnvEncryption)
+	setString(EnvCassette)
+	setString(EnvCassetteMode)
+	return applied
+}
+
+// WithoutEnv returns a copy of c with the values applied by ApplyEnv
+// replaced by the config file's, as Save writes it. Settings are edited on
+// this copy, so a value the user enters is saved even where an environment
+// variable overrides it.
+func (c *Config) WithoutEnv() *Config {
+	out := *c
+	c.restoreEnv(&out)
+	out.env = nil
+	return &out
+}
+
+// restoreEnv puts the config file's values for c's overrides into out.
+func (c *Config) restoreEnv(out *Config) {
+	for _, o := range c.env {
+		if field, ok := envStringFields[o.name]; ok {
+			*field(out) = o.file.(string)
+		} else {
+			*envIntFields[o.name](out) = o.file.(int)
+		}
+	}
+}
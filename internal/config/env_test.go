@@ -0,0 +1,142 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_EnvOverridesFile(t *testing.T) {
+	tmpDir, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	data := []byte(`{"api_key": "file-key", "endpoint": "http://file/v1", "model": "file-model", "execution_timeout": 90}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), data, 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	t.Setenv(EnvAPIKey, "env-key")
+	t.Setenv(EnvModel, "env-model")
+	t.Setenv(EnvExecutionTimeout, "not-a-number")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	if cfg.APIKey != "env-key" {
+		t.Errorf("expected APIKey from env, got %q", cfg.APIKey)
+	}
+	if cfg.Model != "env-model" {
+		t.Errorf("expected Model from env, got %q", cfg.Model)
+	}
+	if cfg.Endpoint != "http://file/v1" {
+		t.Errorf("expected Endpoint from file, got %q", cfg.Endpoint)
+	}
+	if cfg.ExecutionTimeout != 90 {
+		t.Errorf("expected invalid env timeout to be ignored, got %d", cfg.ExecutionTimeout)
+	}
+}
+
+func TestLoadConfig_EnvWithoutFile(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	t.Setenv(EnvEndpoint, "http://localhost:1234/v1")
+	t.Setenv(EnvAPIKey, "lm-studio")
+	t.Setenv(EnvModel, "local-model")
+	t.Setenv(EnvStorageBackend, StorageBackendSQLite)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	if !cfg.IsConfigured() {
+		t.Error("expected config from environment alone to be configured")
+	}
+	if cfg.StorageBackend != StorageBackendSQLite {
+		t.Errorf("expected sqlite backend, got %q", cfg.StorageBackend)
+	}
+	if cfg.ExecutionTimeout != 60 {
+		t.Errorf("expected default ExecutionTimeout=60, got %d", cfg.ExecutionTimeout)
+	}
+}
+
+func TestApplyEnv_Precedence(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	t.Setenv(envOpenAIAPIKey, "openai-key")
+	cfg := &Config{APIKey: "file-key"}
+	applied := cfg.ApplyEnv()
+	if cfg.APIKey != "openai-key" || len(applied) != 1 || applied[0] != envOpenAIAPIKey {
+		t.Errorf("expected OPENAI_API_KEY to apply, got %q (%v)", cfg.APIKey, applied)
+	}
+
+	t.Setenv(EnvAPIKey, "llm-key")
+	cfg.ApplyEnv()
+	if cfg.APIKey != "llm-key" {
+		t.Errorf("expected LLM_API_KEY to take precedence, got %q", cfg.APIKey)
+	}
+	if file := cfg.WithoutEnv(); file.APIKey != "file-key" {
+		t.Errorf("expected the file's key after applying twice, got %q", file.APIKey)
+	}
+}
+
+func TestSave_KeepsEnvOutOfFile(t *testing.T) {
+	tmpDir, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	os.WriteFile(configPath, []byte(`{"endpoint": "http://file/v1", "model": "file-model"}`), 0644)
+	t.Setenv(EnvAPIKey, "env-only-key")
+	t.Setenv(EnvModel, "env-model")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	cfg.Endpoint = "http://edited/v1"
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+	if cfg.APIKey != "env-only-key" {
+		t.Errorf("Save changed the running config's APIKey to %q", cfg.APIKey)
+	}
+	if data, _ := os.ReadFile(configPath); strings.Contains(string(data), "env-only-key") || strings.Contains(string(data), "env-model") {
+		t.Errorf("config.json holds values from the environment:\n%s", data)
+	}
+	if info, err := os.Stat(configPath); err == nil && info.Mode().Perm()&0077 != 0 && runtime.GOOS != "windows" {
+		t.Errorf("config.json mode = %v, want it readable only by the user", info.Mode().Perm())
+	}
+
+	// A value entered in settings is saved even where the environment set
+	// one, including the environment's own value
+	edited := cfg.WithoutEnv()
+	if edited.Model != "file-model" || edited.APIKey != "" {
+		t.Errorf("settings copy = model %q, key %q; want the file's values", edited.Model, edited.APIKey)
+	}
+	edited.Model = "picked-model"
+	edited.APIKey = "env-only-key"
+
+	// Another config's overrides don't affect this one's save
+	other := &Config{Model: "other-model"}
+	other.ApplyEnv()
+	edited.Save()
+	if data, _ := os.ReadFile(configPath); !strings.Contains(string(data), "env-only-key") {
+		t.Errorf("config.json dropped a key entered in settings:\n%s", data)
+	}
+
+	os.Unsetenv(EnvAPIKey)
+	os.Unsetenv(EnvModel)
+	saved, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if saved.APIKey != "env-only-key" || saved.Model != "picked-model" || saved.Endpoint != "http://edited/v1" {
+		t.Errorf("saved config = key %q, model %q, endpoint %q; want the edits", saved.APIKey, saved.Model, saved.Endpoint)
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+// Limits groups the spending and execution limits enforced while the agent
+// runs. A zero value means no limit, except MaxSteps where zero means the
+// step budget is derived from the execution timeout.
+type Limits struct {
+	MaxCostPerDay         float64 `json:"max_cost_per_day,omitempty"`          // Estimated US dollars per calendar day
+	MaxTokensPerRun       int     `json:"max_tokens_per_run,omitempty"`        // Prompt plus completion tokens per agent run
+	MaxSteps              int     `json:"max_steps,omitempty"`                 // Agent steps per run; caps per-conversation overrides
+	MaxOutputBytesPerTool int     `json:"max_output_bytes_per_tool,omitempty"` // Tool output beyond this is truncated
+
+	// Spending alerts, shown once per day or month when estimated spend
+	// reaches them; with PauseOnAlert new runs wait until the alert is
+	// acknowledged
+	AlertCostPerDay   float64 `json:"alert_cost_per_day,omitempty"`
+	AlertCostPerMonth float64 `json:"alert_cost_per_month,omitempty"`
+	PauseOnAlert      bool    `json:"pause_on_alert,omitempty"`
+}
+
+// Validate returns a ValidationErrors if any limit is negative.
+func (l Limits) Validate() error {
+	var d diagnostics
+	l.diagnose(&d)
+	if len(d.list) == 0 {
+		return nil
+	}
+	return ValidationErrors(d.list)
+}
+
+// diagnose reports negative limits.
+func (l Limits) diagnose(d *diagnostics) {
+	for _, limit := range []struct {
+		field string
+		value float64
+	}{
+		{"max_cost_per_day", l.MaxCostPerDay},
+		{"alert_cost_per_day", l.AlertCostPerDay},
+		{"alert_cost_per_month", l.AlertCostPerMonth},
+	} {
+		if limit.value < 0 {
+			d.fail("limits."+limit.field, limit.field+" cannot be negative")
+		}
+	}
+	for _, limit := range []struct {
+		field string
+		value int
+	}{
+		{"max_tokens_per_run", l.MaxTokensPerRun},
+		{"max_steps", l.MaxSteps},
+		{"max_output_bytes_per_tool", l.MaxOutputBytesPerTool},
+	} {
+		if limit.value < 0 {
+			d.fail("limits."+limit.field, limit.field+" cannot be negative")
+		}
+	}
+}
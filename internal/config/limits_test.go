@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestLimits_Validate(t *testing.T) {
+	if err := (Limits{MaxCostPerDay: 5, MaxTokensPerRun: 1000, MaxSteps: 10, MaxOutputBytesPerTool: 4096}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	err := Limits{MaxCostPerDay: -1, MaxSteps: -2, AlertCostPerMonth: -3}.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+	}
+	if findField(verrs, "limits.max_cost_per_day") == nil || findField(verrs, "limits.max_steps") == nil {
+		t.Errorf("expected errors for the negative limits, got %+v", verrs)
+	}
+	if findField(verrs, "limits.alert_cost_per_month") == nil {
+		t.Errorf("expected an error for the negative alert, got %+v", verrs)
+	}
+}
+
+func TestLimits_GroupedInConfigJSON(t *testing.T) {
+	data := []byte(`{"api_key":"k","limits":{"max_cost_per_day":2.5,"max_steps":15}}`)
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if cfg.Limits.MaxCostPerDay != 2.5 || cfg.Limits.MaxSteps != 15 {
+		t.Errorf("Limits = %+v", cfg.Limits)
+	}
+
+	cfg.Limits.MaxSteps = -1
+	if fe := findField(cfg.Diagnose(), "limits.max_steps"); fe == nil {
+		t.Error("expected Diagnose to report negative limits")
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// MCPServer is a Model Context Protocol server whose tools the agent can
+// use. Command starts a local server that speaks over stdio; URL connects
+// to a remote server's SSE endpoint. Exactly one of them is set.
+type MCPServer struct {
+	Name     string            `json:"name"`
+	Command  string            `json:"command,omitempty"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`     // Added to the command's environment
+	URL      string            `json:"url,omitempty"`     // SSE endpoint, e.g. http://localhost:3000/sse
+	Headers  map[string]string `json:"headers,omitempty"` // Sent with SSE requests, e.g. Authorization
+	Disabled bool              `json:"disabled,omitempty"`
+}
+
+// mcpServerName matches names that can prefix tool names sent to the model.
+var mcpServerName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func (c *Config) diagnoseMCPServers(d *diagnostics) {
+	seen := make(map[string]bool)
+	for _, s := range c.MCPServers {
+		switch {
+		case s.Name == "":
+			d.fail("mcp_servers", "every MCP server needs a name")
+		case !mcpServerName.MatchString(s.Name):
+			d.fail("mcp_servers", "MCP server names may only contain letters, digits, - and _: "+s.Name)
+		case seen[s.Name]:
+			d.fail("mcp_servers", "duplicate MCP server: "+s.Name)
+		}
+		seen[s.Name] = true
+
+		switch {
+		case s.Command == "" && s.URL == "":
+			d.fail("mcp_servers", "MCP server "+s.Name+" needs a command or a url")
+		case s.Command != "" && s.URL != "":
+			d.fail("mcp_servers", "MCP server "+s.Name+" has both a command and a url; set one")
+		case s.URL != "":
+			if u, err := url.Parse(s.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				d.fail("mcp_servers", "MCP server "+s.Name+" url must be an http:// or https:// URL")
+			} else if u.Scheme == "http" && !isLocalHost(u.Hostname()) {
+				d.warn("mcp_servers", "MCP server "+s.Name+" uses plain http")
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfig_Diagnose_MCPServers(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []MCPServer
+		want    string // Substring of the expected message, "" for none
+	}{
+		{"stdio", []MCPServer{{Name: "files", Command: "mcp-files"}}, ""},
+		{"sse", []MCPServer{{Name: "remote", URL: "https://mcp.example.com/sse"}}, ""},
+		{"no name", []MCPServer{{Command: "x"}}, "needs a name"},
+		{"bad name", []MCPServer{{Name: "my server", Command: "x"}}, "may only contain"},
+		{"duplicate", []MCPServer{{Name: "a", Command: "x"}, {Name: "a", Command: "y"}}, "duplicate"},
+		{"no transport", []MCPServer{{Name: "a"}}, "needs a command or a url"},
+		{"both transports", []MCPServer{{Name: "a", Command: "x", URL: "http://localhost/sse"}}, "both"},
+		{"bad url", []MCPServer{{Name: "a", URL: "ftp://x"}}, "http:// or https://"},
+		{"plain http", []MCPServer{{Name: "a", URL: "http://mcp.example.com/sse"}}, "plain http"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", MCPServers: tt.servers}
+
+			var got []string
+			for _, fe := range cfg.Diagnose() {
+				if fe.Field == "mcp_servers" {
+					got = append(got, fe.Message)
+				}
+			}
+			if tt.want == "" {
+				if len(got) > 0 {
+					t.Errorf("unexpected problems: %v", got)
+				}
+				return
+			}
+			if len(got) != 1 || !strings.Contains(got[0], tt.want) {
+				t.Errorf("problems = %v, want one containing %q", got, tt.want)
+			}
+		})
+	}
+}
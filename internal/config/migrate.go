@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CurrentVersion is the config.json schema version written by Save.
+// Files without a version field are version 0.
+const CurrentVersion = 1
+
+// migrations[n] upgrades a raw config from version n to n+1.
+var migrations = []func(raw map[string]json.RawMessage) error{
+	migrateAzureFields,
+}
+
+// migrate upgrades raw config JSON to CurrentVersion. It returns the upgraded
+// JSON, the version the data was at, and whether anything changed.
+func migrate(data []byte) ([]byte, int, bool, error) {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, 0, false, err
+	}
+
+	version := 0
+	if v, ok := raw["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, 0, false, fmt.Errorf("invalid config version: %w", err)
+		}
+	}
+	if version >= CurrentVersion {
+		return data, version, false, nil
+	}
+
+	for v := version; v < CurrentVersion; v++ {
+		if err := migrations[v](raw); err != nil {
+			return nil, version, false, fmt.Errorf("failed to migrate config from version %d: %w", v, err)
+		}
+	}
+	raw["version"] = json.RawMessage(fmt.Sprint(CurrentVersion))
+
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, version, false, err
+	}
+	return upgraded, version, true, nil
+}
+
+// migrateAzureFields converts the Azure-style fields used before the client
+// became provider-neutral into api_key, endpoint, and model. Values already
+// present in the generic fields win.
+func migrateAzureFields(raw map[string]json.RawMessage) error {
+	key := takeLegacyString(raw, "OpenAISubscriptionKey", "openai_subscription_key")
+	endpoint := takeLegacyString(raw, "OpenAIEndpoint", "openai_endpoint")
+	deployment := takeLegacyString(raw, "OpenAIDeployment", "openai_deployment")
+
+	if endpoint != "" {
+		endpoint = strings.TrimSuffix(endpoint, "/")
+		// Azure resources serve the OpenAI-compatible API under /openai/v1
+		if strings.HasSuffix(strings.ToLower(endpoint), ".openai.azure.com") {
+			endpoint += "/openai/v1"
+		}
+	}
+
+	for field, value := range map[string]string{"api_key": key, "endpoint": endpoint, "model": deployment} {
+		if value == "" {
+			continue
+		}
+		var existing string
+		if current, ok := raw[field]; ok {
+			json.Unmarshal(current, &existing)
+		}
+		if existing != "" {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		raw[field] = encoded
+	}
+	return nil
+}
+
+// takeLegacyString removes the first of names present in raw and returns its
+// string value. Names are matched case-insensitively, as encoding/json does.
+func takeLegacyString(raw map[string]json.RawMessage, names ...string) string {
+	for key, value := range raw {
+		for _, name := range names {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			delete(raw, key)
+			var s string
+			json.Unmarshal(value, &s)
+			return s
+		}
+	}
+	return ""
+}
+
+// backupConfig copies the pre-migration config file so no settings are lost
+// if the migration got something wrong.
+func backupConfig(path string, data []byte, version int) error {
+	return os.WriteFile(fmt.Sprintf("%s.v%d.bak", path, version), data, 0600)
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig_MigratesAzureFields(t *testing.T) {
+	tmpDir, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	legacy := `{
+		"OpenAISubscriptionKey": "azure-key",
+		"OpenAIEndpoint": "https://myres.openai.azure.com/",
+		"OpenAIDeployment": "gpt-4o-deploy",
+		"execution_timeout": 45
+	}`
+	configPath := filepath.Join(tmpDir, "config.json")
+	if err := os.WriteFile(configPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	if cfg.APIKey != "azure-key" {
+		t.Errorf("expected APIKey='azure-key', got %q", cfg.APIKey)
+	}
+	if cfg.Endpoint != "https://myres.openai.azure.com/openai/v1" {
+		t.Errorf("unexpected Endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Model != "gpt-4o-deploy" {
+		t.Errorf("expected Model='gpt-4o-deploy', got %q", cfg.Model)
+	}
+	if cfg.ExecutionTimeout != 45 {
+		t.Errorf("expected ExecutionTimeout=45, got %d", cfg.ExecutionTimeout)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("expected Version=%d, got %d", CurrentVersion, cfg.Version)
+	}
+
+	// The upgraded file is saved and the original kept as a backup
+	data, _ := os.ReadFile(configPath)
+	var saved map[string]interface{}
+	json.Unmarshal(data, &saved)
+	if saved["api_key"] != "azure-key" || saved["OpenAISubscriptionKey"] != nil {
+		t.Errorf("expected migrated config to be saved, got %s", data)
+	}
+	backup, err := os.ReadFile(configPath + ".v0.bak")
+	if err != nil || string(backup) != legacy {
+		t.Errorf("expected original config to be backed up: %v", err)
+	}
+}
+
+func TestMigrate_KeepsGenericFields(t *testing.T) {
+	data := []byte(`{"api_key": "new-key", "openai_subscription_key": "old-key", "openai_deployment": "old-model"}`)
+
+	upgraded, version, migrated, err := migrate(data)
+	if err != nil {
+		t.Fatalf("migrate returned error: %v", err)
+	}
+	if version != 0 || !migrated {
+		t.Fatalf("expected a migration from version 0, got version=%d migrated=%v", version, migrated)
+	}
+
+	var cfg Config
+	json.Unmarshal(upgraded, &cfg)
+	if cfg.APIKey != "new-key" {
+		t.Errorf("expected existing api_key to win, got %q", cfg.APIKey)
+	}
+	if cfg.Model != "old-model" {
+		t.Errorf("expected model from deployment, got %q", cfg.Model)
+	}
+}
+
+func TestMigrate_CurrentVersionUnchanged(t *testing.T) {
+	data := []byte(`{"version": 1, "api_key": "k"}`)
+
+	upgraded, _, migrated, err := migrate(data)
+	if err != nil {
+		t.Fatalf("migrate returned error: %v", err)
+	}
+	if migrated || string(upgraded) != string(data) {
+		t.Errorf("expected current config to be left alone, got %s", upgraded)
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import "slices"
+
+// Agent lifecycle events that can raise a desktop notification.
+const (
+	NotifyComplete = "complete" // A task finished
+	NotifyError    = "error"    // A run failed
+	NotifyApproval = "approval" // A tool call is waiting for approval
+	NotifyBudget   = "budget"   // Spending is nearing or over a limit
+)
+
+// NotificationEvents lists every event kind, in settings display order.
+var NotificationEvents = []string{NotifyComplete, NotifyError, NotifyApproval, NotifyBudget}
+
+// NotificationSettings controls desktop notifications. Every event is
+// notified unless notifications are disabled or the event is muted.
+type NotificationSettings struct {
+	Disabled bool     `json:"disabled,omitempty"` // Turn off all notifications
+	Muted    []string `json:"muted,omitempty"`    // Events not to notify about
+}
+
+// Enabled reports whether the event should raise a notification.
+func (n NotificationSettings) Enabled(event string) bool {
+	return !n.Disabled && !slices.Contains(n.Muted, event)
+}
@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestNotificationSettings_Enabled(t *testing.T) {
+	var all NotificationSettings
+	for _, event := range NotificationEvents {
+		if !all.Enabled(event) {
+			t.Errorf("expected %s to be notified by default", event)
+		}
+	}
+
+	muted := NotificationSettings{Muted: []string{NotifyComplete}}
+	if muted.Enabled(NotifyComplete) || !muted.Enabled(NotifyError) {
+		t.Error("expected only the muted event to be silenced")
+	}
+
+	off := NotificationSettings{Disabled: true}
+	if off.Enabled(NotifyBudget) {
+		t.Error("expected no notifications when disabled")
+	}
+}
+
+func TestConfig_Diagnose_UnknownNotificationEvent(t *testing.T) {
+	cfg := Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o",
+		Notifications: NotificationSettings{Muted: []string{"finish"}}}
+
+	if fe := findField(cfg.Diagnose(), "notifications"); fe == nil || fe.Severity != SeverityWarning {
+		t.Errorf("notifications diagnostic = %+v, want warning", fe)
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Profile is a named provider configuration. Empty fields inherit the
+// top-level endpoint, API key, and model.
+type Profile struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// ProfileRule selects a profile for new conversations. A rule matches when
+// the conversation's persona contains Persona, or when any keyword appears
+// as a whole word in the first message. Rules are tried in order.
+type ProfileRule struct {
+	Profile  string   `json:"profile"`
+	Persona  string   `json:"persona,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// FindProfile returns the profile with the given name.
+func (c *Config) FindProfile(name string) (Profile, bool) {
+	for _, p := range c.Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// ForProfile returns a copy of the config with the named profile's
+// endpoint, API key, and model applied.
+func (c *Config) ForProfile(name string) (*Config, error) {
+	p, ok := c.FindProfile(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown profile: %s", name)
+	}
+
+	cfg := *c
+	if p.Endpoint != "" {
+		cfg.Endpoint = p.Endpoint
+	}
+	if p.APIKey != "" {
+		cfg.APIKey = p.APIKey
+	}
+	if p.Model != "" {
+		cfg.Model = p.Model
+	}
+	return &cfg, nil
+}
+
+// MatchProfile returns the profile chosen by the first rule matching the
+// conversation's persona or first message, or "" if none match.
+func (c *Config) MatchProfile(persona, message string) string {
+	persona = strings.ToLower(persona)
+	for _, rule := range c.ProfileRules {
+		if rule.Persona != "" && strings.Contains(persona, strings.ToLower(rule.Persona)) {
+			return rule.Profile
+		}
+		for _, keyword := range rule.Keywords {
+			if containsWord(message, keyword) {
+				return rule.Profile
+			}
+		}
+	}
+	return ""
+}
+
+// containsWord reports whether word appears in text as a whole word,
+// ignoring case.
+func containsWord(text, word string) bool {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return false
+	}
+	re, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`)
+	return err == nil && re.MatchString(text)
+}
+
+// diagnoseProfiles reports unnamed or duplicate profiles and rules that
+// refer to missing profiles.
+func (c *Config) diagnoseProfiles(d *diagnostics) {
+	seen := make(map[string]bool)
+	for _, p := range c.Profiles {
+		switch {
+		case p.Name == "":
+			d.fail("profiles", "every profile needs a name")
+		case seen[p.Name]:
+			d.fail("profiles", "duplicate profile: "+p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	for _, rule := range c.ProfileRules {
+		if !seen[rule.Profile] {
+			d.fail("profile_rules", "rule refers to unknown profile: "+rule.Profile)
+		} else if rule.Persona == "" && len(rule.Keywords) == 0 {
+			d.warn("profile_rules", "rule for "+rule.Profile+" has no persona or keywords and never matches")
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import "testing"
+
+// profileConfig returns a config with coding and writing profiles.
+func profileConfig() *Config {
+	return &Config{
+		APIKey:   "default-key",
+		Endpoint: "https://api.openai.com/v1",
+		Model:    "gpt-4o-mini",
+		Profiles: []Profile{
+			{Name: "coding", Endpoint: "https://api.deepseek.com/v1", APIKey: "ds-key", Model: "deepseek-chat"},
+			{Name: "writing", Model: "gpt-4o"},
+		},
+		ProfileRules: []ProfileRule{
+			{Profile: "coding", Persona: "programmer", Keywords: []string{"code", "bug", "refactor"}},
+			{Profile: "writing", Keywords: []string{"essay", "blog post"}},
+		},
+	}
+}
+
+func TestConfig_MatchProfile(t *testing.T) {
+	cfg := profileConfig()
+
+	tests := []struct {
+		name    string
+		persona string
+		message string
+		want    string
+	}{
+		{"keyword", "", "Fix the bug in main.go", "coding"},
+		{"keyword case", "", "Draft a Blog Post about Go", "writing"},
+		{"persona", "You are a senior Programmer.", "Hello", "coding"},
+		{"whole words only", "", "Decode this barcode", ""},
+		{"no match", "", "What's the weather?", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.MatchProfile(tt.persona, tt.message); got != tt.want {
+				t.Errorf("MatchProfile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_ForProfile(t *testing.T) {
+	cfg := profileConfig()
+
+	coding, err := cfg.ForProfile("coding")
+	if err != nil {
+		t.Fatalf("ForProfile() error = %v", err)
+	}
+	if coding.Endpoint != "https://api.deepseek.com/v1" || coding.APIKey != "ds-key" || coding.Model != "deepseek-chat" {
+		t.Errorf("coding profile not applied: %+v", coding)
+	}
+
+	writing, err := cfg.ForProfile("writing")
+	if err != nil {
+		t.Fatalf("ForProfile() error = %v", err)
+	}
+	if writing.Endpoint != cfg.Endpoint || writing.APIKey != cfg.APIKey || writing.Model != "gpt-4o" {
+		t.Errorf("writing profile should inherit endpoint and key: %+v", writing)
+	}
+
+	if _, err := cfg.ForProfile("missing"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+	if cfg.Model != "gpt-4o-mini" {
+		t.Error("ForProfile must not modify the original config")
+	}
+}
+
+func TestConfig_Diagnose_Profiles(t *testing.T) {
+	cfg := profileConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	cfg.ProfileRules = append(cfg.ProfileRules, ProfileRule{Profile: "research", Keywords: []string{"paper"}})
+	if fe := findField(cfg.Diagnose(), "profile_rules"); fe == nil || fe.Severity != SeverityError {
+		t.Errorf("profile_rules diagnostic = %+v, want error", fe)
+	}
+}
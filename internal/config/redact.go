@@ -0,0 +1,142 @@
+package config
+
+import (
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactedSecret replaces secrets in configs shared for debugging.
+const RedactedSecret = "[redacted]"
+
+// minSecretLength is the shortest configured secret scrubbed from text, so
+// short values such as "1" or "true" in an MCP server's environment don't
+// mangle the transcript.
+const minSecretLength = 6
+
+// tokenPatterns match well-known API key and token formats, scrubbed even
+// when they aren't in the config, e.g. a key the agent read from a file.
+var tokenPatterns = regexp.MustCompile(`\b(sk-[A-Za-z0-9_-]{16,}|xox[abprs]-[A-Za-z0-9-]{10,}|xapp-[A-Za-z0-9-]{10,}|gh[pousr]_[A-Za-z0-9]{20,}|AKIA[0-9A-Z]{16})\b|(?i:bearer\s+)[A-Za-z0-9._~+/=-]{16,}`)
+
+// Scrubber replaces secrets in text with RedactedSecret: the given secrets,
+// such as those from Secrets, and well-known API key and token formats.
+type Scrubber struct {
+	replacer *strings.Replacer
+}
+
+// NewScrubber returns a scrubber for secrets. Secrets shorter than six
+// characters are left alone.
+func NewScrubber(secrets []string) *Scrubber {
+	// Longest first, so a secret containing another is replaced whole
+	secrets = append([]string(nil), secrets...)
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	var pairs []string
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength {
+			pairs = append(pairs, secret, RedactedSecret)
+		}
+	}
+	return &Scrubber{replacer: strings.NewReplacer(pairs...)}
+}
+
+// Scrub returns text with the secrets replaced.
+func (s *Scrubber) Scrub(text string) string {
+	return tokenPatterns.ReplaceAllString(s.replacer.Replace(text), RedactedSecret)
+}
+
+// Redacted returns a copy of the config with every secret that is set
+// replaced by RedactedSecret, so it can be attached to a bug report while
+// still showing which secrets were configured. MCP server environment
+// variables and headers, and tracing headers, keep their names only.
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.APIKey = redact(c.APIKey)
+	r.Profiles = make([]Profile, len(c.Profiles))
+	for i, p := range c.Profiles {
+		p.APIKey = redact(p.APIKey)
+		r.Profiles[i] = p
+	}
+	r.MCPServers = make([]MCPServer, len(c.MCPServers))
+	for i, s := range c.MCPServers {
+		s.Env = redactValues(s.Env)
+		s.Headers = redactValues(s.Headers)
+		r.MCPServers[i] = s
+	}
+	r.Webhooks = make([]Webhook, len(c.Webhooks))
+	for i, w := range c.Webhooks {
+		w.Secret = redact(w.Secret)
+		r.Webhooks[i] = w
+	}
+	if s := c.Bridges.Slack; s != nil {
+		slack := *s
+		slack.BotToken, slack.AppToken = redact(s.BotToken), redact(s.AppToken)
+		r.Bridges.Slack = &slack
+	}
+	if d := c.Bridges.Discord; d != nil {
+		discord := *d
+		discord.BotToken = redact(d.BotToken)
+		r.Bridges.Discord = &discord
+	}
+	r.Calendar.Password = redact(c.Calendar.Password)
+	r.Email.Password = redact(c.Email.Password)
+	r.Tracing.Headers = redactValues(c.Tracing.Headers)
+	return &r
+}
+
+// Secrets returns the values of the secrets the config holds, so they can
+// be scrubbed from text such as logs and tool output.
+func (c *Config) Secrets() []string {
+	var secrets []string
+	add := func(s string) {
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	add(c.APIKey)
+	for _, p := range c.Profiles {
+		add(p.APIKey)
+	}
+	for _, s := range c.MCPServers {
+		for _, v := range s.Env {
+			add(v)
+		}
+		for _, v := range s.Headers {
+			add(v)
+		}
+	}
+	for _, w := range c.Webhooks {
+		add(w.Secret)
+	}
+	if s := c.Bridges.Slack; s != nil {
+		add(s.BotToken)
+		add(s.AppToken)
+	}
+	if d := c.Bridges.Discord; d != nil {
+		add(d.BotToken)
+	}
+	add(c.Calendar.Password)
+	add(c.Email.Password)
+	for _, v := range c.Tracing.Headers {
+		add(v)
+	}
+	return secrets
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return RedactedSecret
+}
+
+func redactValues(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	r := maps.Clone(m)
+	for k, v := range r {
+		r[k] = redact(v)
+	}
+	return r
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func secretConfig() *Config {
+	return &Config{
+		APIKey:     "sk-main",
+		Endpoint:   "https://api.openai.com/v1",
+		Model:      "gpt-4o",
+		Profiles:   []Profile{{Name: "local", APIKey: "sk-profile"}, {Name: "keyless"}},
+		MCPServers: []MCPServer{{Name: "github", Env: map[string]string{"GITHUB_TOKEN": "ghp-secret"}, Headers: map[string]string{"Authorization": "Bearer mcp-secret"}}},
+		Webhooks:   []Webhook{{URL: "https://example.com/hook", Secret: "hook-secret"}},
+		Bridges: BridgeSettings{
+			Slack:   &SlackBridge{BotToken: "xoxb-secret", AppToken: "xapp-secret"},
+			Discord: &DiscordBridge{BotToken: "discord-secret"},
+		},
+		Calendar: CalendarSettings{Password: "cal-secret"},
+		Email:    EmailSettings{Password: "mail-secret"},
+		Tracing:  TracingSettings{Endpoint: "https://otlp.example.com", Headers: map[string]string{"x-api-key": "trace-secret"}},
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := secretConfig()
+	data, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range cfg.Secrets() {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("redacted config still contains %q", secret)
+		}
+	}
+
+	r := cfg.Redacted()
+	if r.APIKey != RedactedSecret || r.MCPServers[0].Env["GITHUB_TOKEN"] != RedactedSecret {
+		t.Errorf("expected set secrets to be marked redacted, got %+v", r)
+	}
+	if r.Profiles[1].APIKey != "" {
+		t.Error("expected secrets that aren't set to stay empty")
+	}
+	if r.Endpoint != cfg.Endpoint || r.Model != cfg.Model {
+		t.Error("expected settings that aren't secret to be kept")
+	}
+	if cfg.APIKey != "sk-main" || cfg.MCPServers[0].Env["GITHUB_TOKEN"] != "ghp-secret" || cfg.Bridges.Slack.BotToken != "xoxb-secret" {
+		t.Error("redacting changed the original config")
+	}
+}
+
+func TestConfig_Secrets(t *testing.T) {
+	secrets := secretConfig().Secrets()
+	for _, want := range []string{"sk-main", "sk-profile", "ghp-secret", "Bearer mcp-secret", "hook-secret", "xoxb-secret", "xapp-secret", "discord-secret", "cal-secret", "mail-secret", "trace-secret"} {
+		if !slices.Contains(secrets, want) {
+			t.Errorf("Secrets() is missing %q", want)
+		}
+	}
+	if len((&Config{}).Secrets()) != 0 {
+		t.Error("expected no secrets in an empty config")
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import "net/url"
+
+// TelemetrySettings controls anonymous usage reports. They are off unless
+// the user turns them on, and hold only counts of features and tools used
+// and signatures of crashes: never prompts, paths, or tool output.
+type TelemetrySettings struct {
+	Enabled  bool   `json:"enabled,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"` // URL reports are POSTed to as JSON
+}
+
+// Active reports whether usage is counted and reported.
+func (t TelemetrySettings) Active() bool {
+	return t.Enabled && t.Endpoint != ""
+}
+
+func (c *Config) diagnoseTelemetry(d *diagnostics) {
+	t := c.Telemetry
+	if t.Endpoint == "" {
+		if t.Enabled {
+			d.warn("telemetry.endpoint", "telemetry is on but has no endpoint, so nothing is collected")
+		}
+		return
+	}
+	u, err := url.Parse(t.Endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		d.fail("telemetry.endpoint", "telemetry endpoint must be an http(s) URL")
+	}
+}
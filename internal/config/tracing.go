@@ -0,0 +1,35 @@
+package config
+
+import "net/url"
+
+// TracingSettings exports OpenTelemetry traces of agent runs, model calls,
+// and tool calls to an OTLP collector, such as Jaeger or Grafana Tempo.
+type TracingSettings struct {
+	Endpoint    string            `json:"endpoint,omitempty"`     // OTLP/HTTP URL, e.g. http://localhost:4318 (empty = no tracing)
+	Headers     map[string]string `json:"headers,omitempty"`      // Sent with every export, e.g. an API key for a hosted collector
+	SampleRatio float64           `json:"sample_ratio,omitempty"` // Fraction of runs traced (0 = all)
+}
+
+// Enabled reports whether traces are exported.
+func (t TracingSettings) Enabled() bool {
+	return t.Endpoint != ""
+}
+
+func (c *Config) diagnoseTracing(d *diagnostics) {
+	t := c.Tracing
+	if !t.Enabled() {
+		return
+	}
+	u, err := url.Parse(t.Endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		d.fail("tracing.endpoint", "tracing endpoint must be an http(s) URL, e.g. http://localhost:4318")
+	} else if u.Scheme == "http" && len(t.Headers) > 0 {
+		d.warn("tracing.endpoint", "tracing headers are sent unencrypted over http")
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		d.fail("tracing.sample_ratio", "sample_ratio must be between 0 and 1")
+	}
+	if _, ok := t.Headers[""]; ok {
+		d.fail("tracing.headers", "tracing header names can't be empty")
+	}
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"agent-desktop/internal/i18n"
+)
+
+// Severities reported in FieldError.Severity.
+const (
+	SeverityError   = "error"   // The config cannot be used as is
+	SeverityWarning = "warning" // The config works but is probably not what was meant
+)
+
+// FieldError describes a problem with a single config field. Field is the
+// field's JSON name so the settings UI can highlight the matching input.
+type FieldError struct {
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+// ValidationErrors is the error returned by Validate. It lists every
+// error-severity problem found, in field order.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, fe := range v {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the configuration and returns a ValidationErrors listing
+// every field that prevents it from being used. Warnings are not errors;
+// use Diagnose to see them too.
+func (c *Config) Validate() error {
+	var errs ValidationErrors
+	for _, fe := range c.Diagnose() {
+		if fe.Severity == SeverityError {
+			errs = append(errs, fe)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Diagnose returns every problem found in the configuration, including
+// warnings. It only inspects the config and local files; it never touches
+// the network.
+func (c *Config) Diagnose() []FieldError {
+	var d diagnostics
+
+	if c.APIKey == "" {
+		d.fail("api_key", "api_key is required")
+	} else if strings.TrimSpace(c.APIKey) != c.APIKey {
+		d.warn("api_key", "api_key has leading or trailing whitespace")
+	}
+
+	endpoint := c.diagnoseEndpoint(&d)
+
+	if c.Model == "" {
+		d.fail("model", "model is required")
+	} else if msg := suspiciousModel(endpoint, c.Model); msg != "" {
+		d.warn("model", msg)
+	}
+
+	c.diagnoseProfiles(&d)
+	c.diagnoseMCPServers(&d)
+	c.diagnoseWebhooks(&d)
+	c.diagnoseBridges(&d)
+	c.diagnoseCalendar(&d)
+	c.diagnoseEmail(&d)
+	c.diagnoseTracing(&d)
+	c.diagnoseTelemetry(&d)
+
+	if c.CACertPath != "" {
+		if _, err := os.Stat(c.CACertPath); err != nil {
+			d.fail("ca_cert_path", "CA certificate file not found: "+c.CACertPath)
+		}
+	}
+	if c.InsecureSkipVerify {
+		d.warn("insecure_skip_verify", "TLS certificate verification is disabled")
+	}
+
+	if c.ExecutionTimeout < 0 {
+		d.fail("execution_timeout", "execution_timeout cannot be negative")
+	}
+	if c.ContextWindow < 0 {
+		d.fail("context_window", "context_window cannot be negative")
+	}
+	if c.ShellHistorySize < 0 {
+		d.fail("shell_history_size", "shell_history_size cannot be negative")
+	}
+	if c.DefaultWorkingDir != "" {
+		if info, err := os.Stat(c.DefaultWorkingDir); err != nil || !info.IsDir() {
+			d.warn("default_working_dir", "default working directory does not exist: "+c.DefaultWorkingDir)
+		}
+	}
+	if c.InboxDir != "" {
+		if info, err := os.Stat(c.InboxDir); err != nil || !info.IsDir() {
+			d.warn("inbox_dir", "task inbox folder does not exist: "+c.InboxDir)
+		}
+	}
+	if c.IndexRoot != "" {
+		if info, err := os.Stat(c.IndexRoot); err != nil || !info.IsDir() {
+			d.warn("index_root", "indexed workspace folder does not exist: "+c.IndexRoot)
+		}
+	}
+
+	switch c.StorageBackend {
+	case "", StorageBackendJSON, StorageBackendSQLite:
+	default:
+		d.fail("storage_backend", "unknown storage_backend: "+c.StorageBackend)
+	}
+	switch c.EmbeddingAPI {
+	case "", EmbeddingAPIOpenAI, EmbeddingAPIOllama:
+	default:
+		d.fail("embedding_api", "unknown embedding_api: "+c.EmbeddingAPI)
+	}
+	for _, ext := range slices.Sorted(maps.Keys(c.IndexChunking)) {
+		switch v := c.IndexChunking[ext]; v {
+		case ChunkLines, ChunkTokens, ChunkParagraphs, ChunkCode:
+		default:
+			d.fail("index_chunking", "unknown chunking strategy for "+ext+": "+v)
+		}
+	}
+	switch c.Encryption {
+	case "", EncryptionKeychain, EncryptionPassphrase:
+	default:
+		d.fail("encryption", "unknown encryption mode: "+c.Encryption)
+	}
+
+	c.Limits.diagnose(&d)
+
+	for _, event := range c.Notifications.Muted {
+		if !slices.Contains(NotificationEvents, event) {
+			d.warn("notifications", "unknown notification event: "+event)
+		}
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		d.fail("log_level", "unknown log_level: "+c.LogLevel)
+	}
+
+	switch c.CassetteMode {
+	case "", CassetteReplay, CassetteRecord:
+		if c.CassetteMode != "" && c.Cassette == "" {
+			d.warn("cassette_mode", "cassette_mode has no effect without a cassette")
+		}
+	default:
+		d.fail("cassette_mode", "unknown cassette_mode: "+c.CassetteMode)
+	}
+
+	switch c.UpdateChannel {
+	case "", UpdateChannelStable, UpdateChannelBeta:
+	default:
+		d.fail("update_channel", "unknown update_channel: "+c.UpdateChannel)
+	}
+	if c.UpdateFeedURL != "" {
+		if u, err := url.Parse(c.UpdateFeedURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			d.fail("update_feed_url", "update_feed_url must be an http:// or https:// URL")
+		} else if u.Scheme == "http" && !isLocalHost(u.Hostname()) {
+			d.warn("update_feed_url", "update_feed_url uses plain http")
+		}
+	}
+
+	if c.Locale != "" && !i18n.Supported(c.Locale) {
+		d.warn("locale", "no translation for locale "+c.Locale+"; messages will be in English")
+	}
+
+	for _, limit := range []struct {
+		field string
+		value int
+	}{
+		{"trash_retention_days", c.TrashRetentionDays},
+		{"archive_after_days", c.ArchiveAfterDays},
+		{"delete_after_days", c.DeleteAfterDays},
+		{"max_store_size_mb", c.MaxStoreSizeMB},
+		{"log_retention_days", c.LogRetentionDays},
+	} {
+		if limit.value < 0 {
+			d.fail(limit.field, limit.field+" cannot be negative")
+		}
+	}
+
+	return d.list
+}
+
+// diagnoseEndpoint checks the endpoint URL and returns it parsed, or nil if
+// it is missing or malformed.
+func (c *Config) diagnoseEndpoint(d *diagnostics) *url.URL {
+	if c.Endpoint == "" {
+		d.fail("endpoint", "endpoint is required")
+		return nil
+	}
+
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		d.fail("endpoint", "endpoint must be an http:// or https:// URL")
+		return nil
+	}
+
+	if strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/chat/completions") {
+		d.warn("endpoint", "endpoint should be the API base URL, without /chat/completions")
+	}
+	if u.Scheme == "http" && !isLocalHost(u.Hostname()) {
+		d.warn("endpoint", "endpoint uses plain http; the API key will be sent unencrypted")
+	}
+	return u
+}
+
+// suspiciousModel returns a warning if the model name doesn't look like one
+// the endpoint's provider serves, or "" if it looks fine or the provider is
+// unknown.
+func suspiciousModel(endpoint *url.URL, model string) string {
+	if model != strings.TrimSpace(model) {
+		return "model has leading or trailing whitespace"
+	}
+	if endpoint == nil {
+		return ""
+	}
+	host := strings.ToLower(endpoint.Hostname())
+	lower := strings.ToLower(model)
+
+	switch {
+	case host == "api.openai.com":
+		if strings.Contains(model, "/") {
+			return "OpenAI model names have no provider prefix; " + model + " looks like an OpenRouter model"
+		}
+		for _, other := range []string{"claude", "gemini", "llama", "mistral", "deepseek", "qwen"} {
+			if strings.HasPrefix(lower, other) {
+				return model + " is not an OpenAI model"
+			}
+		}
+	case host == "openrouter.ai" || strings.HasSuffix(host, ".openrouter.ai"):
+		if !strings.Contains(model, "/") {
+			return "OpenRouter model names include the provider, e.g. openai/" + model
+		}
+	}
+	return ""
+}
+
+// isLocalHost reports whether host refers to this machine.
+func isLocalHost(host string) bool {
+	return host == "localhost" || host == "::1" || strings.HasPrefix(host, "127.")
+}
+
+// diagnostics accumulates FieldErrors.
+type diagnostics struct {
+	list []FieldError
+}
+
+func (d *diagnostics) fail(field, msg string) {
+	d.list = append(d.list, FieldError{Field: field, Message: msg, Severity: SeverityError})
+}
+
+func (d *diagnostics) warn(field, msg string) {
+	d.list = append(d.list, FieldError{Field: field, Message: msg, Severity: SeverityWarning})
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// findField returns the first diagnostic for field, or nil.
+func findField(diags []FieldError, field string) *FieldError {
+	for i := range diags {
+		if diags[i].Field == field {
+			return &diags[i]
+		}
+	}
+	return nil
+}
+
+func TestConfig_Validate_ReturnsFieldErrors(t *testing.T) {
+	cfg := Config{Endpoint: "not a url", StorageBackend: "mongo", EmbeddingAPI: "cohere", IndexChunking: map[string]string{".go": "code", ".md": "sentences"}}
+
+	err := cfg.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+	}
+
+	for _, field := range []string{"api_key", "endpoint", "model", "storage_backend", "embedding_api", "index_chunking"} {
+		fe := findField(verrs, field)
+		if fe == nil {
+			t.Errorf("expected an error for %s, got %v", field, verrs)
+			continue
+		}
+		if fe.Severity != SeverityError {
+			t.Errorf("%s severity = %q, want error", field, fe.Severity)
+		}
+	}
+}
+
+func TestConfig_Diagnose_Endpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		severity string // "" means no diagnostic
+	}{
+		{"https://api.openai.com/v1", ""},
+		{"http://localhost:1234/v1", ""},
+		{"http://127.0.0.1:8080/v1", ""},
+		{"ftp://example.com/v1", SeverityError},
+		{"api.openai.com/v1", SeverityError},
+		{"http://example.com/v1", SeverityWarning},
+		{"https://api.openai.com/v1/chat/completions", SeverityWarning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			cfg := Config{APIKey: "key", Endpoint: tt.endpoint, Model: "m"}
+			fe := findField(cfg.Diagnose(), "endpoint")
+			switch {
+			case tt.severity == "" && fe != nil:
+				t.Errorf("unexpected diagnostic: %+v", fe)
+			case tt.severity != "" && fe == nil:
+				t.Errorf("expected a %s, got none", tt.severity)
+			case fe != nil && fe.Severity != tt.severity:
+				t.Errorf("severity = %q, want %q", fe.Severity, tt.severity)
+			}
+		})
+	}
+}
+
+func TestConfig_Diagnose_SuspiciousModel(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		model    string
+		warn     bool
+	}{
+		{"https://api.openai.com/v1", "gpt-4o", false},
+		{"https://api.openai.com/v1", "anthropic/claude-3-opus", true},
+		{"https://api.openai.com/v1", "claude-3-opus", true},
+		{"https://openrouter.ai/api/v1", "anthropic/claude-3-opus", false},
+		{"https://openrouter.ai/api/v1", "gpt-4o", true},
+		{"http://localhost:1234/v1", "local-model", false},
+		{"http://localhost:1234/v1", "local-model ", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint+" "+tt.model, func(t *testing.T) {
+			cfg := Config{APIKey: "key", Endpoint: tt.endpoint, Model: tt.model}
+			fe := findField(cfg.Diagnose(), "model")
+			if (fe != nil) != tt.warn {
+				t.Fatalf("model diagnostic = %+v, want warning %v", fe, tt.warn)
+			}
+			if fe != nil && fe.Severity != SeverityWarning {
+				t.Errorf("severity = %q, want warning", fe.Severity)
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("warnings should not fail Validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfig_Diagnose_Paths(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	cfg := Config{
+		APIKey:            "key",
+		Endpoint:          "https://api.openai.com/v1",
+		Model:             "gpt-4o",
+		CACertPath:        missing,
+		DefaultWorkingDir: missing,
+		InboxDir:          missing,
+		IndexRoot:         missing,
+	}
+
+	diags := cfg.Diagnose()
+	if fe := findField(diags, "ca_cert_path"); fe == nil || fe.Severity != SeverityError {
+		t.Errorf("ca_cert_path diagnostic = %+v, want error", fe)
+	}
+	if fe := findField(diags, "default_working_dir"); fe == nil || fe.Severity != SeverityWarning {
+		t.Errorf("default_working_dir diagnostic = %+v, want warning", fe)
+	}
+	if fe := findField(diags, "inbox_dir"); fe == nil || fe.Severity != SeverityWarning {
+		t.Errorf("inbox_dir diagnostic = %+v, want warning", fe)
+	}
+	if fe := findField(diags, "index_root"); fe == nil || fe.Severity != SeverityWarning {
+		t.Errorf("index_root diagnostic = %+v, want warning", fe)
+	}
+}
+
+func TestConfig_Diagnose_Calendar(t *testing.T) {
+	tests := []struct {
+		calendar CalendarSettings
+		field    string
+		severity string
+	}{
+		{CalendarSettings{Provider: "outlook"}, "calendar.provider", SeverityError},
+		{CalendarSettings{Provider: CalendarProviderCalDAV}, "calendar.url", SeverityError},
+		{CalendarSettings{Provider: CalendarProviderCalDAV, URL: "https://dav.example.com/cal/", TasksURL: "tasks"}, "calendar.tasks_url", SeverityError},
+		{CalendarSettings{Provider: CalendarProviderCalDAV, URL: "http://nas.local/cal/", Password: "secret"}, "calendar.url", SeverityWarning},
+		{CalendarSettings{Provider: CalendarProviderCalDAV, URL: "https://dav.example.com/cal/", Password: "secret"}, "", ""},
+	}
+	for _, tt := range tests {
+		cfg := Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", Calendar: tt.calendar}
+		diags := cfg.Diagnose()
+		fe := findField(diags, tt.field)
+		if tt.field == "" {
+			for _, d := range diags {
+				if strings.HasPrefix(d.Field, "calendar.") {
+					t.Errorf("%+v: unexpected diagnostic %+v", tt.calendar, d)
+				}
+			}
+			continue
+		}
+		if fe == nil || fe.Severity != tt.severity {
+			t.Errorf("%+v: %s diagnostic = %+v, want %s", tt.calendar, tt.field, fe, tt.severity)
+		}
+	}
+}
+
+func TestConfig_Diagnose_Email(t *testing.T) {
+	tests := []struct {
+		email    EmailSettings
+		field    string
+		severity string
+	}{
+		{EmailSettings{IMAPServer: "imap.example.com:993", Password: "secret"}, "email.address", SeverityError},
+		{EmailSettings{Address: "me@example.com", IMAPServer: "imap.example.com", Password: "secret"}, "email.imap_server", SeverityError},
+		{EmailSettings{Address: "me@example.com", SMTPServer: ":465", Password: "secret"}, "email.smtp_server", SeverityError},
+		{EmailSettings{Address: "me@example.com", Password: "secret"}, "email.address", SeverityWarning},
+		{EmailSettings{Address: "me@example.com", SMTPServer: "smtp.example.com:465"}, "email.password", SeverityWarning},
+		{EmailSettings{Address: "me@example.com", IMAPServer: "imap.example.com:993", SMTPServer: "smtp.example.com:587", Password: "secret"}, "", ""},
+	}
+	for _, tt := range tests {
+		cfg := Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", Email: tt.email}
+		diags := cfg.Diagnose()
+		fe := findField(diags, tt.field)
+		if tt.field == "" {
+			for _, d := range diags {
+				if strings.HasPrefix(d.Field, "email.") {
+					t.Errorf("%+v: unexpected diagnostic %+v", tt.email, d)
+				}
+			}
+			continue
+		}
+		if fe == nil || fe.Severity != tt.severity {
+			t.Errorf("%+v: %s diagnostic = %+v, want %s", tt.email, tt.field, fe, tt.severity)
+		}
+	}
+}
+
+func TestValidationErrors_Error(t *testing.T) {
+	err := ValidationErrors{
+		{Field: "api_key", Message: "api_key is required"},
+		{Field: "model", Message: "model is required"},
+	}
+	if got, want := err.Error(), "api_key is required; model is required"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestConfig_Diagnose_LogLevel(t *testing.T) {
+	cfg := Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", LogLevel: "loud"}
+	if fe := findField(cfg.Diagnose(), "log_level"); fe == nil || fe.Severity != SeverityError {
+		t.Errorf("log_level diagnostic = %+v, want error", fe)
+	}
+
+	cfg.LogLevel = "debug"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestConfig_Diagnose_Updates(t *testing.T) {
+	cfg := Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", UpdateChannel: "nightly", UpdateFeedURL: "releases.json"}
+	diags := cfg.Diagnose()
+	for _, field := range []string{"update_channel", "update_feed_url"} {
+		if fe := findField(diags, field); fe == nil || fe.Severity != SeverityError {
+			t.Errorf("%s diagnostic = %+v, want error", field, fe)
+		}
+	}
+
+	cfg.UpdateChannel = UpdateChannelBeta
+	cfg.UpdateFeedURL = "https://example.com/releases.json"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestConfig_Diagnose_Locale(t *testing.T) {
+	cfg := Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", Locale: "xx"}
+	if fe := findField(cfg.Diagnose(), "locale"); fe == nil || fe.Severity != SeverityWarning {
+		t.Errorf("locale diagnostic = %+v, want warning", fe)
+	}
+
+	cfg.Locale = "de_AT.UTF-8"
+	if fe := findField(cfg.Diagnose(), "locale"); fe != nil {
+		t.Errorf("unexpected locale diagnostic: %+v", fe)
+	}
+}
+
+func TestConfig_Diagnose_Tracing(t *testing.T) {
+	tests := []struct {
+		tracing  TracingSettings
+		field    string
+		severity string
+	}{
+		{TracingSettings{Endpoint: "localhost:4318"}, "tracing.endpoint", SeverityError},
+		{TracingSettings{Endpoint: "http://localhost:4318", Headers: map[string]string{"x-api-key": "k"}}, "tracing.endpoint", SeverityWarning},
+		{TracingSettings{Endpoint: "https://otlp.example.com", SampleRatio: 1.5}, "tracing.sample_ratio", SeverityError},
+		{TracingSettings{Endpoint: "https://otlp.example.com", Headers: map[string]string{"": "k"}}, "tracing.headers", SeverityError},
+		{TracingSettings{Endpoint: "https://otlp.example.com", Headers: map[string]string{"x-api-key": "k"}, SampleRatio: 0.25}, "", ""},
+		{TracingSettings{SampleRatio: 5}, "", ""}, // Off, so not checked
+	}
+	for _, tt := range tests {
+		cfg := Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", Tracing: tt.tracing}
+		diags := cfg.Diagnose()
+		fe := findField(diags, tt.field)
+		if tt.field == "" {
+			for _, d := range diags {
+				if strings.HasPrefix(d.Field, "tracing.") {
+					t.Errorf("%+v: unexpected diagnostic %+v", tt.tracing, d)
+				}
+			}
+			continue
+		}
+		if fe == nil || fe.Severity != tt.severity {
+			t.Errorf("%+v: %s diagnostic = %+v, want %s", tt.tracing, tt.field, fe, tt.severity)
+		}
+	}
+}
+
+func TestConfig_Diagnose_Telemetry(t *testing.T) {
+	tests := []struct {
+		telemetry TelemetrySettings
+		severity  string
+	}{
+		{TelemetrySettings{Enabled: true}, SeverityWarning},
+		{TelemetrySettings{Enabled: true, Endpoint: "telemetry.example.com"}, SeverityError},
+		{TelemetrySettings{Enabled: true, Endpoint: "https://telemetry.example.com/v1/reports"}, ""},
+		{TelemetrySettings{}, ""},
+	}
+	for _, tt := range tests {
+		cfg := Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", Telemetry: tt.telemetry}
+		fe := findField(cfg.Diagnose(), "telemetry.endpoint")
+		if tt.severity == "" {
+			if fe != nil {
+				t.Errorf("%+v: unexpected diagnostic %+v", tt.telemetry, fe)
+			}
+		} else if fe == nil || fe.Severity != tt.severity {
+			t.Errorf("%+v: diagnostic = %+v, want %s", tt.telemetry, fe, tt.severity)
+		}
+	}
+}
+
+func TestConfig_Diagnose_Cassette(t *testing.T) {
+	cfg := Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", Cassette: "chat.json", CassetteMode: "rewind"}
+	if fe := findField(cfg.Diagnose(), "cassette_mode"); fe == nil || fe.Severity != SeverityError {
+		t.Errorf("cassette_mode diagnostic = %+v, want error", fe)
+	}
+
+	cfg.CassetteMode = CassetteRecord
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch checks config.json for changes.
+const DefaultWatchInterval = 2 * time.Second
+
+// fileStamp identifies a version of a file by its modification time and size.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// statConfig returns the stamp of the config file.
+func statConfig() fileStamp {
+	info, err := os.Stat(getConfigPath())
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// Watch polls config.json until ctx is cancelled and calls onChange with the
+// reloaded configuration whenever the file is created or modified, including
+// by editors that replace the file. Changes that fail to load (for example a
+// half-written file) are skipped until the file changes again.
+// Polling is used instead of file system events so it works the same on
+// every platform and with editors that save via rename.
+func Watch(ctx context.Context, interval time.Duration, onChange func(*Config)) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	last := statConfig()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := statConfig()
+		if current == last {
+			continue
+		}
+		last = current
+		if !current.exists {
+			continue // Deleted; keep the running config
+		}
+
+		cfg, err := Load()
+		if err != nil {
+			continue
+		}
+		onChange(cfg)
+	}
+}
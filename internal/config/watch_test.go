@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch_ReloadsOnChange(t *testing.T) {
+	tmpDir, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"model": "first"}`), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan *Config, 4)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Watch(ctx, 10*time.Millisecond, func(cfg *Config) {
+			changes <- cfg
+		})
+	}()
+	// Stop the watcher before cleanup resets the config directory
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Let the watcher record the initial state, then edit the file
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(configPath, []byte(`{"model": "second-model"}`), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	select {
+	case cfg := <-changes:
+		if cfg.Model != "second-model" {
+			t.Errorf("expected reloaded Model='second-model', got %q", cfg.Model)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a change notification")
+	}
+
+	// Unparseable edits are skipped
+	os.WriteFile(configPath, []byte(`{"model": `), 0644)
+	select {
+	case cfg := <-changes:
+		t.Errorf("expected invalid config to be skipped, got %+v", cfg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
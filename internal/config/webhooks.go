@@ -0,0 +1,43 @@
+package config
+
+import (
+	"net/url"
+	"slices"
+)
+
+// Agent run events that can fire a webhook.
+const (
+	WebhookComplete = "complete" // A run finished with a final answer
+	WebhookError    = "error"    // A run failed or was stopped by a limit
+)
+
+// WebhookEvents lists every webhook event.
+var WebhookEvents = []string{WebhookComplete, WebhookError}
+
+// Webhook is a URL that receives a JSON payload when an agent run ends.
+type Webhook struct {
+	URL      string   `json:"url"`
+	Secret   string   `json:"secret,omitempty"` // Signs payloads with HMAC-SHA256 (empty = unsigned)
+	Events   []string `json:"events,omitempty"` // Events to send (empty = all)
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// Wants reports whether the webhook should fire for event.
+func (w Webhook) Wants(event string) bool {
+	return !w.Disabled && (len(w.Events) == 0 || slices.Contains(w.Events, event))
+}
+
+func (c *Config) diagnoseWebhooks(d *diagnostics) {
+	for _, w := range c.Webhooks {
+		if u, err := url.Parse(w.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			d.fail("webhooks", "webhook url must be an http:// or https:// URL: "+w.URL)
+		} else if u.Scheme == "http" && !isLocalHost(u.Hostname()) {
+			d.warn("webhooks", "webhook uses plain http: "+u.Host)
+		}
+		for _, event := range w.Events {
+			if !slices.Contains(WebhookEvents, event) {
+				d.fail("webhooks", "unknown webhook event: "+event)
+			}
+		}
+	}
+}
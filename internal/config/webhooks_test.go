@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWebhook_Wants(t *testing.T) {
+	all := Webhook{URL: "https://example.com/hook"}
+	if !all.Wants(WebhookComplete) || !all.Wants(WebhookError) {
+		t.Error("expected a webhook without events to fire for every event")
+	}
+
+	errorsOnly := Webhook{URL: "https://example.com/hook", Events: []string{WebhookError}}
+	if errorsOnly.Wants(WebhookComplete) || !errorsOnly.Wants(WebhookError) {
+		t.Error("expected only the listed event to fire")
+	}
+
+	off := Webhook{URL: "https://example.com/hook", Disabled: true}
+	if off.Wants(WebhookError) {
+		t.Error("expected a disabled webhook not to fire")
+	}
+}
+
+func TestConfig_Diagnose_Webhooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook Webhook
+		want    string // Substring of the expected message, "" for none
+	}{
+		{"valid", Webhook{URL: "https://hooks.example.com/x", Events: []string{WebhookComplete}}, ""},
+		{"local http", Webhook{URL: "http://localhost:8123/api/webhook/agent"}, ""},
+		{"bad url", Webhook{URL: "hooks.example.com"}, "http:// or https://"},
+		{"plain http", Webhook{URL: "http://hooks.example.com/x"}, "plain http"},
+		{"unknown event", Webhook{URL: "https://hooks.example.com/x", Events: []string{"done"}}, "unknown webhook event"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{APIKey: "key", Endpoint: "https://api.openai.com/v1", Model: "gpt-4o", Webhooks: []Webhook{tt.webhook}}
+
+			var got []string
+			for _, fe := range cfg.Diagnose() {
+				if fe.Field == "webhooks" {
+					got = append(got, fe.Message)
+				}
+			}
+			if tt.want == "" {
+				if len(got) > 0 {
+					t.Errorf("unexpected problems: %v", got)
+				}
+				return
+			}
+			if len(got) != 1 || !strings.Contains(got[0], tt.want) {
+				t.Errorf("problems = %v, want one containing %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// windowStateFile stores the window layout next to config.json. It is kept
+// apart from the config so resizing the window doesn't rewrite settings.
+const windowStateFile = "window.json"
+
+// Default and minimum window sizes.
+const (
+	DefaultWindowWidth  = 1280
+	DefaultWindowHeight = 800
+	MinWindowWidth      = 900
+	MinWindowHeight     = 600
+)
+
+// WindowState is the window size, position, and panel layout saved when
+// the app closes and restored at startup.
+type WindowState struct {
+	Width      int            `json:"width"`
+	Height     int            `json:"height"`
+	X          int            `json:"x"`
+	Y          int            `json:"y"`
+	Positioned bool           `json:"positioned,omitempty"` // X and Y were saved; otherwise the window is centered
+	Maximised  bool           `json:"maximised,omitempty"`
+	Panels     map[string]int `json:"panels,omitempty"` // Panel name -> width in pixels, e.g. "sidebar"
+}
+
+// DefaultWindowState is the layout used on first run.
+func DefaultWindowState() WindowState {
+	return WindowState{Width: DefaultWindowWidth, Height: DefaultWindowHeight}
+}
+
+// LoadWindowState reads the saved window layout. Missing or unreadable
+// state gives the default layout, and sizes are kept within the minimums.
+func LoadWindowState() WindowState {
+	state := DefaultWindowState()
+	data, err := os.ReadFile(filepath.Join(configDir, windowStateFile))
+	if err != nil || json.Unmarshal(data, &state) != nil {
+		return DefaultWindowState()
+	}
+	state.Width = max(state.Width, MinWindowWidth)
+	state.Height = max(state.Height, MinWindowHeight)
+	return state
+}
+
+// SaveWindowState writes the window layout to the config directory.
+func SaveWindowState(state WindowState) error {
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(configDir, windowStateFile), data, 0644)
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWindowState_RoundTrip(t *testing.T) {
+	_, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	if got := LoadWindowState(); got.Width != DefaultWindowWidth || got.Height != DefaultWindowHeight || got.Positioned {
+		t.Errorf("LoadWindowState() without a file = %+v, want defaults", got)
+	}
+
+	state := WindowState{Width: 1500, Height: 900, X: 40, Y: 30, Positioned: true, Maximised: true, Panels: map[string]int{"sidebar": 320}}
+	if err := SaveWindowState(state); err != nil {
+		t.Fatalf("SaveWindowState() error = %v", err)
+	}
+
+	got := LoadWindowState()
+	if got.Width != 1500 || got.Height != 900 || got.X != 40 || got.Y != 30 || !got.Positioned || !got.Maximised || got.Panels["sidebar"] != 320 {
+		t.Errorf("LoadWindowState() = %+v, want %+v", got, state)
+	}
+}
+
+func TestLoadWindowState_ClampsAndIgnoresBadFiles(t *testing.T) {
+	dir, cleanup := setupTestConfigDir(t)
+	defer cleanup()
+
+	SaveWindowState(WindowState{Width: 200, Height: 100})
+	if got := LoadWindowState(); got.Width != MinWindowWidth || got.Height != MinWindowHeight {
+		t.Errorf("LoadWindowState() = %dx%d, want the minimum size", got.Width, got.Height)
+	}
+
+	os.WriteFile(filepath.Join(dir, windowStateFile), []byte("{not json"), 0644)
+	if got := LoadWindowState(); got.Width != DefaultWindowWidth {
+		t.Errorf("LoadWindowState() with a corrupt file = %+v, want defaults", got)
+	}
+}
@@ -0,0 +1,170 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/document"
+)
+
+// maxScanFiles bounds the directory walk done to describe a dropped folder.
+const maxScanFiles = 10000
+
+// Attachment is a file or folder added to a conversation, for example by
+// dropping it on the window. Pending attachments are described to the
+// agent with the next user message.
+type Attachment struct {
+	Path      string    `json:"path"`
+	Name      string    `json:"name"`
+	IsDir     bool      `json:"is_dir"`
+	Size      int64     `json:"size"`            // Bytes; for folders, the total of the files counted
+	Files     int       `json:"files,omitempty"` // Files in a folder, up to maxScanFiles
+	Truncated bool      `json:"truncated,omitempty"`
+	AddedAt   time.Time `json:"added_at"`
+	Pending   bool      `json:"pending,omitempty"`
+}
+
+// NewAttachment describes the file or folder at path.
+func NewAttachment(path string) (Attachment, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("cannot attach %s: %w", path, err)
+	}
+
+	att := Attachment{
+		Path:    abs,
+		Name:    info.Name(),
+		IsDir:   info.IsDir(),
+		Size:    info.Size(),
+		AddedAt: time.Now(),
+		Pending: true,
+	}
+	if att.IsDir {
+		att.Size = 0
+		errStop := errors.New("stop")
+		filepath.WalkDir(abs, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if att.Files == maxScanFiles {
+				att.Truncated = true
+				return errStop
+			}
+			if fi, err := d.Info(); err == nil {
+				att.Size += fi.Size()
+			}
+			att.Files++
+			return nil
+		})
+	}
+	return att, nil
+}
+
+// Attach adds files or folders to the active conversation. Paths already
+// attached are refreshed and marked pending again. It returns the new
+// attachments; paths that can't be read are reported in the error.
+func (m *Manager) Attach(paths []string) ([]Attachment, error) {
+	var added []Attachment
+	var errs []error
+	for _, path := range paths {
+		att, err := NewAttachment(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		added = append(added, att)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active == nil {
+		return nil, errors.New("no active conversation")
+	}
+	for _, att := range added {
+		m.active.Attachments = append(removeAttachment(m.active.Attachments, att.Path), att)
+	}
+
+	if len(added) > 0 {
+		if err := m.save(); err != nil {
+			return nil, err
+		}
+	}
+	return added, errors.Join(errs...)
+}
+
+// Detach removes an attachment from the conversation with the given ID.
+func (m *Manager) Detach(id, path string) error {
+	return m.update(id, func(conv *Conversation) {
+		conv.Attachments = removeAttachment(conv.Attachments, path)
+	})
+}
+
+// GetAttachments returns the attachments of the conversation with the given ID.
+func (m *Manager) GetAttachments(id string) ([]Attachment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active != nil && m.active.ID == id {
+		return slices.Clone(m.active.Attachments), nil
+	}
+	conv, err := m.store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	return conv.Attachments, nil
+}
+
+// takePendingAttachments returns conv's pending attachments and marks them
+// as sent.
+func takePendingAttachments(conv *Conversation) []Attachment {
+	var pending []Attachment
+	for i := range conv.Attachments {
+		if conv.Attachments[i].Pending {
+			conv.Attachments[i].Pending = false
+			pending = append(pending, conv.Attachments[i])
+		}
+	}
+	return pending
+}
+
+// DescribeAttachments formats attachments as context for a user message.
+func DescribeAttachments(atts []Attachment) string {
+	if len(atts) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Attached files:")
+	for _, att := range atts {
+		switch {
+		case att.IsDir && att.Truncated:
+			fmt.Fprintf(&b, "\n- %s (folder, more than %d files)", att.Path, att.Files)
+		case att.IsDir:
+			fmt.Fprintf(&b, "\n- %s (folder, %d files, %d bytes)", att.Path, att.Files, att.Size)
+		case document.IsDocument(att.Path):
+			fmt.Fprintf(&b, "\n- %s (%s, %d bytes; read_file returns its text)", att.Path, document.Kind(att.Path), att.Size)
+		default:
+			fmt.Fprintf(&b, "\n- %s (%d bytes)", att.Path, att.Size)
+		}
+	}
+	return b.String()
+}
+
+// removeAttachment returns atts without the attachment at path.
+func removeAttachment(atts []Attachment, path string) []Attachment {
+	kept := atts[:0:0]
+	for _, att := range atts {
+		if att.Path != path {
+			kept = append(kept, att)
+		}
+	}
+	return kept
+}
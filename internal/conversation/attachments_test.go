@@ -0,0 +1,101 @@
+package conversation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewAttachment(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("world!"), 0644)
+
+	folder, err := NewAttachment(dir)
+	if err != nil {
+		t.Fatalf("NewAttachment failed: %v", err)
+	}
+	if !folder.IsDir || folder.Files != 2 || folder.Size != 11 || !folder.Pending {
+		t.Errorf("Unexpected folder attachment: %+v", folder)
+	}
+
+	file, err := NewAttachment(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatalf("NewAttachment failed: %v", err)
+	}
+	if file.IsDir || file.Size != 5 || file.Name != "a.txt" {
+		t.Errorf("Unexpected file attachment: %+v", file)
+	}
+
+	if _, err := NewAttachment(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing path")
+	}
+}
+
+func TestManagerAttach(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	if _, err := manager.Attach([]string{"x"}); err == nil {
+		t.Error("Expected error with no active conversation")
+	}
+
+	conv := manager.New()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.md")
+	os.WriteFile(path, []byte("# Notes"), 0644)
+
+	added, err := manager.Attach([]string{path, filepath.Join(dir, "missing")})
+	if err == nil {
+		t.Error("Expected error for the missing path")
+	}
+	if len(added) != 1 || added[0].Path != path {
+		t.Fatalf("Expected the readable file to be attached, got %+v", added)
+	}
+
+	// Attaching the same path again replaces it
+	manager.Attach([]string{path})
+	atts, _ := manager.GetAttachments(conv.ID)
+	if len(atts) != 1 {
+		t.Errorf("Expected 1 attachment after re-attaching, got %d", len(atts))
+	}
+
+	// Pending attachments are handed out once
+	run := beginRun(t, manager)
+	pending := run.TakePendingAttachments()
+	if len(pending) != 1 {
+		t.Fatalf("Expected 1 pending attachment, got %d", len(pending))
+	}
+	if again := run.TakePendingAttachments(); len(again) != 0 {
+		t.Errorf("Expected no pending attachments after taking them, got %d", len(again))
+	}
+
+	run.End()
+
+	if err := manager.Detach(conv.ID, path); err != nil {
+		t.Fatalf("Detach failed: %v", err)
+	}
+	if atts, _ := manager.GetAttachments(conv.ID); len(atts) != 0 {
+		t.Errorf("Expected no attachments after Detach, got %d", len(atts))
+	}
+}
+
+func TestDescribeAttachments(t *testing.T) {
+	if got := DescribeAttachments(nil); got != "" {
+		t.Errorf("Expected empty description, got %q", got)
+	}
+
+	got := DescribeAttachments([]Attachment{
+		{Path: "/work/project", IsDir: true, Files: 3, Size: 120},
+		{Path: "/work/todo.txt", Size: 42},
+		{Path: "/work/invoice.pdf", Size: 900},
+	})
+	for _, want := range []string{"Attached files:", "/work/project (folder, 3 files, 120 bytes)", "/work/todo.txt (42 bytes)",
+		"/work/invoice.pdf (PDF document, 900 bytes; read_file returns its text)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in description:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/llm"
+)
+
+// DefaultContextWindow is the context window, in tokens, assumed when none is configured.
+const DefaultContextWindow = 32000
+
+// compactionThreshold is the fraction of the context window at which
+// RunHandle.CompactIfNeeded compacts the run's conversation.
+const compactionThreshold = 0.75
+
+// defaultKeepTurns is the number of recent user turns left verbatim by a compaction.
+const defaultKeepTurns = 2
+
+// compactionPrompt instructs the LLM how to summarize older turns.
+const compactionPrompt = "Summarize the following conversation between a user and an AI assistant that executes commands and manages files. " +
+	"Preserve the user's goals, decisions made, files and directories involved, commands that succeeded or failed, and any open questions. " +
+	"Write concise prose or bullet points. Reply with only the summary."
+
+// Compaction records an LLM-generated summary that stands in for the
+// earliest messages of a conversation when it is sent to the LLM.
+// The original messages are kept so the UI can still show them.
+type Compaction struct {
+	Summary   string    `json:"summary"`
+	UpToIndex int       `json:"up_to_index"` // Messages before this index (after the system prompt) are summarized
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LLMMessages returns the messages to send to the LLM. If the conversation
+// has been compacted, the summarized messages are replaced by a single
+// summary message following the system prompt.
+func (c *Conversation) LLMMessages() []llm.Message {
+	if c.Compaction == nil || c.Compaction.UpToIndex > len(c.Messages) {
+		messages := make([]llm.Message, len(c.Messages))
+		copy(messages, c.Messages)
+		return messages
+	}
+
+	head := leadingSystemMessages(c.Messages)
+	messages := make([]llm.Message, 0, head+1+len(c.Messages)-c.Compaction.UpToIndex)
+	messages = append(messages, c.Messages[:head]...)
+	messages = append(messages, llm.Message{
+		Role:    "system",
+		Content: "Summary of the earlier conversation:\n" + c.Compaction.Summary,
+	})
+	messages = append(messages, c.Messages[c.Compaction.UpToIndex:]...)
+	return messages
+}
+
+// compactionCut returns the index of the first message to keep verbatim when
+// keeping the last keepTurns user turns, or 0 if there is nothing to compact.
+// Cuts always fall on a user message so tool calls stay paired with their results.
+func (c *Conversation) compactionCut(keepTurns int) int {
+	start := leadingSystemMessages(c.Messages)
+	if c.Compaction != nil {
+		start = c.Compaction.UpToIndex
+	}
+
+	turns := 0
+	for i := len(c.Messages) - 1; i > start; i-- {
+		if c.Messages[i].Role != "user" {
+			continue
+		}
+		turns++
+		if turns == keepTurns {
+			return i
+		}
+	}
+	return 0
+}
+
+// leadingSystemMessages returns how many system messages open the conversation.
+func leadingSystemMessages(messages []llm.Message) int {
+	n := 0
+	for n < len(messages) && messages[n].Role == "system" {
+		n++
+	}
+	return n
+}
+
+// EstimateTokens roughly estimates the token count of messages using the
+// common four-characters-per-token heuristic.
+func EstimateTokens(messages []llm.Message) int {
+	chars := 0
+	for _, msg := range messages {
+		chars += len(msg.Content) + len(msg.Role)
+		for _, tc := range msg.ToolCalls {
+			chars += len(tc.Name) + len(tc.Arguments)
+		}
+	}
+	return chars/4 + len(messages)*4
+}
+
+// SetContextWindow sets the context window, in tokens, used by RunHandle.CompactIfNeeded.
+func (m *Manager) SetContextWindow(tokens int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.contextWindow = tokens
+}
+
+// GetLLMMessages returns a copy of the active conversation as it should be
+// sent to the LLM, with compacted turns replaced by their summary.
+func (m *Manager) GetLLMMessages() []llm.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active == nil {
+		return nil
+	}
+	return m.active.LLMMessages()
+}
+
+// Compact summarizes all but the most recent turns of the active conversation
+// with the LLM and saves the summary alongside the original messages.
+// Returns false if there was nothing to compact, or if the conversation
+// changed in a way the summary no longer fits while the LLM was asked.
+func (m *Manager) Compact(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	conv := m.active
+	m.mu.Unlock()
+	if conv == nil {
+		return false, errors.New("no active conversation")
+	}
+	return m.compact(ctx, conv.ID)
+}
+
+// compact is Compact for the conversation with the given ID, which is
+// active or being run.
+func (m *Manager) compact(ctx context.Context, id string) (bool, error) {
+	if m.client == nil {
+		return false, errors.New("no LLM client configured")
+	}
+
+	m.mu.Lock()
+	conv := m.loadedLocked(id)
+	if conv == nil {
+		m.mu.Unlock()
+		return false, errors.New("the conversation is not loaded")
+	}
+	cut := conv.compactionCut(defaultKeepTurns)
+	if cut == 0 {
+		m.mu.Unlock()
+		return false, nil
+	}
+
+	start := leadingSystemMessages(conv.Messages)
+	previous := conv.Compaction
+	var transcript strings.Builder
+	if previous != nil {
+		start = previous.UpToIndex
+		transcript.WriteString("Earlier summary:\n" + previous.Summary + "\n\n")
+	}
+	for _, msg := range conv.Messages[start:cut] {
+		transcript.WriteString(msg.Role + ": " + msg.Content + "\n")
+		for _, tc := range msg.ToolCalls {
+			transcript.WriteString("  [tool call] " + tc.Name + " " + tc.Arguments + "\n")
+		}
+	}
+	m.mu.Unlock()
+
+	resp, err := m.client.ChatCompletion(ctx, []llm.Message{
+		{Role: "system", Content: compactionPrompt},
+		{Role: "user", Content: transcript.String()},
+	}, nil)
+	if err != nil {
+		return false, err
+	}
+
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return false, errors.New("LLM returned an empty summary")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// The summary only fits the conversation it was made from, if it still
+	// has the summarized messages and wasn't compacted meanwhile
+	if m.loadedLocked(id) != conv || conv.Compaction != previous || len(conv.Messages) < cut {
+		return false, nil
+	}
+	conv.Compaction = &Compaction{
+		Summary:   summary,
+		UpToIndex: cut,
+		CreatedAt: time.Now(),
+	}
+	return true, m.saveConvLocked(conv)
+}
+
+// compactIfNeeded compacts the conversation with the given ID, which is
+// active or being run, when its estimated size exceeds the compaction
+// threshold of the context window.
+func (m *Manager) compactIfNeeded(ctx context.Context, id string) (bool, error) {
+	if m.client == nil {
+		return false, nil
+	}
+
+	m.mu.Lock()
+	conv := m.loadedLocked(id)
+	if conv == nil {
+		m.mu.Unlock()
+		return false, nil
+	}
+	window := m.contextWindow
+	if window <= 0 {
+		window = DefaultContextWindow
+	}
+	small := float64(EstimateTokens(conv.LLMMessages())) < float64(window)*compactionThreshold
+	m.mu.Unlock()
+
+	if small {
+		return false, nil
+	}
+	return m.compact(ctx, id)
+}
@@ -0,0 +1,145 @@
+package conversation
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
+)
+
+// addTurns adds n user/assistant exchanges to the active conversation.
+func addTurns(manager *Manager, n int) {
+	for i := 0; i < n; i++ {
+		manager.AddUserMessage("Question")
+		manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Answer"})
+	}
+}
+
+func TestLLMMessagesWithoutCompaction(t *testing.T) {
+	conv := New()
+	conv.AddMessage(llm.Message{Role: "system", Content: "System"})
+	conv.AddMessage(llm.Message{Role: "user", Content: "Hi"})
+
+	messages := conv.LLMMessages()
+	if len(messages) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(messages))
+	}
+}
+
+func TestManagerCompact(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	var prompt []llm.Message
+	manager.client = &MockClient{
+		ChatCompletionFunc: func(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error) {
+			prompt = messages
+			return &llm.Response{Content: "The user asked three questions."}, nil
+		},
+	}
+
+	conv := manager.New()
+	addTurns(manager, 4) // system + 8 messages
+
+	compacted, err := manager.Compact(context.Background())
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if !compacted {
+		t.Fatal("Expected conversation to be compacted")
+	}
+
+	// The two oldest turns are summarized; the last two are kept verbatim
+	conv = manager.GetActive()
+	if conv.Compaction == nil || conv.Compaction.UpToIndex != 5 {
+		t.Fatalf("Expected compaction up to index 5, got %+v", conv.Compaction)
+	}
+	if !strings.Contains(prompt[1].Content, "user: Question") {
+		t.Errorf("Expected transcript in summarization prompt, got %q", prompt[1].Content)
+	}
+
+	// Original history is preserved for the UI
+	if len(manager.GetMessages()) != 9 {
+		t.Errorf("Expected 9 preserved messages, got %d", len(manager.GetMessages()))
+	}
+
+	// The LLM sees system + summary + last two turns
+	llmMessages := manager.GetLLMMessages()
+	if len(llmMessages) != 6 {
+		t.Fatalf("Expected 6 LLM messages, got %d", len(llmMessages))
+	}
+	if !strings.Contains(llmMessages[1].Content, "The user asked three questions.") {
+		t.Errorf("Expected summary message, got %q", llmMessages[1].Content)
+	}
+
+	// Compaction is persisted
+	loaded, _ := manager.store.Load(conv.ID)
+	if loaded.Compaction == nil || loaded.Compaction.Summary != "The user asked three questions." {
+		t.Error("Expected compaction to be saved")
+	}
+}
+
+func TestManagerCompactNothingToCompact(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	manager.New()
+	addTurns(manager, 2)
+
+	compacted, err := manager.Compact(context.Background())
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if compacted {
+		t.Error("Expected no compaction with only two turns")
+	}
+}
+
+func TestRunHandleCompactIfNeeded(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	manager.New()
+	addTurns(manager, 4)
+	run := beginRun(t, manager)
+
+	manager.SetContextWindow(100000)
+	compacted, _ := run.CompactIfNeeded(context.Background())
+	if compacted {
+		t.Error("Should not compact a small conversation")
+	}
+
+	manager.SetContextWindow(10)
+	compacted, err := run.CompactIfNeeded(context.Background())
+	if err != nil {
+		t.Fatalf("CompactIfNeeded failed: %v", err)
+	}
+	if !compacted {
+		t.Error("Expected compaction when over the context window")
+	}
+}
+
+func TestTruncateAfterDropsStaleCompaction(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	manager.New()
+	addTurns(manager, 4)
+	manager.Compact(context.Background())
+
+	if err := manager.TruncateAfter(2); err != nil {
+		t.Fatalf("TruncateAfter failed: %v", err)
+	}
+	if manager.GetActive().Compaction != nil {
+		t.Error("Expected compaction to be cleared when its messages are truncated")
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	messages := []llm.Message{{Role: "user", Content: strings.Repeat("a", 400)}}
+	if tokens := EstimateTokens(messages); tokens < 100 || tokens > 110 {
+		t.Errorf("Expected roughly 100 tokens, got %d", tokens)
+	}
+}
@@ -0,0 +1,210 @@
+package conversation
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"agent-desktop/internal/llm"
+)
+
+const (
+	blobsDir = "blobs"
+
+	// BlobMinBytes is the size from which a tool output is kept in a blob,
+	// shared by every message with the same output, instead of inline.
+	BlobMinBytes = 8 << 10
+
+	// blobGracePeriod keeps unreferenced blobs this long before pruning
+	// them, so a blob that a sync client delivered ahead of the
+	// conversation using it isn't deleted.
+	blobGracePeriod = 24 * time.Hour
+)
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// storedConversation is a conversation as written to its file: tool
+// outputs kept in blobs are left empty and listed by message index.
+type storedConversation struct {
+	*Conversation
+	Blobs map[int]string `json:"blobs,omitempty"` // Message index to blob hash
+}
+
+// SetCompression turns on gzipping conversation files and keeping large
+// tool outputs in content-addressed blobs, which tool-heavy transcripts
+// shrink to a fraction of their size with. Conversations are compressed
+// when next saved; compressed ones stay readable with compression off.
+// Blobs aren't used while the store is encrypted, since their names
+// would tell which conversations share an output.
+func (s *FileStore) SetCompression(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compress = on
+}
+
+// marshalConversation encodes conv for its file, moving its large tool
+// outputs into blobs if compression is on (caller must hold write lock).
+func (s *FileStore) marshalConversation(conv *Conversation) ([]byte, error) {
+	stored := storedConversation{Conversation: conv}
+	if s.compress && s.cipher == nil {
+		var messages []llm.Message
+		for i, msg := range conv.Messages {
+			if msg.Role != "tool" || len(msg.Content) < BlobMinBytes {
+				continue
+			}
+			hash, err := s.putBlob(msg.Content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to write blob: %w", err)
+			}
+			if messages == nil {
+				messages = append([]llm.Message(nil), conv.Messages...)
+				stored.Blobs = make(map[int]string)
+			}
+			messages[i].Content = ""
+			stored.Blobs[i] = hash
+		}
+		if messages != nil {
+			trimmed := *conv
+			trimmed.Messages = messages
+			stored.Conversation = &trimmed
+		}
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil || !s.compress {
+		return data, err
+	}
+	return gzipData(data)
+}
+
+// unmarshalConversation decodes a conversation file, filling in the tool
+// outputs kept in blobs (caller must hold lock).
+func (s *FileStore) unmarshalConversation(data []byte) (*Conversation, error) {
+	stored := storedConversation{Conversation: &Conversation{}}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	conv := stored.Conversation
+	for i, hash := range stored.Blobs {
+		if i < 0 || i >= len(conv.Messages) {
+			continue
+		}
+		content, err := s.readData(s.blobPath(hash))
+		if err != nil {
+			// Most likely still on its way from a sync client
+			logger.Warn("tool output missing from blobs", "conversation", conv.ID, "blob", hash, "error", err)
+			content = []byte(fmt.Sprintf("[tool output unavailable: blob %s is missing]", hash))
+		}
+		conv.Messages[i].Content = string(content)
+	}
+	return conv, nil
+}
+
+// putBlob stores content as a blob named by its hash, unless it is stored
+// already, and returns the hash (caller must hold write lock).
+func (s *FileStore) putBlob(content string) (string, error) {
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+	path := s.blobPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	data, err := gzipData([]byte(content))
+	if err != nil {
+		return "", err
+	}
+	return hash, s.writeData(path, data)
+}
+
+// blobPath returns the path of the blob with the given hash.
+func (s *FileStore) blobPath(hash string) string {
+	return filepath.Join(s.basePath, blobsDir, hash)
+}
+
+// pruneBlobs deletes the blobs no conversation file, in the store or in
+// the trash, refers to any more (caller must hold write lock).
+func (s *FileStore) pruneBlobs() error {
+	entries, err := os.ReadDir(filepath.Join(s.basePath, blobsDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	used := map[string]bool{}
+	for _, dir := range []string{s.basePath, filepath.Join(s.basePath, trashDir)} {
+		files, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+				continue
+			}
+			data, err := s.readData(filepath.Join(dir, file.Name()))
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", file.Name(), err) // Can't tell what it uses
+			}
+			var refs struct {
+				Blobs map[int]string `json:"blobs"`
+			}
+			if json.Unmarshal(data, &refs) != nil {
+				continue
+			}
+			for _, hash := range refs.Blobs {
+				used[hash] = true
+			}
+		}
+	}
+
+	cutoff := time.Now().Add(-blobGracePeriod)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || used[entry.Name()] || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.basePath, blobsDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gzipData compresses data.
+func gzipData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzipData decompresses data if it is gzipped and returns it unchanged
+// otherwise.
+func gunzipData(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,97 @@
+package conversation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/llm"
+)
+
+func TestStoreCompression(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+
+	// A plain file saved before compression was turned on
+	plain := New()
+	plain.AddMessage(llm.Message{Role: "user", Content: "hi"})
+	if err := store.Save(plain); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	store.SetCompression(true)
+	output := strings.Repeat("build log line\n", BlobMinBytes/10)
+	convs := []*Conversation{New(), New()}
+	for _, conv := range convs {
+		conv.AddMessage(llm.Message{Role: "user", Content: "build it"})
+		conv.AddMessage(llm.Message{Role: "tool", Content: output, ToolCallID: "call_1"})
+		conv.AddMessage(llm.Message{Role: "tool", Content: "ok", ToolCallID: "call_2"})
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(store.convPath(convs[0].ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), string(gzipMagic)) || len(data) > len(output)/4 {
+		t.Errorf("conversation file is %d bytes, want it gzipped without the tool output", len(data))
+	}
+	if blobs, _ := os.ReadDir(filepath.Join(dir, blobsDir)); len(blobs) != 1 {
+		t.Errorf("blobs = %d, want the shared output stored once", len(blobs))
+	}
+
+	// Loading is transparent, with compression on or off
+	store.SetCompression(false)
+	for _, want := range append(convs, plain) {
+		got, err := store.Load(want.ID)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if len(got.Messages) != len(want.Messages) {
+			t.Fatalf("Load() messages = %d, want %d", len(got.Messages), len(want.Messages))
+		}
+		for i := range want.Messages {
+			if got.Messages[i].Content != want.Messages[i].Content {
+				t.Errorf("message %d content = %.20q..., want %.20q...", i, got.Messages[i].Content, want.Messages[i].Content)
+			}
+		}
+	}
+	if summaries, err := store.List(); err != nil || len(summaries) != 3 {
+		t.Errorf("List() = %d summaries, %v; want 3", len(summaries), err)
+	}
+
+	// The blob outlives the conversations until the trash is emptied
+	old := time.Now().Add(-2 * blobGracePeriod)
+	for _, conv := range convs {
+		if err := store.Delete(conv.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+	}
+	blobs, _ := os.ReadDir(filepath.Join(dir, blobsDir))
+	os.Chtimes(filepath.Join(dir, blobsDir, blobs[0].Name()), old, old)
+	if err := store.Restore(convs[0].ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if err := store.EmptyTrash(); err != nil {
+		t.Fatalf("EmptyTrash() error = %v", err)
+	}
+	if got, err := store.Load(convs[0].ID); err != nil || got.Messages[1].Content != output {
+		t.Errorf("Load() after emptying the trash lost the output of a restored conversation: %v", err)
+	}
+	if err := store.Delete(convs[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EmptyTrash(); err != nil {
+		t.Fatal(err)
+	}
+	if blobs, _ := os.ReadDir(filepath.Join(dir, blobsDir)); len(blobs) != 0 {
+		t.Errorf("blobs = %d after emptying the trash, want 0", len(blobs))
+	}
+}
@@ -2,9 +2,13 @@
 package conversation
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
 
 	"github.com/google/uuid"
 )
@@ -16,6 +20,39 @@ type Conversation struct {
 	CreatedAt time.Time     `json:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at"`
 	Messages  []llm.Message `json:"messages"`
+	Tags      []string      `json:"tags,omitempty"`
+	Folder    string        `json:"folder,omitempty"`
+	ParentID  string        `json:"parent_id,omitempty"` // Conversation this one was forked from
+	Pinned    bool          `json:"pinned,omitempty"`    // Pinned conversations are exempt from retention
+	Archived  bool          `json:"archived,omitempty"`
+
+	// Compaction summarizes older messages for the LLM (nil if never compacted)
+	Compaction *Compaction `json:"compaction,omitempty"`
+
+	// Attachments are files and folders added as working context
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// Settings overrides app configuration while this conversation is active
+	Settings Settings `json:"settings"`
+	// SessionCWD is the shell working directory when the conversation was last saved
+	SessionCWD string `json:"session_cwd,omitempty"`
+	// SessionHistory is the shell commands run for the conversation, with
+	// their exit codes and folders, restored when it is reopened
+	SessionHistory []tools.CommandRecord `json:"session_history,omitempty"`
+
+	// Stats holds cumulative token, cost, and tool usage
+	Stats Stats `json:"stats"`
+
+	// Runs holds the steps of recent agent runs for replay
+	Runs []Run `json:"runs,omitempty"`
+
+	// CustomTitle is set when the user renamed the conversation; auto-titling leaves it alone
+	CustomTitle bool `json:"custom_title,omitempty"`
+	// TitledAtTurn is the turn count when the title was last generated
+	TitledAtTurn int `json:"titled_at_turn,omitempty"`
+
+	// Revision is incremented on every save to detect edits made elsewhere
+	Revision int64 `json:"revision,omitempty"`
 }
 
 // Summary is a lightweight representation of a conversation for listing.
@@ -25,6 +62,11 @@ type Summary struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	TurnCount int       `json:"turn_count"`
+	Tags      []string  `json:"tags,omitempty"`
+	Folder    string    `json:"folder,omitempty"`
+	Pinned    bool      `json:"pinned,omitempty"`
+	Archived  bool      `json:"archived,omitempty"`
+	Stats     Stats     `json:"stats"`
 }
 
 // New creates a new conversation with a generated ID and default title.
@@ -32,7 +74,7 @@ func New() *Conversation {
 	now := time.Now()
 	return &Conversation{
 		ID:        uuid.New().String(),
-		Title:     "New Conversation",
+		Title:     defaultTitle,
 		CreatedAt: now,
 		UpdatedAt: now,
 		Messages:  []llm.Message{},
@@ -41,8 +83,20 @@ func New() *Conversation {
 
 // AddMessage appends a message to the conversation and updates the timestamp.
 func (c *Conversation) AddMessage(msg llm.Message) {
+	now := time.Now()
+
+	// Stamp the message with its creation time, keeping any other metadata
+	meta := llm.MessageMeta{Timestamp: now}
+	if msg.Meta != nil {
+		meta = *msg.Meta
+		if meta.Timestamp.IsZero() {
+			meta.Timestamp = now
+		}
+	}
+	msg.Meta = &meta
+
 	c.Messages = append(c.Messages, msg)
-	c.UpdatedAt = time.Now()
+	c.UpdatedAt = now
 }
 
 // TurnCount returns the number of user messages (turns) in the conversation.
@@ -64,5 +118,128 @@ func (c *Conversation) ToSummary() Summary {
 		CreatedAt: c.CreatedAt,
 		UpdatedAt: c.UpdatedAt,
 		TurnCount: c.TurnCount(),
+		Tags:      append([]string(nil), c.Tags...),
+		Folder:    c.Folder,
+		Pinned:    c.Pinned,
+		Archived:  c.Archived,
+		Stats:     c.Stats,
+	}
+}
+
+// TruncateAfter drops every message after the one at index, keeping
+// messages 0..index inclusive.
+func (c *Conversation) TruncateAfter(index int) error {
+	if index < 0 || index >= len(c.Messages) {
+		return fmt.Errorf("message index %d out of range (conversation has %d messages)", index, len(c.Messages))
+	}
+	c.Messages = c.Messages[:index+1]
+	c.UpdatedAt = time.Now()
+
+	// A summary of messages that no longer exist must not be sent to the LLM
+	if c.Compaction != nil && c.Compaction.UpToIndex > index {
+		c.Compaction = nil
+	}
+
+	// Runs that added only dropped messages can't be replayed
+	for i, run := range c.Runs {
+		if run.FirstMessage > index {
+			c.Runs = c.Runs[:i]
+			break
+		}
+	}
+	return nil
+}
+
+// Fork returns a new conversation containing copies of the messages up to
+// and including the message at index upTo. If that message is an assistant
+// message with tool calls, the tool results that answer it are included too
+// so the forked transcript remains valid for the LLM.
+func (c *Conversation) Fork(upTo int) (*Conversation, error) {
+	if upTo < 0 || upTo >= len(c.Messages) {
+		return nil, fmt.Errorf("message index %d out of range (conversation has %d messages)", upTo, len(c.Messages))
+	}
+
+	end := upTo + 1
+	if len(c.Messages[upTo].ToolCalls) > 0 {
+		for end < len(c.Messages) && c.Messages[end].Role == "tool" {
+			end++
+		}
+	}
+
+	fork := New()
+	fork.Title = "Fork of " + c.Title
+	fork.ParentID = c.ID
+	fork.Folder = c.Folder
+	fork.Tags = append([]string(nil), c.Tags...)
+	fork.Settings = c.Settings
+	fork.SessionCWD = c.SessionCWD
+	fork.Messages = make([]llm.Message, end)
+	copy(fork.Messages, c.Messages[:end])
+	for i := range fork.Messages {
+		fork.Messages[i].ToolCalls = append([]llm.ToolCall(nil), fork.Messages[i].ToolCalls...)
+	}
+	if c.Compaction != nil && c.Compaction.UpToIndex < end {
+		compaction := *c.Compaction
+		fork.Compaction = &compaction
+	}
+
+	return fork, nil
+}
+
+// clone returns a copy of the conversation that doesn't share the slices
+// the manager changes in place, so it can be read while the conversation
+// goes on.
+func (c *Conversation) clone() *Conversation {
+	cp := *c
+	cp.Messages = slices.Clone(c.Messages)
+	cp.Tags = slices.Clone(c.Tags)
+	cp.Attachments = slices.Clone(c.Attachments)
+	cp.Runs = slices.Clone(c.Runs)
+	return &cp
+}
+
+// HasTag reports whether the conversation carries the given tag.
+// Tags are compared case-insensitively.
+func (c *Conversation) HasTag(tag string) bool {
+	return containsTag(c.Tags, tag)
+}
+
+// AddTag adds a tag to the conversation. Returns false if the tag is empty
+// or already present.
+func (c *Conversation) AddTag(tag string) bool {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || c.HasTag(tag) {
+		return false
+	}
+	c.Tags = append(c.Tags, tag)
+	return true
+}
+
+// RemoveTag removes a tag from the conversation. Returns false if the tag
+// was not present.
+func (c *Conversation) RemoveTag(tag string) bool {
+	tag = strings.TrimSpace(tag)
+	for i, existing := range c.Tags {
+		if strings.EqualFold(existing, tag) {
+			c.Tags = append(c.Tags[:i], c.Tags[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// HasTag reports whether the summarized conversation carries the given tag.
+func (s Summary) HasTag(tag string) bool {
+	return containsTag(s.Tags, tag)
+}
+
+// containsTag reports whether tags contains tag, ignoring case and surrounding whitespace.
+func containsTag(tags []string, tag string) bool {
+	tag = strings.TrimSpace(tag)
+	for _, existing := range tags {
+		if strings.EqualFold(existing, tag) {
+			return true
+		}
 	}
+	return false
 }
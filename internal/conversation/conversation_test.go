@@ -1,8 +1,10 @@
 package conversation
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -60,6 +62,25 @@ func TestConversationAddMessage(t *testing.T) {
 	}
 }
 
+func TestConversationAddMessageStampsMeta(t *testing.T) {
+	conv := New()
+
+	conv.AddMessage(llm.Message{Role: "user", Content: "Hi"})
+	if conv.Messages[0].Meta == nil || conv.Messages[0].Meta.Timestamp.IsZero() {
+		t.Fatal("Expected message to be timestamped")
+	}
+
+	meta := &llm.MessageMeta{Model: "gpt-4o", Step: 3}
+	conv.AddMessage(llm.Message{Role: "assistant", Content: "Hello", Meta: meta})
+	got := conv.Messages[1].Meta
+	if got.Model != "gpt-4o" || got.Step != 3 || got.Timestamp.IsZero() {
+		t.Errorf("Expected metadata to be kept and timestamped, got %+v", got)
+	}
+	if !meta.Timestamp.IsZero() {
+		t.Error("Expected caller's metadata not to be modified")
+	}
+}
+
 func TestConversationTurnCount(t *testing.T) {
 	conv := New()
 
@@ -104,7 +125,7 @@ func TestConversationToSummary(t *testing.T) {
 
 // Store tests
 
-func setupTestStore(t *testing.T) (*Store, func()) {
+func setupTestStore(t *testing.T) (*FileStore, func()) {
 	// Create temp directory for test
 	tempDir, err := os.MkdirTemp("", "conversation_test")
 	if err != nil {
@@ -174,6 +195,50 @@ func TestStoreSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestStoreBatchesIndexWrites(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	indexPath := filepath.Join(store.basePath, "index.json")
+	initial, _ := os.ReadFile(indexPath)
+
+	conv := New()
+	for i := 0; i < 20; i++ {
+		conv.Title = fmt.Sprintf("Step %d", i)
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+	if data, _ := os.ReadFile(indexPath); string(data) != string(initial) {
+		t.Error("Expected index writes to be batched")
+	}
+	if summaries, err := store.List(); err != nil || len(summaries) != 1 || summaries[0].Title != "Step 19" {
+		t.Errorf("List() = %+v, %v", summaries, err)
+	}
+
+	// A store opened before the index was written still finds the conversation
+	other, err := NewStore(store.basePath)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	if summaries, _ := other.List(); len(summaries) != 1 || summaries[0].ID != conv.ID {
+		t.Errorf("List() from another store = %+v", summaries)
+	}
+
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if data, _ := os.ReadFile(indexPath); !strings.Contains(string(data), "Step 19") {
+		t.Errorf("index on disk after Flush = %s", data)
+	}
+
+	// After closing, every change is written right away
+	store.Close()
+	store.Delete(conv.ID)
+	if data, _ := os.ReadFile(indexPath); strings.Contains(string(data), conv.ID) {
+		t.Error("Expected the index to be written after closing")
+	}
+}
+
 func TestStoreList(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -290,3 +355,125 @@ func TestStoreUpdateExisting(t *testing.T) {
 		t.Errorf("Expected 1 conversation in list, got %d", len(summaries))
 	}
 }
+
+func TestConversationTags(t *testing.T) {
+	conv := New()
+
+	if !conv.AddTag("work") {
+		t.Error("Expected AddTag to add a new tag")
+	}
+	if conv.AddTag("Work") {
+		t.Error("Expected AddTag to ignore duplicate tag with different case")
+	}
+	if conv.AddTag("  ") {
+		t.Error("Expected AddTag to ignore empty tag")
+	}
+
+	if !conv.HasTag("WORK") {
+		t.Error("Expected HasTag to match case-insensitively")
+	}
+
+	summary := conv.ToSummary()
+	if !summary.HasTag("work") {
+		t.Error("Expected summary to carry tags")
+	}
+
+	if !conv.RemoveTag("work") {
+		t.Error("Expected RemoveTag to remove existing tag")
+	}
+	if conv.HasTag("work") {
+		t.Error("Expected tag to be removed")
+	}
+	if conv.RemoveTag("work") {
+		t.Error("Expected RemoveTag to return false for missing tag")
+	}
+}
+
+func TestStoreSavePersistsTagsAndFolder(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	conv := New()
+	conv.AddTag("research")
+	conv.Folder = "projects/alpha"
+	store.Save(conv)
+
+	loaded, err := store.Load(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if !loaded.HasTag("research") || loaded.Folder != "projects/alpha" {
+		t.Errorf("Expected tags and folder to persist, got %v / %q", loaded.Tags, loaded.Folder)
+	}
+
+	summaries, _ := store.List()
+	if len(summaries) != 1 || summaries[0].Folder != "projects/alpha" || !summaries[0].HasTag("research") {
+		t.Errorf("Expected index to carry tags and folder, got %+v", summaries)
+	}
+}
+
+func TestConversationFork(t *testing.T) {
+	conv := New()
+	conv.Title = "Original"
+	conv.AddTag("work")
+	conv.AddMessage(llm.Message{Role: "system", Content: "System"})
+	conv.AddMessage(llm.Message{Role: "user", Content: "First"})
+	conv.AddMessage(llm.Message{Role: "assistant", Content: "Reply"})
+	conv.AddMessage(llm.Message{Role: "user", Content: "Second"})
+
+	fork, err := conv.Fork(2)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+
+	if fork.ID == conv.ID {
+		t.Error("Fork should have a new ID")
+	}
+	if fork.ParentID != conv.ID {
+		t.Errorf("Expected ParentID %q, got %q", conv.ID, fork.ParentID)
+	}
+	if len(fork.Messages) != 3 {
+		t.Errorf("Expected 3 messages in fork, got %d", len(fork.Messages))
+	}
+	if !fork.HasTag("work") {
+		t.Error("Fork should keep tags")
+	}
+
+	// Modifying the fork must not affect the original
+	fork.Messages[1].Content = "Changed"
+	if conv.Messages[1].Content != "First" {
+		t.Error("Fork should not share messages with the original")
+	}
+}
+
+func TestConversationForkIncludesToolResults(t *testing.T) {
+	conv := New()
+	conv.AddMessage(llm.Message{Role: "user", Content: "List files"})
+	conv.AddMessage(llm.Message{
+		Role:      "assistant",
+		ToolCalls: []llm.ToolCall{{ID: "call_1", Name: "list_directory"}, {ID: "call_2", Name: "get_current_directory"}},
+	})
+	conv.AddMessage(llm.Message{Role: "tool", Content: "a.txt", ToolCallID: "call_1"})
+	conv.AddMessage(llm.Message{Role: "tool", Content: "/home", ToolCallID: "call_2"})
+	conv.AddMessage(llm.Message{Role: "assistant", Content: "Done"})
+
+	fork, err := conv.Fork(1)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if len(fork.Messages) != 4 {
+		t.Errorf("Expected fork to include tool results (4 messages), got %d", len(fork.Messages))
+	}
+}
+
+func TestConversationForkOutOfRange(t *testing.T) {
+	conv := New()
+	conv.AddMessage(llm.Message{Role: "user", Content: "Hi"})
+
+	if _, err := conv.Fork(1); err == nil {
+		t.Error("Expected error for out-of-range index")
+	}
+	if _, err := conv.Fork(-1); err == nil {
+		t.Error("Expected error for negative index")
+	}
+}
@@ -0,0 +1,287 @@
+package conversation
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+)
+
+// sealedMagic prefixes encrypted data so plaintext files written before
+// encryption was enabled can still be read.
+var sealedMagic = []byte("ADENC1")
+
+// boundMagic prefixes encrypted data bound to the ID of the conversation it
+// belongs to, so it can't be passed off as another conversation's.
+var boundMagic = []byte("ADENC2")
+
+// keyringService and keyringUser identify the conversation key in the OS keychain.
+const (
+	keyringService = "agent-desktop"
+	keyringUser    = "conversation-key"
+)
+
+// keyCheckPlaintext is sealed into the key file to verify passphrases.
+const keyCheckPlaintext = "agent-desktop conversation key"
+
+// ErrWrongKey is returned when encrypted data cannot be opened with the current key.
+var ErrWrongKey = errors.New("failed to decrypt: wrong passphrase or key")
+
+// ErrLocked is returned when encrypted data is read, or any data written,
+// while the store is encrypted but its key isn't available.
+var ErrLocked = errors.New("conversation store is encrypted and locked")
+
+// Cipher encrypts and decrypts stored conversation data with AES-256-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// EncryptedStore is a Store that can encrypt its data at rest.
+type EncryptedStore interface {
+	Store
+	// SetCipher enables encryption for subsequent writes and decryption of
+	// sealed data on reads. A nil cipher disables encryption.
+	SetCipher(c *Cipher)
+	// Lock refuses writes with ErrLocked until SetCipher is called, so
+	// nothing is saved in plaintext while the key is unavailable.
+	Lock()
+}
+
+// NewCipher creates a cipher from a 32-byte key.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, returning the magic header, nonce, and ciphertext.
+func (c *Cipher) Seal(plaintext []byte) ([]byte, error) {
+	return c.sealWith(sealedMagic, plaintext, nil)
+}
+
+// SealFor encrypts plaintext like Seal, binding it to the conversation id:
+// it only opens with OpenFor and the same id.
+func (c *Cipher) SealFor(plaintext []byte, id string) ([]byte, error) {
+	return c.sealWith(boundMagic, plaintext, []byte(id))
+}
+
+// sealWith encrypts plaintext authenticating aad, behind magic.
+func (c *Cipher) sealWith(magic, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(magic)+len(nonce)+len(plaintext)+c.aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, nonce...)
+	return c.aead.Seal(out, nonce, plaintext, aad), nil
+}
+
+// Open decrypts data produced by Seal.
+func (c *Cipher) Open(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, sealedMagic) {
+		return nil, errors.New("data is not encrypted")
+	}
+	return c.openWith(data[len(sealedMagic):], nil)
+}
+
+// OpenFor decrypts data produced by SealFor for the conversation id. Data
+// produced by Seal, before conversations were bound to their IDs, is
+// opened too; it is bound when next saved.
+func (c *Cipher) OpenFor(data []byte, id string) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, boundMagic):
+		return c.openWith(data[len(boundMagic):], []byte(id))
+	case bytes.HasPrefix(data, sealedMagic):
+		return c.openWith(data[len(sealedMagic):], nil)
+	}
+	return nil, errors.New("data is not encrypted")
+}
+
+// openWith decrypts a nonce and ciphertext, authenticating aad.
+func (c *Cipher) openWith(data, aad []byte) ([]byte, error) {
+	if len(data) < c.aead.NonceSize() {
+		return nil, errors.New("encrypted data is truncated")
+	}
+
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plaintext, nil
+}
+
+// isSealed reports whether data was produced by Cipher.Seal or SealFor.
+func isSealed(data []byte) bool {
+	return bytes.HasPrefix(data, sealedMagic) || bytes.HasPrefix(data, boundMagic)
+}
+
+// seal encrypts data if c is non-nil, otherwise returns it unchanged.
+func (c *Cipher) seal(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	return c.Seal(data)
+}
+
+// open decrypts sealed data, passing plaintext through unchanged.
+// Sealed data without a cipher is an error.
+func (c *Cipher) open(data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, ErrLocked
+	}
+	return c.Open(data)
+}
+
+// sealFor encrypts data bound to the conversation id if c is non-nil,
+// otherwise returns it unchanged.
+func (c *Cipher) sealFor(data []byte, id string) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	return c.SealFor(data, id)
+}
+
+// openFor decrypts data sealed for the conversation id, passing plaintext
+// through unchanged. Sealed data without a cipher is an error.
+func (c *Cipher) openFor(data []byte, id string) ([]byte, error) {
+	if !isSealed(data) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, ErrLocked
+	}
+	return c.OpenFor(data, id)
+}
+
+// keyFile stores the passphrase salt and a sealed check value used to
+// verify the passphrase before any conversation is read.
+type keyFile struct {
+	Salt  []byte `json:"salt"`
+	Check []byte `json:"check"`
+}
+
+// CipherFromPassphrase derives a cipher from a passphrase using scrypt.
+// The salt and a verification value are kept in encryption.json under
+// basePath; they are created on first use. Returns ErrWrongKey if the
+// passphrase does not match the one used previously.
+func CipherFromPassphrase(basePath, passphrase string) (*Cipher, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is required")
+	}
+
+	path := filepath.Join(basePath, "encryption.json")
+	var kf keyFile
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &kf); err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+	case os.IsNotExist(err):
+		kf.Salt = make([]byte, 16)
+		if _, err := rand.Read(kf.Salt); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), kf.Salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if kf.Check != nil {
+		check, err := c.Open(kf.Check)
+		if err != nil || string(check) != keyCheckPlaintext {
+			return nil, ErrWrongKey
+		}
+		return c, nil
+	}
+
+	// First use: record the salt and check value
+	if kf.Check, err = c.Seal([]byte(keyCheckPlaintext)); err != nil {
+		return nil, err
+	}
+	data, err = json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+	return c, nil
+}
+
+// CipherFromKeychain returns a cipher keyed from the OS keychain, generating
+// and storing a random key on first use.
+func CipherFromKeychain() (*Cipher, error) {
+	encoded, err := keyring.Get(keyringService, keyringUser)
+	if errors.Is(err, keyring.ErrNotFound) {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		encoded = base64.StdEncoding.EncodeToString(key)
+		if err := keyring.Set(keyringService, keyringUser, encoded); err != nil {
+			return nil, fmt.Errorf("failed to store key in keychain: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read key from keychain: %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key in keychain: %w", err)
+	}
+	return NewCipher(key)
+}
+
+// RewriteAll loads and re-saves every conversation in the store. After
+// enabling encryption this converts existing plaintext data to ciphertext.
+func RewriteAll(store Store) error {
+	summaries, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	for _, summary := range summaries {
+		conv, err := store.Load(summary.ID)
+		if err != nil {
+			return err
+		}
+		if err := store.Save(conv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
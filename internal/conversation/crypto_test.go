@@ -0,0 +1,218 @@
+package conversation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"agent-desktop/internal/llm"
+)
+
+func testCipher(t *testing.T, fill byte) *Cipher {
+	t.Helper()
+	c, err := NewCipher(bytes.Repeat([]byte{fill}, 32))
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+	return c
+}
+
+func TestCipherRoundTrip(t *testing.T) {
+	c := testCipher(t, 1)
+
+	sealed, err := c.Seal([]byte("secret transcript"))
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Error("Sealed data should not contain plaintext")
+	}
+
+	opened, err := c.Open(sealed)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if string(opened) != "secret transcript" {
+		t.Errorf("Expected round trip, got %q", opened)
+	}
+
+	if _, err := testCipher(t, 2).Open(sealed); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected ErrWrongKey with a different key, got %v", err)
+	}
+}
+
+func TestNewCipherRejectsShortKey(t *testing.T) {
+	if _, err := NewCipher([]byte("short")); err == nil {
+		t.Error("Expected error for short key")
+	}
+}
+
+func TestCipherFromPassphrase(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := CipherFromPassphrase(dir, "correct horse")
+	if err != nil {
+		t.Fatalf("CipherFromPassphrase failed: %v", err)
+	}
+
+	// Same passphrase yields a compatible key
+	second, err := CipherFromPassphrase(dir, "correct horse")
+	if err != nil {
+		t.Fatalf("CipherFromPassphrase failed on second use: %v", err)
+	}
+	sealed, _ := first.Seal([]byte("data"))
+	if _, err := second.Open(sealed); err != nil {
+		t.Errorf("Expected same passphrase to open data: %v", err)
+	}
+
+	if _, err := CipherFromPassphrase(dir, "wrong"); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected ErrWrongKey for wrong passphrase, got %v", err)
+	}
+	if _, err := CipherFromPassphrase(dir, ""); err == nil {
+		t.Error("Expected error for empty passphrase")
+	}
+}
+
+func TestFileStoreEncryption(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// A conversation saved before encryption was enabled
+	legacy := New()
+	legacy.AddMessage(llm.Message{Role: "user", Content: "plaintext secret"})
+	store.Save(legacy)
+
+	store.SetCipher(testCipher(t, 1))
+
+	conv := New()
+	conv.AddMessage(llm.Message{Role: "user", Content: "encrypted secret"})
+	if err := store.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	raw, _ := os.ReadFile(filepath.Join(store.basePath, "conv_"+conv.ID+".json"))
+	if bytes.Contains(raw, []byte("encrypted secret")) {
+		t.Error("Conversation file should be encrypted on disk")
+	}
+	rawIndex, _ := os.ReadFile(filepath.Join(store.basePath, "index.json"))
+	if !isSealed(rawIndex) {
+		t.Error("Index should be encrypted on disk")
+	}
+
+	// Both legacy and encrypted conversations are readable
+	if _, err := store.Load(legacy.ID); err != nil {
+		t.Errorf("Legacy plaintext conversation should still load: %v", err)
+	}
+	loaded, err := store.Load(conv.ID)
+	if err != nil || loaded.Messages[0].Content != "encrypted secret" {
+		t.Errorf("Expected encrypted conversation to load, got %v", err)
+	}
+
+	// Without the key the store is locked
+	store.SetCipher(nil)
+	if _, err := store.Load(conv.ID); err == nil {
+		t.Error("Expected error loading encrypted conversation without a key")
+	}
+}
+
+func TestRewriteAllEncryptsLegacyData(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	legacy := New()
+	legacy.AddMessage(llm.Message{Role: "user", Content: "plaintext secret"})
+	store.Save(legacy)
+
+	store.SetCipher(testCipher(t, 1))
+	if err := RewriteAll(store); err != nil {
+		t.Fatalf("RewriteAll failed: %v", err)
+	}
+
+	raw, _ := os.ReadFile(filepath.Join(store.basePath, "conv_"+legacy.ID+".json"))
+	if !isSealed(raw) {
+		t.Error("Expected legacy conversation to be encrypted after RewriteAll")
+	}
+}
+
+func TestSQLiteStoreEncryption(t *testing.T) {
+	store := setupTestSQLiteStore(t)
+	store.SetCipher(testCipher(t, 1))
+
+	conv := New()
+	conv.Title = "Secret title"
+	store.Save(conv)
+
+	var raw []byte
+	store.db.QueryRow(`SELECT summary FROM conversations WHERE id = ?`, conv.ID).Scan(&raw)
+	if bytes.Contains(raw, []byte("Secret title")) {
+		t.Error("Summary should be encrypted in the database")
+	}
+
+	summaries, err := store.List()
+	if err != nil || len(summaries) != 1 || summaries[0].Title != "Secret title" {
+		t.Errorf("Expected decrypted listing, got %+v, %v", summaries, err)
+	}
+}
+
+func TestLockedStoresRefuseWrites(t *testing.T) {
+	fileStore, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	for name, store := range map[string]EncryptedStore{"file": fileStore, "sqlite": setupTestSQLiteStore(t)} {
+		store.Lock()
+		conv := New()
+		conv.AddMessage(llm.Message{Role: "user", Content: "plaintext secret"})
+		if err := store.Save(conv); !errors.Is(err, ErrLocked) {
+			t.Errorf("%s: Save while locked error = %v, want ErrLocked", name, err)
+		}
+		if _, err := store.Load(conv.ID); err == nil {
+			t.Errorf("%s: a conversation was saved while locked", name)
+		}
+
+		store.SetCipher(testCipher(t, 1))
+		if err := store.Save(conv); err != nil {
+			t.Errorf("%s: Save after SetCipher failed: %v", name, err)
+		}
+	}
+
+	raw, _ := os.ReadFile(filepath.Join(fileStore.basePath, "index.json"))
+	if bytes.Contains(raw, []byte("plaintext secret")) || (len(raw) > 0 && !isSealed(raw)) {
+		t.Error("Expected no plaintext index to be written while locked")
+	}
+}
+
+func TestSQLiteStoreBindsRowsToIDs(t *testing.T) {
+	store := setupTestSQLiteStore(t)
+	c := testCipher(t, 1)
+	store.SetCipher(c)
+
+	first, second := New(), New()
+	first.Folder = "Taxes"
+	first.AddMessage(llm.Message{Role: "user", Content: "first secret"})
+	second.AddMessage(llm.Message{Role: "user", Content: "second secret"})
+	store.Save(first)
+	store.Save(second)
+
+	var folder string
+	store.db.QueryRow(`SELECT folder FROM conversations WHERE id = ?`, first.ID).Scan(&folder)
+	if folder != "" {
+		t.Errorf("Expected the folder column to be empty when encrypted, got %q", folder)
+	}
+
+	// A row copied over another conversation's doesn't open as that conversation
+	store.db.Exec(`UPDATE conversations SET data = (SELECT data FROM conversations WHERE id = ?) WHERE id = ?`, first.ID, second.ID)
+	if _, err := store.Load(second.ID); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Loading a swapped row error = %v, want ErrWrongKey", err)
+	}
+
+	// Rows sealed before conversations were bound to their IDs still open
+	data, _ := json.Marshal(first)
+	legacy, _ := c.Seal(data)
+	store.db.Exec(`UPDATE conversations SET data = ? WHERE id = ?`, legacy, first.ID)
+	if loaded, err := store.Load(first.ID); err != nil || loaded.Folder != "Taxes" {
+		t.Errorf("Expected a legacy row to load, got %+v, %v", loaded, err)
+	}
+}
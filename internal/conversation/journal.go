@@ -0,0 +1,223 @@
+package conversation
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
+)
+
+// SaveInterval is the least time between saves of the active conversation
+// as a run adds messages to it. Messages added in between are appended to
+// the journal, so they survive the app crashing, and saved with the next
+// save or Flush.
+const SaveInterval = 2 * time.Second
+
+// journalRecord is a message added to a conversation since it was last
+// saved.
+type journalRecord struct {
+	ConversationID string      `json:"conversation_id"`
+	Index          int         `json:"index"` // Position of the message in the conversation
+	Message        llm.Message `json:"message"`
+	SessionCWD     string      `json:"session_cwd,omitempty"`
+}
+
+// journal is an append-only file of journalRecords, emptied whenever the
+// conversation they belong to is saved.
+type journal struct {
+	file *os.File
+}
+
+// append adds a record to the end of the journal.
+func (j *journal) append(rec journalRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(append(data, '\n'))
+	return err
+}
+
+// reset empties the journal.
+func (j *journal) reset() error {
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := j.file.Seek(0, 0)
+	return err
+}
+
+// SetJournal batches the saves of the active conversation during runs,
+// keeping the messages added between saves in a journal at path. Records
+// left in the journal by a crash are first saved into their conversations.
+// Without a journal, every message is saved as it is added.
+func (m *Manager) SetJournal(path string) error {
+	if err := m.recoverJournal(path); err != nil {
+		logger.Warn("failed to recover conversation journal", "path", path, "error", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open conversation journal: %w", err)
+	}
+	j := &journal{file: file}
+	if err := j.reset(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to empty conversation journal: %w", err)
+	}
+	m.mu.Lock()
+	m.journal = j
+	m.mu.Unlock()
+	return nil
+}
+
+// recoverJournal saves the messages recorded in the journal at path into
+// their conversations, skipping those that were saved already.
+func (m *Manager) recoverJournal(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	recovered := map[string]*Conversation{}
+	var order []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+	for scanner.Scan() {
+		var rec journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			break // A record cut short by the crash ends the journal
+		}
+		conv := recovered[rec.ConversationID]
+		if conv == nil {
+			if conv, err = m.store.Load(rec.ConversationID); err != nil {
+				continue
+			}
+			recovered[rec.ConversationID] = conv
+			order = append(order, rec.ConversationID)
+		}
+		if rec.Index != len(conv.Messages) {
+			continue // Saved already, or follows a message that was lost
+		}
+		conv.AddMessage(rec.Message)
+		if rec.SessionCWD != "" {
+			conv.SessionCWD = rec.SessionCWD
+		}
+	}
+
+	var errs []error
+	for _, id := range order {
+		logger.Info("recovering unsaved messages", "conversation", id)
+		if err := m.store.Save(recovered[id]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// saveAdded saves the active conversation after a message was added to it,
+// or with a journal, records the message and saves only if the last save
+// is more than SaveInterval ago. The caller holds m.mu.
+func (m *Manager) saveAdded() error {
+	if m.journal == nil {
+		return m.save()
+	}
+	last := len(m.active.Messages) - 1
+	rec := journalRecord{ConversationID: m.active.ID, Index: last, Message: m.active.Messages[last]}
+	if rec.Message.Role == "tool" {
+		rec.SessionCWD = tools.GetSession().GetCWD()
+	}
+	if err := m.journal.append(rec); err != nil {
+		logger.Warn("failed to write conversation journal", "error", err)
+		return m.save()
+	}
+	m.unsaved = true
+	if time.Since(m.lastSave) < SaveInterval {
+		return nil
+	}
+	return m.save()
+}
+
+// save saves the active conversation, emptying the journal of the messages
+// now saved. The caller holds m.mu.
+func (m *Manager) save() error {
+	if err := m.store.Save(m.active); err != nil {
+		if errors.Is(err, ErrConflict) {
+			m.reloadAfterConflict(m.active, err)
+		}
+		return err
+	}
+	m.unsaved = false
+	m.lastSave = time.Now()
+	if m.journal != nil {
+		if err := m.journal.reset(); err != nil {
+			logger.Warn("failed to empty conversation journal", "error", err)
+		}
+	}
+	return nil
+}
+
+// reloadAfterConflict replaces conv, the active conversation or one being
+// run, with the newer version in the store after saving it failed with err,
+// an ErrConflict. The store kept the local version in a conflict copy, so
+// the journal is emptied too. The caller holds m.mu.
+func (m *Manager) reloadAfterConflict(conv *Conversation, err error) {
+	id := conv.ID
+	reloaded, loadErr := m.store.Load(id)
+	if loadErr != nil {
+		logger.Warn("failed to reload conversation after a conflict", "conversation", id, "error", loadErr)
+		return
+	}
+	logger.Warn("conversation was changed elsewhere; reloaded it", "conversation", id, "error", err)
+	if r := m.running[id]; r != nil && r.conv == conv {
+		r.conv = reloaded
+	}
+	if m.active == conv {
+		m.active = reloaded
+		m.unsaved = false
+		if m.journal != nil {
+			if err := m.journal.reset(); err != nil {
+				logger.Warn("failed to empty conversation journal", "error", err)
+			}
+		}
+	}
+	if m.conflictFunc != nil {
+		m.conflictFunc(id, err)
+	}
+}
+
+// Flush saves the active conversation if messages were added to it since
+// it was last saved.
+func (m *Manager) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.flushLocked()
+}
+
+// flushLocked is Flush for callers holding m.mu.
+func (m *Manager) flushLocked() error {
+	if m.active == nil || !m.unsaved {
+		return nil
+	}
+	return m.save()
+}
+
+// Close saves any unsaved messages and closes the journal.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.flushLocked()
+	if m.journal != nil {
+		err = errors.Join(err, m.journal.file.Close())
+		m.journal = nil
+	}
+	return err
+}
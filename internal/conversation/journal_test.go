@@ -0,0 +1,95 @@
+package conversation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/llm"
+)
+
+func TestManager_JournalBatchesSaves(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	if err := manager.SetJournal(path); err != nil {
+		t.Fatalf("SetJournal failed: %v", err)
+	}
+	defer manager.Close()
+
+	conv := manager.New()
+	manager.AddUserMessage("Clean up the downloads folder")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Listing it first"})
+	manager.AddToolMessage(llm.Message{Content: "a.zip b.zip", ToolCallID: "1"})
+
+	// The user message was saved; the rest is only in the journal
+	saved, _ := manager.GetStore().Load(conv.ID)
+	if len(saved.Messages) != 2 {
+		t.Errorf("saved %d messages, want the system prompt and the user message", len(saved.Messages))
+	}
+	if data, _ := os.ReadFile(path); strings.Count(string(data), "\n") != 2 {
+		t.Errorf("journal = %s", data)
+	}
+
+	if err := manager.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	saved, _ = manager.GetStore().Load(conv.ID)
+	if len(saved.Messages) != 4 {
+		t.Errorf("saved %d messages after Flush, want 4", len(saved.Messages))
+	}
+	if info, _ := os.Stat(path); info.Size() != 0 {
+		t.Errorf("journal holds %d bytes after Flush", info.Size())
+	}
+
+	// Get and switching conversations save first
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Done"})
+	if got, _ := manager.Get(conv.ID); len(got.Messages) != 5 {
+		t.Errorf("Get() returned %d messages, want 5", len(got.Messages))
+	}
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Anything else?"})
+	manager.New()
+	if saved, _ := manager.GetStore().Load(conv.ID); len(saved.Messages) != 6 {
+		t.Errorf("saved %d messages after switching, want 6", len(saved.Messages))
+	}
+}
+
+func TestManager_JournalRecovery(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	manager.SetJournal(path)
+
+	conv := manager.New()
+	manager.AddUserMessage("Rename the photos")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Renaming"})
+	manager.AddToolMessage(llm.Message{Content: "renamed 12 files", ToolCallID: "1"})
+
+	// The app crashes: the journal is left behind, with a torn last record
+	manager.journal.file.WriteString(`{"conversation_id":"` + conv.ID + `","ind`)
+	manager.journal.file.Close()
+	manager.journal = nil
+
+	restarted := NewManager(manager.GetStore(), nil, "Test system prompt")
+	if err := restarted.SetJournal(path); err != nil {
+		t.Fatalf("SetJournal failed: %v", err)
+	}
+	defer restarted.Close()
+	saved, err := restarted.GetStore().Load(conv.ID)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(saved.Messages) != 4 || saved.Messages[3].Content != "renamed 12 files" || saved.SessionCWD == "" {
+		t.Errorf("recovered %d messages: %+v", len(saved.Messages), saved.Messages)
+	}
+
+	// Recovering again adds nothing
+	restarted.Close()
+	again := NewManager(manager.GetStore(), nil, "Test system prompt")
+	again.SetJournal(path)
+	defer again.Close()
+	if saved, _ := again.GetStore().Load(conv.ID); len(saved.Messages) != 4 {
+		t.Errorf("second recovery left %d messages", len(saved.Messages))
+	}
+}
@@ -0,0 +1,167 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// lockFileName is the advisory lock file held by the instance that owns the store.
+const lockFileName = "store.lock"
+
+// ErrStoreLocked is returned by AcquireStoreLock when another process holds the lock.
+var ErrStoreLocked = errors.New("conversation store is in use by another instance")
+
+// ErrReadOnly is returned when writing to a store opened read-only because
+// another instance of the app owns it.
+var ErrReadOnly = errors.New("conversations are open in another Agent Desktop window and are read-only here; close the other instance to make changes")
+
+// StoreLock is an advisory lock giving one process write access to a
+// conversation store directory. The OS releases it if the process exits.
+type StoreLock struct {
+	file *os.File
+}
+
+// AcquireStoreLock takes the lock for the store in dir without blocking.
+// Returns ErrStoreLocked if another process already holds it.
+func AcquireStoreLock(dir string) (*StoreLock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, ErrStoreLocked
+	}
+
+	// Record the owner to help diagnose a stuck lock
+	file.Truncate(0)
+	file.WriteAt([]byte(strconv.Itoa(os.Getpid())+" "+time.Now().Format(time.RFC3339)+"\n"), 0)
+
+	return &StoreLock{file: file}, nil
+}
+
+// Release gives up the lock.
+func (l *StoreLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	unlockFile(l.file)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// ReadOnlyStore wraps a Store so reads pass through and writes fail with
+// ErrReadOnly. It is used when another instance owns the store.
+type ReadOnlyStore struct {
+	inner Store
+}
+
+var (
+	_ EncryptedStore = (*ReadOnlyStore)(nil)
+	_ TrashStore     = (*ReadOnlyStore)(nil)
+)
+
+// NewReadOnlyStore returns a read-only view of store. A FileStore stops
+// writing its index, so listing reconciles the index in memory instead of
+// rewriting the owner's index.json.
+func NewReadOnlyStore(store Store) *ReadOnlyStore {
+	if fs, ok := store.(*FileStore); ok {
+		fs.mu.Lock()
+		fs.readOnly = true
+		fs.mu.Unlock()
+	}
+	return &ReadOnlyStore{inner: store}
+}
+
+// Unwrap returns the underlying store.
+func (s *ReadOnlyStore) Unwrap() Store {
+	return s.inner
+}
+
+// Save always fails with ErrReadOnly.
+func (s *ReadOnlyStore) Save(conv *Conversation) error {
+	return ErrReadOnly
+}
+
+// Load retrieves a conversation from the underlying store.
+func (s *ReadOnlyStore) Load(id string) (*Conversation, error) {
+	return s.inner.Load(id)
+}
+
+// List returns summaries from the underlying store.
+func (s *ReadOnlyStore) List() ([]Summary, error) {
+	return s.inner.List()
+}
+
+// ListPage returns one page of summaries from the underlying store.
+func (s *ReadOnlyStore) ListPage(offset, limit int) (Page, error) {
+	return s.inner.ListPage(offset, limit)
+}
+
+// Search searches the underlying store.
+func (s *ReadOnlyStore) Search(query string, limit int) ([]SearchResult, error) {
+	if ss, ok := s.inner.(SearchStore); ok {
+		return ss.Search(query, limit)
+	}
+	return searchStore(s.inner, query, limit)
+}
+
+// Delete always fails with ErrReadOnly.
+func (s *ReadOnlyStore) Delete(id string) error {
+	return ErrReadOnly
+}
+
+// Restore always fails with ErrReadOnly.
+func (s *ReadOnlyStore) Restore(id string) error {
+	return ErrReadOnly
+}
+
+// ListTrash returns the underlying store's trash, if it has one.
+func (s *ReadOnlyStore) ListTrash() ([]TrashedSummary, error) {
+	if ts, ok := s.inner.(TrashStore); ok {
+		return ts.ListTrash()
+	}
+	return []TrashedSummary{}, nil
+}
+
+// EmptyTrash always fails with ErrReadOnly.
+func (s *ReadOnlyStore) EmptyTrash() error {
+	return ErrReadOnly
+}
+
+// PurgeTrash always fails with ErrReadOnly.
+func (s *ReadOnlyStore) PurgeTrash(cutoff time.Time) (int, error) {
+	return 0, ErrReadOnly
+}
+
+// SetCipher sets the cipher used to read the underlying store.
+func (s *ReadOnlyStore) SetCipher(c *Cipher) {
+	if encrypted, ok := s.inner.(EncryptedStore); ok {
+		encrypted.SetCipher(c)
+	}
+}
+
+// Lock locks the underlying store; it is never written anyway.
+func (s *ReadOnlyStore) Lock() {
+	if encrypted, ok := s.inner.(EncryptedStore); ok {
+		encrypted.Lock()
+	}
+}
+
+// Close closes the underlying store if it holds resources.
+func (s *ReadOnlyStore) Close() error {
+	if closer, ok := s.inner.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
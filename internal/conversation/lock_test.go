@@ -0,0 +1,95 @@
+package conversation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireStoreLock(t *testing.T) {
+	dir := t.TempDir()
+
+	lock, err := AcquireStoreLock(dir)
+	if err != nil {
+		t.Fatalf("AcquireStoreLock failed: %v", err)
+	}
+
+	if _, err := AcquireStoreLock(dir); !errors.Is(err, ErrStoreLocked) {
+		t.Fatalf("Expected ErrStoreLocked for a second lock, got %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	again, err := AcquireStoreLock(dir)
+	if err != nil {
+		t.Fatalf("Expected lock to be available after release: %v", err)
+	}
+	again.Release()
+}
+
+func TestReadOnlyStore(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	conv := New()
+	conv.Title = "Shared"
+	store.Save(conv)
+
+	readOnly := NewReadOnlyStore(store)
+	loaded, err := readOnly.Load(conv.ID)
+	if err != nil || loaded.Title != "Shared" {
+		t.Fatalf("Expected reads to pass through, got %v", err)
+	}
+	if summaries, _ := readOnly.List(); len(summaries) != 1 {
+		t.Errorf("Expected 1 summary, got %d", len(summaries))
+	}
+
+	if err := readOnly.Save(conv); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Save, got %v", err)
+	}
+	if err := readOnly.Delete(conv.ID); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Delete, got %v", err)
+	}
+	if _, err := store.Load(conv.ID); err != nil {
+		t.Error("Expected conversation to survive a read-only delete")
+	}
+
+	manager := NewManager(readOnly, nil, "")
+	if _, err := manager.Load(conv.ID); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := manager.AddUserMessage("Hello"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from AddUserMessage, got %v", err)
+	}
+}
+
+func TestReadOnlyStore_NeverWritesIndex(t *testing.T) {
+	primary, cleanup := setupTestStore(t)
+	defer cleanup()
+	primary.Save(New())
+	primary.Flush()
+
+	other, err := NewStore(primary.basePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readOnly := NewReadOnlyStore(other)
+	indexPath := filepath.Join(primary.basePath, "index.json")
+	before, _ := os.ReadFile(indexPath)
+
+	// A conversation the primary saved but hasn't indexed yet
+	primary.Save(New())
+	defer primary.Flush()
+	if summaries, err := readOnly.List(); err != nil || len(summaries) != 2 {
+		t.Fatalf("List() = %d summaries, %v; want both conversations", len(summaries), err)
+	}
+	cipher, _ := NewCipher(make([]byte, 32))
+	readOnly.SetCipher(cipher)
+	readOnly.Close()
+	if after, _ := os.ReadFile(indexPath); string(after) != string(before) {
+		t.Error("the read-only store rewrote index.json")
+	}
+}
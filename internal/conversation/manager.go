@@ -3,12 +3,20 @@ package conversation
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"agent-desktop/internal/llm"
+	"agent-desktop/internal/logging"
 	"agent-desktop/internal/tools"
 )
 
+// logger records storage problems the manager works around.
+var logger = logging.For("conversation")
+
 // Client interface for LLM calls (allows mocking in tests)
 type Client interface {
 	ChatCompletion(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error)
@@ -16,63 +24,207 @@ type Client interface {
 
 // Manager handles active conversation state and operations.
 type Manager struct {
-	store        *Store
+	store        Store
 	client       Client
 	active       *Conversation
 	systemPrompt string
+
+	// systemPromptFunc, if set, renders the default system prompt for each new conversation
+	systemPromptFunc func() string
+
+	// contextWindow is the LLM context size in tokens used to decide when to compact
+	contextWindow int
+
+	// llmTitles enables LLM-generated titles; when false titles are derived locally
+	llmTitles bool
+
+	// conflictFunc, if set, is told when the active conversation was
+	// reloaded because it was changed elsewhere; see SetConflictFunc
+	conflictFunc func(id string, err error)
+
+	// Batched saves of the active conversation; see SetJournal
+	journal  *journal
+	unsaved  bool      // Messages were added to active since lastSave
+	lastSave time.Time // When active was last saved
+
+	// running holds the conversations agent runs write to, by ID; see
+	// BeginRun. A running conversation that is also active is the same value
+	running map[string]*RunHandle
+
+	// session is the ID of the conversation the tools session was last
+	// restored for. While a run goes on, the session stays its conversation's
+	session string
+
+	// mu guards active, running, and the state of saves. Runs, titling, and
+	// bindings use the manager from different goroutines
+	mu sync.Mutex
 }
 
 // NewManager creates a new conversation manager.
-func NewManager(store *Store, client Client, systemPrompt string) *Manager {
+func NewManager(store Store, client Client, systemPrompt string) *Manager {
 	return &Manager{
 		store:        store,
 		client:       client,
 		systemPrompt: systemPrompt,
+		llmTitles:    true,
+	}
+}
+
+// SetSystemPromptFunc sets a function that renders the default system prompt
+// each time a conversation is created, so templates can include values such
+// as the current date. It takes precedence over the prompt given to NewManager.
+func (m *Manager) SetSystemPromptFunc(fn func() string) {
+	m.systemPromptFunc = fn
+}
+
+// SetConflictFunc sets a function called when saving the active
+// conversation fails with ErrConflict. The local version is kept in a
+// conflict copy and the conversation is reloaded from the store, so later
+// saves go on from the newer version. fn is called with the manager locked
+// and must not call back into it.
+func (m *Manager) SetConflictFunc(fn func(id string, err error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conflictFunc = fn
+}
+
+// defaultSystemPrompt returns the system prompt for conversations without a persona.
+func (m *Manager) defaultSystemPrompt() string {
+	if m.systemPromptFunc != nil {
+		return m.systemPromptFunc()
 	}
+	return m.systemPrompt
 }
 
-// New creates a new conversation, resets the tools session, and makes it active.
+// New creates a new conversation, resets the tools session, and makes it
+// active. It returns a copy of the new conversation.
 func (m *Manager) New() *Conversation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushBeforeSwitch()
+
 	// Reset tools session for new conversation
-	tools.ResetSession()
+	conv := m.newConversation()
+	m.useSessionLocked(conv)
+	m.active = conv
 
-	conv := New()
+	// Auto-save
+	if err := m.store.Save(conv); err != nil {
+		logger.Error("failed to save new conversation", "conversation", conv.ID, "error", err)
+	}
+
+	return conv.clone()
+}
+
+// Create creates and saves a new conversation without making it active,
+// so a run in the active conversation is not disturbed.
+func (m *Manager) Create() (*Conversation, error) {
+	conv := m.newConversation()
+	if err := m.store.Save(conv); err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
 
-	// Add system prompt as first message
+// newConversation returns a new conversation starting with the system prompt.
+func (m *Manager) newConversation() *Conversation {
+	conv := New()
 	conv.AddMessage(llm.Message{
 		Role:    "system",
-		Content: m.systemPrompt,
+		Content: m.defaultSystemPrompt(),
 	})
+	return conv
+}
 
-	m.active = conv
+// Get returns the saved copy of conversation id without making it active.
+func (m *Manager) Get(id string) (*Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active != nil && m.active.ID == id {
+		m.flushLocked()
+	}
+	return m.store.Load(id)
+}
 
-	// Auto-save
-	m.store.Save(conv)
+// Load retrieves a conversation by ID, resets the tools session unless a run
+// is using it, and makes it active. It returns a copy of the conversation.
+func (m *Manager) Load(id string) (*Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushBeforeSwitch()
+	var conv *Conversation
+	if r := m.running[id]; r != nil {
+		conv = r.conv
+	} else {
+		var err error
+		if conv, err = m.store.Load(id); err != nil {
+			return nil, err
+		}
+	}
 
-	return conv
+	// Reset tools session when loading a different conversation,
+	// then restore the directory the conversation was working in
+	m.useSessionLocked(conv)
+
+	m.active = conv
+	return conv.clone(), nil
 }
 
-// Load retrieves a conversation by ID, resets the tools session, and makes it active.
-func (m *Manager) Load(id string) (*Conversation, error) {
-	conv, err := m.store.Load(id)
+// Fork copies the messages of conversation id up to and including
+// fromMessageIndex into a new conversation, saves it, and makes it active.
+// The original conversation is left untouched. It returns a copy of the fork.
+func (m *Manager) Fork(id string, fromMessageIndex int) (*Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	source := m.active
+	if source == nil || source.ID != id {
+		loaded, err := m.store.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		source = loaded
+	}
+
+	fork, err := source.Fork(fromMessageIndex)
 	if err != nil {
 		return nil, err
 	}
 
-	// Reset tools session when loading a different conversation
-	tools.ResetSession()
+	if err := m.store.Save(fork); err != nil {
+		return nil, err
+	}
+	m.flushBeforeSwitch()
+
+	// The fork starts a fresh line of work in the directory the source was using
+	m.useSessionLocked(fork)
 
-	m.active = conv
-	return conv, nil
+	m.active = fork
+	return fork.clone(), nil
+}
+
+// flushBeforeSwitch saves unsaved messages of the active conversation
+// before another one becomes active. The caller holds m.mu.
+func (m *Manager) flushBeforeSwitch() {
+	if err := m.flushLocked(); err != nil {
+		logger.Error("failed to save conversation", "conversation", m.active.ID, "error", err)
+	}
 }
 
-// GetActive returns the currently active conversation, or nil if none.
+// GetActive returns a copy of the currently active conversation, or nil if
+// none. Changes to the copy don't reach the conversation.
 func (m *Manager) GetActive() *Conversation {
-	return m.active
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active == nil {
+		return nil
+	}
+	return m.active.clone()
 }
 
 // AddUserMessage adds a user message to the active conversation and auto-saves.
 func (m *Manager) AddUserMessage(content string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.active == nil {
 		return errors.New("no active conversation")
 	}
@@ -82,37 +234,89 @@ func (m *Manager) AddUserMessage(content string) error {
 		Content: content,
 	})
 
-	return m.store.Save(m.active)
+	// Continuing an archived conversation brings it back
+	m.active.Archived = false
+
+	return m.save()
 }
 
-// AddAssistantMessage adds an assistant message to the active conversation and auto-saves.
+// AddAssistantMessage adds an assistant message to the active conversation
+// and auto-saves, batching saves if a journal is set.
 func (m *Manager) AddAssistantMessage(msg llm.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.active == nil {
 		return errors.New("no active conversation")
 	}
 
 	m.active.AddMessage(msg)
-	return m.store.Save(m.active)
+	return m.saveAdded()
 }
 
-// AddToolMessage adds a tool result message to the active conversation and auto-saves.
-func (m *Manager) AddToolMessage(toolCallID, content string) error {
+// AddToolMessage adds a tool result message to the active conversation and
+// auto-saves, batching saves if a journal is set.
+func (m *Manager) AddToolMessage(msg llm.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.active == nil {
 		return errors.New("no active conversation")
 	}
 
-	m.active.AddMessage(llm.Message{
-		Role:       "tool",
-		Content:    content,
-		ToolCallID: toolCallID,
-	})
+	msg.Role = "tool"
+	m.active.AddMessage(msg)
 
-	return m.store.Save(m.active)
+	// Tools may have changed directory or run commands; remember them for
+	// when the conversation is reloaded
+	m.active.SessionCWD = tools.GetSession().GetCWD()
+	m.active.SessionHistory = tools.GetSession().GetHistory()
+
+	return m.saveAdded()
+}
+
+// TruncateAfter removes every message after index from the active
+// conversation and saves. It is used to regenerate a response from an
+// earlier point in the conversation.
+func (m *Manager) TruncateAfter(index int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active == nil {
+		return errors.New("no active conversation")
+	}
+
+	if err := m.active.TruncateAfter(index); err != nil {
+		return err
+	}
+	return m.save()
+}
+
+// EditUserMessage replaces the content of the user message at index in the
+// active conversation and drops everything after it, so the conversation can
+// be regenerated from the edited message.
+func (m *Manager) EditUserMessage(index int, content string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active == nil {
+		return errors.New("no active conversation")
+	}
+	if index < 0 || index >= len(m.active.Messages) {
+		return fmt.Errorf("message index %d out of range", index)
+	}
+	if m.active.Messages[index].Role != "user" {
+		return fmt.Errorf("message %d is not a user message", index)
+	}
+
+	m.active.Messages[index].Content = content
+	if err := m.active.TruncateAfter(index); err != nil {
+		return err
+	}
+	return m.save()
 }
 
 // GetMessages returns a copy of the current conversation messages.
 // This is safe to pass to the agent loop without risking mutation.
 func (m *Manager) GetMessages() []llm.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.active == nil {
 		return nil
 	}
@@ -125,13 +329,15 @@ func (m *Manager) GetMessages() []llm.Message {
 
 // Rename sets a custom title for the active conversation and saves.
 func (m *Manager) Rename(title string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.active == nil {
 		return errors.New("no active conversation")
 	}
 
 	m.active.Title = title
-	m.active.UpdatedAt = m.active.UpdatedAt // Keep the same timestamp for rename
-	return m.store.Save(m.active)
+	m.active.CustomTitle = true
+	return m.save()
 }
 
 // List returns summaries of all conversations.
@@ -139,86 +345,202 @@ func (m *Manager) List() ([]Summary, error) {
 	return m.store.List()
 }
 
-// Delete removes a conversation by ID.
-// If deleting the active conversation, active is set to nil.
-func (m *Manager) Delete(id string) error {
-	err := m.store.Delete(id)
-	if err != nil {
-		return err
+// TagConversation adds a tag to the conversation with the given ID.
+// The conversation does not need to be active.
+func (m *Manager) TagConversation(id, tag string) error {
+	if strings.TrimSpace(tag) == "" {
+		return errors.New("tag is required")
 	}
+	return m.update(id, func(conv *Conversation) {
+		conv.AddTag(tag)
+	})
+}
 
-	// If we deleted the active conversation, clear it
-	if m.active != nil && m.active.ID == id {
-		m.active = nil
+// UntagConversation removes a tag from the conversation with the given ID.
+func (m *Manager) UntagConversation(id, tag string) error {
+	return m.update(id, func(conv *Conversation) {
+		conv.RemoveTag(tag)
+	})
+}
+
+// SetFolder moves the conversation with the given ID into a folder.
+// An empty folder moves the conversation back to the top level.
+func (m *Manager) SetFolder(id, folder string) error {
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	return m.update(id, func(conv *Conversation) {
+		conv.Folder = folder
+	})
+}
+
+// ListByTag returns summaries of conversations carrying the given tag.
+func (m *Manager) ListByTag(tag string) ([]Summary, error) {
+	return m.filter(func(s Summary) bool {
+		return s.HasTag(tag)
+	})
+}
+
+// ListByFolder returns summaries of conversations in the given folder.
+// An empty folder returns conversations that are not in any folder.
+func (m *Manager) ListByFolder(folder string) ([]Summary, error) {
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	return m.filter(func(s Summary) bool {
+		return s.Folder == folder
+	})
+}
+
+// ListTags returns all distinct tags in use, sorted alphabetically.
+func (m *Manager) ListTags() ([]string, error) {
+	summaries, err := m.store.List()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	tags := []string{}
+	for _, s := range summaries {
+		for _, tag := range s.Tags {
+			if !containsTag(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i]) < strings.ToLower(tags[j])
+	})
+	return tags, nil
 }
 
-// GenerateTitle uses the LLM to generate a title based on the first user message.
-// If the conversation already has a non-default title, this is a no-op.
-func (m *Manager) GenerateTitle(ctx context.Context) error {
-	if m.active == nil {
-		return errors.New("no active conversation")
+// ListFolders returns all distinct non-empty folders in use, sorted alphabetically.
+func (m *Manager) ListFolders() ([]string, error) {
+	summaries, err := m.store.List()
+	if err != nil {
+		return nil, err
 	}
 
-	// Skip if no LLM client configured
-	if m.client == nil {
-		return nil
+	seen := make(map[string]bool)
+	folders := []string{}
+	for _, s := range summaries {
+		if s.Folder != "" && !seen[s.Folder] {
+			seen[s.Folder] = true
+			folders = append(folders, s.Folder)
+		}
 	}
+	sort.Strings(folders)
+	return folders, nil
+}
 
-	// Skip if title is already set (not default)
-	if m.active.Title != "" && m.active.Title != "New Conversation" {
-		return nil
+// filter returns the summaries matching keep, preserving index order.
+func (m *Manager) filter(keep func(Summary) bool) ([]Summary, error) {
+	summaries, err := m.store.List()
+	if err != nil {
+		return nil, err
 	}
 
-	// Find first user message
-	var firstUserMessage string
-	for _, msg := range m.active.Messages {
-		if msg.Role == "user" {
-			firstUserMessage = msg.Content
-			break
+	result := []Summary{}
+	for _, s := range summaries {
+		if keep(s) {
+			result = append(result, s)
 		}
 	}
+	return result, nil
+}
+
+// update applies fn to the conversation with the given ID and saves it.
+// The active conversation is modified in place; other conversations are
+// loaded from the store without changing which conversation is active.
+func (m *Manager) update(id string, fn func(*Conversation)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.updateLocked(id, fn)
+}
+
+// updateLocked is update for callers holding m.mu.
+func (m *Manager) updateLocked(id string, fn func(*Conversation)) error {
+	if conv := m.loadedLocked(id); conv != nil {
+		fn(conv)
+		return m.saveConvLocked(conv)
+	}
+
+	conv, err := m.store.Load(id)
+	if err != nil {
+		return err
+	}
+	fn(conv)
+	return m.store.Save(conv)
+}
+
+// loadedLocked returns the conversation with the given ID if it is held in
+// memory, as the active conversation or one being run, or nil. The caller
+// holds m.mu.
+func (m *Manager) loadedLocked(id string) *Conversation {
+	if m.active != nil && m.active.ID == id {
+		return m.active
+	}
+	if r := m.running[id]; r != nil {
+		return r.conv
+	}
+	return nil
+}
 
-	if firstUserMessage == "" {
-		return nil // No user message yet
+// saveAddedLocked saves conv, the active conversation or one being run,
+// after a message was added to it; see saveAdded. The caller holds m.mu.
+func (m *Manager) saveAddedLocked(conv *Conversation) error {
+	if conv == m.active {
+		return m.saveAdded()
 	}
+	return m.saveConvLocked(conv)
+}
 
-	// Call LLM to generate title
-	prompt := []llm.Message{
-		{
-			Role:    "system",
-			Content: "Generate a short title (3-6 words) for this conversation based on the user's first message. Reply with only the title, no quotes or extra text.",
-		},
-		{
-			Role:    "user",
-			Content: firstUserMessage,
-		},
+// saveConvLocked saves conv, the active conversation or one being run.
+// The caller holds m.mu.
+func (m *Manager) saveConvLocked(conv *Conversation) error {
+	if conv == m.active {
+		return m.save()
+	}
+	if err := m.store.Save(conv); err != nil {
+		if errors.Is(err, ErrConflict) {
+			m.reloadAfterConflict(conv, err)
+		}
+		return err
 	}
+	return nil
+}
 
-	resp, err := m.client.ChatCompletion(ctx, prompt, nil)
+// Delete removes a conversation by ID.
+// If deleting the active conversation, active is set to nil.
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.store.Delete(id)
 	if err != nil {
 		return err
 	}
 
-	// Clean up the title
-	title := strings.TrimSpace(resp.Content)
-	title = strings.Trim(title, "\"'") // Remove quotes if present
+	// A run still going in the conversation stops saving it
+	delete(m.running, id)
 
-	m.active.Title = title
-	return m.store.Save(m.active)
+	// If we deleted the active conversation, clear it
+	if m.active != nil && m.active.ID == id {
+		m.active = nil
+		m.unsaved = false
+		if m.journal != nil {
+			m.journal.reset()
+		}
+	}
+
+	return nil
 }
 
 // Save explicitly saves the active conversation.
 func (m *Manager) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.active == nil {
 		return errors.New("no active conversation")
 	}
-	return m.store.Save(m.active)
+	return m.save()
 }
 
 // GetStore returns the underlying store (for testing purposes).
-func (m *Manager) GetStore() *Store {
+func (m *Manager) GetStore() Store {
 	return m.store
 }
@@ -2,7 +2,9 @@ package conversation
 
 import (
 	"context"
+	"errors"
 	"os"
+	"sync"
 	"testing"
 
 	"agent-desktop/internal/llm"
@@ -80,11 +82,37 @@ func TestManagerNewConversation(t *testing.T) {
 	}
 
 	// Should be the active conversation
-	if manager.GetActive() != conv {
+	if manager.GetActive().ID != conv.ID {
 		t.Error("Expected new conversation to be active")
 	}
 }
 
+func TestManagerCreate(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	active := manager.New()
+	conv, err := manager.Create()
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if manager.GetActive().ID != active.ID {
+		t.Error("Expected Create to leave the active conversation alone")
+	}
+	saved, err := manager.Get(conv.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(saved.Messages) != 1 || saved.Messages[0].Role != "system" {
+		t.Errorf("Expected the saved conversation to start with the system prompt, got %+v", saved.Messages)
+	}
+
+	if _, err := manager.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
 func TestManagerAddUserMessage(t *testing.T) {
 	manager, cleanup := setupTestManager(t)
 	defer cleanup()
@@ -388,3 +416,222 @@ func TestManagerGenerateTitleSkipsIfAlreadySet(t *testing.T) {
 		t.Errorf("Title should remain 'Custom Title', got '%s'", manager.GetActive().Title)
 	}
 }
+
+func TestManagerTagAndFilter(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv1 := manager.New()
+	conv2 := manager.New()
+
+	// Tagging a non-active conversation should not change the active one
+	if err := manager.TagConversation(conv1.ID, "work"); err != nil {
+		t.Fatalf("Failed to tag: %v", err)
+	}
+	if err := manager.TagConversation(conv2.ID, "personal"); err != nil {
+		t.Fatalf("Failed to tag: %v", err)
+	}
+	if manager.GetActive().ID != conv2.ID {
+		t.Error("Tagging should not change the active conversation")
+	}
+
+	work, err := manager.ListByTag("work")
+	if err != nil {
+		t.Fatalf("Failed to list by tag: %v", err)
+	}
+	if len(work) != 1 || work[0].ID != conv1.ID {
+		t.Errorf("Expected only conv1 tagged 'work', got %+v", work)
+	}
+
+	tags, _ := manager.ListTags()
+	if len(tags) != 2 || tags[0] != "personal" || tags[1] != "work" {
+		t.Errorf("Expected sorted tags [personal work], got %v", tags)
+	}
+
+	if err := manager.UntagConversation(conv1.ID, "work"); err != nil {
+		t.Fatalf("Failed to untag: %v", err)
+	}
+	work, _ = manager.ListByTag("work")
+	if len(work) != 0 {
+		t.Errorf("Expected no conversations tagged 'work', got %d", len(work))
+	}
+}
+
+func TestManagerTagRequiresTag(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	if err := manager.TagConversation(conv.ID, " "); err == nil {
+		t.Error("Expected error for empty tag")
+	}
+}
+
+func TestManagerFolders(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv1 := manager.New()
+	conv2 := manager.New()
+	manager.New()
+
+	manager.SetFolder(conv1.ID, "/clients/")
+	manager.SetFolder(conv2.ID, "archive")
+
+	inClients, err := manager.ListByFolder("clients")
+	if err != nil {
+		t.Fatalf("Failed to list by folder: %v", err)
+	}
+	if len(inClients) != 1 || inClients[0].ID != conv1.ID {
+		t.Errorf("Expected conv1 in 'clients', got %+v", inClients)
+	}
+
+	topLevel, _ := manager.ListByFolder("")
+	if len(topLevel) != 1 {
+		t.Errorf("Expected 1 top-level conversation, got %d", len(topLevel))
+	}
+
+	folders, _ := manager.ListFolders()
+	if len(folders) != 2 || folders[0] != "archive" || folders[1] != "clients" {
+		t.Errorf("Expected folders [archive clients], got %v", folders)
+	}
+}
+
+func TestManagerTagNonExistent(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	if err := manager.TagConversation("nonexistent", "work"); err == nil {
+		t.Error("Expected error tagging non-existent conversation")
+	}
+}
+
+func TestManagerFork(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	original := manager.New()
+	manager.AddUserMessage("First")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Reply"})
+	manager.AddUserMessage("Second")
+
+	fork, err := manager.Fork(original.ID, 1)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+
+	if manager.GetActive().ID != fork.ID {
+		t.Error("Fork should become the active conversation")
+	}
+	if len(fork.Messages) != 2 {
+		t.Errorf("Expected 2 messages in fork, got %d", len(fork.Messages))
+	}
+
+	// Original should be preserved in the store
+	loaded, err := manager.store.Load(original.ID)
+	if err != nil {
+		t.Fatalf("Failed to load original: %v", err)
+	}
+	if len(loaded.Messages) != 4 {
+		t.Errorf("Original should keep 4 messages, got %d", len(loaded.Messages))
+	}
+
+	summaries, _ := manager.List()
+	if len(summaries) != 2 {
+		t.Errorf("Expected 2 conversations after fork, got %d", len(summaries))
+	}
+}
+
+func TestManagerTruncateAfter(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	manager.AddUserMessage("First")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Reply"})
+	manager.AddUserMessage("Second")
+
+	if err := manager.TruncateAfter(1); err != nil {
+		t.Fatalf("TruncateAfter failed: %v", err)
+	}
+
+	if len(manager.GetMessages()) != 2 {
+		t.Errorf("Expected 2 messages after truncation, got %d", len(manager.GetMessages()))
+	}
+
+	loaded, _ := manager.store.Load(conv.ID)
+	if len(loaded.Messages) != 2 {
+		t.Errorf("Expected truncation to be saved, got %d messages", len(loaded.Messages))
+	}
+
+	if err := manager.TruncateAfter(5); err == nil {
+		t.Error("Expected error for out-of-range index")
+	}
+}
+
+func TestManagerEditUserMessage(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	manager.New()
+	manager.AddUserMessage("Original question")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Answer"})
+
+	if err := manager.EditUserMessage(2, "Edited"); err == nil {
+		t.Error("Expected error editing a non-user message")
+	}
+
+	if err := manager.EditUserMessage(1, "Edited question"); err != nil {
+		t.Fatalf("EditUserMessage failed: %v", err)
+	}
+
+	messages := manager.GetMessages()
+	if len(messages) != 2 {
+		t.Fatalf("Expected messages after the edit to be dropped, got %d", len(messages))
+	}
+	if messages[1].Content != "Edited question" {
+		t.Errorf("Expected edited content, got %q", messages[1].Content)
+	}
+}
+
+func TestManagerTruncateWithoutActiveConversation(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	if err := manager.TruncateAfter(0); err == nil {
+		t.Error("Expected error without active conversation")
+	}
+}
+
+func TestManagerConcurrentUse(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	manager.New()
+	manager.AddUserMessage("Sort my photos")
+	run := beginRun(t, manager)
+
+	// A run records usage while the conversation is saved and read on other
+	// goroutines; go test -race reports any access outside the manager's lock
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			run.RecordUsage(10, 5, 0.01)
+			run.RecordToolCall(false)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			manager.Save()
+			manager.GetActive()
+		}
+	}()
+	wg.Wait()
+
+	if stats := manager.GetActive().Stats; stats.PromptTokens != 1000 || stats.ToolCalls != 100 {
+		t.Errorf("Expected all usage kept, got %+v", stats)
+	}
+}
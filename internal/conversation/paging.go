@@ -0,0 +1,93 @@
+package conversation
+
+import (
+	"agent-desktop/internal/llm"
+)
+
+// Page is one page of conversation summaries, most recent first.
+type Page struct {
+	Summaries []Summary `json:"summaries"`
+	Offset    int       `json:"offset"`
+	Total     int       `json:"total"`
+	HasMore   bool      `json:"has_more"`
+}
+
+// MessagePage is one page of a conversation's messages.
+type MessagePage struct {
+	ConversationID string        `json:"conversation_id"`
+	Messages       []llm.Message `json:"messages"`
+	Offset         int           `json:"offset"`
+	Total          int           `json:"total"`
+}
+
+// newPage builds a Page from a slice of summaries fetched at offset.
+func newPage(summaries []Summary, offset, total int) Page {
+	return Page{
+		Summaries: summaries,
+		Offset:    offset,
+		Total:     total,
+		HasMore:   offset+len(summaries) < total,
+	}
+}
+
+// pageBounds clamps offset and limit to a slice of length n and returns
+// the start and end indexes. A non-positive limit means "no limit".
+func pageBounds(n, offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > n {
+		offset = n
+	}
+	end := n
+	if limit > 0 && offset+limit < n {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// ListPage returns one page of conversation summaries, most recent first.
+func (m *Manager) ListPage(offset, limit int) (Page, error) {
+	return m.store.ListPage(offset, limit)
+}
+
+// GetMessagesPage returns a page of messages from the conversation with the
+// given ID. A negative offset counts from the end, so (-50, 50) returns the
+// last 50 messages.
+func (m *Manager) GetMessagesPage(id string, offset, limit int) (MessagePage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conv := m.active
+	if conv == nil || conv.ID != id {
+		loaded, err := m.store.Load(id)
+		if err != nil {
+			return MessagePage{}, err
+		}
+		conv = loaded
+	}
+
+	total := len(conv.Messages)
+	if offset < 0 {
+		offset += total
+	}
+	start, end := pageBounds(total, offset, limit)
+
+	messages := make([]llm.Message, end-start)
+	copy(messages, conv.Messages[start:end])
+	return MessagePage{
+		ConversationID: conv.ID,
+		Messages:       messages,
+		Offset:         start,
+		Total:          total,
+	}, nil
+}
+
+// Open loads a conversation and makes it active like Load, but returns only
+// its summary so callers can fetch messages lazily with GetMessagesPage.
+func (m *Manager) Open(id string) (Summary, error) {
+	conv, err := m.Load(id)
+	if err != nil {
+		return Summary{}, err
+	}
+	return conv.ToSummary(), nil
+}
@@ -0,0 +1,115 @@
+package conversation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/llm"
+)
+
+// saveNumbered saves n conversations titled "Conversation 0".."Conversation n-1",
+// each more recent than the last.
+func saveNumbered(t *testing.T, store Store, n int) {
+	t.Helper()
+	base := time.Now()
+	for i := 0; i < n; i++ {
+		conv := New()
+		conv.Title = fmt.Sprintf("Conversation %d", i)
+		conv.UpdatedAt = base.Add(time.Duration(i) * time.Second)
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+}
+
+func TestStoreListPage(t *testing.T) {
+	fileStore, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	stores := map[string]Store{
+		"file":   fileStore,
+		"sqlite": setupTestSQLiteStore(t),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			saveNumbered(t, store, 5)
+
+			page, err := store.ListPage(0, 2)
+			if err != nil {
+				t.Fatalf("ListPage failed: %v", err)
+			}
+			if len(page.Summaries) != 2 || page.Total != 5 || !page.HasMore {
+				t.Fatalf("Unexpected first page: %+v", page)
+			}
+			if page.Summaries[0].Title != "Conversation 4" {
+				t.Errorf("Expected most recent first, got %q", page.Summaries[0].Title)
+			}
+
+			page, _ = store.ListPage(4, 2)
+			if len(page.Summaries) != 1 || page.HasMore {
+				t.Errorf("Unexpected last page: %+v", page)
+			}
+
+			page, _ = store.ListPage(10, 2)
+			if len(page.Summaries) != 0 || page.HasMore {
+				t.Errorf("Expected empty page past the end, got %+v", page)
+			}
+
+			page, _ = store.ListPage(0, 0)
+			if len(page.Summaries) != 5 {
+				t.Errorf("Expected all summaries without a limit, got %d", len(page.Summaries))
+			}
+		})
+	}
+}
+
+func TestManagerGetMessagesPage(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	for i := 0; i < 10; i++ {
+		manager.AddUserMessage(fmt.Sprintf("Message %d", i))
+	}
+	manager.New()
+
+	// Last three messages of a non-active conversation
+	page, err := manager.GetMessagesPage(conv.ID, -3, 3)
+	if err != nil {
+		t.Fatalf("GetMessagesPage failed: %v", err)
+	}
+	if page.Total != 11 || page.Offset != 8 || len(page.Messages) != 3 {
+		t.Fatalf("Unexpected page: offset=%d total=%d len=%d", page.Offset, page.Total, len(page.Messages))
+	}
+	if page.Messages[2].Content != "Message 9" {
+		t.Errorf("Expected last message, got %q", page.Messages[2].Content)
+	}
+
+	page, _ = manager.GetMessagesPage(conv.ID, 0, 2)
+	if len(page.Messages) != 2 || page.Messages[0].Role != "system" {
+		t.Errorf("Expected first two messages, got %+v", page.Messages)
+	}
+}
+
+func TestManagerOpen(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	manager.AddUserMessage("Hello")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Hi"})
+	manager.New()
+
+	summary, err := manager.Open(conv.ID)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if summary.ID != conv.ID || summary.TurnCount != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if manager.GetActive().ID != conv.ID {
+		t.Error("Open should make the conversation active")
+	}
+}
@@ -0,0 +1,144 @@
+package conversation
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy controls how old conversations are cleaned up. Zero values
+// disable the corresponding rule. Pinned conversations are never touched.
+type RetentionPolicy struct {
+	ArchiveAfter  time.Duration // Archive conversations not updated for this long
+	DeleteAfter   time.Duration // Move conversations not updated for this long to the trash
+	MaxStoreBytes int64         // Trash the oldest conversations while the store is larger than this
+}
+
+// Enabled reports whether any retention rule is set.
+func (p RetentionPolicy) Enabled() bool {
+	return p.ArchiveAfter > 0 || p.DeleteAfter > 0 || p.MaxStoreBytes > 0
+}
+
+// RetentionReport describes what a retention run changed.
+type RetentionReport struct {
+	Archived []string `json:"archived"` // IDs of newly archived conversations
+	Deleted  []string `json:"deleted"`  // IDs of conversations moved to the trash
+}
+
+// SizedStore is a Store that can report how much space each conversation uses.
+type SizedStore interface {
+	Store
+	// Sizes returns the stored size in bytes of each conversation, keyed by ID.
+	Sizes() (map[string]int64, error)
+}
+
+var (
+	_ SizedStore = (*FileStore)(nil)
+	_ SizedStore = (*SQLiteStore)(nil)
+)
+
+// SetPinned pins or unpins the conversation with the given ID. Pinned
+// conversations are exempt from the retention policy.
+func (m *Manager) SetPinned(id string, pinned bool) error {
+	return m.update(id, func(conv *Conversation) {
+		conv.Pinned = pinned
+	})
+}
+
+// SetArchived archives or unarchives the conversation with the given ID.
+func (m *Manager) SetArchived(id string, archived bool) error {
+	return m.update(id, func(conv *Conversation) {
+		conv.Archived = archived
+	})
+}
+
+// ApplyRetention archives and deletes conversations according to policy.
+// Deleted conversations go to the trash, so they can still be restored until
+// the trash retention expires. Pinned conversations and the active
+// conversation are never touched. The manager is locked throughout, so the
+// conversations can't change or become active while the policy is applied.
+func (m *Manager) ApplyRetention(policy RetentionPolicy) (RetentionReport, error) {
+	report := RetentionReport{Archived: []string{}, Deleted: []string{}}
+	if !policy.Enabled() {
+		return report, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	summaries, err := m.store.List()
+	if err != nil {
+		return report, err
+	}
+
+	now := time.Now()
+	var kept []Summary
+	for _, s := range summaries {
+		if s.Pinned || (m.active != nil && m.active.ID == s.ID) {
+			continue
+		}
+
+		age := now.Sub(s.UpdatedAt)
+		switch {
+		case policy.DeleteAfter > 0 && age > policy.DeleteAfter:
+			if err := m.store.Delete(s.ID); err != nil {
+				return report, err
+			}
+			report.Deleted = append(report.Deleted, s.ID)
+			continue
+		case policy.ArchiveAfter > 0 && age > policy.ArchiveAfter && !s.Archived:
+			if err := m.updateLocked(s.ID, func(conv *Conversation) { conv.Archived = true }); err != nil {
+				return report, err
+			}
+			report.Archived = append(report.Archived, s.ID)
+		}
+		kept = append(kept, s)
+	}
+
+	if policy.MaxStoreBytes > 0 {
+		deleted, err := m.enforceSizeCap(kept, policy.MaxStoreBytes)
+		report.Deleted = append(report.Deleted, deleted...)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// enforceSizeCap trashes the least recently updated of the candidates until
+// the store fits within maxBytes. Returns the IDs of trashed conversations.
+// The caller holds m.mu.
+func (m *Manager) enforceSizeCap(candidates []Summary, maxBytes int64) ([]string, error) {
+	sized, ok := m.store.(SizedStore)
+	if !ok {
+		return nil, errors.New("conversation store does not report sizes")
+	}
+
+	sizes, err := sized.Sizes()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+
+	// Oldest first
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].UpdatedAt.Before(candidates[j].UpdatedAt)
+	})
+
+	deleted := []string{}
+	for _, s := range candidates {
+		if total <= maxBytes {
+			break
+		}
+		if err := m.store.Delete(s.ID); err != nil {
+			return deleted, err
+		}
+		total -= sizes[s.ID]
+		deleted = append(deleted, s.ID)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,162 @@
+package conversation
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/llm"
+)
+
+// saveAged saves a conversation last updated the given number of days ago.
+func saveAged(t *testing.T, store Store, title string, days int) *Conversation {
+	t.Helper()
+	conv := New()
+	conv.Title = title
+	conv.UpdatedAt = time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+	if err := store.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	return conv
+}
+
+func TestManagerApplyRetention(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	fresh := saveAged(t, manager.store, "Fresh", 1)
+	stale := saveAged(t, manager.store, "Stale", 40)
+	ancient := saveAged(t, manager.store, "Ancient", 400)
+	pinned := saveAged(t, manager.store, "Pinned", 400)
+	if err := manager.SetPinned(pinned.ID, true); err != nil {
+		t.Fatalf("SetPinned failed: %v", err)
+	}
+
+	report, err := manager.ApplyRetention(RetentionPolicy{
+		ArchiveAfter: 30 * 24 * time.Hour,
+		DeleteAfter:  365 * 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+
+	if len(report.Archived) != 1 || report.Archived[0] != stale.ID {
+		t.Errorf("Expected only the stale conversation to be archived, got %v", report.Archived)
+	}
+	if len(report.Deleted) != 1 || report.Deleted[0] != ancient.ID {
+		t.Errorf("Expected only the ancient conversation to be deleted, got %v", report.Deleted)
+	}
+
+	summaries, _ := manager.List()
+	archived := map[string]bool{}
+	for _, s := range summaries {
+		archived[s.ID] = s.Archived
+	}
+	if len(summaries) != 3 {
+		t.Errorf("Expected 3 remaining conversations, got %d", len(summaries))
+	}
+	if !archived[stale.ID] || archived[fresh.ID] || archived[pinned.ID] {
+		t.Errorf("Unexpected archive state: %v", archived)
+	}
+
+	// Archiving keeps the conversation's age
+	loaded, _ := manager.store.Load(stale.ID)
+	if !loaded.UpdatedAt.Equal(stale.UpdatedAt) {
+		t.Error("Expected archiving to leave UpdatedAt unchanged")
+	}
+}
+
+func TestManagerApplyRetentionSizeCap(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	var convs []*Conversation
+	for i, title := range []string{"Oldest", "Middle", "Newest"} {
+		conv := New()
+		conv.Title = title
+		conv.UpdatedAt = time.Now().Add(time.Duration(i-3) * time.Hour)
+		conv.AddMessage(llm.Message{Role: "user", Content: strings.Repeat("x", 4000)})
+		conv.UpdatedAt = time.Now().Add(time.Duration(i-3) * time.Hour)
+		if err := manager.store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		convs = append(convs, conv)
+	}
+	manager.SetPinned(convs[0].ID, true)
+
+	sizes, err := manager.store.(SizedStore).Sizes()
+	if err != nil {
+		t.Fatalf("Sizes failed: %v", err)
+	}
+	total := int64(0)
+	for _, size := range sizes {
+		total += size
+	}
+
+	// Allow room for roughly two conversations
+	report, err := manager.ApplyRetention(RetentionPolicy{MaxStoreBytes: total - total/4})
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+
+	if len(report.Deleted) != 1 || report.Deleted[0] != convs[1].ID {
+		t.Errorf("Expected the oldest unpinned conversation to be deleted, got %v", report.Deleted)
+	}
+}
+
+func TestManagerApplyRetentionSkipsActive(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	conv.UpdatedAt = time.Now().Add(-1000 * 24 * time.Hour)
+	manager.Save()
+
+	report, err := manager.ApplyRetention(RetentionPolicy{DeleteAfter: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if len(report.Deleted) != 0 {
+		t.Errorf("Expected active conversation to be kept, got %v", report.Deleted)
+	}
+}
+
+func TestAddUserMessageUnarchives(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	manager.SetArchived(conv.ID, true)
+	manager.AddUserMessage("Picking this back up")
+
+	if manager.GetActive().Archived {
+		t.Error("Expected new message to unarchive the conversation")
+	}
+}
+
+func TestFileStoreSizesConcurrently(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	saveAged(t, store, "Old", 1)
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// A fresh store reads the index on first use
+	reopened, err := NewStore(store.basePath)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sizes, err := reopened.Sizes(); err != nil || len(sizes) != 1 {
+				t.Errorf("Expected one size, got %v, %v", sizes, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,268 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"agent-desktop/internal/agent"
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
+)
+
+// maxRuns is how many runs' steps a conversation keeps; older runs are
+// dropped, leaving their messages.
+const maxRuns = 100
+
+// Run is the step stream of one agent run, kept with the conversation so
+// reopening it can replay tool calls and their output as they appeared
+// live.
+type Run struct {
+	StartedAt    time.Time    `json:"started_at"`
+	EndedAt      time.Time    `json:"ended_at,omitempty"`
+	FirstMessage int          `json:"first_message"` // Index of the first message the run added
+	Steps        []agent.Step `json:"steps"`
+	Files        []string     `json:"files,omitempty"` // Files the run changed, whose diffs are in the conversation's snapshots
+}
+
+// ErrRunning is returned by BeginRun when a run is already writing to the
+// conversation.
+var ErrRunning = errors.New("an agent run is already writing to this conversation")
+
+// errRunEnded is returned by a RunHandle once its run ended or its
+// conversation was deleted.
+var errRunEnded = errors.New("the run's conversation is no longer running")
+
+// RunHandle writes one agent run's messages, steps, and stats to the
+// conversation the run was begun in, whichever conversation is active
+// meanwhile. It is safe to use on another goroutine than the window's.
+type RunHandle struct {
+	m    *Manager
+	id   string
+	conv *Conversation // The conversation in memory; guarded by m.mu
+
+	started bool // Start recorded a Run in conv
+}
+
+// BeginRun returns the handle of a new run in the conversation with the
+// given ID. The conversation stays in memory until End, so loading it
+// while the run goes on shows the run's progress, and the tools session
+// stays the conversation's.
+func (m *Manager) BeginRun(id string) (*RunHandle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running[id] != nil {
+		return nil, ErrRunning
+	}
+	conv := m.loadedLocked(id)
+	if conv == nil {
+		loaded, err := m.store.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		conv = loaded
+	}
+	// The run works in its conversation's directory, whichever the window
+	// last used
+	if m.session != id {
+		m.useSessionLocked(conv)
+	}
+
+	r := &RunHandle{m: m, id: id, conv: conv}
+	if m.running == nil {
+		m.running = map[string]*RunHandle{}
+	}
+	m.running[id] = r
+	return r, nil
+}
+
+// ID returns the ID of the run's conversation.
+func (r *RunHandle) ID() string {
+	return r.id
+}
+
+// with calls fn with the run's conversation under the manager's lock, or
+// returns errRunEnded if the run ended.
+func (r *RunHandle) with(fn func(conv *Conversation) error) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if r.m.running[r.id] != r {
+		return errRunEnded
+	}
+	return fn(r.conv)
+}
+
+// Get returns a copy of the run's conversation.
+func (r *RunHandle) Get() (*Conversation, error) {
+	var conv *Conversation
+	err := r.with(func(c *Conversation) error {
+		conv = c.clone()
+		return nil
+	})
+	return conv, err
+}
+
+// AddUserMessage adds a user message to the run's conversation and saves
+// it.
+func (r *RunHandle) AddUserMessage(content string) error {
+	return r.with(func(conv *Conversation) error {
+		conv.AddMessage(llm.Message{Role: "user", Content: content})
+
+		// Continuing an archived conversation brings it back
+		conv.Archived = false
+		return r.m.saveConvLocked(conv)
+	})
+}
+
+// AddAssistantMessage adds an assistant message to the run's conversation
+// and saves it, batching saves if it is active and a journal is set.
+func (r *RunHandle) AddAssistantMessage(msg llm.Message) error {
+	return r.with(func(conv *Conversation) error {
+		conv.AddMessage(msg)
+		return r.m.saveAddedLocked(conv)
+	})
+}
+
+// AddToolMessage adds a tool result message to the run's conversation and
+// saves it like AddAssistantMessage.
+func (r *RunHandle) AddToolMessage(msg llm.Message) error {
+	return r.with(func(conv *Conversation) error {
+		msg.Role = "tool"
+		conv.AddMessage(msg)
+
+		// Tools may have changed directory or run commands; remember them
+		// for when the conversation is reloaded
+		conv.SessionCWD = tools.GetSession().GetCWD()
+		conv.SessionHistory = tools.GetSession().GetHistory()
+		return r.m.saveAddedLocked(conv)
+	})
+}
+
+// LLMMessages returns a copy of the run's conversation as it should be sent
+// to the LLM, with compacted turns replaced by their summary.
+func (r *RunHandle) LLMMessages() []llm.Message {
+	var messages []llm.Message
+	r.with(func(conv *Conversation) error {
+		messages = conv.LLMMessages()
+		return nil
+	})
+	return messages
+}
+
+// TakePendingAttachments returns the run's conversation's pending
+// attachments and marks them as sent. They are saved with the next message.
+func (r *RunHandle) TakePendingAttachments() []Attachment {
+	var pending []Attachment
+	r.with(func(conv *Conversation) error {
+		pending = takePendingAttachments(conv)
+		return nil
+	})
+	return pending
+}
+
+// CompactIfNeeded compacts the run's conversation when its estimated size
+// exceeds the compaction threshold of the context window.
+func (r *RunHandle) CompactIfNeeded(ctx context.Context) (bool, error) {
+	return r.m.compactIfNeeded(ctx, r.id)
+}
+
+// PrepareTitle returns the task of titling the run's conversation as
+// Manager.GenerateTitle would, or nil if it needs no title now.
+func (r *RunHandle) PrepareTitle() *TitleTask {
+	var task *TitleTask
+	r.with(func(conv *Conversation) error {
+		task = prepareTitle(conv)
+		return nil
+	})
+	return task
+}
+
+// Start begins recording the steps of the run in its conversation.
+func (r *RunHandle) Start() {
+	r.with(func(conv *Conversation) error {
+		runs := append(conv.Runs, Run{StartedAt: time.Now(), FirstMessage: len(conv.Messages), Steps: []agent.Step{}})
+		if len(runs) > maxRuns {
+			runs = runs[len(runs)-maxRuns:]
+		}
+		conv.Runs = runs
+		r.started = true
+		return nil
+	})
+}
+
+// RecordStep adds a step to the run's record. The conversation's messages
+// carried by the step aren't kept, since they are stored already. Steps are
+// saved with the next message.
+func (r *RunHandle) RecordStep(step agent.Step) {
+	r.with(func(conv *Conversation) error {
+		if !r.started || len(conv.Runs) == 0 {
+			return nil
+		}
+		step.Messages = nil
+		run := &conv.Runs[len(conv.Runs)-1]
+		run.Steps = append(run.Steps, step)
+		return nil
+	})
+}
+
+// RecordUsage adds the usage of one LLM call to the conversation's stats.
+// The stats are saved with the next message.
+func (r *RunHandle) RecordUsage(promptTokens, completionTokens int, cost float64) {
+	r.with(func(conv *Conversation) error {
+		conv.Stats.AddUsage(promptTokens, completionTokens, cost)
+		return nil
+	})
+}
+
+// RecordToolCall counts a tool call in the conversation's stats. The stats
+// are saved with the next message.
+func (r *RunHandle) RecordToolCall(failed bool) {
+	r.with(func(conv *Conversation) error {
+		conv.Stats.AddToolCall(failed)
+		return nil
+	})
+}
+
+// Finish records when the run ended, the files it changed, and its wall
+// time, and saves the conversation.
+func (r *RunHandle) Finish(files []string, wallTime time.Duration) error {
+	return r.with(func(conv *Conversation) error {
+		if r.started && len(conv.Runs) > 0 {
+			run := &conv.Runs[len(conv.Runs)-1]
+			run.EndedAt = time.Now()
+			run.Files = files
+		}
+		conv.Stats.WallTimeMs += wallTime.Milliseconds()
+		return r.m.saveConvLocked(conv)
+	})
+}
+
+// End releases the run's conversation. It stays in memory only if it is
+// the active one. Calling End again does nothing.
+func (r *RunHandle) End() {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if r.m.running[r.id] == r {
+		delete(r.m.running, r.id)
+	}
+}
+
+// GetRuns returns the recorded runs of the conversation with the given ID,
+// oldest first.
+func (m *Manager) GetRuns(id string) ([]Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conv := m.loadedLocked(id)
+	if conv == nil {
+		loaded, err := m.store.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		conv = loaded
+	}
+	if conv.Runs == nil {
+		return []Run{}, nil
+	}
+	return slices.Clone(conv.Runs), nil
+}
@@ -0,0 +1,148 @@
+package conversation
+
+import (
+	"errors"
+	"testing"
+
+	"agent-desktop/internal/agent"
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
+)
+
+func TestManager_RecordsRuns(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	manager.AddUserMessage("List files")
+	run := beginRun(t, manager)
+	run.Start()
+	run.RecordStep(agent.NewToolCallStep(1, "list_directory", map[string]interface{}{"path": "."}))
+	run.RecordStep(agent.NewToolResultStep(1, "list_directory", &tools.ToolResult{Success: true, Output: "a.txt"}))
+	run.RecordStep(agent.NewAssistantMessageStep(2, "There is one file.", []llm.Message{{Role: "assistant", Content: "There is one file."}}))
+	if err := run.Finish([]string{"/tmp/a.txt"}, 0); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	run.End()
+
+	loaded, err := manager.GetStore().Load(conv.ID)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Runs) != 1 {
+		t.Fatalf("runs = %d, want 1", len(loaded.Runs))
+	}
+	recorded := loaded.Runs[0]
+	if len(recorded.Steps) != 3 || recorded.Steps[1].ToolResult == nil || recorded.Steps[1].ToolResult.Output != "a.txt" {
+		t.Errorf("steps = %+v", recorded.Steps)
+	}
+	if recorded.Steps[2].Messages != nil {
+		t.Error("expected the messages carried by steps not to be stored again")
+	}
+	if recorded.FirstMessage != 2 || recorded.EndedAt.IsZero() || len(recorded.Files) != 1 {
+		t.Errorf("run = %+v", recorded)
+	}
+
+	// Another conversation's runs are loaded from the store
+	manager.New()
+	runs, err := manager.GetRuns(conv.ID)
+	if err != nil || len(runs) != 1 {
+		t.Errorf("GetRuns = %d runs, %v", len(runs), err)
+	}
+}
+
+// beginRun begins a run in manager's active conversation.
+func beginRun(t *testing.T, manager *Manager) *RunHandle {
+	t.Helper()
+	run, err := manager.BeginRun(manager.GetActive().ID)
+	if err != nil {
+		t.Fatalf("BeginRun failed: %v", err)
+	}
+	return run
+}
+
+func TestRunHandle_WritesToItsConversation(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	manager.AddUserMessage("List files")
+	run := beginRun(t, manager)
+	if _, err := manager.BeginRun(conv.ID); !errors.Is(err, ErrRunning) {
+		t.Errorf("second BeginRun err = %v, want ErrRunning", err)
+	}
+
+	// The user opens another conversation while the run goes on
+	other := manager.New()
+	run.Start()
+	if err := run.AddAssistantMessage(llm.Message{Role: "assistant", Content: "There is one file."}); err != nil {
+		t.Fatalf("AddAssistantMessage failed: %v", err)
+	}
+	if active := manager.GetActive(); active.ID != other.ID || len(active.Messages) != len(other.Messages) {
+		t.Errorf("active conversation = %s with %d messages, want %s untouched", active.ID, len(active.Messages), other.ID)
+	}
+
+	// Loading the running conversation shows its progress
+	loaded, err := manager.Load(conv.ID)
+	if err != nil || len(loaded.Messages) != 3 {
+		t.Fatalf("Load = %+v, %v", loaded, err)
+	}
+	manager.Load(other.ID)
+
+	if err := run.Finish(nil, 0); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	run.End()
+	if err := run.AddAssistantMessage(llm.Message{Role: "assistant"}); err == nil {
+		t.Error("expected writes after End to fail")
+	}
+
+	saved, err := manager.GetStore().Load(conv.ID)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(saved.Messages) != 3 || len(saved.Runs) != 1 || saved.Runs[0].EndedAt.IsZero() {
+		t.Errorf("saved conversation = %d messages, runs %+v", len(saved.Messages), saved.Runs)
+	}
+}
+
+func TestManager_RecordStepWithoutRun(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	run := beginRun(t, manager)
+	run.RecordStep(agent.NewThinkingStep(1, "ignored"))
+	run.Finish(nil, 0)
+	if runs, _ := manager.GetRuns(conv.ID); len(runs) != 0 {
+		t.Errorf("runs = %+v, want none", runs)
+	}
+}
+
+func TestManager_KeepsRecentRuns(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	manager.New()
+	run := beginRun(t, manager)
+	for i := 0; i < maxRuns+5; i++ {
+		run.Start()
+		run.RecordStep(agent.NewThinkingStep(i, "step"))
+	}
+	runs := manager.GetActive().Runs
+	if len(runs) != maxRuns || runs[len(runs)-1].Steps[0].StepNumber != maxRuns+4 {
+		t.Errorf("kept %d runs, want the last %d", len(runs), maxRuns)
+	}
+}
+
+func TestConversation_TruncateDropsLaterRuns(t *testing.T) {
+	conv := New()
+	conv.Messages = []llm.Message{{Role: "system"}, {Role: "user"}, {Role: "assistant"}, {Role: "user"}, {Role: "assistant"}}
+	conv.Runs = []Run{{FirstMessage: 2}, {FirstMessage: 4}}
+	if err := conv.TruncateAfter(3); err != nil {
+		t.Fatal(err)
+	}
+	if len(conv.Runs) != 1 || conv.Runs[0].FirstMessage != 2 {
+		t.Errorf("runs = %+v, want only the run whose messages remain", conv.Runs)
+	}
+}
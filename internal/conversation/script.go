@@ -0,0 +1,270 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"agent-desktop/internal/llm"
+)
+
+// Script dialects accepted by Script.
+const (
+	ScriptBash       = "bash"
+	ScriptPowerShell = "powershell"
+)
+
+// toolErrorMarker separates a tool's output from its error in the tool
+// messages the agent loop records.
+const toolErrorMarker = "\n\nError: "
+
+// Script converts the tool calls in the conversation into a script that
+// replays them: commands, folder changes, and file writes, copies, moves,
+// and deletions. The assistant's reasoning and the user's requests become
+// comments. Calls that failed, only read state, or used tools the script
+// can't reproduce are noted in comments but not replayed.
+func (c *Conversation) Script(dialect string) (string, error) {
+	var s scriptWriter
+	switch dialect {
+	case ScriptBash:
+		s = bashScript{}
+	case ScriptPowerShell:
+		s = powerShellScript{}
+	default:
+		return "", fmt.Errorf("unknown script dialect: %s", dialect)
+	}
+
+	// Which calls failed, from their results
+	failed := make(map[string]bool)
+	for _, msg := range c.Messages {
+		if msg.Role == "tool" && strings.Contains(msg.Content, toolErrorMarker) {
+			failed[msg.ToolCallID] = true
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(s.header())
+	fmt.Fprintf(&b, "# Replays the agent's actions from %q (%s).\n", c.Title, c.CreatedAt.Format("2006-01-02 15:04"))
+	b.WriteString("# Review it before running: it changes files exactly as the agent did.\n")
+	if c.Settings.WorkingDir != "" {
+		b.WriteString("\n" + s.changeDirectory(c.Settings.WorkingDir))
+	} else {
+		b.WriteString("# Run it from the folder the agent started in.\n")
+	}
+
+	for _, msg := range c.Messages {
+		switch msg.Role {
+		case "user":
+			fmt.Fprintf(&b, "\n%s\n", comment("User: "+msg.Content))
+		case "assistant":
+			if msg.Content != "" && len(msg.ToolCalls) > 0 {
+				fmt.Fprintf(&b, "\n%s\n", comment(msg.Content))
+			}
+			for _, tc := range msg.ToolCalls {
+				b.WriteString("\n")
+				b.WriteString(scriptCall(s, tc, failed[tc.ID]))
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// scriptCall renders one tool call.
+func scriptCall(s scriptWriter, tc llm.ToolCall, failed bool) string {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(tc.Arguments), &args); err != nil {
+		return comment(fmt.Sprintf("%s: unreadable arguments, skipped", tc.Name)) + "\n"
+	}
+	str := func(key string) string {
+		v, _ := args[key].(string)
+		return v
+	}
+	if failed {
+		return comment(fmt.Sprintf("%s failed when the agent ran it, skipped: %s", tc.Name, tc.Arguments)) + "\n"
+	}
+
+	switch tc.Name {
+	case "run_command":
+		return s.command(str("command"), str("working_dir"))
+	case "change_directory":
+		return s.changeDirectory(str("path"))
+	case "write_file":
+		appendMode, _ := args["append"].(bool)
+		return s.writeFile(str("path"), str("content"), appendMode)
+	case "delete_file":
+		return s.deleteFile(str("path"))
+	case "copy_file":
+		return s.copyFile(str("source"), str("destination"))
+	case "move_file":
+		return s.moveFile(str("source"), str("destination"))
+	case "copy_files", "move_files":
+		return bulkScript(s, tc, args)
+	case "read_file", "list_directory", "get_current_directory":
+		return comment(fmt.Sprintf("%s %s (read only)", tc.Name, str("path"))) + "\n"
+	case "task_complete":
+		return comment("Done: "+str("summary")) + "\n"
+	default:
+		return comment(fmt.Sprintf("%s can't be replayed by a script: %s", tc.Name, tc.Arguments)) + "\n"
+	}
+}
+
+// bulkScript renders a bulk copy or move as one copy or move per file. The
+// files its glob patterns matched aren't recorded, so those are left as a
+// comment.
+func bulkScript(s scriptWriter, tc llm.ToolCall, args map[string]interface{}) string {
+	var b strings.Builder
+	files, _ := args["files"].([]interface{})
+	for _, f := range files {
+		pair, _ := f.(map[string]interface{})
+		source, _ := pair["source"].(string)
+		destination, _ := pair["destination"].(string)
+		if tc.Name == "move_files" {
+			b.WriteString(s.moveFile(source, destination))
+		} else {
+			b.WriteString(s.copyFile(source, destination))
+		}
+	}
+	if sources, ok := args["sources"].([]interface{}); ok && len(sources) > 0 {
+		destination, _ := args["destination"].(string)
+		b.WriteString(comment(fmt.Sprintf("%s of %v into %s can't be replayed by a script: the files matched aren't recorded", tc.Name, sources, destination)) + "\n")
+	}
+	return b.String()
+}
+
+// comment turns text into script comment lines; both dialects use "#".
+func comment(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("# "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// scriptWriter renders tool calls in one shell's syntax.
+type scriptWriter interface {
+	header() string
+	command(command, workingDir string) string
+	changeDirectory(path string) string
+	writeFile(path, content string, appendMode bool) string
+	deleteFile(path string) string
+	copyFile(source, destination string) string
+	moveFile(source, destination string) string
+}
+
+// bashScript writes POSIX shell for bash.
+type bashScript struct{}
+
+func (bashScript) header() string {
+	return "#!/usr/bin/env bash\nset -euo pipefail\n\n"
+}
+
+func (bashScript) command(command, workingDir string) string {
+	if workingDir != "" {
+		return fmt.Sprintf("(cd %s && %s)\n", bashPath(workingDir), command)
+	}
+	return command + "\n"
+}
+
+func (bashScript) changeDirectory(path string) string {
+	return "cd " + bashPath(path) + "\n"
+}
+
+func (bashScript) writeFile(path, content string, appendMode bool) string {
+	redirect := ">"
+	if appendMode {
+		redirect = ">>"
+	}
+	p := bashPath(path)
+	return fmt.Sprintf("mkdir -p \"$(dirname %s)\"\nprintf '%%s' %s %s %s\n", p, bashQuote(content), redirect, p)
+}
+
+func (bashScript) deleteFile(path string) string {
+	return "rm -f " + bashPath(path) + "\n"
+}
+
+func (bashScript) copyFile(source, destination string) string {
+	d := bashPath(destination)
+	return fmt.Sprintf("mkdir -p \"$(dirname %s)\"\ncp %s %s\n", d, bashPath(source), d)
+}
+
+func (bashScript) moveFile(source, destination string) string {
+	d := bashPath(destination)
+	return fmt.Sprintf("mkdir -p \"$(dirname %s)\"\nmv %s %s\n", d, bashPath(source), d)
+}
+
+// bashQuote single-quotes s for bash.
+func bashQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// bashPath quotes a path, keeping a leading ~ expandable as the tools do.
+func bashPath(path string) string {
+	if path == "~" {
+		return `"$HOME"`
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return `"$HOME"/` + bashQuote(rest)
+	}
+	return bashQuote(path)
+}
+
+// powerShellScript writes Windows PowerShell. Commands the agent ran went
+// through cmd.exe, so they are run the same way.
+type powerShellScript struct{}
+
+func (powerShellScript) header() string {
+	return "$ErrorActionPreference = 'Stop'\n\n"
+}
+
+func (powerShellScript) command(command, workingDir string) string {
+	run := fmt.Sprintf("cmd /c %s\nif ($LASTEXITCODE -ne 0) { exit $LASTEXITCODE }\n", psQuote(command))
+	if workingDir != "" {
+		return fmt.Sprintf("Push-Location -LiteralPath %s\n%sPop-Location\n", psPath(workingDir), run)
+	}
+	return run
+}
+
+func (powerShellScript) changeDirectory(path string) string {
+	return "Set-Location -LiteralPath " + psPath(path) + "\n"
+}
+
+func (powerShellScript) writeFile(path, content string, appendMode bool) string {
+	verb := "Set-Content"
+	if appendMode {
+		verb = "Add-Content"
+	}
+	p := psPath(path)
+	return fmt.Sprintf("New-Item -ItemType Directory -Force -Path (Split-Path -Parent %s) | Out-Null\n%s -LiteralPath %s -Value %s -NoNewline\n", p, verb, p, psQuote(content))
+}
+
+func (powerShellScript) deleteFile(path string) string {
+	return "Remove-Item -LiteralPath " + psPath(path) + " -Force -ErrorAction SilentlyContinue\n"
+}
+
+func (powerShellScript) copyFile(source, destination string) string {
+	d := psPath(destination)
+	return fmt.Sprintf("New-Item -ItemType Directory -Force -Path (Split-Path -Parent %s) | Out-Null\nCopy-Item -LiteralPath %s -Destination %s -Force\n", d, psPath(source), d)
+}
+
+func (powerShellScript) moveFile(source, destination string) string {
+	d := psPath(destination)
+	return fmt.Sprintf("New-Item -ItemType Directory -Force -Path (Split-Path -Parent %s) | Out-Null\nMove-Item -LiteralPath %s -Destination %s -Force\n", d, psPath(source), d)
+}
+
+// psQuote single-quotes s for PowerShell.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// psPath quotes a path, keeping a leading ~ relative to the home folder.
+func psPath(path string) string {
+	if path == "~" {
+		return "$HOME"
+	}
+	for _, prefix := range []string{"~/", `~\`} {
+		if rest, ok := strings.CutPrefix(path, prefix); ok {
+			return "(Join-Path $HOME " + psQuote(rest) + ")"
+		}
+	}
+	return psQuote(path)
+}
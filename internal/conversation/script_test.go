@@ -0,0 +1,126 @@
+package conversation
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/llm"
+)
+
+// scriptConversation is a run that creates a project folder, writes two
+// files, moves one, backs one up, and has one failing command.
+func scriptConversation(dir string) *Conversation {
+	conv := New()
+	conv.Title = "Set up project"
+	conv.AddMessage(llm.Message{Role: "user", Content: "Create a project"})
+	conv.AddMessage(llm.Message{Role: "assistant", Content: "I'll make the folder first.", ToolCalls: []llm.ToolCall{
+		{ID: "1", Name: "run_command", Arguments: `{"command": "mkdir -p project"}`},
+		{ID: "2", Name: "change_directory", Arguments: `{"path": "project"}`},
+	}})
+	conv.AddMessage(llm.Message{Role: "tool", ToolCallID: "1", Content: ""})
+	conv.AddMessage(llm.Message{Role: "tool", ToolCallID: "2", Content: "Changed directory to " + filepath.Join(dir, "project")})
+	conv.AddMessage(llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{
+		{ID: "3", Name: "write_file", Arguments: `{"path": "notes.txt", "content": "it's \"quoted\"\nline two"}`},
+		{ID: "4", Name: "write_file", Arguments: `{"path": "draft.txt", "content": "draft"}`},
+		{ID: "5", Name: "move_file", Arguments: `{"source": "draft.txt", "destination": "docs/final.txt"}`},
+		{ID: "10", Name: "copy_files", Arguments: `{"files": [{"source": "notes.txt", "destination": "backup/notes.txt"}], "sources": ["*.log"], "destination": "logs"}`},
+		{ID: "6", Name: "run_command", Arguments: `{"command": "false"}`},
+		{ID: "7", Name: "read_file", Arguments: `{"path": "notes.txt"}`},
+		{ID: "8", Name: "github__create_issue", Arguments: `{"title": "x"}`},
+	}})
+	conv.AddMessage(llm.Message{Role: "tool", ToolCallID: "6", Content: "\n\nError: Command failed with exit code 1"})
+	conv.AddMessage(llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{
+		{ID: "9", Name: "task_complete", Arguments: `{"summary": "Project created"}`},
+	}})
+	return conv
+}
+
+func TestConversation_Script_Bash(t *testing.T) {
+	dir := t.TempDir()
+	script, err := scriptConversation(dir).Script(ScriptBash)
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"#!/usr/bin/env bash\n",
+		"# User: Create a project\n",
+		"# I'll make the folder first.\n",
+		"mkdir -p project\n",
+		"cd 'project'\n",
+		"# run_command failed when the agent ran it, skipped",
+		"# read_file notes.txt (read only)\n",
+		"# github__create_issue can't be replayed by a script",
+		"cp 'notes.txt' 'backup/notes.txt'\n",
+		"# copy_files of [*.log] into logs can't be replayed by a script",
+		"# Done: Project created\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script is missing %q:\n%s", want, script)
+		}
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available to run the script")
+	}
+	cmd := exec.Command(bash, "-c", script)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "project", "notes.txt")); string(data) != "it's \"quoted\"\nline two" {
+		t.Errorf("notes.txt = %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "project", "docs", "final.txt")); string(data) != "draft" {
+		t.Errorf("docs/final.txt = %q, want the moved draft", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "project", "backup", "notes.txt")); err != nil {
+		t.Errorf("backup/notes.txt wasn't copied: %v", err)
+	}
+}
+
+func TestConversation_Script_PowerShell(t *testing.T) {
+	script, err := scriptConversation(t.TempDir()).Script(ScriptPowerShell)
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"$ErrorActionPreference = 'Stop'\n",
+		"cmd /c 'mkdir -p project'\n",
+		"Set-Location -LiteralPath 'project'\n",
+		"Set-Content -LiteralPath 'notes.txt' -Value 'it''s \"quoted\"\nline two' -NoNewline\n",
+		"Move-Item -LiteralPath 'draft.txt' -Destination 'docs/final.txt' -Force\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script is missing %q:\n%s", want, script)
+		}
+	}
+
+	if _, err := New().Script("fish"); err == nil {
+		t.Error("expected an error for an unknown dialect")
+	}
+}
+
+func TestScriptPaths(t *testing.T) {
+	if got := bashPath("~/My Files/a.txt"); got != `"$HOME"/'My Files/a.txt'` {
+		t.Errorf("bashPath = %s", got)
+	}
+	if got := psPath(`~\Documents`); got != "(Join-Path $HOME 'Documents')" {
+		t.Errorf("psPath = %s", got)
+	}
+}
+
+func TestConversation_Script_WorkingDir(t *testing.T) {
+	conv := New()
+	conv.Settings.WorkingDir = "/srv/app"
+	script, _ := conv.Script(ScriptBash)
+	if !strings.Contains(script, "\ncd '/srv/app'\n") {
+		t.Errorf("script doesn't start in the conversation's folder:\n%s", script)
+	}
+}
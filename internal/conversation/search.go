@@ -0,0 +1,116 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// searchSnippetRadius is how many characters of context are kept on each
+// side of a search match.
+const searchSnippetRadius = 60
+
+// SearchResult is a conversation whose title or messages contain the text
+// searched for.
+type SearchResult struct {
+	Summary
+	MessageIndex int    `json:"message_index"` // First matching message, or -1 if only the title matched
+	Snippet      string `json:"snippet"`       // Text around the first match
+}
+
+// SearchStore is a Store that can search conversations itself, without the
+// caller loading each one.
+type SearchStore interface {
+	Store
+	// Search returns up to limit conversations containing query, most
+	// recently updated first. A non-positive limit returns every match.
+	Search(query string, limit int) ([]SearchResult, error)
+}
+
+var (
+	_ SearchStore = (*SQLiteStore)(nil)
+	_ SearchStore = (*ReadOnlyStore)(nil)
+)
+
+// Search returns up to limit conversations whose title or messages contain
+// query, ignoring case, most recently updated first. Unsaved messages of
+// the active conversation are saved first so they are found too.
+func (m *Manager) Search(query string, limit int) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("search query is required")
+	}
+	m.Flush()
+
+	if ss, ok := m.store.(SearchStore); ok {
+		return ss.Search(query, limit)
+	}
+	return searchStore(m.store, query, limit)
+}
+
+// searchStore searches a store that can't search itself by loading each
+// conversation in turn.
+func searchStore(store Store, query string, limit int) ([]SearchResult, error) {
+	summaries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []SearchResult{}
+	for _, s := range summaries {
+		if limit > 0 && len(results) == limit {
+			break
+		}
+		conv, err := store.Load(s.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load conversation %s: %w", s.ID, err)
+		}
+		if result, ok := matchConversation(conv, query); ok {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// matchConversation reports whether conv's title or the text of its user
+// and assistant messages contain query, ignoring case.
+func matchConversation(conv *Conversation, query string) (SearchResult, bool) {
+	needle := strings.ToLower(query)
+	for i, msg := range conv.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			continue
+		}
+		if at := strings.Index(strings.ToLower(msg.Content), needle); at >= 0 {
+			return SearchResult{Summary: conv.ToSummary(), MessageIndex: i, Snippet: snippet(msg.Content, at, len(needle))}, true
+		}
+	}
+	if strings.Contains(strings.ToLower(conv.Title), needle) {
+		return SearchResult{Summary: conv.ToSummary(), MessageIndex: -1, Snippet: conv.Title}, true
+	}
+	return SearchResult{}, false
+}
+
+// snippet returns the text around the match of length n at byte offset at
+// in text, on one line, with ellipses where it was cut.
+func snippet(text string, at, n int) string {
+	// Lowercasing can change byte lengths; keep the offsets within text
+	at, end := min(at, len(text)), min(at+n, len(text))
+	start := max(at-searchSnippetRadius, 0)
+	stop := min(end+searchSnippetRadius, len(text))
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for stop < len(text) && !utf8.RuneStart(text[stop]) {
+		stop++
+	}
+
+	s := strings.Join(strings.Fields(text[start:stop]), " ")
+	if start > 0 {
+		s = "..." + s
+	}
+	if stop < len(text) {
+		s += "..."
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package conversation
+
+import (
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/llm"
+)
+
+func TestManagerSearch(t *testing.T) {
+	fileStore, cleanup := setupTestStore(t)
+	defer cleanup()
+	encrypted := setupTestSQLiteStore(t)
+	encrypted.SetCipher(testCipher(t, 1))
+
+	stores := map[string]Store{
+		"file":             fileStore,
+		"sqlite":           setupTestSQLiteStore(t),
+		"encrypted sqlite": encrypted,
+	}
+	for name, store := range stores {
+		manager := NewManager(store, &MockClient{}, "You search the Quarterly reports.")
+
+		taxes := manager.New()
+		manager.AddUserMessage("Where did I put the 2023 tax return?")
+		manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "It is in Documents/Taxes/Return_2023.pdf"})
+		manager.Rename("Finding taxes")
+		photos := manager.New()
+		manager.AddUserMessage("Sort my holiday photos by date")
+
+		results, err := manager.Search("RETURN_2023", 0)
+		if err != nil {
+			t.Fatalf("%s: Search failed: %v", name, err)
+		}
+		if len(results) != 1 || results[0].ID != taxes.ID || results[0].MessageIndex != 2 || !strings.Contains(results[0].Snippet, "Return_2023.pdf") {
+			t.Errorf("%s: Expected the tax conversation's assistant message, got %+v", name, results)
+		}
+
+		// Titles match too; system prompts don't
+		if results, _ := manager.Search("finding", 0); len(results) != 1 || results[0].MessageIndex != -1 {
+			t.Errorf("%s: Expected a title match, got %+v", name, results)
+		}
+		if results, _ := manager.Search("quarterly", 0); len(results) != 0 {
+			t.Errorf("%s: Expected the system prompt not to match, got %+v", name, results)
+		}
+
+		// Most recent first, up to the limit
+		manager.Load(taxes.ID)
+		manager.AddUserMessage("Thanks")
+		results, _ = manager.Search("a", 1)
+		if len(results) != 1 || results[0].ID != taxes.ID {
+			t.Errorf("%s: Expected only the most recent match, got %+v", name, results)
+		}
+		if results, _ := manager.Search("date", 0); len(results) != 1 || results[0].ID != photos.ID {
+			t.Errorf("%s: Expected the photos conversation, got %+v", name, results)
+		}
+
+		if _, err := manager.Search("  ", 0); err == nil {
+			t.Errorf("%s: Expected an error for an empty query", name)
+		}
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	tests := map[string]string{
+		"report":    "%report%",
+		"100%_done": `%100\%\_done%`,
+		`a\b`:       "%",
+		`say "hi"`:  "%",
+		"café":      "%",
+	}
+	for query, want := range tests {
+		if got := likePattern(query); got != want {
+			t.Errorf("likePattern(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("word ", 40) + "needle\n" + strings.Repeat("más ", 40)
+	at := strings.Index(text, "needle")
+	got := snippet(text, at, len("needle"))
+	if !strings.HasPrefix(got, "...") || !strings.HasSuffix(got, "...") || !strings.Contains(got, "word needle más") {
+		t.Errorf("Unexpected snippet %q", got)
+	}
+}
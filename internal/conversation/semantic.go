@@ -0,0 +1,279 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"agent-desktop/internal/index"
+	"agent-desktop/internal/llm"
+)
+
+const (
+	maxExchangeText = 2000 // Bytes of an exchange that are embedded
+	embedBatch      = 64   // Exchanges per embeddings request
+	previewText     = 300  // Bytes of the question and answer shown in a match
+)
+
+// ExchangeMatch is an exchange, a user message and the agent's work on it,
+// that matched a semantic search.
+type ExchangeMatch struct {
+	ConversationID string    `json:"conversation_id"`
+	Title          string    `json:"title"`
+	UpdatedAt      time.Time `json:"updated_at"`    // When the conversation last changed
+	MessageIndex   int       `json:"message_index"` // The user message
+	Question       string    `json:"question"`
+	Answer         string    `json:"answer,omitempty"`   // The agent's last reply
+	Commands       []string  `json:"commands,omitempty"` // Shell commands the agent ran
+	Score          float64   `json:"score"`              // Cosine similarity to the query
+}
+
+// SemanticIndex keeps the embeddings of past exchanges in a file so each is
+// embedded once. A nil SemanticIndex keeps them only for one search.
+type SemanticIndex struct {
+	mu    sync.Mutex
+	path  string
+	state semanticState
+}
+
+type semanticState struct {
+	Model         string                    `json:"model"`
+	Conversations map[string]*semanticEntry `json:"conversations"`
+}
+
+// semanticEntry holds the embedded exchanges of a conversation as it was at
+// UpdatedAt.
+type semanticEntry struct {
+	UpdatedAt time.Time          `json:"updated_at"`
+	Exchanges []semanticExchange `json:"exchanges"`
+}
+
+type semanticExchange struct {
+	MessageIndex int       `json:"message_index"`
+	Vector       []float32 `json:"vector"`
+}
+
+// OpenSemanticIndex loads the embeddings saved at path. The index is usable
+// even when the file can't be read, in which case it starts empty and the
+// error says why.
+func OpenSemanticIndex(path string) (*SemanticIndex, error) {
+	x := &SemanticIndex{path: path, state: semanticState{Conversations: map[string]*semanticEntry{}}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return x, nil
+	}
+	if err != nil {
+		return x, err
+	}
+	var s semanticState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return x, fmt.Errorf("failed to read conversation embeddings: %w", err)
+	}
+	if s.Conversations != nil {
+		x.state = s
+	}
+	return x, nil
+}
+
+// save writes the embeddings (caller must hold lock).
+func (x *SemanticIndex) save() error {
+	if x.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(x.state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(x.path, data, 0644)
+}
+
+// exchange is a user message and the replies and tool calls that follow it.
+type exchange struct {
+	index    int
+	question string
+	answer   string
+	commands []string
+}
+
+// text is what is embedded for the exchange.
+func (e exchange) text() string {
+	var b strings.Builder
+	b.WriteString("User: " + e.question)
+	for _, c := range e.commands {
+		b.WriteString("\nCommand: " + c)
+	}
+	if e.answer != "" {
+		b.WriteString("\nAssistant: " + e.answer)
+	}
+	return truncate(b.String(), maxExchangeText)
+}
+
+// exchanges splits messages into exchanges, one per user message.
+func exchanges(messages []llm.Message) []exchange {
+	var list []exchange
+	for i, msg := range messages {
+		switch {
+		case msg.Role == "user":
+			list = append(list, exchange{index: i, question: msg.Content})
+		case len(list) == 0:
+		case msg.Role == "assistant":
+			e := &list[len(list)-1]
+			if strings.TrimSpace(msg.Content) != "" {
+				e.answer = msg.Content
+			}
+			for _, call := range msg.ToolCalls {
+				var args struct {
+					Command string `json:"command"`
+				}
+				if json.Unmarshal([]byte(call.Arguments), &args) == nil && args.Command != "" {
+					e.commands = append(e.commands, args.Command)
+				}
+			}
+		}
+	}
+	return list
+}
+
+// SemanticSearch returns up to limit past exchanges, in any conversation,
+// most similar in meaning to query, best first. Exchanges are embedded the
+// first time they are searched and again when their conversation changes,
+// and the vectors are kept in x.
+func (m *Manager) SemanticSearch(ctx context.Context, x *SemanticIndex, embedder index.Embedder, query string, limit int) ([]ExchangeMatch, error) {
+	if embedder == nil {
+		return nil, errors.New("no embeddings provider configured")
+	}
+	if x == nil {
+		x = &SemanticIndex{state: semanticState{Conversations: map[string]*semanticEntry{}}}
+	}
+	summaries, err := m.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	changed := false
+	if model := embedder.EmbeddingModel(); x.state.Model != model {
+		// Vectors from different models can't be compared
+		x.state = semanticState{Model: model, Conversations: map[string]*semanticEntry{}}
+		changed = true
+	}
+
+	live := map[string]Summary{}
+	for _, s := range summaries {
+		live[s.ID] = s
+		if entry := x.state.Conversations[s.ID]; entry != nil && entry.UpdatedAt.Equal(s.UpdatedAt) {
+			continue
+		}
+		conv, err := m.store.Load(s.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load conversation %s: %w", s.ID, err)
+		}
+		list := exchanges(conv.Messages)
+		entry := &semanticEntry{UpdatedAt: s.UpdatedAt}
+		for start := 0; start < len(list); start += embedBatch {
+			batch := list[start:min(start+embedBatch, len(list))]
+			texts := make([]string, len(batch))
+			for i, e := range batch {
+				texts[i] = e.text()
+			}
+			vectors, err := embedder.Embeddings(ctx, texts)
+			if err != nil {
+				x.save() // Keep the conversations embedded so far
+				return nil, err
+			}
+			for i, v := range vectors {
+				entry.Exchanges = append(entry.Exchanges, semanticExchange{MessageIndex: batch[i].index, Vector: v})
+			}
+		}
+		x.state.Conversations[s.ID] = entry
+		changed = true
+	}
+	for id := range x.state.Conversations {
+		if _, ok := live[id]; !ok {
+			delete(x.state.Conversations, id)
+			changed = true
+		}
+	}
+	if changed {
+		if err := x.save(); err != nil {
+			logger.Warn("failed to save conversation embeddings", "error", err)
+		}
+	}
+
+	vectors, err := embedder.Embeddings(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	type scored struct {
+		id    string
+		index int
+		score float64
+	}
+	var hits []scored
+	for id, entry := range x.state.Conversations {
+		for _, e := range entry.Exchanges {
+			hits = append(hits, scored{id, e.MessageIndex, index.Cosine(vectors[0], e.Vector)})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return live[hits[i].id].UpdatedAt.After(live[hits[j].id].UpdatedAt)
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	matches := []ExchangeMatch{}
+	loaded := map[string]*Conversation{}
+	for _, hit := range hits {
+		conv := loaded[hit.id]
+		if conv == nil {
+			if conv, err = m.store.Load(hit.id); err != nil {
+				continue
+			}
+			loaded[hit.id] = conv
+		}
+		for _, e := range exchanges(conv.Messages) {
+			if e.index == hit.index {
+				matches = append(matches, ExchangeMatch{
+					ConversationID: conv.ID,
+					Title:          conv.Title,
+					UpdatedAt:      conv.UpdatedAt,
+					MessageIndex:   e.index,
+					Question:       truncate(e.question, previewText),
+					Answer:         truncate(e.answer, previewText),
+					Commands:       e.commands,
+					Score:          hit.score,
+				})
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+// truncate shortens text to at most n bytes, on a rune boundary.
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	cut := n - len("…")
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
@@ -0,0 +1,95 @@
+package conversation
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/llm"
+)
+
+// wordEmbedder embeds text as counts of a few words and counts the texts
+// it embeds.
+type wordEmbedder struct {
+	model string
+	texts int
+}
+
+func (e *wordEmbedder) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	e.texts += len(texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		text = strings.ToLower(text)
+		vectors[i] = []float32{
+			float32(strings.Count(text, "printer")),
+			float32(strings.Count(text, "weather")),
+			float32(strings.Count(text, "invoice")),
+			0.1,
+		}
+	}
+	return vectors, nil
+}
+
+func (e *wordEmbedder) EmbeddingModel() string { return e.model }
+
+func TestManagerSemanticSearch(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	printer := manager.New()
+	manager.AddUserMessage("The printer is stuck again")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{
+		{ID: "call_1", Name: "run_command", Arguments: `{"command": "cancel -a && cupsenable office"}`},
+	}})
+	manager.AddToolMessage(llm.Message{Role: "tool", Content: "", ToolCallID: "call_1"})
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Cleared the queue and re-enabled the printer."})
+	manager.AddUserMessage("Thanks! What's the weather?")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Sunny."})
+
+	other := manager.New()
+	manager.AddUserMessage("Draft an invoice for March")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Here is the invoice."})
+
+	embedder := &wordEmbedder{model: "small"}
+	path := filepath.Join(t.TempDir(), "embeddings.json")
+	x, _ := OpenSemanticIndex(path)
+
+	matches, err := manager.SemanticSearch(context.Background(), x, embedder, "what fixed the printer?", 2)
+	if err != nil {
+		t.Fatalf("SemanticSearch failed: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("matches = %+v", matches)
+	}
+	m := matches[0]
+	if m.ConversationID != printer.ID || m.MessageIndex != 1 || m.Question != "The printer is stuck again" ||
+		m.Answer != "Cleared the queue and re-enabled the printer." || len(m.Commands) != 1 || m.Commands[0] != "cancel -a && cupsenable office" {
+		t.Errorf("best match = %+v", m)
+	}
+	if embedder.texts != 4 { // Three exchanges and the query
+		t.Errorf("embedded %d texts, want 4", embedder.texts)
+	}
+
+	// Embeddings are kept, so only the query is embedded again, even
+	// after a restart
+	x, _ = OpenSemanticIndex(path)
+	embedder.texts = 0
+	matches, _ = manager.SemanticSearch(context.Background(), x, embedder, "invoice", 1)
+	if embedder.texts != 1 || len(matches) != 1 || matches[0].ConversationID != other.ID {
+		t.Errorf("search with saved embeddings embedded %d texts and found %+v", embedder.texts, matches)
+	}
+
+	// A changed conversation is embedded again and a deleted one dropped
+	manager.AddUserMessage("Add the weather to the invoice")
+	manager.Delete(printer.ID)
+	embedder.texts = 0
+	matches, _ = manager.SemanticSearch(context.Background(), x, embedder, "weather", 0)
+	if embedder.texts != 3 || len(matches) != 2 {
+		t.Errorf("search after changes embedded %d texts and found %+v", embedder.texts, matches)
+	}
+
+	if _, err := manager.SemanticSearch(context.Background(), nil, nil, "printer", 1); err == nil {
+		t.Error("expected an error without an embedder")
+	}
+}
@@ -0,0 +1,138 @@
+package conversation
+
+import (
+	"fmt"
+
+	"agent-desktop/internal/tools"
+)
+
+// Approval policies for tool calls made during a conversation.
+const (
+	ApprovalPolicyAuto        = "auto"        // Run every tool call without asking
+	ApprovalPolicyDestructive = "destructive" // Ask before commands and deleting, moving, or overwriting files
+	ApprovalPolicyAlways      = "always"      // Ask before every tool call
+)
+
+// Settings holds per-conversation overrides. Empty fields fall back to the
+// application configuration.
+type Settings struct {
+	Profile        string `json:"profile,omitempty"` // Provider profile from the config
+	Model          string `json:"model,omitempty"`
+	SystemPrompt   string `json:"system_prompt,omitempty"` // Persona replacing the default system prompt
+	WorkingDir     string `json:"working_dir,omitempty"`   // Starting directory for the shell session
+	ApprovalPolicy string `json:"approval_policy,omitempty"`
+	MaxSteps       int    `json:"max_steps,omitempty"`
+}
+
+// Validate checks that the settings contain only supported values.
+func (s Settings) Validate() error {
+	switch s.ApprovalPolicy {
+	case "", ApprovalPolicyAuto, ApprovalPolicyDestructive, ApprovalPolicyAlways:
+	default:
+		return fmt.Errorf("unknown approval policy: %s", s.ApprovalPolicy)
+	}
+	if s.MaxSteps < 0 {
+		return fmt.Errorf("max_steps must not be negative")
+	}
+	return nil
+}
+
+// NeedsApproval reports whether the approval policy asks before a tool
+// call. destructive is whether the call can lose data; see
+// tools.IsDestructive. An empty policy runs every call.
+func NeedsApproval(policy string, destructive bool) bool {
+	switch policy {
+	case ApprovalPolicyAlways:
+		return true
+	case ApprovalPolicyDestructive:
+		return destructive
+	}
+	return false
+}
+
+// GetSettings returns the settings of the conversation with the given ID.
+func (m *Manager) GetSettings(id string) (Settings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active != nil && m.active.ID == id {
+		return m.active.Settings, nil
+	}
+
+	conv, err := m.store.Load(id)
+	if err != nil {
+		return Settings{}, err
+	}
+	return conv.Settings, nil
+}
+
+// UpdateSettings replaces the settings of the conversation with the given ID.
+// The system prompt is rewritten to match, and if the conversation is active
+// the new working directory is applied to the shell session immediately.
+func (m *Manager) UpdateSettings(id string, settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.updateLocked(id, func(conv *Conversation) {
+		conv.Settings = settings
+		m.applySystemPrompt(conv)
+		if settings.WorkingDir != "" {
+			conv.SessionCWD = ""
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if conv := m.loadedLocked(id); conv != nil && m.session == id {
+		m.restoreSession(conv)
+	}
+	return nil
+}
+
+// applySystemPrompt sets the conversation's system message to its persona,
+// or to the manager's default prompt when no persona is set.
+func (m *Manager) applySystemPrompt(conv *Conversation) {
+	prompt := conv.Settings.SystemPrompt
+	if prompt == "" {
+		prompt = m.defaultSystemPrompt()
+	}
+	if len(conv.Messages) > 0 && conv.Messages[0].Role == "system" {
+		conv.Messages[0].Content = prompt
+	}
+}
+
+// useSessionLocked resets the tools session for conv, with the directory it
+// was working in and its command history, unless a run is using the
+// session. The caller holds m.mu.
+func (m *Manager) useSessionLocked(conv *Conversation) {
+	if len(m.running) > 0 {
+		return
+	}
+	tools.ResetSession()
+	m.restoreSession(conv)
+	tools.GetSession().SetHistory(conv.SessionHistory)
+	m.session = conv.ID
+}
+
+// SessionConversation returns the ID of the conversation the tools session
+// belongs to: the one last opened, or the one being run.
+func (m *Manager) SessionConversation() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.session
+}
+
+// restoreSession points the shell session at the directory the conversation
+// was last working in, falling back to its configured working directory.
+// Directories that no longer exist are ignored.
+func (m *Manager) restoreSession(conv *Conversation) {
+	session := tools.GetSession()
+	for _, dir := range []string{conv.SessionCWD, conv.Settings.WorkingDir} {
+		if dir != "" && session.SetCWD(tools.ExpandPath(dir, session.GetCWD())) == nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package conversation
+
+import (
+	"testing"
+
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
+)
+
+func TestSettingsValidate(t *testing.T) {
+	valid := Settings{ApprovalPolicy: ApprovalPolicyDestructive, MaxSteps: 5}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid settings, got %v", err)
+	}
+
+	if err := (Settings{ApprovalPolicy: "sometimes"}).Validate(); err == nil {
+		t.Error("Expected error for unknown approval policy")
+	}
+	if err := (Settings{MaxSteps: -1}).Validate(); err == nil {
+		t.Error("Expected error for negative max steps")
+	}
+}
+
+func TestNeedsApproval(t *testing.T) {
+	tests := []struct {
+		policy      string
+		destructive bool
+		want        bool
+	}{
+		{"", true, false},
+		{ApprovalPolicyAuto, true, false},
+		{ApprovalPolicyDestructive, false, false},
+		{ApprovalPolicyDestructive, true, true},
+		{ApprovalPolicyAlways, false, true},
+	}
+	for _, tt := range tests {
+		if got := NeedsApproval(tt.policy, tt.destructive); got != tt.want {
+			t.Errorf("NeedsApproval(%q, %v) = %v, want %v", tt.policy, tt.destructive, got, tt.want)
+		}
+	}
+}
+
+func TestManagerUpdateSettingsPersona(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+
+	err := manager.UpdateSettings(conv.ID, Settings{Model: "gpt-4o-mini", SystemPrompt: "You are a pirate."})
+	if err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	if content := manager.GetActive().Messages[0].Content; content != "You are a pirate." {
+		t.Errorf("Expected persona system prompt, got %q", content)
+	}
+
+	settings, _ := manager.GetSettings(conv.ID)
+	if settings.Model != "gpt-4o-mini" {
+		t.Errorf("Expected model override, got %q", settings.Model)
+	}
+
+	// Clearing the persona restores the default prompt
+	manager.UpdateSettings(conv.ID, Settings{})
+	if content := manager.GetActive().Messages[0].Content; content != "You are a helpful assistant." {
+		t.Errorf("Expected default system prompt, got %q", content)
+	}
+}
+
+func TestManagerLoadRestoresWorkingDirectory(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+	defer tools.ResetSession()
+
+	workDir := t.TempDir()
+	conv := manager.New()
+	if err := manager.UpdateSettings(conv.ID, Settings{WorkingDir: workDir}); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+	if tools.GetSession().GetCWD() != workDir {
+		t.Errorf("Expected settings to apply to active session, got %q", tools.GetSession().GetCWD())
+	}
+
+	// Switching away resets the session; loading restores it
+	manager.New()
+	if _, err := manager.Load(conv.ID); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if tools.GetSession().GetCWD() != workDir {
+		t.Errorf("Expected CWD %q after load, got %q", workDir, tools.GetSession().GetCWD())
+	}
+}
+
+func TestManagerLoadRestoresSessionCWD(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+	defer tools.ResetSession()
+
+	lastDir := t.TempDir()
+	conv := manager.New()
+	manager.AddUserMessage("cd somewhere")
+	tools.GetSession().SetCWD(lastDir)
+	manager.AddToolMessage(llm.Message{ToolCallID: "call_1", Content: "Changed directory"})
+
+	manager.New()
+	manager.Load(conv.ID)
+
+	if tools.GetSession().GetCWD() != lastDir {
+		t.Errorf("Expected session CWD %q to be restored, got %q", lastDir, tools.GetSession().GetCWD())
+	}
+}
+
+func TestManagerLoadRestoresSessionHistory(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+	defer tools.ResetSession()
+
+	conv := manager.New()
+	manager.AddUserMessage("build it")
+	tools.GetSession().RecordCommand("make", 2)
+	manager.AddToolMessage(llm.Message{ToolCallID: "call_1", Content: "make failed"})
+
+	other := manager.New()
+	tools.ResetSession()
+	tools.GetSession().RecordCommand("ls", 0)
+	manager.AddToolMessage(llm.Message{ToolCallID: "call_2", Content: "files"})
+
+	// Reopening swaps in the conversation's own history, as after a restart
+	manager.Load(conv.ID)
+	history := tools.GetSession().GetHistory()
+	if len(history) != 1 || history[0].Command != "make" || history[0].ExitCode != 2 {
+		t.Errorf("Expected the saved history to be restored, got %+v", history)
+	}
+	manager.Load(other.ID)
+	if history := tools.GetSession().GetHistory(); len(history) != 1 || history[0].Command != "ls" {
+		t.Errorf("Expected the other conversation's history, got %+v", history)
+	}
+}
+
+func TestManagerSystemPromptFunc(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	calls := 0
+	manager.SetSystemPromptFunc(func() string {
+		calls++
+		return "Rendered prompt"
+	})
+
+	conv := manager.New()
+	if conv.Messages[0].Content != "Rendered prompt" {
+		t.Errorf("Expected rendered system prompt, got %q", conv.Messages[0].Content)
+	}
+
+	// Clearing a persona falls back to the rendered prompt
+	manager.UpdateSettings(conv.ID, Settings{SystemPrompt: "Pirate persona"})
+	manager.UpdateSettings(conv.ID, Settings{})
+	if content := manager.GetActive().Messages[0].Content; content != "Rendered prompt" || calls != 2 {
+		t.Errorf("Expected persona reset to re-render the prompt, got %q after %d calls", content, calls)
+	}
+}
+
+func TestBeginRunRestoresItsSession(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+	defer tools.ResetSession()
+
+	// A task queued in a conversation the window doesn't show
+	workDir := t.TempDir()
+	conv, err := manager.Create()
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	settings := conv.Settings
+	settings.WorkingDir = workDir
+	if err := manager.UpdateSettings(conv.ID, settings); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+	manager.New()
+
+	run, err := manager.BeginRun(conv.ID)
+	if err != nil {
+		t.Fatalf("BeginRun failed: %v", err)
+	}
+	if cwd := tools.GetSession().GetCWD(); cwd != workDir {
+		t.Errorf("Expected the run to work in %q, got %q", workDir, cwd)
+	}
+
+	// Opening another conversation leaves the run's session alone
+	manager.New()
+	if cwd := tools.GetSession().GetCWD(); cwd != workDir {
+		t.Errorf("Expected the session to stay in %q during the run, got %q", workDir, cwd)
+	}
+	run.End()
+}
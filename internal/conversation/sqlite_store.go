@@ -0,0 +1,463 @@
+package conversation
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// SQLiteStore persists conversations in a single SQLite database.
+// Unlike FileStore, saving a conversation only touches its own row, so
+// listing and updating hundreds of conversations stays cheap.
+type SQLiteStore struct {
+	db     *sql.DB
+	mu     sync.RWMutex
+	cipher *Cipher
+	locked bool // Encrypted without a key; refuse writes until SetCipher
+}
+
+var _ EncryptedStore = (*SQLiteStore)(nil)
+
+// errNoRow is returned by moveRow when there was no row to move.
+var errNoRow = errors.New("no such conversation")
+
+// sqliteSchema creates the conversations and trash tables. The summary column
+// holds the JSON-encoded Summary so new summary fields don't require a
+// migration; the updated_at and folder columns exist for ordering and filtering.
+// With encryption, summary and data are sealed and bound to the row's id.
+// updated_at stays in plaintext for ordering, so the database shows when
+// each conversation last changed; folder is left empty, since the sealed
+// summary holds it.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS conversations (
+	id         TEXT PRIMARY KEY,
+	updated_at INTEGER NOT NULL,
+	folder     TEXT NOT NULL DEFAULT '',
+	summary    TEXT NOT NULL,
+	data       BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_conversations_updated_at ON conversations(updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_conversations_folder ON conversations(folder);
+CREATE TABLE IF NOT EXISTS trash (
+	id         TEXT PRIMARY KEY,
+	deleted_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	folder     TEXT NOT NULL DEFAULT '',
+	summary    TEXT NOT NULL,
+	data       BLOB NOT NULL
+);
+`
+
+// NewSQLiteStore opens (or creates) a SQLite conversation store at the given
+// database path. The database is opened in WAL mode so readers don't block
+// the writer.
+func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	dsn := "file:" + dbPath + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Save persists a conversation, inserting or replacing its row.
+func (s *SQLiteStore) Save(conv *Conversation) error {
+	return s.saveWith(s.db.Exec, conv)
+}
+
+// saveWith is Save running its statement with exec, which may belong to a
+// transaction.
+func (s *SQLiteStore) saveWith(exec func(query string, args ...any) (sql.Result, error), conv *Conversation) error {
+	data, err := json.Marshal(conv)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	summary, err := json.Marshal(conv.ToSummary())
+	if err != nil {
+		return fmt.Errorf("failed to marshal summary: %w", err)
+	}
+
+	c, err := s.writeCipher()
+	if err != nil {
+		return err
+	}
+	if data, err = c.sealFor(data, conv.ID); err != nil {
+		return fmt.Errorf("failed to encrypt conversation: %w", err)
+	}
+	if summary, err = c.sealFor(summary, conv.ID); err != nil {
+		return fmt.Errorf("failed to encrypt summary: %w", err)
+	}
+	folder := conv.Folder
+	if c != nil {
+		folder = ""
+	}
+
+	_, err = exec(
+		`INSERT INTO conversations (id, updated_at, folder, summary, data)
+		 VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET
+			updated_at = excluded.updated_at,
+			folder     = excluded.folder,
+			summary    = excluded.summary,
+			data       = excluded.data`,
+		conv.ID, conv.UpdatedAt.UnixNano(), folder, summary, data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+
+	return nil
+}
+
+// Load retrieves a conversation by ID.
+func (s *SQLiteStore) Load(id string) (*Conversation, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM conversations WHERE id = ?`, id).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+
+	if data, err = s.getCipher().openFor(data, id); err != nil {
+		return nil, err
+	}
+
+	var conv Conversation
+	if err := json.Unmarshal(data, &conv); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversation: %w", err)
+	}
+
+	return &conv, nil
+}
+
+// List returns summaries of all conversations, sorted by most recent first.
+func (s *SQLiteStore) List() ([]Summary, error) {
+	rows, err := s.db.Query(`SELECT id, summary FROM conversations ORDER BY updated_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanSummaries(rows)
+}
+
+// scanSummaries decodes rows of id and summary, decrypting them if needed.
+func (s *SQLiteStore) scanSummaries(rows *sql.Rows) ([]Summary, error) {
+	c := s.getCipher()
+	summaries := []Summary{}
+	for rows.Next() {
+		var id string
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, fmt.Errorf("failed to read summary: %w", err)
+		}
+		raw, err := c.openFor(raw, id)
+		if err != nil {
+			return nil, err
+		}
+
+		var summary Summary
+		if err := json.Unmarshal(raw, &summary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal summary: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// ListPage returns one page of summaries using LIMIT/OFFSET, so only the
+// requested rows are read and decoded.
+func (s *SQLiteStore) ListPage(offset, limit int) (Page, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM conversations`).Scan(&total); err != nil {
+		return Page{}, fmt.Errorf("failed to count conversations: %w", err)
+	}
+
+	rows, err := s.db.Query(`SELECT id, summary FROM conversations ORDER BY updated_at DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	summaries, err := s.scanSummaries(rows)
+	if err != nil {
+		return Page{}, err
+	}
+	if offset > total {
+		offset = total
+	}
+	return newPage(summaries, offset, total), nil
+}
+
+// Search returns up to limit conversations whose title or messages contain
+// query, ignoring case, most recently updated first. Without encryption,
+// SQLite narrows the rows down before they are decoded; encrypted rows are
+// all decrypted and checked.
+func (s *SQLiteStore) Search(query string, limit int) ([]SearchResult, error) {
+	// Encrypted rows can only be matched after decrypting them
+	c := s.getCipher()
+	pattern := "%"
+	if c == nil {
+		pattern = likePattern(query)
+	}
+	rows, err := s.db.Query(
+		`SELECT id, data FROM conversations
+		 WHERE ? = '%' OR CAST(data AS TEXT) LIKE ? ESCAPE '\'
+		 ORDER BY updated_at DESC`,
+		pattern, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search conversations: %w", err)
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() && (limit <= 0 || len(results) < limit) {
+		var id string
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("failed to read conversation: %w", err)
+		}
+		if data, err = c.openFor(data, id); err != nil {
+			return nil, err
+		}
+		var conv Conversation
+		if err := json.Unmarshal(data, &conv); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal conversation: %w", err)
+		}
+		if result, ok := matchConversation(&conv, query); ok {
+			results = append(results, result)
+		}
+	}
+	return results, rows.Err()
+}
+
+// likePattern returns a LIKE pattern matching JSON-encoded data that may
+// contain query. SQLite's LIKE ignores case only for ASCII, and JSON escapes
+// some characters, so other queries match every row and are checked after
+// decoding.
+func likePattern(query string) string {
+	for _, r := range query {
+		if r < ' ' || r > '~' || strings.ContainsRune(`"\<>&`, r) {
+			return "%"
+		}
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query)
+	return "%" + escaped + "%"
+}
+
+// Delete moves a conversation into the trash table. Deleting a missing
+// conversation is not an error.
+func (s *SQLiteStore) Delete(id string) error {
+	err := s.moveRow(id,
+		`INSERT OR REPLACE INTO trash (id, deleted_at, updated_at, folder, summary, data)
+		 SELECT id, ?, updated_at, folder, summary, data FROM conversations WHERE id = ?`,
+		`DELETE FROM conversations WHERE id = ?`,
+		time.Now().UnixNano())
+	if err != nil && !errors.Is(err, errNoRow) {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
+
+// Restore moves a conversation out of the trash table.
+func (s *SQLiteStore) Restore(id string) error {
+	err := s.moveRow(id,
+		`INSERT OR REPLACE INTO conversations (id, updated_at, folder, summary, data)
+		 SELECT id, updated_at, folder, summary, data FROM trash WHERE id = ?`,
+		`DELETE FROM trash WHERE id = ?`)
+	if errors.Is(err, errNoRow) {
+		return fmt.Errorf("conversation not in trash: %s", id)
+	}
+	return err
+}
+
+// ListTrash returns the conversations in the trash table.
+func (s *SQLiteStore) ListTrash() ([]TrashedSummary, error) {
+	rows, err := s.db.Query(`SELECT id, summary, deleted_at FROM trash ORDER BY deleted_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	defer rows.Close()
+
+	c := s.getCipher()
+	trashed := []TrashedSummary{}
+	for rows.Next() {
+		var id string
+		var raw []byte
+		var deletedAt int64
+		if err := rows.Scan(&id, &raw, &deletedAt); err != nil {
+			return nil, fmt.Errorf("failed to read summary: %w", err)
+		}
+		raw, err := c.openFor(raw, id)
+		if err != nil {
+			return nil, err
+		}
+
+		var summary Summary
+		if err := json.Unmarshal(raw, &summary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal summary: %w", err)
+		}
+		trashed = append(trashed, TrashedSummary{Summary: summary, DeletedAt: time.Unix(0, deletedAt)})
+	}
+
+	return trashed, rows.Err()
+}
+
+// EmptyTrash permanently deletes every row in the trash table.
+func (s *SQLiteStore) EmptyTrash() error {
+	if _, err := s.db.Exec(`DELETE FROM trash`); err != nil {
+		return fmt.Errorf("failed to empty trash: %w", err)
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes conversations trashed before cutoff.
+func (s *SQLiteStore) PurgeTrash(cutoff time.Time) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM trash WHERE deleted_at < ?`, cutoff.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// moveRow copies a conversation row with copySQL and removes the original
+// with deleteSQL in one transaction. It fails if no row was copied.
+func (s *SQLiteStore) moveRow(id, copySQL, deleteSQL string, args ...any) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(copySQL, append(args, id)...)
+	if err != nil {
+		return fmt.Errorf("failed to move conversation: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return errNoRow
+	}
+	if _, err := tx.Exec(deleteSQL, id); err != nil {
+		return fmt.Errorf("failed to move conversation: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Sizes returns the stored size of each conversation's data.
+func (s *SQLiteStore) Sizes() (map[string]int64, error) {
+	rows, err := s.db.Query(`SELECT id, length(data) FROM conversations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation sizes: %w", err)
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var id string
+		var size int64
+		if err := rows.Scan(&id, &size); err != nil {
+			return nil, fmt.Errorf("failed to read conversation size: %w", err)
+		}
+		sizes[id] = size
+	}
+	return sizes, rows.Err()
+}
+
+// SetCipher enables encryption of stored conversations and summaries.
+// Existing plaintext rows remain readable and are encrypted when next saved.
+func (s *SQLiteStore) SetCipher(c *Cipher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cipher, s.locked = c, false
+}
+
+// Lock refuses saves until SetCipher is called.
+func (s *SQLiteStore) Lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locked = true
+}
+
+// getCipher returns the current cipher, which may be nil.
+func (s *SQLiteStore) getCipher() *Cipher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cipher
+}
+
+// writeCipher returns the cipher to save with, which may be nil, or
+// ErrLocked if the store is locked.
+func (s *SQLiteStore) writeCipher() (*Cipher, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.locked {
+		return nil, ErrLocked
+	}
+	return s.cipher, nil
+}
+
+// Close releases the underlying database handle.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// ImportFrom copies every conversation from another store into this one.
+// It is used to migrate an existing JSON store to SQLite. The conversations
+// are copied in one transaction, so an import that fails leaves nothing
+// behind and can be tried again.
+func (s *SQLiteStore) ImportFrom(src Store) (int, error) {
+	summaries, err := src.List()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, summary := range summaries {
+		conv, err := src.Load(summary.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load conversation %s: %w", summary.ID, err)
+		}
+		if err := s.saveWith(tx.Exec, conv); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return len(summaries), nil
+}
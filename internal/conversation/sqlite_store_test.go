@@ -0,0 +1,169 @@
+package conversation
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/llm"
+)
+
+func setupTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "conversations.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteStoreWALMode(t *testing.T) {
+	store := setupTestSQLiteStore(t)
+
+	var mode string
+	if err := store.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatalf("Failed to query journal mode: %v", err)
+	}
+	if mode != "wal" {
+		t.Errorf("Expected WAL journal mode, got %q", mode)
+	}
+}
+
+func TestSQLiteStoreSaveAndLoad(t *testing.T) {
+	store := setupTestSQLiteStore(t)
+
+	conv := New()
+	conv.Title = "SQLite Conversation"
+	conv.AddTag("db")
+	conv.Folder = "storage"
+	conv.AddMessage(llm.Message{Role: "user", Content: "Hello"})
+	conv.AddMessage(llm.Message{
+		Role:      "assistant",
+		ToolCalls: []llm.ToolCall{{ID: "call_1", Name: "list_directory", Arguments: "{}"}},
+	})
+
+	if err := store.Save(conv); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	loaded, err := store.Load(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if loaded.Title != conv.Title || loaded.Folder != "storage" || !loaded.HasTag("db") {
+		t.Errorf("Loaded conversation does not match saved one: %+v", loaded)
+	}
+	if len(loaded.Messages) != 2 || len(loaded.Messages[1].ToolCalls) != 1 {
+		t.Errorf("Expected messages and tool calls to round-trip, got %+v", loaded.Messages)
+	}
+}
+
+func TestSQLiteStoreListAndUpdate(t *testing.T) {
+	store := setupTestSQLiteStore(t)
+
+	conv1 := New()
+	conv1.Title = "First"
+	store.Save(conv1)
+
+	time.Sleep(10 * time.Millisecond)
+
+	conv2 := New()
+	conv2.Title = "Second"
+	store.Save(conv2)
+
+	summaries, err := store.List()
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	if len(summaries) != 2 || summaries[0].Title != "Second" {
+		t.Fatalf("Expected most recent first, got %+v", summaries)
+	}
+
+	// Updating the older conversation moves it to the top without duplicating it
+	time.Sleep(10 * time.Millisecond)
+	conv1.AddMessage(llm.Message{Role: "user", Content: "Bump"})
+	store.Save(conv1)
+
+	summaries, _ = store.List()
+	if len(summaries) != 2 || summaries[0].ID != conv1.ID || summaries[0].TurnCount != 1 {
+		t.Errorf("Expected updated conversation first with 1 turn, got %+v", summaries)
+	}
+}
+
+func TestSQLiteStoreDelete(t *testing.T) {
+	store := setupTestSQLiteStore(t)
+
+	conv := New()
+	store.Save(conv)
+
+	if err := store.Delete(conv.ID); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, err := store.Load(conv.ID); err == nil {
+		t.Error("Expected error loading deleted conversation")
+	}
+	if err := store.Delete(conv.ID); err != nil {
+		t.Errorf("Deleting a missing conversation should not fail: %v", err)
+	}
+}
+
+func TestSQLiteStoreImportFrom(t *testing.T) {
+	fileStore, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		fileStore.Save(New())
+	}
+
+	store := setupTestSQLiteStore(t)
+	imported, err := store.ImportFrom(fileStore)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if imported != 3 {
+		t.Errorf("Expected 3 imported conversations, got %d", imported)
+	}
+
+	summaries, _ := store.List()
+	if len(summaries) != 3 {
+		t.Errorf("Expected 3 conversations after import, got %d", len(summaries))
+	}
+}
+
+func TestSQLiteStoreImportFromIsAllOrNothing(t *testing.T) {
+	fileStore, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// One conversation can't be read without its key
+	fileStore.SetCipher(testCipher(t, 1))
+	fileStore.Save(New())
+	fileStore.SetCipher(nil)
+	fileStore.Save(New())
+
+	store := setupTestSQLiteStore(t)
+	if _, err := store.ImportFrom(fileStore); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected the import to fail with ErrLocked, got %v", err)
+	}
+	if summaries, _ := store.List(); len(summaries) != 0 {
+		t.Errorf("Expected a failed import to leave nothing behind, got %d conversations", len(summaries))
+	}
+}
+
+func TestManagerWithSQLiteStore(t *testing.T) {
+	store := setupTestSQLiteStore(t)
+	manager := NewManager(store, &MockClient{}, "System")
+
+	conv := manager.New()
+	manager.AddUserMessage("Hello")
+
+	loaded, err := manager.GetStore().Load(conv.ID)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if len(loaded.Messages) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(loaded.Messages))
+	}
+}
@@ -0,0 +1,45 @@
+package conversation
+
+// Stats holds cumulative usage totals for a conversation.
+type Stats struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	EstimatedCost    float64 `json:"estimated_cost"` // US dollars
+	LLMCalls         int     `json:"llm_calls"`
+	ToolCalls        int     `json:"tool_calls"`
+	ToolErrors       int     `json:"tool_errors"`
+	WallTimeMs       int64   `json:"wall_time_ms"` // Time spent running the agent
+}
+
+// AddUsage adds the token usage and cost of one LLM call.
+func (s *Stats) AddUsage(promptTokens, completionTokens int, cost float64) {
+	s.PromptTokens += promptTokens
+	s.CompletionTokens += completionTokens
+	s.TotalTokens += promptTokens + completionTokens
+	s.EstimatedCost += cost
+	s.LLMCalls++
+}
+
+// AddToolCall counts one tool call, noting whether it failed.
+func (s *Stats) AddToolCall(failed bool) {
+	s.ToolCalls++
+	if failed {
+		s.ToolErrors++
+	}
+}
+
+// GetStats returns the stats of the conversation with the given ID.
+func (m *Manager) GetStats(id string) (Stats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if conv := m.loadedLocked(id); conv != nil {
+		return conv.Stats, nil
+	}
+
+	conv, err := m.store.Load(id)
+	if err != nil {
+		return Stats{}, err
+	}
+	return conv.Stats, nil
+}
@@ -0,0 +1,60 @@
+package conversation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsAccumulate(t *testing.T) {
+	var stats Stats
+	stats.AddUsage(100, 20, 0.01)
+	stats.AddUsage(50, 10, 0.005)
+	stats.AddToolCall(false)
+	stats.AddToolCall(true)
+
+	if stats.PromptTokens != 150 || stats.CompletionTokens != 30 || stats.TotalTokens != 180 {
+		t.Errorf("Unexpected token totals: %+v", stats)
+	}
+	if stats.LLMCalls != 2 {
+		t.Errorf("Expected 2 LLM calls, got %d", stats.LLMCalls)
+	}
+	if stats.ToolCalls != 2 || stats.ToolErrors != 1 {
+		t.Errorf("Expected 2 tool calls with 1 error, got %d/%d", stats.ToolCalls, stats.ToolErrors)
+	}
+}
+
+func TestManagerStatsPersistAndSurfaceInSummary(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	run := beginRun(t, manager)
+	run.RecordUsage(1000, 200, 0.02)
+	run.RecordToolCall(false)
+	if err := run.Finish(nil, 1500*time.Millisecond); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	run.End()
+
+	stats, err := manager.GetStats(conv.ID)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.TotalTokens != 1200 || stats.ToolCalls != 1 || stats.WallTimeMs != 1500 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Stats are saved and appear in the listing
+	manager.New()
+	stats, _ = manager.GetStats(conv.ID)
+	if stats.TotalTokens != 1200 {
+		t.Errorf("Expected stats to be saved, got %+v", stats)
+	}
+
+	summaries, _ := manager.List()
+	for _, s := range summaries {
+		if s.ID == conv.ID && s.Stats.EstimatedCost != 0.02 {
+			t.Errorf("Expected summary to carry cost, got %f", s.Stats.EstimatedCost)
+		}
+	}
+}
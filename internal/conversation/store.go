@@ -2,35 +2,81 @@ package conversation
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
-// Store handles persistence of conversations to disk.
-type Store struct {
+// Store persists conversations. Implementations must be safe for concurrent use.
+type Store interface {
+	// Save persists a conversation and updates its listing summary.
+	Save(conv *Conversation) error
+	// Load retrieves a conversation by ID.
+	Load(id string) (*Conversation, error)
+	// List returns summaries of all conversations, sorted by most recent first.
+	List() ([]Summary, error)
+	// ListPage returns up to limit summaries starting at offset, sorted by
+	// most recent first. A non-positive limit returns all remaining summaries.
+	ListPage(offset, limit int) (Page, error)
+	// Delete removes a conversation by ID.
+	Delete(id string) error
+}
+
+// ErrNotFound is returned by Store.Load for an unknown conversation ID.
+var ErrNotFound = errors.New("conversation not found")
+
+// IndexFlushDelay is how long FileStore batches changes to index.json
+// before writing it, so a burst of saves during a run writes it once.
+const IndexFlushDelay = 2 * time.Second
+
+// FileStore handles persistence of conversations to disk as one JSON file
+// per conversation plus an index.json of summaries. Conversation files are
+// written on every save; the index is kept in memory and written after
+// IndexFlushDelay. An index that missed its last write is repaired from
+// the conversation files the next time it is listed.
+type FileStore struct {
 	basePath string
+	cipher   *Cipher
+	compress bool // Gzip conversation files and keep large tool outputs in blobs
 	mu       sync.RWMutex
+
+	// The index as last read or written, nil until first needed
+	index      []indexEntry
+	indexDirty bool        // index has changes not yet written
+	flushTimer *time.Timer // Pending write of the index, if any
+	closed     bool        // Write the index right away from now on
+	readOnly   bool        // Another instance owns the store; keep index changes in memory
+	locked     bool        // Encrypted without a key; refuse writes until SetCipher
 }
 
-// NewStore creates a new conversation store at the given path.
+var (
+	_ EncryptedStore = (*FileStore)(nil)
+	_ io.Closer      = (*FileStore)(nil)
+)
+
+// NewStore creates a new JSON file conversation store at the given path.
 // It creates the directory and index file if they don't exist.
-func NewStore(basePath string) (*Store, error) {
+func NewStore(basePath string) (*FileStore, error) {
 	// Create directory if it doesn't exist
 	if err := os.MkdirAll(basePath, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create store directory: %w", err)
 	}
 
-	store := &Store{
+	store := &FileStore{
 		basePath: basePath,
 	}
 
 	// Initialize index file if it doesn't exist
 	indexPath := filepath.Join(basePath, "index.json")
 	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
-		if err := store.writeIndex([]Summary{}); err != nil {
+		if err := store.writeIndexFile([]indexEntry{}); err != nil {
 			return nil, fmt.Errorf("failed to create index file: %w", err)
 		}
 	}
@@ -39,18 +85,28 @@ func NewStore(basePath string) (*Store, error) {
 }
 
 // Save persists a conversation to disk and updates the index.
-func (s *Store) Save(conv *Conversation) error {
+// If the file on disk has a newer revision than conv (for example because a
+// sync client delivered an edit made on another machine), the file is left
+// untouched, conv is written to a conflict copy, and ErrConflict is returned.
+func (s *FileStore) Save(conv *Conversation) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	convPath := s.convPath(conv.ID)
+	if diskRevision := s.readRevision(convPath); diskRevision > conv.Revision {
+		return s.saveConflictCopy(conv, diskRevision)
+	}
+
 	// Write conversation file
-	convPath := filepath.Join(s.basePath, fmt.Sprintf("conv_%s.json", conv.ID))
-	data, err := json.MarshalIndent(conv, "", "  ")
+	conv.Revision++
+	data, err := s.marshalConversation(conv)
 	if err != nil {
+		conv.Revision--
 		return fmt.Errorf("failed to marshal conversation: %w", err)
 	}
 
-	if err := os.WriteFile(convPath, data, 0644); err != nil {
+	if err := s.writeData(convPath, data); err != nil {
+		conv.Revision--
 		return fmt.Errorf("failed to write conversation file: %w", err)
 	}
 
@@ -61,23 +117,21 @@ func (s *Store) Save(conv *Conversation) error {
 	}
 
 	// Update or add summary in index
-	summary := conv.ToSummary()
+	entry := indexEntry{Summary: conv.ToSummary(), FileModTime: fileModTime(convPath)}
 	found := false
 	for i, existing := range index {
 		if existing.ID == conv.ID {
-			index[i] = summary
+			index[i] = entry
 			found = true
 			break
 		}
 	}
 	if !found {
-		index = append(index, summary)
+		index = append(index, entry)
 	}
 
 	// Sort by UpdatedAt descending (most recent first)
-	sort.Slice(index, func(i, j int) bool {
-		return index[i].UpdatedAt.After(index[j].UpdatedAt)
-	})
+	sortIndex(index)
 
 	if err := s.writeIndex(index); err != nil {
 		return fmt.Errorf("failed to write index: %w", err)
@@ -87,43 +141,60 @@ func (s *Store) Save(conv *Conversation) error {
 }
 
 // Load retrieves a conversation by ID.
-func (s *Store) Load(id string) (*Conversation, error) {
+func (s *FileStore) Load(id string) (*Conversation, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	convPath := filepath.Join(s.basePath, fmt.Sprintf("conv_%s.json", id))
-	data, err := os.ReadFile(convPath)
+	data, err := s.readData(s.convPath(id))
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("conversation not found: %s", id)
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
 		}
 		return nil, fmt.Errorf("failed to read conversation file: %w", err)
 	}
 
-	var conv Conversation
-	if err := json.Unmarshal(data, &conv); err != nil {
+	conv, err := s.unmarshalConversation(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal conversation: %w", err)
 	}
 
-	return &conv, nil
+	return conv, nil
 }
 
 // List returns summaries of all conversations, sorted by most recent first.
-func (s *Store) List() ([]Summary, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// The index is first reconciled with the conversation files on disk.
+func (s *FileStore) List() ([]Summary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	return s.readIndex()
+	index, err := s.reconcileIndex()
+	if err != nil {
+		return nil, err
+	}
+	return summariesOf(index), nil
 }
 
-// Delete removes a conversation by ID.
-func (s *Store) Delete(id string) error {
+// ListPage returns one page of summaries from the reconciled index.
+func (s *FileStore) ListPage(offset, limit int) (Page, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Delete conversation file
-	convPath := filepath.Join(s.basePath, fmt.Sprintf("conv_%s.json", id))
-	if err := os.Remove(convPath); err != nil && !os.IsNotExist(err) {
+	index, err := s.reconcileIndex()
+	if err != nil {
+		return Page{}, err
+	}
+
+	start, end := pageBounds(len(index), offset, limit)
+	return newPage(summariesOf(index[start:end]), start, len(index)), nil
+}
+
+// Delete moves a conversation into the trash and removes it from the index.
+func (s *FileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Move conversation file to the trash
+	if err := s.moveToTrash(id); err != nil {
 		return fmt.Errorf("failed to delete conversation file: %w", err)
 	}
 
@@ -134,10 +205,10 @@ func (s *Store) Delete(id string) error {
 	}
 
 	// Remove from index
-	newIndex := make([]Summary, 0, len(index))
-	for _, summary := range index {
-		if summary.ID != id {
-			newIndex = append(newIndex, summary)
+	newIndex := make([]indexEntry, 0, len(index))
+	for _, entry := range index {
+		if entry.ID != id {
+			newIndex = append(newIndex, entry)
 		}
 	}
 
@@ -148,31 +219,346 @@ func (s *Store) Delete(id string) error {
 	return nil
 }
 
-// readIndex reads the index file (caller must hold lock).
-func (s *Store) readIndex() ([]Summary, error) {
+// readIndex returns a copy of the index, reading the index file the first
+// time (caller must hold lock).
+func (s *FileStore) readIndex() ([]indexEntry, error) {
+	if s.index == nil {
+		data, err := s.readData(filepath.Join(s.basePath, "index.json"))
+		if err != nil {
+			return nil, err
+		}
+		var index []indexEntry
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, err
+		}
+		if index == nil {
+			index = []indexEntry{}
+		}
+		s.index = index
+	}
+	return slices.Clone(s.index), nil
+}
+
+// writeIndex replaces the index and schedules writing it to the index file
+// (caller must hold write lock).
+func (s *FileStore) writeIndex(index []indexEntry) error {
+	if index == nil {
+		index = []indexEntry{}
+	}
+	s.index = index
+	if s.readOnly {
+		return nil
+	}
+	s.indexDirty = true
+	if s.closed {
+		return s.flushIndex()
+	}
+	if s.flushTimer == nil {
+		s.flushTimer = time.AfterFunc(IndexFlushDelay, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.flushTimer = nil
+			if err := s.flushIndex(); err != nil {
+				logger.Warn("failed to write conversation index", "error", err)
+			}
+		})
+	}
+	return nil
+}
+
+// flushIndex writes the index file if it has unwritten changes (caller
+// must hold write lock).
+func (s *FileStore) flushIndex() error {
+	if !s.indexDirty || s.readOnly {
+		return nil
+	}
+	if err := s.writeIndexFile(s.index); err != nil {
+		return err
+	}
+	s.indexDirty = false
+	return nil
+}
+
+// writeIndexFile writes index to the index file (caller must hold lock).
+func (s *FileStore) writeIndexFile(index []indexEntry) error {
 	indexPath := filepath.Join(s.basePath, "index.json")
-	data, err := os.ReadFile(indexPath)
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return s.writeData(indexPath, data)
+}
+
+// Flush writes any pending changes to the index file.
+func (s *FileStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	return s.flushIndex()
+}
+
+// Close writes any pending changes to the index file. The store remains
+// usable, writing the index on every change.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return s.Flush()
+}
+
+// SetCipher enables encryption of conversation files and the index.
+// Existing plaintext files remain readable and are encrypted when next
+// saved; the index is rewritten right away.
+func (s *FileStore) SetCipher(c *Cipher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locked = false
+	if c == s.cipher {
+		return
+	}
+	index, err := s.readIndex()
+	s.cipher = c
+	if err != nil {
+		return // Unreadable with the old cipher; reconciling rebuilds it
+	}
+	s.index, s.indexDirty = index, true
+	if err := s.flushIndex(); err != nil {
+		logger.Warn("failed to rewrite conversation index", "error", err)
+	}
+}
+
+// Lock refuses writes until SetCipher is called. Index changes are kept in
+// memory meanwhile.
+func (s *FileStore) Lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locked = true
+}
+
+// readData reads a file, decrypting and decompressing it if it was
+// encrypted or compressed (caller must hold lock).
+func (s *FileStore) readData(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = s.cipher.open(data)
 	if err != nil {
 		return nil, err
 	}
+	return gunzipData(data)
+}
+
+// writeData writes a file, encrypting it if a cipher is set (caller must hold lock).
+func (s *FileStore) writeData(path string, data []byte) error {
+	if s.locked {
+		return ErrLocked
+	}
+	sealed, err := s.cipher.seal(data)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it into place so sync clients
+	// and other readers never see a partially written file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, sealed, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// convPath returns the path of the file holding the conversation with the given ID.
+func (s *FileStore) convPath(id string) string {
+	return filepath.Join(s.basePath, fmt.Sprintf("conv_%s.json", id))
+}
+
+// trashPath returns the path of the trashed file for the conversation with the given ID.
+func (s *FileStore) trashPath(id string) string {
+	return filepath.Join(s.basePath, trashDir, fmt.Sprintf("conv_%s.json", id))
+}
+
+// Restore moves a conversation out of the trash and back into the index.
+func (s *FileStore) Restore(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	convPath := s.convPath(id)
+	if err := os.Rename(s.trashPath(id), convPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("conversation not in trash: %s", id)
+		}
+		return fmt.Errorf("failed to restore conversation: %w", err)
+	}
 
-	var index []Summary
-	if err := json.Unmarshal(data, &index); err != nil {
+	entry, ok := s.indexEntryFor(id, fileModTime(convPath))
+	if !ok {
+		return fmt.Errorf("failed to read restored conversation: %s", id)
+	}
+
+	index, err := s.readIndex()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	restored := make([]indexEntry, 0, len(index)+1)
+	for _, existing := range index {
+		if existing.ID != id {
+			restored = append(restored, existing)
+		}
+	}
+	restored = append(restored, entry)
+	sortIndex(restored)
+
+	if err := s.writeIndex(restored); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// ListTrash returns the conversations in the trash. A trashed file's
+// modification time records when it was deleted.
+func (s *FileStore) ListTrash() ([]TrashedSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := s.trashEntries()
+	if err != nil {
 		return nil, err
 	}
 
-	return index, nil
+	trashed := []TrashedSummary{}
+	for _, entry := range entries {
+		data, err := s.readData(filepath.Join(s.basePath, trashDir, entry.Name()))
+		if err != nil {
+			logger.Warn("skipping unreadable conversation in trash", "file", entry.Name(), "error", err)
+			continue
+		}
+		var conv Conversation
+		if err := json.Unmarshal(data, &conv); err != nil {
+			logger.Warn("skipping corrupt conversation in trash", "file", entry.Name(), "error", err)
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		trashed = append(trashed, TrashedSummary{Summary: conv.ToSummary(), DeletedAt: info.ModTime()})
+	}
+
+	sort.Slice(trashed, func(i, j int) bool {
+		return trashed[i].DeletedAt.After(trashed[j].DeletedAt)
+	})
+	return trashed, nil
 }
 
-// writeIndex writes the index file (caller must hold lock).
-func (s *Store) writeIndex(index []Summary) error {
-	indexPath := filepath.Join(s.basePath, "index.json")
-	data, err := json.MarshalIndent(index, "", "  ")
+// EmptyTrash permanently deletes every conversation in the trash.
+func (s *FileStore) EmptyTrash() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.RemoveAll(filepath.Join(s.basePath, trashDir)); err != nil {
+		return fmt.Errorf("failed to empty trash: %w", err)
+	}
+	if err := s.pruneBlobs(); err != nil {
+		logger.Warn("failed to prune blobs", "error", err)
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes conversations trashed before cutoff.
+func (s *FileStore) PurgeTrash(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.trashEntries()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.basePath, trashDir, entry.Name())); err != nil {
+			return purged, fmt.Errorf("failed to purge trash: %w", err)
+		}
+		purged++
+	}
+	if purged > 0 {
+		if err := s.pruneBlobs(); err != nil {
+			logger.Warn("failed to prune blobs", "error", err)
+		}
+	}
+	return purged, nil
+}
+
+// trashEntries lists the conversation files in the trash (caller must hold lock).
+func (s *FileStore) trashEntries() ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(filepath.Join(s.basePath, trashDir))
 	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+
+	files := make([]os.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, "conv_") && strings.HasSuffix(name, ".json") {
+			files = append(files, entry)
+		}
+	}
+	return files, nil
+}
+
+// moveToTrash moves a conversation file into the trash, stamping it with the
+// deletion time (caller must hold lock).
+func (s *FileStore) moveToTrash(id string) error {
+	if err := os.MkdirAll(filepath.Join(s.basePath, trashDir), 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(indexPath, data, 0644)
+	trashPath := s.trashPath(id)
+	if err := os.Rename(s.convPath(id), trashPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+	return os.Chtimes(trashPath, now, now)
+}
+
+// Sizes returns the size of each indexed conversation file.
+func (s *FileStore) Sizes() (map[string]int64, error) {
+	// Write lock: the first readIndex caches the index
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]int64, len(index))
+	for _, entry := range index {
+		if info, err := os.Stat(s.convPath(entry.ID)); err == nil {
+			sizes[entry.ID] = info.Size()
+		}
+	}
+	return sizes, nil
 }
 
 // GetDefaultStorePath returns the default path for conversation storage.
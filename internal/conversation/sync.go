@@ -0,0 +1,187 @@
+package conversation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrConflict is returned by FileStore.Save when the conversation file on
+// disk is newer than the conversation being saved. The Manager reloads the
+// active conversation when saving it conflicts; see SetConflictFunc.
+var ErrConflict = errors.New("conversation was modified elsewhere")
+
+// indexEntry is an index.json record: a Summary plus the modification time
+// of its conversation file when the summary was taken. The time lets the
+// store notice files changed by sync clients or other machines.
+type indexEntry struct {
+	Summary
+	FileModTime int64 `json:"file_mod_time,omitempty"` // UnixNano
+}
+
+// readRevision returns the revision stamp of the conversation file at path,
+// or 0 if it doesn't exist or can't be read (caller must hold lock).
+func (s *FileStore) readRevision(path string) int64 {
+	data, err := s.readData(path)
+	if err != nil {
+		return 0
+	}
+
+	var stamp struct {
+		Revision int64 `json:"revision"`
+	}
+	if json.Unmarshal(data, &stamp) != nil {
+		return 0
+	}
+	return stamp.Revision
+}
+
+// saveConflictCopy writes conv next to the newer file on disk so neither
+// version is lost, and returns ErrConflict (caller must hold lock).
+// Conflict copies are not indexed.
+func (s *FileStore) saveConflictCopy(conv *Conversation, diskRevision int64) error {
+	data, err := json.MarshalIndent(conv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	copyPath := filepath.Join(s.basePath, fmt.Sprintf("conv_%s.conflict-%d.json", conv.ID, time.Now().UnixNano()))
+	if err := s.writeData(copyPath, data); err != nil {
+		return fmt.Errorf("failed to write conflict copy: %w", err)
+	}
+
+	return fmt.Errorf("%w: %s is at revision %d on disk, local copy is at %d (saved to %s)",
+		ErrConflict, conv.ID, diskRevision, conv.Revision, filepath.Base(copyPath))
+}
+
+// reconcileIndex brings the index in line with the conversation files on
+// disk: entries whose file disappeared are dropped, and files that are new
+// or were modified since they were indexed are re-read. The index is only
+// rewritten if something changed (caller must hold write lock).
+func (s *FileStore) reconcileIndex() ([]indexEntry, error) {
+	index, err := s.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.conversationFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	reconciled := make([]indexEntry, 0, len(files))
+	seen := make(map[string]bool, len(index))
+	for _, entry := range index {
+		modTime, exists := files[entry.ID]
+		if !exists {
+			changed = true // Deleted elsewhere
+			continue
+		}
+		seen[entry.ID] = true
+
+		if modTime != entry.FileModTime {
+			if refreshed, ok := s.indexEntryFor(entry.ID, modTime); ok {
+				entry = refreshed
+				changed = true
+			}
+		}
+		reconciled = append(reconciled, entry)
+	}
+
+	for id, modTime := range files {
+		if seen[id] {
+			continue
+		}
+		// A file that can't be read yet (e.g. still syncing) is picked up next time
+		if entry, ok := s.indexEntryFor(id, modTime); ok {
+			reconciled = append(reconciled, entry)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return index, nil
+	}
+
+	sortIndex(reconciled)
+	if err := s.writeIndex(reconciled); err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+	return reconciled, nil
+}
+
+// conversationFiles maps the ID of every conversation file in the store to
+// its modification time. Temporary files, conflict copies, and copies made
+// by sync clients are ignored (caller must hold lock).
+func (s *FileStore) conversationFiles() (map[string]int64, error) {
+	entries, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]int64)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "conv_") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		id := strings.TrimSuffix(strings.TrimPrefix(name, "conv_"), ".json")
+		if id == "" || strings.ContainsAny(id, ". ()") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files[id] = info.ModTime().UnixNano()
+	}
+	return files, nil
+}
+
+// indexEntryFor reads a conversation file and builds its index entry
+// (caller must hold lock).
+func (s *FileStore) indexEntryFor(id string, modTime int64) (indexEntry, bool) {
+	data, err := s.readData(s.convPath(id))
+	if err != nil {
+		return indexEntry{}, false
+	}
+
+	var conv Conversation
+	if err := json.Unmarshal(data, &conv); err != nil || conv.ID != id {
+		return indexEntry{}, false
+	}
+	return indexEntry{Summary: conv.ToSummary(), FileModTime: modTime}, true
+}
+
+// fileModTime returns the modification time of path in UnixNano, or 0 on error.
+func fileModTime(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// sortIndex sorts index entries by UpdatedAt descending (most recent first).
+func sortIndex(index []indexEntry) {
+	sort.Slice(index, func(i, j int) bool {
+		return index[i].UpdatedAt.After(index[j].UpdatedAt)
+	})
+}
+
+// summariesOf returns the summaries of index entries.
+func summariesOf(index []indexEntry) []Summary {
+	summaries := make([]Summary, len(index))
+	for i, entry := range index {
+		summaries[i] = entry.Summary
+	}
+	return summaries
+}
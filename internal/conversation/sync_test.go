@@ -0,0 +1,196 @@
+package conversation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreSaveIncrementsRevision(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	conv := New()
+	if err := store.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if conv.Revision != 2 {
+		t.Errorf("Expected revision 2, got %d", conv.Revision)
+	}
+
+	loaded, err := store.Load(conv.ID)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Revision != 2 {
+		t.Errorf("Expected loaded revision 2, got %d", loaded.Revision)
+	}
+}
+
+func TestStoreSaveDetectsConflict(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	conv := New()
+	conv.Title = "Original"
+	if err := store.Save(conv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Another machine loads, edits, and saves the same conversation
+	remote, _ := store.Load(conv.ID)
+	remote.Title = "Remote edit"
+	if err := store.Save(remote); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// The stale local copy must not clobber the newer file
+	conv.Title = "Local edit"
+	err := store.Save(conv)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+
+	loaded, _ := store.Load(conv.ID)
+	if loaded.Title != "Remote edit" {
+		t.Errorf("Expected remote edit to be kept, got '%s'", loaded.Title)
+	}
+
+	copies, _ := filepath.Glob(filepath.Join(store.basePath, "conv_"+conv.ID+".conflict-*.json"))
+	if len(copies) != 1 {
+		t.Fatalf("Expected 1 conflict copy, got %d", len(copies))
+	}
+	data, _ := os.ReadFile(copies[0])
+	if !strings.Contains(string(data), "Local edit") {
+		t.Error("Expected conflict copy to contain the local edit")
+	}
+
+	// Conflict copies are not listed
+	summaries, _ := store.List()
+	if len(summaries) != 1 {
+		t.Errorf("Expected 1 conversation, got %d", len(summaries))
+	}
+}
+
+func TestStoreListPicksUpExternalChanges(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	kept := New()
+	kept.Title = "Kept"
+	removed := New()
+	removed.Title = "Removed"
+	for _, conv := range []*Conversation{kept, removed} {
+		if err := store.Save(conv); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	// Simulate a sync client: a file arrives from another machine, one is
+	// deleted, and one is modified without the index being updated.
+	other, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	arrived := New()
+	arrived.Title = "Arrived"
+	arrived.UpdatedAt = time.Now().Add(time.Hour)
+	if err := other.Save(arrived); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, _ := os.ReadFile(other.convPath(arrived.ID))
+	if err := os.WriteFile(store.convPath(arrived.ID), data, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if err := os.Remove(store.convPath(removed.ID)); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	kept.Title = "Kept (edited remotely)"
+	if err := other.Save(kept); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, _ = os.ReadFile(other.convPath(kept.ID))
+	if err := os.WriteFile(store.convPath(kept.ID), data, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(store.convPath(kept.ID), future, future)
+
+	// Files that aren't conversations are ignored
+	os.WriteFile(filepath.Join(store.basePath, "conv_"+kept.ID+" (conflicted copy).json"), data, 0644)
+	os.WriteFile(store.convPath(kept.ID)+".tmp", data, 0644)
+
+	summaries, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 conversations, got %d", len(summaries))
+	}
+	if summaries[0].ID != arrived.ID {
+		t.Errorf("Expected arrived conversation first, got '%s'", summaries[0].Title)
+	}
+	if summaries[1].Title != "Kept (edited remotely)" {
+		t.Errorf("Expected refreshed title, got '%s'", summaries[1].Title)
+	}
+
+	// The reconciled index is persisted
+	index, err := store.readIndex()
+	if err != nil {
+		t.Fatalf("readIndex failed: %v", err)
+	}
+	if len(index) != 2 {
+		t.Errorf("Expected index to hold 2 entries, got %d", len(index))
+	}
+}
+
+func TestManagerReloadsAfterConflict(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+	store := manager.store.(*FileStore)
+
+	var conflicts []string
+	manager.SetConflictFunc(func(id string, err error) {
+		conflicts = append(conflicts, id)
+	})
+	conv := manager.New()
+
+	// Another machine edits the conversation while it is open here
+	remote, _ := store.Load(conv.ID)
+	remote.Title = "Remote edit"
+	if err := store.Save(remote); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if err := manager.Rename("Local edit"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != conv.ID {
+		t.Errorf("Expected the conflict to be reported once, got %v", conflicts)
+	}
+	if title := manager.GetActive().Title; title != "Remote edit" {
+		t.Errorf("Expected the remote version to be reloaded, got title %q", title)
+	}
+
+	// Later saves go on from the remote version instead of conflicting again
+	if err := manager.AddUserMessage("Hello again"); err != nil {
+		t.Fatalf("AddUserMessage after a conflict failed: %v", err)
+	}
+	copies, _ := filepath.Glob(filepath.Join(store.basePath, "conv_"+conv.ID+".conflict-*.json"))
+	if len(copies) != 1 {
+		t.Errorf("Expected 1 conflict copy, got %d", len(copies))
+	}
+	loaded, _ := store.Load(conv.ID)
+	if loaded.Title != "Remote edit" || loaded.Messages[len(loaded.Messages)-1].Content != "Hello again" {
+		t.Errorf("Expected the new message saved onto the remote version, got %+v", loaded)
+	}
+}
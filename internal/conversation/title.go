@@ -0,0 +1,267 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"agent-desktop/internal/llm"
+)
+
+// defaultTitle is the title given to new conversations.
+const defaultTitle = "New Conversation"
+
+// titleTurns is how many leading turns are shown to the LLM when titling.
+const titleTurns = 3
+
+// retitleInterval is how many turns must pass after titling before the
+// conversation is checked for a topic shift.
+const retitleInterval = 6
+
+// titleExcerptLen caps how much of each message is sent for titling.
+const titleExcerptLen = 500
+
+// localTitleWords caps the length of titles derived without the LLM.
+const localTitleWords = 6
+
+// SetLLMTitles enables or disables LLM-generated titles. When disabled,
+// titles are derived from the first user message and no conversation
+// content is sent to the LLM for titling.
+func (m *Manager) SetLLMTitles(enabled bool) {
+	m.llmTitles = enabled
+}
+
+// GenerateTitle titles the active conversation from its first few turns.
+// Conversations the user renamed are left alone. Auto-titled conversations
+// are re-checked every few turns and re-titled if the topic has shifted.
+func (m *Manager) GenerateTitle(ctx context.Context) error {
+	m.mu.Lock()
+	conv := m.active
+	var task *TitleTask
+	if conv != nil {
+		task = prepareTitle(conv)
+	}
+	m.mu.Unlock()
+	if conv == nil {
+		return errors.New("no active conversation")
+	}
+	if task == nil {
+		return nil
+	}
+	return m.CompleteTitle(ctx, task)
+}
+
+// TitleTask is the work of titling a conversation: a copy of what the
+// title is made from, so the LLM can be asked on another goroutine while
+// the conversation goes on.
+type TitleTask struct {
+	ConversationID string
+	conv           *Conversation // Copy of the conversation when the task was made
+	retitle        bool
+}
+
+// prepareTitle returns the task of titling conv as GenerateTitle would, or
+// nil if it needs no title now. The caller holds m.mu.
+func prepareTitle(conv *Conversation) *TitleTask {
+	if conv.CustomTitle {
+		return nil
+	}
+
+	hasDefaultTitle := conv.Title == "" || conv.Title == defaultTitle
+	if !hasDefaultTitle {
+		// Titles from before titling was tracked are treated as final
+		if conv.TitledAtTurn == 0 || conv.TurnCount() < conv.TitledAtTurn+retitleInterval {
+			return nil
+		}
+	}
+
+	return &TitleTask{ConversationID: conv.ID, conv: conv.clone(), retitle: !hasDefaultTitle}
+}
+
+// CompleteTitle asks for the title of task and saves it. It is safe to
+// call on another goroutine than the one using the manager. If the
+// conversation was titled or renamed since the task was made, nothing is
+// done.
+func (m *Manager) CompleteTitle(ctx context.Context, task *TitleTask) error {
+	if !m.titleUnchanged(task) {
+		return nil
+	}
+	title, err := m.titleFor(ctx, task.conv, task.retitle)
+	if err != nil || title == "" {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if conv := m.loadedLocked(task.ConversationID); conv != nil {
+		if !sameTitle(conv, task.conv) {
+			return nil
+		}
+		conv.Title = title
+		conv.TitledAtTurn = task.conv.TurnCount()
+		return m.saveConvLocked(conv)
+	}
+
+	// The user moved on to another conversation
+	conv, err := m.store.Load(task.ConversationID)
+	if err != nil || !sameTitle(conv, task.conv) {
+		return err
+	}
+	conv.Title = title
+	conv.TitledAtTurn = task.conv.TurnCount()
+	return m.store.Save(conv)
+}
+
+// titleUnchanged reports whether the conversation of task, if it is
+// loaded, still has the title it had when task was made.
+func (m *Manager) titleUnchanged(task *TitleTask) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conv := m.loadedLocked(task.ConversationID)
+	return conv == nil || sameTitle(conv, task.conv)
+}
+
+// sameTitle reports whether conv still has the title of was, an earlier
+// copy of it, and wasn't renamed by the user since.
+func sameTitle(conv, was *Conversation) bool {
+	return !conv.CustomTitle && conv.Title == was.Title && conv.TitledAtTurn == was.TitledAtTurn
+}
+
+// RegenerateTitle generates a fresh title for the conversation with the given
+// ID, replacing any existing title including one set by the user.
+func (m *Manager) RegenerateTitle(ctx context.Context, id string) (string, error) {
+	m.mu.Lock()
+	var conv *Conversation
+	if loaded := m.loadedLocked(id); loaded != nil {
+		conv = loaded.clone()
+	}
+	m.mu.Unlock()
+	if conv == nil {
+		loaded, err := m.store.Load(id)
+		if err != nil {
+			return "", err
+		}
+		conv = loaded
+	}
+
+	title, err := m.titleFor(ctx, conv, false)
+	if err != nil {
+		return "", err
+	}
+	if title == "" {
+		return conv.Title, nil
+	}
+
+	return title, m.update(id, func(conv *Conversation) {
+		conv.Title = title
+		conv.CustomTitle = false
+		conv.TitledAtTurn = conv.TurnCount()
+	})
+}
+
+// titleFor produces a title for conv. When retitle is set, the LLM is shown
+// the current title and recent turns and keeps the title unless the topic
+// has shifted. Returns an empty title if there is nothing to title yet.
+func (m *Manager) titleFor(ctx context.Context, conv *Conversation, retitle bool) (string, error) {
+	var firstUserMessage string
+	for _, msg := range conv.Messages {
+		if msg.Role == "user" {
+			firstUserMessage = msg.Content
+			break
+		}
+	}
+	if firstUserMessage == "" {
+		return "", nil // No user message yet
+	}
+
+	// Without an LLM, derive the title from the first request
+	if m.client == nil || !m.llmTitles {
+		if retitle {
+			return "", nil
+		}
+		return localTitle(firstUserMessage), nil
+	}
+
+	var prompt []llm.Message
+	if retitle {
+		prompt = []llm.Message{
+			{
+				Role: "system",
+				Content: "The conversation below is titled \"" + conv.Title + "\". If it has moved on to a clearly different topic, " +
+					"reply with a new short title (3-6 words). Otherwise reply with the current title unchanged. Reply with only the title, no quotes or extra text.",
+			},
+			{Role: "user", Content: titleExcerpt(conv.Messages, recentTurnsStart(conv.Messages, titleTurns))},
+		}
+	} else {
+		prompt = []llm.Message{
+			{
+				Role:    "system",
+				Content: "Generate a short title (3-6 words) for this conversation based on its opening turns. Reply with only the title, no quotes or extra text.",
+			},
+			{Role: "user", Content: titleExcerpt(conv.Messages, 0)},
+		}
+	}
+
+	resp, err := m.client.ChatCompletion(ctx, prompt, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Clean up the title
+	title := strings.TrimSpace(resp.Content)
+	title = strings.Trim(title, "\"'") // Remove quotes if present
+	return title, nil
+}
+
+// titleExcerpt renders up to titleTurns user turns with the assistant's text
+// replies, starting from message index from. Tool traffic is skipped.
+func titleExcerpt(messages []llm.Message, from int) string {
+	var b strings.Builder
+	turns := 0
+	for _, msg := range messages[from:] {
+		if msg.Role == "user" {
+			if turns == titleTurns {
+				break
+			}
+			turns++
+		}
+		if (msg.Role != "user" && msg.Role != "assistant") || msg.Content == "" {
+			continue
+		}
+
+		content := msg.Content
+		if len(content) > titleExcerptLen {
+			content = content[:titleExcerptLen] + "..."
+		}
+		b.WriteString(msg.Role + ": " + content + "\n\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// recentTurnsStart returns the index of the user message that begins the
+// last n turns, or 0 if there are fewer than n turns.
+func recentTurnsStart(messages []llm.Message, n int) int {
+	turns := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			turns++
+			if turns == n {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// localTitle derives a title from the first words of a message.
+func localTitle(message string) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(message), "\n", 2)[0])
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return defaultTitle
+	}
+	if len(words) > localTitleWords {
+		return strings.Join(words[:localTitleWords], " ") + "..."
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,175 @@
+package conversation
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
+)
+
+func TestGenerateTitleUsesFirstTurns(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	var prompt []llm.Message
+	manager.client = &MockClient{
+		ChatCompletionFunc: func(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error) {
+			prompt = messages
+			return &llm.Response{Content: "\"Organizing Photos\""}, nil
+		},
+	}
+
+	manager.New()
+	manager.AddUserMessage("Hi")
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Hello! What can I do?"})
+	manager.AddUserMessage("Sort my vacation photos by date")
+
+	if err := manager.GenerateTitle(context.Background()); err != nil {
+		t.Fatalf("GenerateTitle failed: %v", err)
+	}
+
+	if manager.GetActive().Title != "Organizing Photos" {
+		t.Errorf("Expected cleaned title, got %q", manager.GetActive().Title)
+	}
+	if !strings.Contains(prompt[1].Content, "vacation photos") {
+		t.Errorf("Expected later turns in title prompt, got %q", prompt[1].Content)
+	}
+}
+
+func TestGenerateTitleRetitlesAfterTopicShift(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	title := "First Topic"
+	calls := 0
+	manager.client = &MockClient{
+		ChatCompletionFunc: func(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error) {
+			calls++
+			return &llm.Response{Content: title}, nil
+		},
+	}
+
+	manager.New()
+	manager.AddUserMessage("Start")
+	manager.GenerateTitle(context.Background())
+
+	// Too soon to re-check
+	addTurns(manager, 2)
+	manager.GenerateTitle(context.Background())
+	if calls != 1 {
+		t.Fatalf("Expected 1 LLM call before retitle interval, got %d", calls)
+	}
+
+	title = "Second Topic"
+	addTurns(manager, retitleInterval)
+	manager.GenerateTitle(context.Background())
+	if calls != 2 {
+		t.Fatalf("Expected a retitle check after the interval, got %d calls", calls)
+	}
+	if manager.GetActive().Title != "Second Topic" {
+		t.Errorf("Expected title to change after topic shift, got %q", manager.GetActive().Title)
+	}
+}
+
+func TestGenerateTitleWithLLMTitlesDisabled(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	manager.client = &MockClient{
+		ChatCompletionFunc: func(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error) {
+			t.Error("LLM should not be called when LLM titles are disabled")
+			return &llm.Response{}, nil
+		},
+	}
+	manager.SetLLMTitles(false)
+
+	manager.New()
+	manager.AddUserMessage("Please rename every file in my downloads folder\nwith more detail")
+	manager.GenerateTitle(context.Background())
+
+	if manager.GetActive().Title != "Please rename every file in my..." {
+		t.Errorf("Expected locally derived title, got %q", manager.GetActive().Title)
+	}
+}
+
+func TestRegenerateTitleOverridesCustomTitle(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	manager.client = &MockClient{
+		ChatCompletionFunc: func(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error) {
+			return &llm.Response{Content: "Fresh Title"}, nil
+		},
+	}
+
+	conv := manager.New()
+	manager.AddUserMessage("Hello")
+	manager.Rename("My Title")
+	manager.New()
+
+	title, err := manager.RegenerateTitle(context.Background(), conv.ID)
+	if err != nil {
+		t.Fatalf("RegenerateTitle failed: %v", err)
+	}
+	if title != "Fresh Title" {
+		t.Errorf("Expected 'Fresh Title', got %q", title)
+	}
+
+	loaded, _ := manager.store.Load(conv.ID)
+	if loaded.Title != "Fresh Title" || loaded.CustomTitle {
+		t.Errorf("Expected regenerated title to be saved as automatic, got %+v", loaded.Title)
+	}
+}
+
+func TestCompleteTitle(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	calls := 0
+	manager.client = &MockClient{
+		ChatCompletionFunc: func(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error) {
+			calls++
+			return &llm.Response{Content: "Sorting Photos"}, nil
+		},
+	}
+
+	// The conversation goes on while the title is asked for
+	manager.New()
+	manager.AddUserMessage("Sort my vacation photos by date")
+	first := manager.GetActive().ID
+	task := beginRun(t, manager).PrepareTitle()
+	manager.AddAssistantMessage(llm.Message{Role: "assistant", Content: "Sorted."})
+	if err := manager.CompleteTitle(context.Background(), task); err != nil {
+		t.Fatalf("CompleteTitle failed: %v", err)
+	}
+	if conv := manager.GetActive(); conv.Title != "Sorting Photos" || conv.Messages[len(conv.Messages)-1].Content != "Sorted." {
+		t.Errorf("active conversation = %q, last message %+v", conv.Title, conv.Messages[len(conv.Messages)-1])
+	}
+
+	// A second task made before the first was done is skipped
+	manager.New()
+	manager.AddUserMessage("Rename the screenshots")
+	run := beginRun(t, manager)
+	stale := run.PrepareTitle()
+	again := run.PrepareTitle()
+	manager.CompleteTitle(context.Background(), again)
+	manager.CompleteTitle(context.Background(), stale)
+	if calls != 2 {
+		t.Errorf("LLM asked %d times, want 2", calls)
+	}
+
+	// A title arriving after the user switched conversations is saved
+	manager.New()
+	manager.AddUserMessage("Back up the laptop")
+	third := manager.GetActive().ID
+	task = beginRun(t, manager).PrepareTitle()
+	manager.Load(first)
+	if err := manager.CompleteTitle(context.Background(), task); err != nil {
+		t.Fatalf("CompleteTitle failed: %v", err)
+	}
+	if conv, _ := manager.GetStore().Load(third); conv.Title != "Sorting Photos" {
+		t.Errorf("title saved for an inactive conversation = %q", conv.Title)
+	}
+}
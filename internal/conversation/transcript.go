@@ -0,0 +1,43 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the conversation as a readable transcript: user and
+// assistant messages as sections, tool calls with their arguments, and
+// tool results as code blocks. System messages are left out.
+func (c *Conversation) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", c.Title)
+	fmt.Fprintf(&b, "_Created %s_\n", c.CreatedAt.Format("2006-01-02 15:04"))
+
+	for _, msg := range c.Messages {
+		switch msg.Role {
+		case "user":
+			fmt.Fprintf(&b, "\n## User\n\n%s\n", msg.Content)
+		case "assistant":
+			b.WriteString("\n## Assistant\n")
+			if msg.Content != "" {
+				fmt.Fprintf(&b, "\n%s\n", msg.Content)
+			}
+			for _, tc := range msg.ToolCalls {
+				fmt.Fprintf(&b, "\n**Tool call:** `%s`\n\n```json\n%s\n```\n", tc.Name, tc.Arguments)
+			}
+		case "tool":
+			fmt.Fprintf(&b, "\n**Tool result:**\n\n%s\n", codeBlock(msg.Content))
+		}
+	}
+	return b.String()
+}
+
+// codeBlock fences text, using a fence longer than any backtick run inside
+// it so the block can't be closed early.
+func codeBlock(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + "\n" + strings.TrimRight(text, "\n") + "\n" + fence
+}
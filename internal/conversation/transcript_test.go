@@ -0,0 +1,37 @@
+package conversation
+
+import (
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/llm"
+)
+
+func TestConversation_Markdown(t *testing.T) {
+	conv := New()
+	conv.Title = "Cleanup"
+	conv.AddMessage(llm.Message{Role: "system", Content: "You are an agent"})
+	conv.AddMessage(llm.Message{Role: "user", Content: "List files"})
+	conv.AddMessage(llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{
+		{ID: "1", Name: "list_directory", Arguments: `{"path":"."}`},
+	}})
+	conv.AddMessage(llm.Message{Role: "tool", ToolCallID: "1", Content: "a.txt\n```b```"})
+	conv.AddMessage(llm.Message{Role: "assistant", Content: "Found a.txt"})
+
+	md := conv.Markdown()
+
+	for _, want := range []string{
+		"# Cleanup\n",
+		"## User\n\nList files\n",
+		"**Tool call:** `list_directory`",
+		"````\na.txt\n```b```\n````",
+		"## Assistant\n\nFound a.txt\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("transcript missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "You are an agent") {
+		t.Error("transcript should leave out the system prompt")
+	}
+}
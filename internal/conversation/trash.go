@@ -0,0 +1,88 @@
+package conversation
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultTrashRetention is how long deleted conversations are kept before
+// they are purged, when no retention is configured.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// trashDir is the FileStore subdirectory holding deleted conversations.
+const trashDir = ".trash"
+
+// TrashedSummary describes a deleted conversation that can still be restored.
+type TrashedSummary struct {
+	Summary
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TrashStore is a Store whose Delete moves conversations to a trash from
+// which they can be restored until the trash is emptied or purged.
+type TrashStore interface {
+	Store
+	// Restore moves a deleted conversation back into the store.
+	Restore(id string) error
+	// ListTrash returns the deleted conversations, most recently deleted first.
+	ListTrash() ([]TrashedSummary, error)
+	// EmptyTrash permanently removes every deleted conversation.
+	EmptyTrash() error
+	// PurgeTrash permanently removes conversations deleted before cutoff and
+	// returns how many were removed.
+	PurgeTrash(cutoff time.Time) (int, error)
+}
+
+var (
+	_ TrashStore = (*FileStore)(nil)
+	_ TrashStore = (*SQLiteStore)(nil)
+)
+
+// trashStore returns the manager's store as a TrashStore.
+func (m *Manager) trashStore() (TrashStore, error) {
+	ts, ok := m.store.(TrashStore)
+	if !ok {
+		return nil, errors.New("conversation store does not support trash")
+	}
+	return ts, nil
+}
+
+// Restore moves a deleted conversation back out of the trash.
+func (m *Manager) Restore(id string) error {
+	ts, err := m.trashStore()
+	if err != nil {
+		return err
+	}
+	return ts.Restore(id)
+}
+
+// ListTrash returns the deleted conversations that can still be restored.
+func (m *Manager) ListTrash() ([]TrashedSummary, error) {
+	ts, err := m.trashStore()
+	if err != nil {
+		return nil, err
+	}
+	return ts.ListTrash()
+}
+
+// EmptyTrash permanently removes every deleted conversation.
+func (m *Manager) EmptyTrash() error {
+	ts, err := m.trashStore()
+	if err != nil {
+		return err
+	}
+	return ts.EmptyTrash()
+}
+
+// PurgeTrash permanently removes conversations deleted longer than retention
+// ago. A non-positive retention uses DefaultTrashRetention.
+func (m *Manager) PurgeTrash(retention time.Duration) (int, error) {
+	ts, err := m.trashStore()
+	if err != nil {
+		return 0, err
+	}
+	if retention <= 0 {
+		retention = DefaultTrashRetention
+	}
+	return ts.PurgeTrash(time.Now().Add(-retention))
+}
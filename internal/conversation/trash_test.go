@@ -0,0 +1,130 @@
+package conversation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreTrash(t *testing.T) {
+	fileStore, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	stores := map[string]TrashStore{
+		"file":   fileStore,
+		"sqlite": setupTestSQLiteStore(t),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			conv := New()
+			conv.Title = "Long transcript"
+			if err := store.Save(conv); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+
+			if err := store.Delete(conv.ID); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if _, err := store.Load(conv.ID); err == nil {
+				t.Error("Expected deleted conversation to be unloadable")
+			}
+
+			trashed, err := store.ListTrash()
+			if err != nil {
+				t.Fatalf("ListTrash failed: %v", err)
+			}
+			if len(trashed) != 1 || trashed[0].Title != "Long transcript" || trashed[0].DeletedAt.IsZero() {
+				t.Fatalf("Unexpected trash contents: %+v", trashed)
+			}
+
+			if err := store.Restore(conv.ID); err != nil {
+				t.Fatalf("Restore failed: %v", err)
+			}
+			loaded, err := store.Load(conv.ID)
+			if err != nil {
+				t.Fatalf("Load after restore failed: %v", err)
+			}
+			if loaded.Title != "Long transcript" {
+				t.Errorf("Expected restored title, got '%s'", loaded.Title)
+			}
+			summaries, _ := store.List()
+			if len(summaries) != 1 {
+				t.Errorf("Expected restored conversation to be listed, got %d", len(summaries))
+			}
+			if trashed, _ := store.ListTrash(); len(trashed) != 0 {
+				t.Errorf("Expected empty trash after restore, got %d", len(trashed))
+			}
+
+			if err := store.Restore(conv.ID); err == nil {
+				t.Error("Expected error restoring a conversation not in the trash")
+			}
+		})
+	}
+}
+
+func TestStoreEmptyAndPurgeTrash(t *testing.T) {
+	fileStore, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	stores := map[string]TrashStore{
+		"file":   fileStore,
+		"sqlite": setupTestSQLiteStore(t),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			saveNumbered(t, store, 3)
+			summaries, _ := store.List()
+			for _, s := range summaries {
+				store.Delete(s.ID)
+			}
+
+			purged, err := store.PurgeTrash(time.Now().Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("PurgeTrash failed: %v", err)
+			}
+			if purged != 0 {
+				t.Errorf("Expected recent deletions to be kept, purged %d", purged)
+			}
+
+			purged, _ = store.PurgeTrash(time.Now().Add(time.Hour))
+			if purged != 3 {
+				t.Errorf("Expected 3 expired deletions to be purged, got %d", purged)
+			}
+
+			saveNumbered(t, store, 2)
+			summaries, _ = store.List()
+			for _, s := range summaries {
+				store.Delete(s.ID)
+			}
+			if err := store.EmptyTrash(); err != nil {
+				t.Fatalf("EmptyTrash failed: %v", err)
+			}
+			if trashed, _ := store.ListTrash(); len(trashed) != 0 {
+				t.Errorf("Expected empty trash, got %d", len(trashed))
+			}
+		})
+	}
+}
+
+func TestManagerRestore(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	conv := manager.New()
+	if err := manager.Delete(conv.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	trashed, err := manager.ListTrash()
+	if err != nil || len(trashed) != 1 {
+		t.Fatalf("Expected 1 trashed conversation, got %d (%v)", len(trashed), err)
+	}
+
+	if err := manager.Restore(conv.ID); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if _, err := manager.Load(conv.ID); err != nil {
+		t.Errorf("Expected restored conversation to load: %v", err)
+	}
+}
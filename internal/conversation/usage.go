@@ -0,0 +1,129 @@
+package conversation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// UsageRow is the usage of one model in one conversation.
+type UsageRow struct {
+	ConversationID   string    `json:"conversation_id"`
+	Title            string    `json:"title"`
+	Model            string    `json:"model"`
+	FirstUsed        time.Time `json:"first_used"`
+	LastUsed         time.Time `json:"last_used"`
+	LLMCalls         int       `json:"llm_calls"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Cost             float64   `json:"cost"` // Estimated, in US dollars
+}
+
+// UsageReport is the usage of each model in each conversation over a range
+// of time, for expense reports.
+type UsageReport struct {
+	From             time.Time  `json:"from,omitempty"` // Zero for all time
+	To               time.Time  `json:"to"`
+	Rows             []UsageRow `json:"rows"` // Oldest first
+	LLMCalls         int        `json:"llm_calls"`
+	PromptTokens     int        `json:"prompt_tokens"`
+	CompletionTokens int        `json:"completion_tokens"`
+	TotalTokens      int        `json:"total_tokens"`
+	Cost             float64    `json:"cost"`
+}
+
+// UsageReport totals the token usage and cost recorded on the assistant
+// messages of every conversation, trashed ones aside, from from up to to.
+func (m *Manager) UsageReport(from, to time.Time) (UsageReport, error) {
+	report := UsageReport{From: from, To: to, Rows: []UsageRow{}}
+	summaries, err := m.store.List()
+	if err != nil {
+		return report, err
+	}
+
+	for _, s := range summaries {
+		if s.UpdatedAt.Before(from) {
+			continue
+		}
+		conv, err := m.store.Load(s.ID)
+		if err != nil {
+			return report, fmt.Errorf("failed to load conversation %s: %w", s.ID, err)
+		}
+
+		byModel := map[string]*UsageRow{}
+		var models []string
+		for _, msg := range conv.Messages {
+			meta := msg.Meta
+			if msg.Role != "assistant" || meta == nil || meta.Usage == nil ||
+				meta.Timestamp.Before(from) || !meta.Timestamp.Before(to) {
+				continue
+			}
+			row, ok := byModel[meta.Model]
+			if !ok {
+				row = &UsageRow{ConversationID: conv.ID, Title: conv.Title, Model: meta.Model, FirstUsed: meta.Timestamp}
+				byModel[meta.Model] = row
+				models = append(models, meta.Model)
+			}
+			row.LastUsed = meta.Timestamp
+			row.LLMCalls++
+			row.PromptTokens += meta.Usage.PromptTokens
+			row.CompletionTokens += meta.Usage.CompletionTokens
+			row.TotalTokens += meta.Usage.PromptTokens + meta.Usage.CompletionTokens
+			row.Cost += meta.Cost
+		}
+		for _, model := range models {
+			row := byModel[model]
+			report.Rows = append(report.Rows, *row)
+			report.LLMCalls += row.LLMCalls
+			report.PromptTokens += row.PromptTokens
+			report.CompletionTokens += row.CompletionTokens
+			report.TotalTokens += row.TotalTokens
+			report.Cost += row.Cost
+		}
+	}
+
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		return report.Rows[i].FirstUsed.Before(report.Rows[j].FirstUsed)
+	})
+	return report, nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r UsageReport) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteCSV writes the report as CSV with a header row, a row per model per
+// conversation, and a final row of totals.
+func (r UsageReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"conversation_id", "title", "model", "first_used", "last_used",
+		"llm_calls", "prompt_tokens", "completion_tokens", "total_tokens", "cost_usd"})
+	for _, row := range r.Rows {
+		cw.Write([]string{
+			row.ConversationID, row.Title, row.Model,
+			row.FirstUsed.Format(time.RFC3339), row.LastUsed.Format(time.RFC3339),
+			strconv.Itoa(row.LLMCalls), strconv.Itoa(row.PromptTokens), strconv.Itoa(row.CompletionTokens),
+			strconv.Itoa(row.TotalTokens), formatCost(row.Cost),
+		})
+	}
+	cw.Write([]string{"", "Total", "", "", "", strconv.Itoa(r.LLMCalls),
+		strconv.Itoa(r.PromptTokens), strconv.Itoa(r.CompletionTokens), strconv.Itoa(r.TotalTokens), formatCost(r.Cost)})
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatCost formats US dollars to a hundredth of a cent.
+func formatCost(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', 4, 64)
+}
@@ -0,0 +1,82 @@
+package conversation
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/llm"
+)
+
+// assistantAt returns an assistant message produced by model at t.
+func assistantAt(model string, t time.Time, prompt, completion int, cost float64) llm.Message {
+	return llm.Message{Role: "assistant", Content: "ok", Meta: &llm.MessageMeta{
+		Timestamp: t,
+		Model:     model,
+		Usage:     &llm.TokenUsage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion},
+		Cost:      cost,
+	}}
+}
+
+func TestManagerUsageReport(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	day := time.Date(2025, 6, 2, 10, 0, 0, 0, time.Local)
+	first := manager.New()
+	manager.AddUserMessage("Plan the trip")
+	manager.AddAssistantMessage(assistantAt("gpt-4o", day.AddDate(0, 0, -3), 5000, 500, 1))
+	manager.AddAssistantMessage(assistantAt("gpt-4o", day, 1000, 100, 0.25))
+	manager.AddAssistantMessage(assistantAt("claude", day.Add(time.Hour), 2000, 200, 0.5))
+	manager.AddAssistantMessage(assistantAt("gpt-4o", day.Add(2*time.Hour), 1000, 100, 0.25))
+
+	second := manager.New()
+	manager.AddUserMessage("Fix the build")
+	manager.AddAssistantMessage(assistantAt("local", day.Add(30*time.Minute), 300, 30, 0))
+	manager.AddToolMessage(llm.Message{Role: "tool", Content: "done", ToolCallID: "call_1"})
+
+	report, err := manager.UsageReport(day.Add(-time.Hour), day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("UsageReport failed: %v", err)
+	}
+	if len(report.Rows) != 3 {
+		t.Fatalf("rows = %+v", report.Rows)
+	}
+	gpt, local, claude := report.Rows[0], report.Rows[1], report.Rows[2]
+	if gpt.ConversationID != first.ID || gpt.Model != "gpt-4o" || gpt.LLMCalls != 2 || gpt.TotalTokens != 2200 || gpt.Cost != 0.5 ||
+		!gpt.FirstUsed.Equal(day) || !gpt.LastUsed.Equal(day.Add(2*time.Hour)) {
+		t.Errorf("gpt-4o row = %+v", gpt)
+	}
+	if local.ConversationID != second.ID || local.Model != "local" || local.PromptTokens != 300 {
+		t.Errorf("local row = %+v", local)
+	}
+	if claude.Model != "claude" || claude.CompletionTokens != 200 {
+		t.Errorf("claude row = %+v", claude)
+	}
+	if report.LLMCalls != 4 || report.TotalTokens != 4730 || report.Cost != 1 {
+		t.Errorf("totals = %+v", report)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("report is not valid CSV: %v", err)
+	}
+	if len(records) != 5 || records[0][2] != "model" || records[1][9] != "0.5000" || records[4][1] != "Total" || records[4][9] != "1.0000" {
+		t.Errorf("CSV = %v", records)
+	}
+
+	buf.Reset()
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded UsageReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Rows) != 3 || decoded.TotalTokens != 4730 {
+		t.Errorf("JSON = %s, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,97 @@
+// Package crash turns panics into crash reports. Goroutines that run agent
+// and tool code defer Recover so a panic is logged with its stack trace and
+// reported to the app instead of taking the whole process down.
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	goruntime "runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report describes a recovered panic.
+type Report struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Where      string    `json:"where"` // What was running, e.g. "agent loop" or "tool read_file"
+	Panic      string    `json:"panic"` // The panic value
+	Stack      string    `json:"stack"`
+	AppVersion string    `json:"app_version,omitempty"`
+	GoVersion  string    `json:"go_version"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+}
+
+// Message is a one-line description of the crash for error messages.
+func (r *Report) Message() string {
+	return fmt.Sprintf("internal error in %s: %s", r.Where, r.Panic)
+}
+
+// Save writes the report to path as indented JSON.
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+var (
+	mu      sync.Mutex
+	handler func(*Report)
+)
+
+// SetHandler sets the function every crash report is passed to, e.g. to
+// show it to the user. It is called from the goroutine that panicked.
+func SetHandler(fn func(*Report)) {
+	mu.Lock()
+	handler = fn
+	mu.Unlock()
+}
+
+// Recover recovers a panic in the calling goroutine, reports it with
+// Capture, and then calls onPanic, if set, so the caller can turn the crash
+// into an error result. It must be called directly in a defer statement:
+//
+//	defer crash.Recover("agent loop", nil)
+func Recover(where string, onPanic func(*Report)) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	r := Capture(where, v)
+	if onPanic != nil {
+		onPanic(r)
+	}
+}
+
+// Capture builds a report for a recovered panic value, logs it with the
+// current stack, and passes it to the handler.
+func Capture(where string, v any) *Report {
+	r := &Report{
+		ID:        uuid.New().String(),
+		Time:      time.Now(),
+		Where:     where,
+		Panic:     fmt.Sprint(v),
+		Stack:     string(debug.Stack()),
+		GoVersion: goruntime.Version(),
+		OS:        goruntime.GOOS,
+		Arch:      goruntime.GOARCH,
+	}
+	slog.Error("recovered from panic", "where", where, "panic", r.Panic, "crash_id", r.ID, "stack", r.Stack)
+
+	mu.Lock()
+	fn := handler
+	mu.Unlock()
+	if fn != nil {
+		fn(r)
+	}
+	return r
+}
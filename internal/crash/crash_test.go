@@ -0,0 +1,63 @@
+package crash
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	var handled, got *Report
+	SetHandler(func(r *Report) { handled = r })
+	defer SetHandler(nil)
+
+	func() {
+		defer Recover("test", func(r *Report) { got = r })
+		panic("boom")
+	}()
+
+	if got == nil || handled != got {
+		t.Fatalf("Expected the report to reach onPanic and the handler, got %v and %v", got, handled)
+	}
+	if got.Where != "test" || got.Panic != "boom" || got.ID == "" {
+		t.Errorf("Unexpected report: %+v", got)
+	}
+	if !strings.Contains(got.Stack, "TestRecover") {
+		t.Errorf("Expected stack to include the panicking function:\n%s", got.Stack)
+	}
+	if got.Message() != "internal error in test: boom" {
+		t.Errorf("Unexpected message: %q", got.Message())
+	}
+}
+
+func TestRecover_NoPanic(t *testing.T) {
+	called := false
+	func() {
+		defer Recover("test", func(r *Report) { called = true })
+	}()
+	if called {
+		t.Error("Expected onPanic not to be called without a panic")
+	}
+}
+
+func TestReport_Save(t *testing.T) {
+	r := Capture("save test", "oops")
+	path := filepath.Join(t.TempDir(), "crash.json")
+	if err := r.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	var saved Report
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Saved report is not JSON: %v", err)
+	}
+	if saved.ID != r.ID || saved.Panic != "oops" || saved.Stack == "" {
+		t.Errorf("Unexpected saved report: %+v", saved)
+	}
+}
@@ -0,0 +1,94 @@
+// Package deeplink parses agent-desktop:// URLs. Links open the app at a
+// conversation or start a new one with a task filled in:
+//
+//	agent-desktop://new
+//	agent-desktop://conversation/<id>
+//	agent-desktop://task?text=<task>[&conversation=<id>]
+//
+// A task link only fills in the message box; the user still sends it, so a
+// link on a web page can't make the agent run commands.
+package deeplink
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Scheme is the URL scheme registered for the app.
+const Scheme = "agent-desktop"
+
+// Actions a link can ask for.
+const (
+	ActionNew          = "new"
+	ActionConversation = "conversation"
+	ActionTask         = "task"
+)
+
+// maxTextLength bounds the task text a link can carry.
+const maxTextLength = 32 << 10
+
+// Link is a parsed deep link.
+type Link struct {
+	Action         string `json:"action"`
+	ConversationID string `json:"conversation_id,omitempty"` // Conversation to open; empty for a new one
+	Text           string `json:"text,omitempty"`            // Task to fill in the message box
+}
+
+// Parse parses an agent-desktop:// URL.
+func Parse(raw string) (Link, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return Link{}, fmt.Errorf("invalid link: %w", err)
+	}
+	if !strings.EqualFold(u.Scheme, Scheme) {
+		return Link{}, fmt.Errorf("not an %s:// link: %s", Scheme, raw)
+	}
+
+	// agent-desktop://task?x puts the action in the host; agent-desktop:task?x
+	// (as some launchers pass it) puts it in the opaque part
+	action, rest := u.Host, strings.Trim(u.Path, "/")
+	if action == "" {
+		action, rest, _ = strings.Cut(strings.Trim(u.Opaque+u.Path, "/"), "/")
+	}
+	query := u.Query()
+
+	link := Link{Action: strings.ToLower(action)}
+	switch link.Action {
+	case ActionNew:
+	case ActionConversation:
+		link.ConversationID = rest
+		if link.ConversationID == "" {
+			link.ConversationID = query.Get("id")
+		}
+		if link.ConversationID == "" {
+			return Link{}, fmt.Errorf("conversation link has no conversation ID")
+		}
+	case ActionTask:
+		link.Text = query.Get("text")
+		link.ConversationID = query.Get("conversation")
+		if strings.TrimSpace(link.Text) == "" {
+			return Link{}, fmt.Errorf("task link has no text")
+		}
+		if len(link.Text) > maxTextLength {
+			return Link{}, fmt.Errorf("task text is longer than %d bytes", maxTextLength)
+		}
+	default:
+		return Link{}, fmt.Errorf("unknown link action: %q", action)
+	}
+	if strings.ContainsAny(link.ConversationID, `/\`) {
+		return Link{}, fmt.Errorf("invalid conversation ID: %q", link.ConversationID)
+	}
+	return link, nil
+}
+
+// FromArgs returns the first deep link among command-line arguments, which
+// is how Windows and Linux pass a link to the app.
+func FromArgs(args []string) (string, bool) {
+	for _, arg := range args {
+		if strings.HasPrefix(strings.ToLower(arg), Scheme+":") {
+			return arg, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,55 @@
+package deeplink
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Link
+	}{
+		{"agent-desktop://new", Link{Action: ActionNew}},
+		{"agent-desktop://conversation/abc-123", Link{Action: ActionConversation, ConversationID: "abc-123"}},
+		{"agent-desktop://conversation?id=abc-123", Link{Action: ActionConversation, ConversationID: "abc-123"}},
+		{"agent-desktop://task?text=list%20files", Link{Action: ActionTask, Text: "list files"}},
+		{"agent-desktop://task/?text=hi&conversation=c1", Link{Action: ActionTask, Text: "hi", ConversationID: "c1"}},
+		{"AGENT-DESKTOP://Task?text=hi", Link{Action: ActionTask, Text: "hi"}},
+		{"agent-desktop:task?text=hi", Link{Action: ActionTask, Text: "hi"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.raw)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.raw, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		"https://example.com/task?text=hi",
+		"agent-desktop://delete",
+		"agent-desktop://conversation",
+		"agent-desktop://conversation/..%2F..%2Fetc",
+		"agent-desktop://task",
+		"agent-desktop://task?text=" + strings.Repeat("a", maxTextLength+1),
+	} {
+		if _, err := Parse(raw); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestFromArgs(t *testing.T) {
+	if link, ok := FromArgs([]string{"--flag", "agent-desktop://new"}); !ok || link != "agent-desktop://new" {
+		t.Errorf("FromArgs = %q, %v", link, ok)
+	}
+	if _, ok := FromArgs([]string{"--flag", "file.txt"}); ok {
+		t.Error("FromArgs found a link where there is none")
+	}
+}
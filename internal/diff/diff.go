@@ -0,0 +1,273 @@
+// Package diff computes line-based differences between two texts and
+// formats them as unified diffs.
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ContextLines is the number of unchanged lines shown around each change.
+const ContextLines = 3
+
+// OpKind is the kind of a line in an edit script.
+type OpKind int
+
+// Edit operations.
+const (
+	Equal OpKind = iota
+	Delete
+	Insert
+)
+
+// Op is one line of an edit script.
+type Op struct {
+	Kind OpKind
+	Line string
+}
+
+// Lines splits text into lines, keeping a final line without a newline.
+func Lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// maxTraceCells bounds the memory Compute uses to remember its search. Texts
+// too different to diff within it are shown as replaced outright.
+const maxTraceCells = 1 << 22
+
+// Compute returns the shortest edit script turning a into b, using Myers'
+// O(ND) algorithm.
+func Compute(a, b []string) []Op {
+	n, m := len(a), len(b)
+	offset := n + m
+	if offset == 0 {
+		return nil
+	}
+
+	// v[k+offset] is the furthest x reached on diagonal k; trace keeps v for
+	// each edit distance d so the path can be walked back.
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+	for d := 0; d <= offset; d++ {
+		if (d+1)*len(v) > maxTraceCells {
+			return replaceAll(a, b)
+		}
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset] // Insertion: move down
+			} else {
+				x = v[k-1+offset] + 1 // Deletion: move right
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[k+offset] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// replaceAll returns an edit script deleting all of a and inserting all of
+// b.
+func replaceAll(a, b []string) []Op {
+	ops := make([]Op, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, Op{Delete, line})
+	}
+	for _, line := range b {
+		ops = append(ops, Op{Insert, line})
+	}
+	return ops
+}
+
+// backtrack walks the trace from the end back to the start and returns the
+// edit script in order.
+func backtrack(a, b []string, trace [][]int, d, offset int) []Op {
+	var ops []Op
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+offset]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, Op{Equal, a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, Op{Insert, b[y]})
+		} else {
+			x--
+			ops = append(ops, Op{Delete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		ops = append(ops, Op{Equal, a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// Unified returns a unified diff turning before into after, with the given
+// file names in the header. It returns "" if the texts are equal.
+func Unified(fromName, toName, before, after string) string {
+	var sb strings.Builder
+	w := newHunkWriter(&sb, fromName, toName)
+	for _, op := range Compute(Lines(before), Lines(after)) {
+		w.add(op)
+	}
+	w.flush()
+	return sb.String()
+}
+
+// hunk is a run of changes with their surrounding context.
+type hunk struct {
+	fromLine, toLine int // 1-based start lines
+	ops              []Op
+}
+
+// hunkWriter groups a stream of edit operations into hunks, merging changes
+// whose context would overlap, and writes each hunk when it is complete.
+// It holds only the hunk being built and the context before it, so a long
+// edit script needn't be kept whole.
+type hunkWriter struct {
+	w                io.Writer
+	header           string // Written before the first hunk
+	fromLine, toLine int    // Lines of the next operation, 1-based
+	context          []Op   // Up to ContextLines unchanged lines while no hunk is open
+	cur              *hunk
+	unchanged        int // Unchanged lines at the end of cur
+	pending          int // Bytes of the lines in cur
+}
+
+func newHunkWriter(w io.Writer, fromName, toName string) *hunkWriter {
+	return &hunkWriter{w: w, header: fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName), fromLine: 1, toLine: 1}
+}
+
+// add adds the next operation of the edit script.
+func (hw *hunkWriter) add(op Op) {
+	switch {
+	case op.Kind != Equal && hw.cur == nil:
+		n := len(hw.context)
+		hw.cur = &hunk{fromLine: hw.fromLine - n, toLine: hw.toLine - n, ops: append(hw.context, op)}
+		for _, op := range hw.cur.ops {
+			hw.pending += len(op.Line)
+		}
+		hw.context, hw.unchanged = nil, 0
+	case op.Kind != Equal:
+		hw.cur.ops = append(hw.cur.ops, op)
+		hw.pending += len(op.Line)
+		hw.unchanged = 0
+	case hw.cur != nil:
+		hw.cur.ops = append(hw.cur.ops, op)
+		hw.pending += len(op.Line)
+		hw.unchanged++
+		if hw.unchanged == 2*ContextLines {
+			// The next change is too far for one hunk: end this one with
+			// its context, keeping the rest as the next one's
+			cut := len(hw.cur.ops) - ContextLines
+			hw.context = append([]Op(nil), hw.cur.ops[cut:]...)
+			hw.cur.ops = hw.cur.ops[:cut]
+			hw.write()
+		}
+	default:
+		if len(hw.context) == ContextLines {
+			hw.context = append(hw.context[:0], hw.context[1:]...)
+		}
+		hw.context = append(hw.context, op)
+	}
+
+	switch op.Kind {
+	case Equal:
+		hw.fromLine++
+		hw.toLine++
+	case Delete:
+		hw.fromLine++
+	case Insert:
+		hw.toLine++
+	}
+}
+
+// flush writes the last hunk.
+func (hw *hunkWriter) flush() {
+	if hw.cur == nil {
+		return
+	}
+	if extra := hw.unchanged - ContextLines; extra > 0 {
+		hw.cur.ops = hw.cur.ops[:len(hw.cur.ops)-extra]
+	}
+	hw.write()
+}
+
+// write writes the open hunk, after the header if it is the first.
+func (hw *hunkWriter) write() {
+	io.WriteString(hw.w, hw.header)
+	hw.header = ""
+	hw.cur.write(hw.w)
+	hw.cur, hw.pending = nil, 0
+}
+
+func (h hunk) write(w io.Writer) {
+	fromCount, toCount := 0, 0
+	for _, op := range h.ops {
+		if op.Kind != Insert {
+			fromCount++
+		}
+		if op.Kind != Delete {
+			toCount++
+		}
+	}
+	fmt.Fprintf(w, "@@ -%s +%s @@\n", rangeSpec(h.fromLine, fromCount), rangeSpec(h.toLine, toCount))
+
+	for _, op := range h.ops {
+		prefix := " "
+		switch op.Kind {
+		case Delete:
+			prefix = "-"
+		case Insert:
+			prefix = "+"
+		}
+		io.WriteString(w, prefix)
+		io.WriteString(w, op.Line)
+		if !strings.HasSuffix(op.Line, "\n") {
+			io.WriteString(w, "\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// rangeSpec formats a hunk range. An empty range starts at the line before.
+func rangeSpec(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+// apply replays an edit script, checking it turns a into b.
+func apply(t *testing.T, a, b []string, ops []Op) {
+	t.Helper()
+	var from, to []string
+	for _, op := range ops {
+		switch op.Kind {
+		case Equal:
+			from = append(from, op.Line)
+			to = append(to, op.Line)
+		case Delete:
+			from = append(from, op.Line)
+		case Insert:
+			to = append(to, op.Line)
+		}
+	}
+	if strings.Join(from, "") != strings.Join(a, "") || strings.Join(to, "") != strings.Join(b, "") {
+		t.Errorf("Edit script does not turn %q into %q: %+v", a, b, ops)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"", ""},
+		{"", "a\nb\n"},
+		{"a\nb\n", ""},
+		{"a\nb\nc\n", "a\nc\n"},
+		{"a\nb\nc\n", "a\nx\nb\nc\ny\n"},
+		{"a\nb\nc\nd\n", "d\nc\nb\na\n"},
+	}
+	for _, tt := range tests {
+		a, b := Lines(tt.a), Lines(tt.b)
+		apply(t, a, b, Compute(a, b))
+	}
+}
+
+func TestCompute_Minimal(t *testing.T) {
+	ops := Compute(Lines("a\nb\nc\n"), Lines("a\nB\nc\n"))
+	changes := 0
+	for _, op := range ops {
+		if op.Kind != Equal {
+			changes++
+		}
+	}
+	if changes != 2 {
+		t.Errorf("Expected one line replaced, got %+v", ops)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	before := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	after := "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+
+	got := Unified("a/file.txt", "b/file.txt", before, after)
+	want := `--- a/file.txt
++++ b/file.txt
+@@ -1,6 +1,6 @@
+ one
+ two
+-three
++THREE
+ four
+ five
+ six
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+`
+	if got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnified_NewAndEqualFiles(t *testing.T) {
+	if got := Unified("a", "b", "same\n", "same\n"); got != "" {
+		t.Errorf("Expected no diff for equal texts, got %q", got)
+	}
+
+	got := Unified("/dev/null", "b/new.txt", "", "hello")
+	want := "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hello\n\\ No newline at end of file\n"
+	if got != want {
+		t.Errorf("Unified() = %q, want %q", got, want)
+	}
+}
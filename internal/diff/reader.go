@@ -0,0 +1,154 @@
+package diff
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strings"
+)
+
+// How much of each text UnifiedReader holds and diffs at a time.
+const (
+	windowLines = 1024
+	windowBytes = 1 << 20
+)
+
+// UnifiedReader is Unified for texts read from before and after, for files
+// too large to hold in memory twice over. The texts are diffed a window of
+// lines at a time, committing each window's changes up to a point where
+// the texts agree and carrying the rest over to the next, so the diff may
+// be longer than Unified's where the texts differ throughout. The diff is
+// cut after about maxBytes (0 = no limit); truncated reports whether it
+// was.
+func UnifiedReader(fromName, toName string, before, after io.Reader, maxBytes int) (text string, truncated bool, err error) {
+	out := &limitedBuilder{max: maxBytes}
+	w := newHunkWriter(out, fromName, toName)
+	from, to := &lineReader{r: bufio.NewReader(before)}, &lineReader{r: bufio.NewReader(after)}
+
+	var a, b []string
+	for {
+		if out.full || maxBytes > 0 && w.pending > maxBytes {
+			// The diff is already too long, or the hunk being built is too
+			// long to write whole
+			truncated = true
+			break
+		}
+		if a, err = from.fill(a); err != nil {
+			return "", false, err
+		}
+		if b, err = to.fill(b); err != nil {
+			return "", false, err
+		}
+		ops := Compute(a, b)
+		if from.eof && to.eof {
+			for _, op := range ops {
+				w.add(op)
+			}
+			break
+		}
+
+		limitA, limitB := len(a), len(b)
+		if !from.eof {
+			limitA -= len(a) / 4
+		}
+		if !to.eof {
+			limitB -= len(b) / 4
+		}
+		n := commitPoint(ops, limitA, limitB)
+		usedA, usedB := 0, 0
+		for _, op := range ops[:n] {
+			w.add(op)
+			if op.Kind != Insert {
+				usedA++
+			}
+			if op.Kind != Delete {
+				usedB++
+			}
+		}
+		a, b = slices.Clone(a[usedA:]), slices.Clone(b[usedB:])
+	}
+	w.flush()
+	return out.text(), truncated || out.full, nil
+}
+
+// commitPoint returns how many of a window's operations to commit, using
+// no more than limitA and limitB lines of the two texts: the end of the
+// window's diff may change once the lines after it are read. It cuts after
+// the last unchanged line it can, where the texts are known to agree, or
+// else after as many operations as fit.
+func commitPoint(ops []Op, limitA, limitB int) int {
+	usedA, usedB := 0, 0
+	fit, lastEqual := 0, 0
+	for i, op := range ops {
+		if op.Kind != Insert {
+			usedA++
+		}
+		if op.Kind != Delete {
+			usedB++
+		}
+		if usedA > limitA || usedB > limitB {
+			break
+		}
+		fit = i + 1
+		if op.Kind == Equal {
+			lastEqual = i + 1
+		}
+	}
+	if lastEqual > 0 {
+		return lastEqual
+	}
+	return fit
+}
+
+// lineReader reads a text a window of lines at a time.
+type lineReader struct {
+	r   *bufio.Reader
+	eof bool
+}
+
+// fill reads lines onto lines until it holds a window's worth or the text
+// ends.
+func (lr *lineReader) fill(lines []string) ([]string, error) {
+	size := 0
+	for _, line := range lines {
+		size += len(line)
+	}
+	for !lr.eof && len(lines) < windowLines && size < windowBytes {
+		line, err := lr.r.ReadString('\n')
+		if line != "" {
+			lines = append(lines, line)
+			size += len(line)
+		}
+		if err == io.EOF {
+			lr.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+// limitedBuilder collects a diff, discarding everything from the write
+// that would take it past max bytes.
+type limitedBuilder struct {
+	sb   strings.Builder
+	max  int
+	full bool // Something was discarded
+}
+
+func (lb *limitedBuilder) Write(p []byte) (int, error) {
+	if lb.full || lb.max > 0 && lb.sb.Len()+len(p) > lb.max {
+		lb.full = true
+		return len(p), nil
+	}
+	return lb.sb.Write(p)
+}
+
+// text returns the diff collected, cut after its last whole line.
+func (lb *limitedBuilder) text() string {
+	s := lb.sb.String()
+	if lb.full {
+		s = s[:strings.LastIndexByte(s, '\n')+1]
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedReader_Small(t *testing.T) {
+	before := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	after := "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven"
+	got, truncated, err := UnifiedReader("a", "b", strings.NewReader(before), strings.NewReader(after), 0)
+	if err != nil || truncated {
+		t.Fatalf("UnifiedReader() error = %v, truncated = %v", err, truncated)
+	}
+	if want := Unified("a", "b", before, after); got != want {
+		t.Errorf("UnifiedReader() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedReader_Large(t *testing.T) {
+	// Scattered edits through a text several windows long, including some
+	// that fall on window boundaries
+	var before, after strings.Builder
+	edits := 0
+	for i := range 20000 {
+		fmt.Fprintf(&before, "line %d\n", i)
+		if i%700 == 0 || i%windowLines == 0 {
+			fmt.Fprintf(&after, "LINE %d\n", i)
+			edits++
+		} else {
+			fmt.Fprintf(&after, "line %d\n", i)
+		}
+	}
+	after.WriteString("the end\n")
+
+	got, truncated, err := UnifiedReader("a", "b", strings.NewReader(before.String()), strings.NewReader(after.String()), 0)
+	if err != nil || truncated {
+		t.Fatalf("UnifiedReader() error = %v, truncated = %v", err, truncated)
+	}
+	removed, added := 0, 0
+	for _, line := range strings.Split(got, "\n") {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+		case strings.HasPrefix(line, "-"):
+			removed++
+		case strings.HasPrefix(line, "+"):
+			added++
+		}
+	}
+	if removed != edits || added != edits+1 {
+		t.Errorf("diff removes %d lines and adds %d, want %d and %d", removed, added, edits, edits+1)
+	}
+	for _, want := range []string{"-line 7000\n+LINE 7000\n", "-line 2048\n+LINE 2048\n", "+the end\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("diff is missing %q", want)
+		}
+	}
+
+	cut, truncated, err := UnifiedReader("a", "b", strings.NewReader(before.String()), strings.NewReader(after.String()), 1000)
+	if err != nil || !truncated || len(cut) > 1000 || !strings.HasSuffix(cut, "\n") || !strings.HasPrefix(got, cut) {
+		t.Errorf("UnifiedReader() with a limit = %d bytes, truncated %v, error %v", len(cut), truncated, err)
+	}
+}
+
+func TestUnifiedReader_Replaced(t *testing.T) {
+	// Texts with nothing in common are still diffed window by window
+	before := strings.Repeat("old\n", 5000)
+	after := strings.Repeat("new\n", 3000)
+	got, _, err := UnifiedReader("a", "b", strings.NewReader(before), strings.NewReader(after), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(got, "\n-old") != 5000 || strings.Count(got, "\n+new") != 3000 {
+		t.Errorf("diff removes %d lines and adds %d, want 5000 and 3000", strings.Count(got, "\n-old"), strings.Count(got, "\n+new"))
+	}
+}
@@ -0,0 +1,71 @@
+// Package document extracts the text of documents desktop users keep, PDF,
+// Word (.docx), and Excel (.xlsx) files, as Markdown-flavored plain text
+// the agent can read. Only the standard library is used: Office files are
+// zipped XML, and PDF text is read from the page content streams, so
+// scanned PDFs and ones with unusual font encodings yield no text.
+package document
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxBytes bounds the size of a document Extract will read.
+const MaxBytes = 50 << 20
+
+// ErrNoText is returned for documents with no extractable text, such as
+// scanned PDFs.
+var ErrNoText = errors.New("no text could be extracted; it may be scanned or use an unsupported font encoding")
+
+// extractors maps a lowercase file extension to its extractor.
+var extractors = map[string]func(path string) (string, error){
+	".pdf":  extractPDF,
+	".docx": extractDOCX,
+	".xlsx": extractXLSX,
+}
+
+// kinds names each document type for people.
+var kinds = map[string]string{
+	".pdf":  "PDF document",
+	".docx": "Word document",
+	".xlsx": "Excel workbook",
+}
+
+// IsDocument reports whether path names a document Extract can read.
+func IsDocument(path string) bool {
+	_, ok := extractors[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// Kind names the type of document at path, e.g. "PDF document", or
+// returns "" if it isn't one.
+func Kind(path string) string {
+	return kinds[strings.ToLower(filepath.Ext(path))]
+}
+
+// Extract returns the text of the document at path.
+func Extract(path string) (string, error) {
+	extract, ok := extractors[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return "", fmt.Errorf("%s is not a supported document", filepath.Base(path))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > MaxBytes {
+		return "", fmt.Errorf("document is larger than %d MB", MaxBytes>>20)
+	}
+	text, err := extract(path)
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
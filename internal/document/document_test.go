@@ -0,0 +1,40 @@
+package document
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsDocument(t *testing.T) {
+	for path, want := range map[string]bool{
+		"report.PDF":   true,
+		"notes.docx":   true,
+		"budget.xlsx":  true,
+		"old.doc":      false,
+		"main.go":      false,
+		"no-extension": false,
+	} {
+		if got := IsDocument(path); got != want {
+			t.Errorf("IsDocument(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if Kind("a.xlsx") != "Excel workbook" || Kind("a.txt") != "" {
+		t.Errorf("Kind() = %q, %q", Kind("a.xlsx"), Kind("a.txt"))
+	}
+}
+
+func TestExtract_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Extract(filepath.Join(dir, "notes.txt")); err == nil {
+		t.Error("expected an error for a file that isn't a document")
+	}
+	if _, err := Extract(filepath.Join(dir, "missing.pdf")); !os.IsNotExist(err) {
+		t.Errorf("Extract() of a missing file = %v", err)
+	}
+	corrupt := filepath.Join(dir, "corrupt.docx")
+	os.WriteFile(corrupt, []byte("not a zip"), 0644)
+	if _, err := Extract(corrupt); err == nil {
+		t.Error("expected an error for a corrupt document")
+	}
+}
@@ -0,0 +1,361 @@
+package document
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxPartBytes = 100 << 20 // Uncompressed size of one part of an Office file
+	maxCells     = 200000    // Cells read from a workbook
+)
+
+// openPart opens the part called name in an Office zip.
+func openPart(zr *zip.ReadCloser, name string) (io.ReadCloser, error) {
+	for _, f := range zr.File {
+		if f.Name == name {
+			if f.UncompressedSize64 > maxPartBytes {
+				return nil, fmt.Errorf("%s is too large", name)
+			}
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("not a valid Office file: %s is missing", name)
+}
+
+// decodePart unmarshals the part called name into v.
+func decodePart(zr *zip.ReadCloser, name string, v interface{}) error {
+	r, err := openPart(zr, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// extractDOCX returns the paragraphs of a Word document, with headings and
+// list items marked up as Markdown and tables as Markdown tables.
+func extractDOCX(name string) (string, error) {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return "", fmt.Errorf("not a valid Word document: %w", err)
+	}
+	defer zr.Close()
+	r, err := openPart(zr, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	var (
+		out     strings.Builder
+		para    strings.Builder
+		prefix  string // Markdown marking the paragraph as a heading or list item
+		inText  bool   // Inside a w:t element
+		deleted int    // Depth inside tracked deletions, whose text is left out
+		tables  int    // Depth of nested tables
+		cells   []string
+	)
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("not a valid Word document: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "del":
+				deleted++
+			case "tbl":
+				tables++
+			case "tr":
+				cells = nil
+			case "tc":
+				cells = append(cells, "")
+			case "pStyle":
+				prefix = stylePrefix(attr(t, "val"))
+			case "numPr":
+				if prefix == "" {
+					prefix = "- "
+				}
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "del":
+				deleted--
+			case "p":
+				text := strings.TrimSpace(para.String())
+				para.Reset()
+				switch {
+				case text == "":
+				case tables > 0 && len(cells) > 0:
+					cell := &cells[len(cells)-1]
+					if *cell != "" {
+						*cell += " "
+					}
+					*cell += strings.ReplaceAll(text, "\n", " ")
+				default:
+					out.WriteString(prefix + text + "\n\n")
+				}
+				prefix = ""
+			case "tr":
+				for i := range cells {
+					cells[i] = strings.ReplaceAll(cells[i], "|", `\|`)
+				}
+				out.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+			case "tbl":
+				tables--
+				out.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText && deleted == 0 {
+				para.Write(t)
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+// stylePrefix returns the Markdown for a Word paragraph style: "# " for
+// Title and Heading1, "## " for Heading2, and so on, "- " for list styles.
+func stylePrefix(style string) string {
+	style = strings.ToLower(style)
+	switch {
+	case style == "title":
+		return "# "
+	case strings.HasPrefix(style, "heading"):
+		if n, err := strconv.Atoi(strings.TrimPrefix(style, "heading")); err == nil {
+			return strings.Repeat("#", min(max(n, 1), 6)) + " "
+		}
+	case strings.HasPrefix(style, "list"):
+		return "- "
+	}
+	return ""
+}
+
+// attr returns the value of the attribute with the local name name.
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// xlsxWorkbook is xl/workbook.xml.
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"id,attr"` // r:id, the relationship naming the sheet's part
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRels is xl/_rels/workbook.xml.rels.
+type xlsxRels struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is rich or plain text in a shared string or inline cell.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	s := t.T
+	for _, r := range t.Runs {
+		s += r.T
+	}
+	return s
+}
+
+// xlsxRow is a row of a worksheet.
+type xlsxRow struct {
+	Cells []struct {
+		Ref    string   `xml:"r,attr"` // e.g. "B3"
+		Type   string   `xml:"t,attr"`
+		Value  string   `xml:"v"`
+		Inline xlsxText `xml:"is"`
+	} `xml:"c"`
+}
+
+// extractXLSX returns each sheet of an Excel workbook as a Markdown table
+// under a heading with its name. Cells show their stored values, so dates
+// appear as serial numbers and formulas as their last computed result.
+func extractXLSX(name string) (string, error) {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return "", fmt.Errorf("not a valid Excel workbook: %w", err)
+	}
+	defer zr.Close()
+
+	var wb xlsxWorkbook
+	if err := decodePart(zr, "xl/workbook.xml", &wb); err != nil {
+		return "", err
+	}
+	var rels xlsxRels
+	if err := decodePart(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	targets := map[string]string{}
+	for _, r := range rels.Relationships {
+		if strings.HasPrefix(r.Target, "/") {
+			targets[r.ID] = strings.TrimPrefix(r.Target, "/")
+		} else {
+			targets[r.ID] = path.Join("xl", r.Target)
+		}
+	}
+	var shared struct {
+		Items []xlsxText `xml:"si"`
+	}
+	decodePart(zr, "xl/sharedStrings.xml", &shared) // Absent when no cell holds text
+
+	var out strings.Builder
+	cells := 0
+	for _, sheet := range wb.Sheets {
+		rows, n, err := readSheet(zr, targets[sheet.RID], shared.Items, maxCells-cells)
+		if err != nil {
+			return "", fmt.Errorf("sheet %s: %w", sheet.Name, err)
+		}
+		cells += n
+		fmt.Fprintf(&out, "## %s\n\n", sheet.Name)
+		writeTable(&out, rows)
+		if cells >= maxCells {
+			fmt.Fprintf(&out, "... (stopped after %d cells)\n", maxCells)
+			break
+		}
+	}
+	return out.String(), nil
+}
+
+// readSheet returns the cell values of a worksheet by row, up to limit
+// cells, and how many cells it read.
+func readSheet(zr *zip.ReadCloser, part string, shared []xlsxText, limit int) ([][]string, int, error) {
+	r, err := openPart(zr, part)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+
+	var rows [][]string
+	cells := 0
+	dec := xml.NewDecoder(r)
+	for cells < limit {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row xlsxRow
+		if err := dec.DecodeElement(&row, &start); err != nil {
+			return nil, 0, err
+		}
+		var values []string
+		for _, c := range row.Cells {
+			col := len(values)
+			if ref := columnIndex(c.Ref); ref >= 0 {
+				col = ref
+			}
+			if col > 16384 { // Excel's last column
+				continue
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			switch c.Type {
+			case "s":
+				if i, err := strconv.Atoi(c.Value); err == nil && i >= 0 && i < len(shared) {
+					values[col] = shared[i].String()
+				}
+			case "inlineStr":
+				values[col] = c.Inline.String()
+			case "b":
+				values[col] = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+			default:
+				values[col] = c.Value
+			}
+			cells++
+		}
+		rows = append(rows, values)
+	}
+	return rows, cells, nil
+}
+
+// columnIndex returns the zero-based column of a cell reference like
+// "AB12", or -1 if it has none.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+// writeTable writes rows as a Markdown table whose first row is the header,
+// leaving out empty rows and trailing empty columns.
+func writeTable(out *strings.Builder, rows [][]string) {
+	width := 0
+	var kept [][]string
+	for _, row := range rows {
+		last := -1
+		for i, v := range row {
+			if strings.TrimSpace(v) != "" {
+				last = i
+			}
+		}
+		if last < 0 {
+			continue
+		}
+		kept = append(kept, row[:last+1])
+		width = max(width, last+1)
+	}
+	if len(kept) == 0 {
+		out.WriteString("(empty)\n\n")
+		return
+	}
+	for i, row := range kept {
+		cells := make([]string, width)
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = strings.ReplaceAll(strings.ReplaceAll(row[j], "|", `\|`), "\n", " ")
+			}
+		}
+		out.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			out.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	out.WriteString("\n")
+}
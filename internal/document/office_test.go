@@ -0,0 +1,88 @@
+package document
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip writes an Office file holding parts, by name.
+func writeZip(t *testing.T, name string, parts map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range parts {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	f.Close()
+	return path
+}
+
+const docx = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Quarterly report</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Sales grew </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>12%</w:t></w:r><w:del><w:r><w:delText>by a lot</w:delText></w:r></w:del></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>More stores</w:t></w:r></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Sales</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>North</w:t></w:r></w:p><w:p><w:r><w:t>and East</w:t></w:r></w:p></w:tc><w:tc><w:p/></w:tc></w:tr>
+</w:tbl>
+<w:p><w:r><w:t>The end</w:t></w:r></w:p>
+</w:body></w:document>`
+
+func TestExtractDOCX(t *testing.T) {
+	path := writeZip(t, "report.docx", map[string]string{"word/document.xml": docx})
+	got, err := Extract(path)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	want := "# Quarterly report\n\nSales grew 12%\n\n- More stores\n\n| Region | Sales |\n| North and East |  |\n\nThe end"
+	if got != want {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+
+	bad := writeZip(t, "bad.docx", map[string]string{"other.xml": "<x/>"})
+	if _, err := Extract(bad); err == nil || !strings.Contains(err.Error(), "word/document.xml") {
+		t.Errorf("Extract() of a zip without a document = %v", err)
+	}
+}
+
+func TestExtractXLSX(t *testing.T) {
+	path := writeZip(t, "budget.xlsx", map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Budget" sheetId="1" r:id="rId1"/><sheet name="Notes" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>Item</t></si><si><t>Cost</t></si><si><r><t>Rent</t></r><r><t> | office</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><v>1200.5</v></c></row>
+<row r="3"><c r="A3" t="inlineStr"><is><t>Paid</t></is></c><c r="B3" t="b"><v>1</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData/></worksheet>`,
+	})
+	got, err := Extract(path)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	want := "## Budget\n\n| Item | Cost |  |\n| --- | --- | --- |\n| Rent \\| office |  | 1200.5 |\n| Paid | TRUE |  |\n\n## Notes\n\n(empty)"
+	if got != want {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "C7": 2, "Z1": 25, "AA10": 26, "AB2": 27, "": -1} {
+		if got := columnIndex(ref); got != want {
+			t.Errorf("columnIndex(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
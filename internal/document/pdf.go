@@ -0,0 +1,474 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// maxStreamBytes bounds a decompressed PDF stream.
+const maxStreamBytes = 64 << 20
+
+// skipStreams are dictionary entries of streams that hold no page text:
+// images, fonts, metadata, and cross-reference and object streams.
+var skipStreams = [][]byte{
+	[]byte("/Image"), []byte("/Length1"), []byte("/Length2"), []byte("/Length3"),
+	[]byte("/XRef"), []byte("/ObjStm"), []byte("/Metadata"), []byte("/EmbeddedFile"),
+	[]byte("/DCTDecode"), []byte("/JPXDecode"), []byte("/CCITTFaxDecode"), []byte("/JBIG2Decode"),
+}
+
+// extractPDF returns the text shown by the content streams of a PDF, in
+// the order they appear in the file.
+func extractPDF(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF")) {
+		return "", errors.New("not a valid PDF document")
+	}
+
+	var out strings.Builder
+	for _, content := range pdfStreams(data) {
+		if text := strings.TrimSpace(contentText(content)); text != "" {
+			out.WriteString(text + "\n\n")
+		}
+	}
+	return out.String(), nil
+}
+
+// pdfStreams returns the decoded body of every stream in a PDF that may
+// hold page content.
+func pdfStreams(data []byte) [][]byte {
+	var streams [][]byte
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], []byte("stream"))
+		if i < 0 {
+			break
+		}
+		keyword := pos + i
+		pos = keyword + len("stream")
+		if keyword >= 3 && string(data[keyword-3:keyword]) == "end" {
+			continue
+		}
+		dict := streamDict(data[:keyword])
+		if dict == nil {
+			continue
+		}
+
+		// The body starts after the end of line following the keyword
+		start := pos
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		body := data[start : start+end]
+		pos = start + end
+
+		skip := false
+		for _, key := range skipStreams {
+			if bytes.Contains(dict, key) {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Contains(dict, []byte("/Fl ")) {
+			r, err := zlib.NewReader(bytes.NewReader(body))
+			if err != nil {
+				continue
+			}
+			// Keep what inflated before any error; some writers pad streams
+			body, _ = io.ReadAll(io.LimitReader(r, maxStreamBytes))
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue // An encoding this package can't read
+		}
+		streams = append(streams, body)
+	}
+	return streams
+}
+
+// streamDict returns the dictionary that ends data, just before a stream
+// keyword, or nil if data doesn't end with one.
+func streamDict(data []byte) []byte {
+	end := len(bytes.TrimRight(data, "\x00\t\r\n\f "))
+	if end < 2 || string(data[end-2:end]) != ">>" {
+		return nil
+	}
+	depth := 0
+	for i := end - 1; i > 0; i-- {
+		switch {
+		case data[i] == '>' && data[i-1] == '>':
+			depth++
+			i--
+		case data[i] == '<' && data[i-1] == '<':
+			depth--
+			i--
+			if depth == 0 {
+				return data[i:end]
+			}
+		}
+	}
+	return nil
+}
+
+// contentText returns the text a page content stream shows, starting a new
+// line when the text moves down the page.
+func contentText(content []byte) string {
+	var (
+		out      strings.Builder
+		operands []interface{}
+		inText   bool
+		lastY    float64
+	)
+	newline := func() {
+		s := out.String()
+		if s != "" && !strings.HasSuffix(s, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	space := func() {
+		s := out.String()
+		if s != "" && !strings.HasSuffix(s, "\n") && !strings.HasSuffix(s, " ") {
+			out.WriteString(" ")
+		}
+	}
+	number := func(i int) float64 {
+		if i < 0 || i >= len(operands) {
+			return 0
+		}
+		f, _ := operands[i].(float64)
+		return f
+	}
+
+	lex := &pdfLexer{data: content}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		op, isOp := tok.(pdfOperator)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+		switch op {
+		case "BT":
+			inText = true
+		case "ET":
+			inText = false
+			newline()
+		case "Td", "TD":
+			if number(len(operands)-1) != 0 {
+				newline()
+			} else {
+				space()
+			}
+		case "Tm":
+			if y := number(len(operands) - 1); y != lastY {
+				newline()
+				lastY = y
+			} else {
+				space()
+			}
+		case "T*":
+			newline()
+		case "Tj", "'", "\"":
+			if op != "Tj" {
+				newline()
+			}
+			if inText && len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					out.WriteString(s.text())
+				}
+			}
+		case "TJ":
+			if arr, ok := lastArray(operands); ok && inText {
+				for _, el := range arr {
+					switch v := el.(type) {
+					case pdfString:
+						out.WriteString(v.text())
+					case float64:
+						if v < -200 { // A gap wide enough to be a space
+							space()
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return out.String()
+}
+
+// lastArray returns the last operand if it is an array.
+func lastArray(operands []interface{}) ([]interface{}, bool) {
+	if len(operands) == 0 {
+		return nil, false
+	}
+	arr, ok := operands[len(operands)-1].([]interface{})
+	return arr, ok
+}
+
+// pdfOperator is an operator in a content stream, such as "Tj".
+type pdfOperator string
+
+// pdfString is a string operand.
+type pdfString struct {
+	data []byte
+	hex  bool
+}
+
+// text decodes a string shown with a simple font, as PDFDocEncoding, or
+// with a two-byte font as UTF-16 when it looks like it. Glyph codes of
+// other embedded fonts can't be mapped without their tables and are
+// dropped as control characters where they fall outside printable text.
+func (s pdfString) text() string {
+	b := s.data
+	if len(b) >= 2 && (b[0] == 0xFE && b[1] == 0xFF || s.hex && len(b)%2 == 0 && looksUTF16(b)) {
+		if b[0] == 0xFE && b[1] == 0xFF {
+			b = b[2:]
+		}
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		}
+		return printable(string(utf16.Decode(units)))
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return printable(string(runes))
+}
+
+// looksUTF16 reports whether b looks like UTF-16BE Latin text: every other
+// byte zero.
+func looksUTF16(b []byte) bool {
+	for i := 0; i < len(b); i += 2 {
+		if b[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// printable drops control characters from s.
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\t' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// pdfLexer splits a content stream into operands and operators.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+// isDelimiter reports whether c ends a number, name, or operator.
+func isDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/% \t\r\n\f\x00", c) >= 0
+}
+
+// next returns the next token: a float64, pdfString, name string, array
+// ([]interface{}), or pdfOperator. Dictionaries are skipped.
+func (l *pdfLexer) next() (interface{}, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0:
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return pdfString{data: l.literal()}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+			l.skipDict()
+		case c == '<':
+			return pdfString{data: l.hexString(), hex: true}, true
+		case c == '[':
+			l.pos++
+			var arr []interface{}
+			for {
+				l.skipSpace()
+				if l.pos >= len(l.data) {
+					return arr, true
+				}
+				if l.data[l.pos] == ']' {
+					l.pos++
+					return arr, true
+				}
+				tok, ok := l.next()
+				if !ok {
+					return arr, true
+				}
+				arr = append(arr, tok)
+			}
+		case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+			l.pos++
+		case c == '/':
+			start := l.pos
+			l.pos++
+			for l.pos < len(l.data) && !isDelimiter(l.data[l.pos]) {
+				l.pos++
+			}
+			return string(l.data[start:l.pos]), true
+		default:
+			start := l.pos
+			for l.pos < len(l.data) && !isDelimiter(l.data[l.pos]) {
+				l.pos++
+			}
+			word := string(l.data[start:l.pos])
+			if f, err := strconv.ParseFloat(word, 64); err == nil {
+				return f, true
+			}
+			if word == "BI" {
+				l.skipInlineImage()
+				continue
+			}
+			return pdfOperator(word), true
+		}
+	}
+	return nil, false
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) && strings.IndexByte(" \t\r\n\f\x00", l.data[l.pos]) >= 0 {
+		l.pos++
+	}
+}
+
+// literal reads a (string) with balanced parentheses and escapes.
+func (l *pdfLexer) literal() []byte {
+	l.pos++ // (
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return b
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return b
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for n := 0; n < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; n++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// hexString reads a <hex string>.
+func (l *pdfLexer) hexString() []byte {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		b[i] = byte(v)
+	}
+	return b
+}
+
+// skipDict skips to the end of a << dictionary >>, which in content
+// streams only holds marked-content properties.
+func (l *pdfLexer) skipDict() {
+	depth := 1
+	for l.pos < len(l.data) && depth > 0 {
+		switch {
+		case l.data[l.pos] == '(':
+			l.literal()
+			continue
+		case bytes.HasPrefix(l.data[l.pos:], []byte("<<")):
+			depth++
+			l.pos++
+		case bytes.HasPrefix(l.data[l.pos:], []byte(">>")):
+			depth--
+			l.pos++
+		}
+		l.pos++
+	}
+}
+
+// skipInlineImage skips the data of an inline image, from BI to EI.
+func (l *pdfLexer) skipInlineImage() {
+	for l.pos < len(l.data) {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		l.pos += i + 2
+		if l.pos >= len(l.data) || isDelimiter(l.data[l.pos]) {
+			return
+		}
+	}
+}
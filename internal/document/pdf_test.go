@@ -0,0 +1,69 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePDF writes a PDF with one page per content stream. Streams are
+// compressed with Flate when compress is set.
+func writePDF(t *testing.T, compress bool, pages ...string) string {
+	t.Helper()
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	for i, content := range pages {
+		body := []byte(content)
+		dict := fmt.Sprintf("<< /Length %d >>", len(body))
+		if compress {
+			var z bytes.Buffer
+			w := zlib.NewWriter(&z)
+			w.Write(body)
+			w.Close()
+			body = z.Bytes()
+			dict = fmt.Sprintf("<< /Length %d /Filter /FlateDecode /DecodeParms << /Predictor 1 >> >>", len(body))
+		}
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nstream\r\n%s\nendstream\nendobj\n", i+1, dict, body)
+	}
+	// A font file, whose bytes must not be read as text
+	b.WriteString("9 0 obj\n<< /Length 20 /Length1 20 >>\nstream\nBT (font bytes) Tj ET\nendstream\nendobj\n%%EOF\n")
+
+	path := filepath.Join(t.TempDir(), "doc.pdf")
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractPDF(t *testing.T) {
+	page1 := "BT /F1 24 Tf 72 720 Td (Invoice \\(draft\\)) Tj 0 -30 Td [(Total:)-300(42)] TJ ET\n" +
+		"q 100 0 0 100 0 0 cm BI /W 1 /H 1 ID \x00\x01 EI Q\n" +
+		"BT 72 600 Td <FEFF00440075006500200073006f006f006e> Tj T* (Thanks) Tj ET"
+	page2 := "% page two\nBT /F1 12 Tf 1 0 0 1 72 700 Tm (Second) Tj 1 0 0 1 150 700 Tm (page) Tj ET"
+
+	for _, compress := range []bool{true, false} {
+		got, err := Extract(writePDF(t, compress, page1, page2))
+		if err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		want := "Invoice (draft)\nTotal: 42\nDue soon\nThanks\n\nSecond page"
+		if got != want {
+			t.Errorf("Extract(compress=%v) = %q, want %q", compress, got, want)
+		}
+	}
+
+	// A scanned page shows no text
+	if _, err := Extract(writePDF(t, true, "q 600 0 0 800 0 0 cm /Im1 Do Q")); err != ErrNoText {
+		t.Errorf("Extract() of a page without text = %v, want ErrNoText", err)
+	}
+
+	notPDF := filepath.Join(t.TempDir(), "fake.pdf")
+	os.WriteFile(notPDF, []byte("hello"), 0644)
+	if _, err := Extract(notPDF); err == nil || !strings.Contains(err.Error(), "not a valid PDF") {
+		t.Errorf("Extract() of a text file = %v", err)
+	}
+}
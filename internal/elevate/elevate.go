@@ -0,0 +1,163 @@
+// Package elevate runs package installer commands that need administrator
+// rights through the operating system's own elevation prompt: polkit
+// (pkexec) on Linux, the administrator password dialog on macOS, and UAC on
+// Windows. The agent has no terminal to answer sudo, so these commands
+// would otherwise hang or fail. The prompt is the user's consent; nothing
+// runs elevated without it.
+package elevate
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// ErrUnsupported is returned when there is no elevation prompt on this
+// system.
+var ErrUnsupported = errors.New("running commands as administrator is not supported on this system")
+
+// ErrCancelled is returned when the user dismisses the elevation prompt.
+var ErrCancelled = errors.New("the administrator prompt was dismissed")
+
+// PromptTitle introduces the command in elevation prompts that show a
+// message.
+const PromptTitle = "Agent Desktop needs administrator rights to run:"
+
+// Method is how a command gets administrator rights.
+type Method int
+
+const (
+	// MethodElevate runs the whole command elevated.
+	MethodElevate Method = iota + 1
+	// MethodAskpass runs the command as the user and lets it call sudo with
+	// a graphical password prompt. Homebrew refuses to run as root but
+	// calls sudo itself for cask installers.
+	MethodAskpass
+)
+
+// Plan is how to run a command that needs administrator rights.
+type Plan struct {
+	Command string // The command with any sudo prefixes removed
+	Method  Method
+}
+
+// Result is the outcome of an elevated command.
+type Result struct {
+	Output   string
+	ExitCode int
+}
+
+// installerPatterns match installer commands that need administrator
+// rights, per operating system. They match from the start of the command,
+// after any sudo.
+var installerPatterns = map[string][]*regexp.Regexp{
+	"linux": compile(
+		`^(apt|apt-get|aptitude)\s+(-\S+\s+)*(install|reinstall|remove|purge|upgrade|dist-upgrade|full-upgrade|update|autoremove)\b`,
+		`^(dnf|yum)\s+(-\S+\s+)*(install|reinstall|remove|erase|upgrade|update|autoremove)\b`,
+		`^zypper\s+(-\S+\s+)*(install|in|remove|rm|update|up|dist-upgrade|dup)\b`,
+		`^pacman\s+-[SRU]`,
+		`^snap\s+(install|remove|refresh)\b`,
+		`^dpkg\s+(-i|--install|-r|--remove|-P|--purge)\b`,
+		`^rpm\s+(-i|-U|-e|--install|--upgrade|--erase)`,
+	),
+	"darwin": compile(
+		`^installer\s+.*-pkg\b`,
+		`^softwareupdate\s+.*(-i|--install)\b`,
+	),
+	"windows": compile(
+		`(?i)^choco(\.exe)?\s+(install|upgrade|uninstall)\b`,
+		`(?i)^winget(\.exe)?\s+(install|upgrade|uninstall)\b.*--scope\s+machine\b`,
+	),
+}
+
+// askpassPatterns match commands that call sudo themselves and so only need
+// a password prompt.
+var askpassPatterns = map[string][]*regexp.Regexp{
+	"darwin": compile(`^brew\s+(install|reinstall|upgrade|uninstall)\b.*--cask\b`),
+}
+
+// sudoPrefix matches sudo and its options at the start of a command.
+var sudoPrefix = regexp.MustCompile(`^sudo\s+(-\S+\s+)*`)
+
+// shellMeta are the characters that chain, pipe, redirect, group, or
+// substitute commands in sh and cmd.exe. A command containing any isn't
+// elevated, so only a single installer invocation ever runs as
+// administrator.
+const shellMeta = ";&|<>()`$\n\r"
+
+// ErrCompound is returned by Run for a command that does more than run one
+// program.
+var ErrCompound = errors.New("only a single installer command can run as administrator")
+
+func compile(patterns ...string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		res[i] = regexp.MustCompile(p)
+	}
+	return res
+}
+
+// Detect reports whether command runs a package installer that needs
+// administrator rights on this system, and how to run it. Only a single,
+// unchained installer invocation qualifies: commands that chain, pipe,
+// redirect, or substitute commands run as the user.
+func Detect(command string) (Plan, bool) {
+	return detect(runtime.GOOS, command)
+}
+
+func detect(goos, command string) (Plan, bool) {
+	if strings.ContainsAny(command, shellMeta) {
+		return Plan{}, false
+	}
+	stripped := sudoPrefix.ReplaceAllString(strings.TrimSpace(command), "")
+	if goos == "darwin" {
+		for _, re := range askpassPatterns[goos] {
+			if re.MatchString(stripped) {
+				return Plan{Command: stripped, Method: MethodAskpass}, true
+			}
+		}
+	}
+	for _, re := range installerPatterns[goos] {
+		if re.MatchString(stripped) {
+			return Plan{Command: stripped, Method: MethodElevate}, true
+		}
+	}
+	return Plan{}, false
+}
+
+// lookPath finds a helper program. It is a variable so tests can pretend
+// the helper is installed.
+var lookPath = exec.LookPath
+
+// runCommand runs a command and returns its combined output. It is a
+// variable so tests can capture the command instead of running it.
+var runCommand = func(cmd *exec.Cmd) ([]byte, error) {
+	return cmd.CombinedOutput()
+}
+
+// Run runs a planned command in dir, showing the system's elevation prompt.
+// A non-zero exit status is returned in the result, not as an error;
+// ErrCancelled means the user declined and the command didn't run, and
+// ErrCompound that the command wasn't a single program to run.
+func Run(ctx context.Context, plan Plan, dir string) (Result, error) {
+	if strings.ContainsAny(plan.Command, shellMeta) {
+		return Result{}, ErrCompound
+	}
+	if plan.Method == MethodAskpass {
+		return runAskpass(ctx, plan.Command, dir)
+	}
+	return runElevated(ctx, plan.Command, dir)
+}
+
+// exitCode returns the exit status of a finished command, or -1 if it
+// didn't run.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
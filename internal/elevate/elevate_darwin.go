@@ -0,0 +1,90 @@
+package elevate
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// adminScript runs a shell command with administrator privileges. The
+// command and prompt are script arguments so they need no escaping.
+var adminScript = []string{
+	"-e", "on run argv",
+	"-e", "do shell script (item 1 of argv) with administrator privileges with prompt (item 2 of argv)",
+	"-e", "end run",
+}
+
+// userCancelled is the AppleScript error number for a dismissed dialog.
+const userCancelled = "(-128)"
+
+// scriptError matches the exit status at the end of an osascript error,
+// e.g. "execution error: E: Unable to locate package (100)".
+var scriptError = regexp.MustCompile(`\((-?\d+)\)\s*$`)
+
+// runElevated runs the command as root after the system's administrator
+// password dialog.
+func runElevated(ctx context.Context, command, dir string) (Result, error) {
+	shell := "cd " + shellQuote(dir) + " && " + command + " 2>&1"
+	args := append(append([]string{}, adminScript...), shell, PromptTitle+"\n\n"+command)
+	cmd := exec.CommandContext(ctx, "osascript", args...)
+
+	output, err := runCommand(cmd)
+	if err == nil {
+		return Result{Output: strings.TrimSuffix(string(output), "\n")}, nil
+	}
+	text := strings.TrimSpace(string(output))
+	if strings.HasSuffix(text, userCancelled) {
+		return Result{}, ErrCancelled
+	}
+	m := scriptError.FindStringSubmatch(text)
+	if m == nil {
+		return Result{}, err
+	}
+	code, _ := strconv.Atoi(m[1])
+	text = strings.TrimSpace(strings.TrimSuffix(text, m[0]))
+	_, text, _ = strings.Cut(text, "execution error: ")
+	return Result{Output: text, ExitCode: code}, nil
+}
+
+// askpassScript asks for the user's password with a dialog when sudo is
+// run with SUDO_ASKPASS set.
+const askpassScript = `#!/bin/sh
+exec osascript -e 'text returned of (display dialog "` + PromptTitle + `\n\n" & (system attribute "AGENT_DESKTOP_COMMAND") & "\n\nEnter your password:" default answer "" with hidden answer with title "Agent Desktop" with icon caution)'
+`
+
+// runAskpass runs the command as the user with SUDO_ASKPASS pointing at a
+// password dialog. Homebrew passes -A to sudo when SUDO_ASKPASS is set.
+func runAskpass(ctx context.Context, command, dir string) (Result, error) {
+	tmp, err := os.MkdirTemp("", "agent-desktop-askpass")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(tmp)
+	askpass := filepath.Join(tmp, "askpass.sh")
+	if err := os.WriteFile(askpass, []byte(askpassScript), 0700); err != nil {
+		return Result{}, err
+	}
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "SUDO_ASKPASS="+askpass, "AGENT_DESKTOP_COMMAND="+command)
+
+	output, err := runCommand(cmd)
+	if err != nil {
+		code := exitCode(err)
+		if code < 0 {
+			return Result{}, err
+		}
+		return Result{Output: string(output), ExitCode: code}, nil
+	}
+	return Result{Output: string(output)}, nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
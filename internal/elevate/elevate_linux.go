@@ -0,0 +1,49 @@
+package elevate
+
+import (
+	"context"
+	"os/exec"
+)
+
+// pkexec exit statuses when the user dismisses or fails the prompt.
+const (
+	pkexecDismissed    = 126
+	pkexecUnauthorized = 127
+)
+
+// systemPath is the PATH installers run with as root. The user's own PATH
+// is not passed on, since a directory in it the user can write to would let
+// any program named like a package manager run as root.
+const systemPath = "/usr/sbin:/usr/bin:/sbin:/bin"
+
+// runElevated runs the command as root with pkexec, which shows the
+// desktop's polkit password prompt. pkexec clears the environment, so a
+// fixed system PATH is set and package managers are told not to ask
+// questions.
+func runElevated(ctx context.Context, command, dir string) (Result, error) {
+	pkexec, err := lookPath("pkexec")
+	if err != nil {
+		return Result{}, ErrUnsupported
+	}
+	cmd := exec.CommandContext(ctx, pkexec, "env",
+		"PATH="+systemPath, "DEBIAN_FRONTEND=noninteractive",
+		"bash", "-c", `cd "$0" && `+command, dir)
+
+	output, err := runCommand(cmd)
+	if err != nil {
+		code := exitCode(err)
+		if code == pkexecDismissed || code == pkexecUnauthorized {
+			return Result{}, ErrCancelled
+		}
+		if code < 0 {
+			return Result{}, err
+		}
+		return Result{Output: string(output), ExitCode: code}, nil
+	}
+	return Result{Output: string(output)}, nil
+}
+
+// runAskpass is not needed on Linux, where installers run elevated.
+func runAskpass(ctx context.Context, command, dir string) (Result, error) {
+	return runElevated(ctx, command, dir)
+}
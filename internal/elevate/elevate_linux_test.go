@@ -0,0 +1,45 @@
+package elevate
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"slices"
+	"testing"
+)
+
+// exitError returns the error of a command that exited with code.
+func exitError(t *testing.T, code string) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", "exit "+code).Run()
+	if err == nil {
+		t.Fatal("expected the command to fail")
+	}
+	return err
+}
+
+func TestRun_Pkexec(t *testing.T) {
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	t.Cleanup(func() { lookPath = exec.LookPath })
+	plan := Plan{Command: "apt install nosuchpackage", Method: MethodElevate}
+
+	t.Setenv("PATH", "/home/user/bin:/usr/bin")
+	ran := stubCommand(t, "", exitError(t, "126"))
+	if _, err := Run(context.Background(), plan, t.TempDir()); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Run() error = %v, want ErrCancelled", err)
+	}
+	if len(*ran) != 1 || !slices.Contains((*ran)[0].Args, "PATH="+systemPath) {
+		t.Errorf("expected the installer to run with the system PATH, got %v", *ran)
+	}
+
+	stubCommand(t, "E: Unable to locate package", exitError(t, "100"))
+	result, err := Run(context.Background(), plan, t.TempDir())
+	if err != nil || result.ExitCode != 100 || result.Output != "E: Unable to locate package" {
+		t.Errorf("Run() = %+v, %v; want exit code 100 with output", result, err)
+	}
+
+	lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	if _, err := Run(context.Background(), plan, t.TempDir()); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Run() error = %v without pkexec, want ErrUnsupported", err)
+	}
+}
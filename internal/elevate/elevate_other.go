@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !windows
+
+package elevate
+
+import "context"
+
+// runElevated reports that there is no elevation prompt.
+func runElevated(ctx context.Context, command, dir string) (Result, error) {
+	return Result{}, ErrUnsupported
+}
+
+// runAskpass reports that there is no elevation prompt.
+func runAskpass(ctx context.Context, command, dir string) (Result, error) {
+	return Result{}, ErrUnsupported
+}
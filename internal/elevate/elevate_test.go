@@ -0,0 +1,99 @@
+package elevate
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		goos, command string
+		want          Plan
+		ok            bool
+	}{
+		{"linux", "sudo apt-get install -y ripgrep", Plan{"apt-get install -y ripgrep", MethodElevate}, true},
+		{"linux", "sudo -E apt install jq", Plan{"apt install jq", MethodElevate}, true},
+		{"linux", "sudo -E apt update && sudo apt install jq", Plan{}, false},
+		{"linux", "rm -rf x; apt install y", Plan{}, false},
+		{"linux", "apt install y || rm -rf x", Plan{}, false},
+		{"linux", "apt install $(cat list)", Plan{}, false},
+		{"linux", "apt install `cat list`", Plan{}, false},
+		{"linux", "apt install y > /etc/passwd", Plan{}, false},
+		{"linux", "curl example.com/x | apt install", Plan{}, false},
+		{"linux", "apt install y\nrm -rf x", Plan{}, false},
+		{"linux", "echo apt install y", Plan{}, false},
+		{"linux", "sudo -u bob apt install y", Plan{}, false},
+		{"linux", "dnf -y install git", Plan{"dnf -y install git", MethodElevate}, true},
+		{"linux", "sudo pacman -S htop", Plan{"pacman -S htop", MethodElevate}, true},
+		{"linux", "apt list --installed", Plan{}, false},
+		{"linux", "sudo rm file", Plan{}, false},
+		{"linux", "pip install requests", Plan{}, false},
+		{"darwin", "sudo installer -pkg Foo.pkg -target /", Plan{"installer -pkg Foo.pkg -target /", MethodElevate}, true},
+		{"darwin", "brew install --cask firefox", Plan{"brew install --cask firefox", MethodAskpass}, true},
+		{"darwin", "brew install wget", Plan{}, false},
+		{"windows", "choco install -y nodejs", Plan{"choco install -y nodejs", MethodElevate}, true},
+		{"windows", "winget install Git.Git --scope machine", Plan{"winget install Git.Git --scope machine", MethodElevate}, true},
+		{"windows", "winget install Git.Git", Plan{}, false},
+		{"windows", "del x & choco install -y nodejs", Plan{}, false},
+		{"freebsd", "sudo apt install jq", Plan{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := detect(tt.goos, tt.command)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("detect(%s, %q) = %+v, %v; want %+v, %v", tt.goos, tt.command, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// stubCommand replaces runCommand for the test with one returning output
+// and err, and returns the commands that would have run.
+func stubCommand(t *testing.T, output string, err error) *[]*exec.Cmd {
+	t.Helper()
+	var ran []*exec.Cmd
+	original := runCommand
+	runCommand = func(cmd *exec.Cmd) ([]byte, error) {
+		ran = append(ran, cmd)
+		return []byte(output), err
+	}
+	t.Cleanup(func() { runCommand = original })
+	return &ran
+}
+
+func TestRun_RefusesCompoundCommands(t *testing.T) {
+	ran := stubCommand(t, "", nil)
+	_, err := Run(context.Background(), Plan{Command: "rm -rf x; apt install y", Method: MethodElevate}, t.TempDir())
+	if !errors.Is(err, ErrCompound) || len(*ran) != 0 {
+		t.Errorf("Run() error = %v with %d commands run, want ErrCompound and none", err, len(*ran))
+	}
+}
+
+func TestRun(t *testing.T) {
+	ran := stubCommand(t, "Setting up jq", nil)
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	t.Cleanup(func() { lookPath = exec.LookPath })
+	dir := t.TempDir()
+
+	result, err := Run(context.Background(), Plan{Command: "apt install jq", Method: MethodElevate}, dir)
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip("no elevation prompt on this system")
+	}
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	// Windows passes the command in a batch file and reads output from a file
+	if runtime.GOOS != "windows" {
+		if !strings.Contains(result.Output, "Setting up jq") {
+			t.Errorf("Expected the command's output, got %q", result.Output)
+		}
+		if len(*ran) != 1 || !strings.Contains(strings.Join((*ran)[0].Args, " "), "apt install jq") {
+			t.Errorf("Expected one elevated command running the installer, got %v", *ran)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package elevate
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// uacScript starts the batch file elevated, waits for it, and exits with
+// its status. Paths come from the environment so they need no escaping. A
+// dismissed UAC prompt makes Start-Process fail with exit status 1223.
+const uacScript = `
+try {
+  $p = Start-Process -FilePath cmd.exe -ArgumentList '/C', ('"' + $env:AGENT_DESKTOP_SCRIPT + '" > "' + $env:AGENT_DESKTOP_OUTPUT + '" 2>&1') -Verb RunAs -Wait -PassThru -WindowStyle Hidden
+  exit $p.ExitCode
+} catch {
+  exit 1223
+}
+`
+
+// uacCancelled is ERROR_CANCELLED, reported when the UAC prompt is
+// dismissed.
+const uacCancelled = 1223
+
+// runElevated runs the command elevated after a UAC prompt. Elevated
+// processes can't share the app's console, so the command runs from a
+// batch file and its output is read back from a file.
+func runElevated(ctx context.Context, command, dir string) (Result, error) {
+	tmp, err := os.MkdirTemp("", "agent-desktop-elevate")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(tmp)
+
+	script := filepath.Join(tmp, "run.cmd")
+	output := filepath.Join(tmp, "output.txt")
+	batch := "@echo off\r\ncd /d \"" + dir + "\"\r\n" + command + "\r\n"
+	if err := os.WriteFile(script, []byte(batch), 0600); err != nil {
+		return Result{}, err
+	}
+
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", uacScript)
+	cmd.Env = append(os.Environ(), "AGENT_DESKTOP_SCRIPT="+script, "AGENT_DESKTOP_OUTPUT="+output)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+
+	_, err = runCommand(cmd)
+	code := 0
+	if err != nil {
+		if code = exitCode(err); code < 0 {
+			return Result{}, err
+		}
+		if code == uacCancelled {
+			return Result{}, ErrCancelled
+		}
+	}
+	data, _ := os.ReadFile(output)
+	return Result{Output: strings.TrimRight(string(data), "\r\n"), ExitCode: code}, nil
+}
+
+// runAskpass is not needed on Windows, where installers run elevated.
+func runAskpass(ctx context.Context, command, dir string) (Result, error) {
+	return runElevated(ctx, command, dir)
+}
@@ -0,0 +1,342 @@
+// Package email gives the agent tools to search the user's mailbox over
+// IMAP and to send mail over SMTP. Both speak TLS only, search opens
+// folders read-only, and every message sent waits for the user's approval.
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+)
+
+// Source is the tools package source email tools are registered under.
+const Source = "email"
+
+const (
+	dialTimeout = 30 * time.Second
+	callTimeout = 2 * time.Minute
+
+	maxResults     = 50
+	fetchBytes     = 64 << 10 // How much of each found message to fetch
+	previewLength  = 300
+	maxAttachments = 10 << 20 // Total size of a message's attachments
+)
+
+// Register makes the email tools the settings configure available to the
+// agent, replacing any registered before. Tools named like another
+// source's are left out and reported.
+func Register(s config.EmailSettings) error {
+	if !s.Enabled() {
+		return tools.SetExternalTools(Source, nil)
+	}
+	return tools.SetExternalTools(Source, Tools(s))
+}
+
+// Tools returns search_email when the settings name an IMAP server and
+// send_email when they name an SMTP server.
+func Tools(s config.EmailSettings) []tools.ExternalTool {
+	var list []tools.ExternalTool
+	if s.IMAPServer != "" {
+		list = append(list, tools.ExternalTool{
+			Definition: definition("search_email",
+				"Search the user's mailbox, newest messages first. Searching doesn't mark messages as read.",
+				map[string]interface{}{
+					"query":       map[string]interface{}{"type": "string", "description": "Text anywhere in the message"},
+					"from":        map[string]interface{}{"type": "string", "description": "Text in the sender's name or address"},
+					"subject":     map[string]interface{}{"type": "string", "description": "Text in the subject"},
+					"since":       map[string]interface{}{"type": "string", "description": "Only messages on or after this date, as 'YYYY-MM-DD'"},
+					"unread_only": map[string]interface{}{"type": "boolean", "description": "Only unread messages"},
+					"folder":      map[string]interface{}{"type": "string", "description": "Folder to search. Default is INBOX."},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Most messages to return, up to %d. Default is 10.", maxResults),
+						"default":     10,
+					},
+					"full_body": map[string]interface{}{"type": "boolean", "description": "Return whole message bodies instead of previews"},
+				}),
+			Execute: func(args map[string]interface{}) tools.ToolResult {
+				return searchEmail(s, args)
+			},
+		})
+	}
+	if s.SMTPServer != "" {
+		list = append(list, tools.ExternalTool{
+			Definition: definition("send_email",
+				"Send an email from the user's address. The user is asked to approve every message, and only allowed recipients can be written to.",
+				map[string]interface{}{
+					"to": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Recipient addresses. Default is the user's own address.",
+					},
+					"cc": map[string]interface{}{
+						"type":  "array",
+						"items": map[string]interface{}{"type": "string"},
+					},
+					"subject": map[string]interface{}{"type": "string"},
+					"body":    map[string]interface{}{"type": "string", "description": "Plain text body"},
+					"attachments": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Paths of files to attach",
+					},
+				}, "subject", "body"),
+			Execute: func(args map[string]interface{}) tools.ToolResult {
+				return sendEmail(s, args)
+			},
+			RequireApproval: true,
+		})
+	}
+	return list
+}
+
+func definition(name, description string, properties map[string]interface{}, required ...string) tools.ToolDefinition {
+	if required == nil {
+		required = []string{}
+	}
+	return tools.ToolDefinition{
+		Type: "function",
+		Function: tools.ToolFunction{
+			Name:        name,
+			Description: description,
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": properties,
+				"required":   required,
+			},
+		},
+	}
+}
+
+// searchCriteria turns search_email's arguments into IMAP search keys.
+func searchCriteria(args map[string]interface{}) ([]interface{}, error) {
+	var criteria []interface{}
+	for _, key := range []struct{ arg, key string }{{"query", "TEXT"}, {"from", "FROM"}, {"subject", "SUBJECT"}} {
+		if v, _ := args[key.arg].(string); strings.TrimSpace(v) != "" {
+			criteria = append(criteria, key.key, imapString(strings.TrimSpace(v)))
+		}
+	}
+	if v, _ := args["since"].(string); v != "" {
+		since, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, fmt.Errorf("since must be a date like 2025-03-10, not %q", v)
+		}
+		criteria = append(criteria, "SINCE", imapDate(since))
+	}
+	if unread, _ := args["unread_only"].(bool); unread {
+		criteria = append(criteria, "UNSEEN")
+	}
+	if len(criteria) == 0 {
+		criteria = append(criteria, "ALL")
+	}
+	return criteria, nil
+}
+
+func searchEmail(s config.EmailSettings, args map[string]interface{}) tools.ToolResult {
+	criteria, err := searchCriteria(args)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: err.Error()}
+	}
+	limit := 10
+	if v, ok := args["limit"].(float64); ok {
+		limit = int(v)
+	}
+	if limit < 1 || limit > maxResults {
+		return tools.ToolResult{Success: false, Error: fmt.Sprintf("limit must be between 1 and %d", maxResults)}
+	}
+	folder, _ := args["folder"].(string)
+	if folder == "" {
+		folder = "INBOX"
+	}
+	fullBody, _ := args["full_body"].(bool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	c, err := dialIMAP(ctx, s.IMAPServer, s.Login(), s.Password)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: "failed to connect to the mail server: " + err.Error()}
+	}
+	defer c.Close()
+
+	messages, total, err := search(c, folder, criteria, limit)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: "search failed: " + err.Error()}
+	}
+	return tools.ToolResult{Success: true, Output: formatResults(messages, total, fullBody)}
+}
+
+// search finds the newest limit messages in folder matching criteria. It
+// also returns how many matched in all.
+func search(c *imapConn, folder string, criteria []interface{}, limit int) ([]Message, int, error) {
+	if err := c.examine(folder); err != nil {
+		return nil, 0, fmt.Errorf("can't open %s: %w", folder, err)
+	}
+	uids, err := c.search(criteria...)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] > uids[j] })
+	total := len(uids)
+	if len(uids) > limit {
+		uids = uids[:limit]
+	}
+	if len(uids) == 0 {
+		return nil, 0, nil
+	}
+
+	raw, err := c.fetch(uids, fetchBytes)
+	if err != nil {
+		return nil, 0, err
+	}
+	messages := make([]Message, 0, len(uids))
+	for _, uid := range uids {
+		if data, ok := raw[uid]; ok {
+			messages = append(messages, parseMessage(uid, data))
+		}
+	}
+	return messages, total, nil
+}
+
+func formatResults(messages []Message, total int, fullBody bool) string {
+	if len(messages) == 0 {
+		return "No messages found."
+	}
+	var b strings.Builder
+	if total > len(messages) {
+		fmt.Fprintf(&b, "Newest %d of %d messages:\n\n", len(messages), total)
+	}
+	for i, m := range messages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "[UID %d]", m.UID)
+		if !m.Date.IsZero() {
+			fmt.Fprintf(&b, " %s", m.Date.Local().Format("Mon Jan 2, 2006 15:04"))
+		}
+		fmt.Fprintf(&b, "\nFrom: %s\nSubject: %s\n", m.From, m.Subject)
+		body := m.Body
+		if !fullBody {
+			body = preview(body)
+		}
+		b.WriteString(body)
+	}
+	return b.String()
+}
+
+// preview shortens a body to a few lines of text.
+func preview(body string) string {
+	body = strings.Join(strings.Fields(body), " ")
+	if r := []rune(body); len(r) > previewLength {
+		return string(r[:previewLength]) + "…"
+	}
+	return body
+}
+
+// compose builds the message send_email's arguments describe, refusing
+// recipients the settings don't allow.
+func compose(s config.EmailSettings, args map[string]interface{}, cwd string) (Outgoing, error) {
+	msg := Outgoing{From: s.Address}
+	msg.Subject, _ = args["subject"].(string)
+	msg.Body, _ = args["body"].(string)
+	if strings.TrimSpace(msg.Subject) == "" && strings.TrimSpace(msg.Body) == "" {
+		return msg, errors.New("send_email needs a subject or a body")
+	}
+
+	var err error
+	if msg.To, err = recipients(s, args["to"]); err != nil {
+		return msg, err
+	}
+	if len(msg.To) == 0 {
+		msg.To = []string{s.Address}
+	}
+	if msg.Cc, err = recipients(s, args["cc"]); err != nil {
+		return msg, err
+	}
+
+	total := 0
+	for _, path := range stringList(args["attachments"]) {
+		path = tools.ExpandPath(path, cwd)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return msg, fmt.Errorf("can't attach %s: %w", path, err)
+		}
+		if total += len(data); total > maxAttachments {
+			return msg, fmt.Errorf("attachments are limited to %d MB in all", maxAttachments>>20)
+		}
+		msg.Attachments = append(msg.Attachments, Attachment{Name: filepath.Base(path), Data: data})
+	}
+	return msg, nil
+}
+
+// recipients parses a list of addresses and checks each is allowed.
+func recipients(s config.EmailSettings, v interface{}) ([]string, error) {
+	var list []string
+	for _, entry := range stringList(v) {
+		addr, err := mail.ParseAddress(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q", entry)
+		}
+		if !s.AllowsRecipient(addr.Address) {
+			return nil, fmt.Errorf("%s isn't an allowed recipient; the user can add it to email.allowed_recipients in settings", addr.Address)
+		}
+		list = append(list, addr.Address)
+	}
+	return list, nil
+}
+
+// stringList reads a tool argument given as a list of strings, or as a
+// single comma-separated string.
+func stringList(v interface{}) []string {
+	var list []string
+	switch v := v.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				list = append(list, strings.TrimSpace(s))
+			}
+		}
+	}
+	return list
+}
+
+func sendEmail(s config.EmailSettings, args map[string]interface{}) tools.ToolResult {
+	msg, err := compose(s, args, tools.GetSession().GetCWD())
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: err.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	c, err := dialSMTP(ctx, s.SMTPServer)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: "failed to connect to the mail server: " + err.Error()}
+	}
+	defer c.Close()
+
+	var auth smtp.Auth
+	if s.Password != "" {
+		host, _, _ := net.SplitHostPort(s.SMTPServer)
+		auth = smtp.PlainAuth("", s.Login(), s.Password, host)
+	}
+	if err := send(c, auth, msg); err != nil {
+		return tools.ToolResult{Success: false, Error: "failed to send: " + err.Error()}
+	}
+	return tools.ToolResult{Success: true, Output: fmt.Sprintf("Sent %q to %s", msg.Subject, strings.Join(append(msg.To, msg.Cc...), ", "))}
+}
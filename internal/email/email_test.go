@@ -0,0 +1,110 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+)
+
+func TestRegister(t *testing.T) {
+	defer tools.SetExternalTools(Source, nil)
+
+	s := config.EmailSettings{Address: "me@example.com", IMAPServer: "imap.example.com:993", SMTPServer: "smtp.example.com:465"}
+	if err := Register(s); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if !tools.RequiresApproval("send_email") {
+		t.Error("send_email should always need approval")
+	}
+	if tools.RequiresApproval("search_email") {
+		t.Error("search_email shouldn't need approval")
+	}
+
+	s.SMTPServer = ""
+	list := Tools(s)
+	if len(list) != 1 || list[0].Definition.Function.Name != "search_email" {
+		t.Errorf("tools without an SMTP server = %v", list)
+	}
+
+	Register(config.EmailSettings{})
+	if tools.RequiresApproval("send_email") {
+		t.Error("send_email should be gone once email is turned off")
+	}
+}
+
+func TestSearchCriteria(t *testing.T) {
+	criteria, err := searchCriteria(map[string]interface{}{"query": " invoice ", "since": "2025-03-01", "unread_only": true})
+	if err != nil {
+		t.Fatalf("searchCriteria failed: %v", err)
+	}
+	want := []interface{}{"TEXT", imapString("invoice"), "SINCE", "1-Mar-2025", "UNSEEN"}
+	if len(criteria) != len(want) {
+		t.Fatalf("criteria = %v, want %v", criteria, want)
+	}
+	for i := range want {
+		if criteria[i] != want[i] {
+			t.Errorf("criteria[%d] = %v, want %v", i, criteria[i], want[i])
+		}
+	}
+
+	if criteria, _ := searchCriteria(map[string]interface{}{}); len(criteria) != 1 || criteria[0] != "ALL" {
+		t.Errorf("criteria with no arguments = %v", criteria)
+	}
+	if _, err := searchCriteria(map[string]interface{}{"since": "last week"}); err == nil {
+		t.Error("expected an error for a bad date")
+	}
+}
+
+func TestCompose(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "report.csv"), []byte("a,b\n"), 0644)
+	s := config.EmailSettings{Address: "me@example.com", AllowedRecipients: []string{"ann@example.com", "@team.example.com"}}
+
+	msg, err := compose(s, map[string]interface{}{
+		"to":          []interface{}{"Ann <ann@example.com>", "joe@team.example.com"},
+		"subject":     "Report",
+		"body":        "Here it is",
+		"attachments": []interface{}{"report.csv"},
+	}, dir)
+	if err != nil {
+		t.Fatalf("compose failed: %v", err)
+	}
+	if strings.Join(msg.To, ",") != "ann@example.com,joe@team.example.com" || msg.From != "me@example.com" {
+		t.Errorf("message = %+v", msg)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Name != "report.csv" {
+		t.Errorf("attachments = %+v", msg.Attachments)
+	}
+
+	msg, err = compose(s, map[string]interface{}{"subject": "Note to self"}, dir)
+	if err != nil || len(msg.To) != 1 || msg.To[0] != "me@example.com" {
+		t.Errorf("default recipient: %+v, %v", msg.To, err)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"to": []interface{}{"stranger@example.org"}, "subject": "Hi"},
+		{"cc": "ann@example.com, eve@example.org", "subject": "Hi"},
+		{"to": "not an address", "subject": "Hi"},
+		{"subject": "Hi", "attachments": []interface{}{"missing.txt"}},
+		{"to": []interface{}{"ann@example.com"}},
+	} {
+		if _, err := compose(s, args, dir); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
+
+func TestFormatResults(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	out := formatResults([]Message{{UID: 5, From: "ann@example.com", Subject: "Hi", Body: long}}, 12, false)
+	if !strings.HasPrefix(out, "Newest 1 of 12 messages:") || !strings.Contains(out, "[UID 5]") || !strings.HasSuffix(out, "…") {
+		t.Errorf("results = %s", out)
+	}
+	if out := formatResults(nil, 0, false); out != "No messages found." {
+		t.Errorf("empty results = %s", out)
+	}
+}
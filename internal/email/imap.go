@@ -0,0 +1,430 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLiteral bounds a single string the IMAP server sends.
+const maxLiteral = 8 << 20
+
+// imapConn is a minimal IMAP4rev1 (RFC 3501) client: enough to log in,
+// open a folder read-only, search it, and fetch messages.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// dialIMAP connects and logs in to server (host:port): with TLS on port
+// 993 and with STARTTLS otherwise. Connections without TLS are refused.
+func dialIMAP(ctx context.Context, server, username, password string) (*imapConn, error) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	if port == "993" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", server)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := newIMAPConn(conn)
+	if err == nil && port != "993" {
+		err = c.startTLS(host)
+	}
+	if err == nil {
+		err = c.login(username, password)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newIMAPConn reads the server's greeting on conn.
+func newIMAPConn(conn net.Conn) (*imapConn, error) {
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	if len(greeting) < 2 || greeting[0] != "*" || !isAtom(greeting[1], "OK") {
+		return nil, fmt.Errorf("unexpected IMAP greeting: %s", describe(greeting))
+	}
+	return c, nil
+}
+
+func (c *imapConn) startTLS(host string) error {
+	if _, err := c.command("STARTTLS"); err != nil {
+		return fmt.Errorf("the IMAP server doesn't support STARTTLS, which is required: %w", err)
+	}
+	tlsConn := tls.Client(c.conn, &tls.Config{ServerName: host})
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.conn, c.r = tlsConn, bufio.NewReader(tlsConn)
+	return nil
+}
+
+func (c *imapConn) login(username, password string) error {
+	if _, err := c.command("LOGIN", imapString(username), imapString(password)); err != nil {
+		return fmt.Errorf("IMAP login failed: %w", err)
+	}
+	return nil
+}
+
+// Close logs out and closes the connection.
+func (c *imapConn) Close() error {
+	c.command("LOGOUT")
+	return c.conn.Close()
+}
+
+// examine opens a folder read-only, so fetching doesn't mark mail as read.
+func (c *imapConn) examine(folder string) error {
+	_, err := c.command("EXAMINE", imapString(folder))
+	return err
+}
+
+// search returns the UIDs of the messages matching criteria, oldest first.
+func (c *imapConn) search(criteria ...interface{}) ([]uint32, error) {
+	args := []interface{}{"UID", "SEARCH"}
+	for _, arg := range criteria {
+		if s, ok := arg.(imapString); ok && !isASCII(string(s)) {
+			args = append(args, "CHARSET", "UTF-8")
+			break
+		}
+	}
+	responses, err := c.command(append(args, criteria...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var uids []uint32
+	for _, resp := range responses {
+		if len(resp) < 2 || !isAtom(resp[1], "SEARCH") {
+			continue
+		}
+		for _, field := range resp[2:] {
+			if n, err := strconv.ParseUint(atomText(field), 10, 32); err == nil {
+				uids = append(uids, uint32(n))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns up to limit bytes of each message, keyed by UID.
+func (c *imapConn) fetch(uids []uint32, limit int) (map[uint32][]byte, error) {
+	set := make([]string, len(uids))
+	for i, uid := range uids {
+		set[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+	responses, err := c.command("UID", "FETCH", strings.Join(set, ","), fmt.Sprintf("(UID BODY.PEEK[]<0.%d>)", limit))
+	if err != nil {
+		return nil, err
+	}
+
+	messages := map[uint32][]byte{}
+	for _, resp := range responses {
+		if len(resp) < 4 || !isAtom(resp[2], "FETCH") {
+			continue
+		}
+		items, ok := resp[3].([]interface{})
+		if !ok {
+			continue
+		}
+		var uid uint32
+		var body []byte
+		for i := 0; i+1 < len(items); i += 2 {
+			key := strings.ToUpper(atomText(items[i]))
+			switch {
+			case key == "UID":
+				n, _ := strconv.ParseUint(atomText(items[i+1]), 10, 32)
+				uid = uint32(n)
+			case strings.HasPrefix(key, "BODY["):
+				body, _ = items[i+1].([]byte)
+			}
+		}
+		if uid != 0 {
+			messages[uid] = body
+		}
+	}
+	return messages, nil
+}
+
+// imapString is a command argument sent as a quoted string or literal.
+type imapString string
+
+// atom is an unquoted word in a server response.
+type atom string
+
+// command sends a command and returns the untagged responses that arrived
+// before it completed. Arguments are strings sent as they are, and
+// imapStrings.
+func (c *imapConn) command(args ...interface{}) ([][]interface{}, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%03d", c.tag)
+
+	var line bytes.Buffer
+	line.WriteString(tag)
+	for _, arg := range args {
+		line.WriteByte(' ')
+		switch v := arg.(type) {
+		case imapString:
+			if isQuotable(string(v)) {
+				line.WriteString(`"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(string(v)) + `"`)
+				continue
+			}
+			// Send a literal once the server is ready for it
+			fmt.Fprintf(&line, "{%d}\r\n", len(v))
+			if _, err := c.conn.Write(line.Bytes()); err != nil {
+				return nil, err
+			}
+			line.Reset()
+			resp, err := c.readResponse()
+			if err != nil {
+				return nil, err
+			}
+			if len(resp) == 0 || resp[0] != "+" {
+				return nil, fmt.Errorf("IMAP server refused the command: %s", describe(resp))
+			}
+			line.WriteString(string(v))
+		default:
+			fmt.Fprint(&line, v)
+		}
+	}
+	line.WriteString("\r\n")
+	if _, err := c.conn.Write(line.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var untagged [][]interface{}
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if len(resp) == 0 || resp[0] != atom(tag) {
+			untagged = append(untagged, resp)
+			continue
+		}
+		if len(resp) < 2 || !isAtom(resp[1], "OK") {
+			return nil, errors.New(describe(resp[1:]))
+		}
+		return untagged, nil
+	}
+}
+
+// readResponse reads one response line, with any literals it contains,
+// into its fields: atoms, strings ([]byte), and lists ([]interface{}). The
+// first field is "*", "+", or a command's tag.
+func (c *imapConn) readResponse() ([]interface{}, error) {
+	first, err := c.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if first == '+' {
+		// A continuation request: the rest of the line is free text
+		if _, err := c.r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		return []interface{}{"+"}, nil
+	}
+	c.r.UnreadByte()
+
+	fields, err := c.readList(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		if a, ok := fields[0].(atom); ok && a == "*" {
+			fields[0] = "*"
+		}
+	}
+	return fields, nil
+}
+
+// readList reads fields up to the end of the line, or up to the closing
+// parenthesis of a list when depth > 0.
+func (c *imapConn) readList(depth int) ([]interface{}, error) {
+	var fields []interface{}
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case b == ' ':
+		case b == '\r':
+		case b == '\n':
+			if depth > 0 {
+				return nil, errors.New("unexpected end of line in IMAP response")
+			}
+			return fields, nil
+		case b == ')' && depth > 0:
+			return fields, nil
+		case b == '(':
+			list, err := c.readList(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, list)
+		case b == '"':
+			s, err := c.readQuoted()
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, s)
+		case b == '{':
+			s, err := c.readLiteral()
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, s)
+		default:
+			c.r.UnreadByte()
+			a, err := c.readAtom()
+			if err != nil {
+				return nil, err
+			}
+			if depth == 0 && len(fields) >= 2 && (isAtom(fields[1], "OK") || isAtom(fields[1], "NO") || isAtom(fields[1], "BAD") || isAtom(fields[1], "BYE")) {
+				// The rest of a status response is human-readable text
+				rest, err := c.r.ReadString('\n')
+				if err != nil {
+					return nil, err
+				}
+				return append(fields, atom(string(a)+strings.TrimRight(rest, "\r\n"))), nil
+			}
+			fields = append(fields, a)
+		}
+	}
+}
+
+// readAtom reads an atom. Brackets are kept whole, so section specifiers
+// such as BODY[HEADER.FIELDS (SUBJECT)] read as one atom.
+func (c *imapConn) readAtom() (atom, error) {
+	var b strings.Builder
+	brackets := 0
+	for {
+		ch, err := c.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case ch == '[':
+			brackets++
+		case ch == ']':
+			brackets--
+		case brackets == 0 && (ch == ' ' || ch == '(' || ch == ')' || ch == '\r' || ch == '\n'):
+			c.r.UnreadByte()
+			return atom(b.String()), nil
+		}
+		b.WriteByte(ch)
+	}
+}
+
+func (c *imapConn) readQuoted() ([]byte, error) {
+	var b []byte
+	for {
+		ch, err := c.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch ch {
+		case '"':
+			return b, nil
+		case '\\':
+			if ch, err = c.r.ReadByte(); err != nil {
+				return nil, err
+			}
+		case '\r', '\n':
+			return nil, errors.New("unterminated string in IMAP response")
+		}
+		b = append(b, ch)
+	}
+}
+
+func (c *imapConn) readLiteral() ([]byte, error) {
+	spec, err := c.r.ReadString('}')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(spec, "}"))
+	if err != nil || n < 0 || n > maxLiteral {
+		return nil, fmt.Errorf("bad literal size in IMAP response: %q", spec)
+	}
+	if line, err := c.r.ReadString('\n'); err != nil || strings.TrimRight(line, "\r\n") != "" {
+		return nil, errors.New("malformed literal in IMAP response")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// isAtom reports whether field is the atom name, ignoring case.
+func isAtom(field interface{}, name string) bool {
+	a, ok := field.(atom)
+	return ok && strings.EqualFold(string(a), name)
+}
+
+// atomText returns the text of an atom or string field.
+func atomText(field interface{}) string {
+	switch v := field.(type) {
+	case atom:
+		return string(v)
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return ""
+}
+
+// describe formats response fields for an error message.
+func describe(fields []interface{}) string {
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts = append(parts, atomText(f))
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// isQuotable reports whether s can be sent as a quoted string.
+func isQuotable(s string) bool {
+	return isASCII(s) && !strings.ContainsAny(s, "\r\n")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 || s[i] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// imapDate formats a date for SINCE and BEFORE search keys.
+func imapDate(t time.Time) string {
+	return t.Format("2-Jan-2006")
+}
@@ -0,0 +1,115 @@
+package email
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// exchange is one command a fake server expects and its reply.
+type exchange struct {
+	command string // The command line the client should send, without the tag
+	reply   string // Lines to send back; "TAG" is replaced with the command's tag
+}
+
+// fakeServer answers the client end of a pipe by script, after greeting
+// it with greeting.
+func fakeServer(t *testing.T, greeting string, script []exchange) net.Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		server.Write([]byte(greeting))
+		var tag string
+		for _, ex := range script {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimRight(line, "\r\n")
+			if t, rest, ok := strings.Cut(command, " "); ok && strings.HasPrefix(t, "a") {
+				tag, command = t, rest
+			} // Otherwise the line is the rest of a literal
+			if command != ex.command {
+				t.Errorf("command = %q, want %q", command, ex.command)
+				server.Write([]byte(tag + " BAD unexpected command\r\n"))
+				continue
+			}
+			server.Write([]byte(strings.ReplaceAll(ex.reply, "TAG", tag)))
+		}
+	}()
+	return client
+}
+
+func TestIMAP_Search(t *testing.T) {
+	message := "From: Ann <ann@example.com>\r\nSubject: Invoice\r\n\r\nPlease pay.\r\n"
+	conn := fakeServer(t, "* OK [CAPABILITY IMAP4rev1] ready\r\n", []exchange{
+		{`LOGIN "me@example.com" "p\"w"`, "TAG OK logged in\r\n"},
+		{`EXAMINE "INBOX"`, "* 3 EXISTS\r\n* OK [UIDVALIDITY 1] ok\r\nTAG OK [READ-ONLY] done\r\n"},
+		{`UID SEARCH FROM "ann" UNSEEN`, "* SEARCH 4 9 7\r\nTAG OK done\r\n"},
+		{`UID FETCH 9,7 (UID BODY.PEEK[]<0.65536>)`,
+			"* 2 FETCH (UID 9 BODY[]<0> {" + strconv.Itoa(len(message)) + "}\r\n" + message + ")\r\n" +
+				"* 3 FETCH (FLAGS (\\Seen) UID 7 BODY[]<0> \"\")\r\n" +
+				"TAG OK done\r\n"},
+		{"LOGOUT", "* BYE bye\r\nTAG OK done\r\n"},
+	})
+
+	c, err := newIMAPConn(conn)
+	if err != nil {
+		t.Fatalf("newIMAPConn failed: %v", err)
+	}
+	if err := c.login("me@example.com", `p"w`); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	messages, total, err := search(c, "INBOX", []interface{}{"FROM", imapString("ann"), "UNSEEN"}, 2)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if total != 3 || len(messages) != 2 {
+		t.Fatalf("got %d of %d messages: %+v", len(messages), total, messages)
+	}
+	if m := messages[0]; m.UID != 9 || m.Subject != "Invoice" || m.Body != "Please pay." {
+		t.Errorf("first message = %+v", m)
+	}
+	if messages[1].UID != 7 {
+		t.Errorf("second message = %+v", messages[1])
+	}
+	c.Close()
+}
+
+func TestIMAP_Literal(t *testing.T) {
+	conn := fakeServer(t, "* OK ready\r\n", []exchange{
+		{`UID SEARCH CHARSET UTF-8 SUBJECT {5}`, "+ go ahead\r\n"},
+		{`café`, "* SEARCH\r\nTAG OK done\r\n"},
+	})
+	c, err := newIMAPConn(conn)
+	if err != nil {
+		t.Fatalf("newIMAPConn failed: %v", err)
+	}
+	uids, err := c.search("SUBJECT", imapString("café"))
+	if err != nil || len(uids) != 0 {
+		t.Errorf("uids = %v, err = %v", uids, err)
+	}
+}
+
+func TestIMAP_Errors(t *testing.T) {
+	conn := fakeServer(t, "* BYE too busy\r\n", nil)
+	if _, err := newIMAPConn(conn); err == nil || !strings.Contains(err.Error(), "too busy") {
+		t.Errorf("newIMAPConn error = %v", err)
+	}
+
+	conn = fakeServer(t, "* OK ready\r\n", []exchange{
+		{`LOGIN "me" "wrong"`, "TAG NO [AUTHENTICATIONFAILED] Invalid credentials\r\n"},
+	})
+	c, err := newIMAPConn(conn)
+	if err != nil {
+		t.Fatalf("newIMAPConn failed: %v", err)
+	}
+	if err := c.login("me", "wrong"); err == nil || !strings.Contains(err.Error(), "Invalid credentials") {
+		t.Errorf("login error = %v", err)
+	}
+}
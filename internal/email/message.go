@@ -0,0 +1,153 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Message is a message found by search_email.
+type Message struct {
+	UID     uint32
+	From    string
+	To      string
+	Subject string
+	Date    time.Time
+	Body    string // The plain text of the message, as far as it was fetched
+}
+
+// headerDecoder decodes RFC 2047 encoded words in headers. Charsets other
+// than UTF-8, ASCII, and Latin-1 are left encoded.
+var headerDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "iso-8859-1") || strings.EqualFold(charset, "windows-1252") {
+			data, err := io.ReadAll(input)
+			return strings.NewReader(latin1(data)), err
+		}
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	},
+}
+
+// parseMessage reads a message, which may be cut off after its first few
+// kilobytes.
+func parseMessage(uid uint32, raw []byte) Message {
+	msg := Message{UID: uid}
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		msg.Body = strings.TrimSpace(string(raw))
+		return msg
+	}
+	decode := func(name string) string {
+		value := m.Header.Get(name)
+		if decoded, err := headerDecoder.DecodeHeader(value); err == nil {
+			return decoded
+		}
+		return value
+	}
+	msg.From = decode("From")
+	msg.To = decode("To")
+	msg.Subject = decode("Subject")
+	msg.Date, _ = m.Header.Date()
+	msg.Body = strings.TrimSpace(textBody(m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), m.Body))
+	return msg
+}
+
+// textBody returns the text of a message part: its text/plain content, or
+// its text/html content without the markup, looking inside multiparts.
+func textBody(contentType, encoding string, body io.Reader) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var plain, rich string
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := r.NextRawPart()
+			if err != nil {
+				break // The end, or where the fetch cut the message off
+			}
+			text := textBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if text == "" {
+				continue
+			}
+			ptype, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			if ptype == "text/html" {
+				rich = first(rich, text)
+			} else {
+				plain = first(plain, text)
+			}
+		}
+		return first(plain, rich)
+	}
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return ""
+	}
+
+	var data []byte
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		data, _ = io.ReadAll(quotedprintable.NewReader(body))
+	case "base64":
+		raw, _ := io.ReadAll(body)
+		// Only decode whole groups, since the fetch may have cut the part off
+		clean := bytes.Join(bytes.Fields(raw), nil)
+		data, _ = base64.StdEncoding.DecodeString(string(clean[:len(clean)/4*4]))
+	default:
+		data, _ = io.ReadAll(body)
+	}
+
+	text := string(data)
+	if charset := strings.ToLower(params["charset"]); charset == "iso-8859-1" || charset == "windows-1252" {
+		text = latin1(data)
+	}
+	text = strings.ToValidUTF8(text, "�")
+	if mediaType == "text/html" {
+		text = stripHTML(text)
+	}
+	return text
+}
+
+var (
+	htmlDropped = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)>`)
+	htmlBreaks  = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/h[1-6]|/li)\b[^>]*>`)
+	htmlTags    = regexp.MustCompile(`<[^>]*>`)
+	blankLines  = regexp.MustCompile(`\n\s*\n\s*\n+`)
+)
+
+// stripHTML reduces HTML to its text.
+func stripHTML(s string) string {
+	s = htmlDropped.ReplaceAllString(s, "")
+	s = htmlBreaks.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTags.ReplaceAllString(s, ""))
+	return blankLines.ReplaceAllString(s, "\n\n")
+}
+
+// latin1 converts ISO 8859-1 bytes to UTF-8.
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// first returns the first non-empty value.
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want Message
+	}{
+		{
+			name: "plain quoted-printable",
+			raw: "From: =?utf-8?q?Jos=C3=A9?= <jose@example.com>\r\n" +
+				"Subject: =?iso-8859-1?q?Caf=E9?=\r\n" +
+				"Date: Mon, 10 Mar 2025 09:00:00 +0000\r\n" +
+				"Content-Type: text/plain; charset=utf-8\r\n" +
+				"Content-Transfer-Encoding: quoted-printable\r\n\r\n" +
+				"Hello=2C a long line that =\r\ncontinues.\r\n",
+			want: Message{From: "José <jose@example.com>", Subject: "Café", Body: "Hello, a long line that continues."},
+		},
+		{
+			name: "multipart prefers plain text",
+			raw: "Subject: Both\r\n" +
+				"Content-Type: multipart/alternative; boundary=b1\r\n\r\n" +
+				"--b1\r\nContent-Type: text/html\r\n\r\n<p>Rich</p>\r\n" +
+				"--b1\r\nContent-Type: text/plain\r\n\r\nPlain\r\n" +
+				"--b1--\r\n",
+			want: Message{Subject: "Both", Body: "Plain"},
+		},
+		{
+			name: "html only",
+			raw: "Subject: Rich\r\nContent-Type: text/html\r\n\r\n" +
+				"<html><head><style>p{}</style></head><body><p>One &amp; two</p><p>Three</p></body></html>",
+			want: Message{Subject: "Rich", Body: "One & two\nThree"},
+		},
+		{
+			name: "attachment skipped",
+			raw: "Subject: Report\r\n" +
+				"Content-Type: multipart/mixed; boundary=\"b2\"\r\n\r\n" +
+				"--b2\r\nContent-Type: text/plain\r\nContent-Transfer-Encoding: base64\r\n\r\nU2VlIGF0dGFjaGVk\r\n" +
+				"--b2\r\nContent-Type: application/pdf\r\nContent-Transfer-Encoding: base64\r\n\r\nJVBERi0=\r\n" +
+				"--b2--\r\n",
+			want: Message{Subject: "Report", Body: "See attached"},
+		},
+		{
+			name: "cut off base64",
+			raw: "Subject: Long\r\nContent-Type: text/plain\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+				"SGVsbG8gdGhlcmUg\r\nd29y",
+			want: Message{Subject: "Long", Body: "Hello there wor"},
+		},
+		{
+			name: "cut off multipart",
+			raw: "Subject: Cut\r\nContent-Type: multipart/alternative; boundary=b3\r\n\r\n" +
+				"--b3\r\nContent-Type: text/plain\r\n\r\nFirst part\r\n--b3\r\nContent-Type: text/ht",
+			want: Message{Subject: "Cut", Body: "First part"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseMessage(1, []byte(tt.raw))
+			if got.From != tt.want.From || got.Subject != tt.want.Subject || got.Body != tt.want.Body {
+				t.Errorf("got From %q, Subject %q, Body %q", got.From, got.Subject, got.Body)
+			}
+		})
+	}
+}
+
+func TestParseMessage_Date(t *testing.T) {
+	m := parseMessage(3, []byte("Date: Mon, 10 Mar 2025 09:00:00 +0100\r\n\r\nHi"))
+	if m.UID != 3 || m.Date.UTC().Hour() != 8 || m.Body != "Hi" {
+		t.Errorf("message = %+v", m)
+	}
+	if m := parseMessage(4, []byte("not a message")); !strings.Contains(m.Body, "not a message") {
+		t.Errorf("unparseable message = %+v", m)
+	}
+}
@@ -0,0 +1,145 @@
+package email
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one message on the server end of a pipe, rejecting
+// recipients in refuse, and records the commands and data it received.
+func fakeSMTP(t *testing.T, refuse string) (net.Conn, <-chan []string, <-chan string) {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	commands, data := make(chan []string, 1), make(chan string, 1)
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		reply := func(s string) { server.Write([]byte(s + "\r\n")) }
+		var seen []string
+		defer func() { commands <- seen }()
+		reply("220 localhost ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			seen = append(seen, line)
+			switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); {
+			case verb == "EHLO":
+				reply("250-localhost\r\n250 AUTH PLAIN")
+			case verb == "AUTH":
+				reply("235 ok")
+			case verb == "RCPT" && refuse != "" && strings.Contains(line, refuse):
+				reply("550 no such user")
+			case verb == "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					b.WriteString(l)
+				}
+				data <- b.String()
+				reply("250 queued")
+			case verb == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return client, commands, data
+}
+
+func TestSend(t *testing.T) {
+	conn, commands, data := fakeSMTP(t, "")
+	c, err := smtp.NewClient(conn, "localhost")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	msg := Outgoing{
+		From:        "me@example.com",
+		To:          []string{"ann@example.com"},
+		Cc:          []string{"bob@example.com"},
+		Subject:     "Résumé",
+		Body:        "Attached.\nThanks",
+		Attachments: []Attachment{{Name: "notes.txt", Data: []byte("some notes")}},
+	}
+	if err := send(c, smtp.PlainAuth("", "me", "secret", "localhost"), msg); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	got := <-commands
+	want := []string{"AUTH PLAIN", "MAIL FROM:<me@example.com>", "RCPT TO:<ann@example.com>", "RCPT TO:<bob@example.com>", "DATA", "QUIT"}
+	for i, prefix := range want {
+		if i+1 >= len(got) || !strings.HasPrefix(got[i+1], prefix) {
+			t.Fatalf("commands = %q, want %q after EHLO", got, want)
+		}
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(<-data))
+	if err != nil {
+		t.Fatalf("sent message doesn't parse: %v", err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); subject != "Résumé" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if m.Header.Get("Cc") != "bob@example.com" || m.Header.Get("Message-ID") == "" {
+		t.Errorf("headers = %v", m.Header)
+	}
+	_, params, _ := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	r := multipart.NewReader(m.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		var body []byte
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			body, _ = io.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+		} else {
+			body, _ = io.ReadAll(p)
+		}
+		parts = append(parts, p.FileName()+":"+string(body))
+	}
+	if len(parts) != 2 || parts[0] != ":Attached.\r\nThanks" || parts[1] != "notes.txt:some notes" {
+		t.Errorf("parts = %q", parts)
+	}
+}
+
+func TestSend_RefusedRecipient(t *testing.T) {
+	conn, _, _ := fakeSMTP(t, "nobody@")
+	c, err := smtp.NewClient(conn, "localhost")
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	err = send(c, nil, Outgoing{From: "me@example.com", To: []string{"nobody@example.com"}, Subject: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "nobody@example.com") {
+		t.Errorf("send error = %v", err)
+	}
+}
+
+func TestOutgoing_Bytes(t *testing.T) {
+	date := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	raw := string(Outgoing{From: "me@example.com", To: []string{"me@example.com"}, Subject: "Plain", Body: "a=b"}.Bytes(date))
+	for _, want := range []string{"Date: Mon, 10 Mar 2025 09:00:00 +0000\r\n", "Subject: Plain\r\n", "Content-Transfer-Encoding: quoted-printable\r\n\r\na=3Db"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("message lacks %q:\n%s", want, raw)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// Package grpcapi serves the Agent gRPC service defined in api/agentpb,
+// alongside the REST API, for Go services and test harnesses that drive the
+// agent programmatically. It uses the same backend and API token as the
+// REST API, and streams app events such as agent steps.
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"agent-desktop/api/agentpb"
+	"agent-desktop/internal/agent"
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/runqueue"
+	"agent-desktop/internal/server"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// subscriberBuffer is how many events a slow stream can fall behind before
+// events are dropped for it.
+const subscriberBuffer = 256
+
+// Server implements the Agent service against a backend.
+type Server struct {
+	agentpb.UnimplementedAgentServer
+
+	backend server.Backend
+	token   string
+
+	mu          sync.Mutex
+	subscribers map[chan *agentpb.Event]struct{}
+}
+
+// New creates a service for backend that accepts calls carrying token.
+func New(backend server.Backend, token string) *Server {
+	return &Server{
+		backend:     backend,
+		token:       token,
+		subscribers: map[chan *agentpb.Event]struct{}{},
+	}
+}
+
+// GRPCServer returns a gRPC server with the service registered and every
+// call checked for the API token.
+func (s *Server) GRPCServer() *grpc.Server {
+	g := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := s.authenticate(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.authenticate(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	agentpb.RegisterAgentServer(g, s)
+	return g
+}
+
+// authenticate checks the "authorization: Bearer" metadata for the token.
+func (s *Server) authenticate(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid API token")
+}
+
+// Publish sends an event to every connected stream. Streams that have
+// fallen too far behind miss the event rather than blocking the app.
+func (s *Server) Publish(name string, data ...interface{}) {
+	if data == nil {
+		data = []interface{}{}
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		slog.Warn("failed to encode event", "event", name, "error", err)
+		return
+	}
+	event := &agentpb.Event{Name: name, DataJson: string(raw)}
+	if name == "agent:step" && len(data) > 0 {
+		event.Step = toStep(data[0])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			slog.Warn("dropping event for slow gRPC client", "event", name)
+		}
+	}
+}
+
+// ListConversations returns a summary of every conversation.
+func (s *Server) ListConversations(ctx context.Context, _ *agentpb.ListConversationsRequest) (*agentpb.ListConversationsResponse, error) {
+	summaries, err := s.backend.ListConversations()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &agentpb.ListConversationsResponse{}
+	for _, sum := range summaries {
+		resp.Conversations = append(resp.Conversations, &agentpb.ConversationSummary{
+			Id:        sum.ID,
+			Title:     sum.Title,
+			CreatedAt: timestamp(sum.CreatedAt),
+			UpdatedAt: timestamp(sum.UpdatedAt),
+			TurnCount: int32(sum.TurnCount),
+			Tags:      sum.Tags,
+			Folder:    sum.Folder,
+			Pinned:    sum.Pinned,
+			Archived:  sum.Archived,
+		})
+	}
+	return resp, nil
+}
+
+// NewConversation starts an empty conversation.
+func (s *Server) NewConversation(ctx context.Context, _ *agentpb.NewConversationRequest) (*agentpb.Conversation, error) {
+	conv, err := s.backend.NewConversation()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toConversation(conv), nil
+}
+
+// GetConversation returns a conversation with its messages.
+func (s *Server) GetConversation(ctx context.Context, req *agentpb.GetConversationRequest) (*agentpb.Conversation, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	conv, err := s.backend.GetConversation(req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toConversation(conv), nil
+}
+
+// SendMessage queues a message, starting a conversation if none is given.
+func (s *Server) SendMessage(ctx context.Context, req *agentpb.SendMessageRequest) (*agentpb.Run, error) {
+	if strings.TrimSpace(req.GetText()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "text is required")
+	}
+	convID := req.GetConversationId()
+	if convID == "" {
+		conv, err := s.backend.NewConversation()
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		convID = conv.ID
+	} else if _, err := s.backend.GetConversation(convID); err != nil {
+		return nil, toStatus(err)
+	}
+
+	item, err := s.backend.SendMessage(convID, req.GetText())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toRun(item), nil
+}
+
+// ListRuns returns queued, running, and finished runs.
+func (s *Server) ListRuns(ctx context.Context, _ *agentpb.ListRunsRequest) (*agentpb.ListRunsResponse, error) {
+	resp := &agentpb.ListRunsResponse{}
+	for _, item := range s.backend.ListRuns() {
+		resp.Runs = append(resp.Runs, toRun(item))
+	}
+	return resp, nil
+}
+
+// GetRun returns one run.
+func (s *Server) GetRun(ctx context.Context, req *agentpb.GetRunRequest) (*agentpb.Run, error) {
+	for _, item := range s.backend.ListRuns() {
+		if item.ID == req.GetId() {
+			return toRun(item), nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "run not found")
+}
+
+// CancelRun removes a queued run or stops a running one.
+func (s *Server) CancelRun(ctx context.Context, req *agentpb.CancelRunRequest) (*agentpb.CancelRunResponse, error) {
+	if err := s.backend.CancelRun(req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &agentpb.CancelRunResponse{}, nil
+}
+
+// StreamEvents sends app events until the client disconnects.
+func (s *Server) StreamEvents(req *agentpb.StreamEventsRequest, stream agentpb.Agent_StreamEventsServer) error {
+	ch := make(chan *agentpb.Event, subscriberBuffer)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	// Let the client know it is subscribed before any event arrives
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-ch:
+			if len(req.GetNames()) > 0 && !slices.Contains(req.GetNames(), event.Name) {
+				continue
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toStatus converts a backend error to a gRPC status.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, server.ErrNotFound), errors.Is(err, conversation.ErrNotFound), errors.Is(err, runqueue.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, conversation.ErrReadOnly):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// timestamp converts t, leaving the zero time unset.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func toConversation(conv *conversation.Conversation) *agentpb.Conversation {
+	pb := &agentpb.Conversation{
+		Id:        conv.ID,
+		Title:     conv.Title,
+		CreatedAt: timestamp(conv.CreatedAt),
+		UpdatedAt: timestamp(conv.UpdatedAt),
+	}
+	for _, msg := range conv.Messages {
+		m := &agentpb.Message{Role: msg.Role, Content: msg.Content, ToolCallId: msg.ToolCallID}
+		for _, tc := range msg.ToolCalls {
+			m.ToolCalls = append(m.ToolCalls, &agentpb.ToolCall{Id: tc.ID, Name: tc.Name, Arguments: tc.Arguments})
+		}
+		pb.Messages = append(pb.Messages, m)
+	}
+	return pb
+}
+
+func toRun(item runqueue.Item) *agentpb.Run {
+	return &agentpb.Run{
+		Id:             item.ID,
+		Task:           item.Task,
+		ConversationId: item.ConversationID,
+		Status:         string(item.Status),
+		Error:          item.Error,
+		EnqueuedAt:     timestamp(item.EnqueuedAt),
+		StartedAt:      timestamp(item.StartedAt),
+		FinishedAt:     timestamp(item.FinishedAt),
+	}
+}
+
+// toStep converts the data of an agent:step event, or returns nil if it
+// isn't a step.
+func toStep(data interface{}) *agentpb.Step {
+	var step agent.Step
+	switch v := data.(type) {
+	case agent.Step:
+		step = v
+	case *agent.Step:
+		step = *v
+	default:
+		return nil
+	}
+
+	pb := &agentpb.Step{
+		StepNumber: int32(step.StepNumber),
+		Type:       step.Type,
+		Content:    step.Content,
+		ToolName:   step.ToolName,
+		ToolCallId: step.ToolCallID,
+	}
+	if step.ToolArgs != nil {
+		args, _ := json.Marshal(step.ToolArgs)
+		pb.ToolArgsJson = string(args)
+	}
+	if r := step.ToolResult; r != nil {
+		pb.ToolResult = &agentpb.ToolResult{Success: r.Success, Output: r.Output, Error: r.Error}
+	}
+	if u := step.Usage; u != nil {
+		pb.Usage = &agentpb.Usage{
+			PromptTokens:     int32(u.PromptTokens),
+			CompletionTokens: int32(u.CompletionTokens),
+			TotalTokens:      int32(u.TotalTokens),
+		}
+	}
+	return pb
+}
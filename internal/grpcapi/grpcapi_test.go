@@ -0,0 +1,194 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"agent-desktop/api/agentpb"
+	"agent-desktop/internal/agent"
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/runqueue"
+	"agent-desktop/internal/tools"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeBackend keeps conversations in memory and records queued messages.
+type fakeBackend struct {
+	mu            sync.Mutex
+	conversations map[string]*conversation.Conversation
+	runs          []runqueue.Item
+}
+
+func (b *fakeBackend) ListConversations() ([]conversation.Summary, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var summaries []conversation.Summary
+	for _, conv := range b.conversations {
+		summaries = append(summaries, conv.ToSummary())
+	}
+	return summaries, nil
+}
+
+func (b *fakeBackend) NewConversation() (*conversation.Conversation, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	conv := conversation.New()
+	b.conversations[conv.ID] = conv
+	return conv, nil
+}
+
+func (b *fakeBackend) GetConversation(id string) (*conversation.Conversation, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	conv, ok := b.conversations[id]
+	if !ok {
+		return nil, conversation.ErrNotFound
+	}
+	return conv, nil
+}
+
+func (b *fakeBackend) SendMessage(conversationID, text string) (runqueue.Item, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.conversations[conversationID].AddMessage(llm.Message{Role: "user", Content: text})
+	item := runqueue.Item{ID: "run-1", Task: text, ConversationID: conversationID, Status: runqueue.StatusQueued, EnqueuedAt: time.Now()}
+	b.runs = append(b.runs, item)
+	return item, nil
+}
+
+func (b *fakeBackend) ListRuns() []runqueue.Item {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.runs
+}
+
+func (b *fakeBackend) CancelRun(id string) error {
+	return runqueue.ErrNotFound
+}
+
+// startServer serves s over an in-memory connection and returns a client
+// and a context carrying token.
+func startServer(t *testing.T, s *Server, token string) (agentpb.AgentClient, context.Context) {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	g := s.GRPCServer()
+	go g.Serve(listener)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return agentpb.NewAgentClient(conn), metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestServer(t *testing.T) {
+	backend := &fakeBackend{conversations: map[string]*conversation.Conversation{}}
+	client, ctx := startServer(t, New(backend, "secret"), "secret")
+
+	run, err := client.SendMessage(ctx, &agentpb.SendMessageRequest{Text: "list files"})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if run.Task != "list files" || run.Status != "queued" || run.EnqueuedAt == nil || run.StartedAt != nil {
+		t.Errorf("run = %+v", run)
+	}
+
+	conv, err := client.GetConversation(ctx, &agentpb.GetConversationRequest{Id: run.ConversationId})
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if len(conv.Messages) != 1 || conv.Messages[0].Content != "list files" {
+		t.Errorf("messages = %+v", conv.Messages)
+	}
+
+	list, err := client.ListConversations(ctx, &agentpb.ListConversationsRequest{})
+	if err != nil || len(list.Conversations) != 1 || list.Conversations[0].Id != conv.Id {
+		t.Errorf("ListConversations = %+v, %v", list, err)
+	}
+	if got, err := client.GetRun(ctx, &agentpb.GetRunRequest{Id: "run-1"}); err != nil || got.Id != "run-1" {
+		t.Errorf("GetRun = %+v, %v", got, err)
+	}
+
+	for name, err := range map[string]error{
+		"GetConversation(missing)": second(client.GetConversation(ctx, &agentpb.GetConversationRequest{Id: "missing"})),
+		"CancelRun(missing)":       second(client.CancelRun(ctx, &agentpb.CancelRunRequest{Id: "missing"})),
+	} {
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("%s error = %v, want NotFound", name, err)
+		}
+	}
+	if _, err := client.SendMessage(ctx, &agentpb.SendMessageRequest{Text: " "}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SendMessage(empty) error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestServer_Unauthenticated(t *testing.T) {
+	backend := &fakeBackend{conversations: map[string]*conversation.Conversation{}}
+	client, ctx := startServer(t, New(backend, "secret"), "wrong")
+
+	if _, err := client.ListRuns(ctx, &agentpb.ListRunsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("ListRuns error = %v, want Unauthenticated", err)
+	}
+	stream, err := client.StreamEvents(ctx, &agentpb.StreamEventsRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("StreamEvents error = %v, want Unauthenticated", err)
+	}
+}
+
+func TestServer_StreamEvents(t *testing.T) {
+	s := New(&fakeBackend{conversations: map[string]*conversation.Conversation{}}, "secret")
+	client, ctx := startServer(t, s, "secret")
+
+	stream, err := client.StreamEvents(ctx, &agentpb.StreamEventsRequest{Names: []string{"agent:step"}})
+	if err != nil {
+		t.Fatalf("StreamEvents failed: %v", err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("Header failed: %v", err)
+	}
+
+	s.Publish("queue:changed", []runqueue.Item{})
+	s.Publish("agent:step", agent.Step{
+		StepNumber: 2,
+		Type:       agent.StepTypeToolResult,
+		ToolName:   "run_command",
+		ToolArgs:   map[string]interface{}{"command": "ls"},
+		ToolResult: &tools.ToolResult{Success: true, Output: "README.md"},
+	})
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if event.Name != "agent:step" || event.Step == nil {
+		t.Fatalf("event = %+v, want the step and not the filtered queue event", event)
+	}
+	if event.Step.StepNumber != 2 || event.Step.ToolArgsJson != `{"command":"ls"}` || event.Step.ToolResult.GetOutput() != "README.md" {
+		t.Errorf("step = %+v", event.Step)
+	}
+}
+
+// second returns the error of a call's results.
+func second[T any](_ T, err error) error {
+	return err
+}
@@ -0,0 +1,133 @@
+// Package hotkey registers system-wide keyboard shortcuts that fire even
+// when the app window is not focused.
+package hotkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupported is returned by Register on platforms without global
+// shortcut support.
+var ErrUnsupported = errors.New("global shortcuts are not supported on this system")
+
+// Modifier is a set of modifier keys.
+type Modifier uint8
+
+// Modifier keys that can be combined in a Hotkey.
+const (
+	ModCtrl Modifier = 1 << iota
+	ModShift
+	ModAlt
+	ModSuper // Windows key on Windows, Command on macOS
+)
+
+// Hotkey is a key combined with modifiers, such as Ctrl+Shift+Space.
+type Hotkey struct {
+	Mods Modifier
+	Key  string // Upper-case letter or digit, "Space", or "F1" to "F12"
+}
+
+// modifierNames maps accepted modifier spellings to modifiers.
+var modifierNames = map[string]Modifier{
+	"ctrl":    ModCtrl,
+	"control": ModCtrl,
+	"shift":   ModShift,
+	"alt":     ModAlt,
+	"option":  ModAlt,
+	"super":   ModSuper,
+	"win":     ModSuper,
+	"cmd":     ModSuper,
+	"command": ModSuper,
+}
+
+// Parse reads a shortcut such as "Ctrl+Shift+Space" or "alt+f2". At least
+// one modifier is required so the shortcut doesn't swallow normal typing.
+func Parse(s string) (Hotkey, error) {
+	parts := strings.Split(s, "+")
+	var hk Hotkey
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i < len(parts)-1 {
+			mod, ok := modifierNames[strings.ToLower(part)]
+			if !ok {
+				return Hotkey{}, fmt.Errorf("unknown modifier %q in shortcut %q", part, s)
+			}
+			hk.Mods |= mod
+			continue
+		}
+
+		key, ok := normalizeKey(part)
+		if !ok {
+			return Hotkey{}, fmt.Errorf("unsupported key %q in shortcut %q", part, s)
+		}
+		hk.Key = key
+	}
+	if hk.Mods == 0 {
+		return Hotkey{}, fmt.Errorf("shortcut %q needs at least one modifier", s)
+	}
+	return hk, nil
+}
+
+// String formats the hotkey the way Parse accepts it.
+func (hk Hotkey) String() string {
+	var parts []string
+	for _, m := range []struct {
+		mod  Modifier
+		name string
+	}{{ModCtrl, "Ctrl"}, {ModShift, "Shift"}, {ModAlt, "Alt"}, {ModSuper, "Super"}} {
+		if hk.Mods&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(append(parts, hk.Key), "+")
+}
+
+// normalizeKey returns the canonical name of a supported key.
+func normalizeKey(key string) (string, bool) {
+	upper := strings.ToUpper(key)
+	switch {
+	case len(upper) == 1 && (upper[0] >= 'A' && upper[0] <= 'Z' || upper[0] >= '0' && upper[0] <= '9'):
+		return upper, true
+	case upper == "SPACE":
+		return "Space", true
+	case functionKey(upper) > 0:
+		return upper, true
+	}
+	return "", false
+}
+
+// functionKey returns n for "Fn" with n between 1 and 12, or 0.
+func functionKey(key string) int {
+	var n int
+	if _, err := fmt.Sscanf(key, "F%d", &n); err != nil || n < 1 || n > 12 || key != fmt.Sprintf("F%d", n) {
+		return 0
+	}
+	return n
+}
+
+// Registration is an active global shortcut. A nil *Registration ignores
+// Unregister.
+type Registration struct {
+	stop func()
+}
+
+// Unregister releases the shortcut.
+func (r *Registration) Unregister() {
+	if r == nil || r.stop == nil {
+		return
+	}
+	r.stop()
+	r.stop = nil
+}
+
+// Register makes fn run whenever hk is pressed anywhere on the desktop. fn
+// runs on a background goroutine.
+func Register(hk Hotkey, fn func()) (*Registration, error) {
+	stop, err := register(hk, fn)
+	if err != nil {
+		return nil, err
+	}
+	return &Registration{stop: stop}, nil
+}
@@ -0,0 +1,119 @@
+package hotkey
+
+import (
+	"fmt"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// X11 modifier masks.
+const (
+	x11Shift   = 1 << 0
+	x11Lock    = 1 << 1 // Caps Lock
+	x11Control = 1 << 2
+	x11Mod1    = 1 << 3 // Alt
+	x11Mod2    = 1 << 4 // Num Lock
+	x11Mod4    = 1 << 6 // Super
+)
+
+// lockMasks are grabbed in addition to the shortcut's modifiers so it still
+// fires with Caps Lock or Num Lock on.
+var lockMasks = []uint16{0, x11Lock, x11Mod2, x11Lock | x11Mod2}
+
+// register grabs the key on the X11 root window. Wayland sessions without
+// XWayland have no X display and get ErrUnsupported.
+func register(hk Hotkey, fn func()) (func(), error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	root := xproto.Setup(conn).DefaultScreen(conn).Root
+
+	keycode, err := keycodeFor(conn, keysym(hk.Key))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	mods := x11Modifiers(hk.Mods)
+	for _, lock := range lockMasks {
+		err := xproto.GrabKeyChecked(conn, true, root, mods|lock, keycode,
+			xproto.GrabModeAsync, xproto.GrabModeAsync).Check()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("shortcut %s is already in use: %v", hk, err)
+		}
+	}
+
+	go func() {
+		for {
+			ev, xerr := conn.WaitForEvent()
+			if ev == nil && xerr == nil {
+				return // Connection closed
+			}
+			if press, ok := ev.(xproto.KeyPressEvent); ok && press.Detail == keycode {
+				fn()
+			}
+		}
+	}()
+
+	return func() {
+		for _, lock := range lockMasks {
+			xproto.UngrabKey(conn, keycode, root, mods|lock)
+		}
+		conn.Close()
+	}, nil
+}
+
+// x11Modifiers converts modifiers to an X11 modifier mask.
+func x11Modifiers(mods Modifier) uint16 {
+	var mask uint16
+	if mods&ModCtrl != 0 {
+		mask |= x11Control
+	}
+	if mods&ModShift != 0 {
+		mask |= x11Shift
+	}
+	if mods&ModAlt != 0 {
+		mask |= x11Mod1
+	}
+	if mods&ModSuper != 0 {
+		mask |= x11Mod4
+	}
+	return mask
+}
+
+// keysym returns the X11 keysym of a key name accepted by Parse.
+func keysym(key string) xproto.Keysym {
+	if n := functionKey(key); n > 0 {
+		return xproto.Keysym(0xffbe + n - 1) // XK_F1
+	}
+	if key == "Space" {
+		return 0x20
+	}
+	c := key[0]
+	if c >= 'A' && c <= 'Z' {
+		c += 'a' - 'A' // Letter keysyms are lower case
+	}
+	return xproto.Keysym(c)
+}
+
+// keycodeFor finds the keycode that produces sym on the current keyboard.
+func keycodeFor(conn *xgb.Conn, sym xproto.Keysym) (xproto.Keycode, error) {
+	setup := xproto.Setup(conn)
+	first := setup.MinKeycode
+	count := byte(setup.MaxKeycode - first + 1)
+
+	mapping, err := xproto.GetKeyboardMapping(conn, first, count).Reply()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read keyboard mapping: %w", err)
+	}
+	per := int(mapping.KeysymsPerKeycode)
+	for i, s := range mapping.Keysyms {
+		if s == sym {
+			return first + xproto.Keycode(i/per), nil
+		}
+	}
+	return 0, fmt.Errorf("no key produces keysym %#x on this keyboard", sym)
+}
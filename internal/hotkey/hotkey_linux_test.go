@@ -0,0 +1,31 @@
+package hotkey
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+func TestKeysym(t *testing.T) {
+	tests := map[string]xproto.Keysym{
+		"A":     'a',
+		"7":     '7',
+		"Space": 0x20,
+		"F1":    0xffbe,
+		"F12":   0xffc9,
+	}
+	for key, want := range tests {
+		if got := keysym(key); got != want {
+			t.Errorf("keysym(%q) = %#x, want %#x", key, got, want)
+		}
+	}
+}
+
+func TestX11Modifiers(t *testing.T) {
+	if got := x11Modifiers(ModCtrl | ModShift); got != x11Control|x11Shift {
+		t.Errorf("x11Modifiers(Ctrl+Shift) = %#x", got)
+	}
+	if got := x11Modifiers(ModAlt | ModSuper); got != x11Mod1|x11Mod4 {
+		t.Errorf("x11Modifiers(Alt+Super) = %#x", got)
+	}
+}
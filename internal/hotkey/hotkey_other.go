@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+
+package hotkey
+
+// register reports that global shortcuts are unsupported. On macOS this
+// needs the Carbon hotkey API, which is not wired up yet.
+func register(hk Hotkey, fn func()) (func(), error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,50 @@
+package hotkey
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Hotkey
+		wantErr bool
+	}{
+		{"Ctrl+Shift+Space", Hotkey{Mods: ModCtrl | ModShift, Key: "Space"}, false},
+		{"alt + f2", Hotkey{Mods: ModAlt, Key: "F2"}, false},
+		{"Cmd+k", Hotkey{Mods: ModSuper, Key: "K"}, false},
+		{"Control+Option+7", Hotkey{Mods: ModCtrl | ModAlt, Key: "7"}, false},
+		{"Space", Hotkey{}, true},    // No modifier
+		{"Ctrl+F13", Hotkey{}, true}, // Unsupported key
+		{"Hyper+A", Hotkey{}, true},  // Unknown modifier
+		{"Ctrl+Tab", Hotkey{}, true}, // Unsupported key
+		{"Ctrl+F01", Hotkey{}, true}, // Not a canonical function key
+		{"", Hotkey{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHotkey_StringRoundTrip(t *testing.T) {
+	hk := Hotkey{Mods: ModSuper | ModCtrl | ModShift, Key: "F12"}
+	if got := hk.String(); got != "Ctrl+Shift+Super+F12" {
+		t.Errorf("String() = %q", got)
+	}
+	parsed, err := Parse(hk.String())
+	if err != nil || parsed != hk {
+		t.Errorf("Parse(String()) = %+v, %v; want %+v", parsed, err, hk)
+	}
+}
+
+func TestRegistration_NilUnregister(t *testing.T) {
+	var r *Registration
+	r.Unregister()
+}
@@ -0,0 +1,115 @@
+package hotkey
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Win32 modifier flags for RegisterHotKey.
+const (
+	modAlt      = 0x0001
+	modControl  = 0x0002
+	modShift    = 0x0004
+	modWin      = 0x0008
+	modNoRepeat = 0x4000
+)
+
+// Win32 messages used by the hotkey thread.
+const (
+	wmHotkey = 0x0312
+	wmQuit   = 0x0012
+)
+
+// hotkeyID identifies our registration in WM_HOTKEY messages.
+const hotkeyID = 1
+
+var (
+	user32                = windows.NewLazySystemDLL("user32.dll")
+	procRegisterHotKey    = user32.NewProc("RegisterHotKey")
+	procUnregisterHotKey  = user32.NewProc("UnregisterHotKey")
+	procGetMessageW       = user32.NewProc("GetMessageW")
+	procPostThreadMessage = user32.NewProc("PostThreadMessageW")
+)
+
+// msg mirrors the Win32 MSG structure.
+type msg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      struct{ x, y int32 }
+}
+
+// register registers the hotkey on a dedicated OS thread, since Windows
+// delivers WM_HOTKEY to the thread that registered it.
+func register(hk Hotkey, fn func()) (func(), error) {
+	ready := make(chan error, 1)
+	threadID := make(chan uint32, 1)
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		threadID <- windows.GetCurrentThreadId()
+		ok, _, err := procRegisterHotKey.Call(0, hotkeyID, uintptr(win32Modifiers(hk.Mods)|modNoRepeat), uintptr(virtualKey(hk.Key)))
+		if ok == 0 {
+			ready <- fmt.Errorf("shortcut %s is already in use: %v", hk, err)
+			return
+		}
+		ready <- nil
+		defer procUnregisterHotKey.Call(0, hotkeyID)
+
+		var m msg
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 {
+				return // WM_QUIT or error
+			}
+			if m.message == wmHotkey && m.wParam == hotkeyID {
+				go fn()
+			}
+		}
+	}()
+
+	tid := <-threadID
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+	return func() {
+		procPostThreadMessage.Call(uintptr(tid), wmQuit, 0, 0)
+	}, nil
+}
+
+// win32Modifiers converts modifiers to RegisterHotKey flags.
+func win32Modifiers(mods Modifier) uint32 {
+	var flags uint32
+	if mods&ModCtrl != 0 {
+		flags |= modControl
+	}
+	if mods&ModShift != 0 {
+		flags |= modShift
+	}
+	if mods&ModAlt != 0 {
+		flags |= modAlt
+	}
+	if mods&ModSuper != 0 {
+		flags |= modWin
+	}
+	return flags
+}
+
+// virtualKey returns the Win32 virtual-key code of a key name accepted by
+// Parse. Letters and digits use their ASCII codes.
+func virtualKey(key string) uint32 {
+	if n := functionKey(key); n > 0 {
+		return 0x70 + uint32(n) - 1 // VK_F1
+	}
+	if key == "Space" {
+		return 0x20
+	}
+	return uint32(key[0])
+}
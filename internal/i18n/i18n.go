@@ -0,0 +1,136 @@
+// Package i18n translates the user-facing strings the backend produces:
+// tool results and errors, safety refusals, agent status messages, and
+// notifications. Strings are looked up by key in the catalog for the
+// current locale, falling back to English.
+//
+// Catalogs live in locales/<language>.json. en.json is the reference; other
+// catalogs must use the same keys and the same format verbs in the same
+// order.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when no catalog matches the requested locale.
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs maps a language code to its strings.
+var catalogs = loadCatalogs()
+
+var (
+	mu      sync.RWMutex
+	current = DefaultLocale
+)
+
+func loadCatalogs() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	result := map[string]map[string]string{}
+	for _, e := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %v", e.Name(), err))
+		}
+		result[e.Name()[:len(e.Name())-len(".json")]] = messages
+	}
+	return result
+}
+
+// Available returns the supported locales, sorted.
+func Available() []string {
+	locales := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supported reports whether locale, e.g. "de" or "de-AT", has a catalog.
+func Supported(locale string) bool {
+	_, ok := catalogs[language(locale)]
+	return ok
+}
+
+// SetLocale selects the catalog for locale. Region and encoding are
+// ignored, so "de_AT.UTF-8" selects German. An empty locale uses the
+// system locale; an unsupported one falls back to English.
+func SetLocale(locale string) {
+	if locale == "" {
+		locale = SystemLocale()
+	}
+	lang := language(locale)
+	if _, ok := catalogs[lang]; !ok {
+		lang = DefaultLocale
+	}
+	mu.Lock()
+	current = lang
+	mu.Unlock()
+}
+
+// Locale returns the language of the selected catalog.
+func Locale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// SystemLocale returns the user's locale from the environment, or
+// DefaultLocale if none is set.
+func SystemLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG", "LANGUAGE"} {
+		if v := os.Getenv(name); v != "" && v != "C" && v != "POSIX" {
+			// LANGUAGE may list several languages, most preferred first
+			v, _, _ = strings.Cut(v, ":")
+			return v
+		}
+	}
+	return DefaultLocale
+}
+
+// T returns the string for key in the current locale, formatted with args
+// as by fmt.Sprintf. Keys missing from the catalog fall back to English,
+// then to the key itself.
+func T(key string, args ...any) string {
+	mu.RLock()
+	lang := current
+	mu.RUnlock()
+
+	s, ok := catalogs[lang][key]
+	if !ok {
+		if s, ok = catalogs[DefaultLocale][key]; !ok {
+			s = key
+		}
+	}
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
+
+// language reduces a locale such as "pt_BR.UTF-8" or "de-AT" to its
+// lowercase language code.
+func language(locale string) string {
+	lang, _, _ := strings.Cut(locale, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	lang = strings.ReplaceAll(lang, "_", "-")
+	lang, _, _ = strings.Cut(lang, "-")
+	return strings.ToLower(lang)
+}
@@ -0,0 +1,78 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9.]*[a-zA-Z%]`)
+
+// TestCatalogs checks that every catalog translates every English string
+// with the same format verbs, so a translation can't break formatting.
+func TestCatalogs(t *testing.T) {
+	en := catalogs[DefaultLocale]
+	for lang, catalog := range catalogs {
+		for key, want := range en {
+			got, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing %s", lang, key)
+				continue
+			}
+			if !slices.Equal(verbPattern.FindAllString(got, -1), verbPattern.FindAllString(want, -1)) {
+				t.Errorf("%s: %s has format verbs %q, want %q", lang, key, got, want)
+			}
+		}
+		for key := range catalog {
+			if _, ok := en[key]; !ok {
+				t.Errorf("%s: %s is not in the English catalog", lang, key)
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLocale(DefaultLocale)
+
+	SetLocale("de_DE.UTF-8")
+	if Locale() != "de" {
+		t.Fatalf("Locale() = %q, want de", Locale())
+	}
+	if got := T("file.not_found", "a.txt"); got != "Datei nicht gefunden: a.txt" {
+		t.Errorf("T = %q", got)
+	}
+
+	// Unknown keys fall back to the key itself
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Errorf("T(unknown) = %q", got)
+	}
+
+	SetLocale("xx-YY")
+	if Locale() != DefaultLocale {
+		t.Errorf("Locale() = %q after an unsupported locale, want %s", Locale(), DefaultLocale)
+	}
+	if got := T("file.not_found", "a.txt"); got != "File not found: a.txt" {
+		t.Errorf("T = %q", got)
+	}
+}
+
+func TestSystemLocale(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "es_MX.UTF-8")
+	if got := SystemLocale(); got != "es_MX.UTF-8" {
+		t.Errorf("SystemLocale() = %q", got)
+	}
+
+	t.Setenv("LANG", "C")
+	t.Setenv("LANGUAGE", "fr:en")
+	if got := SystemLocale(); got != "fr" {
+		t.Errorf("SystemLocale() = %q, want fr", got)
+	}
+}
+
+func TestSupported(t *testing.T) {
+	if !Supported("de-AT") || !Supported("EN") || Supported("xx") {
+		t.Error("unexpected Supported results")
+	}
+}
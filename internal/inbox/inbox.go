@@ -0,0 +1,227 @@
+// Package inbox turns task files dropped into a watched folder into agent
+// runs. A task file is task.md or task.json, or a name ending in .task.md
+// or .task.json, in the folder or one of its direct subfolders. Each task
+// gets a result file next to it, e.g. task.result.md, which is written as
+// soon as the task is picked up and again when the run ends; a task with a
+// result file is never run again.
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch looks for new task files.
+const DefaultWatchInterval = 2 * time.Second
+
+// maxTaskBytes bounds the size of a task file.
+const maxTaskBytes = 256 << 10
+
+// Result statuses.
+const (
+	StatusRunning   = "running"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Task is a task read from a task file.
+type Task struct {
+	Path       string // The task file
+	Text       string // What to ask the agent
+	WorkingDir string // Where the agent runs: the task file's folder unless the file sets one
+}
+
+// taskFile is the format of task.json.
+type taskFile struct {
+	Task       string `json:"task"`
+	WorkingDir string `json:"working_dir,omitempty"`
+}
+
+// IsTaskFile reports whether name is the name of a task file.
+func IsTaskFile(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".md", ".json"} {
+		if lower == "task"+ext || strings.HasSuffix(lower, ".task"+ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResultPath returns the result file of a task file: the name with
+// ".result.md" in place of its extension.
+func ResultPath(taskPath string) string {
+	return strings.TrimSuffix(taskPath, filepath.Ext(taskPath)) + ".result.md"
+}
+
+// Read parses a task file.
+func Read(path string) (Task, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Task{}, err
+	}
+	if info.Size() > maxTaskBytes {
+		return Task{}, fmt.Errorf("%s: task file is larger than %d KB", path, maxTaskBytes>>10)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Task{}, err
+	}
+
+	task := Task{Path: path, WorkingDir: filepath.Dir(path)}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var f taskFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return Task{}, fmt.Errorf("%s: %w", path, err)
+		}
+		task.Text = f.Task
+		if f.WorkingDir != "" {
+			task.WorkingDir = f.WorkingDir
+			if !filepath.IsAbs(f.WorkingDir) && !strings.HasPrefix(f.WorkingDir, "~") {
+				task.WorkingDir = filepath.Join(filepath.Dir(path), f.WorkingDir)
+			}
+		}
+	} else {
+		task.Text = string(data)
+	}
+	task.Text = strings.TrimSpace(task.Text)
+	if task.Text == "" {
+		return Task{}, fmt.Errorf("%s: task is empty", path)
+	}
+	return task, nil
+}
+
+// Prompt is the message sent to the agent for the task: the task itself
+// and where it came from.
+func (t Task) Prompt() string {
+	return fmt.Sprintf("%s\n\n(This task comes from the file %s. Put any files you create next to it unless the task says otherwise.)", t.Text, t.Path)
+}
+
+// Pending lists the task files in dir and its direct subfolders that have
+// no result file yet.
+func Pending(dir string) []string {
+	var paths []string
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			sub, _ := os.ReadDir(path)
+			for _, e := range sub {
+				if !e.IsDir() && IsTaskFile(e.Name()) {
+					paths = append(paths, filepath.Join(path, e.Name()))
+				}
+			}
+		} else if IsTaskFile(entry.Name()) {
+			paths = append(paths, path)
+		}
+	}
+
+	pending := paths[:0]
+	for _, path := range paths {
+		if _, err := os.Stat(ResultPath(path)); errors.Is(err, os.ErrNotExist) {
+			pending = append(pending, path)
+		}
+	}
+	return pending
+}
+
+// Watch calls onTask for each new task file in dir until ctx is cancelled.
+// A file is only read once it has stopped changing for one interval, so
+// tasks still being written aren't picked up early. Task files that can't
+// be read get a failed result and are passed to onError.
+func Watch(ctx context.Context, dir string, interval time.Duration, onTask func(Task), onError func(error)) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	// Size and modification time of each pending file at the last look
+	seen := map[string]string{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		current := map[string]string{}
+		for _, path := range Pending(dir) {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			stamp := fmt.Sprintf("%d|%d", info.Size(), info.ModTime().UnixNano())
+			if seen[path] != stamp {
+				current[path] = stamp // New or still changing; look again next time
+				continue
+			}
+
+			task, err := Read(path)
+			if err == nil {
+				err = WriteResult(Task{Path: path}, Result{Status: StatusRunning, Started: time.Now()})
+			} else {
+				WriteResult(Task{Path: path}, Result{Status: StatusFailed, Error: err.Error(), Finished: time.Now()})
+			}
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				continue
+			}
+			onTask(task)
+		}
+		seen = current
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Result is the outcome of a task, written next to its file.
+type Result struct {
+	Status         string
+	ConversationID string
+	Summary        string   // The agent's final message
+	Error          string   // Why the run failed
+	FilesModified  []string // Files the agent changed
+	Started        time.Time
+	Finished       time.Time
+}
+
+// WriteResult writes the task's result file.
+func WriteResult(task Task, r Result) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Result of %s\n\n", filepath.Base(task.Path))
+	fmt.Fprintf(&b, "- Status: %s\n", r.Status)
+	if r.ConversationID != "" {
+		fmt.Fprintf(&b, "- Conversation: %s\n", r.ConversationID)
+	}
+	if !r.Started.IsZero() {
+		fmt.Fprintf(&b, "- Started: %s\n", r.Started.Format(time.RFC3339))
+	}
+	if !r.Finished.IsZero() {
+		fmt.Fprintf(&b, "- Finished: %s\n", r.Finished.Format(time.RFC3339))
+	}
+
+	if r.Error != "" {
+		fmt.Fprintf(&b, "\n## Error\n\n%s\n", r.Error)
+	}
+	if r.Summary != "" {
+		fmt.Fprintf(&b, "\n## Summary\n\n%s\n", r.Summary)
+	}
+	if len(r.FilesModified) > 0 {
+		b.WriteString("\n## Files Modified\n\n")
+		for _, path := range r.FilesModified {
+			fmt.Fprintf(&b, "- %s\n", path)
+		}
+	}
+	return os.WriteFile(ResultPath(task.Path), []byte(b.String()), 0644)
+}
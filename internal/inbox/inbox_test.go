@@ -0,0 +1,125 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsTaskFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"task.md":            true,
+		"Task.JSON":          true,
+		"report.task.md":     true,
+		"task.result.md":     false,
+		"notes.md":           false,
+		"task.txt":           false,
+		"report.task.result": false,
+	} {
+		if got := IsTaskFile(name); got != want {
+			t.Errorf("IsTaskFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if got := ResultPath(filepath.Join("in", "report.task.json")); got != filepath.Join("in", "report.task.result.md") {
+		t.Errorf("ResultPath = %s", got)
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	md := filepath.Join(dir, "task.md")
+	os.WriteFile(md, []byte("\nSummarize notes.txt\n"), 0644)
+	task, err := Read(md)
+	if err != nil || task.Text != "Summarize notes.txt" || task.WorkingDir != dir {
+		t.Errorf("Read(md) = %+v, %v", task, err)
+	}
+	if !strings.Contains(task.Prompt(), md) {
+		t.Errorf("prompt doesn't name the task file: %s", task.Prompt())
+	}
+
+	js := filepath.Join(dir, "build.task.json")
+	os.WriteFile(js, []byte(`{"task": "Run the tests", "working_dir": "project"}`), 0644)
+	task, err = Read(js)
+	if err != nil || task.Text != "Run the tests" || task.WorkingDir != filepath.Join(dir, "project") {
+		t.Errorf("Read(json) = %+v, %v", task, err)
+	}
+
+	empty := filepath.Join(dir, "empty.task.json")
+	os.WriteFile(empty, []byte(`{"working_dir": "x"}`), 0644)
+	if _, err := Read(empty); err == nil {
+		t.Error("expected an error for a task file without a task")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "report"), 0755)
+	os.WriteFile(filepath.Join(dir, "report", "task.md"), []byte("Write the report"), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.task.json"), []byte("{"), 0644)
+	os.WriteFile(filepath.Join(dir, "done.task.md"), []byte("Already done"), 0644)
+	os.WriteFile(filepath.Join(dir, "done.task.result.md"), []byte("- Status: done"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tasks := make(chan Task, 4)
+	errs := make(chan error, 4)
+	go Watch(ctx, dir, 20*time.Millisecond, func(task Task) { tasks <- task }, func(err error) { errs <- err })
+
+	select {
+	case task := <-tasks:
+		if task.Text != "Write the report" || task.WorkingDir != filepath.Join(dir, "report") {
+			t.Errorf("task = %+v", task)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task")
+	}
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "broken.task.json") {
+			t.Errorf("error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the broken task's error")
+	}
+
+	// Both now have result files, so they aren't picked up again
+	data, _ := os.ReadFile(filepath.Join(dir, "report", "task.result.md"))
+	if !strings.Contains(string(data), "Status: running") {
+		t.Errorf("result after pickup = %q", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "broken.task.result.md"))
+	if !strings.Contains(string(data), "Status: failed") {
+		t.Errorf("result of broken task = %q", data)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if len(tasks) != 0 {
+		t.Errorf("tasks ran again: %d", len(tasks))
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task.md")
+	err := WriteResult(Task{Path: path}, Result{
+		Status:         StatusDone,
+		ConversationID: "c1",
+		Summary:        "Wrote report.pdf",
+		FilesModified:  []string{"/tmp/report.pdf"},
+		Finished:       time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("WriteResult failed: %v", err)
+	}
+	data, err := os.ReadFile(ResultPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatal("no result file")
+	}
+	for _, want := range []string{"- Status: done\n", "- Conversation: c1\n", "- Finished: 2025-01-02T03:04:05Z\n", "## Summary\n\nWrote report.pdf\n", "- /tmp/report.pdf\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("result is missing %q:\n%s", want, data)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Cache keeps embeddings on disk, one file per text and model, so text
+// that was embedded before isn't sent to the provider again: rebuilding
+// the index of an unchanged folder, or of one whose files were only
+// touched, costs nothing. A nil Cache keeps nothing.
+type Cache struct {
+	dir string
+}
+
+// OpenCache returns the cache of embeddings kept in dir.
+func OpenCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Wrap returns an embedder that answers from the cache where it can and
+// asks embedder for the rest, caching what it returns. A nil embedder
+// stays nil.
+func (c *Cache) Wrap(embedder Embedder) Embedder {
+	if c == nil || embedder == nil {
+		return embedder
+	}
+	return &cachedEmbedder{Embedder: embedder, cache: c}
+}
+
+// Get returns the embedding of text by model, if it is cached.
+func (c *Cache) Get(model, text string) ([]float32, bool) {
+	if c == nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.file(model, text))
+	if err != nil || len(data) == 0 || len(data)%4 != 0 {
+		return nil, false
+	}
+	v := make([]float32, len(data)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return v, true
+}
+
+// Put caches the embedding of text by model.
+func (c *Cache) Put(model, text string, vector []float32) error {
+	if c == nil || len(vector) == 0 {
+		return nil
+	}
+	data := make([]byte, len(vector)*4)
+	for i, f := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(f))
+	}
+	path := c.file(model, text)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Write a temporary file first, so a crash can't leave half a vector
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// file returns the path of the cached embedding of text by model, named
+// by their hash and spread over folders by its first byte.
+func (c *Cache) file(model, text string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + text))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, key[:2], key[2:])
+}
+
+// cachedEmbedder is an Embedder backed by a Cache.
+type cachedEmbedder struct {
+	Embedder
+	cache *Cache
+}
+
+// Embeddings returns the cached embeddings of texts, embedding only those
+// that aren't cached.
+func (e *cachedEmbedder) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	model := e.EmbeddingModel()
+	vectors := make([][]float32, len(texts))
+	var missing []int
+	for i, text := range texts {
+		if v, ok := e.cache.Get(model, text); ok {
+			vectors[i] = v
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	batch := make([]string, len(missing))
+	for j, i := range missing {
+		batch[j] = texts[i]
+	}
+	embedded, err := e.Embedder.Embeddings(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(batch) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(embedded))
+	}
+	for j, i := range missing {
+		vectors[i] = embedded[j]
+		// A vector that can't be cached is embedded again next time
+		_ = e.cache.Put(model, texts[i], embedded[j])
+	}
+	return vectors, nil
+}
@@ -0,0 +1,80 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	cache := OpenCache(t.TempDir())
+	inner := &fakeEmbedder{model: "small"}
+	embedder := cache.Wrap(inner)
+	ctx := context.Background()
+
+	first, err := embedder.Embeddings(ctx, []string{"retry backoff", "theme color"})
+	if err != nil {
+		t.Fatalf("Embeddings failed: %v", err)
+	}
+	if inner.texts != 2 {
+		t.Errorf("embedded %d texts, want 2", inner.texts)
+	}
+
+	// Only the new text is sent to the provider
+	again, err := embedder.Embeddings(ctx, []string{"theme color", "database", "retry backoff"})
+	if err != nil {
+		t.Fatalf("Embeddings failed: %v", err)
+	}
+	if inner.texts != 3 || inner.calls != 2 {
+		t.Errorf("embedded %d texts in %d calls, want 3 in 2", inner.texts, inner.calls)
+	}
+	if Cosine(again[0], first[1]) < 0.999 || Cosine(again[2], first[0]) < 0.999 || again[1][4] != 1 {
+		t.Errorf("Embeddings() = %v, want the cached vectors in order", again)
+	}
+
+	// Cached vectors survive reopening, and are kept apart by model
+	reopened := OpenCache(cache.dir)
+	if v, ok := reopened.Get("small", "database"); !ok || len(v) != len(vocabulary)+1 {
+		t.Errorf("Get() = %v, %v", v, ok)
+	}
+	other := &fakeEmbedder{model: "large"}
+	reopened.Wrap(other).Embeddings(ctx, []string{"database"})
+	if other.texts != 1 {
+		t.Error("expected another model's embedding not to come from the cache")
+	}
+
+	// Nothing is cached when the provider fails
+	inner.err = errors.New("offline")
+	if _, err := embedder.Embeddings(ctx, []string{"new text"}); err == nil {
+		t.Error("expected the provider's error")
+	}
+	if _, err := embedder.Embeddings(ctx, []string{"database"}); err != nil {
+		t.Errorf("cached text while offline: %v", err)
+	}
+}
+
+func TestCache_Nil(t *testing.T) {
+	var cache *Cache
+	inner := &fakeEmbedder{model: "small"}
+	if cache.Wrap(inner) != Embedder(inner) {
+		t.Error("expected a nil cache to leave the embedder as it is")
+	}
+	if OpenCache(t.TempDir()).Wrap(nil) != nil {
+		t.Error("expected a nil embedder to stay nil")
+	}
+	if _, ok := cache.Get("small", "x"); ok {
+		t.Error("expected a nil cache to hold nothing")
+	}
+}
+
+func TestCache_Corrupt(t *testing.T) {
+	cache := OpenCache(t.TempDir())
+	path := cache.file("small", "x")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte{1, 2, 3}, 0644)
+	if _, ok := cache.Get("small", "x"); ok {
+		t.Error("expected a truncated file to be a miss")
+	}
+}
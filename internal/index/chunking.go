@@ -0,0 +1,228 @@
+package index
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"agent-desktop/internal/config"
+)
+
+const (
+	chunkTokens   = 512 // Tokens per chunk for the tokens strategy, and most merged paragraphs
+	minCodeChunk  = 8   // Definitions shorter than this many lines share a chunk with the next
+	bytesPerToken = 4   // Rough size of a token in source and English text
+)
+
+// proseExts are the extensions of files split into paragraphs by default.
+var proseExts = map[string]bool{
+	".md": true, ".markdown": true, ".txt": true, ".rst": true, ".adoc": true, ".org": true, ".tex": true,
+}
+
+// Chunking chooses how files are split into chunks: a strategy from
+// config.Chunk* by extension, with "*" for other files. Extensions it
+// leaves out are split by definition if they are source code, by
+// paragraph if they are prose, and by lines otherwise.
+type Chunking map[string]string
+
+// strategy returns the strategy for the file at path.
+func (c Chunking) strategy(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	for key, s := range c {
+		if normalizeExt(key) == ext {
+			return s
+		}
+	}
+	if s := c["*"]; s != "" {
+		return s
+	}
+	switch {
+	case symbolPatterns[ext] != nil:
+		return config.ChunkCode
+	case proseExts[ext]:
+		return config.ChunkParagraphs
+	}
+	return config.ChunkLines
+}
+
+// signature returns a canonical form of c saved with the index, so that
+// changing it re-chunks every file.
+func (c Chunking) signature() string {
+	pairs := make([]string, 0, len(c))
+	for key, s := range c {
+		pairs = append(pairs, normalizeExt(key)+"="+s)
+	}
+	sort.Strings(pairs)
+	// The version tells indexes saved before chunking was configurable apart
+	return "v1;" + strings.Join(pairs, ",")
+}
+
+// normalizeExt turns "MD" and ".md" alike into ".md", leaving "*" as it is.
+func normalizeExt(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "*" || strings.HasPrefix(key, ".") {
+		return key
+	}
+	return "." + key
+}
+
+// chunk splits text, the contents of the file at path, into chunks.
+func (c Chunking) chunk(path, text string) []Chunk {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	switch c.strategy(path) {
+	case config.ChunkTokens:
+		return chunksOf(lines, tokenRanges(lines))
+	case config.ChunkParagraphs:
+		return chunksOf(lines, paragraphRanges(lines))
+	case config.ChunkCode:
+		if ps := symbolPatterns[strings.ToLower(filepath.Ext(path))]; ps != nil {
+			return chunksOf(lines, codeRanges(lines, ps))
+		}
+	}
+	return chunksOf(lines, []lineRange{{0, len(lines)}})
+}
+
+// split cuts text into chunks of chunkLines lines.
+func split(text string) []Chunk {
+	return Chunking{"*": config.ChunkLines}.chunk("", text)
+}
+
+// lineRange is the lines [start, end) of a file, counting from 0.
+type lineRange struct{ start, end int }
+
+// tokens estimates how many tokens line is.
+func tokens(line string) int {
+	return (len(line) + bytesPerToken - 1) / bytesPerToken
+}
+
+// tokenRanges groups lines into runs of about chunkTokens tokens.
+func tokenRanges(lines []string) []lineRange {
+	var ranges []lineRange
+	start, n := 0, 0
+	for i, line := range lines {
+		if n > 0 && n+tokens(line) > chunkTokens {
+			ranges = append(ranges, lineRange{start, i})
+			start, n = i, 0
+		}
+		n += tokens(line) + 1 // The newline
+	}
+	return append(ranges, lineRange{start, len(lines)})
+}
+
+// paragraphRanges groups lines into paragraphs separated by blank lines,
+// merging short paragraphs up to chunkTokens tokens.
+func paragraphRanges(lines []string) []lineRange {
+	var ranges []lineRange
+	start, n := -1, 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		size := 0
+		for _, line := range lines[start:i] {
+			size += tokens(line) + 1
+		}
+		if last := len(ranges) - 1; last >= 0 && n+size <= chunkTokens {
+			ranges[last].end = i
+			n += size
+		} else {
+			ranges = append(ranges, lineRange{start, i})
+			n = size
+		}
+		start = -1
+	}
+	return ranges
+}
+
+// codeRanges groups lines into definitions found by ps, each starting
+// with the comments and annotations above it. Lines before the first
+// definition, such as imports, are a range of their own, and short
+// definitions are merged with the next.
+func codeRanges(lines []string, ps []symbolPattern) []lineRange {
+	var starts []int
+	for i, line := range lines {
+		for _, p := range ps {
+			if m := p.re.FindStringSubmatch(line); m != nil && !notSymbols[m[1]] {
+				start := i
+				prev := 0
+				if len(starts) > 0 {
+					prev = starts[len(starts)-1] + 1
+				}
+				for start > prev && isLeadingComment(lines[start-1]) {
+					start--
+				}
+				starts = append(starts, start)
+				break
+			}
+		}
+	}
+	if len(starts) == 0 || starts[0] != 0 {
+		starts = append([]int{0}, starts...)
+	}
+
+	var ranges []lineRange
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		if last := len(ranges) - 1; last >= 0 && ranges[last].end-ranges[last].start < minCodeChunk {
+			ranges[last].end = end
+			continue
+		}
+		ranges = append(ranges, lineRange{start, end})
+	}
+	return ranges
+}
+
+// isLeadingComment reports whether line is a comment, annotation, or
+// attribute that belongs with the definition below it.
+func isLeadingComment(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "@", "--", ";"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// chunksOf makes chunks of the lines in ranges, cutting ranges longer than
+// chunkLines lines or maxChunkBytes bytes, dropping trailing blank lines,
+// and skipping blank ranges.
+func chunksOf(lines []string, ranges []lineRange) []Chunk {
+	var chunks []Chunk
+	add := func(start, end int) {
+		for end > start && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		body := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(body) == "" {
+			return
+		}
+		if len(body) > maxChunkBytes {
+			body = body[:maxChunkBytes] // One very long line
+		}
+		chunks = append(chunks, Chunk{StartLine: start + 1, EndLine: end, Text: body})
+	}
+	for _, r := range ranges {
+		start, size := r.start, 0
+		for i := r.start; i < r.end; i++ {
+			if i > start && (i-start >= chunkLines || size+len(lines[i]) > maxChunkBytes) {
+				add(start, i)
+				start, size = i, 0
+			}
+			size += len(lines[i]) + 1
+		}
+		if start < r.end {
+			add(start, r.end)
+		}
+	}
+	return chunks
+}
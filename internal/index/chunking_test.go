@@ -0,0 +1,106 @@
+package index
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/config"
+)
+
+func TestChunking_Strategy(t *testing.T) {
+	c := Chunking{"MD": config.ChunkLines, ".txt": config.ChunkTokens}
+	for path, want := range map[string]string{
+		"main.go":       config.ChunkCode,
+		"docs/guide.md": config.ChunkLines,
+		"notes.txt":     config.ChunkTokens,
+		"README.rst":    config.ChunkParagraphs,
+		"data.csv":      config.ChunkLines,
+	} {
+		if got := c.strategy(path); got != want {
+			t.Errorf("strategy(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if got := (Chunking{"*": config.ChunkTokens}).strategy("main.go"); got != config.ChunkTokens {
+		t.Errorf("strategy with a default = %q", got)
+	}
+	if (Chunking{".md": "lines"}).signature() != (Chunking{"md": "lines"}).signature() || Chunking(nil).signature() == "" {
+		t.Error("expected signatures to ignore how extensions are written, and to be versioned")
+	}
+}
+
+func TestChunking_Code(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("package store\n\nimport \"os\"\n\n// Store keeps things.\ntype Store struct {\n")
+	for i := 0; i < 10; i++ {
+		b.WriteString("\tfield int\n")
+	}
+	b.WriteString("}\n\nconst a = 1\nconst b = 2\n\n// Add adds.\nfunc (s *Store) Add() {\n")
+	for i := 0; i < 10; i++ {
+		b.WriteString("\tos.Exit(0)\n")
+	}
+	b.WriteString("}\n")
+
+	chunks := Chunking(nil).chunk("store.go", b.String())
+	var starts []string
+	for _, c := range chunks {
+		starts = append(starts, strings.SplitN(c.Text, "\n", 2)[0])
+	}
+	// The imports and the short constants share a chunk with the definition
+	// that follows them
+	want := []string{"package store", "const a = 1"}
+	if strings.Join(starts, "|") != strings.Join(want, "|") {
+		t.Fatalf("chunks start with %q, want %q", starts, want)
+	}
+	if !strings.HasSuffix(chunks[0].Text, "\tfield int\n}") || !strings.Contains(chunks[1].Text, "// Add adds.\nfunc (s *Store) Add()") {
+		t.Errorf("chunks = %+v", chunks)
+	}
+}
+
+func TestChunking_Paragraphs(t *testing.T) {
+	long := strings.Repeat("word ", chunkTokens*bytesPerToken/5)
+	text := "# Title\n\nShort intro.\n\n\n" + long + "\n\nClosing line.\n"
+	chunks := Chunking(nil).chunk("guide.md", text)
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 3 || !strings.Contains(chunks[0].Text, "Short intro") {
+		t.Errorf("short paragraphs weren't merged: %+v", chunks[0])
+	}
+	if chunks[1].StartLine != 6 || chunks[2].Text != "Closing line." {
+		t.Errorf("chunks = %+v", chunks[1:])
+	}
+}
+
+func TestChunking_Tokens(t *testing.T) {
+	line := strings.Repeat("x", 99)
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, line)
+	}
+	chunks := Chunking{"*": config.ChunkTokens}.chunk("a.log", strings.Join(lines, "\n"))
+	perChunk := chunkTokens / (tokens(line) + 1)
+	if len(chunks) != 3 || chunks[0].EndLine != perChunk || chunks[1].StartLine != perChunk+1 {
+		t.Errorf("chunks = %d, first ends at %d, want %d lines each", len(chunks), chunks[0].EndLine, perChunk)
+	}
+}
+
+func TestIndex_ChunkingChange(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "notes.md", "retry\n\nbackoff\n")
+	embedder := &fakeEmbedder{model: "small"}
+	x, _ := Open(root, "", embedder)
+	x.Update(context.Background())
+	if st := x.Status(); st.Chunks != 1 {
+		t.Errorf("status = %+v", st)
+	}
+
+	x.SetChunking(Chunking{".md": config.ChunkLines})
+	if changed, err := x.Update(context.Background()); err != nil || !changed {
+		t.Errorf("Update() after changing the chunking = %v, %v", changed, err)
+	}
+	x.SetChunking(Chunking{"md": config.ChunkLines})
+	if changed, _ := x.Update(context.Background()); changed {
+		t.Error("expected the same chunking written differently not to re-chunk")
+	}
+}
@@ -0,0 +1,54 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Mentions reports whether text names the indexed folder: by its path, by
+// its path under ~, or by its name as a word, e.g. "fix the tests in
+// billing-service".
+func (x *Index) Mentions(text string) bool {
+	root := filepath.Clean(x.Root())
+	lower := strings.ToLower(text)
+	if strings.Contains(lower, strings.ToLower(root)) {
+		return true
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, root); err == nil && !strings.HasPrefix(rel, "..") && rel != "." {
+			if strings.Contains(lower, strings.ToLower("~/"+filepath.ToSlash(rel))) {
+				return true
+			}
+		}
+	}
+	name := filepath.Base(root)
+	if len(name) < 3 {
+		return false // Too short to tell from an ordinary word
+	}
+	word := regexp.MustCompile(`(?i)(^|[^\pL\pN_.-])` + regexp.QuoteMeta(name) + `($|[^\pL\pN_-])`)
+	return word.MatchString(text)
+}
+
+// Context returns the k chunks most related to task, formatted as a
+// message giving the agent context, and their locations as path:start-end.
+// It returns no text when nothing is indexed yet.
+func (x *Index) Context(ctx context.Context, task string, k int) (string, []string, error) {
+	if x.Status().Updated.IsZero() {
+		return "", nil, nil
+	}
+	results, err := x.Search(ctx, task, k)
+	if err != nil || len(results) == 0 {
+		return "", nil, err
+	}
+	sources := make([]string, len(results))
+	for i, r := range results {
+		sources[i] = fmt.Sprintf("%s:%d-%d", r.Path, r.StartLine, r.EndLine)
+	}
+	text := "Passages of the files in " + x.Root() + " that may be relevant to the next task, found by semantic search. " +
+		"They may be incomplete or out of date; read the files before changing them.\n\n" + formatResults(x.Root(), results)
+	return text, sources, nil
+}
@@ -0,0 +1,55 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "billing-service")
+	x, _ := Open(root, "", nil)
+
+	tests := map[string]bool{
+		"fix the tests in billing-service":        true,
+		"Look at Billing-Service/main.go":         true,
+		"open " + root + "/README.md":             true,
+		"fix the tests in billing-service-v2":     false,
+		"the billing service is down":             false,
+		"see notes.billing-service.md for detail": false,
+	}
+	for text, want := range tests {
+		if got := x.Mentions(text); got != want {
+			t.Errorf("Mentions(%q) = %v, want %v", text, got, want)
+		}
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		x, _ := Open(filepath.Join(home, "src", "app"), "", nil)
+		if !x.Mentions("deploy ~/src/app please") {
+			t.Error("a path under ~ should count as a mention")
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "client/retry.go", "// retry with exponential backoff\nfunc retry() {}\n")
+	writeFile(t, root, "ui/theme.css", "/* theme color */\n")
+	x, _ := Open(root, "", &fakeEmbedder{model: "small"})
+
+	if text, sources, err := x.Context(context.Background(), "retry", 1); text != "" || sources != nil || err != nil {
+		t.Errorf("Context() before indexing = %q, %v, %v", text, sources, err)
+	}
+
+	x.Update(context.Background())
+	text, sources, err := x.Context(context.Background(), "make retry backoff longer", 1)
+	if err != nil {
+		t.Fatalf("Context failed: %v", err)
+	}
+	if len(sources) != 1 || sources[0] != "client/retry.go:1-2" || !strings.Contains(text, "exponential backoff") || !strings.Contains(text, root) {
+		t.Errorf("Context() = %q, %v", text, sources)
+	}
+}
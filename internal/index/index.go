@@ -0,0 +1,400 @@
+// Package index keeps a local vector index of the text files under a
+// workspace folder for semantic search. Files are split into chunks, by
+// definition, paragraph, or run of lines as Chunking says, each chunk is
+// embedded, and the index is saved as one JSON file.
+// Update re-embeds only files whose size or modification time changed, and
+// Watch runs it periodically so the index follows edits.
+package index
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch looks for changed files.
+const DefaultWatchInterval = 30 * time.Second
+
+const (
+	maxFileBytes  = 512 << 10 // Larger files are skipped, as they're rarely hand-written
+	chunkLines    = 60        // Most lines per chunk
+	maxChunkBytes = 4000      // Most bytes per chunk, to stay within embedding model limits
+	batchSize     = 64        // Chunks per embeddings request
+)
+
+// skipDirs are folders that hold dependencies or build output rather than
+// the user's own files. Folders starting with a dot are skipped too.
+var skipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	"__pycache__":  true,
+}
+
+// Embedder turns text into vectors. *llm.Client is one.
+type Embedder interface {
+	Embeddings(ctx context.Context, texts []string) ([][]float32, error)
+	EmbeddingModel() string
+}
+
+// Chunk is a run of lines of a file and its embedding.
+type Chunk struct {
+	StartLine int       `json:"start_line"` // 1-based
+	EndLine   int       `json:"end_line"`   // Inclusive
+	Text      string    `json:"text"`
+	Vector    []float32 `json:"vector"`
+}
+
+// file is the indexed state of one file.
+type file struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Chunks  []Chunk   `json:"chunks"`
+}
+
+// state is what is saved to disk.
+type state struct {
+	Root     string           `json:"root"`
+	Model    string           `json:"model"`
+	Chunking string           `json:"chunking"` // Chunking.signature of the chunks
+	Updated  time.Time        `json:"updated"`
+	Files    map[string]*file `json:"files"` // By slash-separated path relative to Root
+}
+
+// Status describes an index.
+type Status struct {
+	Root    string    `json:"root"`
+	Model   string    `json:"model"`
+	Files   int       `json:"files"`
+	Chunks  int       `json:"chunks"`
+	Updated time.Time `json:"updated,omitempty"` // Zero before the first update
+}
+
+// Result is a chunk that matched a search.
+type Result struct {
+	Path      string  `json:"path"` // Relative to the root
+	StartLine int     `json:"start_line"`
+	EndLine   int     `json:"end_line"`
+	Text      string  `json:"text"`
+	Score     float64 `json:"score"` // Cosine similarity to the query
+}
+
+// Index is the vector index of one workspace folder.
+type Index struct {
+	path string
+
+	// Serializes updates, which embed outside mu
+	updateMu sync.Mutex
+
+	mu       sync.Mutex
+	embedder Embedder
+	chunking Chunking
+	state    state
+}
+
+// Open loads the index of root saved at path, or starts an empty one. The
+// index is usable even when the file can't be read, in which case it
+// starts empty and the error says why.
+func Open(root, path string, embedder Embedder) (*Index, error) {
+	x := &Index{path: path, embedder: embedder, state: state{Root: root, Files: map[string]*file{}}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return x, nil
+	}
+	if err != nil {
+		return x, err
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return x, fmt.Errorf("failed to read index: %w", err)
+	}
+	if s.Root == root && s.Files != nil {
+		x.state = s
+	}
+	return x, nil
+}
+
+// FileName returns the name of the file the index of root is saved in
+// within a folder of indexes.
+func FileName(root string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(root)))
+	return hex.EncodeToString(sum[:8]) + ".json"
+}
+
+// SetEmbedder changes the embedder, e.g. when the provider settings change.
+// If it uses a different model, the next update re-embeds every file.
+func (x *Index) SetEmbedder(embedder Embedder) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.embedder = embedder
+}
+
+// SetChunking changes how files are split into chunks. If it differs from
+// the chunking the index was built with, the next update re-chunks every
+// file.
+func (x *Index) SetChunking(c Chunking) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.chunking = c
+}
+
+// Root returns the folder the index covers.
+func (x *Index) Root() string {
+	return x.state.Root
+}
+
+// Status returns the size of the index and when it was last updated.
+func (x *Index) Status() Status {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	st := Status{Root: x.state.Root, Model: x.state.Model, Files: len(x.state.Files), Updated: x.state.Updated}
+	for _, f := range x.state.Files {
+		st.Chunks += len(f.Chunks)
+	}
+	return st
+}
+
+// Update brings the index up to date with the files under the root,
+// embedding new and changed files and dropping deleted ones, and saves it.
+// It reports whether anything changed. Files that can't be read are
+// skipped; an embedding error stops the update, keeping the files indexed
+// so far.
+func (x *Index) Update(ctx context.Context) (bool, error) {
+	x.updateMu.Lock()
+	defer x.updateMu.Unlock()
+
+	x.mu.Lock()
+	embedder, chunking, root := x.embedder, x.chunking, x.state.Root
+	x.mu.Unlock()
+	if embedder == nil {
+		return false, errors.New("no embeddings provider configured")
+	}
+	model := embedder.EmbeddingModel()
+
+	x.mu.Lock()
+	if x.state.Model != model || x.state.Chunking != chunking.signature() {
+		// Vectors from different models can't be compared, and chunks
+		// from different chunking mustn't be mixed
+		x.state.Model, x.state.Chunking = model, chunking.signature()
+		x.state.Files = map[string]*file{}
+	}
+	indexed := make(map[string]*file, len(x.state.Files))
+	for rel, f := range x.state.Files {
+		indexed[rel] = f
+	}
+	x.mu.Unlock()
+
+	seen := map[string]bool{}
+	changed := false
+	var updateErr error
+	walkErr := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable folder; skip it
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || skipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(name, ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileBytes {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if f := indexed[rel]; f != nil && f.Size == info.Size() && f.ModTime.Equal(info.ModTime()) {
+			seen[rel] = true
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil || isBinary(data) {
+			return nil
+		}
+		chunks := chunking.chunk(rel, string(data))
+		if err := embed(ctx, embedder, chunks); err != nil {
+			updateErr = err
+			return err
+		}
+		seen[rel] = true
+		changed = true
+		x.mu.Lock()
+		x.state.Files[rel] = &file{ModTime: info.ModTime(), Size: info.Size(), Chunks: chunks}
+		x.mu.Unlock()
+		return nil
+	})
+
+	x.mu.Lock()
+	if walkErr == nil {
+		for rel := range x.state.Files {
+			if !seen[rel] {
+				delete(x.state.Files, rel)
+				changed = true
+			}
+		}
+		x.state.Updated = time.Now()
+	}
+	err := x.save()
+	x.mu.Unlock()
+
+	switch {
+	case updateErr != nil:
+		return changed, updateErr
+	case walkErr != nil:
+		return changed, walkErr
+	}
+	return changed, err
+}
+
+// embed fills in the vectors of chunks.
+func embed(ctx context.Context, embedder Embedder, chunks []Chunk) error {
+	for start := 0; start < len(chunks); start += batchSize {
+		end := min(start+batchSize, len(chunks))
+		texts := make([]string, 0, end-start)
+		for _, c := range chunks[start:end] {
+			texts = append(texts, c.Text)
+		}
+		vectors, err := embedder.Embeddings(ctx, texts)
+		if err != nil {
+			return err
+		}
+		for i, v := range vectors {
+			chunks[start+i].Vector = v
+		}
+	}
+	return nil
+}
+
+// Search returns the k chunks most similar to query, best first.
+func (x *Index) Search(ctx context.Context, query string, k int) ([]Result, error) {
+	x.mu.Lock()
+	embedder := x.embedder
+	x.mu.Unlock()
+	if embedder == nil {
+		return nil, errors.New("no embeddings provider configured")
+	}
+	vectors, err := embedder.Embeddings(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	q := vectors[0]
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.state.Model != embedder.EmbeddingModel() {
+		return nil, errors.New("the index is out of date; reindex the workspace")
+	}
+	var results []Result
+	for rel, f := range x.state.Files {
+		for _, c := range f.Chunks {
+			results = append(results, Result{Path: rel, StartLine: c.StartLine, EndLine: c.EndLine, Text: c.Text, Score: Cosine(q, c.Vector)})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Path != results[j].Path {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].StartLine < results[j].StartLine
+	})
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// Watch updates the index right away and then every interval until ctx is
+// cancelled, calling onUpdate after each update that changed it and onError
+// when one fails.
+func (x *Index) Watch(ctx context.Context, interval time.Duration, onUpdate func(Status), onError func(error)) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		changed, err := x.Update(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			onError(err)
+		}
+		if changed {
+			onUpdate(x.Status())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// save writes the index (caller must hold mu).
+func (x *Index) save() error {
+	if x.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(x.state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(x.path, data, 0644)
+}
+
+// isBinary reports whether data looks like a binary file: one with a NUL
+// byte near the start.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if they can't be
+// compared.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,152 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEmbedder embeds text as counts of a few words, so texts about the
+// same thing are similar.
+type fakeEmbedder struct {
+	model string
+	calls int
+	texts int
+	err   error
+}
+
+var vocabulary = []string{"retry", "backoff", "color", "theme", "database"}
+
+func (e *fakeEmbedder) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.calls++
+	e.texts += len(texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(vocabulary)+1)
+		for j, word := range vocabulary {
+			v[j] = float32(strings.Count(strings.ToLower(text), word))
+		}
+		v[len(vocabulary)] = 0.1 // So no vector is all zeros
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func (e *fakeEmbedder) EmbeddingModel() string { return e.model }
+
+// writeFile writes a file under root, creating folders as needed.
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "client/retry.go", "// retry with exponential backoff\nfunc retry() {}\n")
+	writeFile(t, root, "ui/theme.css", "/* theme color */\nbody { color: red; }\n")
+	writeFile(t, root, "node_modules/lib/retry.js", "retry retry retry")
+	writeFile(t, root, ".git/config", "retry")
+	writeFile(t, root, "logo.png", "\x89PNG\x00\x00retry")
+
+	embedder := &fakeEmbedder{model: "small"}
+	path := filepath.Join(t.TempDir(), "index.json")
+	x, err := Open(root, path, embedder)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	changed, err := x.Update(context.Background())
+	if err != nil || !changed {
+		t.Fatalf("Update() = %v, %v", changed, err)
+	}
+	if st := x.Status(); st.Files != 2 || st.Chunks != 2 || st.Model != "small" || st.Updated.IsZero() {
+		t.Errorf("status = %+v", st)
+	}
+
+	results, err := x.Search(context.Background(), "where is the retry backoff", 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != "client/retry.go" || results[0].StartLine != 1 || results[0].EndLine != 2 {
+		t.Errorf("results = %+v", results)
+	}
+
+	// Unchanged files aren't embedded again
+	embedder.texts = 0
+	if changed, err := x.Update(context.Background()); err != nil || changed || embedder.texts != 0 {
+		t.Errorf("second Update() = %v, %v after embedding %d texts", changed, err, embedder.texts)
+	}
+
+	// Changed files are, and deleted ones are dropped
+	later := time.Now().Add(time.Minute)
+	writeFile(t, root, "ui/theme.css", "/* database theme */\n")
+	os.Chtimes(filepath.Join(root, "ui/theme.css"), later, later)
+	os.Remove(filepath.Join(root, "client/retry.go"))
+	if changed, err := x.Update(context.Background()); err != nil || !changed || embedder.texts != 1 {
+		t.Errorf("Update() after edits = %v, %v after embedding %d texts", changed, err, embedder.texts)
+	}
+	if st := x.Status(); st.Files != 1 {
+		t.Errorf("status after edits = %+v", st)
+	}
+
+	// The index survives a restart
+	x, _ = Open(root, path, embedder)
+	if st := x.Status(); st.Files != 1 || st.Chunks != 1 {
+		t.Errorf("status after reopening = %+v", st)
+	}
+
+	// A new embedding model re-embeds everything
+	x.SetEmbedder(&fakeEmbedder{model: "large"})
+	if _, err := x.Search(context.Background(), "theme", 1); err == nil {
+		t.Error("expected an error searching with another model")
+	}
+	if changed, err := x.Update(context.Background()); err != nil || !changed || x.Status().Model != "large" {
+		t.Errorf("Update() with a new model = %v, %v", changed, err)
+	}
+}
+
+func TestIndex_EmbeddingError(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "a.txt", "retry")
+	x, _ := Open(root, "", &fakeEmbedder{model: "small", err: errors.New("offline")})
+	if _, err := x.Update(context.Background()); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("Update() error = %v", err)
+	}
+	if st := x.Status(); st.Files != 0 {
+		t.Errorf("status = %+v", st)
+	}
+
+	x, _ = Open(root, "", nil)
+	if _, err := x.Update(context.Background()); err == nil {
+		t.Error("expected an error without an embedder")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	var lines []string
+	for i := 0; i < chunkLines+10; i++ {
+		lines = append(lines, "line")
+	}
+	chunks := split(strings.Join(lines, "\n") + "\n")
+	if len(chunks) != 2 || chunks[0].EndLine != chunkLines || chunks[1].StartLine != chunkLines+1 || chunks[1].EndLine != chunkLines+10 {
+		t.Errorf("chunks = %+v", chunks)
+	}
+	if chunks := split(strings.Repeat("x", maxChunkBytes*2)); len(chunks[0].Text) != maxChunkBytes {
+		t.Errorf("long chunk kept %d bytes", len(chunks[0].Text))
+	}
+	if chunks := split("\n\n  \n"); len(chunks) != 0 {
+		t.Errorf("blank text gave %+v", chunks)
+	}
+}
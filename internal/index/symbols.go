@@ -0,0 +1,300 @@
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxSymbolFiles = 20000 // Files scanned per folder, so a huge tree can't stall a lookup
+	maxSymbolRoots = 4     // Folders whose symbols are kept between lookups
+	maxSymbolText  = 200   // Longest definition line kept with a symbol
+)
+
+// Symbol is a definition found in a source file.
+type Symbol struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // e.g. "function", "method", "type", or "class"
+	Path string `json:"path"` // Relative to the folder searched
+	Line int    `json:"line"` // 1-based
+	Text string `json:"text"` // The line it is defined on
+}
+
+// symbolPattern finds one kind of definition on a line. The first group
+// is the name.
+type symbolPattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+func patterns(pairs ...string) []symbolPattern {
+	var ps []symbolPattern
+	for i := 0; i+1 < len(pairs); i += 2 {
+		ps = append(ps, symbolPattern{kind: pairs[i], re: regexp.MustCompile(pairs[i+1])})
+	}
+	return ps
+}
+
+var (
+	jsPatterns = patterns(
+		"function", `^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`,
+		"class", `^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`,
+		"interface", `^\s*(?:export\s+)?interface\s+([A-Za-z_$][\w$]*)`,
+		"type", `^\s*(?:export\s+)?(?:type|enum)\s+([A-Za-z_$][\w$]*)\b`,
+		"function", `^\s*(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|[A-Za-z_$][\w$]*\s*=>)`,
+	)
+	cPatterns = patterns(
+		"macro", `^#\s*define\s+([A-Za-z_]\w*)`,
+		"type", `^(?:typedef\s+)?(?:struct|class|enum|union)\s+([A-Za-z_]\w*)\s*(?:[:{]|$)`,
+		"function", `^[A-Za-z_][\w\s\*&:<>,]*?[\s\*&:]([A-Za-z_]\w*)\s*\([^;]*$`,
+	)
+	javaPatterns = patterns(
+		"class", `^\s*(?:(?:public|private|protected|internal|static|final|abstract|sealed|partial|data|open)\s+)*(?:class|record|object)\s+([A-Za-z_]\w*)`,
+		"interface", `^\s*(?:(?:public|private|protected|internal|static|sealed)\s+)*interface\s+([A-Za-z_]\w*)`,
+		"type", `^\s*(?:(?:public|private|protected|internal|static)\s+)*enum\s+(?:class\s+)?([A-Za-z_]\w*)`,
+		"function", `^\s*(?:(?:public|private|protected|internal|static|override|suspend|inline)\s+)*fun\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?([A-Za-z_]\w*)`,
+		"method", `^\s*(?:(?:public|private|protected|internal|static|final|abstract|synchronized|override|async|virtual)\s+)+[\w<>\[\],.?\s]*?\s([A-Za-z_]\w*)\s*\([^;]*$`,
+	)
+)
+
+// symbolPatterns are the definitions found in each kind of source file, by
+// extension.
+var symbolPatterns = map[string][]symbolPattern{
+	".go": patterns(
+		"method", `^func\s+\([^)]*\)\s*([A-Za-z_]\w*)`,
+		"function", `^func\s+([A-Za-z_]\w*)`,
+		"type", `^type\s+([A-Za-z_]\w*)`,
+		"constant", `^const\s+([A-Za-z_]\w*)`,
+		"variable", `^var\s+([A-Za-z_]\w*)`,
+	),
+	".py": patterns(
+		"function", `^\s*(?:async\s+)?def\s+([A-Za-z_]\w*)`,
+		"class", `^\s*class\s+([A-Za-z_]\w*)`,
+	),
+	".rs": patterns(
+		"function", `^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"[^"]*"\s+)?fn\s+([A-Za-z_]\w*)`,
+		"type", `^\s*(?:pub(?:\([^)]*\))?\s+)?(?:struct|enum|union|type)\s+([A-Za-z_]\w*)`,
+		"interface", `^\s*(?:pub(?:\([^)]*\))?\s+)?(?:unsafe\s+)?trait\s+([A-Za-z_]\w*)`,
+		"module", `^\s*(?:pub(?:\([^)]*\))?\s+)?mod\s+([A-Za-z_]\w*)`,
+		"macro", `^\s*macro_rules!\s*([A-Za-z_]\w*)`,
+	),
+	".rb": patterns(
+		"method", `^\s*def\s+(?:self\.)?([A-Za-z_]\w*[?!=]?)`,
+		"class", `^\s*class\s+([A-Z]\w*)`,
+		"module", `^\s*module\s+([A-Z]\w*)`,
+	),
+	".php": patterns(
+		"function", `^\s*(?:(?:public|private|protected|static|final|abstract)\s+)*function\s+&?([A-Za-z_]\w*)`,
+		"class", `^\s*(?:(?:final|abstract)\s+)?class\s+([A-Za-z_]\w*)`,
+		"interface", `^\s*(?:interface|trait)\s+([A-Za-z_]\w*)`,
+	),
+	".swift": patterns(
+		"function", `^\s*(?:(?:public|private|fileprivate|internal|open|static|class|override|mutating)\s+)*func\s+([A-Za-z_]\w*)`,
+		"class", `^\s*(?:(?:public|private|fileprivate|internal|open|final)\s+)*(?:class|struct|enum|actor|extension)\s+([A-Za-z_]\w*)`,
+		"interface", `^\s*(?:(?:public|private|fileprivate|internal)\s+)*protocol\s+([A-Za-z_]\w*)`,
+	),
+	".js": jsPatterns, ".jsx": jsPatterns, ".mjs": jsPatterns, ".cjs": jsPatterns,
+	".ts": jsPatterns, ".tsx": jsPatterns, ".mts": jsPatterns, ".cts": jsPatterns,
+	".c": cPatterns, ".h": cPatterns, ".cc": cPatterns, ".cpp": cPatterns, ".cxx": cPatterns, ".hpp": cPatterns, ".hh": cPatterns,
+	".java": javaPatterns, ".kt": javaPatterns, ".kts": javaPatterns, ".cs": javaPatterns, ".scala": javaPatterns,
+}
+
+// notSymbols are words the looser patterns can take for a name.
+var notSymbols = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "return": true, "catch": true,
+	"else": true, "new": true, "sizeof": true, "defined": true,
+}
+
+// symbolFile is the scanned state of one file.
+type symbolFile struct {
+	modTime time.Time
+	size    int64
+	symbols []Symbol
+}
+
+// Symbols is a ctags-style table of the definitions in the source files
+// under folders, rebuilt on each lookup from the files that changed since
+// the last one.
+type Symbols struct {
+	mu    sync.Mutex
+	roots map[string]map[string]*symbolFile // By folder, then by relative path
+	order []string                          // Folders, least recently used first
+}
+
+// NewSymbols returns an empty symbol table.
+func NewSymbols() *Symbols {
+	return &Symbols{roots: map[string]map[string]*symbolFile{}}
+}
+
+// Find returns up to limit definitions named name under root: exact
+// matches first, then matches ignoring case, then names starting with or
+// containing it. A qualified name such as "Store.Add" is looked up by its
+// last part. An empty kind matches every kind.
+func (s *Symbols) Find(ctx context.Context, root, name, kind string, limit int) ([]Symbol, error) {
+	if i := strings.LastIndexAny(name, ".:"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	files, err := s.scan(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		Symbol
+		rank int
+	}
+	lower := strings.ToLower(name)
+	var matches []match
+	for _, f := range files {
+		for _, sym := range f.symbols {
+			if kind != "" && sym.Kind != kind {
+				continue
+			}
+			rank := -1
+			switch l := strings.ToLower(sym.Name); {
+			case sym.Name == name:
+				rank = 0
+			case l == lower:
+				rank = 1
+			case strings.HasPrefix(l, lower):
+				rank = 2
+			case strings.Contains(l, lower):
+				rank = 3
+			}
+			if rank >= 0 {
+				matches = append(matches, match{sym, rank})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	symbols := make([]Symbol, len(matches))
+	for i, m := range matches {
+		symbols[i] = m.Symbol
+	}
+	return symbols, nil
+}
+
+// scan brings the table of root up to date and returns its files.
+func (s *Symbols) scan(ctx context.Context, root string) (map[string]*symbolFile, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New(root + " is not a folder")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.roots[root]
+	files := make(map[string]*symbolFile, len(old))
+	count := 0
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable folder; skip it
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || skipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ps := symbolPatterns[strings.ToLower(filepath.Ext(name))]
+		if ps == nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if count++; count > maxSymbolFiles {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileBytes {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if f := old[rel]; f != nil && f.size == info.Size() && f.modTime.Equal(info.ModTime()) {
+			files[rel] = f
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || isBinary(data) {
+			return nil
+		}
+		files[rel] = &symbolFile{modTime: info.ModTime(), size: info.Size(), symbols: findSymbols(rel, data, ps)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.roots[root] = files
+	for i, r := range s.order {
+		if r == root {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	s.order = append(s.order, root)
+	if len(s.order) > maxSymbolRoots {
+		delete(s.roots, s.order[0])
+		s.order = s.order[1:]
+	}
+	return files, nil
+}
+
+// findSymbols returns the definitions in data, the contents of the file at
+// path, using the first of ps to match each line.
+func findSymbols(path string, data []byte, ps []symbolPattern) []Symbol {
+	var symbols []Symbol
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), maxFileBytes)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, p := range ps {
+			m := p.re.FindStringSubmatch(text)
+			if m == nil || notSymbols[m[1]] {
+				continue
+			}
+			text = strings.TrimSpace(text)
+			if len(text) > maxSymbolText {
+				text = text[:maxSymbolText]
+			}
+			symbols = append(symbols, Symbol{Name: m[1], Kind: p.kind, Path: path, Line: line, Text: text})
+			break
+		}
+	}
+	return symbols
+}
@@ -0,0 +1,96 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindSymbols(t *testing.T) {
+	for _, tt := range []struct {
+		path, line string
+		name, kind string
+	}{
+		{"a.go", "func NewClient(cfg *Config) *Client {", "NewClient", "function"},
+		{"a.go", "func (c *Client) Send(ctx context.Context) error {", "Send", "method"},
+		{"a.go", "type Client struct {", "Client", "type"},
+		{"a.py", "    async def fetch(self, url):", "fetch", "function"},
+		{"a.py", "class Parser(Base):", "Parser", "class"},
+		{"a.ts", "export default async function loadConfig() {", "loadConfig", "function"},
+		{"a.ts", "export const useTheme = (name: string) => {", "useTheme", "function"},
+		{"a.ts", "export interface Props {", "Props", "interface"},
+		{"a.rs", "pub(crate) async fn connect(addr: &str) -> Result<()> {", "connect", "function"},
+		{"a.rs", "pub trait Store {", "Store", "interface"},
+		{"a.c", "static int parse_args(int argc, char **argv)", "parse_args", "function"},
+		{"a.cpp", "void Buffer::append(const char *data) {", "append", "function"},
+		{"a.h", "#define MAX_LEN 64", "MAX_LEN", "macro"},
+		{"A.java", "    public static List<String> split(String s) {", "split", "method"},
+		{"a.rb", "  def self.valid?(value)", "valid?", "method"},
+	} {
+		symbols := findSymbols(tt.path, []byte(tt.line), symbolPatterns[filepath.Ext(tt.path)])
+		if len(symbols) != 1 || symbols[0].Name != tt.name || symbols[0].Kind != tt.kind {
+			t.Errorf("findSymbols(%q) = %+v, want %s %s", tt.line, symbols, tt.kind, tt.name)
+		}
+	}
+
+	for _, line := range []string{"\tName string", "    if (ready) {", "  return compute(x);", "x = foo(1)"} {
+		for _, ext := range []string{".go", ".c", ".java", ".py"} {
+			if symbols := findSymbols("a"+ext, []byte(line), symbolPatterns[ext]); len(symbols) != 0 {
+				t.Errorf("findSymbols(%q) in %s = %+v, want none", line, ext, symbols)
+			}
+		}
+	}
+}
+
+func TestSymbols_Find(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "store/store.go", "package store\n\ntype Store struct{}\n\nfunc (s *Store) Add(x int) {}\n\nfunc AddAll() {}\n")
+	writeFile(t, root, "web/add.js", "function add(a, b) {\n  return a + b\n}\n")
+	writeFile(t, root, "node_modules/lib/index.js", "function Add() {}\n")
+	writeFile(t, root, "README.md", "func Add()\n")
+	s := NewSymbols()
+	ctx := context.Background()
+
+	got, err := s.Find(ctx, root, "Store.Add", "", 0)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	want := []Symbol{
+		{Name: "Add", Kind: "method", Path: "store/store.go", Line: 5, Text: "func (s *Store) Add(x int) {}"},
+		{Name: "add", Kind: "function", Path: "web/add.js", Line: 1, Text: "function add(a, b) {"},
+		{Name: "AddAll", Kind: "function", Path: "store/store.go", Line: 7, Text: "func AddAll() {}"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Find() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Find()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got, _ := s.Find(ctx, root, "add", "method", 0); len(got) != 1 || got[0].Kind != "method" {
+		t.Errorf("Find(kind method) = %+v", got)
+	}
+	if got, _ := s.Find(ctx, root, "add", "", 1); len(got) != 1 || got[0].Path != "web/add.js" {
+		t.Errorf("Find(limit 1) = %+v", got)
+	}
+
+	// Changed files are scanned again
+	path := filepath.Join(root, "web/add.js")
+	os.WriteFile(path, []byte("function sum(a, b) {}\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if got, _ := s.Find(ctx, root, "sum", "", 0); len(got) != 1 {
+		t.Errorf("Find after an edit = %+v", got)
+	}
+
+	if _, err := s.Find(ctx, root, " ", "", 0); err == nil {
+		t.Error("expected an error for an empty name")
+	}
+	if _, err := s.Find(ctx, filepath.Join(root, "missing"), "Add", "", 0); err == nil {
+		t.Error("expected an error for a missing folder")
+	}
+}
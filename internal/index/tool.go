@@ -0,0 +1,187 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/tools"
+)
+
+// Source is the tools package source the semantic_search tool is
+// registered under.
+const Source = "index"
+
+// SymbolsSource is the tools package source the find_symbol tool is
+// registered under.
+const SymbolsSource = "symbols"
+
+const (
+	searchTimeout  = 30 * time.Second
+	defaultResults = 5
+	maxResults     = 20
+	defaultSymbols = 20
+	maxSymbols     = 100
+)
+
+// Register offers the agent semantic_search over x, replacing the index
+// offered before. A nil x removes the tool. A tool of the same name from
+// another source keeps it, and the error says so.
+func Register(x *Index) error {
+	if x == nil {
+		return tools.SetExternalTools(Source, nil)
+	}
+	return tools.SetExternalTools(Source, []tools.ExternalTool{x.Tool()})
+}
+
+// Tool returns the semantic_search tool, which finds the chunks of the
+// workspace's files most related to a question.
+func (x *Index) Tool() tools.ExternalTool {
+	return tools.ExternalTool{
+		Definition: tools.ToolDefinition{
+			Type: "function",
+			Function: tools.ToolFunction{
+				Name: "semantic_search",
+				Description: "Search the files of the indexed workspace folder (" + x.Root() + ") by meaning rather than exact text, " +
+					"e.g. 'where is retry logic configured'. Returns the best matching passages with their paths and line ranges. " +
+					"Prefer it to grep for questions about where something is done; use grep for exact names.",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"query": map[string]interface{}{"type": "string", "description": "What to look for, in plain words"},
+						"limit": map[string]interface{}{
+							"type":        "integer",
+							"description": fmt.Sprintf("Most passages to return, up to %d. Default is %d.", maxResults, defaultResults),
+							"default":     defaultResults,
+						},
+					},
+					"required": []string{"query"},
+				},
+			},
+		},
+		Execute: x.searchTool,
+	}
+}
+
+// searchTool runs semantic_search.
+func (x *Index) searchTool(args map[string]interface{}) tools.ToolResult {
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return tools.ToolResult{Success: false, Error: "query is required"}
+	}
+	limit := defaultResults
+	if v, ok := args["limit"].(float64); ok {
+		limit = int(v)
+	}
+	if limit < 1 || limit > maxResults {
+		return tools.ToolResult{Success: false, Error: fmt.Sprintf("limit must be between 1 and %d", maxResults)}
+	}
+	if x.Status().Updated.IsZero() {
+		return tools.ToolResult{Success: false, Error: "the workspace is still being indexed; try again shortly or use grep"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+	defer cancel()
+	results, err := x.Search(ctx, query, limit)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: "search failed: " + err.Error()}
+	}
+	return tools.ToolResult{Success: true, Output: formatResults(x.Root(), results)}
+}
+
+// formatResults lists results with their location, best first.
+func formatResults(root string, results []Result) string {
+	if len(results) == 0 {
+		return "No indexed files in " + root
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Best matches in %s:\n", root)
+	for _, r := range results {
+		fmt.Fprintf(&b, "\n%s:%d-%d (score %.2f)\n%s\n", r.Path, r.StartLine, r.EndLine, r.Score, r.Text)
+	}
+	return b.String()
+}
+
+// RegisterSymbols offers the agent find_symbol over s. It looks in the
+// folder defaultRoot returns unless the agent names another. A nil s
+// removes the tool. A tool of the same name from another source keeps it,
+// and the error says so.
+func RegisterSymbols(s *Symbols, defaultRoot func() string) error {
+	if s == nil {
+		return tools.SetExternalTools(SymbolsSource, nil)
+	}
+	return tools.SetExternalTools(SymbolsSource, []tools.ExternalTool{s.Tool(defaultRoot)})
+}
+
+// Tool returns the find_symbol tool, which finds where functions, types,
+// and classes are defined.
+func (s *Symbols) Tool(defaultRoot func() string) tools.ExternalTool {
+	return tools.ExternalTool{
+		Definition: tools.ToolDefinition{
+			Type: "function",
+			Function: tools.ToolFunction{
+				Name: "find_symbol",
+				Description: "Find where a function, method, type, or class is defined in source code, as file:line with the defining line. " +
+					"Use it to jump to a definition instead of reading or grepping whole files. " +
+					"Matches the exact name first, then names that start with or contain it.",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name": map[string]interface{}{"type": "string", "description": "The symbol's name, e.g. 'NewClient' or 'Store.Add'"},
+						"kind": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"function", "method", "type", "class", "interface", "constant", "variable", "module", "macro"},
+							"description": "Only find this kind of symbol",
+						},
+						"path": map[string]interface{}{"type": "string", "description": "Folder to search. Default is the indexed workspace folder, or else the current directory."},
+						"limit": map[string]interface{}{
+							"type":        "integer",
+							"description": fmt.Sprintf("Most definitions to return, up to %d. Default is %d.", maxSymbols, defaultSymbols),
+							"default":     defaultSymbols,
+						},
+					},
+					"required": []string{"name"},
+				},
+			},
+		},
+		Execute: func(args map[string]interface{}) tools.ToolResult {
+			return s.findTool(args, defaultRoot)
+		},
+	}
+}
+
+// findTool runs find_symbol.
+func (s *Symbols) findTool(args map[string]interface{}, defaultRoot func() string) tools.ToolResult {
+	name, _ := args["name"].(string)
+	kind, _ := args["kind"].(string)
+	root, _ := args["path"].(string)
+	limit := defaultSymbols
+	if v, ok := args["limit"].(float64); ok {
+		limit = int(v)
+	}
+	if limit < 1 || limit > maxSymbols {
+		return tools.ToolResult{Success: false, Error: fmt.Sprintf("limit must be between 1 and %d", maxSymbols)}
+	}
+	if root == "" {
+		root = defaultRoot()
+	} else {
+		root = tools.ExpandPath(root, tools.GetSession().GetCWD())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+	defer cancel()
+	symbols, err := s.Find(ctx, root, name, kind, limit)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: "symbol lookup failed: " + err.Error()}
+	}
+	if len(symbols) == 0 {
+		return tools.ToolResult{Success: true, Output: fmt.Sprintf("No definitions of %s in %s", name, root)}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Definitions in %s:\n", root)
+	for _, sym := range symbols {
+		fmt.Fprintf(&b, "%s:%d %s %s\n    %s\n", sym.Path, sym.Line, sym.Kind, sym.Name, sym.Text)
+	}
+	return tools.ToolResult{Success: true, Output: b.String()}
+}
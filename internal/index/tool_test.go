@@ -0,0 +1,71 @@
+package index
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/tools"
+)
+
+func TestSemanticSearchTool(t *testing.T) {
+	defer Register(nil)
+
+	root := t.TempDir()
+	writeFile(t, root, "client/retry.go", "// retry with exponential backoff\nfunc retry() {}\n")
+	writeFile(t, root, "ui/theme.css", "/* theme color */\n")
+	x, _ := Open(root, "", &fakeEmbedder{model: "small"})
+	Register(x)
+
+	result := tools.ExecuteTool("semantic_search", map[string]interface{}{"query": "retry backoff"})
+	if result.Success || !strings.Contains(result.Error, "still being indexed") {
+		t.Errorf("search before indexing = %+v", result)
+	}
+
+	x.Update(context.Background())
+	result = tools.ExecuteTool("semantic_search", map[string]interface{}{"query": "retry backoff", "limit": float64(1)})
+	if !result.Success || !strings.Contains(result.Output, "client/retry.go:1-2") || strings.Contains(result.Output, "theme.css") {
+		t.Errorf("search = %+v", result)
+	}
+
+	for _, args := range []map[string]interface{}{{}, {"query": "x", "limit": float64(maxResults + 1)}} {
+		if result := tools.ExecuteTool("semantic_search", args); result.Success {
+			t.Errorf("search with %v succeeded", args)
+		}
+	}
+
+	Register(nil)
+	for _, def := range tools.GetToolDefinitions() {
+		if def.Function.Name == "semantic_search" {
+			t.Error("semantic_search offered after removing the index")
+		}
+	}
+}
+
+func TestFindSymbolTool(t *testing.T) {
+	defer RegisterSymbols(nil, nil)
+	defer tools.ResetSession()
+
+	root := t.TempDir()
+	writeFile(t, root, "client.go", "package llm\n\nfunc NewClient() *Client {\n\treturn nil\n}\n")
+	other := t.TempDir()
+	writeFile(t, other, "main.py", "def main():\n    pass\n")
+	tools.GetSession().SetCWD(other)
+	RegisterSymbols(NewSymbols(), func() string { return root })
+
+	result := tools.ExecuteTool("find_symbol", map[string]interface{}{"name": "NewClient"})
+	if !result.Success || !strings.Contains(result.Output, "client.go:3 function NewClient") {
+		t.Errorf("find_symbol = %+v", result)
+	}
+	result = tools.ExecuteTool("find_symbol", map[string]interface{}{"name": "main", "path": "."})
+	if !result.Success || !strings.Contains(result.Output, "main.py:1") {
+		t.Errorf("find_symbol in the current directory = %+v", result)
+	}
+	result = tools.ExecuteTool("find_symbol", map[string]interface{}{"name": "Missing"})
+	if !result.Success || !strings.Contains(result.Output, "No definitions") {
+		t.Errorf("find_symbol for a missing name = %+v", result)
+	}
+	if result := tools.ExecuteTool("find_symbol", map[string]interface{}{"name": "x", "limit": float64(maxSymbols + 1)}); result.Success {
+		t.Errorf("find_symbol with too high a limit = %+v", result)
+	}
+}
@@ -0,0 +1,115 @@
+// Package jobs runs background maintenance work, such as titling
+// conversations, one job at a time, so runs that end in quick succession
+// don't turn into concurrent calls to the LLM.
+package jobs
+
+import (
+	"context"
+	"sync"
+
+	"agent-desktop/internal/crash"
+)
+
+// Func is a job. ctx is cancelled when the queue is closed.
+type Func func(ctx context.Context)
+
+// job is a submitted Func with its key.
+type job struct {
+	key string
+	fn  Func
+}
+
+// Queue runs submitted jobs in order on a goroutine of its own, started
+// when there is work and ended when there is none.
+type Queue struct {
+	mu      sync.Mutex
+	idle    *sync.Cond // Signalled when the worker stops
+	pending []*job
+	working bool // The worker goroutine is running
+	closed  bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// New returns an empty queue.
+func New() *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{ctx: ctx, cancel: cancel}
+	q.idle = sync.NewCond(&q.mu)
+	return q
+}
+
+// Submit queues fn to run after the jobs already queued. A job with a key
+// replaces the waiting job with the same key, keeping its place, so only
+// the latest of a burst runs; a job with an empty key is always run. Jobs
+// submitted after Close are dropped.
+func (q *Queue) Submit(key string, fn Func) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	if key != "" {
+		for _, j := range q.pending {
+			if j.key == key {
+				j.fn = fn
+				return
+			}
+		}
+	}
+	q.pending = append(q.pending, &job{key: key, fn: fn})
+	if !q.working {
+		q.working = true
+		go q.work()
+	}
+}
+
+// Pending returns how many jobs are waiting to run.
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Wait blocks until every job has run, including those they submit.
+func (q *Queue) Wait() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.working {
+		q.idle.Wait()
+	}
+}
+
+// Close drops the waiting jobs, cancels the running one, and waits for it
+// to return.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.pending = nil
+	q.mu.Unlock()
+	q.cancel()
+	q.Wait()
+}
+
+// work runs jobs until none are left.
+func (q *Queue) work() {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.working = false
+			q.idle.Broadcast()
+			q.mu.Unlock()
+			return
+		}
+		j := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+		q.run(j)
+	}
+}
+
+// run runs one job; a job that panics is reported and skipped.
+func (q *Queue) run(j *job) {
+	defer crash.Recover("background job "+j.key, nil)
+	j.fn(q.ctx)
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	q := New()
+	defer q.Close()
+
+	var mu sync.Mutex
+	var ran []string
+	record := func(name string) Func {
+		return func(context.Context) {
+			mu.Lock()
+			ran = append(ran, name)
+			mu.Unlock()
+		}
+	}
+
+	// Hold the worker so the rest wait behind it
+	started, release := make(chan struct{}), make(chan struct{})
+	q.Submit("", func(context.Context) {
+		close(started)
+		<-release
+	})
+	<-started
+	q.Submit("title:a", record("a1"))
+	q.Submit("", record("summary"))
+	q.Submit("title:a", record("a2"))
+	q.Submit("title:b", record("b"))
+	if n := q.Pending(); n != 3 {
+		t.Errorf("Pending() = %d, want 3", n)
+	}
+	close(release)
+	q.Wait()
+
+	if want := []string{"a2", "summary", "b"}; !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestQueueClose(t *testing.T) {
+	q := New()
+	started := make(chan struct{})
+	var cancelled, ran bool
+	q.Submit("", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		cancelled = true
+	})
+	q.Submit("", func(context.Context) { ran = true })
+	<-started
+	q.Close()
+
+	if !cancelled || ran {
+		t.Errorf("Close() cancelled the running job: %v, ran the waiting one: %v", cancelled, ran)
+	}
+	q.Submit("", func(context.Context) { t.Error("a job submitted after Close ran") })
+	q.Wait()
+}
+
+func TestQueuePanic(t *testing.T) {
+	q := New()
+	defer q.Close()
+	ran := false
+	q.Submit("", func(context.Context) { panic("boom") })
+	q.Submit("", func(context.Context) { ran = true })
+	q.Wait()
+	if !ran {
+		t.Error("a panicking job stopped the queue")
+	}
+}
@@ -0,0 +1,272 @@
+// Package jsonrpc lets editor extensions drive the agent over JSON-RPC 2.0
+// on stdin and stdout, one message per line. It offers the same
+// conversations and run queue as the REST API, and streams agent steps as
+// notifications once a client asks for them.
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/runqueue"
+	"agent-desktop/internal/server"
+)
+
+// JSON-RPC error codes. Codes from -32000 down are this server's own.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeNotFound       = -32001 // Unknown conversation or run
+	CodeReadOnly       = -32002 // Another instance owns the conversations
+)
+
+// EventMethod is the notification method agent events are sent with, with
+// params {"event": name, "data": [...]}.
+const EventMethod = "event"
+
+// maxMessageBytes bounds a single incoming message.
+const maxMessageBytes = 4 << 20
+
+// message is a JSON-RPC 2.0 request, notification, or response.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Server answers JSON-RPC requests against a backend. Methods:
+//
+//	listConversations                      -> [summary]
+//	newConversation                        -> conversation
+//	getConversation {id}                   -> conversation
+//	sendMessage {conversationId?, text}    -> queued run; a new conversation if no ID
+//	listRuns                               -> [run]
+//	cancelRun {id}                         -> {}
+//	streamSteps {enabled?}                 -> {}; turns event notifications on (default) or off
+type Server struct {
+	backend server.Backend
+
+	mu        sync.Mutex
+	w         io.Writer
+	streaming bool
+}
+
+// New creates a server for backend.
+func New(backend server.Backend) *Server {
+	return &Server{backend: backend}
+}
+
+// Serve answers requests from r on w until r ends or ctx is cancelled.
+// Requests are handled in order.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.mu.Lock()
+	s.w = w
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.w, s.streaming = nil, false
+		s.mu.Unlock()
+	}()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageBytes)
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				lines <- append([]byte(nil), line...)
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case line := <-lines:
+			if reply := s.handle(line); reply != nil {
+				s.write(*reply)
+			}
+		}
+	}
+}
+
+// Publish sends an app event to the client if it asked for events. It is
+// the app's event emitter in JSON-RPC mode.
+func (s *Server) Publish(name string, data ...interface{}) {
+	s.mu.Lock()
+	streaming := s.streaming && s.w != nil
+	s.mu.Unlock()
+	if !streaming {
+		return
+	}
+	if data == nil {
+		data = []interface{}{}
+	}
+	params, err := json.Marshal(map[string]interface{}{"event": name, "data": data})
+	if err != nil {
+		slog.Warn("failed to encode event", "event", name, "error", err)
+		return
+	}
+	s.write(message{Method: EventMethod, Params: params})
+}
+
+// handle answers one line of input. Notifications get no reply.
+func (s *Server) handle(line []byte) *message {
+	var req message
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &message{ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: "invalid JSON"}}
+	}
+	if req.Method == "" {
+		return &message{ID: idOrNull(req.ID), Error: &Error{Code: CodeInvalidRequest, Message: "missing method"}}
+	}
+
+	result, err := s.call(req.Method, req.Params)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	reply := &message{ID: req.ID}
+	if err != nil {
+		reply.Error = toError(err)
+	} else {
+		reply.Result = result
+	}
+	return reply
+}
+
+// call runs a method.
+func (s *Server) call(method string, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ID             string `json:"id"`
+		ConversationID string `json:"conversationId"`
+		Text           string `json:"text"`
+		Enabled        *bool  `json:"enabled"`
+	}
+	if len(params) > 0 && string(params) != "null" {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: "params must be an object"}
+		}
+	}
+
+	switch method {
+	case "listConversations":
+		summaries, err := s.backend.ListConversations()
+		if summaries == nil {
+			summaries = []conversation.Summary{}
+		}
+		return summaries, err
+
+	case "newConversation":
+		return s.backend.NewConversation()
+
+	case "getConversation":
+		if p.ID == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "id is required"}
+		}
+		return s.backend.GetConversation(p.ID)
+
+	case "sendMessage":
+		if strings.TrimSpace(p.Text) == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "text is required"}
+		}
+		if p.ConversationID == "" {
+			conv, err := s.backend.NewConversation()
+			if err != nil {
+				return nil, err
+			}
+			p.ConversationID = conv.ID
+		} else if _, err := s.backend.GetConversation(p.ConversationID); err != nil {
+			return nil, err
+		}
+		return s.backend.SendMessage(p.ConversationID, p.Text)
+
+	case "listRuns":
+		runs := s.backend.ListRuns()
+		if runs == nil {
+			runs = []runqueue.Item{}
+		}
+		return runs, nil
+
+	case "cancelRun":
+		if p.ID == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "id is required"}
+		}
+		return struct{}{}, s.backend.CancelRun(p.ID)
+
+	case "streamSteps":
+		s.mu.Lock()
+		s.streaming = p.Enabled == nil || *p.Enabled
+		s.mu.Unlock()
+		return struct{}{}, nil
+	}
+	return nil, &Error{Code: CodeMethodNotFound, Message: "unknown method: " + method}
+}
+
+// write sends a message to the client.
+func (s *Server) write(msg message) {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Warn("failed to encode JSON-RPC message", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return
+	}
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		slog.Debug("failed to write to JSON-RPC client", "error", err)
+	}
+}
+
+// toError converts a backend error to a JSON-RPC error.
+func toError(err error) *Error {
+	var rpcErr *Error
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case errors.Is(err, server.ErrNotFound), errors.Is(err, conversation.ErrNotFound), errors.Is(err, runqueue.ErrNotFound):
+		return &Error{Code: CodeNotFound, Message: err.Error()}
+	case errors.Is(err, conversation.ErrReadOnly):
+		return &Error{Code: CodeReadOnly, Message: err.Error()}
+	}
+	return &Error{Code: CodeInternalError, Message: err.Error()}
+}
+
+// idOrNull returns id, or JSON null for a request without one.
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
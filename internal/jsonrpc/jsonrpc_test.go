@@ -0,0 +1,186 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/runqueue"
+)
+
+// fakeBackend keeps conversations in memory and records queued messages.
+type fakeBackend struct {
+	conversations map[string]*conversation.Conversation
+	runs          []runqueue.Item
+}
+
+func (b *fakeBackend) ListConversations() ([]conversation.Summary, error) {
+	var summaries []conversation.Summary
+	for _, conv := range b.conversations {
+		summaries = append(summaries, conv.ToSummary())
+	}
+	return summaries, nil
+}
+
+func (b *fakeBackend) NewConversation() (*conversation.Conversation, error) {
+	conv := conversation.New()
+	b.conversations[conv.ID] = conv
+	return conv, nil
+}
+
+func (b *fakeBackend) GetConversation(id string) (*conversation.Conversation, error) {
+	conv, ok := b.conversations[id]
+	if !ok {
+		return nil, conversation.ErrNotFound
+	}
+	return conv, nil
+}
+
+func (b *fakeBackend) SendMessage(conversationID, text string) (runqueue.Item, error) {
+	item := runqueue.Item{ID: "run-1", Task: text, ConversationID: conversationID, Status: runqueue.StatusQueued}
+	b.runs = append(b.runs, item)
+	return item, nil
+}
+
+func (b *fakeBackend) ListRuns() []runqueue.Item {
+	return b.runs
+}
+
+func (b *fakeBackend) CancelRun(id string) error {
+	return runqueue.ErrNotFound
+}
+
+// reply is a response or notification as the client sees it.
+type reply struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// testClient runs a server and talks to it line by line.
+type testClient struct {
+	t       *testing.T
+	in      io.WriteCloser
+	replies chan reply
+}
+
+func startServer(t *testing.T, s *Server) *testClient {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	requests, in := io.Pipe()
+	out, replies := io.Pipe()
+	go func() {
+		s.Serve(ctx, requests, replies)
+		replies.Close()
+	}()
+
+	c := &testClient{t: t, in: in, replies: make(chan reply, 16)}
+	go func() {
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			var r reply
+			json.Unmarshal(scanner.Bytes(), &r)
+			c.replies <- r
+		}
+	}()
+	t.Cleanup(func() {
+		in.Close()
+		cancel()
+	})
+	return c
+}
+
+func (c *testClient) send(line string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.in, line+"\n"); err != nil {
+		c.t.Fatalf("write failed: %v", err)
+	}
+}
+
+func (c *testClient) next() reply {
+	c.t.Helper()
+	select {
+	case r := <-c.replies:
+		return r
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for a reply")
+		return reply{}
+	}
+}
+
+func TestServer_Methods(t *testing.T) {
+	backend := &fakeBackend{conversations: map[string]*conversation.Conversation{}}
+	c := startServer(t, New(backend))
+
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"sendMessage","params":{"text":"list files"}}`)
+	r := c.next()
+	var run runqueue.Item
+	if err := json.Unmarshal(r.Result, &run); err != nil || r.Error != nil {
+		t.Fatalf("sendMessage = %+v", r)
+	}
+	if run.Task != "list files" || backend.conversations[run.ConversationID] == nil {
+		t.Errorf("run = %+v, want it queued in a new conversation", run)
+	}
+
+	c.send(`{"jsonrpc":"2.0","id":2,"method":"listConversations"}`)
+	var summaries []conversation.Summary
+	json.Unmarshal(c.next().Result, &summaries)
+	if len(summaries) != 1 || summaries[0].ID != run.ConversationID {
+		t.Errorf("listConversations = %+v", summaries)
+	}
+
+	c.send(`{"jsonrpc":"2.0","id":"three","method":"getConversation","params":{"id":"missing"}}`)
+	if r := c.next(); string(r.ID) != `"three"` || r.Error == nil || r.Error.Code != CodeNotFound {
+		t.Errorf("getConversation(missing) = %+v, want not found", r)
+	}
+
+	c.send(`{"jsonrpc":"2.0","id":4,"method":"sendMessage","params":{"text":" "}}`)
+	if r := c.next(); r.Error == nil || r.Error.Code != CodeInvalidParams {
+		t.Errorf("sendMessage(empty) = %+v, want invalid params", r)
+	}
+
+	c.send(`{"jsonrpc":"2.0","id":5,"method":"deleteEverything"}`)
+	if r := c.next(); r.Error == nil || r.Error.Code != CodeMethodNotFound {
+		t.Errorf("unknown method = %+v", r)
+	}
+
+	c.send(`not json`)
+	if r := c.next(); r.Error == nil || r.Error.Code != CodeParseError {
+		t.Errorf("invalid JSON = %+v", r)
+	}
+
+	// Notifications get no reply, so the next reply answers id 6
+	c.send(`{"jsonrpc":"2.0","method":"listRuns"}`)
+	c.send(`{"jsonrpc":"2.0","id":6,"method":"listRuns"}`)
+	if r := c.next(); string(r.ID) != "6" || !strings.Contains(string(r.Result), "run-1") {
+		t.Errorf("listRuns = %+v", r)
+	}
+}
+
+func TestServer_StreamSteps(t *testing.T) {
+	s := New(&fakeBackend{conversations: map[string]*conversation.Conversation{}})
+	c := startServer(t, s)
+
+	// Make sure Serve is running before publishing
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"listRuns"}`)
+	c.next()
+
+	s.Publish("agent:step", "ignored")
+	c.send(`{"jsonrpc":"2.0","id":2,"method":"streamSteps"}`)
+	if r := c.next(); string(r.ID) != "2" || r.Error != nil {
+		t.Fatalf("streamSteps = %+v, want the reply before any event", r)
+	}
+
+	s.Publish("agent:complete", "Done")
+	r := c.next()
+	if r.Method != EventMethod || string(r.Params) != `{"data":["Done"],"event":"agent:complete"}` {
+		t.Errorf("notification = %s %s", r.Method, r.Params)
+	}
+}
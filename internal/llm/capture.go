@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// captureSize is how many exchanges the capture keeps.
+	captureSize = 50
+	// maxCapturedBody is how much of each request and response body is
+	// kept.
+	maxCapturedBody = 256 << 10
+)
+
+// Exchange is a captured request to the provider and its response.
+type Exchange struct {
+	Time            time.Time         `json:"time"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     string            `json:"request_body"`
+	Status          int               `json:"status,omitempty"` // 0 if no response arrived
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	DurationMs      int64             `json:"duration_ms"`
+	Error           string            `json:"error,omitempty"`
+	Truncated       bool              `json:"truncated,omitempty"` // A body was longer than 256 KB and was cut
+}
+
+var (
+	capturing atomic.Bool
+
+	captureMu   sync.Mutex
+	captured    = make([]Exchange, captureSize)
+	captureNext int
+	captureLen  int
+)
+
+// SetCapture turns capturing of requests to the provider on or off. While
+// on, the full bodies of the last 50 requests and responses are kept in
+// memory, with keys masked, for Trace.
+func SetCapture(on bool) {
+	capturing.Store(on)
+}
+
+// Trace returns the captured exchanges, oldest first.
+func Trace() []Exchange {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	out := make([]Exchange, captureLen)
+	start := captureNext - captureLen
+	for i := range out {
+		out[i] = captured[(start+i+captureSize)%captureSize]
+	}
+	return out
+}
+
+// ClearTrace forgets the captured exchanges.
+func ClearTrace() {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	captured = make([]Exchange, captureSize)
+	captureNext, captureLen = 0, 0
+}
+
+func capture(e Exchange) {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	captured[captureNext] = e
+	captureNext = (captureNext + 1) % captureSize
+	if captureLen < captureSize {
+		captureLen++
+	}
+}
+
+// captureTransport records exchanges while capturing is on.
+type captureTransport struct {
+	base http.RoundTripper
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !capturing.Load() {
+		return t.base.RoundTrip(req)
+	}
+
+	e := Exchange{
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            maskURL(req.URL),
+		RequestHeaders: maskHeaders(req.Header),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		e.RequestBody, e.Truncated = capBody(body)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	e.DurationMs = time.Since(e.Time).Milliseconds()
+	if err != nil {
+		e.Error = err.Error()
+		capture(e)
+		return nil, err
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	e.Status = resp.StatusCode
+	e.ResponseHeaders = maskHeaders(resp.Header)
+	var truncated bool
+	e.ResponseBody, truncated = capBody(body)
+	e.Truncated = e.Truncated || truncated
+	if readErr != nil {
+		e.Error = readErr.Error()
+	}
+	capture(e)
+	return resp, readErr
+}
+
+func capBody(body []byte) (string, bool) {
+	if len(body) > maxCapturedBody {
+		return strings.ToValidUTF8(string(body[:maxCapturedBody]), ""), true
+	}
+	return string(body), false
+}
+
+// secretHeaderParts mark headers and query parameters whose values are
+// masked.
+var secretHeaderParts = []string{"authorization", "key", "token", "secret", "cookie"}
+
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range secretHeaderParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// mask hides a secret, keeping its last four characters so the user can
+// tell which key was sent.
+func mask(secret string) string {
+	scheme := ""
+	if i := strings.IndexByte(secret, ' '); i >= 0 {
+		scheme, secret = secret[:i+1], secret[i+1:]
+	}
+	if len(secret) <= 8 {
+		return scheme + "****"
+	}
+	return scheme + "****" + secret[len(secret)-4:]
+}
+
+func maskHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		value := strings.Join(values, ", ")
+		if isSecretName(name) {
+			value = mask(value)
+		}
+		out[name] = value
+	}
+	return out
+}
+
+func maskURL(u *url.URL) string {
+	masked := *u
+	masked.User = nil
+	q := masked.Query()
+	changed := false
+	for name, values := range q {
+		if isSecretName(name) {
+			for i := range values {
+				values[i] = mask(values[i])
+			}
+			changed = true
+		}
+	}
+	if changed {
+		masked.RawQuery = q.Encode()
+	}
+	return masked.String()
+}
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/config"
+)
+
+func TestCapture(t *testing.T) {
+	ClearTrace()
+	SetCapture(true)
+	defer func() {
+		SetCapture(false)
+		ClearTrace()
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "hi"}}]}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(&config.Config{APIKey: "sk-capture-test-key-1234", Endpoint: server.URL, Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hello there"}}, nil)
+	if err != nil || resp.Content != "hi" {
+		t.Fatalf("ChatCompletion = %+v, %v; capturing must not change the response", resp, err)
+	}
+
+	trace := Trace()
+	if len(trace) != 1 {
+		t.Fatalf("captured %d exchanges, want 1", len(trace))
+	}
+	e := trace[0]
+	if e.Method != "POST" || !strings.HasSuffix(e.URL, "/chat/completions") || e.Status != 200 {
+		t.Errorf("exchange = %+v", e)
+	}
+	if !strings.Contains(e.RequestBody, "hello there") || !strings.Contains(e.ResponseBody, `"content": "hi"`) {
+		t.Errorf("bodies = %q, %q", e.RequestBody, e.ResponseBody)
+	}
+	if auth := e.RequestHeaders["Authorization"]; auth != "Bearer ****1234" {
+		t.Errorf("Authorization = %q, want the key masked", auth)
+	}
+
+	// Nothing is captured with capture off
+	SetCapture(false)
+	client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "again"}}, nil)
+	if len(Trace()) != 1 {
+		t.Error("expected nothing captured with capture off")
+	}
+}
+
+func TestCapture_KeepsRecentExchanges(t *testing.T) {
+	ClearTrace()
+	defer ClearTrace()
+	for i := 0; i < captureSize+3; i++ {
+		capture(Exchange{Status: i})
+	}
+	trace := Trace()
+	if len(trace) != captureSize || trace[0].Status != 3 || trace[len(trace)-1].Status != captureSize+2 {
+		t.Errorf("kept %d exchanges from %d to %d", len(trace), trace[0].Status, trace[len(trace)-1].Status)
+	}
+}
+
+func TestCapture_Truncates(t *testing.T) {
+	body, truncated := capBody([]byte(strings.Repeat("x", maxCapturedBody+10)))
+	if !truncated || len(body) != maxCapturedBody {
+		t.Errorf("body is %d bytes, truncated = %v", len(body), truncated)
+	}
+}
+
+func TestMaskURL(t *testing.T) {
+	u, _ := url.Parse("https://generativelanguage.googleapis.com/v1/models?key=AIzaSyExample1234&alt=json")
+	got := maskURL(u)
+	if strings.Contains(got, "AIzaSyExample") || !strings.Contains(got, "alt=json") {
+		t.Errorf("maskURL = %q", got)
+	}
+}
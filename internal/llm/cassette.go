@@ -0,0 +1,229 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"agent-desktop/internal/config"
+)
+
+// Interaction is a recorded request to the provider and its response. The
+// URL's host and request headers aren't kept, and secrets in the rest are
+// replaced with config.RedactedSecret, so cassettes can be committed as
+// test fixtures.
+type Interaction struct {
+	Method       string          `json:"method"`
+	Path         string          `json:"path"` // Path and query, with secret parameters masked
+	RequestBody  json.RawMessage `json:"request_body,omitempty"`
+	Status       int             `json:"status"`
+	ContentType  string          `json:"content_type,omitempty"`
+	ResponseBody json.RawMessage `json:"response_body,omitempty"`
+}
+
+// cassetteFile is the format of a cassette on disk.
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// cassette is a file of interactions being recorded or replayed.
+type cassette struct {
+	path   string
+	mode   string
+	scrub  *config.Scrubber
+	mu     sync.Mutex
+	played []bool // Interactions already replayed
+	file   cassetteFile
+}
+
+var activeCassette atomic.Pointer[cassette]
+
+// SetCassette records exchanges with the provider to the cassette at path,
+// or replays them from it, depending on mode (config.CassetteRecord or
+// config.CassetteReplay, the default). An empty path turns cassettes off.
+// Recording starts a new cassette, saved after each exchange, with secrets
+// and well-known token formats scrubbed from it. Replaying answers each
+// request with the first unplayed interaction of the same method, path, and
+// body, and fails requests with none without contacting the provider.
+// Setting the cassette already in use leaves it as it is.
+func SetCassette(path, mode string, secrets []string) error {
+	if path == "" {
+		activeCassette.Store(nil)
+		return nil
+	}
+	if mode == "" {
+		mode = config.CassetteReplay
+	}
+	if c := activeCassette.Load(); c != nil && c.path == path && c.mode == mode {
+		return nil
+	}
+
+	c := &cassette{path: path, mode: mode, scrub: config.NewScrubber(secrets)}
+	switch mode {
+	case config.CassetteRecord:
+	case config.CassetteReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &c.file); err != nil {
+			return fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		c.played = make([]bool, len(c.file.Interactions))
+	default:
+		return fmt.Errorf("unknown cassette mode: %s", mode)
+	}
+	activeCassette.Store(c)
+	logger.Info("using cassette", "path", path, "mode", mode)
+	return nil
+}
+
+// cassetteTransport records or replays exchanges while a cassette is set.
+type cassetteTransport struct {
+	base http.RoundTripper
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := activeCassette.Load()
+	if c == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	in := Interaction{Method: req.Method, Path: cassettePath(req.URL), RequestBody: c.scrubBody(body)}
+
+	if c.mode == config.CassetteReplay {
+		out, ok := c.replay(in)
+		if !ok {
+			return nil, fmt.Errorf("no matching interaction in cassette %s for %s %s", c.path, in.Method, in.Path)
+		}
+		header := make(http.Header)
+		if out.ContentType != "" {
+			header.Set("Content-Type", out.ContentType)
+		}
+		respBody := rawBody(out.ResponseBody)
+		return &http.Response{
+			Status:        strconv.Itoa(out.Status) + " " + http.StatusText(out.Status),
+			StatusCode:    out.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(respBody)),
+			ContentLength: int64(len(respBody)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	if err != nil {
+		return resp, err
+	}
+	in.Status = resp.StatusCode
+	in.ContentType = resp.Header.Get("Content-Type")
+	in.ResponseBody = c.scrubBody(respBody)
+	if err := c.record(in); err != nil {
+		logger.Warn("failed to save cassette", "path", c.path, "error", err)
+	}
+	return resp, nil
+}
+
+// replay returns the interaction answering in and marks it played.
+func (c *cassette) replay(in Interaction) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	want := canonicalBody(in.RequestBody)
+	for i, out := range c.file.Interactions {
+		if !c.played[i] && out.Method == in.Method && out.Path == in.Path && canonicalBody(out.RequestBody) == want {
+			c.played[i] = true
+			return out, true
+		}
+	}
+	return Interaction{}, false
+}
+
+// record adds an interaction to the cassette and saves it.
+func (c *cassette) record(in Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.file.Interactions = append(c.file.Interactions, in)
+	data, err := json.MarshalIndent(c.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// scrubBody returns body with secrets scrubbed, kept as JSON if it is JSON
+// and as a JSON string otherwise, such as a stream of server-sent events.
+func (c *cassette) scrubBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	text := c.scrub.Scrub(string(body))
+	if json.Valid([]byte(text)) {
+		return json.RawMessage(text)
+	}
+	quoted, _ := json.Marshal(text)
+	return quoted
+}
+
+// rawBody returns the body stored by scrubBody.
+func rawBody(stored json.RawMessage) []byte {
+	var text string
+	if json.Unmarshal(stored, &text) == nil {
+		return []byte(text)
+	}
+	return stored
+}
+
+// canonicalBody returns a JSON body re-encoded with its keys sorted and
+// without insignificant space, so equal bodies compare equal.
+func canonicalBody(body json.RawMessage) string {
+	var v interface{}
+	if json.Unmarshal(body, &v) != nil {
+		return string(body)
+	}
+	canonical, _ := json.Marshal(v)
+	return string(canonical)
+}
+
+// cassettePath returns a request's path and query, with secret query
+// parameters masked.
+func cassettePath(u *url.URL) string {
+	masked, err := url.Parse(maskURL(u))
+	if err != nil {
+		return u.Path
+	}
+	return masked.RequestURI()
+}
@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/config"
+)
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures", "chat.json")
+	const key = "sk-cassette-test-key-1234"
+	defer SetCassette("", "", nil)
+
+	replies := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replies++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices": [{"message": {"role": "assistant", "content": "reply %d, my key is %s"}}]}`, replies, key)
+	}))
+	cfg := &config.Config{APIKey: key, Endpoint: server.URL, Model: "gpt-4o"}
+	if err := SetCassette(path, config.CassetteRecord, cfg.Secrets()); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"hello", "again"} {
+		if _, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: content}}, nil); err != nil {
+			t.Fatalf("recording: %v", err)
+		}
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), key) || strings.Contains(string(data), server.URL) {
+		t.Errorf("cassette holds the key or host:\n%s", data)
+	}
+
+	// Replay answers without the server, matching requests by body
+	if err := SetCassette(path, config.CassetteReplay, cfg.Secrets()); err != nil {
+		t.Fatal(err)
+	}
+	client, _ = NewClient(cfg)
+	for content, want := range map[string]string{"again": "reply 2", "hello": "reply 1"} {
+		resp, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: content}}, nil)
+		if err != nil || resp.Content != want+", my key is "+config.RedactedSecret {
+			t.Fatalf("replaying %q = %+v, %v", content, resp, err)
+		}
+	}
+	if _, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hello"}}, nil); err == nil || !strings.Contains(err.Error(), "no matching interaction") {
+		t.Errorf("replaying past the cassette's end: error = %v", err)
+	}
+
+	// A request that was never recorded fails even with interactions left
+	SetCassette("", "", nil)
+	SetCassette(path, config.CassetteReplay, cfg.Secrets())
+	if _, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "something else"}}, nil); err == nil || !strings.Contains(err.Error(), "no matching interaction") {
+		t.Errorf("replaying an unrecorded request: error = %v", err)
+	}
+}
+
+func TestSetCassette_Errors(t *testing.T) {
+	defer SetCassette("", "", nil)
+	dir := t.TempDir()
+	if err := SetCassette(filepath.Join(dir, "missing.json"), "", nil); err == nil {
+		t.Error("replaying a missing cassette: expected an error")
+	}
+	if err := SetCassette(filepath.Join(dir, "new.json"), "rewind", nil); err == nil {
+		t.Error("unknown mode: expected an error")
+	}
+	if activeCassette.Load() != nil {
+		t.Error("a failed SetCassette set a cassette")
+	}
+}
@@ -7,21 +7,42 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"agent-desktop/internal/config"
+	"agent-desktop/internal/logging"
 	"agent-desktop/internal/tools"
+	"agent-desktop/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// logger records requests to the model.
+var logger = logging.For("llm")
+
 // Message represents a chat message.
 type Message struct {
-	Role       string     `json:"role"` // system, user, assistant, tool
-	Content    string     `json:"content"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Role       string       `json:"role"` // system, user, assistant, tool
+	Content    string       `json:"content"`
+	ToolCalls  []ToolCall   `json:"tool_calls,omitempty"`
+	ToolCallID string       `json:"tool_call_id,omitempty"`
+	Meta       *MessageMeta `json:"meta,omitempty"` // Stored with the conversation; never sent to the API
+}
+
+// MessageMeta records when a message was created and, for assistant
+// messages, which model produced it and what it cost.
+type MessageMeta struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Model     string      `json:"model,omitempty"`
+	Usage     *TokenUsage `json:"usage,omitempty"`
+	Cost      float64     `json:"cost,omitempty"` // Estimated US dollars
+	Step      int         `json:"step,omitempty"` // Agent step that produced the message
 }
 
 // ToolCall represents a tool call from the assistant.
@@ -56,6 +77,14 @@ type Client struct {
 	endpoint   string
 	apiKey     string
 	model      string
+	profile    string // Provider profile the client was made for, for Health
+
+	embeddingModel string // Model for Embeddings, empty for the API's default
+	embeddingAPI   string // config.EmbeddingAPIOpenAI or config.EmbeddingAPIOllama
+
+	tools *toolsCache // Shared with copies made by WithModel and WithProfile
+
+	maxTokens int // Completion tokens to ask for at most (0 = the API's default)
 }
 
 // NewClient creates a new OpenAI-compatible client from the given configuration.
@@ -70,19 +99,31 @@ func NewClient(cfg *config.Config) (*Client, error) {
 
 	endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
 
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
-		httpClient: &http.Client{Timeout: 120 * time.Second},
+		httpClient: httpClient,
 		endpoint:   endpoint,
 		apiKey:     cfg.APIKey,
 		model:      cfg.Model,
+
+		embeddingModel: cfg.EmbeddingModel,
+		embeddingAPI:   embeddingAPI(cfg),
+
+		tools: &toolsCache{},
 	}, nil
 }
 
 // chatRequest is the request body for chat completions.
 type chatRequest struct {
-	Model    string        `json:"model"`
-	Messages []chatMessage `json:"messages"`
-	Tools    []chatTool    `json:"tools,omitempty"`
+	Model    string          `json:"model"`
+	Messages []chatMessage   `json:"messages"`
+	Tools    json.RawMessage `json:"tools,omitempty"` // Encoded []chatTool
+
+	MaxTokens int `json:"max_tokens,omitempty"`
 }
 
 type chatMessage struct {
@@ -103,6 +144,61 @@ type chatToolDefinition struct {
 	Parameters  interface{} `json:"parameters"`
 }
 
+// toolsCache keeps the tools of the last request encoded, so the steps of
+// a run, which send the same tools each time, encode them once.
+type toolsCache struct {
+	mu      sync.Mutex
+	hash    uint64 // hashTools of the encoded definitions
+	encoded json.RawMessage
+}
+
+// hashTools returns a hash of the content of defs. fmt prints maps with
+// sorted keys, so equal definitions hash the same.
+func hashTools(defs []tools.ToolDefinition) uint64 {
+	h := fnv.New64a()
+	for _, def := range defs {
+		fmt.Fprintf(h, "%q %q %v\x00", def.Function.Name, def.Function.Description, def.Function.Parameters)
+	}
+	return h.Sum64()
+}
+
+// encode returns defs in API format, reusing the last encoding if defs has
+// the same content as last time. A nil cache encodes every time.
+func (tc *toolsCache) encode(defs []tools.ToolDefinition) (json.RawMessage, error) {
+	if len(defs) == 0 {
+		return nil, nil
+	}
+	var hash uint64
+	if tc != nil {
+		hash = hashTools(defs)
+		tc.mu.Lock()
+		defer tc.mu.Unlock()
+		if tc.encoded != nil && tc.hash == hash {
+			return tc.encoded, nil
+		}
+	}
+
+	chatTools := make([]chatTool, len(defs))
+	for i, def := range defs {
+		chatTools[i] = chatTool{
+			Type: "function",
+			Function: chatToolDefinition{
+				Name:        def.Function.Name,
+				Description: def.Function.Description,
+				Parameters:  def.Function.Parameters,
+			},
+		}
+	}
+	encoded, err := json.Marshal(chatTools)
+	if err != nil {
+		return nil, err
+	}
+	if tc != nil {
+		tc.hash, tc.encoded = hash, encoded
+	}
+	return encoded, nil
+}
+
 type chatToolCall struct {
 	ID       string           `json:"id"`
 	Type     string           `json:"type"`
@@ -143,6 +239,33 @@ type chatResponse struct {
 
 // ChatCompletion sends a chat completion request with optional tool definitions.
 func (c *Client) ChatCompletion(ctx context.Context, messages []Message, toolDefs []tools.ToolDefinition) (*Response, error) {
+	ctx, span := tracing.Start(ctx, "chat "+c.model,
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.request.model", c.model),
+		attribute.Int("gen_ai.request.messages", len(messages)),
+		attribute.Int("gen_ai.request.tools", len(toolDefs)),
+	)
+	defer span.End()
+
+	start := time.Now()
+	resp, err := c.chatCompletion(ctx, messages, toolDefs)
+	recordHealth(c.profile, c.endpoint, time.Since(start), err)
+	if err != nil {
+		span.RecordError(err)
+		tracing.Fail(span, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("gen_ai.response.tool_calls", len(resp.ToolCalls)))
+	if resp.Usage != nil {
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", resp.Usage.PromptTokens),
+			attribute.Int("gen_ai.usage.output_tokens", resp.Usage.CompletionTokens),
+		)
+	}
+	return resp, nil
+}
+
+func (c *Client) chatCompletion(ctx context.Context, messages []Message, toolDefs []tools.ToolDefinition) (*Response, error) {
 	// Convert messages to API format
 	chatMessages := make([]chatMessage, len(messages))
 	for i, msg := range messages {
@@ -173,28 +296,18 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []Message, toolDef
 	}
 
 	// Convert tool definitions to API format
-	var chatTools []chatTool
-	if len(toolDefs) > 0 {
-		chatTools = make([]chatTool, len(toolDefs))
-		for i, def := range toolDefs {
-			chatTools[i] = chatTool{
-				Type: "function",
-				Function: chatToolDefinition{
-					Name:        def.Function.Name,
-					Description: def.Function.Description,
-					Parameters:  def.Function.Parameters,
-				},
-			}
-		}
+	chatTools, err := c.tools.encode(toolDefs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tools: %w", err)
 	}
 
 	// Build request body
 	reqBody := chatRequest{
 		Model:    c.model,
 		Messages: chatMessages,
-	}
-	if len(chatTools) > 0 {
-		reqBody.Tools = chatTools
+		Tools:    chatTools,
+
+		MaxTokens: c.maxTokens,
 	}
 
 	bodyBytes, err := json.Marshal(reqBody)
@@ -215,9 +328,11 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []Message, toolDef
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 
 	// Make request
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		logger.Warn("chat completion request failed", "model", c.model, "error", err, "duration", time.Since(start))
+		return nil, requestError(err)
 	}
 	defer resp.Body.Close()
 
@@ -227,9 +342,14 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []Message, toolDef
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	logger.Debug("chat completion", "model", c.model, "status", resp.StatusCode, "bytes", len(respBody), "duration", time.Since(start))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
 	// Check for HTTP errors
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(respBody))
+		apiErr := responseError(resp.StatusCode, respBody)
+		logger.Warn("chat completion returned an error", "model", c.model, "status", resp.StatusCode, "kind", apiErr.Kind)
+		return nil, apiErr
 	}
 
 	// Parse response
@@ -239,8 +359,12 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []Message, toolDef
 	}
 
 	// Check for API error in response
-	if chatResp.Error != nil {
-		return nil, fmt.Errorf("API error: %s", chatResp.Error.Message)
+	if e := chatResp.Error; e != nil {
+		code := e.Type
+		if e.Code != "" {
+			code = e.Code
+		}
+		return nil, &Error{Kind: classifyResponse(0, code, e.Message), Message: "API error: " + e.Message}
 	}
 
 	// Parse response
@@ -277,6 +401,25 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []Message, toolDef
 	return result, nil
 }
 
+// WithModel returns a copy of the client that requests the given model.
+// The copy shares the underlying HTTP client. An empty model returns c unchanged.
+func (c *Client) WithModel(model string) *Client {
+	if model == "" || model == c.model {
+		return c
+	}
+	clone := *c
+	clone.model = model
+	return &clone
+}
+
+// WithProfile returns a copy of the client whose requests are counted
+// under the named provider profile in Health.
+func (c *Client) WithProfile(name string) *Client {
+	clone := *c
+	clone.profile = name
+	return &clone
+}
+
 // GetModel returns the model name.
 func (c *Client) GetModel() string {
 	return c.model
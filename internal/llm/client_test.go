@@ -1,9 +1,22 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 
 	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestNewClient_ValidConfig(t *testing.T) {
@@ -213,3 +226,124 @@ func TestClient_GetEndpoint(t *testing.T) {
 
 // Note: Actual API call tests would require mocking or integration test setup
 // The ChatCompletion method will be tested via integration tests with a real endpoint
+
+func TestClient_WithModel(t *testing.T) {
+	client, err := NewClient(&config.Config{
+		APIKey:   "test-key",
+		Endpoint: "https://api.openai.com/v1",
+		Model:    "gpt-4o",
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	other := client.WithModel("gpt-4o-mini")
+	if other.GetModel() != "gpt-4o-mini" {
+		t.Errorf("model = %q, want %q", other.GetModel(), "gpt-4o-mini")
+	}
+	if client.GetModel() != "gpt-4o" {
+		t.Error("WithModel should not modify the original client")
+	}
+	if client.WithModel("") != client {
+		t.Error("WithModel with empty model should return the same client")
+	}
+}
+
+func TestClient_ChatCompletion_Traced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.Config{APIKey: "key", Endpoint: server.URL + "/v1", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if _, err := client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hello"}}, nil); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	ended := recorder.Ended()
+	if len(ended) != 1 || ended[0].Name() != "chat gpt-4o" {
+		t.Fatalf("expected one span named chat gpt-4o, got %d", len(ended))
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range ended[0].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["gen_ai.usage.input_tokens"].AsInt64() != 12 || attrs["gen_ai.usage.output_tokens"].AsInt64() != 3 {
+		t.Errorf("usage attributes = %v", attrs)
+	}
+	if attrs["http.response.status_code"].AsInt64() != http.StatusOK {
+		t.Errorf("status attribute = %v", attrs["http.response.status_code"])
+	}
+}
+
+func TestClient_ChatCompletion_CachesTools(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Tools json.RawMessage `json:"tools"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		bodies = append(bodies, string(req.Tools))
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	messages := []Message{{Role: "user", Content: "hello"}}
+	defs := tools.GetToolDefinitions()
+	for range 2 {
+		if _, err := client.WithModel("gpt-4o-mini").ChatCompletion(context.Background(), messages, defs); err != nil {
+			t.Fatalf("ChatCompletion failed: %v", err)
+		}
+	}
+	cached := client.tools.encoded
+	if _, err := client.ChatCompletion(context.Background(), messages, defs); err != nil {
+		t.Fatal(err)
+	}
+	if &client.tools.encoded[0] != &cached[0] {
+		t.Error("the same tools were encoded again")
+	}
+
+	// The same slice changed in place is encoded again
+	changed := slices.Clone(defs)
+	if _, err := client.ChatCompletion(context.Background(), messages, changed); err != nil {
+		t.Fatal(err)
+	}
+	changed[0].Function.Description = "Changed in place"
+	if _, err := client.ChatCompletion(context.Background(), messages, changed); err != nil {
+		t.Fatal(err)
+	}
+
+	other := []tools.ToolDefinition{defs[0]}
+	if _, err := client.ChatCompletion(context.Background(), messages, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ChatCompletion(context.Background(), messages, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 7 || bodies[0] != bodies[2] || bodies[3] != bodies[0] || !strings.Contains(bodies[0], `"name":"read_file"`) {
+		t.Fatalf("tools sent = %d requests, first %.80s", len(bodies), bodies[0])
+	}
+	if !strings.Contains(bodies[4], "Changed in place") {
+		t.Errorf("stale tools sent after changing them in place = %.80s", bodies[4])
+	}
+	var sent []chatTool
+	if err := json.Unmarshal([]byte(bodies[5]), &sent); err != nil || len(sent) != 1 || sent[0].Function.Name != defs[0].Function.Name {
+		t.Errorf("tools sent for a different list = %s", bodies[5])
+	}
+	if bodies[6] != "" {
+		t.Errorf("tools sent without tools = %s", bodies[6])
+	}
+}
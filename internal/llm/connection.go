@@ -2,6 +2,9 @@ package llm
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"agent-desktop/internal/config"
@@ -41,3 +44,44 @@ func TestConnection(cfg *config.Config) (bool, string) {
 
 	return true, "Connected successfully to " + cfg.Endpoint + "!"
 }
+
+// probeTimeout bounds the reachability check in ProbeEndpoint.
+const probeTimeout = 10 * time.Second
+
+// ProbeEndpoint checks that the configured endpoint answers by requesting
+// its model list, without spending any tokens. It returns field diagnostics
+// for an unreachable endpoint or a rejected API key, or nil if the endpoint
+// responded.
+func ProbeEndpoint(ctx context.Context, cfg *config.Config) []config.FieldError {
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return []config.FieldError{{Field: "ca_cert_path", Message: err.Error(), Severity: config.SeverityError}}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(cfg.Endpoint, "/")+"/models", nil)
+	if err != nil {
+		return []config.FieldError{{Field: "endpoint", Message: "invalid endpoint: " + err.Error(), Severity: config.SeverityError}}
+	}
+	if cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return []config.FieldError{{Field: "endpoint", Message: "endpoint unreachable: " + err.Error(), Severity: config.SeverityError}}
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return []config.FieldError{{Field: "api_key", Message: "the endpoint rejected the API key", Severity: config.SeverityError}}
+	case resp.StatusCode == http.StatusNotFound:
+		return []config.FieldError{{Field: "endpoint", Message: "endpoint has no /models route; check the base URL", Severity: config.SeverityWarning}}
+	case resp.StatusCode >= 500:
+		return []config.FieldError{{Field: "endpoint", Message: fmt.Sprintf("endpoint returned status %d", resp.StatusCode), Severity: config.SeverityWarning}}
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package llm
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -52,3 +55,57 @@ func TestTestConnection_InvalidEndpoint(t *testing.T) {
 
 // Note: Testing successful connection requires a real API endpoint
 // This should be done via integration tests with proper credentials
+
+func TestProbeEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		field    string
+		severity string
+	}{
+		{"ok", http.StatusOK, "", ""},
+		{"bad key", http.StatusUnauthorized, "api_key", config.SeverityError},
+		{"wrong path", http.StatusNotFound, "endpoint", config.SeverityWarning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotAuth string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{APIKey: "key", Endpoint: server.URL + "/v1/", Model: "m"}
+			diags := ProbeEndpoint(context.Background(), cfg)
+
+			if gotPath != "/v1/models" {
+				t.Errorf("path = %q, want /v1/models", gotPath)
+			}
+			if gotAuth != "Bearer key" {
+				t.Errorf("Authorization = %q", gotAuth)
+			}
+			if tt.field == "" {
+				if len(diags) != 0 {
+					t.Errorf("unexpected diagnostics: %+v", diags)
+				}
+				return
+			}
+			if len(diags) != 1 || diags[0].Field != tt.field || diags[0].Severity != tt.severity {
+				t.Errorf("diagnostics = %+v, want %s %s", diags, tt.severity, tt.field)
+			}
+		})
+	}
+}
+
+func TestProbeEndpoint_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	diags := ProbeEndpoint(context.Background(), &config.Config{APIKey: "key", Endpoint: url, Model: "m"})
+	if len(diags) != 1 || diags[0].Field != "endpoint" || diags[0].Severity != config.SeverityError {
+		t.Errorf("diagnostics = %+v, want endpoint error", diags)
+	}
+}
@@ -0,0 +1,293 @@
+package llm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+)
+
+// Check statuses in a connection diagnosis.
+const (
+	CheckPassed  = "passed"
+	CheckWarning = "warning" // Works, but something looks off
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped" // Not run, usually because an earlier check failed
+)
+
+// Checks in a connection diagnosis, in the order they run.
+const (
+	CheckConfig      = "config"
+	CheckDNS         = "dns"
+	CheckTLS         = "tls"
+	CheckHTTP        = "http"
+	CheckAuth        = "auth"
+	CheckToolCalling = "tool_calling"
+	CheckLatency     = "latency"
+)
+
+// slowResponse is the round trip above which the latency check warns.
+const slowResponse = 15 * time.Second
+
+// Check is one step of a connection diagnosis.
+type Check struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	Hint       string `json:"hint,omitempty"` // What to try when the check didn't pass
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Diagnosis is the result of testing the connection to the configured
+// endpoint step by step, so a failure points at its cause.
+type Diagnosis struct {
+	Success   bool    `json:"success"` // The model answered and can call tools
+	Message   string  `json:"message"` // One-line summary
+	Endpoint  string  `json:"endpoint"`
+	Model     string  `json:"model"`
+	LatencyMS int64   `json:"latency_ms,omitempty"` // Round trip of a short chat request
+	Checks    []Check `json:"checks"`
+}
+
+// diagnosis collects checks, skipping the rest once one fails.
+type diagnosis struct {
+	Diagnosis
+	failed bool
+}
+
+// run runs a check unless an earlier one failed. fn returns the status,
+// message, and hint.
+func (d *diagnosis) run(name string, fn func() (string, string, string)) {
+	if d.failed {
+		d.Checks = append(d.Checks, Check{Name: name, Status: CheckSkipped, Message: "Skipped because an earlier check failed"})
+		return
+	}
+	start := time.Now()
+	status, message, hint := fn()
+	d.Checks = append(d.Checks, Check{Name: name, Status: status, Message: message, Hint: hint, DurationMS: time.Since(start).Milliseconds()})
+	if status == CheckFailed {
+		d.failed = true
+		if d.Message == "" {
+			d.Message = message
+		}
+	}
+}
+
+// Diagnose tests the connection to the configured endpoint one layer at a
+// time: configuration, DNS, TLS, HTTP, API key, tool calling, and latency.
+// Checks after the first failure are skipped.
+func Diagnose(ctx context.Context, cfg *config.Config) Diagnosis {
+	d := &diagnosis{Diagnosis: Diagnosis{Endpoint: cfg.Endpoint, Model: cfg.Model}}
+
+	var endpoint *url.URL
+	d.run(CheckConfig, func() (string, string, string) {
+		if err := cfg.Validate(); err != nil {
+			return CheckFailed, err.Error(), "Fix the highlighted settings"
+		}
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil || u.Host == "" {
+			return CheckFailed, "The endpoint is not a valid URL", "Use a base URL such as https://api.openai.com/v1"
+		}
+		endpoint = u
+		return CheckPassed, "Settings are complete", ""
+	})
+
+	d.run(CheckDNS, func() (string, string, string) {
+		host := endpoint.Hostname()
+		if net.ParseIP(host) != nil {
+			return CheckPassed, host + " is an IP address", ""
+		}
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return CheckFailed, "Could not resolve " + host + ": " + err.Error(),
+				"Check the host name in the endpoint, your network connection, and any VPN or proxy"
+		}
+		return CheckPassed, fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", ")), ""
+	})
+
+	d.run(CheckTLS, func() (string, string, string) {
+		return checkTLS(ctx, cfg, endpoint)
+	})
+
+	var status int
+	d.run(CheckHTTP, func() (string, string, string) {
+		var result string
+		var message, hint string
+		status, result, message, hint = checkModelsRoute(ctx, cfg)
+		return result, message, hint
+	})
+
+	d.run(CheckAuth, func() (string, string, string) {
+		switch {
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			return CheckFailed, "The endpoint rejected the API key", "Copy the key again from your provider's dashboard and check it hasn't expired"
+		case status >= 200 && status < 300:
+			return CheckPassed, "The endpoint accepted the API key", ""
+		}
+		return CheckSkipped, "Checked with the chat request instead", ""
+	})
+
+	var latency time.Duration
+	d.run(CheckToolCalling, func() (string, string, string) {
+		var result, message, hint string
+		latency, result, message, hint = checkToolCalling(ctx, cfg)
+		return result, message, hint
+	})
+
+	d.run(CheckLatency, func() (string, string, string) {
+		d.LatencyMS = latency.Milliseconds()
+		message := fmt.Sprintf("A short request took %d ms", d.LatencyMS)
+		if latency > slowResponse {
+			return CheckWarning, message, "The model is slow to answer; a smaller model or a closer region may help"
+		}
+		return CheckPassed, message, ""
+	})
+
+	d.Success = !d.failed
+	if d.Success {
+		d.Message = fmt.Sprintf("Connected to %s at %s with tool calling", cfg.Model, cfg.Endpoint)
+	}
+	return d.Diagnosis
+}
+
+// checkTLS completes a TLS handshake with an https endpoint and reports
+// the negotiated version and the certificate.
+func checkTLS(ctx context.Context, cfg *config.Config, endpoint *url.URL) (string, string, string) {
+	if endpoint.Scheme != "https" {
+		if isLocalHost(endpoint.Hostname()) {
+			return CheckSkipped, "Plain http to a local server", ""
+		}
+		return CheckWarning, "The endpoint uses plain http, so requests and the API key are not encrypted", "Use https unless the server is on a trusted network"
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return CheckFailed, err.Error(), "Check the CA certificate file in the TLS settings"
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.ServerName = endpoint.Hostname()
+
+	port := endpoint.Port()
+	if port == "" {
+		port = "443"
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	dialer := &tls.Dialer{Config: tlsConfig}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(endpoint.Hostname(), port))
+	if err != nil {
+		var unknownAuthority x509.UnknownAuthorityError
+		var invalid x509.CertificateInvalidError
+		var hostname x509.HostnameError
+		switch {
+		case errors.As(err, &unknownAuthority):
+			return CheckFailed, "The server's certificate is not signed by a trusted authority",
+				"If the server uses a private or self-signed certificate, set the CA certificate file in the TLS settings"
+		case errors.As(err, &invalid):
+			return CheckFailed, "The server's certificate is invalid: " + invalid.Error(),
+				"Check the server's certificate and your computer's clock"
+		case errors.As(err, &hostname):
+			return CheckFailed, "The server's certificate is for a different host: " + hostname.Error(),
+				"Check the host name in the endpoint"
+		}
+		return CheckFailed, "TLS connection failed: " + err.Error(), "Check the endpoint's port and that the server speaks https"
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	message := tls.VersionName(state.Version)
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		message += fmt.Sprintf(", certificate for %s valid until %s", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+	}
+	if cfg.InsecureSkipVerify {
+		return CheckWarning, message + " (not verified)", "Certificate verification is turned off; use a CA certificate file instead"
+	}
+	return CheckPassed, message, ""
+}
+
+// checkModelsRoute requests the endpoint's model list and returns the HTTP
+// status with the check result.
+func checkModelsRoute(ctx context.Context, cfg *config.Config) (int, string, string, string) {
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return 0, CheckFailed, err.Error(), "Check the CA certificate file in the TLS settings"
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(cfg.Endpoint, "/")+"/models", nil)
+	if err != nil {
+		return 0, CheckFailed, "Invalid endpoint: " + err.Error(), ""
+	}
+	if cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, CheckFailed, "The endpoint did not answer: " + err.Error(), "Check that the server is running and the port is right"
+	}
+	resp.Body.Close()
+
+	message := fmt.Sprintf("GET /models returned %s", resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return resp.StatusCode, CheckWarning, message, "The base URL usually ends in /v1"
+	case resp.StatusCode >= 500:
+		return resp.StatusCode, CheckWarning, message, "The server reported an error; it may be overloaded or misconfigured"
+	}
+	return resp.StatusCode, CheckPassed, message, ""
+}
+
+// checkToolCalling asks the model to call a tool and returns the round
+// trip time with the check result.
+func checkToolCalling(ctx context.Context, cfg *config.Config) (time.Duration, string, string, string) {
+	client, err := NewClient(cfg)
+	if err != nil {
+		return 0, CheckFailed, "Failed to create client: " + err.Error(), ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	messages := []Message{
+		{Role: "user", Content: "Call the report_ready tool now. Do not reply with text."},
+	}
+	start := time.Now()
+	resp, err := client.ChatCompletion(ctx, messages, []tools.ToolDefinition{probeTool})
+	latency := time.Since(start)
+	if err != nil {
+		hint := "Check the model name; the provider may not offer it"
+		if Classify(err) == ErrorAuth {
+			hint = "The endpoint rejected the API key"
+		}
+		return latency, CheckFailed, "The chat request failed: " + err.Error(), hint
+	}
+	for _, tc := range resp.ToolCalls {
+		if tc.Name == probeTool.Function.Name {
+			return latency, CheckPassed, cfg.Model + " called the test tool", ""
+		}
+	}
+	return latency, CheckFailed, cfg.Model + " answered but did not call tools",
+		"The agent needs a model with tool calling support; pick another model"
+}
+
+// isLocalHost reports whether host is this machine.
+func isLocalHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agent-desktop/internal/config"
+)
+
+const toolCallResponse = `{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"1","type":"function","function":{"name":"report_ready","arguments":"{}"}}]}}]}`
+
+// checkStatuses maps check names to their statuses.
+func checkStatuses(d Diagnosis) map[string]string {
+	statuses := make(map[string]string)
+	for _, c := range d.Checks {
+		statuses[c.Name] = c.Status
+	}
+	return statuses
+}
+
+func TestDiagnose(t *testing.T) {
+	server := newFakeProvider(t, toolCallResponse)
+	cfg := &config.Config{APIKey: "key", Endpoint: server.URL + "/v1", Model: "local-a"}
+
+	d := Diagnose(context.Background(), cfg)
+	if !d.Success {
+		t.Fatalf("expected success, got %+v", d)
+	}
+	want := map[string]string{
+		CheckConfig:      CheckPassed,
+		CheckDNS:         CheckPassed,
+		CheckTLS:         CheckSkipped,
+		CheckHTTP:        CheckPassed,
+		CheckAuth:        CheckPassed,
+		CheckToolCalling: CheckPassed,
+		CheckLatency:     CheckPassed,
+	}
+	if got := checkStatuses(d); len(got) != len(want) {
+		t.Errorf("checks = %v, want %v", got, want)
+	} else {
+		for name, status := range want {
+			if got[name] != status {
+				t.Errorf("%s = %q, want %q", name, got[name], status)
+			}
+		}
+	}
+}
+
+func TestDiagnose_NoToolCalling(t *testing.T) {
+	server := newFakeProvider(t, `{"choices":[{"message":{"role":"assistant","content":"Ready!"}}]}`)
+	cfg := &config.Config{APIKey: "key", Endpoint: server.URL + "/v1", Model: "local-a"}
+
+	d := Diagnose(context.Background(), cfg)
+	statuses := checkStatuses(d)
+	if d.Success || statuses[CheckToolCalling] != CheckFailed {
+		t.Errorf("expected tool calling to fail, got %+v", d)
+	}
+	if statuses[CheckLatency] != CheckSkipped {
+		t.Errorf("latency = %q, want skipped after a failure", statuses[CheckLatency])
+	}
+}
+
+func TestDiagnose_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid api key"}}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	cfg := &config.Config{APIKey: "wrong", Endpoint: server.URL + "/v1", Model: "m"}
+
+	d := Diagnose(context.Background(), cfg)
+	statuses := checkStatuses(d)
+	if d.Success || statuses[CheckAuth] != CheckFailed {
+		t.Errorf("expected auth to fail, got %+v", d)
+	}
+	if statuses[CheckHTTP] != CheckPassed {
+		t.Errorf("http = %q, want passed", statuses[CheckHTTP])
+	}
+}
+
+func TestDiagnose_TLS(t *testing.T) {
+	server, certPath := newTLSChatServer(t)
+
+	tests := []struct {
+		name   string
+		caPath string
+		want   string
+	}{
+		{"untrusted certificate", "", CheckFailed},
+		{"custom CA", certPath, CheckPassed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{APIKey: "key", Endpoint: server.URL, Model: "m", CACertPath: tt.caPath}
+			d := Diagnose(context.Background(), cfg)
+			for _, c := range d.Checks {
+				if c.Name != CheckTLS {
+					continue
+				}
+				if c.Status != tt.want {
+					t.Errorf("tls = %q (%s), want %q", c.Status, c.Message, tt.want)
+				}
+				if c.Status == CheckFailed && c.Hint == "" {
+					t.Error("expected a hint for the TLS failure")
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnose_InvalidConfig(t *testing.T) {
+	d := Diagnose(context.Background(), &config.Config{Endpoint: "https://api.openai.com/v1"})
+	if d.Success || d.Message == "" {
+		t.Errorf("expected a failed diagnosis with a message, got %+v", d)
+	}
+	for _, c := range d.Checks[1:] {
+		if c.Status != CheckSkipped {
+			t.Errorf("%s = %q, want skipped", c.Name, c.Status)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Default embedding models, used when Config.EmbeddingModel is empty.
+const (
+	DefaultEmbeddingModel       = "text-embedding-3-small"
+	DefaultOllamaEmbeddingModel = "nomic-embed-text"
+)
+
+// ollamaPort is the port Ollama listens on unless told otherwise.
+const ollamaPort = "11434"
+
+// embeddingAPI picks the embedding API for cfg: the configured one, or
+// Ollama's for endpoints on its default port.
+func embeddingAPI(cfg *config.Config) string {
+	if cfg.EmbeddingAPI != "" {
+		return cfg.EmbeddingAPI
+	}
+	if u, err := url.Parse(cfg.Endpoint); err == nil && u.Port() == ollamaPort {
+		return config.EmbeddingAPIOllama
+	}
+	return config.EmbeddingAPIOpenAI
+}
+
+// embeddingRequest is the request body of both embedding APIs.
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// openAIEmbeddingResponse is the response body of POST /embeddings.
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// ollamaEmbeddingResponse is the response body of Ollama's POST /api/embed.
+type ollamaEmbeddingResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// EmbeddingModel returns the model Embeddings requests.
+func (c *Client) EmbeddingModel() string {
+	switch {
+	case c.embeddingModel != "":
+		return c.embeddingModel
+	case c.embeddingAPI == config.EmbeddingAPIOllama:
+		return DefaultOllamaEmbeddingModel
+	}
+	return DefaultEmbeddingModel
+}
+
+// Embeddings returns an embedding vector for each of texts, in order, from
+// the endpoint's OpenAI-compatible /embeddings route or, for Ollama, its
+// native /api/embed route.
+func (c *Client) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	model := c.EmbeddingModel()
+	ctx, span := tracing.Start(ctx, "embeddings "+model,
+		attribute.String("gen_ai.operation.name", "embeddings"),
+		attribute.String("gen_ai.request.model", model),
+		attribute.Int("gen_ai.request.inputs", len(texts)),
+	)
+	defer span.End()
+
+	vectors, err := c.embeddings(ctx, model, texts)
+	if err != nil {
+		span.RecordError(err)
+		tracing.Fail(span, err.Error())
+		return nil, err
+	}
+	return vectors, nil
+}
+
+func (c *Client) embeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	bodyBytes, err := json.Marshal(embeddingRequest{Model: model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := c.endpoint + "/embeddings"
+	if c.embeddingAPI == config.EmbeddingAPIOllama {
+		// Ollama serves its native API beside the OpenAI-compatible one under /v1
+		url = strings.TrimSuffix(c.endpoint, "/v1") + "/api/embed"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Warn("embeddings request failed", "model", model, "error", err, "duration", time.Since(start))
+		return nil, requestError(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	logger.Debug("embeddings", "model", model, "inputs", len(texts), "status", resp.StatusCode, "duration", time.Since(start))
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := responseError(resp.StatusCode, respBody)
+		logger.Warn("embeddings returned an error", "model", model, "status", resp.StatusCode, "kind", apiErr.Kind)
+		return nil, apiErr
+	}
+
+	var vectors [][]float32
+	if c.embeddingAPI == config.EmbeddingAPIOllama {
+		var parsed ollamaEmbeddingResponse
+		if err := json.Unmarshal(respBody, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		vectors = parsed.Embeddings
+	} else {
+		var parsed openAIEmbeddingResponse
+		if err := json.Unmarshal(respBody, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		sort.SliceStable(parsed.Data, func(i, j int) bool { return parsed.Data[i].Index < parsed.Data[j].Index })
+		for _, d := range parsed.Data {
+			vectors = append(vectors, d.Embedding)
+		}
+	}
+
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(vectors))
+	}
+	return vectors, nil
+}
@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agent-desktop/internal/config"
+)
+
+func TestEmbeddings_OpenAI(t *testing.T) {
+	var got embeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("request to %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		// Out of order, as the API allows
+		w.Write([]byte(`{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.Config{APIKey: "key", Endpoint: server.URL + "/v1", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := client.Embeddings(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("Embeddings failed: %v", err)
+	}
+	if got.Model != DefaultEmbeddingModel || len(got.Input) != 2 || got.Input[1] != "second" {
+		t.Errorf("request = %+v", got)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+
+	if vectors, err := client.Embeddings(context.Background(), nil); vectors != nil || err != nil {
+		t.Errorf("Embeddings(nil) = %v, %v", vectors, err)
+	}
+}
+
+func TestEmbeddings_Ollama(t *testing.T) {
+	var got embeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("request to %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"model": "nomic-embed-text", "embeddings": [[0.5, 0.25]]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.Config{APIKey: "ollama", Endpoint: server.URL + "/v1", Model: "llama3", EmbeddingAPI: config.EmbeddingAPIOllama})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := client.Embeddings(context.Background(), []string{"hello"})
+	if err != nil {
+		t.Fatalf("Embeddings failed: %v", err)
+	}
+	if got.Model != DefaultOllamaEmbeddingModel || len(vectors) != 1 || vectors[0][1] != 0.25 {
+		t.Errorf("request = %+v, vectors = %v", got, vectors)
+	}
+}
+
+func TestEmbeddings_Errors(t *testing.T) {
+	status, body := http.StatusUnauthorized, `{"error": {"message": "bad key", "code": "invalid_api_key"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o", EmbeddingModel: "custom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.EmbeddingModel() != "custom" {
+		t.Errorf("EmbeddingModel() = %q", client.EmbeddingModel())
+	}
+
+	_, err = client.Embeddings(context.Background(), []string{"a"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Kind != ErrorAuth {
+		t.Errorf("error = %v, want an auth error", err)
+	}
+
+	status, body = http.StatusOK, `{"data": [{"index": 0, "embedding": [1]}]}`
+	if _, err := client.Embeddings(context.Background(), []string{"a", "b"}); err == nil {
+		t.Error("expected an error when vectors are missing")
+	}
+}
+
+func TestEmbeddingAPI(t *testing.T) {
+	tests := []struct {
+		cfg  config.Config
+		want string
+	}{
+		{config.Config{Endpoint: "https://api.openai.com/v1"}, config.EmbeddingAPIOpenAI},
+		{config.Config{Endpoint: "http://localhost:11434/v1"}, config.EmbeddingAPIOllama},
+		{config.Config{Endpoint: "http://localhost:11434/v1", EmbeddingAPI: config.EmbeddingAPIOpenAI}, config.EmbeddingAPIOpenAI},
+	}
+	for _, tt := range tests {
+		if got := embeddingAPI(&tt.cfg); got != tt.want {
+			t.Errorf("embeddingAPI(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
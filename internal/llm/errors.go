@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrorKind classifies why a request to the model failed, so the UI can
+// suggest a fix instead of showing the provider's response.
+type ErrorKind string
+
+const (
+	ErrorAuth            ErrorKind = "auth"             // The API key was rejected
+	ErrorRateLimit       ErrorKind = "rate_limit"       // Too many requests; retrying later works
+	ErrorQuota           ErrorKind = "quota"            // The account is out of credit
+	ErrorContextOverflow ErrorKind = "context_overflow" // The conversation is longer than the model's context
+	ErrorSafetyBlock     ErrorKind = "safety_block"     // The provider's content filter refused the request
+	ErrorModelNotFound   ErrorKind = "model_not_found"
+	ErrorTimeout         ErrorKind = "timeout"
+	ErrorNetwork         ErrorKind = "network" // The endpoint couldn't be reached
+	ErrorServer          ErrorKind = "server"  // The provider failed or is overloaded
+	ErrorUnknown         ErrorKind = "unknown"
+)
+
+// maxErrorMessage is how much of a response body is kept as the message
+// when the provider didn't send a structured error.
+const maxErrorMessage = 500
+
+// Error is a failed request to the model.
+type Error struct {
+	Kind       ErrorKind
+	StatusCode int    // HTTP status, or 0 if there was no error response
+	Message    string // The provider's explanation, or what went wrong
+	Err        error  // The underlying error, if any
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("API error: status %d: %s", e.StatusCode, e.Message)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the kind of a ChatCompletion error.
+func Classify(err error) ErrorKind {
+	var e *Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &e):
+		return e.Kind
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorNetwork
+	}
+	return ErrorUnknown
+}
+
+// requestError wraps an error sending a request, which got no response.
+func requestError(err error) *Error {
+	e := &Error{Kind: ErrorNetwork, Message: "failed to make request: " + err.Error(), Err: err}
+	if Classify(err) == ErrorTimeout {
+		e.Kind = ErrorTimeout
+	}
+	return e
+}
+
+// responseError reads an error response from the provider.
+func responseError(status int, body []byte) *Error {
+	message, code := errorDetails(body)
+	return &Error{Kind: classifyResponse(status, code, message), StatusCode: status, Message: message}
+}
+
+// errorDetails finds the message and code in an error body, which
+// OpenAI-compatible providers send as {"error": {"message", "type",
+// "code"}}, but some send as {"error": "..."} or {"message": "..."}.
+func errorDetails(body []byte) (message, code string) {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		var detail struct {
+			Message string      `json:"message"`
+			Type    string      `json:"type"`
+			Code    interface{} `json:"code"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(parsed.Error, &detail) == nil && detail.Message != "":
+			code = detail.Type
+			if c, ok := detail.Code.(string); ok && c != "" {
+				code = c
+			}
+			return detail.Message, code
+		case json.Unmarshal(parsed.Error, &text) == nil && text != "":
+			return text, ""
+		case parsed.Message != "":
+			return parsed.Message, ""
+		}
+	}
+
+	message = strings.TrimSpace(string(body))
+	if len(message) > maxErrorMessage {
+		message = strings.ToValidUTF8(message[:maxErrorMessage], "") + "…"
+	}
+	if message == "" {
+		message = "no error message"
+	}
+	return message, ""
+}
+
+// Phrases providers use in messages for errors they give no specific
+// status or code for.
+var (
+	contextPhrases = []string{"context_length", "context length", "context window", "maximum context", "too many tokens", "prompt is too long", "reduce the length"}
+	safetyPhrases  = []string{"content_filter", "content filter", "content_policy", "content management policy", "safety", "flagged"}
+	quotaPhrases   = []string{"insufficient_quota", "quota", "billing", "credit balance"}
+	modelPhrases   = []string{"model_not_found", "model not found", "no such model", "unknown model"}
+)
+
+func classifyResponse(status int, code, message string) ErrorKind {
+	text := strings.ToLower(code + " " + message)
+	has := func(phrases []string) bool {
+		for _, p := range phrases {
+			if strings.Contains(text, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case has(contextPhrases):
+		return ErrorContextOverflow
+	case has(safetyPhrases):
+		return ErrorSafetyBlock
+	case has(quotaPhrases) && (status == http.StatusTooManyRequests || status == http.StatusPaymentRequired || status == 0):
+		return ErrorQuota
+	case has(modelPhrases):
+		return ErrorModelNotFound
+	}
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorAuth
+	case status == http.StatusPaymentRequired:
+		return ErrorQuota
+	case status == http.StatusNotFound:
+		return ErrorModelNotFound
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrorTimeout
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrorContextOverflow
+	case status == http.StatusTooManyRequests:
+		return ErrorRateLimit
+	case status >= 500:
+		return ErrorServer
+	}
+	return ErrorUnknown
+}
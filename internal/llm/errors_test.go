@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/config"
+)
+
+func TestResponseError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		kind    ErrorKind
+		message string
+	}{
+		{"bad key", 401, `{"error": {"message": "Incorrect API key provided", "type": "invalid_request_error", "code": "invalid_api_key"}}`, ErrorAuth, "Incorrect API key provided"},
+		{"rate limit", 429, `{"error": {"message": "Rate limit reached for requests", "type": "requests", "code": "rate_limit_exceeded"}}`, ErrorRateLimit, "Rate limit reached for requests"},
+		{"quota", 429, `{"error": {"message": "You exceeded your current quota", "type": "insufficient_quota"}}`, ErrorQuota, "You exceeded your current quota"},
+		{"context", 400, `{"error": {"message": "This model's maximum context length is 8192 tokens", "code": "context_length_exceeded"}}`, ErrorContextOverflow, "This model's maximum context length is 8192 tokens"},
+		{"content filter", 400, `{"error": {"message": "The response was filtered", "code": "content_filter"}}`, ErrorSafetyBlock, "The response was filtered"},
+		{"model", 404, `{"error": {"message": "The model gpt-9 does not exist", "code": "model_not_found"}}`, ErrorModelNotFound, "The model gpt-9 does not exist"},
+		{"string error", 503, `{"error": "overloaded"}`, ErrorServer, "overloaded"},
+		{"plain message", 504, `{"message": "upstream timed out"}`, ErrorTimeout, "upstream timed out"},
+		{"html", 502, `<html>Bad Gateway</html>`, ErrorServer, "<html>Bad Gateway</html>"},
+		{"bad request", 400, `{"error": {"message": "Invalid value for tools"}}`, ErrorUnknown, "Invalid value for tools"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := responseError(tt.status, []byte(tt.body))
+			if err.Kind != tt.kind || err.Message != tt.message || err.StatusCode != tt.status {
+				t.Errorf("error = %+v, want %s %q", err, tt.kind, tt.message)
+			}
+			if !strings.Contains(err.Error(), fmt.Sprint(tt.status)) {
+				t.Errorf("Error() = %q, want the status", err.Error())
+			}
+		})
+	}
+}
+
+func TestResponseError_LongBody(t *testing.T) {
+	err := responseError(500, []byte(strings.Repeat("x", 2000)))
+	if len(err.Message) > maxErrorMessage+len("…") {
+		t.Errorf("message is %d bytes, want it shortened", len(err.Message))
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorKind
+	}{
+		{nil, ""},
+		{fmt.Errorf("run failed: %w", &Error{Kind: ErrorRateLimit}), ErrorRateLimit},
+		{context.DeadlineExceeded, ErrorTimeout},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrorNetwork},
+		{errors.New("something else"), ErrorUnknown},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClient_ChatCompletion_ClassifiesErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"message": "Invalid API key"}}`))
+	}))
+	client, err := NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil)
+	if Classify(err) != ErrorAuth {
+		t.Errorf("error = %v, want an auth error", err)
+	}
+
+	// Nothing listening
+	server.Close()
+	_, err = client.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil)
+	if Classify(err) != ErrorNetwork {
+		t.Errorf("error = %v, want a network error", err)
+	}
+}
@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// healthSamples is how many recent request latencies are kept per
+	// provider.
+	healthSamples = 100
+	// unhealthyStreak is how many requests in a row must fail before a
+	// provider is reported unhealthy.
+	unhealthyStreak = 3
+)
+
+// ProviderHealth is how requests to a provider profile have gone since the
+// app started.
+type ProviderHealth struct {
+	Profile       string    `json:"profile"` // Empty for the top-level settings
+	Endpoint      string    `json:"endpoint"`
+	Requests      int       `json:"requests"`
+	Failures      int       `json:"failures"`
+	ErrorStreak   int       `json:"error_streak"` // Requests failed in a row, up to the latest
+	Healthy       bool      `json:"healthy"`      // Fewer than 3 requests in a row failed
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorKind ErrorKind `json:"last_error_kind,omitempty"`
+	LastErrorAt   time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt time.Time `json:"last_success_at,omitempty"`
+	LastMs        int64     `json:"last_ms"` // Latency of the latest request
+	P50Ms         int64     `json:"p50_ms"`  // Over the last 100 requests
+	P95Ms         int64     `json:"p95_ms"`
+}
+
+// providerRecord is what is kept about a provider.
+type providerRecord struct {
+	ProviderHealth
+	latencies []int64 // Ring of the last healthSamples latencies, in ms
+	next      int
+}
+
+var (
+	healthMu  sync.Mutex
+	providers = map[string]*providerRecord{} // By profile
+)
+
+// Health returns how requests to each provider profile used since the app
+// started have gone, unhealthy providers last and otherwise by profile
+// name.
+func Health() []ProviderHealth {
+	healthMu.Lock()
+	out := make([]ProviderHealth, 0, len(providers))
+	for _, r := range providers {
+		h := r.ProviderHealth
+		sorted := append([]int64(nil), r.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		h.P50Ms = percentile(sorted, 50)
+		h.P95Ms = percentile(sorted, 95)
+		out = append(out, h)
+	}
+	healthMu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Healthy != out[j].Healthy {
+			return out[i].Healthy
+		}
+		return out[i].Profile < out[j].Profile
+	})
+	return out
+}
+
+// resetHealth forgets the requests recorded for Health.
+func resetHealth() {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	providers = map[string]*providerRecord{}
+}
+
+// recordHealth records a request to the provider that took d and failed
+// with err, if not nil. Cancelled requests say nothing about the provider
+// and aren't recorded.
+func recordHealth(profile, endpoint string, d time.Duration, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	r, ok := providers[profile]
+	if !ok {
+		r = &providerRecord{ProviderHealth: ProviderHealth{Profile: profile}}
+		providers[profile] = r
+	}
+
+	ms := d.Milliseconds()
+	if len(r.latencies) < healthSamples {
+		r.latencies = append(r.latencies, ms)
+	} else {
+		r.latencies[r.next] = ms
+	}
+	r.next = (r.next + 1) % healthSamples
+
+	r.Endpoint = endpoint
+	r.Requests++
+	r.LastMs = ms
+	if err != nil {
+		r.Failures++
+		r.ErrorStreak++
+		r.LastError = err.Error()
+		r.LastErrorKind = Classify(err)
+		r.LastErrorAt = time.Now()
+	} else {
+		r.ErrorStreak = 0
+		r.LastSuccessAt = time.Now()
+	}
+	r.Healthy = r.ErrorStreak < unhealthyStreak
+}
+
+// percentile returns the nearest-rank pth percentile of sorted values, or 0
+// if there are none.
+func percentile(sorted []int64, p int) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
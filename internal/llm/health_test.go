@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+func TestHealth(t *testing.T) {
+	resetHealth()
+	defer resetHealth()
+
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": {"message": "overloaded"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "hi"}}]}`))
+	}))
+	defer server.Close()
+	base, err := NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup := base.WithProfile("backup")
+	msgs := []Message{{Role: "user", Content: "hi"}}
+
+	for i := 0; i < unhealthyStreak; i++ {
+		backup.ChatCompletion(context.Background(), msgs, nil)
+	}
+	failing = false
+	if _, err := base.ChatCompletion(context.Background(), msgs, nil); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	health := Health()
+	if len(health) != 2 {
+		t.Fatalf("Health() = %+v", health)
+	}
+	if h := health[0]; h.Profile != "" || !h.Healthy || h.Requests != 1 || h.Endpoint != server.URL || h.LastSuccessAt.IsZero() {
+		t.Errorf("default provider = %+v", h)
+	}
+	h := health[1]
+	if h.Profile != "backup" || h.Healthy || h.ErrorStreak != unhealthyStreak || h.Failures != unhealthyStreak || h.LastErrorKind != ErrorServer {
+		t.Errorf("failing provider = %+v", h)
+	}
+
+	// A success ends the streak
+	backup.ChatCompletion(context.Background(), msgs, nil)
+	if h := Health()[1]; !h.Healthy || h.ErrorStreak != 0 || h.Failures != unhealthyStreak || h.Requests != unhealthyStreak+1 {
+		t.Errorf("recovered provider = %+v", h)
+	}
+}
+
+func TestHealth_Latency(t *testing.T) {
+	resetHealth()
+	defer resetHealth()
+
+	for ms := 1; ms <= healthSamples+20; ms++ {
+		recordHealth("", "http://local", time.Duration(ms)*time.Millisecond, nil)
+	}
+	recordHealth("", "http://local", time.Hour, context.Canceled)
+	recordHealth("", "http://local", time.Hour, &Error{Kind: ErrorNetwork, Err: context.Canceled})
+
+	h := Health()[0]
+	if h.Requests != healthSamples+20 || h.LastMs != int64(healthSamples+20) {
+		t.Errorf("cancelled requests should not be recorded: %+v", h)
+	}
+	if h.P50Ms != 70 || h.P95Ms != 115 {
+		t.Errorf("percentiles over the last %d = %d, %d", healthSamples, h.P50Ms, h.P95Ms)
+	}
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+)
+
+// Pricing is the price of a model in US dollars per million tokens.
+type Pricing struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// Cost returns the estimated cost in US dollars for the given token counts.
+func (p Pricing) Cost(promptTokens, completionTokens int) float64 {
+	return float64(promptTokens)*p.InputPerMillion/1e6 +
+		float64(completionTokens)*p.OutputPerMillion/1e6
+}
+
+// knownPricing holds list prices for common hosted models, keyed by model
+// name prefix. Models served locally (LM Studio, etc.) are not listed and
+// are treated as free.
+var knownPricing = map[string]Pricing{
+	"gpt-4o-mini":   {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	"gpt-4o":        {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	"gpt-4.1-nano":  {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gpt-4.1-mini":  {InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	"gpt-4.1":       {InputPerMillion: 2.00, OutputPerMillion: 8.00},
+	"gpt-4-turbo":   {InputPerMillion: 10.00, OutputPerMillion: 30.00},
+	"gpt-3.5-turbo": {InputPerMillion: 0.50, OutputPerMillion: 1.50},
+	"o3-mini":       {InputPerMillion: 1.10, OutputPerMillion: 4.40},
+	"o1-mini":       {InputPerMillion: 1.10, OutputPerMillion: 4.40},
+	"o1":            {InputPerMillion: 15.00, OutputPerMillion: 60.00},
+	"deepseek-chat": {InputPerMillion: 0.27, OutputPerMillion: 1.10},
+}
+
+// LookupPricing returns the known pricing for a model. The longest matching
+// name prefix wins, and provider prefixes such as "openai/" are ignored.
+// Returns false if the model is unknown.
+func LookupPricing(model string) (Pricing, bool) {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	prefixes := make([]string, 0, len(knownPricing))
+	for prefix := range knownPricing {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return knownPricing[prefix], true
+		}
+	}
+	return Pricing{}, false
+}
+
+// EstimateCost returns the estimated cost in US dollars of a request to the
+// given model. Unknown models are estimated at zero.
+func EstimateCost(model string, promptTokens, completionTokens int) float64 {
+	pricing, _ := LookupPricing(model)
+	return pricing.Cost(promptTokens, completionTokens)
+}
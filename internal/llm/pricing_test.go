@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLookupPricing(t *testing.T) {
+	tests := []struct {
+		model string
+		want  Pricing
+		found bool
+	}{
+		{"gpt-4o", knownPricing["gpt-4o"], true},
+		{"gpt-4o-mini-2024-07-18", knownPricing["gpt-4o-mini"], true},
+		{"openai/gpt-4o", knownPricing["gpt-4o"], true},
+		{"local-model", Pricing{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, found := LookupPricing(tt.model)
+			if found != tt.found || got != tt.want {
+				t.Errorf("LookupPricing(%q) = %+v, %v; want %+v, %v", tt.model, got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	cost := EstimateCost("gpt-4o", 1_000_000, 100_000)
+	if math.Abs(cost-3.50) > 1e-9 {
+		t.Errorf("EstimateCost = %f, want 3.50", cost)
+	}
+
+	if EstimateCost("local-model", 1000, 1000) != 0 {
+		t.Error("Unknown models should be estimated at zero")
+	}
+}
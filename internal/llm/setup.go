@@ -0,0 +1,179 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+)
+
+// detectTimeout bounds each local provider check in DetectProviders.
+const detectTimeout = 1500 * time.Millisecond
+
+// ProviderSuggestion is an endpoint offered to the user during first-run
+// setup. Local providers don't check API keys, so they come with a
+// placeholder key that satisfies Config.Validate.
+type ProviderSuggestion struct {
+	Name      string   `json:"name"`
+	Endpoint  string   `json:"endpoint"`
+	APIKey    string   `json:"api_key,omitempty"` // Placeholder for local servers
+	Local     bool     `json:"local"`
+	Available bool     `json:"available"` // A local server answered
+	Models    []string `json:"models,omitempty"`
+}
+
+// localProviders are the servers DetectProviders looks for on this machine.
+// It is a variable so tests can point it at fake servers.
+var localProviders = []ProviderSuggestion{
+	{Name: "LM Studio", Endpoint: "http://localhost:1234/v1", APIKey: "lm-studio", Local: true},
+	{Name: "Ollama", Endpoint: "http://localhost:11434/v1", APIKey: "ollama", Local: true},
+}
+
+// remoteProviders are hosted endpoints that need the user's API key.
+var remoteProviders = []ProviderSuggestion{
+	{Name: "OpenAI", Endpoint: "https://api.openai.com/v1"},
+	{Name: "OpenRouter", Endpoint: "https://openrouter.ai/api/v1"},
+}
+
+// DetectProviders checks for local OpenAI-compatible servers and returns
+// suggestions with running local servers first, followed by the hosted
+// providers.
+func DetectProviders(ctx context.Context) []ProviderSuggestion {
+	local := make([]ProviderSuggestion, len(localProviders))
+	copy(local, localProviders)
+
+	var wg sync.WaitGroup
+	for i := range local {
+		wg.Add(1)
+		go func(p *ProviderSuggestion) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, detectTimeout)
+			defer cancel()
+
+			models, err := ListModels(ctx, &config.Config{Endpoint: p.Endpoint, APIKey: p.APIKey})
+			if err == nil {
+				p.Available = true
+				p.Models = models
+			}
+		}(&local[i])
+	}
+	wg.Wait()
+
+	var available, unavailable []ProviderSuggestion
+	for _, p := range local {
+		if p.Available {
+			available = append(available, p)
+		} else {
+			unavailable = append(unavailable, p)
+		}
+	}
+
+	suggestions := append(available, remoteProviders...)
+	return append(suggestions, unavailable...)
+}
+
+// ListModels returns the model IDs served by the configured endpoint.
+func ListModels(ctx context.Context, cfg *config.Config) ([]string, error) {
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(cfg.Endpoint, "/")+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model list returned status %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
+	}
+
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+// SetupProbeResult reports whether a configuration is ready for agent use.
+type SetupProbeResult struct {
+	Connected   bool                `json:"connected"`
+	ToolCalling bool                `json:"tool_calling"` // The model called the probe tool
+	Message     string              `json:"message"`
+	Diagnostics []config.FieldError `json:"diagnostics,omitempty"`
+}
+
+// probeTool is offered to the model during ProbeSetup. Models that support
+// tool calling reliably call it when asked to.
+var probeTool = tools.ToolDefinition{
+	Type: "function",
+	Function: tools.ToolFunction{
+		Name:        "report_ready",
+		Description: "Report that the assistant is ready.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+	},
+}
+
+// ProbeSetup validates cfg, connects to the endpoint, and checks that the
+// model can call tools, which the agent depends on.
+func ProbeSetup(ctx context.Context, cfg *config.Config) SetupProbeResult {
+	if err := cfg.Validate(); err != nil {
+		return SetupProbeResult{Message: err.Error(), Diagnostics: cfg.Diagnose()}
+	}
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		return SetupProbeResult{Message: "Failed to create client: " + err.Error()}
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	messages := []Message{
+		{Role: "user", Content: "Call the report_ready tool now. Do not reply with text."},
+	}
+	resp, err := client.ChatCompletion(callCtx, messages, []tools.ToolDefinition{probeTool})
+	if err != nil {
+		return SetupProbeResult{
+			Message:     "Connection failed: " + err.Error(),
+			Diagnostics: ProbeEndpoint(ctx, cfg),
+		}
+	}
+
+	for _, tc := range resp.ToolCalls {
+		if tc.Name == probeTool.Function.Name {
+			return SetupProbeResult{Connected: true, ToolCalling: true, Message: "Connected to " + cfg.Model + " with tool calling."}
+		}
+	}
+	return SetupProbeResult{
+		Connected: true,
+		Message:   cfg.Model + " responded but did not call tools; the agent needs a model with tool calling support.",
+	}
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agent-desktop/internal/config"
+)
+
+// newFakeProvider starts a server that answers /v1/models and
+// /v1/chat/completions with the given chat response body.
+func newFakeProvider(t *testing.T, chatResponse string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"id":"local-a"},{"id":"local-b"}]}`))
+	})
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatResponse))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDetectProviders(t *testing.T) {
+	server := newFakeProvider(t, "")
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	original := localProviders
+	localProviders = []ProviderSuggestion{
+		{Name: "Down", Endpoint: closed.URL + "/v1", Local: true},
+		{Name: "Up", Endpoint: server.URL + "/v1", APIKey: "up", Local: true},
+	}
+	defer func() { localProviders = original }()
+
+	got := DetectProviders(context.Background())
+
+	if len(got) != 2+len(remoteProviders) {
+		t.Fatalf("got %d suggestions, want %d", len(got), 2+len(remoteProviders))
+	}
+	if got[0].Name != "Up" || !got[0].Available {
+		t.Errorf("first suggestion = %+v, want available Up", got[0])
+	}
+	if len(got[0].Models) != 2 || got[0].Models[0] != "local-a" {
+		t.Errorf("models = %v", got[0].Models)
+	}
+	last := got[len(got)-1]
+	if last.Name != "Down" || last.Available {
+		t.Errorf("last suggestion = %+v, want unavailable Down", last)
+	}
+}
+
+func TestProbeSetup(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    string
+		toolCalling bool
+	}{
+		{
+			name:        "tool call",
+			response:    `{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"1","type":"function","function":{"name":"report_ready","arguments":"{}"}}]}}]}`,
+			toolCalling: true,
+		},
+		{
+			name:     "text only",
+			response: `{"choices":[{"message":{"role":"assistant","content":"Ready!"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeProvider(t, tt.response)
+			cfg := &config.Config{APIKey: "key", Endpoint: server.URL + "/v1", Model: "local-a"}
+
+			result := ProbeSetup(context.Background(), cfg)
+			if !result.Connected {
+				t.Fatalf("expected connection, got %+v", result)
+			}
+			if result.ToolCalling != tt.toolCalling {
+				t.Errorf("ToolCalling = %v, want %v", result.ToolCalling, tt.toolCalling)
+			}
+		})
+	}
+}
+
+func TestProbeSetup_InvalidConfig(t *testing.T) {
+	result := ProbeSetup(context.Background(), &config.Config{Endpoint: "https://api.openai.com/v1"})
+	if result.Connected {
+		t.Error("expected no connection for invalid config")
+	}
+	if len(result.Diagnostics) == 0 {
+		t.Error("expected diagnostics for invalid config")
+	}
+}
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+// requestTimeout bounds a single chat completion request.
+const requestTimeout = 120 * time.Second
+
+// newHTTPClient builds the HTTP client for an endpoint, applying the TLS
+// options from the config. Extra CA certificates are trusted in addition to
+// the system trust store. Requests are captured while SetCapture is on, and
+// recorded or replayed while SetCassette has set a cassette.
+func newHTTPClient(cfg *config.Config) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		return &http.Client{Timeout: requestTimeout, Transport: &captureTransport{base: &cassetteTransport{base: http.DefaultTransport}}}, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Timeout: requestTimeout, Transport: &captureTransport{base: &cassetteTransport{base: transport}}}, nil
+}
+
+// newTLSConfig builds the TLS settings from the config, or returns nil if
+// the defaults apply.
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.CACertPath == "" && !cfg.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CACertPath != "" {
+		pem, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", cfg.CACertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
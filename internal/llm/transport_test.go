@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"agent-desktop/internal/config"
+)
+
+// newTLSChatServer starts a TLS server answering chat completions and
+// returns it with the path of a PEM file holding its certificate.
+func newTLSChatServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hi"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	certPath := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+	return server, certPath
+}
+
+func TestNewClient_TLSOptions(t *testing.T) {
+	server, certPath := newTLSChatServer(t)
+	messages := []Message{{Role: "user", Content: "Hi"}}
+
+	tests := []struct {
+		name    string
+		caPath  string
+		skip    bool
+		wantErr bool
+	}{
+		{"untrusted certificate", "", false, true},
+		{"custom CA", certPath, false, false},
+		{"insecure skip verify", "", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(&config.Config{
+				APIKey:             "key",
+				Endpoint:           server.URL,
+				Model:              "model",
+				CACertPath:         tt.caPath,
+				InsecureSkipVerify: tt.skip,
+			})
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			_, err = client.ChatCompletion(context.Background(), messages, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ChatCompletion error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewClient_InvalidCACert(t *testing.T) {
+	badPath := filepath.Join(t.TempDir(), "bad.pem")
+	os.WriteFile(badPath, []byte("not a certificate"), 0644)
+
+	for _, path := range []string{badPath, filepath.Join(t.TempDir(), "missing.pem")} {
+		_, err := NewClient(&config.Config{APIKey: "key", Endpoint: "https://x", Model: "m", CACertPath: path})
+		if err == nil {
+			t.Errorf("expected error for CA file %s", path)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package llm
+
+import (
+	"context"
+
+	"agent-desktop/internal/tools"
+)
+
+// warmPrompt is the user message of a priming request. Any will do; the
+// reply is cut off after one token.
+const warmPrompt = "Hi"
+
+// Warm readies the endpoint for a conversation that starts with the system
+// messages in prefix and offers toolDefs, so its first real turn doesn't
+// pay for the connection and cold caches. It sends them with a short user
+// message as a request for a single token: the request opens the TLS
+// connection, which stays pooled for the next, makes a local server load
+// the model, and lets a provider that caches prompt prefixes cache the
+// system prompt and tools. It returns the request's usage, which is billed
+// like any other.
+func (c *Client) Warm(ctx context.Context, prefix []Message, toolDefs []tools.ToolDefinition) (*TokenUsage, error) {
+	messages := append(prefix[:len(prefix):len(prefix)], Message{Role: "user", Content: warmPrompt})
+	prime := *c
+	prime.maxTokens = 1
+	resp, err := prime.ChatCompletion(ctx, messages, toolDefs)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("warmed up endpoint", "model", c.model, "endpoint", c.endpoint)
+	return resp.Usage, nil
+}
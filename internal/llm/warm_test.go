@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+)
+
+func TestClient_Warm(t *testing.T) {
+	type request struct {
+		Messages  []chatMessage   `json:"messages"`
+		Tools     json.RawMessage `json:"tools"`
+		MaxTokens int             `json:"max_tokens"`
+	}
+	var req request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = request{}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Hel"}}],
+			"usage":{"prompt_tokens":1500,"completion_tokens":1,"total_tokens":1501}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.Config{APIKey: "key", Endpoint: server.URL, Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	prefix := []Message{{Role: "system", Content: "You are an AI assistant"}}
+	usage, err := client.Warm(context.Background(), prefix, tools.GetToolDefinitions())
+	if err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+
+	if req.MaxTokens != 1 || len(req.Tools) == 0 {
+		t.Errorf("max_tokens = %d with %d bytes of tools, want 1 and the tools", req.MaxTokens, len(req.Tools))
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Content != prefix[0].Content || req.Messages[1].Role != "user" {
+		t.Errorf("messages = %+v, want the system prompt and a user message", req.Messages)
+	}
+	if usage == nil || usage.PromptTokens != 1500 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if len(prefix) != 1 {
+		t.Error("Warm changed its prefix")
+	}
+
+	// Later requests ask for as many tokens as they like
+	client.ChatCompletion(context.Background(), prefix, nil)
+	if req.MaxTokens != 0 {
+		t.Errorf("max_tokens = %d after warming, want it unset", req.MaxTokens)
+	}
+}
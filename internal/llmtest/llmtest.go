@@ -0,0 +1,405 @@
+// Package llmtest runs an in-process server that speaks the OpenAI chat
+// completions protocol, so the LLM client and the agent loop can be tested
+// end to end without a real provider. Replies are scripted: each request
+// is answered with the next queued Reply, or by a function deciding from
+// the request. Streaming requests are answered with server-sent events
+// the way OpenAI sends them.
+package llmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+// Model is the model the server lists and the model of Config.
+const Model = "test-model"
+
+// Message is a message of a request.
+type Message struct {
+	Role       string
+	Content    string
+	ToolCalls  []ToolCall
+	ToolCallID string
+}
+
+// ToolCall is a tool call in a reply, or in an assistant message of a
+// request.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON
+}
+
+// Request is a chat completion request the server received.
+type Request struct {
+	Path      string
+	Header    http.Header
+	Model     string
+	Messages  []Message
+	Tools     []string // Names of the tools offered
+	Stream    bool
+	MaxTokens int
+}
+
+// LastMessage returns the last message of the request.
+func (r Request) LastMessage() Message {
+	if len(r.Messages) == 0 {
+		return Message{}
+	}
+	return r.Messages[len(r.Messages)-1]
+}
+
+// Reply is the server's answer to one request.
+type Reply struct {
+	Content   string
+	ToolCalls []ToolCall
+
+	// Usage to report; by default it is estimated at four characters a
+	// token
+	PromptTokens, CompletionTokens int
+
+	// Status answers with an HTTP error and Error as its message instead
+	Status int
+	Error  string
+
+	Delay time.Duration // Wait this long before answering
+}
+
+// Text returns a reply with content.
+func Text(content string) Reply {
+	return Reply{Content: content}
+}
+
+// Call returns a reply calling a tool with args, marshaled as JSON.
+func Call(name string, args any) Reply {
+	data, err := json.Marshal(args)
+	if err != nil {
+		panic("llmtest: unmarshalable tool arguments: " + err.Error())
+	}
+	return Reply{ToolCalls: []ToolCall{{Name: name, Arguments: string(data)}}}
+}
+
+// Fail returns a reply failing with an HTTP status and message.
+func Fail(status int, message string) Reply {
+	return Reply{Status: status, Error: message}
+}
+
+// Server is a mock chat completions server. Its URL is the endpoint to
+// configure, like https://api.openai.com/v1. Requests to any path ending
+// in /chat/completions are answered, so Azure-style deployment paths work
+// too.
+type Server struct {
+	*httptest.Server
+
+	// APIKey, if set, must be sent as a bearer token or an api-key header
+	APIKey string
+
+	mu       sync.Mutex
+	replies  []Reply
+	respond  func(Request) Reply
+	requests []Request
+	calls    int // Tool call IDs handed out
+}
+
+// NewServer starts a server that answers with replies in order. Close it
+// when done.
+func NewServer(replies ...Reply) *Server {
+	s := &Server{replies: replies}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serve)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Enqueue queues more replies.
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// Respond sets a function answering requests once the queued replies run
+// out. Without one, such requests fail with a 500 error.
+func (s *Server) Respond(fn func(Request) Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.respond = fn
+}
+
+// Requests returns the chat completion requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Config returns a config for the server, with an API key it accepts.
+func (s *Server) Config() *config.Config {
+	key := s.APIKey
+	if key == "" {
+		key = "test-key"
+	}
+	return &config.Config{Endpoint: s.URL, APIKey: key, Model: Model, ExecutionTimeout: 60}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if s.APIKey != "" && r.Header.Get("Authorization") != "Bearer "+s.APIKey && r.Header.Get("api-key") != s.APIKey {
+		writeError(w, http.StatusUnauthorized, "invalid_api_key", "Incorrect API key provided")
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/models"):
+		writeJSON(w, map[string]any{"object": "list", "data": []map[string]any{{"id": Model, "object": "model"}}})
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/chat/completions"):
+		s.chat(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "Unknown request "+r.Method+" "+r.URL.Path)
+	}
+}
+
+// chatRequest is the part of a request body the server reads.
+type chatRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role       string `json:"role"`
+		Content    string `json:"content"`
+		ToolCallID string `json:"tool_call_id"`
+		ToolCalls  []struct {
+			ID       string `json:"id"`
+			Function struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	} `json:"messages"`
+	Tools []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+	Stream        bool `json:"stream"`
+	MaxTokens     int  `json:"max_tokens"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+func (s *Server) chat(w http.ResponseWriter, r *http.Request) {
+	var body chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "Invalid JSON body: "+err.Error())
+		return
+	}
+	req := Request{Path: r.URL.Path, Header: r.Header.Clone(), Model: body.Model, Stream: body.Stream, MaxTokens: body.MaxTokens}
+	prompt := 0
+	for _, m := range body.Messages {
+		msg := Message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: tc.Function.Arguments})
+		}
+		req.Messages = append(req.Messages, msg)
+		prompt += len(m.Content)
+	}
+	for _, t := range body.Tools {
+		req.Tools = append(req.Tools, t.Function.Name)
+	}
+
+	reply, ok := s.next(req)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "server_error", "llmtest: no reply scripted for this request")
+		return
+	}
+	if reply.Delay > 0 {
+		select {
+		case <-time.After(reply.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if reply.Status != 0 {
+		writeError(w, reply.Status, errorCode(reply.Status), reply.Error)
+		return
+	}
+
+	if reply.PromptTokens == 0 {
+		reply.PromptTokens = prompt/4 + 1
+	}
+	if reply.CompletionTokens == 0 {
+		completion := len(reply.Content)
+		for _, tc := range reply.ToolCalls {
+			completion += len(tc.Name) + len(tc.Arguments)
+		}
+		reply.CompletionTokens = completion/4 + 1
+	}
+	if body.Stream {
+		stream(w, body.Model, reply, body.StreamOptions.IncludeUsage)
+		return
+	}
+	writeJSON(w, map[string]any{
+		"id":      "chatcmpl-llmtest",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   body.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"finish_reason": finishReason(reply),
+			"message":       map[string]any{"role": "assistant", "content": reply.Content, "tool_calls": toolCalls(reply.ToolCalls)},
+		}},
+		"usage": usage(reply),
+	})
+}
+
+// next records req and returns the reply to it, giving tool calls without
+// an ID one.
+func (s *Server) next(req Request) (Reply, bool) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	queued := len(s.replies) > 0
+	var reply Reply
+	if queued {
+		reply, s.replies = s.replies[0], s.replies[1:]
+	}
+	respond := s.respond
+	s.mu.Unlock()
+
+	switch {
+	case queued:
+	case respond != nil:
+		reply = respond(req)
+	default:
+		return Reply{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := make([]ToolCall, len(reply.ToolCalls))
+	for i, tc := range reply.ToolCalls {
+		if tc.ID == "" {
+			s.calls++
+			tc.ID = fmt.Sprintf("call_%d", s.calls)
+		}
+		calls[i] = tc
+	}
+	reply.ToolCalls = calls
+	return reply, true
+}
+
+// stream writes reply as server-sent events: the role, the content a few
+// words at a time, each tool call's name and then its arguments in two
+// pieces, the finish reason, the usage if asked for, and [DONE].
+func stream(w http.ResponseWriter, model string, reply Reply, includeUsage bool) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	send := func(delta map[string]any, finish any) {
+		chunk := map[string]any{
+			"id":      "chatcmpl-llmtest",
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}},
+		}
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	send(map[string]any{"role": "assistant", "content": ""}, nil)
+	for _, piece := range splitWords(reply.Content) {
+		send(map[string]any{"content": piece}, nil)
+	}
+	for i, tc := range reply.ToolCalls {
+		send(map[string]any{"tool_calls": []map[string]any{{
+			"index": i, "id": tc.ID, "type": "function",
+			"function": map[string]any{"name": tc.Name, "arguments": ""},
+		}}}, nil)
+		half := len(tc.Arguments) / 2
+		for _, piece := range []string{tc.Arguments[:half], tc.Arguments[half:]} {
+			send(map[string]any{"tool_calls": []map[string]any{{
+				"index": i, "function": map[string]any{"arguments": piece},
+			}}}, nil)
+		}
+	}
+	send(map[string]any{}, finishReason(reply))
+	if includeUsage {
+		data, _ := json.Marshal(map[string]any{"id": "chatcmpl-llmtest", "object": "chat.completion.chunk", "model": model, "choices": []any{}, "usage": usage(reply)})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// splitWords splits s into words, each with the space after it.
+func splitWords(s string) []string {
+	var words []string
+	for s != "" {
+		i := strings.IndexByte(s, ' ') + 1
+		if i == 0 {
+			i = len(s)
+		}
+		words = append(words, s[:i])
+		s = s[i:]
+	}
+	return words
+}
+
+func toolCalls(calls []ToolCall) []map[string]any {
+	if len(calls) == 0 {
+		return nil
+	}
+	result := make([]map[string]any, len(calls))
+	for i, tc := range calls {
+		result[i] = map[string]any{"id": tc.ID, "type": "function", "function": map[string]any{"name": tc.Name, "arguments": tc.Arguments}}
+	}
+	return result
+}
+
+func finishReason(reply Reply) string {
+	if len(reply.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func usage(reply Reply) map[string]int {
+	return map[string]int{
+		"prompt_tokens":     reply.PromptTokens,
+		"completion_tokens": reply.CompletionTokens,
+		"total_tokens":      reply.PromptTokens + reply.CompletionTokens,
+	}
+}
+
+// errorCode returns the OpenAI error code sent with an HTTP status.
+func errorCode(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "invalid_api_key"
+	case http.StatusNotFound:
+		return "model_not_found"
+	case http.StatusTooManyRequests:
+		return "rate_limit_exceeded"
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	default:
+		return "server_error"
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": message, "type": code, "code": code}})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,171 @@
+package llmtest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
+)
+
+func TestServer_Client(t *testing.T) {
+	server := NewServer(Call("read_file", map[string]any{"path": "notes.txt"}), Text("It says hello"))
+	defer server.Close()
+	client, err := llm.NewClient(server.Config())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	messages := []llm.Message{{Role: "system", Content: "Be brief"}, {Role: "user", Content: "What's in notes.txt?"}}
+	resp, err := client.ChatCompletion(context.Background(), messages, tools.GetToolDefinitions())
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Arguments != `{"path":"notes.txt"}` || resp.Usage == nil {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	messages = append(messages,
+		llm.Message{Role: "assistant", ToolCalls: resp.ToolCalls},
+		llm.Message{Role: "tool", ToolCallID: "call_1", Content: "hello"})
+	if resp, err = client.ChatCompletion(context.Background(), messages, nil); err != nil || resp.Content != "It says hello" {
+		t.Fatalf("ChatCompletion = %+v, %v", resp, err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 || requests[0].Model != Model || len(requests[0].Tools) == 0 || requests[0].Header.Get("Authorization") != "Bearer test-key" {
+		t.Fatalf("requests = %+v", requests)
+	}
+	last := requests[1].LastMessage()
+	if last.Role != "tool" || last.ToolCallID != "call_1" || requests[1].Messages[2].ToolCalls[0].Name != "read_file" {
+		t.Errorf("second request ends with %+v", requests[1].Messages[2:])
+	}
+
+	// Out of replies
+	if _, err := client.ChatCompletion(context.Background(), messages, nil); err == nil {
+		t.Error("expected an error once the replies ran out")
+	}
+}
+
+func TestServer_Respond(t *testing.T) {
+	server := NewServer(Fail(http.StatusTooManyRequests, "Slow down"))
+	defer server.Close()
+	server.Respond(func(req Request) Reply {
+		return Text("echo: " + req.LastMessage().Content)
+	})
+	client, _ := llm.NewClient(server.Config())
+
+	_, err := client.ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "hi"}}, nil)
+	var apiErr *llm.Error
+	if !errors.As(err, &apiErr) || apiErr.Kind != llm.ErrorRateLimit {
+		t.Errorf("error = %v, want a rate limit error", err)
+	}
+	resp, err := client.ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "hi"}}, nil)
+	if err != nil || resp.Content != "echo: hi" {
+		t.Errorf("ChatCompletion = %+v, %v", resp, err)
+	}
+}
+
+func TestServer_AzureAndAPIKey(t *testing.T) {
+	server := NewServer(Text("hi"))
+	server.APIKey = "secret"
+	defer server.Close()
+
+	post := func(header, value string) int {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/openai/deployments/gpt-4o/chat/completions?api-version=2024-06-01",
+			strings.NewReader(`{"messages": [{"role": "user", "content": "hi"}]}`))
+		req.Header.Set(header, value)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := post("api-key", "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("status with a wrong key = %d", status)
+	}
+	if status := post("api-key", "secret"); status != http.StatusOK {
+		t.Errorf("status with an Azure api-key header = %d", status)
+	}
+}
+
+func TestServer_Stream(t *testing.T) {
+	server := NewServer(Reply{
+		Content:   "Let me look that up",
+		ToolCalls: []ToolCall{{Name: "list_directory", Arguments: `{"path": "~/Downloads"}`}},
+	})
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/chat/completions", "application/json",
+		strings.NewReader(`{"model": "m", "stream": true, "stream_options": {"include_usage": true}, "messages": [{"role": "user", "content": "hi"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// Reassemble the reply from the chunks
+	var content, name, args, finish string
+	var usage map[string]int
+	done := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage map[string]int `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("bad chunk %s: %v", data, err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, c := range chunk.Choices {
+			content += c.Delta.Content
+			for _, tc := range c.Delta.ToolCalls {
+				name += tc.Function.Name
+				args += tc.Function.Arguments
+			}
+			if c.FinishReason != nil {
+				finish = *c.FinishReason
+			}
+		}
+	}
+
+	if !done || content != "Let me look that up" || name != "list_directory" || args != `{"path": "~/Downloads"}` || finish != "tool_calls" {
+		t.Errorf("stream = done %v, content %q, tool %s(%s), finish %q", done, content, name, args, finish)
+	}
+	if usage["total_tokens"] == 0 {
+		t.Errorf("usage = %v", usage)
+	}
+	if !server.Requests()[0].Stream {
+		t.Error("the request wasn't recorded as streaming")
+	}
+}
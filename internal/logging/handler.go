@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// fanout is a handler that passes records on to several handlers.
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(fanout, len(f))
+	for i, h := range f {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	out := make(fanout, len(f))
+	for i, h := range f {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
+
+// For returns a logger for a component of the app, such as "llm" or
+// "tools". Its records carry a component attribute and go to the default
+// logger at the time they are written, so loggers can be package variables
+// made before Init runs.
+func For(component string) *slog.Logger {
+	return slog.New(&componentHandler{
+		wrap: func(h slog.Handler) slog.Handler {
+			return h.WithAttrs([]slog.Attr{slog.String("component", component)})
+		},
+	})
+}
+
+// componentHandler hands records to the current default handler, wrapped
+// with the component and any attributes and groups added since.
+type componentHandler struct {
+	wrap func(slog.Handler) slog.Handler
+}
+
+func (c *componentHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return slog.Default().Handler().Enabled(ctx, l)
+}
+
+func (c *componentHandler) Handle(ctx context.Context, r slog.Record) error {
+	return c.wrap(slog.Default().Handler()).Handle(ctx, r)
+}
+
+func (c *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	wrap := c.wrap
+	return &componentHandler{wrap: func(h slog.Handler) slog.Handler { return wrap(h).WithAttrs(attrs) }}
+}
+
+func (c *componentHandler) WithGroup(name string) slog.Handler {
+	wrap := c.wrap
+	return &componentHandler{wrap: func(h slog.Handler) slog.Handler { return wrap(h).WithGroup(name) }}
+}
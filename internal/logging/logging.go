@@ -0,0 +1,238 @@
+// Package logging sets up the application-wide logger. Records are written
+// with log/slog to stderr as text and to a log file as JSON lines, and the
+// most recent are kept in memory for the UI. The log file holds one day:
+// a file from an earlier day is renamed with its date, at startup or when
+// the day changes, and dated files older than the retention period are
+// removed.
+//
+// Packages log through a component-scoped logger from For, which tags each
+// record with the component and follows the logger Init installs.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRetentionDays is how long rotated log files are kept when
+// Options.RetentionDays is zero.
+const DefaultRetentionDays = 7
+
+// rotatedDateLayout is the date suffix of rotated log files.
+const rotatedDateLayout = "2006-01-02"
+
+// Options configures the logger.
+type Options struct {
+	Level         string // "debug", "info" (default), "warn", or "error"
+	File          string // Log file path; empty logs to stderr only
+	RetentionDays int    // Days rotated log files are kept (0 = DefaultRetentionDays)
+}
+
+var (
+	mu        sync.Mutex
+	level     slog.LevelVar
+	file      *rotatingFile
+	path      string
+	installed bool // Init has replaced the default handler
+)
+
+// ParseLevel converts a level name to a slog.Level. An empty name is info.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level: %s", name)
+}
+
+// Init installs the application-wide logger as the slog default. It may be
+// called again when the options change; the level is updated in place and
+// the log file is reopened only if its path changed.
+func Init(opts Options) error {
+	lvl, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	level.Set(lvl)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if installed && opts.File == path {
+		return nil
+	}
+
+	var f *rotatingFile
+	if opts.File != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.File), 0755); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+		if f, err = openRotating(opts.File, retention(opts.RetentionDays)); err != nil {
+			return err
+		}
+	}
+
+	slog.SetDefault(slog.New(newHandler(f)))
+	installed = true
+
+	if file != nil {
+		file.Close()
+	}
+	file, path = f, opts.File
+	return nil
+}
+
+// newHandler returns a handler writing text to stderr, JSON lines to f if
+// it is set, and records to the recent buffer.
+func newHandler(f *rotatingFile) slog.Handler {
+	opts := &slog.HandlerOptions{Level: &level}
+	handlers := []slog.Handler{slog.NewTextHandler(os.Stderr, opts), &recentHandler{opts: opts}}
+	if f != nil {
+		handlers = append(handlers, slog.NewJSONHandler(f, opts))
+	}
+	return fanout(handlers)
+}
+
+// Close flushes and closes the log file. Later records go to stderr.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if file == nil {
+		return nil
+	}
+	slog.SetDefault(slog.New(newHandler(nil)))
+	err := file.Close()
+	file, path = nil, ""
+	installed = false
+	return err
+}
+
+// rotatingFile is a log file that is rotated when a write comes on a later
+// day than the file was opened.
+type rotatingFile struct {
+	mu     sync.Mutex
+	name   string
+	keep   time.Duration
+	f      *os.File
+	opened time.Time
+}
+
+// now returns the current time. It is a variable so tests can move it.
+var now = time.Now
+
+// openRotating rotates a log file left from an earlier day, prunes expired
+// ones, and opens name for appending.
+func openRotating(name string, keep time.Duration) (*rotatingFile, error) {
+	r := &rotatingFile{name: name, keep: keep}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	t := now()
+	if err := rotate(r.name, t); err != nil {
+		return err
+	}
+	prune(r.name, r.keep, t)
+	f, err := os.OpenFile(r.name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	r.f, r.opened = f, t
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if !sameDay(r.opened, now()) {
+		// Close first so the file can be renamed on Windows
+		r.f.Close()
+		if err := r.open(); err != nil {
+			r.f = nil
+			return 0, err
+		}
+	}
+	return r.f.Write(p)
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// rotate renames the log file to a dated name if it was last written
+// before today, so each file holds one day of logs.
+func rotate(name string, now time.Time) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil
+	}
+	modified := info.ModTime()
+	if sameDay(modified, now) {
+		return nil
+	}
+	if err := os.Rename(name, rotatedName(name, modified)); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
+
+// prune removes rotated log files older than the retention period.
+func prune(name string, keep time.Duration, now time.Time) {
+	ext := filepath.Ext(name)
+	matches, err := filepath.Glob(strings.TrimSuffix(name, ext) + "-*" + ext)
+	if err != nil {
+		return
+	}
+	cutoff := now.Add(-keep)
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(m)
+		}
+	}
+}
+
+// rotatedName returns the name a log file written on day t is rotated to,
+// e.g. agent-desktop-2024-05-01.log.
+func rotatedName(name string, t time.Time) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + t.Format(rotatedDateLayout) + ext
+}
+
+// retention converts a retention setting in days to a duration.
+func retention(days int) time.Duration {
+	if days <= 0 {
+		days = DefaultRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
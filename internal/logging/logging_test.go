@@ -0,0 +1,203 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"debug", slog.LevelDebug, false},
+		{"WARN", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", slog.LevelInfo, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInit_WritesToFileAtLevel(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "logs", "app.log")
+	if err := Init(Options{Level: "warn", File: logFile}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+
+	slog.Info("hidden message")
+	slog.Warn("visible message")
+
+	// Changing the level takes effect without reopening the file
+	if err := Init(Options{Level: "debug", File: logFile}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	slog.Debug("debug message")
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	out := string(data)
+	if strings.Contains(out, "hidden message") {
+		t.Error("info record written at warn level")
+	}
+	if !strings.Contains(out, "visible message") || !strings.Contains(out, "debug message") {
+		t.Errorf("expected records missing from log:\n%s", out)
+	}
+}
+
+func TestInit_RotatesAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "app.log")
+	now := time.Now()
+
+	yesterday := now.Add(-24 * time.Hour)
+	old := now.Add(-10 * 24 * time.Hour)
+	writeAged(t, logFile, yesterday)
+	writeAged(t, rotatedName(logFile, old), old)
+
+	if err := Init(Options{File: logFile, RetentionDays: 3}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+
+	if _, err := os.Stat(rotatedName(logFile, yesterday)); err != nil {
+		t.Errorf("expected yesterday's log to be rotated: %v", err)
+	}
+	if _, err := os.Stat(rotatedName(logFile, old)); !os.IsNotExist(err) {
+		t.Errorf("expected expired log to be pruned, stat err = %v", err)
+	}
+	if _, err := os.Stat(logFile); err != nil {
+		t.Errorf("expected a fresh log file: %v", err)
+	}
+}
+
+func TestInit_WritesJSONLines(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := Init(Options{File: logFile}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+
+	For("tools").Info("tool finished", "tool", "read_file", "ms", 12)
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	var rec map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &rec); err != nil {
+		t.Fatalf("log line isn't JSON: %v\n%s", err, data)
+	}
+	if rec["msg"] != "tool finished" || rec["component"] != "tools" || rec["tool"] != "read_file" || rec["level"] != "INFO" {
+		t.Errorf("record = %v", rec)
+	}
+}
+
+func TestRotatingFile_RotatesAtMidnight(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := Init(Options{File: logFile}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer Close()
+	slog.Info("today")
+
+	today := time.Now()
+	now = func() time.Time { return today.Add(24 * time.Hour) }
+	defer func() { now = time.Now }()
+	slog.Info("tomorrow")
+
+	old, err := os.ReadFile(rotatedName(logFile, today))
+	if err != nil || !strings.Contains(string(old), "today") || strings.Contains(string(old), "tomorrow") {
+		t.Errorf("rotated log = %q, %v", old, err)
+	}
+	if data, _ := os.ReadFile(logFile); !strings.Contains(string(data), "tomorrow") {
+		t.Errorf("new log = %q", data)
+	}
+}
+
+func TestRecent(t *testing.T) {
+	if err := Init(Options{Level: "debug"}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	For("llm").With("model", "gpt-4o").WithGroup("req").Debug("request sent", "status", 200)
+	slog.Warn("plain warning")
+
+	entries := Recent(2)
+	if len(entries) != 2 {
+		t.Fatalf("Recent(2) = %+v", entries)
+	}
+	e := entries[0]
+	if e.Component != "llm" || e.Message != "request sent" || e.Level != "DEBUG" || e.Attrs["model"] != "gpt-4o" || e.Attrs["req.status"] != "200" {
+		t.Errorf("first entry = %+v", e)
+	}
+	if e := entries[1]; e.Component != "" || e.Message != "plain warning" {
+		t.Errorf("second entry = %+v", e)
+	}
+
+	for i := 0; i < recentSize+5; i++ {
+		slog.Info("filler")
+	}
+	if got := Recent(0); len(got) != recentSize || got[len(got)-1].Message != "filler" {
+		t.Errorf("Recent(0) returned %d entries", len(got))
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	if err := Init(Options{Level: "debug"}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	got := make(chan Entry, 10)
+	stop := Subscribe(slog.LevelWarn, func(e Entry) { got <- e })
+
+	slog.Info("ignored")
+	For("llm").Error("request failed", "status", 500)
+	select {
+	case e := <-got:
+		if e.Message != "request failed" || e.Component != "llm" || e.Attrs["status"] != "500" {
+			t.Errorf("entry = %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber got nothing")
+	}
+
+	stop()
+	stop()
+	slog.Error("after stop")
+	select {
+	case e := <-got:
+		t.Errorf("got %+v after stop", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// writeAged creates a file with the given modification time.
+func writeAged(t *testing.T, name string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte("old\n"), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatalf("failed to age %s: %v", name, err)
+	}
+}
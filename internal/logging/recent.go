@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// recentSize is how many records are kept in memory for Recent.
+const recentSize = 1000
+
+// Entry is a log record kept for the UI.
+type Entry struct {
+	Time      time.Time         `json:"time"`
+	Level     string            `json:"level"`
+	Component string            `json:"component,omitempty"`
+	Message   string            `json:"message"`
+	Attrs     map[string]string `json:"attrs,omitempty"`
+}
+
+var (
+	recentMu   sync.Mutex
+	recent     = make([]Entry, recentSize)
+	recentNext int // Index the next entry is written at
+	recentLen  int
+)
+
+// Recent returns up to the last n records logged, oldest first.
+func Recent(n int) []Entry {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+	if n <= 0 || n > recentLen {
+		n = recentLen
+	}
+	out := make([]Entry, n)
+	start := recentNext - n
+	for i := range out {
+		out[i] = recent[(start+i+recentSize)%recentSize]
+	}
+	return out
+}
+
+func record(e Entry) {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+	recent[recentNext] = e
+	recentNext = (recentNext + 1) % recentSize
+	if recentLen < recentSize {
+		recentLen++
+	}
+}
+
+// recentHandler keeps records in the recent buffer.
+type recentHandler struct {
+	opts   *slog.HandlerOptions
+	attrs  []slog.Attr
+	prefix string // Group names from WithGroup, each followed by a dot
+}
+
+func (h *recentHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= h.opts.Level.Level()
+}
+
+func (h *recentHandler) Handle(ctx context.Context, r slog.Record) error {
+	e := Entry{Time: r.Time, Level: r.Level.String(), Message: r.Message}
+	for _, a := range h.attrs {
+		if a.Key == "component" {
+			e.Component = a.Value.String()
+			continue
+		}
+		flatten(&e.Attrs, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		flatten(&e.Attrs, h.prefix, a)
+		return true
+	})
+	record(e)
+	publish(r.Level, e)
+	return nil
+}
+
+func (h *recentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := *h
+	out.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		// Attributes added inside a group belong to it
+		a.Key = h.prefix + a.Key
+		out.attrs = append(out.attrs, a)
+	}
+	return &out
+}
+
+func (h *recentHandler) WithGroup(name string) slog.Handler {
+	out := *h
+	out.prefix += name + "."
+	return &out
+}
+
+// flatten adds an attribute to attrs, with groups as dotted keys.
+func flatten(attrs *map[string]string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		for _, g := range a.Value.Group() {
+			flatten(attrs, prefix+a.Key+".", g)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	if *attrs == nil {
+		*attrs = map[string]string{}
+	}
+	(*attrs)[prefix+a.Key] = a.Value.String()
+}
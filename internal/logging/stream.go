@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// streamBuffer is how many records a subscriber can fall behind by before
+// records are dropped for it.
+const streamBuffer = 256
+
+type subscriber struct {
+	level slog.Level
+	ch    chan Entry
+}
+
+var (
+	subsMu sync.Mutex
+	subs   = map[*subscriber]struct{}{}
+)
+
+// Subscribe calls fn with each record at or above level logged from now
+// on, in order, on a goroutine of its own, so logging never waits for fn.
+// Records are dropped while fn is more than 256 behind. Records below the
+// level set with Init aren't logged, so they aren't passed on either. The
+// returned function ends the subscription.
+func Subscribe(level slog.Level, fn func(Entry)) (stop func()) {
+	s := &subscriber{level: level, ch: make(chan Entry, streamBuffer)}
+	subsMu.Lock()
+	subs[s] = struct{}{}
+	subsMu.Unlock()
+
+	go func() {
+		for e := range s.ch {
+			fn(e)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			subsMu.Lock()
+			delete(subs, s)
+			close(s.ch)
+			subsMu.Unlock()
+		})
+	}
+}
+
+// publish passes a record to the subscribers that want its level.
+func publish(l slog.Level, e Entry) {
+	subsMu.Lock()
+	defer subsMu.Unlock()
+	for s := range subs {
+		if l < s.level {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+		}
+	}
+}
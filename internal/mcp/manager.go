@@ -0,0 +1,328 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+)
+
+// connectTimeout bounds starting a server and the MCP handshake.
+const connectTimeout = 30 * time.Second
+
+// callTimeout bounds a single tool call.
+const callTimeout = 5 * time.Minute
+
+// maxToolName is the longest function name the OpenAI API accepts.
+const maxToolName = 64
+
+// ServerStatus describes the connection to a configured MCP server.
+type ServerStatus struct {
+	Name      string   `json:"name"`
+	Transport string   `json:"transport"` // "stdio" or "sse"
+	State     string   `json:"state"`     // "connecting", "connected", "failed", or "disabled"
+	Tools     []string `json:"tools"`     // Names the model sees
+	Error     string   `json:"error,omitempty"`
+}
+
+// Connection states in ServerStatus.State.
+const (
+	StateConnecting = "connecting"
+	StateConnected  = "connected"
+	StateFailed     = "failed"
+	StateDisabled   = "disabled"
+)
+
+// connection is a configured server and its current client.
+type connection struct {
+	config config.MCPServer
+	client *Client
+	status ServerStatus
+	ctx    context.Context // Cancelled when the server is removed or replaced
+	cancel context.CancelFunc
+}
+
+// Manager keeps connections to the configured MCP servers and registers
+// their tools with the tools package, named "<server>__<tool>".
+type Manager struct {
+	mu          sync.Mutex
+	connections map[string]*connection
+
+	// onChange, if set, is called after a server's status changes
+	onChange func([]ServerStatus)
+}
+
+// NewManager creates a manager with no servers. onChange, if set, is
+// called with every server's status after any of them changes.
+func NewManager(onChange func([]ServerStatus)) *Manager {
+	return &Manager{connections: map[string]*connection{}, onChange: onChange}
+}
+
+// Apply makes the connections match servers: new and changed servers are
+// connected in the background, and removed or disabled ones are stopped.
+// Unchanged servers keep their connection.
+func (m *Manager) Apply(servers []config.MCPServer) {
+	m.mu.Lock()
+	wanted := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		wanted[server.Name] = true
+		if existing, ok := m.connections[server.Name]; ok && reflect.DeepEqual(existing.config, server) {
+			continue
+		}
+		m.replaceLocked(server)
+	}
+	for name, conn := range m.connections {
+		if !wanted[name] {
+			m.stopLocked(conn)
+			delete(m.connections, name)
+		}
+	}
+	m.mu.Unlock()
+	m.changed()
+}
+
+// Reconnect stops and restarts the connection to the named server.
+func (m *Manager) Reconnect(name string) error {
+	m.mu.Lock()
+	conn, ok := m.connections[name]
+	if ok {
+		m.replaceLocked(conn.config)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown MCP server: %s", name)
+	}
+	m.changed()
+	return nil
+}
+
+// Status returns the status of every configured server, sorted by name.
+func (m *Manager) Status() []ServerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]ServerStatus, 0, len(m.connections))
+	for _, conn := range m.connections {
+		statuses = append(statuses, conn.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Close stops every server and removes their tools.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, conn := range m.connections {
+		m.stopLocked(conn)
+		delete(m.connections, name)
+	}
+}
+
+// replaceLocked stops any connection to server and starts a new one.
+func (m *Manager) replaceLocked(server config.MCPServer) {
+	if existing, ok := m.connections[server.Name]; ok {
+		m.stopLocked(existing)
+	}
+
+	transport := "stdio"
+	if server.URL != "" {
+		transport = "sse"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := &connection{
+		config: server,
+		status: ServerStatus{Name: server.Name, Transport: transport, State: StateConnecting, Tools: []string{}},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	m.connections[server.Name] = conn
+
+	if server.Disabled {
+		conn.status.State = StateDisabled
+		return
+	}
+	go m.connect(conn)
+}
+
+// stopLocked closes conn and removes its tools.
+func (m *Manager) stopLocked(conn *connection) {
+	conn.cancel()
+	tools.SetExternalTools(toolSource(conn.config.Name), nil)
+	if conn.client != nil {
+		go conn.client.Close()
+	}
+}
+
+// connect connects to conn's server, registers its tools, and watches for
+// the connection ending.
+func (m *Manager) connect(conn *connection) {
+	name := conn.config.Name
+	connectCtx, cancel := context.WithTimeout(conn.ctx, connectTimeout)
+	defer cancel()
+
+	client, err := Connect(connectCtx, conn.config, func() { m.refreshTools(conn) })
+	m.mu.Lock()
+	stale := conn.ctx.Err() != nil
+	if err == nil && !stale {
+		conn.client = client
+	}
+	m.mu.Unlock()
+	if stale {
+		// Removed or replaced while connecting
+		if client != nil {
+			client.Close()
+		}
+		return
+	}
+	if err == nil {
+		err = m.refreshTools(conn)
+	}
+	if err != nil {
+		slog.Warn("failed to connect to MCP server", "server", name, "error", err)
+		m.setStatus(conn, StateFailed, err)
+		return
+	}
+	slog.Info("connected to MCP server", "server", name, "name", client.Info().Name, "version", client.Info().Version)
+
+	select {
+	case <-client.Done():
+		if conn.ctx.Err() == nil {
+			slog.Warn("MCP server disconnected", "server", name, "error", client.Err())
+			tools.SetExternalTools(toolSource(name), nil)
+			m.setStatus(conn, StateFailed, client.Err())
+		}
+	case <-conn.ctx.Done():
+	}
+}
+
+// refreshTools lists the server's tools and registers them.
+func (m *Manager) refreshTools(conn *connection) error {
+	ctx, cancel := context.WithTimeout(conn.ctx, connectTimeout)
+	defer cancel()
+
+	m.mu.Lock()
+	client := conn.client
+	m.mu.Unlock()
+	if client == nil {
+		return ErrClosed
+	}
+	list, err := client.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+
+	external := make([]tools.ExternalTool, 0, len(list))
+	for _, tool := range list {
+		external = append(external, externalTool(conn.config.Name, client, tool))
+	}
+
+	m.mu.Lock()
+	if conn.ctx.Err() != nil {
+		m.mu.Unlock()
+		return nil
+	}
+	// Tools named like another source's are left out; the status says so
+	source := toolSource(conn.config.Name)
+	err = tools.SetExternalTools(source, external)
+	names := make([]string, 0, len(external))
+	for _, t := range external {
+		if owner, _ := tools.ExternalSource(t.Definition.Function.Name); owner == source {
+			names = append(names, t.Definition.Function.Name)
+		}
+	}
+	conn.status.Tools = names
+	conn.status.State = StateConnected
+	conn.status.Error = ""
+	if err != nil {
+		slog.Warn("left out MCP tools", "server", conn.config.Name, "error", err)
+		conn.status.Error = err.Error()
+	}
+	m.mu.Unlock()
+	m.changed()
+	return nil
+}
+
+// setStatus records a connection's state and reports the change.
+func (m *Manager) setStatus(conn *connection, state string, err error) {
+	m.mu.Lock()
+	conn.status.State = state
+	conn.status.Error = ""
+	if err != nil {
+		conn.status.Error = err.Error()
+	}
+	if state != StateConnected {
+		conn.status.Tools = []string{}
+	}
+	m.mu.Unlock()
+	m.changed()
+}
+
+// changed reports every server's status to onChange.
+func (m *Manager) changed() {
+	if m.onChange != nil {
+		m.onChange(m.Status())
+	}
+}
+
+// externalTool adapts an MCP tool for the tools package.
+func externalTool(server string, client *Client, tool Tool) tools.ExternalTool {
+	schema := tool.InputSchema
+	if schema == nil {
+		schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	description := tool.Description
+	if description == "" {
+		description = tool.Name
+	}
+
+	return tools.ExternalTool{
+		Definition: tools.ToolDefinition{
+			Type: "function",
+			Function: tools.ToolFunction{
+				Name:        ToolName(server, tool.Name),
+				Description: fmt.Sprintf("%s (from the %s MCP server)", description, server),
+				Parameters:  schema,
+			},
+		},
+		Execute: func(args map[string]interface{}) tools.ToolResult {
+			ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+			defer cancel()
+
+			result, err := client.CallTool(ctx, tool.Name, args)
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: fmt.Sprintf("MCP server %s: %v", server, err)}
+			}
+			if result.IsError {
+				return tools.ToolResult{Success: false, Error: result.Text()}
+			}
+			return tools.ToolResult{Success: true, Output: result.Text()}
+		},
+	}
+}
+
+// invalidToolChars matches characters not allowed in function names.
+var invalidToolChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// ToolName returns the name the model sees for a server's tool:
+// "<server>__<tool>", with characters the API rejects replaced by "_" and
+// cut to the API's length limit.
+func ToolName(server, tool string) string {
+	name := invalidToolChars.ReplaceAllString(server+"__"+tool, "_")
+	if len(name) > maxToolName {
+		name = name[:maxToolName]
+	}
+	return name
+}
+
+// toolSource is the tools package source for a server's tools.
+func toolSource(server string) string {
+	return "mcp:" + server
+}
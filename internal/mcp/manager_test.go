@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/tools"
+)
+
+// waitForState polls until the named server reaches state.
+func waitForState(t *testing.T, m *Manager, name, state string) ServerStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, s := range m.Status() {
+			if s.Name == name && s.State == state {
+				return s
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s to be %s; status %+v", name, state, m.Status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManager(t *testing.T) {
+	m := NewManager(nil)
+	defer m.Close()
+
+	m.Apply([]config.MCPServer{
+		fakeStdioServer(t, "fake"),
+		{Name: "off", Command: "unused", Disabled: true},
+		{Name: "broken", Command: "definitely-not-an-mcp-server"},
+	})
+
+	status := waitForState(t, m, "fake", StateConnected)
+	if len(status.Tools) != 2 || status.Tools[0] != "fake__echo" {
+		t.Errorf("tools = %v, want fake__echo and fake__fail", status.Tools)
+	}
+	waitForState(t, m, "off", StateDisabled)
+	if failed := waitForState(t, m, "broken", StateFailed); failed.Error == "" {
+		t.Error("expected an error for the broken server")
+	}
+
+	result := tools.ExecuteTool("fake__echo", map[string]interface{}{"text": "via tools"})
+	if !result.Success || result.Output != "via tools" {
+		t.Errorf("fake__echo result = %+v", result)
+	}
+	result = tools.ExecuteTool("fake__fail", nil)
+	if result.Success || result.Error != "it broke" {
+		t.Errorf("fake__fail result = %+v", result)
+	}
+
+	found := false
+	for _, def := range tools.GetToolDefinitions() {
+		if def.Function.Name == "fake__echo" {
+			found = strings.Contains(def.Function.Description, "fake MCP server")
+		}
+	}
+	if !found {
+		t.Error("expected fake__echo in the tool definitions, attributed to its server")
+	}
+
+	// Removing the server removes its tools
+	m.Apply(nil)
+	if len(m.Status()) != 0 {
+		t.Errorf("status after removal = %+v", m.Status())
+	}
+	if result := tools.ExecuteTool("fake__echo", nil); result.Success {
+		t.Error("expected fake__echo to be gone")
+	}
+}
+
+func TestToolName(t *testing.T) {
+	tests := []struct {
+		server, tool, want string
+	}{
+		{"github", "create_issue", "github__create_issue"},
+		{"files", "read.file", "files__read_file"},
+		{"s", strings.Repeat("x", 80), "s__" + strings.Repeat("x", 61)},
+	}
+	for _, tt := range tests {
+		if got := ToolName(tt.server, tt.tool); got != tt.want {
+			t.Errorf("ToolName(%q, %q) = %q, want %q", tt.server, tt.tool, got, tt.want)
+		}
+	}
+}
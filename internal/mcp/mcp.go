@@ -0,0 +1,374 @@
+// Package mcp is a client for the Model Context Protocol. It connects to
+// MCP servers over stdio or SSE, lists their tools, and calls them, so the
+// agent can use tools from the MCP ecosystem alongside its own. Server
+// works the other way round, offering the built-in tools to MCP clients.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"agent-desktop/internal/config"
+)
+
+// ProtocolVersion is the MCP revision this client speaks.
+const ProtocolVersion = "2024-11-05"
+
+// clientName identifies this client to servers.
+const clientName = "agent-desktop"
+
+// ErrClosed is returned for requests on a closed connection.
+var ErrClosed = errors.New("MCP connection closed")
+
+// JSON-RPC error codes used in replies to server requests.
+const codeMethodNotFound = -32601
+
+// Tool is a tool offered by an MCP server.
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema,omitempty"`
+}
+
+// Content is one item of a tool call's result.
+type Content struct {
+	Type     string    `json:"type"` // "text", "image", or "resource"
+	Text     string    `json:"text,omitempty"`
+	MimeType string    `json:"mimeType,omitempty"`
+	Data     string    `json:"data,omitempty"` // Base64 image data
+	Resource *Resource `json:"resource,omitempty"`
+}
+
+// Resource is a resource embedded in a tool call's result.
+type Resource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// CallResult is the result of a tool call. IsError means the tool ran and
+// reported a failure, as opposed to the call itself failing.
+type CallResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text renders the result's content as text for the model. Non-text
+// content is described rather than included.
+func (r *CallResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		switch {
+		case c.Type == "text":
+			parts = append(parts, c.Text)
+		case c.Type == "resource" && c.Resource != nil && c.Resource.Text != "":
+			parts = append(parts, c.Resource.Text)
+		case c.Type == "resource" && c.Resource != nil:
+			parts = append(parts, fmt.Sprintf("[resource %s]", c.Resource.URI))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content, %s]", c.Type, c.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ServerInfo names an MCP server, as reported when connecting.
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// message is a JSON-RPC 2.0 request, notification, or response.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
+}
+
+// transport carries JSON-RPC messages to and from a server.
+type transport interface {
+	// start connects and delivers every incoming message to receive. When
+	// the connection ends, closed is called once with the reason.
+	start(ctx context.Context, receive func([]byte), closed func(error)) error
+	send(ctx context.Context, msg []byte) error
+	close() error
+}
+
+// Client is a connection to one MCP server. It is safe for concurrent use.
+type Client struct {
+	transport transport
+	info      ServerInfo
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+	err     error // Why the connection closed; nil while open
+	done    chan struct{}
+
+	// toolsChanged is called when the server says its tools changed
+	toolsChanged func()
+}
+
+// Connect starts or connects to the server and completes the MCP
+// handshake. onToolsChanged, if set, is called when the server's tool list
+// changes.
+func Connect(ctx context.Context, server config.MCPServer, onToolsChanged func()) (*Client, error) {
+	var t transport
+	if server.URL != "" {
+		t = newSSETransport(server.URL, server.Headers)
+	} else {
+		t = newStdioTransport(server.Name, server.Command, server.Args, server.Env)
+	}
+	return connect(ctx, t, onToolsChanged)
+}
+
+// connect completes the handshake over t.
+func connect(ctx context.Context, t transport, onToolsChanged func()) (*Client, error) {
+	c := &Client{
+		transport:    t,
+		pending:      map[int64]chan *message{},
+		done:         make(chan struct{}),
+		toolsChanged: onToolsChanged,
+	}
+	if err := t.start(ctx, c.receive, c.closed); err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		ProtocolVersion string     `json:"protocolVersion"`
+		ServerInfo      ServerInfo `json:"serverInfo"`
+	}
+	params := map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": clientName, "version": "1.0"},
+	}
+	if err := c.request(ctx, "initialize", params, &result); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("MCP handshake failed: %w", err)
+	}
+	c.info = result.ServerInfo
+
+	if err := c.notify(ctx, "notifications/initialized", nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Info returns the server's name and version.
+func (c *Client) Info() ServerInfo {
+	return c.info
+}
+
+// Done is closed when the connection ends.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended, or nil while it is open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// ListTools returns every tool the server offers.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.request(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool calls a tool with the given arguments.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var result CallResult
+	if err := c.request(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close ends the connection, stopping a stdio server.
+func (c *Client) Close() error {
+	err := c.transport.close()
+	c.closed(ErrClosed)
+	return err
+}
+
+// request sends a request and decodes its result into result.
+func (c *Client) request(ctx context.Context, method string, params, result interface{}) error {
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	reply := make(chan *message, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(ctx, message{ID: json.RawMessage(fmt.Sprint(id)), Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-reply:
+		if msg == nil {
+			return c.Err()
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	case <-ctx.Done():
+		c.notify(context.Background(), "notifications/cancelled", map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification, which has no reply.
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	return c.write(ctx, message{Method: method}, params)
+}
+
+// write encodes msg with params and sends it.
+func (c *Client) write(ctx context.Context, msg message, params interface{}) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = data
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.transport.send(ctx, data)
+}
+
+// receive handles a message from the server: a reply to one of our
+// requests, a request from the server, or a notification.
+func (c *Client) receive(data []byte) {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		slog.Debug("ignoring malformed MCP message", "error", err)
+		return
+	}
+
+	switch {
+	case msg.Method == "" && len(msg.ID) > 0:
+		var id int64
+		if err := json.Unmarshal(msg.ID, &id); err != nil {
+			return
+		}
+		c.mu.Lock()
+		reply, ok := c.pending[id]
+		c.mu.Unlock()
+		if ok {
+			select {
+			case reply <- &msg:
+			default: // Duplicate reply
+			}
+		}
+
+	case len(msg.ID) > 0:
+		// The server asks something of us; only pings are supported
+		go c.answer(msg)
+
+	case msg.Method == "notifications/tools/list_changed":
+		if c.toolsChanged != nil {
+			go c.toolsChanged()
+		}
+
+	case msg.Method == "notifications/message":
+		slog.Debug("MCP server log", "server", c.info.Name, "params", string(msg.Params))
+	}
+}
+
+// answer replies to a request from the server.
+func (c *Client) answer(req message) {
+	reply := message{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &rpcError{Code: codeMethodNotFound, Message: "method not supported: " + req.Method}
+	}
+	data, _ := json.Marshal(reply)
+	if err := c.transport.send(context.Background(), data); err != nil {
+		slog.Debug("failed to answer MCP server request", "method", req.Method, "error", err)
+	}
+}
+
+// closed records why the connection ended and fails pending requests.
+// Only the first call has an effect.
+func (c *Client) closed(reason error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if reason == nil {
+		reason = ErrClosed
+	}
+	c.err = reason
+	for id, reply := range c.pending {
+		select {
+		case reply <- nil:
+		default: // Already answered
+		}
+		delete(c.pending, id)
+	}
+	close(c.done)
+}
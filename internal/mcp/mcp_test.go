@@ -0,0 +1,168 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+// fakeServerEnv makes the test binary act as a stdio MCP server.
+const fakeServerEnv = "AGENT_DESKTOP_FAKE_MCP_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) == "1" {
+		serveFake(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeReply answers one request of the fake server, or returns nil for
+// notifications.
+func fakeReply(req message) *message {
+	if len(req.ID) == 0 {
+		return nil
+	}
+	reply := &message{JSONRPC: "2.0", ID: req.ID}
+	var result interface{}
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"serverInfo":      ServerInfo{Name: "fake", Version: "1.2"},
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+		}
+	case "tools/list":
+		var params struct{ Cursor string }
+		json.Unmarshal(req.Params, &params)
+		// Two pages, to exercise pagination
+		if params.Cursor == "" {
+			result = map[string]interface{}{
+				"tools":      []Tool{{Name: "echo", Description: "Echo text", InputSchema: map[string]interface{}{"type": "object"}}},
+				"nextCursor": "2",
+			}
+		} else {
+			result = map[string]interface{}{"tools": []Tool{{Name: "fail"}}}
+		}
+	case "tools/call":
+		var params struct {
+			Name      string
+			Arguments map[string]interface{}
+		}
+		json.Unmarshal(req.Params, &params)
+		if params.Name == "fail" {
+			result = CallResult{Content: []Content{{Type: "text", Text: "it broke"}}, IsError: true}
+		} else {
+			result = CallResult{Content: []Content{{Type: "text", Text: fmt.Sprint(params.Arguments["text"])}}}
+		}
+	default:
+		reply.Error = &rpcError{Code: codeMethodNotFound, Message: "unknown method"}
+		return reply
+	}
+	reply.Result, _ = json.Marshal(result)
+	return reply
+}
+
+// serveFake runs the fake server over newline-delimited JSON.
+func serveFake(r io.Reader, w io.Writer) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var req message
+		if json.Unmarshal(scanner.Bytes(), &req) != nil {
+			continue
+		}
+		if reply := fakeReply(req); reply != nil {
+			data, _ := json.Marshal(reply)
+			fmt.Fprintf(w, "%s\n", data)
+		}
+	}
+}
+
+// fakeStdioServer returns a config that runs the fake server over stdio.
+func fakeStdioServer(t *testing.T, name string) config.MCPServer {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to find test binary: %v", err)
+	}
+	return config.MCPServer{Name: name, Command: exe, Env: map[string]string{fakeServerEnv: "1"}}
+}
+
+func testClient(t *testing.T, client *Client) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if info := client.Info(); info.Name != "fake" || info.Version != "1.2" {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+		t.Errorf("ListTools() = %+v, want echo and fail", tools)
+	}
+
+	result, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "hello"})
+	if err != nil || result.IsError || result.Text() != "hello" {
+		t.Errorf("CallTool(echo) = %+v, %v", result, err)
+	}
+	result, err = client.CallTool(ctx, "fail", nil)
+	if err != nil || !result.IsError || result.Text() != "it broke" {
+		t.Errorf("CallTool(fail) = %+v, %v", result, err)
+	}
+}
+
+func TestClient_Stdio(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := Connect(ctx, fakeStdioServer(t, "fake"), nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	testClient(t, client)
+
+	client.Close()
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the connection to close")
+	}
+	if _, err := client.ListTools(context.Background()); err == nil {
+		t.Error("expected an error after Close")
+	}
+}
+
+func TestClient_StdioServerExits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := Connect(ctx, config.MCPServer{Name: "broken", Command: "definitely-not-an-mcp-server"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Connect error = %v, want a start failure naming the server", err)
+	}
+}
+
+func TestCallResult_Text(t *testing.T) {
+	result := CallResult{Content: []Content{
+		{Type: "text", Text: "one"},
+		{Type: "image", MimeType: "image/png", Data: "AAAA"},
+		{Type: "resource", Resource: &Resource{URI: "file:///a.txt", Text: "contents"}},
+		{Type: "resource", Resource: &Resource{URI: "file:///b.bin"}},
+	}}
+	want := "one\n[image content, image/png]\ncontents\n[resource file:///b.bin]"
+	if got := result.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,208 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+
+	"agent-desktop/internal/tools"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	codeParseError    = -32700
+	codeInvalidParams = -32602
+)
+
+// hiddenTools are built-in tools that only make sense inside the agent
+// loop, so they are not offered to other clients.
+var hiddenTools = map[string]bool{"task_complete": true}
+
+// Server serves the built-in tools to an MCP client over newline-delimited
+// JSON-RPC, the stdio transport. Tool calls run one at a time in a shared
+// shell session, with the same safety checks as in the app.
+type Server struct {
+	version string
+
+	writeMu sync.Mutex
+	w       io.Writer
+
+	// callMu runs tool calls one at a time: they share the shell session
+	callMu sync.Mutex
+
+	progressMu sync.Mutex
+	progress   map[string]json.RawMessage // Call ID -> client's progress token
+}
+
+// NewServer creates a server that reports version to clients.
+func NewServer(version string) *Server {
+	return &Server{version: version, progress: map[string]json.RawMessage{}}
+}
+
+// Serve reads requests from r and writes replies to w until r ends or ctx
+// is cancelled. Tool progress is sent as notifications/progress to clients
+// that asked for it.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.w = w
+	tools.SetProgressHook(s.sendProgress)
+	defer tools.SetProgressHook(nil)
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				lines <- line
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case line := <-lines:
+			var req message
+			if err := json.Unmarshal(line, &req); err != nil {
+				s.write(message{Error: &rpcError{Code: codeParseError, Message: "invalid JSON"}, ID: json.RawMessage("null")})
+				continue
+			}
+			if len(req.ID) == 0 {
+				// Notifications need no reply
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.write(s.handle(req))
+			}()
+		}
+	}
+}
+
+// handle answers one request.
+func (s *Server) handle(req message) message {
+	reply := message{ID: req.ID}
+	var result interface{}
+
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"serverInfo":      ServerInfo{Name: clientName, Version: s.version},
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+		}
+	case "ping":
+		result = map[string]interface{}{}
+	case "tools/list":
+		result = map[string]interface{}{"tools": builtinTools()}
+	case "tools/call":
+		var params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+			Meta      struct {
+				ProgressToken json.RawMessage `json:"progressToken"`
+			} `json:"_meta"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" || hiddenTools[params.Name] {
+			reply.Error = &rpcError{Code: codeInvalidParams, Message: "tools/call needs the name of a tool from tools/list"}
+			return reply
+		}
+		result = s.callTool(string(req.ID), params.Name, params.Arguments, params.Meta.ProgressToken)
+	default:
+		reply.Error = &rpcError{Code: codeMethodNotFound, Message: "method not supported: " + req.Method}
+		return reply
+	}
+
+	reply.Result, _ = json.Marshal(result)
+	return reply
+}
+
+// callTool runs a built-in tool and converts its result.
+func (s *Server) callTool(callID, name string, args map[string]interface{}, progressToken json.RawMessage) CallResult {
+	if len(progressToken) > 0 {
+		s.progressMu.Lock()
+		s.progress[callID] = progressToken
+		s.progressMu.Unlock()
+		defer func() {
+			s.progressMu.Lock()
+			delete(s.progress, callID)
+			s.progressMu.Unlock()
+		}()
+	}
+
+	s.callMu.Lock()
+	result := tools.ExecuteToolCall(callID, name, args)
+	s.callMu.Unlock()
+
+	if !result.Success {
+		return CallResult{Content: []Content{{Type: "text", Text: result.Error}}, IsError: true}
+	}
+	return CallResult{Content: []Content{{Type: "text", Text: result.Output}}}
+}
+
+// sendProgress forwards tool progress to a client that asked for it.
+func (s *Server) sendProgress(p tools.Progress) {
+	s.progressMu.Lock()
+	token, ok := s.progress[p.ToolCallID]
+	s.progressMu.Unlock()
+	if !ok {
+		return
+	}
+
+	params := map[string]interface{}{"progressToken": token, "progress": p.Done}
+	if p.Total > 0 {
+		params["total"] = p.Total
+	}
+	data, _ := json.Marshal(params)
+	s.write(message{Method: "notifications/progress", Params: data})
+}
+
+// write sends a message to the client.
+func (s *Server) write(msg message) {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Warn("failed to encode MCP message", "error", err)
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		slog.Debug("failed to write to MCP client", "error", err)
+	}
+}
+
+// builtinTools returns the built-in tools in MCP form.
+func builtinTools() []Tool {
+	var list []Tool
+	for _, def := range tools.GetToolDefinitions() {
+		if hiddenTools[def.Function.Name] {
+			continue
+		}
+		list = append(list, Tool{
+			Name:        def.Function.Name,
+			Description: def.Function.Description,
+			InputSchema: def.Function.Parameters,
+		})
+	}
+	return list
+}
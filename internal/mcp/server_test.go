@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/tools"
+)
+
+// pipeTransport connects a client to an in-process server.
+type pipeTransport struct {
+	r io.Reader
+	w io.WriteCloser
+}
+
+func (p *pipeTransport) start(ctx context.Context, receive func([]byte), closed func(error)) error {
+	go func() {
+		scanner := bufio.NewScanner(p.r)
+		for scanner.Scan() {
+			receive(append([]byte(nil), scanner.Bytes()...))
+		}
+		closed(scanner.Err())
+	}()
+	return nil
+}
+
+func (p *pipeTransport) send(ctx context.Context, msg []byte) error {
+	_, err := p.w.Write(append(msg, '\n'))
+	return err
+}
+
+func (p *pipeTransport) close() error {
+	return p.w.Close()
+}
+
+func TestServer(t *testing.T) {
+	defer tools.ResetSession()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	requests, toServer := io.Pipe()
+	fromServer, replies := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- NewServer("1.0").Serve(ctx, requests, replies)
+		replies.Close()
+	}()
+
+	client, err := connect(ctx, &pipeTransport{r: fromServer, w: toServer}, nil)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	if info := client.Info(); info.Name != "agent-desktop" || info.Version != "1.0" {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	list, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	names := map[string]bool{}
+	for _, tool := range list {
+		names[tool.Name] = true
+		if tool.InputSchema == nil {
+			t.Errorf("tool %s has no input schema", tool.Name)
+		}
+	}
+	if !names["read_file"] || !names["run_command"] || names["task_complete"] {
+		t.Errorf("tools = %v, want the built-in tools without task_complete", names)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.txt")
+	result, err := client.CallTool(ctx, "write_file", map[string]interface{}{"path": path, "content": "hello"})
+	if err != nil || result.IsError {
+		t.Fatalf("write_file = %+v, %v", result, err)
+	}
+	result, err = client.CallTool(ctx, "read_file", map[string]interface{}{"path": path})
+	if err != nil || result.IsError || !strings.Contains(result.Text(), "hello") {
+		t.Errorf("read_file = %+v, %v; want the written text", result, err)
+	}
+
+	// The shell session lasts across calls
+	if result, err := client.CallTool(ctx, "change_directory", map[string]interface{}{"path": dir}); err != nil || result.IsError {
+		t.Fatalf("change_directory = %+v, %v", result, err)
+	}
+	result, err = client.CallTool(ctx, "read_file", map[string]interface{}{"path": "note.txt"})
+	if err != nil || result.IsError {
+		t.Errorf("relative read_file = %+v, %v; want the session's directory used", result, err)
+	}
+
+	result, err = client.CallTool(ctx, "read_file", map[string]interface{}{"path": filepath.Join(dir, "missing.txt")})
+	if err != nil || !result.IsError {
+		t.Errorf("read_file(missing) = %+v, %v; want a tool error", result, err)
+	}
+	if _, err := client.CallTool(ctx, "task_complete", nil); err == nil {
+		t.Error("expected task_complete to be refused")
+	}
+
+	client.Close()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve returned %v, want nil when the client disconnects", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Serve to return")
+	}
+}
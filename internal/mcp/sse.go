@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// sseTransport connects to a remote server with MCP's HTTP+SSE transport:
+// messages from the server arrive on an event stream, and messages to it
+// are posted to the endpoint the stream announces first.
+type sseTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu       sync.Mutex
+	endpoint string
+	cancel   context.CancelFunc
+}
+
+func newSSETransport(rawURL string, headers map[string]string) *sseTransport {
+	return &sseTransport{url: rawURL, headers: headers, client: http.DefaultClient}
+}
+
+func (t *sseTransport) start(ctx context.Context, receive func([]byte), closed func(error)) error {
+	// The stream outlives ctx, which only bounds connecting
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, t.url, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to connect to MCP server: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("MCP server returned %s", resp.Status)
+	}
+	t.cancel = cancel
+
+	ready := make(chan string, 1)
+	go func() {
+		defer resp.Body.Close()
+		err := readEvents(resp.Body, func(event, data string) {
+			switch event {
+			case "endpoint":
+				select {
+				case ready <- data:
+				default:
+				}
+			case "message":
+				receive([]byte(data))
+			}
+		})
+		if err == nil {
+			err = errors.New("MCP server closed the event stream")
+		}
+		closed(err)
+	}()
+
+	select {
+	case endpoint := <-ready:
+		resolved, err := resolveEndpoint(t.url, endpoint)
+		if err != nil {
+			cancel()
+			return err
+		}
+		t.mu.Lock()
+		t.endpoint = resolved
+		t.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return fmt.Errorf("MCP server did not announce its message endpoint: %w", ctx.Err())
+	}
+}
+
+func (t *sseTransport) send(ctx context.Context, msg []byte) error {
+	t.mu.Lock()
+	endpoint := t.endpoint
+	t.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("MCP server returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (t *sseTransport) close() error {
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return nil
+}
+
+func (t *sseTransport) setHeaders(req *http.Request) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// resolveEndpoint resolves the announced message endpoint against the
+// stream's URL. The endpoint must be on the same host, so a server can't
+// direct messages (and headers such as Authorization) elsewhere.
+func resolveEndpoint(streamURL, endpoint string) (string, error) {
+	base, err := url.Parse(streamURL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", fmt.Errorf("invalid MCP message endpoint: %w", err)
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != base.Scheme || resolved.Host != base.Host {
+		return "", fmt.Errorf("MCP message endpoint %s is not on %s", resolved, base.Host)
+	}
+	return resolved.String(), nil
+}
+
+// readEvents parses a server-sent event stream, calling handle for each
+// event. It returns when the stream ends.
+func readEvents(r io.Reader, handle func(event, data string)) error {
+	reader := bufio.NewReader(r)
+	event := ""
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if event == "" {
+					event = "message"
+				}
+				handle(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+	}
+}
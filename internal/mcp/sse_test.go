@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+// newFakeSSEServer serves the fake MCP server over the HTTP+SSE transport.
+// Requests must carry the given Authorization header.
+func newFakeSSEServer(t *testing.T, auth string) *httptest.Server {
+	t.Helper()
+	replies := make(chan []byte, 16)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != auth {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": hello\n\nevent: endpoint\ndata: /messages?session=1\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case data := <-replies:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+				w.(http.Flusher).Flush()
+			}
+		}
+	})
+	mux.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != auth || r.URL.Query().Get("session") != "1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req message
+		json.NewDecoder(r.Body).Decode(&req)
+		if reply := fakeReply(req); reply != nil {
+			data, _ := json.Marshal(reply)
+			replies <- data
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_SSE(t *testing.T) {
+	server := newFakeSSEServer(t, "Bearer abc")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cfg := config.MCPServer{Name: "remote", URL: server.URL + "/sse", Headers: map[string]string{"Authorization": "Bearer abc"}}
+	client, err := Connect(ctx, cfg, nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer client.Close()
+	testClient(t, client)
+
+	cfg.Headers = nil
+	if _, err := Connect(ctx, cfg, nil); err == nil {
+		t.Error("expected Connect to fail without the Authorization header")
+	}
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+		wantErr  bool
+	}{
+		{"/messages?session=1", "https://mcp.example.com/messages?session=1", false},
+		{"messages", "https://mcp.example.com/mcp/messages", false},
+		{"https://mcp.example.com/other", "https://mcp.example.com/other", false},
+		{"https://evil.example.com/steal", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveEndpoint("https://mcp.example.com/mcp/sse", tt.endpoint)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveEndpoint(%q) = %q, %v; want %q", tt.endpoint, got, err, tt.want)
+		}
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := ": comment\r\nevent: endpoint\r\ndata: /post\r\n\r\ndata: {\"a\":\ndata: 1}\n\nevent: ignored\n\n"
+	var got []string
+	readEvents(strings.NewReader(stream), func(event, data string) {
+		got = append(got, event+"="+data)
+	})
+
+	want := []string{"endpoint=/post", "message={\"a\":\n1}"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
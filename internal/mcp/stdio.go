@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stopGrace is how long a stdio server gets to exit after its input is
+// closed before it is killed.
+const stopGrace = 2 * time.Second
+
+// stdioTransport runs a server as a child process and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout.
+type stdioTransport struct {
+	name    string
+	command string
+	args    []string
+	env     map[string]string
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	exited  chan struct{}
+}
+
+func newStdioTransport(name, command string, args []string, env map[string]string) *stdioTransport {
+	return &stdioTransport{name: name, command: command, args: args, env: env}
+}
+
+func (t *stdioTransport) start(ctx context.Context, receive func([]byte), closed func(error)) error {
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Env = os.Environ()
+	for k, v := range t.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stderr = &logWriter{server: t.name}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start MCP server %s: %w", t.name, err)
+	}
+	t.cmd, t.stdin = cmd, stdin
+	t.exited = make(chan struct{})
+
+	go func() {
+		reader := bufio.NewReader(stdout)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				receive(line)
+			}
+			if err != nil {
+				break
+			}
+		}
+		err := cmd.Wait()
+		close(t.exited)
+		if err == nil {
+			err = fmt.Errorf("MCP server %s exited", t.name)
+		} else {
+			err = fmt.Errorf("MCP server %s exited: %w", t.name, err)
+		}
+		closed(err)
+	}()
+	return nil
+}
+
+func (t *stdioTransport) send(ctx context.Context, msg []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err := t.stdin.Write(append(msg, '\n'))
+	return err
+}
+
+// close closes the server's input, which asks it to exit, and kills it if
+// it hasn't after stopGrace.
+func (t *stdioTransport) close() error {
+	if t.cmd == nil {
+		return nil
+	}
+	t.stdin.Close()
+	select {
+	case <-t.exited:
+	case <-time.After(stopGrace):
+		t.cmd.Process.Kill()
+		<-t.exited
+	}
+	return nil
+}
+
+// logWriter logs a stdio server's stderr line by line.
+type logWriter struct {
+	server string
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(w.buf[:i]); len(line) > 0 {
+			slog.Debug("MCP server stderr", "server", w.server, "line", string(line))
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
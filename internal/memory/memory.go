@@ -0,0 +1,253 @@
+// Package memory keeps facts the agent was asked to remember, such as "the
+// staging server is staging.example.com", a small profile of the user's
+// preferences, and a rolling summary of recent work, in a JSON file the
+// user can read and edit. A memory belongs to every conversation or to one
+// project: the git repository, or else the folder, the agent was working
+// in.
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Scopes a memory can have.
+const (
+	ScopeGlobal  = "global"  // Recalled in every conversation
+	ScopeProject = "project" // Recalled only while working in the same project
+)
+
+// maxFactLength bounds a remembered fact.
+const maxFactLength = 1000
+
+// Memory is a remembered fact.
+type Memory struct {
+	ID      string    `json:"id"`
+	Fact    string    `json:"fact"`
+	Scope   string    `json:"scope"`             // ScopeGlobal or ScopeProject
+	Project string    `json:"project,omitempty"` // Project folder, for ScopeProject
+	Created time.Time `json:"created"`
+}
+
+// Store holds memories and the profile in a JSON file. A nil Store holds
+// nothing.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	memories []Memory
+	profile  Profile
+	summary  Summary
+
+	// Serializes summary updates, which call the LLM outside mu
+	summaryMu sync.Mutex
+}
+
+// file is the format of the memories file.
+type file struct {
+	Profile  Profile  `json:"profile"`
+	Summary  Summary  `json:"summary"`
+	Memories []Memory `json:"memories"`
+}
+
+// Open loads the memories saved at path. The store is usable even when the
+// file can't be read, in which case it starts empty and the error says why.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		// Files from before the profile hold just the list of memories
+		if err := json.Unmarshal(data, &s.memories); err != nil {
+			return s, fmt.Errorf("failed to read memories: %w", err)
+		}
+		return s, nil
+	}
+	s.memories, s.profile, s.summary = f.Memories, f.Profile, f.Summary
+	return s, nil
+}
+
+// Add remembers fact in scope, for project when the scope is ScopeProject.
+// A fact already remembered in the same scope isn't added again; the
+// existing memory is returned instead.
+func (s *Store) Add(fact, scope, project string) (Memory, error) {
+	if s == nil {
+		return Memory{}, errors.New("memory is not available")
+	}
+	fact = strings.TrimSpace(fact)
+	switch {
+	case fact == "":
+		return Memory{}, errors.New("fact is required")
+	case len(fact) > maxFactLength:
+		return Memory{}, fmt.Errorf("fact is longer than %d characters; remember something shorter", maxFactLength)
+	}
+	switch scope {
+	case "", ScopeGlobal:
+		scope, project = ScopeGlobal, ""
+	case ScopeProject:
+		if project == "" {
+			return Memory{}, errors.New("no project to remember this for")
+		}
+	default:
+		return Memory{}, fmt.Errorf("unknown scope %q: use global or project", scope)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.memories {
+		if m.Scope == scope && m.Project == project && strings.EqualFold(m.Fact, fact) {
+			return m, nil
+		}
+	}
+	m := Memory{ID: uuid.NewString(), Fact: fact, Scope: scope, Project: project, Created: time.Now()}
+	s.memories = append(s.memories, m)
+	if err := s.save(); err != nil {
+		s.memories = s.memories[:len(s.memories)-1]
+		return Memory{}, err
+	}
+	return m, nil
+}
+
+// List returns every memory, oldest first.
+func (s *Store) List() []Memory {
+	if s == nil {
+		return []Memory{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Memory{}, s.memories...)
+}
+
+// Delete forgets the memory with id.
+func (s *Store) Delete(id string) error {
+	if s == nil {
+		return errors.New("memory is not available")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.memories {
+		if m.ID == id {
+			s.memories = append(s.memories[:i:i], s.memories[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("memory %s not found", id)
+}
+
+// Recall returns up to limit memories that apply in project, global or for
+// that project, that share the most words with query, best first. An
+// empty query returns the newest.
+func (s *Store) Recall(query, project string, limit int) []Memory {
+	if s == nil {
+		return nil
+	}
+	terms := words(query)
+
+	s.mu.Lock()
+	type scored struct {
+		m     Memory
+		score int
+	}
+	var matches []scored
+	for _, m := range s.memories {
+		if m.Scope == ScopeProject && m.Project != project {
+			continue
+		}
+		score := 0
+		if len(terms) > 0 {
+			fact := words(m.Fact)
+			for term := range terms {
+				if fact[term] {
+					score++
+				}
+			}
+			if score == 0 {
+				continue
+			}
+		}
+		matches = append(matches, scored{m, score})
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].m.Created.After(matches[j].m.Created)
+	})
+	var result []Memory
+	for _, match := range matches {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		result = append(result, match.m)
+	}
+	return result
+}
+
+// save writes the memories, profile, and summary (caller must hold lock).
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	memories := s.memories
+	if memories == nil {
+		memories = []Memory{}
+	}
+	data, err := json.MarshalIndent(file{Profile: s.profile, Summary: s.summary, Memories: memories}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// words returns the lowercase words of text longer than two letters, which
+// leaves out most words that match everything.
+func words(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) > 2 {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+// ProjectOf returns the project dir belongs to: the nearest folder at or
+// above it holding a .git folder or file, or else dir itself.
+func ProjectOf(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	dir = filepath.Clean(dir)
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
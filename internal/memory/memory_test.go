@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memories.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	shell, err := s.Add("The user prefers zsh over bash", "", "")
+	if err != nil || shell.Scope != ScopeGlobal {
+		t.Fatalf("Add() = %+v, %v", shell, err)
+	}
+	deploy, _ := s.Add("Deploy with make deploy", ScopeProject, "/src/app")
+	s.Add("Deploy with ./ship.sh", ScopeProject, "/src/other")
+	if again, _ := s.Add("the user prefers ZSH over bash", ScopeGlobal, ""); again.ID != shell.ID || len(s.List()) != 3 {
+		t.Errorf("duplicate fact added: %+v", s.List())
+	}
+
+	for _, tt := range []struct{ fact, scope, project string }{
+		{"", "", ""},
+		{strings.Repeat("x", maxFactLength+1), "", ""},
+		{"fact", "team", ""},
+		{"fact", ScopeProject, ""},
+	} {
+		if _, err := s.Add(tt.fact, tt.scope, tt.project); err == nil {
+			t.Errorf("Add(%q, %q, %q) should fail", tt.fact, tt.scope, tt.project)
+		}
+	}
+
+	got := s.Recall("how do we deploy?", "/src/app", 5)
+	if len(got) != 1 || got[0].ID != deploy.ID {
+		t.Errorf("Recall() = %+v", got)
+	}
+	if got := s.Recall("", "/src/app", 0); len(got) != 2 {
+		t.Errorf("Recall of everything = %+v", got)
+	}
+
+	// Memories survive a restart and can be forgotten
+	s, _ = Open(path)
+	if err := s.Delete(deploy.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.Delete(deploy.ID); err == nil {
+		t.Error("expected an error deleting a missing memory")
+	}
+	s, _ = Open(path)
+	if list := s.List(); len(list) != 2 || list[0].ID != shell.ID {
+		t.Errorf("List() after deleting = %+v", list)
+	}
+}
+
+func TestProjectOf(t *testing.T) {
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0755)
+	sub := filepath.Join(repo, "cmd", "server")
+	os.MkdirAll(sub, 0755)
+	if got := ProjectOf(sub); got != repo {
+		t.Errorf("ProjectOf(%q) = %q, want %q", sub, got, repo)
+	}
+
+	plain := t.TempDir()
+	if got := ProjectOf(plain); got != plain {
+		t.Errorf("ProjectOf(%q) = %q", plain, got)
+	}
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxDirectories bounds Profile.Directories; the oldest are dropped first.
+const maxDirectories = 10
+
+// Profile fields the remember tool can set.
+const (
+	FieldShell          = "shell"
+	FieldPackageManager = "package_manager"
+	FieldCodeStyle      = "code_style"
+	FieldDirectory      = "directory" // Adds to Profile.Directories
+)
+
+// Profile is what the agent should know about the user in every
+// conversation. It is shown at the end of the system prompt.
+type Profile struct {
+	Shell          string   `json:"shell,omitempty"`           // e.g. "zsh"
+	PackageManager string   `json:"package_manager,omitempty"` // e.g. "pnpm" or "brew"
+	CodeStyle      string   `json:"code_style,omitempty"`      // e.g. "tabs, no semicolons"
+	Directories    []string `json:"directories,omitempty"`     // Folders the user often works in
+}
+
+// Render formats the profile for the system prompt, or returns "" if it is
+// empty.
+func (p Profile) Render() string {
+	var lines []string
+	for _, field := range []struct{ label, value string }{
+		{"Preferred shell", p.Shell},
+		{"Package manager", p.PackageManager},
+		{"Code style", p.CodeStyle},
+		{"Folders they often work in", strings.Join(p.Directories, ", ")},
+	} {
+		if v := strings.TrimSpace(field.value); v != "" {
+			lines = append(lines, "- "+field.label+": "+v)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "ABOUT THE USER:\n" + strings.Join(lines, "\n")
+}
+
+// Profile returns the user's profile.
+func (s *Store) Profile() Profile {
+	if s == nil {
+		return Profile{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.profile
+	p.Directories = slices.Clone(p.Directories)
+	return p
+}
+
+// SetProfile replaces the user's profile, e.g. after editing it in settings.
+func (s *Store) SetProfile(p Profile) error {
+	if s == nil {
+		return errors.New("memory is not available")
+	}
+	p.Shell = strings.TrimSpace(p.Shell)
+	p.PackageManager = strings.TrimSpace(p.PackageManager)
+	p.CodeStyle = strings.TrimSpace(p.CodeStyle)
+	var dirs []string
+	for _, d := range p.Directories {
+		if d = strings.TrimSpace(d); d != "" && !slices.Contains(dirs, d) {
+			dirs = append(dirs, d)
+		}
+	}
+	if len(dirs) > maxDirectories {
+		dirs = dirs[len(dirs)-maxDirectories:]
+	}
+	p.Directories = dirs
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profile = p
+	return s.save()
+}
+
+// SetProfileField sets one field of the profile to value, or for
+// FieldDirectory adds value to the folders the user often works in.
+func (s *Store) SetProfileField(field, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return errors.New("a value is required to update the profile")
+	}
+	p := s.Profile()
+	switch field {
+	case FieldShell:
+		p.Shell = value
+	case FieldPackageManager:
+		p.PackageManager = value
+	case FieldCodeStyle:
+		p.CodeStyle = value
+	case FieldDirectory:
+		p.Directories = append(slices.DeleteFunc(p.Directories, func(d string) bool { return d == value }), value)
+	default:
+		return fmt.Errorf("unknown profile field %q", field)
+	}
+	return s.SetProfile(p)
+}
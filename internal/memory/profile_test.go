@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memories.json")
+	s, _ := Open(path)
+	if s.Profile().Render() != "" {
+		t.Errorf("new profile renders %q", s.Profile().Render())
+	}
+
+	for _, set := range [][2]string{
+		{FieldShell, "zsh"},
+		{FieldShell, "fish"},
+		{FieldCodeStyle, "tabs, no semicolons"},
+		{FieldDirectory, "~/src/website"},
+		{FieldDirectory, "~/Documents"},
+		{FieldDirectory, "~/src/website"},
+	} {
+		if err := s.SetProfileField(set[0], set[1]); err != nil {
+			t.Fatalf("SetProfileField(%q, %q) failed: %v", set[0], set[1], err)
+		}
+	}
+	if err := s.SetProfileField(FieldShell, " "); err == nil {
+		t.Error("expected an error for an empty value")
+	}
+	if err := s.SetProfileField("editor", "vim"); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+
+	want := "ABOUT THE USER:\n- Preferred shell: fish\n- Code style: tabs, no semicolons\n- Folders they often work in: ~/Documents, ~/src/website"
+	if got := s.Profile().Render(); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if got := reopened.Profile().Render(); got != want {
+		t.Errorf("reopened Render() = %q", got)
+	}
+
+	var dirs []string
+	for i := 0; i < maxDirectories+3; i++ {
+		dirs = append(dirs, strings.Repeat("d", i+1))
+	}
+	s.SetProfile(Profile{Directories: dirs})
+	if got := s.Profile().Directories; len(got) != maxDirectories || got[0] != "dddd" {
+		t.Errorf("Directories = %v", got)
+	}
+}
+
+func TestOpen_ListFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memories.json")
+	os.WriteFile(path, []byte(`[{"id":"1","fact":"The user's editor is Helix","scope":"global"}]`), 0644)
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if list := s.List(); len(list) != 1 || list[0].Fact != "The user's editor is Helix" {
+		t.Errorf("List() = %+v", list)
+	}
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
+)
+
+const (
+	maxSummaryChars = 2000 // Longer summaries are cut, so they can't crowd the system prompt
+	maxRunChars     = 2000 // Longest task or result shown when updating the summary
+)
+
+// Summary is a rolling account of what the user has been working on
+// lately, updated after each run and shown to new conversations.
+type Summary struct {
+	Text    string    `json:"text,omitempty"`
+	Updated time.Time `json:"updated,omitempty"`
+}
+
+// Client is the LLM the summary is written with.
+type Client interface {
+	ChatCompletion(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error)
+}
+
+// Render formats the summary for the system prompt, or returns "" if it is
+// empty.
+func (s Summary) Render() string {
+	if strings.TrimSpace(s.Text) == "" {
+		return ""
+	}
+	return "WHAT THE USER HAS BEEN WORKING ON LATELY (as of " + s.Updated.Format("Monday, January 2") + "):\n" + s.Text
+}
+
+// Summary returns the summary of recent work.
+func (s *Store) Summary() Summary {
+	if s == nil {
+		return Summary{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.summary
+}
+
+// SetSummary replaces the summary of recent work. An empty text clears it.
+func (s *Store) SetSummary(text string) error {
+	if s == nil {
+		return errors.New("memory is not available")
+	}
+	text = strings.TrimSpace(text)
+	if len(text) > maxSummaryChars {
+		text = text[:maxSummaryChars]
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summary = Summary{Text: text}
+	if text != "" {
+		s.summary.Updated = time.Now()
+	}
+	return s.save()
+}
+
+// UpdateSummary asks client to fold a finished run, the task and how it
+// ended, into the summary of recent work.
+func (s *Store) UpdateSummary(ctx context.Context, client Client, task, result string) error {
+	if s == nil || client == nil {
+		return errors.New("memory is not available")
+	}
+	task, result = strings.TrimSpace(task), strings.TrimSpace(result)
+	if task == "" {
+		return nil
+	}
+	s.summaryMu.Lock()
+	defer s.summaryMu.Unlock()
+
+	current := s.Summary()
+	var b strings.Builder
+	if current.Text != "" {
+		b.WriteString("Current summary (as of " + current.Updated.Format("Monday, January 2") + "):\n" + current.Text + "\n\n")
+	} else {
+		b.WriteString("There is no summary yet.\n\n")
+	}
+	b.WriteString("Just finished (" + time.Now().Format("Monday, January 2") + "):\n")
+	b.WriteString("Task: " + cut(task, maxRunChars) + "\n")
+	if result != "" {
+		b.WriteString("Result: " + cut(result, maxRunChars) + "\n")
+	}
+
+	resp, err := client.ChatCompletion(ctx, []llm.Message{
+		{
+			Role: "system",
+			Content: "You keep a short summary of what the user has been working on lately across conversations, so that a new conversation " +
+				"can pick up ongoing projects without the user explaining them again. Update the summary with the task that just finished. " +
+				"Keep ongoing projects with their state, decisions, and next steps; drop one-off questions and work that is done and no longer relevant. " +
+				"Reply with only the updated summary as at most 8 short bullet points, under 150 words in total.",
+		},
+		{Role: "user", Content: b.String()},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(resp.Content) == "" {
+		return errors.New("the model returned an empty summary")
+	}
+	return s.SetSummary(resp.Content)
+}
+
+// cut shortens text to at most n bytes, marking the cut.
+func cut(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	return text[:n] + "..."
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/tools"
+)
+
+// fakeClient replies with reply and keeps the last prompt.
+type fakeClient struct {
+	reply  string
+	err    error
+	prompt []llm.Message
+}
+
+func (c *fakeClient) ChatCompletion(ctx context.Context, messages []llm.Message, toolDefs []tools.ToolDefinition) (*llm.Response, error) {
+	c.prompt = messages
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &llm.Response{Content: c.reply}, nil
+}
+
+func TestUpdateSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memories.json")
+	s, _ := Open(path)
+	client := &fakeClient{reply: "- Migrating the blog to Hugo; redirects next\n"}
+
+	if err := s.UpdateSummary(context.Background(), client, "Port the theme to Hugo", "Ported the theme"); err != nil {
+		t.Fatalf("UpdateSummary failed: %v", err)
+	}
+	if got := client.prompt[1].Content; !strings.Contains(got, "no summary yet") || !strings.Contains(got, "Task: Port the theme to Hugo") {
+		t.Errorf("first prompt = %q", got)
+	}
+	summary := s.Summary()
+	if summary.Text != "- Migrating the blog to Hugo; redirects next" || summary.Updated.IsZero() {
+		t.Errorf("Summary() = %+v", summary)
+	}
+	if !strings.HasPrefix(summary.Render(), "WHAT THE USER HAS BEEN WORKING ON LATELY") {
+		t.Errorf("Render() = %q", summary.Render())
+	}
+
+	// The next update builds on the current summary
+	client.reply = "- Migrating the blog to Hugo; done"
+	s.UpdateSummary(context.Background(), client, "Add the redirects", "")
+	if got := client.prompt[1].Content; !strings.Contains(got, "redirects next") || strings.Contains(got, "Result:") {
+		t.Errorf("second prompt = %q", got)
+	}
+
+	reopened, _ := Open(path)
+	if reopened.Summary().Text != "- Migrating the blog to Hugo; done" {
+		t.Errorf("reopened summary = %+v", reopened.Summary())
+	}
+
+	// A failed or empty reply keeps the summary
+	for _, c := range []*fakeClient{{err: errors.New("offline")}, {reply: "  "}} {
+		if err := s.UpdateSummary(context.Background(), c, "Something else", ""); err == nil {
+			t.Error("expected an error")
+		}
+	}
+	if s.Summary().Text != "- Migrating the blog to Hugo; done" {
+		t.Errorf("summary after failures = %+v", s.Summary())
+	}
+
+	if err := s.SetSummary(""); err != nil || s.Summary().Render() != "" || !s.Summary().Updated.IsZero() {
+		t.Errorf("cleared summary = %+v, %v", s.Summary(), err)
+	}
+}
@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+
+	"agent-desktop/internal/tools"
+)
+
+// Source is the tools package source the memory tools are registered under.
+const Source = "memory"
+
+const (
+	defaultRecall = 10
+	maxRecall     = 50
+)
+
+// Register offers the agent remember and recall over s, replacing the
+// store offered before. A nil s removes the tools. Tools named like
+// another source's are left out and reported.
+func Register(s *Store) error {
+	if s == nil {
+		return tools.SetExternalTools(Source, nil)
+	}
+	return tools.SetExternalTools(Source, s.Tools())
+}
+
+// Tools returns the remember and recall tools. The project is the one the
+// shell session is working in when the tool is called.
+func (s *Store) Tools() []tools.ExternalTool {
+	return []tools.ExternalTool{
+		{
+			Definition: definition("remember",
+				"Remember a fact for future conversations, such as a preference the user stated or a detail about their setup. "+
+					"Keep it to one short, self-contained sentence. Don't remember secrets such as passwords or API keys. "+
+					"When the fact is the user's preferred shell, package manager, or code style, or a folder they often work in, "+
+					"also set profile and value so every conversation starts with it.",
+				map[string]interface{}{
+					"fact": map[string]interface{}{"type": "string", "description": "The fact, e.g. 'The user deploys with make deploy'"},
+					"scope": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{ScopeGlobal, ScopeProject},
+						"description": "global for every conversation, project for conversations working in the current project. Default is global.",
+					},
+					"profile": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{FieldShell, FieldPackageManager, FieldCodeStyle, FieldDirectory},
+						"description": "The profile field the fact sets, if any",
+					},
+					"value": map[string]interface{}{"type": "string", "description": "The value for the profile field, e.g. 'zsh', 'pnpm', or '~/src/website'"},
+				}, "fact"),
+			Execute: s.rememberTool,
+		},
+		{
+			Definition: definition("recall",
+				"Look up facts remembered in earlier conversations, global ones and those for the current project.",
+				map[string]interface{}{
+					"query": map[string]interface{}{"type": "string", "description": "Words to look for. Empty returns the newest memories."},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Most memories to return, up to %d. Default is %d.", maxRecall, defaultRecall),
+						"default":     defaultRecall,
+					},
+				}),
+			Execute: s.recallTool,
+		},
+	}
+}
+
+func definition(name, description string, properties map[string]interface{}, required ...string) tools.ToolDefinition {
+	if required == nil {
+		required = []string{}
+	}
+	return tools.ToolDefinition{
+		Type: "function",
+		Function: tools.ToolFunction{
+			Name:        name,
+			Description: description,
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": properties,
+				"required":   required,
+			},
+		},
+	}
+}
+
+// currentProject returns the project the shell session is working in.
+func currentProject() string {
+	return ProjectOf(tools.GetSession().GetCWD())
+}
+
+// rememberTool runs remember.
+func (s *Store) rememberTool(args map[string]interface{}) tools.ToolResult {
+	fact, _ := args["fact"].(string)
+	scope, _ := args["scope"].(string)
+	project := ""
+	if scope == ScopeProject {
+		project = currentProject()
+	}
+	m, err := s.Add(fact, scope, project)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: err.Error()}
+	}
+	if field, _ := args["profile"].(string); field != "" {
+		value, _ := args["value"].(string)
+		if err := s.SetProfileField(field, value); err != nil {
+			return tools.ToolResult{Success: false, Error: "remembered the fact but couldn't update the profile: " + err.Error()}
+		}
+	}
+	if m.Scope == ScopeProject {
+		return tools.ToolResult{Success: true, Output: "Remembered for " + m.Project + ": " + m.Fact}
+	}
+	return tools.ToolResult{Success: true, Output: "Remembered: " + m.Fact}
+}
+
+// recallTool runs recall.
+func (s *Store) recallTool(args map[string]interface{}) tools.ToolResult {
+	query, _ := args["query"].(string)
+	limit := defaultRecall
+	if v, ok := args["limit"].(float64); ok {
+		limit = int(v)
+	}
+	if limit < 1 || limit > maxRecall {
+		return tools.ToolResult{Success: false, Error: fmt.Sprintf("limit must be between 1 and %d", maxRecall)}
+	}
+
+	found := s.Recall(query, currentProject(), limit)
+	if len(found) == 0 {
+		return tools.ToolResult{Success: true, Output: "Nothing remembered about that."}
+	}
+	var b strings.Builder
+	for _, m := range found {
+		fmt.Fprintf(&b, "- %s (%s, %s)\n", m.Fact, m.Scope, m.Created.Format("2006-01-02"))
+	}
+	return tools.ToolResult{Success: true, Output: b.String()}
+}
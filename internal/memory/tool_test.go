@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent-desktop/internal/tools"
+)
+
+func TestTools(t *testing.T) {
+	defer Register(nil)
+	defer tools.ResetSession()
+
+	project := t.TempDir()
+	tools.GetSession().SetCWD(project)
+	s, _ := Open(filepath.Join(t.TempDir(), "memories.json"))
+	Register(s)
+
+	result := tools.ExecuteTool("remember", map[string]interface{}{"fact": "Tests run with go test ./...", "scope": "project"})
+	if !result.Success || !strings.Contains(result.Output, project) {
+		t.Errorf("remember = %+v", result)
+	}
+	if list := s.List(); len(list) != 1 || list[0].Project != project {
+		t.Errorf("memories = %+v", list)
+	}
+
+	result = tools.ExecuteTool("recall", map[string]interface{}{"query": "how are tests run"})
+	if !result.Success || !strings.Contains(result.Output, "go test") {
+		t.Errorf("recall = %+v", result)
+	}
+	tools.GetSession().SetCWD(t.TempDir())
+	result = tools.ExecuteTool("recall", map[string]interface{}{"query": "how are tests run"})
+	if !result.Success || strings.Contains(result.Output, "go test") {
+		t.Errorf("recall in another project = %+v", result)
+	}
+}
+
+func TestTools_Profile(t *testing.T) {
+	defer Register(nil)
+	s, _ := Open(filepath.Join(t.TempDir(), "memories.json"))
+	Register(s)
+
+	result := tools.ExecuteTool("remember", map[string]interface{}{"fact": "The user installs packages with pnpm", "profile": "package_manager", "value": "pnpm"})
+	if !result.Success || s.Profile().PackageManager != "pnpm" {
+		t.Errorf("remember with a profile field = %+v, profile %+v", result, s.Profile())
+	}
+	result = tools.ExecuteTool("remember", map[string]interface{}{"fact": "The user likes hats", "profile": "hat"})
+	if result.Success {
+		t.Errorf("remember with an unknown profile field = %+v", result)
+	}
+}
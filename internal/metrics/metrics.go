@@ -0,0 +1,365 @@
+// Package metrics keeps usage totals for the usage dashboard: tokens, cost,
+// model calls, runs, and tool calls, per day and model, and how long each
+// tool takes. Totals are kept in memory and saved to a small JSON file when
+// a run ends.
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// retentionDays is how many days of totals are kept.
+const retentionDays = 400
+
+// dayLayout formats the days totals are kept for, in local time.
+const dayLayout = "2006-01-02"
+
+// Outcome is how a run ended.
+type Outcome int
+
+const (
+	RunSucceeded Outcome = iota
+	RunFailed
+	RunCancelled
+)
+
+// Counts are usage totals.
+type Counts struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"` // Estimated, in US dollars
+	LLMCalls         int     `json:"llm_calls"`
+	Runs             int     `json:"runs"`
+	SucceededRuns    int     `json:"succeeded_runs"`
+	FailedRuns       int     `json:"failed_runs"`
+	CancelledRuns    int     `json:"cancelled_runs"`
+	ToolCalls        int     `json:"tool_calls"`
+	ToolErrors       int     `json:"tool_errors"`
+	RunTimeMs        int64   `json:"run_time_ms"`
+}
+
+// SuccessRate returns the fraction of finished runs that succeeded, not
+// counting cancelled runs, or 0 if there were none.
+func (c Counts) SuccessRate() float64 {
+	if finished := c.SucceededRuns + c.FailedRuns; finished > 0 {
+		return float64(c.SucceededRuns) / float64(finished)
+	}
+	return 0
+}
+
+func (c *Counts) add(o Counts) {
+	c.PromptTokens += o.PromptTokens
+	c.CompletionTokens += o.CompletionTokens
+	c.TotalTokens += o.TotalTokens
+	c.Cost += o.Cost
+	c.LLMCalls += o.LLMCalls
+	c.Runs += o.Runs
+	c.SucceededRuns += o.SucceededRuns
+	c.FailedRuns += o.FailedRuns
+	c.CancelledRuns += o.CancelledRuns
+	c.ToolCalls += o.ToolCalls
+	c.ToolErrors += o.ToolErrors
+	c.RunTimeMs += o.RunTimeMs
+}
+
+// Summary is usage totals with the success rate worked out.
+type Summary struct {
+	Counts
+	SuccessRate float64 `json:"success_rate"`
+}
+
+func summarize(c Counts) Summary {
+	return Summary{Counts: c, SuccessRate: c.SuccessRate()}
+}
+
+// DayStats is a day's usage.
+type DayStats struct {
+	Date string `json:"date"` // YYYY-MM-DD, local time
+	Summary
+}
+
+// ModelStats is a model's usage.
+type ModelStats struct {
+	Model string `json:"model"`
+	Summary
+}
+
+// Stats is the usage over a range of days.
+type Stats struct {
+	From   string       `json:"from"` // First day, or empty for all time
+	To     string       `json:"to"`   // Last day
+	Total  Summary      `json:"total"`
+	Days   []DayStats   `json:"days"`   // Each day in the range, oldest first
+	Models []ModelStats `json:"models"` // Models used in the range, most expensive first
+}
+
+// bucket is the usage of one model on one day.
+type bucket struct {
+	Day   string `json:"day"`
+	Model string `json:"model"`
+	Counts
+}
+
+// file is the saved form of a Store.
+type file struct {
+	Buckets []bucket               `json:"buckets"`
+	Tools   map[string]*toolRecord `json:"tools,omitempty"`
+}
+
+// Store keeps usage totals. A nil Store records nothing.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	buckets map[[2]string]*bucket // By day and model
+	tools   map[string]*toolRecord
+}
+
+// now returns the current time. It is a variable so tests can fix it.
+var now = time.Now
+
+// Open loads the totals saved at path. The store is usable even when the
+// file can't be read, in which case it starts empty and the error says why.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, buckets: map[[2]string]*bucket{}, tools: map[string]*toolRecord{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	var f file
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		// Files from before tool stats were kept hold only the buckets
+		err = json.Unmarshal(data, &f.Buckets)
+	} else {
+		err = json.Unmarshal(data, &f)
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read usage metrics: %w", err)
+	}
+	for i := range f.Buckets {
+		b := f.Buckets[i]
+		s.buckets[[2]string{b.Day, b.Model}] = &b
+	}
+	for tool, r := range f.Tools {
+		if r != nil {
+			s.tools[tool] = r
+		}
+	}
+	return s, nil
+}
+
+// at returns the bucket for model today (caller must hold lock).
+func (s *Store) at(model string) *bucket {
+	key := [2]string{now().Format(dayLayout), model}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{Day: key[0], Model: model}
+		s.buckets[key] = b
+	}
+	return b
+}
+
+// RecordUsage counts one model call and its tokens and cost.
+func (s *Store) RecordUsage(model string, promptTokens, completionTokens int, cost float64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.at(model).add(Counts{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+		Cost:             cost,
+		LLMCalls:         1,
+	})
+}
+
+// RecordToolCall counts one call of tool that took d, noting whether it
+// failed.
+func (s *Store) RecordToolCall(model, tool string, failed bool, d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.at(model)
+	b.ToolCalls++
+	if failed {
+		b.ToolErrors++
+	}
+	s.recordTool(tool, failed, d)
+}
+
+// RecordRun counts a run that ended and saves the totals.
+func (s *Store) RecordRun(model string, outcome Outcome, d time.Duration) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.at(model)
+	b.Runs++
+	b.RunTimeMs += d.Milliseconds()
+	switch outcome {
+	case RunSucceeded:
+		b.SucceededRuns++
+	case RunFailed:
+		b.FailedRuns++
+	case RunCancelled:
+		b.CancelledRuns++
+	}
+	return s.save()
+}
+
+// save prunes expired days and writes the totals (caller must hold lock).
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	cutoff := now().AddDate(0, 0, -retentionDays).Format(dayLayout)
+	list := make([]bucket, 0, len(s.buckets))
+	for key, b := range s.buckets {
+		if b.Day < cutoff {
+			delete(s.buckets, key)
+			continue
+		}
+		list = append(list, *b)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Day != list[j].Day {
+			return list[i].Day < list[j].Day
+		}
+		return list[i].Model < list[j].Model
+	})
+
+	data, err := json.MarshalIndent(file{Buckets: list, Tools: s.tools}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Stats returns the usage over the last days days, today included, with
+// every day in the range listed. days <= 0 returns all the usage kept,
+// listing only days with any.
+func (s *Store) Stats(days int) Stats {
+	today := now()
+	st := Stats{To: today.Format(dayLayout), Days: []DayStats{}, Models: []ModelStats{}}
+	if days > 0 {
+		st.From = today.AddDate(0, 0, 1-days).Format(dayLayout)
+	}
+	if s == nil {
+		return st
+	}
+
+	s.mu.Lock()
+	byDay := map[string]*Counts{}
+	byModel := map[string]*Counts{}
+	var total Counts
+	for _, b := range s.buckets {
+		if b.Day < st.From || b.Day > st.To {
+			continue
+		}
+		total.add(b.Counts)
+		addTo(byDay, b.Day, b.Counts)
+		addTo(byModel, b.Model, b.Counts)
+	}
+	s.mu.Unlock()
+
+	st.Total = summarize(total)
+	if days > 0 {
+		for i := days - 1; i >= 0; i-- {
+			day := today.AddDate(0, 0, -i).Format(dayLayout)
+			c := Counts{}
+			if byDay[day] != nil {
+				c = *byDay[day]
+			}
+			st.Days = append(st.Days, DayStats{Date: day, Summary: summarize(c)})
+		}
+	} else {
+		for day, c := range byDay {
+			st.Days = append(st.Days, DayStats{Date: day, Summary: summarize(*c)})
+		}
+		sort.Slice(st.Days, func(i, j int) bool { return st.Days[i].Date < st.Days[j].Date })
+		if len(st.Days) > 0 {
+			st.From = st.Days[0].Date
+		}
+	}
+	for model, c := range byModel {
+		st.Models = append(st.Models, ModelStats{Model: model, Summary: summarize(*c)})
+	}
+	sort.Slice(st.Models, func(i, j int) bool {
+		if st.Models[i].Cost != st.Models[j].Cost {
+			return st.Models[i].Cost > st.Models[j].Cost
+		}
+		return st.Models[i].Model < st.Models[j].Model
+	})
+	return st
+}
+
+// Spend returns the estimated cost of today's usage and of this calendar
+// month's, across all models.
+func (s *Store) Spend() (day, month float64) {
+	if s == nil {
+		return 0, 0
+	}
+	today := now().Format(dayLayout)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.buckets {
+		if b.Day[:7] != today[:7] {
+			continue
+		}
+		month += b.Cost
+		if b.Day == today {
+			day += b.Cost
+		}
+	}
+	return day, month
+}
+
+// addTo adds c to the totals under key.
+func addTo(totals map[string]*Counts, key string, c Counts) {
+	if totals[key] == nil {
+		totals[key] = &Counts{}
+	}
+	totals[key].add(c)
+}
+
+// ParseRange converts a dashboard range to a number of days for Stats:
+// "today", "all", or a count of days such as "7d" or "30d". An empty range
+// is the last 30 days.
+func ParseRange(r string) (int, error) {
+	switch r {
+	case "":
+		return 30, nil
+	case "today":
+		return 1, nil
+	case "all":
+		return 0, nil
+	}
+	var days int
+	if n, err := fmt.Sscanf(r, "%dd", &days); err != nil || n != 1 || fmt.Sprintf("%dd", days) != r || days < 1 || days > retentionDays {
+		return 0, fmt.Errorf("unknown usage range %q: use today, all, or a number of days like 7d", r)
+	}
+	return days, nil
+}
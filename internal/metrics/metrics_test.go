@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fixNow sets the current time for the rest of the test.
+func fixNow(t *testing.T, at time.Time) {
+	t.Helper()
+	orig := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = orig })
+}
+
+func TestStore(t *testing.T) {
+	day1 := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	fixNow(t, day1)
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s.RecordUsage("gpt-4o", 1000, 200, 0.5)
+	s.RecordUsage("gpt-4o", 500, 100, 0.25)
+	s.RecordToolCall("gpt-4o", "read_file", false, 10*time.Millisecond)
+	s.RecordToolCall("gpt-4o", "run_command", true, 8*time.Second)
+	if err := s.RecordRun("gpt-4o", RunSucceeded, 3*time.Second); err != nil {
+		t.Fatalf("RecordRun failed: %v", err)
+	}
+
+	fixNow(t, day1.AddDate(0, 0, 2))
+	s.RecordUsage("local", 300, 30, 0)
+	s.RecordRun("local", RunFailed, time.Second)
+	s.RecordRun("local", RunCancelled, time.Second)
+
+	// Totals survive reopening
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	st := s.Stats(7)
+	if st.From != "2025-03-06" || st.To != "2025-03-12" || len(st.Days) != 7 {
+		t.Fatalf("range = %s to %s with %d days", st.From, st.To, len(st.Days))
+	}
+	total := st.Total
+	if total.TotalTokens != 2130 || total.LLMCalls != 3 || total.Cost != 0.75 || total.Runs != 3 || total.ToolErrors != 1 || total.RunTimeMs != 5000 {
+		t.Errorf("total = %+v", total)
+	}
+	if total.SuccessRate != 0.5 {
+		t.Errorf("success rate = %v, want 0.5 with the cancelled run left out", total.SuccessRate)
+	}
+	if d := st.Days[4]; d.Date != "2025-03-10" || d.PromptTokens != 1500 || d.SuccessRate != 1 {
+		t.Errorf("first day = %+v", d)
+	}
+	if d := st.Days[5]; d.Date != "2025-03-11" || d.Runs != 0 {
+		t.Errorf("empty day = %+v", d)
+	}
+	if len(st.Models) != 2 || st.Models[0].Model != "gpt-4o" || st.Models[1].CancelledRuns != 1 {
+		t.Errorf("models = %+v", st.Models)
+	}
+
+	if today := s.Stats(1); today.Total.Runs != 2 || len(today.Days) != 1 {
+		t.Errorf("today = %+v", today)
+	}
+	if all := s.Stats(0); all.From != "2025-03-10" || len(all.Days) != 2 {
+		t.Errorf("all time = %+v", all)
+	}
+}
+
+func TestStore_Prunes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	fixNow(t, start)
+	s, _ := Open(filepath.Join(t.TempDir(), "metrics.json"))
+	s.RecordRun("gpt-4o", RunSucceeded, 0)
+
+	fixNow(t, start.AddDate(0, 0, retentionDays+1))
+	s.RecordRun("gpt-4o", RunSucceeded, 0)
+	if all := s.Stats(0); len(all.Days) != 1 || all.Total.Runs != 1 {
+		t.Errorf("expected the old day to be pruned: %+v", all)
+	}
+}
+
+func TestStore_Spend(t *testing.T) {
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local)
+	fixNow(t, day.AddDate(0, 0, -1))
+	s, _ := Open("")
+	s.RecordUsage("gpt-4o", 1000, 100, 4)
+
+	fixNow(t, day)
+	s.RecordUsage("gpt-4o", 1000, 100, 1)
+	s.RecordUsage("claude", 1000, 100, 0.5)
+	if d, m := s.Spend(); d != 1.5 || m != 1.5 {
+		t.Errorf("Spend() on the 1st = %v, %v", d, m)
+	}
+
+	fixNow(t, day.AddDate(0, 0, 1))
+	s.RecordUsage("gpt-4o", 1000, 100, 2)
+	if d, m := s.Spend(); d != 2 || m != 3.5 {
+		t.Errorf("Spend() on the 2nd = %v, %v", d, m)
+	}
+}
+
+func TestStore_Nil(t *testing.T) {
+	var s *Store
+	s.RecordUsage("gpt-4o", 1, 1, 0)
+	if err := s.RecordRun("gpt-4o", RunSucceeded, 0); err != nil {
+		t.Errorf("RecordRun on nil store = %v", err)
+	}
+	if st := s.Stats(7); st.Total.Runs != 0 {
+		t.Errorf("Stats on nil store = %+v", st)
+	}
+	if d, m := s.Spend(); d != 0 || m != 0 {
+		t.Errorf("Spend on nil store = %v, %v", d, m)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := map[string]int{"": 30, "today": 1, "all": 0, "7d": 7, "90d": 90}
+	for r, want := range tests {
+		if got, err := ParseRange(r); err != nil || got != want {
+			t.Errorf("ParseRange(%q) = %d, %v, want %d", r, got, err, want)
+		}
+	}
+	for _, r := range []string{"week", "0d", "7", "7dd", "-1d", "1000d"} {
+		if _, err := ParseRange(r); err == nil {
+			t.Errorf("ParseRange(%q) should fail", r)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// toolSamples is how many recent durations are kept per tool to work out
+// percentiles.
+const toolSamples = 500
+
+// toolRecord is a tool's call counts and recent durations.
+type toolRecord struct {
+	Calls     int     `json:"calls"`
+	Failures  int     `json:"failures"`
+	Durations []int64 `json:"durations_ms"` // Most recent last
+}
+
+// ToolStats is how often a tool ran, how long it took, and how often it
+// failed.
+type ToolStats struct {
+	Tool        string  `json:"tool"`
+	Calls       int     `json:"calls"`
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failure_rate"`
+	P50Ms       int64   `json:"p50_ms"` // Over the last calls, up to 500
+	P95Ms       int64   `json:"p95_ms"`
+	MaxMs       int64   `json:"max_ms"`
+}
+
+// recordTool counts a call of tool that took d (caller must hold lock).
+func (s *Store) recordTool(tool string, failed bool, d time.Duration) {
+	r := s.tools[tool]
+	if r == nil {
+		r = &toolRecord{}
+		s.tools[tool] = r
+	}
+	r.Calls++
+	if failed {
+		r.Failures++
+	}
+	r.Durations = append(r.Durations, d.Milliseconds())
+	if len(r.Durations) > toolSamples {
+		r.Durations = r.Durations[len(r.Durations)-toolSamples:]
+	}
+}
+
+// ToolStats returns the stats of every tool that has run, slowest first by
+// 95th percentile duration.
+func (s *Store) ToolStats() []ToolStats {
+	list := []ToolStats{}
+	if s == nil {
+		return list
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for tool, r := range s.tools {
+		st := ToolStats{Tool: tool, Calls: r.Calls, Failures: r.Failures}
+		if r.Calls > 0 {
+			st.FailureRate = float64(r.Failures) / float64(r.Calls)
+		}
+		if len(r.Durations) > 0 {
+			sorted := append([]int64(nil), r.Durations...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			st.P50Ms = percentile(sorted, 50)
+			st.P95Ms = percentile(sorted, 95)
+			st.MaxMs = sorted[len(sorted)-1]
+		}
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].P95Ms != list[j].P95Ms {
+			return list[i].P95Ms > list[j].P95Ms
+		}
+		return list[i].Tool < list[j].Tool
+	})
+	return list
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values.
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_ToolStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	s, _ := Open(path)
+	for i := 1; i <= 20; i++ {
+		s.RecordToolCall("gpt-4o", "run_command", i == 20, time.Duration(i)*time.Second)
+	}
+	s.RecordToolCall("gpt-4o", "read_file", false, 5*time.Millisecond)
+	if err := s.RecordRun("gpt-4o", RunSucceeded, time.Minute); err != nil {
+		t.Fatalf("RecordRun failed: %v", err)
+	}
+
+	// Stats survive reopening
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	stats := s.ToolStats()
+	if len(stats) != 2 || stats[0].Tool != "run_command" {
+		t.Fatalf("stats = %+v, want run_command first as the slowest", stats)
+	}
+	run := stats[0]
+	if run.Calls != 20 || run.Failures != 1 || run.FailureRate != 0.05 {
+		t.Errorf("run_command counts = %+v", run)
+	}
+	if run.P50Ms != 10000 || run.P95Ms != 19000 || run.MaxMs != 20000 {
+		t.Errorf("run_command durations = %+v", run)
+	}
+	if read := stats[1]; read.P50Ms != 5 || read.P95Ms != 5 || read.FailureRate != 0 {
+		t.Errorf("read_file = %+v", read)
+	}
+}
+
+func TestStore_ToolStatsKeepRecentSamples(t *testing.T) {
+	s, _ := Open("")
+	for i := 0; i < toolSamples; i++ {
+		s.RecordToolCall("gpt-4o", "run_command", false, time.Minute)
+	}
+	for i := 0; i < toolSamples; i++ {
+		s.RecordToolCall("gpt-4o", "run_command", false, time.Second)
+	}
+	st := s.ToolStats()[0]
+	if st.Calls != 2*toolSamples || st.P95Ms != 1000 {
+		t.Errorf("stats = %+v, want all calls counted but only recent durations used", st)
+	}
+}
+
+func TestOpen_ReadsBucketList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	old := `[{"day": "2025-03-10", "model": "gpt-4o", "runs": 2, "succeeded_runs": 2}]`
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fixNow(t, time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local))
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if st := s.Stats(1); st.Total.Runs != 2 {
+		t.Errorf("total = %+v, want the saved runs", st.Total)
+	}
+	if len(s.ToolStats()) != 0 {
+		t.Error("expected no tool stats")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for _, tt := range []struct {
+		p    int
+		want int64
+	}{{50, 5}, {95, 10}, {90, 9}, {0, 1}, {100, 10}} {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%d) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile([]int64{7}, 95); got != 7 {
+		t.Errorf("percentile of one value = %d", got)
+	}
+}
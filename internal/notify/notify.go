@@ -0,0 +1,52 @@
+// Package notify shows native desktop notifications using the tools each
+// operating system ships with: notify-send on Linux, osascript on macOS,
+// and a PowerShell toast on Windows.
+package notify
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// AppName is shown as the source of notifications where supported.
+const AppName = "Agent Desktop"
+
+// ErrUnsupported is returned when notifications can't be shown on this
+// system.
+var ErrUnsupported = errors.New("desktop notifications are not supported on this system")
+
+// Notification is a message shown outside the app window.
+type Notification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// maxBodyLength keeps notification bodies to a glanceable size.
+const maxBodyLength = 200
+
+// runCommand starts a notification helper. It is a variable so tests can
+// capture the command instead of running it.
+var runCommand = func(cmd *exec.Cmd) error {
+	return cmd.Run()
+}
+
+// Send shows a notification.
+func Send(n Notification) error {
+	n.Body = shorten(n.Body)
+	cmd, err := command(n)
+	if err != nil {
+		return err
+	}
+	return runCommand(cmd)
+}
+
+// shorten trims a body to maxBodyLength runes, collapsing whitespace.
+func shorten(body string) string {
+	body = strings.Join(strings.Fields(body), " ")
+	runes := []rune(body)
+	if len(runes) <= maxBodyLength {
+		return body
+	}
+	return string(runes[:maxBodyLength-1]) + "…"
+}
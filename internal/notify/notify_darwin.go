@@ -0,0 +1,13 @@
+package notify
+
+import "os/exec"
+
+// command builds an osascript invocation. The title and body are passed as
+// script arguments so they need no escaping.
+func command(n Notification) (*exec.Cmd, error) {
+	return exec.Command("osascript",
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		n.Title, n.Body), nil
+}
@@ -0,0 +1,12 @@
+package notify
+
+import "os/exec"
+
+// command builds a notify-send invocation.
+func command(n Notification) (*exec.Cmd, error) {
+	path, err := exec.LookPath("notify-send")
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	return exec.Command(path, "--app-name="+AppName, n.Title, n.Body), nil
+}
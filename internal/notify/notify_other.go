@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package notify
+
+import "os/exec"
+
+// command reports that notifications are unsupported.
+func command(n Notification) (*exec.Cmd, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestShorten(t *testing.T) {
+	if got := shorten("  Task\n\tdone  "); got != "Task done" {
+		t.Errorf("shorten() = %q, want whitespace collapsed", got)
+	}
+
+	long := strings.Repeat("é", maxBodyLength+10)
+	got := []rune(shorten(long))
+	if len(got) != maxBodyLength || got[len(got)-1] != '…' {
+		t.Errorf("shorten() returned %d runes ending in %q", len(got), got[len(got)-1])
+	}
+}
+
+func TestSend_RunsPlatformCommand(t *testing.T) {
+	var ran *exec.Cmd
+	original := runCommand
+	runCommand = func(cmd *exec.Cmd) error {
+		ran = cmd
+		return nil
+	}
+	defer func() { runCommand = original }()
+
+	err := Send(Notification{Title: "Task complete", Body: "Listed 3 files"})
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip("no notification helper on this system")
+	}
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if ran == nil {
+		t.Fatal("expected a notification command to run")
+	}
+	args := strings.Join(ran.Args, " ") + " " + strings.Join(ran.Env, " ")
+	if !strings.Contains(args, "Task complete") || !strings.Contains(args, "Listed 3 files") {
+		t.Errorf("command does not carry the notification: %v", ran.Args)
+	}
+}
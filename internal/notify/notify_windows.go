@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// toastScript shows a toast notification with the title and body read from
+// the environment, so they need no escaping.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:AGENT_DESKTOP_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:AGENT_DESKTOP_NOTIFY_BODY)) > $null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($env:AGENT_DESKTOP_NOTIFY_APP).Show($toast)
+`
+
+// command builds a hidden PowerShell invocation that shows a toast.
+func command(n Notification) (*exec.Cmd, error) {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(),
+		"AGENT_DESKTOP_NOTIFY_TITLE="+n.Title,
+		"AGENT_DESKTOP_NOTIFY_BODY="+n.Body,
+		"AGENT_DESKTOP_NOTIFY_APP="+AppName,
+	)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return cmd, nil
+}
@@ -0,0 +1,196 @@
+package personas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxImportBytes bounds the size of a file Import reads.
+const maxImportBytes = 4 << 20
+
+// definition is an agent definition from another tool. It covers:
+//   - OpenAI assistants as returned by the Assistants API (name,
+//     description, model, instructions), alone, in a list, or in a
+//     {"data": [...]} list response
+//   - Custom GPT configurations (name, description, instructions,
+//     conversation_starters), including the {"gizmo": {...}} form
+//   - YAML or JSON persona files (name, description, prompt or
+//     system_prompt, model, templates or starters), one or more per file
+type definition struct {
+	Name                 string   `json:"name"`
+	Title                string   `json:"title"`
+	Description          string   `json:"description"`
+	Instructions         string   `json:"instructions"`
+	Prompt               string   `json:"prompt"`
+	SystemPrompt         string   `json:"system_prompt"`
+	Model                string   `json:"model"`
+	Templates            []string `json:"templates"`
+	Starters             []string `json:"starters"`
+	ConversationStarters []string `json:"conversation_starters"`
+	PromptStarters       []string `json:"prompt_starters"`
+	Gizmo                *struct {
+		Instructions string `json:"instructions"`
+		Display      struct {
+			Name           string   `json:"name"`
+			Description    string   `json:"description"`
+			PromptStarters []string `json:"prompt_starters"`
+		} `json:"display"`
+	} `json:"gizmo"`
+}
+
+// persona converts the definition.
+func (d definition) persona() Persona {
+	p := Persona{
+		Name:        first(d.Name, d.Title),
+		Description: d.Description,
+		Prompt:      first(d.Prompt, d.SystemPrompt, d.Instructions),
+		Model:       d.Model,
+	}
+	for _, list := range [][]string{d.Templates, d.Starters, d.ConversationStarters, d.PromptStarters} {
+		p.Templates = append(p.Templates, list...)
+	}
+	if g := d.Gizmo; g != nil {
+		p.Name = first(p.Name, g.Display.Name)
+		p.Description = first(p.Description, g.Display.Description)
+		p.Prompt = first(p.Prompt, g.Instructions)
+		p.Templates = append(p.Templates, g.Display.PromptStarters...)
+	}
+
+	p.Name = strings.TrimSpace(p.Name)
+	p.Description = strings.TrimSpace(p.Description)
+	p.Prompt = strings.TrimSpace(p.Prompt)
+	templates := p.Templates[:0]
+	for _, t := range p.Templates {
+		if t = strings.TrimSpace(t); t != "" {
+			templates = append(templates, t)
+		}
+	}
+	p.Templates = templates
+	if len(p.Templates) == 0 {
+		p.Templates = nil
+	}
+	return p
+}
+
+// ImportFile reads the agent definitions in a file; see Import.
+func ImportFile(path string) ([]Persona, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxImportBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportBytes {
+		return nil, fmt.Errorf("%s is larger than %d MB", filepath.Base(path), maxImportBytes>>20)
+	}
+	return Import(path, data)
+}
+
+// Import converts the agent definitions in data, read from the file at
+// path, into personas. JSON and YAML are both accepted whatever the file's
+// extension. A definition without a name is named after the file.
+// Definitions without instructions are an error, since they'd make empty
+// personas.
+func Import(path string, data []byte) ([]Persona, error) {
+	docs, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	var items []interface{}
+	for _, doc := range docs {
+		switch v := doc.(type) {
+		case []interface{}:
+			items = append(items, v...)
+		case map[string]interface{}:
+			if list, ok := v["data"].([]interface{}); ok {
+				items = append(items, list...)
+			} else {
+				items = append(items, v)
+			}
+		case nil:
+		default:
+			return nil, fmt.Errorf("%s: not an agent definition", filepath.Base(path))
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s: no agent definitions found", filepath.Base(path))
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var list []Persona
+	for i, item := range items {
+		// Round-trip through JSON so every format maps onto definition
+		raw, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		var d definition
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return nil, fmt.Errorf("%s: definition %d: %w", filepath.Base(path), i+1, err)
+		}
+
+		p := d.persona()
+		if p.Name == "" {
+			p.Name = base
+			if len(items) > 1 {
+				p.Name = fmt.Sprintf("%s %d", base, i+1)
+			}
+		}
+		p.Source = path
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// decode parses data as JSON or, failing that, as one or more YAML
+// documents.
+func decode(data []byte) ([]interface{}, error) {
+	var doc interface{}
+	jsonErr := json.Unmarshal(data, &doc)
+	if jsonErr == nil {
+		return []interface{}{doc}, nil
+	}
+
+	var docs []interface{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+				return nil, jsonErr
+			}
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// first returns the first non-blank value.
+func first(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
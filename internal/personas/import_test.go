@@ -0,0 +1,96 @@
+package personas
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		data string
+		want []Persona
+	}{
+		{
+			name: "assistant",
+			path: "assistant.json",
+			data: `{"id": "asst_abc", "object": "assistant", "name": "Data Helper", "description": "Analyzes CSVs",
+				"model": "gpt-4o", "instructions": "You analyze data.", "tools": [{"type": "code_interpreter"}]}`,
+			want: []Persona{{Name: "Data Helper", Description: "Analyzes CSVs", Prompt: "You analyze data.", Model: "gpt-4o", Source: "assistant.json"}},
+		},
+		{
+			name: "assistant list",
+			path: "assistants.json",
+			data: `{"object": "list", "data": [
+				{"object": "assistant", "name": "One", "instructions": "First."},
+				{"object": "assistant", "name": "Two", "instructions": "Second."}]}`,
+			want: []Persona{
+				{Name: "One", Prompt: "First.", Source: "assistants.json"},
+				{Name: "Two", Prompt: "Second.", Source: "assistants.json"},
+			},
+		},
+		{
+			name: "gpt",
+			path: "gpt.json",
+			data: `{"name": "Trip Planner", "instructions": "Plan trips.", "conversation_starters": ["Plan a weekend in Rome", " "]}`,
+			want: []Persona{{Name: "Trip Planner", Prompt: "Plan trips.", Templates: []string{"Plan a weekend in Rome"}, Source: "gpt.json"}},
+		},
+		{
+			name: "gizmo",
+			path: "gizmo.json",
+			data: `{"gizmo": {"instructions": "Write haiku.", "display": {"name": "Poet", "description": "Haiku only", "prompt_starters": ["A haiku about rain"]}}}`,
+			want: []Persona{{Name: "Poet", Description: "Haiku only", Prompt: "Write haiku.", Templates: []string{"A haiku about rain"}, Source: "gizmo.json"}},
+		},
+		{
+			name: "yaml",
+			path: "reviewer.yaml",
+			data: "system_prompt: |\n  You review Go code.\n  Be brief.\nmodel: qwen2.5-coder\ntemplates:\n  - Review the staged changes\n",
+			want: []Persona{{Name: "reviewer", Prompt: "You review Go code.\nBe brief.", Model: "qwen2.5-coder", Templates: []string{"Review the staged changes"}, Source: "reviewer.yaml"}},
+		},
+		{
+			name: "yaml documents",
+			path: "team.yml",
+			data: "name: Tester\nprompt: Write tests.\n---\nname: Fixer\nprompt: Fix bugs.\nstarters: [Fix the failing test]\n",
+			want: []Persona{
+				{Name: "Tester", Prompt: "Write tests.", Source: "team.yml"},
+				{Name: "Fixer", Prompt: "Fix bugs.", Templates: []string{"Fix the failing test"}, Source: "team.yml"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Import(tt.path, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Import =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImport_Errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"no instructions": `{"name": "Empty", "model": "gpt-4o"}`,
+		"bad json":        `{"name": "Broken",`,
+		"not a mapping":   `"just a string"`,
+		"empty":           ``,
+	} {
+		if _, err := Import("agent.json", []byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestImportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "helper.yaml")
+	os.WriteFile(path, []byte("instructions: Help out.\n"), 0644)
+	list, err := ImportFile(path)
+	if err != nil || len(list) != 1 || list[0].Name != "helper" || list[0].Source != path {
+		t.Errorf("ImportFile = %+v, %v", list, err)
+	}
+}
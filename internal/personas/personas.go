@@ -0,0 +1,118 @@
+// Package personas keeps the user's library of personas: named system
+// prompts, with an optional model and prompt templates to start
+// conversations with. Personas can be imported from other tools' agent
+// definitions; see Import.
+package personas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agent-desktop/internal/config"
+)
+
+// libraryFile returns the path of the persona library. It is a variable so
+// tests can redirect it.
+var libraryFile = func() string {
+	return filepath.Join(config.Dir(), config.PersonasFile)
+}
+
+// Persona is a reusable system prompt for conversations.
+type Persona struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Prompt      string   `json:"prompt"`              // System prompt for conversations using the persona
+	Model       string   `json:"model,omitempty"`     // Model to use (empty = the conversation's)
+	Templates   []string `json:"templates,omitempty"` // Messages to start conversations with
+	Source      string   `json:"source,omitempty"`    // File the persona was imported from
+}
+
+// Validate checks that the persona has a name and a prompt.
+func (p Persona) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("persona needs a name")
+	}
+	if strings.TrimSpace(p.Prompt) == "" {
+		return fmt.Errorf("persona %q has no prompt", p.Name)
+	}
+	return nil
+}
+
+// Load returns the personas in the library, or none if it hasn't been
+// saved yet.
+func Load() ([]Persona, error) {
+	data, err := os.ReadFile(libraryFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Persona
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", config.PersonasFile, err)
+	}
+	return list, nil
+}
+
+// Save replaces the library with list.
+func Save(list []Persona) error {
+	for _, p := range list {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := libraryFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Find returns the persona named name, ignoring case.
+func Find(list []Persona, name string) (Persona, bool) {
+	for _, p := range list {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Persona{}, false
+}
+
+// Merge adds personas to list. A persona with the name of one already in
+// list replaces it, so importing the same file again updates its personas.
+func Merge(list, personas []Persona) []Persona {
+	merged := append([]Persona(nil), list...)
+	for _, p := range personas {
+		replaced := false
+		for i := range merged {
+			if strings.EqualFold(merged[i].Name, p.Name) {
+				merged[i], replaced = p, true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// Remove returns list without the persona named name, or an error if there
+// is none.
+func Remove(list []Persona, name string) ([]Persona, error) {
+	for i, p := range list {
+		if strings.EqualFold(p.Name, name) {
+			return append(list[:i:i], list[i+1:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown persona: %s", name)
+}
@@ -0,0 +1,48 @@
+package personas
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLibrary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "personas.json")
+	orig := libraryFile
+	libraryFile = func() string { return path }
+	defer func() { libraryFile = orig }()
+
+	if list, err := Load(); err != nil || len(list) != 0 {
+		t.Fatalf("Load without a library = %v, %v", list, err)
+	}
+
+	list := Merge(nil, []Persona{
+		{Name: "Reviewer", Prompt: "Review code."},
+		{Name: "Writer", Prompt: "Write docs.", Templates: []string{"Document this folder"}},
+	})
+	list = Merge(list, []Persona{{Name: "reviewer", Prompt: "Review code carefully."}})
+	if len(list) != 2 || list[0].Prompt != "Review code carefully." {
+		t.Fatalf("Merge = %+v, want the reviewer replaced", list)
+	}
+	if err := Save(list); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load()
+	if err != nil || len(loaded) != 2 {
+		t.Fatalf("Load = %+v, %v", loaded, err)
+	}
+	if p, ok := Find(loaded, "WRITER"); !ok || len(p.Templates) != 1 {
+		t.Errorf("Find = %+v, %v", p, ok)
+	}
+
+	loaded, err = Remove(loaded, "Writer")
+	if err != nil || len(loaded) != 1 || loaded[0].Name != "reviewer" {
+		t.Errorf("Remove = %+v, %v", loaded, err)
+	}
+	if _, err := Remove(loaded, "Writer"); err == nil {
+		t.Error("expected an error removing a missing persona")
+	}
+	if err := Save([]Persona{{Name: "Empty"}}); err == nil {
+		t.Error("expected an error saving a persona without a prompt")
+	}
+}
@@ -0,0 +1,293 @@
+// Package plugins turns executables in the tools folder into agent tools.
+// Each plugin is an executable with a JSON manifest next to it naming the
+// tool, describing it, and giving the JSON schema of its arguments. The
+// plugin is run with the arguments as JSON on stdin, and what it prints to
+// stdout is the tool's result.
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"agent-desktop/internal/tools"
+)
+
+// Source is the tools package source plugin tools are registered under.
+const Source = "plugins"
+
+// DefaultTimeout is how long a plugin may run when its manifest doesn't
+// say.
+const DefaultTimeout = 60 * time.Second
+
+// maxStderr bounds the stderr quoted in a failed call's error.
+const maxStderr = 2000
+
+// Manifest describes a plugin. It is stored as <name>.json in the tools
+// folder.
+type Manifest struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"` // JSON schema of the arguments
+	Command     string                 `json:"command,omitempty"`    // Executable, relative to the folder (default: the manifest's file name without .json)
+	Timeout     int                    `json:"timeout,omitempty"`    // Seconds (0 = DefaultTimeout)
+}
+
+// Plugin is a loaded plugin.
+type Plugin struct {
+	Manifest
+	ManifestPath string `json:"manifest_path"`
+	Executable   string `json:"executable"`
+}
+
+// toolName matches names the OpenAI API accepts for functions.
+var toolName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Load reads every manifest in dir. Plugins that can't be used are left
+// out and reported in the errors, one per manifest. A missing folder has no
+// plugins.
+func Load(dir string) ([]Plugin, []error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var plugins []Plugin
+	var errs []error
+	seen := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		p, err := loadPlugin(dir, entry.Name())
+		if err == nil && seen[p.Name] != "" {
+			err = fmt.Errorf("tool %s is already defined by %s", p.Name, seen[p.Name])
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		seen[p.Name] = entry.Name()
+		plugins = append(plugins, p)
+	}
+	return plugins, errs
+}
+
+// loadPlugin reads and checks one manifest.
+func loadPlugin(dir, file string) (Plugin, error) {
+	path := filepath.Join(dir, file)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Plugin{}, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Plugin{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	switch {
+	case !toolName.MatchString(m.Name):
+		return Plugin{}, errors.New("name must be 1-64 letters, digits, - or _")
+	case tools.IsBuiltinTool(m.Name):
+		return Plugin{}, fmt.Errorf("%s is a built-in tool", m.Name)
+	case strings.TrimSpace(m.Description) == "":
+		return Plugin{}, errors.New("description is required")
+	case m.Timeout < 0:
+		return Plugin{}, errors.New("timeout cannot be negative")
+	}
+	if source, ok := tools.ExternalSource(m.Name); ok && source != Source {
+		return Plugin{}, fmt.Errorf("%s is already provided by %s", m.Name, source)
+	}
+	if m.Parameters == nil {
+		m.Parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+
+	exe, err := findExecutable(dir, file, m.Command)
+	if err != nil {
+		return Plugin{}, err
+	}
+	return Plugin{Manifest: m, ManifestPath: path, Executable: exe}, nil
+}
+
+// findExecutable returns the plugin's executable: the manifest's command,
+// or the file named like the manifest without .json (with a Windows
+// executable extension on Windows).
+func findExecutable(dir, manifest, command string) (string, error) {
+	var candidates []string
+	if command != "" {
+		if filepath.IsAbs(command) {
+			candidates = []string{command}
+		} else {
+			candidates = []string{filepath.Join(dir, command)}
+		}
+	} else {
+		base := filepath.Join(dir, strings.TrimSuffix(manifest, filepath.Ext(manifest)))
+		candidates = []string{base}
+		if runtime.GOOS == "windows" {
+			candidates = append(candidates, base+".exe", base+".bat", base+".cmd")
+		}
+	}
+
+	for _, path := range candidates {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+			return "", fmt.Errorf("%s is not executable (chmod +x it)", filepath.Base(path))
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("executable %s not found", filepath.Base(candidates[0]))
+}
+
+// Tool adapts the plugin for the tools package.
+func (p Plugin) Tool() tools.ExternalTool {
+	return tools.ExternalTool{
+		Definition: tools.ToolDefinition{
+			Type: "function",
+			Function: tools.ToolFunction{
+				Name:        p.Name,
+				Description: p.Description,
+				Parameters:  p.Parameters,
+			},
+		},
+		Execute: p.Run,
+	}
+}
+
+// Run runs the plugin in the shell session's working directory with args
+// as JSON on stdin. A zero exit status makes stdout the result; otherwise
+// the call fails with stderr as the reason.
+func (p Plugin) Run(args map[string]interface{}) tools.ToolResult {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	input, err := json.Marshal(args)
+	if err != nil {
+		return tools.ToolResult{Success: false, Error: fmt.Sprintf("plugin %s: invalid arguments: %v", p.Name, err)}
+	}
+
+	timeout := DefaultTimeout
+	if p.Timeout > 0 {
+		timeout = time.Duration(p.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	session := tools.GetSession()
+	cmd := exec.CommandContext(ctx, p.Executable)
+	cmd.Dir = session.GetCWD()
+	cmd.Env = append(os.Environ(), "AGENT_DESKTOP_TOOL="+p.Name)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait on children of a killed plugin that still hold its output open
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	output := strings.TrimRight(stdout.String(), "\r\n")
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return tools.ToolResult{Success: false, Output: output, Error: fmt.Sprintf("plugin %s timed out after %s", p.Name, timeout)}
+	case err != nil:
+		reason := strings.TrimSpace(stderr.String())
+		if len(reason) > maxStderr {
+			reason = reason[len(reason)-maxStderr:]
+		}
+		if reason == "" {
+			reason = err.Error()
+		}
+		return tools.ToolResult{Success: false, Output: output, Error: fmt.Sprintf("plugin %s failed: %s", p.Name, reason)}
+	}
+	return tools.ToolResult{Success: true, Output: output}
+}
+
+// Register loads the plugins in dir and makes them the agent's plugin
+// tools, replacing any loaded before. Plugins named like a tool from
+// another source are left out and reported.
+func Register(dir string) ([]Plugin, []error) {
+	plugins, errs := Load(dir)
+	list := make([]tools.ExternalTool, 0, len(plugins))
+	for _, p := range plugins {
+		list = append(list, p.Tool())
+	}
+	if err := tools.SetExternalTools(Source, list); err != nil {
+		errs = append(errs, err)
+		plugins = slices.DeleteFunc(plugins, func(p Plugin) bool {
+			source, _ := tools.ExternalSource(p.Name)
+			return source != Source
+		})
+	}
+	return plugins, errs
+}
+
+// DefaultWatchInterval is how often Watch checks the tools folder.
+const DefaultWatchInterval = 2 * time.Second
+
+// Watch registers the plugins in dir, then polls the folder until ctx is
+// cancelled and registers them again whenever a file is added, removed, or
+// changed. onChange, if set, is called with the result of every load.
+func Watch(ctx context.Context, dir string, interval time.Duration, onChange func([]Plugin, []error)) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	last := stamp(dir)
+	reload := func() {
+		plugins, errs := Register(dir)
+		if onChange != nil {
+			onChange(plugins, errs)
+		}
+	}
+	reload()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if current := stamp(dir); current != last {
+			last = current
+			reload()
+		}
+	}
+}
+
+// stamp summarizes the names, sizes, modification times, and modes of the
+// files in dir, so any change to the folder changes it.
+func stamp(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s|%d|%d|%v", entry.Name(), info.Size(), info.ModTime().UnixNano(), info.Mode()))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\n")
+}
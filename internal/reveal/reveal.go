@@ -0,0 +1,43 @@
+// Package reveal opens folders and shows files in the system file manager:
+// Explorer on Windows, Finder on macOS, and the desktop's default file
+// manager (via xdg-open) on Linux.
+package reveal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// ErrUnsupported is returned when there is no file manager to open.
+var ErrUnsupported = errors.New("opening the file manager is not supported on this system")
+
+// startCommand starts the file manager without waiting for it to exit. It
+// is a variable so tests can capture the command instead of running it.
+var startCommand = func(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// Path opens the file manager at path. A folder is opened; a file is
+// selected in its folder where the file manager supports it, otherwise its
+// folder is opened.
+func Path(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return err
+	}
+	cmd, err := command(abs, info.IsDir())
+	if err != nil {
+		return err
+	}
+	return startCommand(cmd)
+}
@@ -0,0 +1,11 @@
+package reveal
+
+import "os/exec"
+
+// command builds an open invocation; -R selects a file in Finder.
+func command(path string, isDir bool) (*exec.Cmd, error) {
+	if isDir {
+		return exec.Command("open", path), nil
+	}
+	return exec.Command("open", "-R", path), nil
+}
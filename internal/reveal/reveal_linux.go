@@ -0,0 +1,19 @@
+package reveal
+
+import (
+	"os/exec"
+	"path/filepath"
+)
+
+// command builds an xdg-open invocation. xdg-open can't select a file, so
+// files are shown by opening their folder.
+func command(path string, isDir bool) (*exec.Cmd, error) {
+	xdgOpen, err := exec.LookPath("xdg-open")
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if !isDir {
+		path = filepath.Dir(path)
+	}
+	return exec.Command(xdgOpen, path), nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package reveal
+
+import "os/exec"
+
+// command reports that there is no file manager to open.
+func command(path string, isDir bool) (*exec.Cmd, error) {
+	return nil, ErrUnsupported
+}
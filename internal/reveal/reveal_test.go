@@ -0,0 +1,60 @@
+package reveal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureCommands replaces startCommand for the test and returns the
+// commands that would have run.
+func captureCommands(t *testing.T) *[]*exec.Cmd {
+	t.Helper()
+	var started []*exec.Cmd
+	original := startCommand
+	startCommand = func(cmd *exec.Cmd) error {
+		started = append(started, cmd)
+		return nil
+	}
+	t.Cleanup(func() { startCommand = original })
+	return &started
+}
+
+func TestPath_OpensFileManager(t *testing.T) {
+	started := captureCommands(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "report.txt")
+	os.WriteFile(file, []byte("done"), 0644)
+
+	for _, path := range []string{dir, file} {
+		err := Path(path)
+		if errors.Is(err, ErrUnsupported) {
+			t.Skip("no file manager on this system")
+		}
+		if err != nil {
+			t.Fatalf("Path(%s) error = %v", path, err)
+		}
+	}
+
+	if len(*started) != 2 {
+		t.Fatalf("expected 2 commands, got %d", len(*started))
+	}
+	for _, cmd := range *started {
+		if !strings.Contains(strings.Join(cmd.Args, " "), dir) {
+			t.Errorf("command %v does not open %s", cmd.Args, dir)
+		}
+	}
+}
+
+func TestPath_Missing(t *testing.T) {
+	started := captureCommands(t)
+	if err := Path(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+	if len(*started) != 0 {
+		t.Errorf("expected no command for a missing path, got %v", (*started)[0].Args)
+	}
+}
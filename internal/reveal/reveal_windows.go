@@ -0,0 +1,12 @@
+package reveal
+
+import "os/exec"
+
+// command builds an Explorer invocation; /select, highlights a file in its
+// folder.
+func command(path string, isDir bool) (*exec.Cmd, error) {
+	if isDir {
+		return exec.Command("explorer", path), nil
+	}
+	return exec.Command("explorer", "/select,", path), nil
+}
@@ -0,0 +1,294 @@
+// Package runqueue runs queued agent tasks in order, a limited number at a
+// time. Items waiting to run can be reordered or cancelled, and every change
+// is reported to an observer so the UI can show the queue.
+package runqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status is the state of a queued item.
+type Status string
+
+// Item states. Queued and running items are pending; the rest are finished.
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusDone      Status = "done"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// ErrNotFound is returned for an unknown item ID.
+var ErrNotFound = errors.New("queue item not found")
+
+// Item is a task waiting in, or already run by, the queue.
+type Item struct {
+	ID             string    `json:"id"`
+	Task           string    `json:"task"`
+	ConversationID string    `json:"conversation_id,omitempty"` // Empty until a new conversation is started for the task
+	Status         Status    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	EnqueuedAt     time.Time `json:"enqueued_at"`
+	StartedAt      time.Time `json:"started_at,omitempty"`
+	FinishedAt     time.Time `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the item has stopped running for good.
+func (i Item) Finished() bool {
+	return i.Status != StatusQueued && i.Status != StatusRunning
+}
+
+// RunFunc runs an item. The context is cancelled when the item is cancelled.
+type RunFunc func(ctx context.Context, item Item) error
+
+// Queue runs items in order with a limited number running at once.
+type Queue struct {
+	mu          sync.Mutex
+	items       []*Item
+	cancels     map[string]context.CancelFunc // Running item ID -> cancel
+	run         RunFunc
+	concurrency int
+	paused      bool
+	onChange    func([]Item)
+}
+
+// New creates a queue that runs items with run, at most concurrency at a
+// time (at least one). onChange, if set, is called with a snapshot of the
+// queue after every change; it is called without the queue's lock held.
+func New(run RunFunc, concurrency int, onChange func([]Item)) *Queue {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Queue{
+		cancels:     map[string]context.CancelFunc{},
+		run:         run,
+		concurrency: concurrency,
+		onChange:    onChange,
+	}
+}
+
+// Enqueue adds a task to the end of the queue. conversationID continues an
+// existing conversation; empty starts a new one. The item starts right
+// away if a run slot is free.
+func (q *Queue) Enqueue(task, conversationID string) Item {
+	q.mu.Lock()
+	item := &Item{
+		ID:             uuid.New().String(),
+		Task:           task,
+		ConversationID: conversationID,
+		Status:         StatusQueued,
+		EnqueuedAt:     time.Now(),
+	}
+	q.items = append(q.items, item)
+	snapshot := *item
+	q.mu.Unlock()
+
+	q.changed()
+	return snapshot
+}
+
+// Items returns a snapshot of the queue in order.
+func (q *Queue) Items() []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.snapshot()
+}
+
+// Move places a queued item at index among the queued items, so it runs
+// after index others. Running and finished items can't be moved.
+func (q *Queue) Move(id string, index int) error {
+	q.mu.Lock()
+	from := q.indexOf(id)
+	if from < 0 {
+		q.mu.Unlock()
+		return ErrNotFound
+	}
+	item := q.items[from]
+	if item.Status != StatusQueued {
+		q.mu.Unlock()
+		return fmt.Errorf("only queued items can be moved; item is %s", item.Status)
+	}
+
+	if index < 0 {
+		index = 0
+	}
+	rest := slices.Delete(slices.Clone(q.items), from, from+1)
+	pos := len(rest)
+	queued := 0
+	for i, it := range rest {
+		if it.Status != StatusQueued {
+			continue
+		}
+		if queued == index {
+			pos = i
+			break
+		}
+		queued++
+	}
+	q.items = slices.Insert(rest, pos, item)
+	q.mu.Unlock()
+
+	q.changed()
+	return nil
+}
+
+// Cancel removes a queued item from the run order or stops a running one.
+// Cancelling a finished item is a no-op.
+func (q *Queue) Cancel(id string) error {
+	q.mu.Lock()
+	i := q.indexOf(id)
+	if i < 0 {
+		q.mu.Unlock()
+		return ErrNotFound
+	}
+	item := q.items[i]
+	switch item.Status {
+	case StatusQueued:
+		item.Status = StatusCancelled
+		item.FinishedAt = time.Now()
+	case StatusRunning:
+		// The run goroutine records the cancellation when run returns
+		q.cancels[id]()
+	}
+	q.mu.Unlock()
+
+	q.changed()
+	return nil
+}
+
+// CancelAll cancels every queued and running item.
+func (q *Queue) CancelAll() {
+	for _, item := range q.Items() {
+		if !item.Finished() {
+			q.Cancel(item.ID)
+		}
+	}
+}
+
+// ClearFinished removes finished items from the queue.
+func (q *Queue) ClearFinished() {
+	q.mu.Lock()
+	kept := q.items[:0]
+	for _, item := range q.items {
+		if !item.Finished() {
+			kept = append(kept, item)
+		}
+	}
+	clear(q.items[len(kept):])
+	q.items = kept
+	q.mu.Unlock()
+
+	q.changed()
+}
+
+// SetPaused stops starting new items while paused. Running items continue.
+func (q *Queue) SetPaused(paused bool) {
+	q.mu.Lock()
+	q.paused = paused
+	q.mu.Unlock()
+
+	q.changed()
+}
+
+// Paused reports whether the queue is paused.
+func (q *Queue) Paused() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.paused
+}
+
+// SetConversation records the conversation a running item started, so the
+// UI can open it.
+func (q *Queue) SetConversation(id, conversationID string) {
+	q.mu.Lock()
+	if i := q.indexOf(id); i >= 0 {
+		q.items[i].ConversationID = conversationID
+	}
+	q.mu.Unlock()
+
+	q.changed()
+}
+
+// changed starts items if run slots are free and reports the queue.
+func (q *Queue) changed() {
+	q.mu.Lock()
+	q.startReady()
+	snapshot := q.snapshot()
+	q.mu.Unlock()
+
+	if q.onChange != nil {
+		q.onChange(snapshot)
+	}
+}
+
+// startReady starts queued items in order until the concurrency limit is
+// reached. q.mu must be held.
+func (q *Queue) startReady() {
+	if q.paused {
+		return
+	}
+	for _, item := range q.items {
+		if len(q.cancels) >= q.concurrency {
+			return
+		}
+		if item.Status != StatusQueued {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		q.cancels[item.ID] = cancel
+		item.Status = StatusRunning
+		item.StartedAt = time.Now()
+		go q.runItem(ctx, item.ID, *item)
+	}
+}
+
+// runItem runs an item and records how it ended.
+func (q *Queue) runItem(ctx context.Context, id string, item Item) {
+	err := q.run(ctx, item)
+	cancelled := ctx.Err() != nil || errors.Is(err, context.Canceled)
+
+	q.mu.Lock()
+	q.cancels[id]()
+	delete(q.cancels, id)
+	if i := q.indexOf(id); i >= 0 {
+		it := q.items[i]
+		it.FinishedAt = time.Now()
+		switch {
+		case cancelled:
+			it.Status = StatusCancelled
+		case err != nil:
+			it.Status = StatusFailed
+			it.Error = err.Error()
+		default:
+			it.Status = StatusDone
+		}
+	}
+	q.mu.Unlock()
+
+	q.changed()
+}
+
+func (q *Queue) indexOf(id string) int {
+	for i, item := range q.items {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Queue) snapshot() []Item {
+	items := make([]Item, len(q.items))
+	for i, item := range q.items {
+		items[i] = *item
+	}
+	return items
+}
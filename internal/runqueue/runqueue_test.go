@@ -0,0 +1,185 @@
+package runqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingRunner runs items until they are released or cancelled and
+// records the order they started in.
+type blockingRunner struct {
+	mu      sync.Mutex
+	started []string
+	release map[string]chan error
+}
+
+func newBlockingRunner() *blockingRunner {
+	return &blockingRunner{release: map[string]chan error{}}
+}
+
+func (r *blockingRunner) channel(task string) chan error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.release[task] == nil {
+		r.release[task] = make(chan error, 1)
+	}
+	return r.release[task]
+}
+
+func (r *blockingRunner) run(ctx context.Context, item Item) error {
+	r.mu.Lock()
+	r.started = append(r.started, item.Task)
+	r.mu.Unlock()
+
+	select {
+	case err := <-r.channel(item.Task):
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *blockingRunner) startedTasks() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.started...)
+}
+
+// waitFor polls until cond holds or fails the test.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for queue")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func statusOf(q *Queue, id string) Status {
+	for _, item := range q.Items() {
+		if item.ID == id {
+			return item.Status
+		}
+	}
+	return ""
+}
+
+func TestQueue_RunsSerially(t *testing.T) {
+	r := newBlockingRunner()
+	q := New(r.run, 1, nil)
+
+	first := q.Enqueue("first", "")
+	second := q.Enqueue("second", "conv-2")
+
+	waitFor(t, func() bool { return statusOf(q, first.ID) == StatusRunning })
+	if statusOf(q, second.ID) != StatusQueued {
+		t.Fatalf("Expected second item to wait, got %s", statusOf(q, second.ID))
+	}
+
+	r.channel("first") <- nil
+	waitFor(t, func() bool { return statusOf(q, second.ID) == StatusRunning })
+	r.channel("second") <- errors.New("boom")
+	waitFor(t, func() bool { return statusOf(q, second.ID) == StatusFailed })
+
+	items := q.Items()
+	if items[0].Status != StatusDone || items[1].Error != "boom" {
+		t.Errorf("Unexpected final state: %+v", items)
+	}
+	if items[1].ConversationID != "conv-2" {
+		t.Errorf("Expected conversation ID to be kept, got %q", items[1].ConversationID)
+	}
+}
+
+func TestQueue_ConcurrencyLimit(t *testing.T) {
+	r := newBlockingRunner()
+	q := New(r.run, 2, nil)
+
+	q.Enqueue("a", "")
+	q.Enqueue("b", "")
+	c := q.Enqueue("c", "")
+
+	waitFor(t, func() bool { return len(r.startedTasks()) == 2 })
+	if statusOf(q, c.ID) != StatusQueued {
+		t.Errorf("Expected third item to wait for a free slot")
+	}
+
+	r.channel("a") <- nil
+	waitFor(t, func() bool { return statusOf(q, c.ID) == StatusRunning })
+	q.CancelAll()
+	waitFor(t, func() bool {
+		for _, item := range q.Items() {
+			if !item.Finished() {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestQueue_MoveAndCancel(t *testing.T) {
+	r := newBlockingRunner()
+	q := New(r.run, 1, nil)
+	q.SetPaused(true)
+
+	a := q.Enqueue("a", "")
+	b := q.Enqueue("b", "")
+	c := q.Enqueue("c", "")
+
+	if err := q.Move(c.ID, 0); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if err := q.Cancel(a.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if err := q.Move("missing", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := q.Move(a.ID, 0); err == nil {
+		t.Error("Expected error moving a cancelled item")
+	}
+
+	q.SetPaused(false)
+	waitFor(t, func() bool { return statusOf(q, c.ID) == StatusRunning })
+	r.channel("c") <- nil
+	waitFor(t, func() bool { return statusOf(q, b.ID) == StatusRunning })
+
+	// Cancelling a running item stops it
+	q.Cancel(b.ID)
+	waitFor(t, func() bool { return statusOf(q, b.ID) == StatusCancelled })
+
+	if got := r.startedTasks(); len(got) != 2 || got[0] != "c" || got[1] != "b" {
+		t.Errorf("Expected c then b to run, got %v", got)
+	}
+
+	q.ClearFinished()
+	if items := q.Items(); len(items) != 0 {
+		t.Errorf("Expected finished items to be cleared, got %d", len(items))
+	}
+}
+
+func TestQueue_OnChange(t *testing.T) {
+	var mu sync.Mutex
+	var last []Item
+	var q *Queue
+	q = New(func(ctx context.Context, item Item) error {
+		q.SetConversation(item.ID, "conv-1")
+		return nil
+	}, 1, func(items []Item) {
+		mu.Lock()
+		last = items
+		mu.Unlock()
+	})
+
+	q.Enqueue("task", "")
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(last) == 1 && last[0].Status == StatusDone && last[0].ConversationID == "conv-1"
+	})
+}
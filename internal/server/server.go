@@ -0,0 +1,381 @@
+// Package server exposes conversations and agent runs over HTTP so the
+// agent can be driven from scripts or a home server without the desktop
+// window. Every request needs the API token; agent progress is streamed
+// as server-sent events or over a WebSocket.
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/runqueue"
+)
+
+// EnvToken is the environment variable that sets the API token, taking
+// precedence over the token file.
+const EnvToken = "AGENT_DESKTOP_API_TOKEN"
+
+// DefaultAddr is where the server listens unless told otherwise. It only
+// accepts local connections; listen on another address to reach it from
+// other machines.
+const DefaultAddr = "127.0.0.1:8765"
+
+// maxBodyBytes limits the size of request bodies.
+const maxBodyBytes = 1 << 20
+
+// keepAliveInterval is how often an idle event stream gets a comment so
+// proxies don't close it.
+const keepAliveInterval = 30 * time.Second
+
+// subscriberBuffer is how many events a slow event stream can fall behind
+// before events are dropped for it.
+const subscriberBuffer = 256
+
+// ErrNotFound is returned by a Backend for an unknown conversation or run.
+var ErrNotFound = errors.New("not found")
+
+// Backend is what the server drives: the app's conversations and run queue.
+type Backend interface {
+	ListConversations() ([]conversation.Summary, error)
+	NewConversation() (*conversation.Conversation, error)
+	GetConversation(id string) (*conversation.Conversation, error)
+	// SendMessage queues text as a new message in the conversation and
+	// returns the queued run.
+	SendMessage(conversationID, text string) (runqueue.Item, error)
+	ListRuns() []runqueue.Item
+	CancelRun(id string) error
+}
+
+// Event is an app event forwarded to event stream clients.
+type Event struct {
+	Name string
+	Data []interface{}
+}
+
+// Server serves the REST API and the event stream.
+type Server struct {
+	backend Backend
+	token   string
+
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// New creates a server for backend that accepts requests carrying token.
+func New(backend Backend, token string) *Server {
+	return &Server{
+		backend:     backend,
+		token:       token,
+		subscribers: map[chan Event]struct{}{},
+	}
+}
+
+// Handler returns the HTTP handler for the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/conversations", s.listConversations)
+	mux.HandleFunc("POST /api/conversations", s.createConversation)
+	mux.HandleFunc("GET /api/conversations/{id}", s.getConversation)
+	mux.HandleFunc("GET /api/conversations/{id}/transcript", s.getTranscript)
+	mux.HandleFunc("POST /api/conversations/{id}/messages", s.sendMessage)
+	mux.HandleFunc("GET /api/runs", s.listRuns)
+	mux.HandleFunc("GET /api/runs/{id}", s.getRun)
+	mux.HandleFunc("DELETE /api/runs/{id}", s.cancelRun)
+	mux.HandleFunc("GET /api/events", s.streamEvents)
+	mux.HandleFunc("GET /api/ws", s.streamWebSocket)
+	return s.authenticate(mux)
+}
+
+// Publish sends an event to every connected event stream. Streams that
+// have fallen too far behind miss the event rather than blocking the app.
+func (s *Server) Publish(name string, data ...interface{}) {
+	event := Event{Name: name, Data: data}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			slog.Warn("dropping event for slow API client", "event", name)
+		}
+	}
+}
+
+// subscribe registers an event stream and returns its channel and a
+// function that unregisters it.
+func (s *Server) subscribe() (chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}
+}
+
+// authenticate rejects requests without the API token. The token is read
+// from an "Authorization: Bearer" header, or from the token query
+// parameter for clients such as EventSource that can't set headers.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = r.URL.Query().Get("token")
+		}
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid API token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listConversations(w http.ResponseWriter, r *http.Request) {
+	summaries, err := s.backend.ListConversations()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if summaries == nil {
+		summaries = []conversation.Summary{}
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *Server) createConversation(w http.ResponseWriter, r *http.Request) {
+	conv, err := s.backend.NewConversation()
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, conv)
+}
+
+func (s *Server) getConversation(w http.ResponseWriter, r *http.Request) {
+	conv, ok := s.conversation(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, conv)
+}
+
+// getTranscript returns the conversation as Markdown, or as JSON with
+// ?format=json.
+func (s *Server) getTranscript(w http.ResponseWriter, r *http.Request) {
+	conv, ok := s.conversation(w, r)
+	if !ok {
+		return
+	}
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "markdown", "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", conv.ID+".md"))
+		w.Write([]byte(conv.Markdown()))
+	case "json":
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", conv.ID+".json"))
+		writeJSON(w, http.StatusOK, conv)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown format %q; use markdown or json", format))
+	}
+}
+
+// messageRequest is the body of a send message request.
+type messageRequest struct {
+	Text string `json:"text"`
+}
+
+// sendMessage queues a message and returns the run with 202 Accepted.
+// Progress arrives on the event stream.
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req messageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("text is required"))
+		return
+	}
+	if _, err := s.backend.GetConversation(id); err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	item, err := s.backend.SendMessage(id, req.Text)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, item)
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	runs := s.backend.ListRuns()
+	if runs == nil {
+		runs = []runqueue.Item{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	for _, item := range s.backend.ListRuns() {
+		if item.ID == id {
+			writeJSON(w, http.StatusOK, item)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, ErrNotFound)
+}
+
+// cancelRun removes a queued run or stops a running one.
+func (s *Server) cancelRun(w http.ResponseWriter, r *http.Request) {
+	if err := s.backend.CancelRun(r.PathValue("id")); err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// streamEvents sends app events, such as agent:step and queue:changed, as
+// server-sent events until the client disconnects. Each event's data is a
+// JSON array of the event's arguments.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	events, unsubscribe := s.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-events:
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				slog.Warn("failed to encode event", "event", event.Name, "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// conversation loads the conversation named in the path, writing an error
+// response and returning false if it can't.
+func (s *Server) conversation(w http.ResponseWriter, r *http.Request) (*conversation.Conversation, bool) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return nil, false
+	}
+	conv, err := s.backend.GetConversation(id)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return nil, false
+	}
+	return conv, true
+}
+
+// pathID returns the id path value, rejecting IDs that could name a path
+// outside the conversation store.
+func pathID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.PathValue("id")
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid ID %q", id))
+		return "", false
+	}
+	return id, true
+}
+
+// statusFor maps a backend error to an HTTP status.
+func statusFor(err error) int {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, conversation.ErrNotFound) || errors.Is(err, runqueue.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, conversation.ErrReadOnly) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("failed to write API response", "error", err)
+	}
+}
+
+// writeError writes err as a JSON error response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// LoadToken returns the API token from EnvToken, or from the file at path.
+// If neither is set, a random token is generated and saved to path with
+// owner-only permissions, so it stays the same across restarts.
+func LoadToken(path string) (string, error) {
+	if token := strings.TrimSpace(os.Getenv(EnvToken)); token != "" {
+		return token, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read API token: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to save API token: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save API token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/llm"
+	"agent-desktop/internal/runqueue"
+)
+
+const testToken = "secret"
+
+// fakeBackend keeps conversations in memory and records queued messages.
+type fakeBackend struct {
+	conversations map[string]*conversation.Conversation
+	runs          []runqueue.Item
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{conversations: map[string]*conversation.Conversation{}}
+}
+
+func (b *fakeBackend) ListConversations() ([]conversation.Summary, error) {
+	var summaries []conversation.Summary
+	for _, conv := range b.conversations {
+		summaries = append(summaries, conv.ToSummary())
+	}
+	return summaries, nil
+}
+
+func (b *fakeBackend) NewConversation() (*conversation.Conversation, error) {
+	conv := conversation.New()
+	b.conversations[conv.ID] = conv
+	return conv, nil
+}
+
+func (b *fakeBackend) GetConversation(id string) (*conversation.Conversation, error) {
+	conv, ok := b.conversations[id]
+	if !ok {
+		return nil, conversation.ErrNotFound
+	}
+	return conv, nil
+}
+
+func (b *fakeBackend) SendMessage(conversationID, text string) (runqueue.Item, error) {
+	item := runqueue.Item{ID: "run-1", Task: text, ConversationID: conversationID, Status: runqueue.StatusQueued}
+	b.runs = append(b.runs, item)
+	return item, nil
+}
+
+func (b *fakeBackend) ListRuns() []runqueue.Item {
+	return b.runs
+}
+
+func (b *fakeBackend) CancelRun(id string) error {
+	for i, item := range b.runs {
+		if item.ID == id {
+			b.runs[i].Status = runqueue.StatusCancelled
+			return nil
+		}
+	}
+	return runqueue.ErrNotFound
+}
+
+// do sends an authenticated request to the server's handler.
+func do(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_Auth(t *testing.T) {
+	h := New(newFakeBackend(), testToken).Handler()
+
+	tests := []struct {
+		name   string
+		header string
+		query  string
+		want   int
+	}{
+		{"no token", "", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", "", http.StatusUnauthorized},
+		{"header", "Bearer " + testToken, "", http.StatusOK},
+		{"query", "", "?token=" + testToken, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/conversations"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_Conversations(t *testing.T) {
+	backend := newFakeBackend()
+	h := New(backend, testToken).Handler()
+
+	rec := do(t, h, "POST", "/api/conversations", "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body)
+	}
+	var conv conversation.Conversation
+	json.Unmarshal(rec.Body.Bytes(), &conv)
+
+	backend.conversations[conv.ID].AddMessage(llm.Message{Role: "user", Content: "Hello there"})
+
+	rec = do(t, h, "GET", "/api/conversations", "")
+	var summaries []conversation.Summary
+	json.Unmarshal(rec.Body.Bytes(), &summaries)
+	if len(summaries) != 1 || summaries[0].ID != conv.ID {
+		t.Errorf("list = %+v, want the new conversation", summaries)
+	}
+
+	rec = do(t, h, "GET", "/api/conversations/"+conv.ID+"/transcript", "")
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+		t.Errorf("transcript content type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "Hello there") {
+		t.Errorf("transcript = %q, want the message", rec.Body)
+	}
+
+	rec = do(t, h, "GET", "/api/conversations/"+conv.ID+"/transcript?format=json", "")
+	if !strings.Contains(rec.Body.String(), `"id":"`+conv.ID+`"`) {
+		t.Errorf("JSON transcript = %q", rec.Body)
+	}
+
+	if rec := do(t, h, "GET", "/api/conversations/missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("missing conversation status = %d, want 404", rec.Code)
+	}
+	if rec := do(t, h, "GET", "/api/conversations/..%2Fx", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("path ID status = %d, want 400", rec.Code)
+	}
+}
+
+func TestServer_SendMessage(t *testing.T) {
+	backend := newFakeBackend()
+	conv, _ := backend.NewConversation()
+	h := New(backend, testToken).Handler()
+
+	if rec := do(t, h, "POST", "/api/conversations/"+conv.ID+"/messages", `{"text":" "}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty text status = %d, want 400", rec.Code)
+	}
+	if rec := do(t, h, "POST", "/api/conversations/missing/messages", `{"text":"hi"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("missing conversation status = %d, want 404", rec.Code)
+	}
+
+	rec := do(t, h, "POST", "/api/conversations/"+conv.ID+"/messages", `{"text":"list files"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("send status = %d: %s", rec.Code, rec.Body)
+	}
+	if len(backend.runs) != 1 || backend.runs[0].Task != "list files" || backend.runs[0].ConversationID != conv.ID {
+		t.Errorf("runs = %+v, want the message queued", backend.runs)
+	}
+
+	if rec := do(t, h, "GET", "/api/runs/run-1", ""); rec.Code != http.StatusOK {
+		t.Errorf("get run status = %d", rec.Code)
+	}
+	if rec := do(t, h, "DELETE", "/api/runs/run-1", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("cancel status = %d", rec.Code)
+	}
+	if rec := do(t, h, "DELETE", "/api/runs/missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("cancel missing run status = %d, want 404", rec.Code)
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	s := New(newFakeBackend(), testToken)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/events?token=" + testToken)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	reader.ReadString('\n') // ": connected"
+	reader.ReadString('\n')
+
+	// The stream subscribes before it says it's connected
+	s.Publish("agent:complete", "Done")
+
+	lines := make(chan string, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			line, _ := reader.ReadString('\n')
+			lines <- line
+		}
+	}()
+	for _, want := range []string{"event: agent:complete\n", "data: [\"Done\"]\n"} {
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Errorf("line = %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "api_token")
+	t.Setenv(EnvToken, "")
+
+	first, err := LoadToken(path)
+	if err != nil || len(first) != 64 {
+		t.Fatalf("LoadToken() = %q, %v; want a generated token", first, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("token file not saved: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		t.Errorf("token file mode = %v, want owner-only", info.Mode().Perm())
+	}
+	if again, _ := LoadToken(path); again != first {
+		t.Errorf("second LoadToken() = %q, want the saved %q", again, first)
+	}
+
+	t.Setenv(EnvToken, "from-env")
+	if token, _ := LoadToken(path); token != "from-env" {
+		t.Errorf("LoadToken() = %q, want the environment token", token)
+	}
+}
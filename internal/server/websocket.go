@@ -0,0 +1,73 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// writeTimeout bounds how long a WebSocket write may block on a slow client.
+const writeTimeout = 10 * time.Second
+
+// upgrader accepts WebSocket connections from any origin: the API token,
+// not the page's origin, decides who may connect, which lets frontends
+// served from elsewhere attach.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// socketMessage is an event sent to WebSocket clients.
+type socketMessage struct {
+	Event string        `json:"event"`
+	Data  []interface{} `json:"data"` // The event's arguments
+}
+
+// streamWebSocket sends app events, such as agent:step and queue:changed,
+// as JSON text messages until the client disconnects. It carries the same
+// events as the server-sent event stream for clients that prefer
+// WebSockets; messages from the client are ignored.
+func (s *Server) streamWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an error
+		slog.Debug("WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := s.subscribe()
+	defer unsubscribe()
+
+	// Reading handles pings and close frames and notices when the client
+	// goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-keepAlive.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		case event := <-events:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteJSON(socketMessage{Event: event.Name, Data: event.Data}); err != nil {
+				slog.Debug("failed to write to WebSocket client", "error", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestServer_WebSocket(t *testing.T) {
+	s := New(newFakeBackend(), testToken)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws"
+
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized without a token, got %v", err)
+	}
+
+	header := http.Header{"Authorization": {"Bearer " + testToken}, "Origin": {"http://elsewhere.example"}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Wait for the connection to subscribe before publishing
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.subscribers)
+		s.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the WebSocket to subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.Publish("agent:error", "LLM not configured")
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg socketMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+	if msg.Event != "agent:error" || len(msg.Data) != 1 || msg.Data[0] != "LLM not configured" {
+		t.Errorf("message = %+v, want the agent:error event", msg)
+	}
+}
@@ -0,0 +1,332 @@
+// Package snapshot keeps the original contents of files the agent changes,
+// per conversation, so the changes can be reviewed as diffs. A file is
+// snapshotted the first time it is changed in a conversation; later
+// changes in the same conversation keep that first snapshot.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"agent-desktop/internal/diff"
+)
+
+// MaxFileSize is the largest file whose contents are snapshotted. Larger
+// files are listed as changed without contents.
+const MaxFileSize = 10 << 20
+
+// InlineSize is the largest file whose contents Diff returns with the
+// diff. Diffs of larger files are computed a window of lines at a time,
+// without reading either version whole, and return only the diff.
+const InlineSize = 1 << 20
+
+// MaxDiffBytes caps the diff of a file larger than InlineSize.
+const MaxDiffBytes = 1 << 20
+
+// indexFile lists a conversation's snapshots in its directory.
+const indexFile = "index.json"
+
+// ErrNotFound is returned for a file with no snapshot in the conversation.
+var ErrNotFound = errors.New("no snapshot for this file")
+
+// Entry describes a file changed in a conversation.
+type Entry struct {
+	Path     string    `json:"path"`
+	Existed  bool      `json:"existed"`             // The file existed before the first change
+	Size     int64     `json:"size"`                // Size before the first change
+	TooLarge bool      `json:"too_large,omitempty"` // Contents were not kept
+	Blob     string    `json:"blob,omitempty"`      // File holding the original contents
+	TakenAt  time.Time `json:"taken_at"`
+}
+
+// FileDiff compares a file's original contents with its current contents.
+type FileDiff struct {
+	Path     string `json:"path"`
+	Before   string `json:"before"`
+	After    string `json:"after"`
+	Unified  string `json:"unified"`             // Unified diff; empty if unchanged or binary
+	Existed  bool   `json:"existed"`             // The file existed before the agent changed it
+	Exists   bool   `json:"exists"`              // The file exists now
+	Binary   bool   `json:"binary,omitempty"`    // Contents are not text; Before and After are empty
+	TooLarge bool   `json:"too_large,omitempty"` // The file was too large to snapshot
+	Omitted  bool   `json:"omitted,omitempty"`   // Over InlineSize; Before and After are empty
+	Cut      bool   `json:"cut,omitempty"`       // Unified was cut at MaxDiffBytes
+}
+
+// Store keeps snapshots under a directory, one subdirectory per
+// conversation.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates a store rooted at dir. The directory is created when the
+// first snapshot is taken.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Record snapshots path for the conversation unless it already has a
+// snapshot there. Call it just before the file is changed.
+func (s *Store) Record(convID, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.loadIndex(convID)
+	if err != nil {
+		return err
+	}
+	if _, ok := index[path]; ok {
+		return nil
+	}
+
+	entry := Entry{Path: path, TakenAt: time.Now()}
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		// A new file; its original contents are empty
+	case err != nil:
+		return err
+	case info.IsDir():
+		return nil
+	case info.Size() > MaxFileSize:
+		entry.Existed, entry.Size, entry.TooLarge = true, info.Size(), true
+	default:
+		entry.Existed, entry.Size = true, info.Size()
+		entry.Blob = blobName(path)
+		if err := s.copyBlob(convID, path, entry.Blob); err != nil {
+			return err
+		}
+	}
+
+	index[path] = entry
+	return s.saveIndex(convID, index)
+}
+
+// List returns the files changed in the conversation, sorted by path.
+func (s *Store) List(convID string) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.loadIndex(convID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(index))
+	for _, e := range index {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// Diff compares the file's snapshot in the conversation with the file as it
+// is now.
+func (s *Store) Diff(convID, path string) (*FileDiff, error) {
+	s.mu.Lock()
+	index, err := s.loadIndex(convID)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := index[path]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	result := &FileDiff{Path: path, Existed: entry.Existed, TooLarge: entry.TooLarge}
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	case info.Size() > MaxFileSize:
+		result.Exists, result.TooLarge = true, true
+	default:
+		result.Exists = true
+	}
+	if result.TooLarge {
+		return result, nil
+	}
+
+	fromName, toName := path, path
+	if !result.Existed {
+		fromName = "/dev/null"
+	}
+	if !result.Exists {
+		toName = "/dev/null"
+	}
+	blob := ""
+	if entry.Blob != "" {
+		blob = filepath.Join(s.convDir(convID), entry.Blob)
+	}
+	if entry.Size > InlineSize || result.Exists && info.Size() > InlineSize {
+		return diffLarge(result, blob, fromName, toName)
+	}
+
+	var before []byte
+	if blob != "" {
+		if before, err = os.ReadFile(blob); err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+	}
+	var after []byte
+	if result.Exists {
+		if after, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if isBinary(before) || isBinary(after) {
+		result.Binary = true
+		return result, nil
+	}
+	result.Before, result.After = string(before), string(after)
+	result.Unified = diff.Unified(fromName, toName, result.Before, result.After)
+	return result, nil
+}
+
+// diffLarge fills in the diff of a file over InlineSize, streaming its
+// snapshot in blob ("" = it didn't exist) and the file as it is now.
+func diffLarge(result *FileDiff, blob, fromName, toName string) (*FileDiff, error) {
+	result.Omitted = true
+	before, err := openText(blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer before.Close()
+	current := ""
+	if result.Exists {
+		current = result.Path
+	}
+	after, err := openText(current)
+	if err != nil {
+		return nil, err
+	}
+	defer after.Close()
+
+	if before.binary || after.binary {
+		result.Binary = true
+		return result, nil
+	}
+	result.Unified, result.Cut, err = diff.UnifiedReader(fromName, toName, before, after, MaxDiffBytes)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// openedText is a file opened for diffing.
+type openedText struct {
+	*bufio.Reader
+	file   *os.File // nil for no file
+	binary bool     // The file looks like binary
+}
+
+// openText opens the file at path for diffing, telling whether it is
+// binary from its start. An empty path reads as an empty file.
+func openText(path string) (openedText, error) {
+	if path == "" {
+		return openedText{Reader: bufio.NewReader(strings.NewReader(""))}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return openedText{}, err
+	}
+	r := bufio.NewReaderSize(f, 64<<10)
+	start, _ := r.Peek(8000)
+	return openedText{Reader: r, file: f, binary: isBinary(start)}, nil
+}
+
+// Close closes the file.
+func (t openedText) Close() error {
+	if t.file == nil {
+		return nil
+	}
+	return t.file.Close()
+}
+
+// Delete removes the conversation's snapshots.
+func (s *Store) Delete(convID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.RemoveAll(s.convDir(convID))
+}
+
+func (s *Store) convDir(convID string) string {
+	return filepath.Join(s.dir, filepath.Base(convID))
+}
+
+func (s *Store) loadIndex(convID string) (map[string]Entry, error) {
+	data, err := os.ReadFile(filepath.Join(s.convDir(convID), indexFile))
+	if os.IsNotExist(err) {
+		return map[string]Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]Entry{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot index: %w", err)
+	}
+	return index, nil
+}
+
+func (s *Store) saveIndex(convID string, index map[string]Entry) error {
+	if err := os.MkdirAll(s.convDir(convID), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.convDir(convID), indexFile), data, 0644)
+}
+
+func (s *Store) copyBlob(convID, path, blob string) error {
+	if err := os.MkdirAll(s.convDir(convID), 0755); err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(filepath.Join(s.convDir(convID), blob))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// blobName derives a stable file name for a path's snapshot.
+func blobName(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:16])
+}
+
+// isBinary reports whether data looks like binary rather than text, using
+// the same NUL-byte check as git.
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
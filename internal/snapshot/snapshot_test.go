@@ -0,0 +1,126 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	return NewStore(t.TempDir()), t.TempDir()
+}
+
+func TestStore_RecordAndDiff(t *testing.T) {
+	store, work := setupTestStore(t)
+	path := filepath.Join(work, "notes.txt")
+	os.WriteFile(path, []byte("one\ntwo\n"), 0644)
+
+	if err := store.Record("conv-1", path); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	os.WriteFile(path, []byte("one\n2\n"), 0644)
+
+	// Later changes keep the first snapshot
+	store.Record("conv-1", path)
+	os.WriteFile(path, []byte("one\nTWO\n"), 0644)
+
+	d, err := store.Diff("conv-1", path)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if d.Before != "one\ntwo\n" || d.After != "one\nTWO\n" || !d.Existed || !d.Exists {
+		t.Errorf("Unexpected diff: %+v", d)
+	}
+	if !strings.Contains(d.Unified, "-two\n+TWO\n") {
+		t.Errorf("Unexpected unified diff:\n%s", d.Unified)
+	}
+
+	// Other conversations have their own snapshots
+	if _, err := store.Diff("conv-2", path); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another conversation, got %v", err)
+	}
+}
+
+func TestStore_NewAndDeletedFiles(t *testing.T) {
+	store, work := setupTestStore(t)
+	created := filepath.Join(work, "new.txt")
+	deleted := filepath.Join(work, "old.txt")
+	os.WriteFile(deleted, []byte("bye\n"), 0644)
+
+	store.Record("conv", created)
+	os.WriteFile(created, []byte("hi\n"), 0644)
+	store.Record("conv", deleted)
+	os.Remove(deleted)
+
+	d, err := store.Diff("conv", created)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if d.Existed || !strings.HasPrefix(d.Unified, "--- /dev/null\n") {
+		t.Errorf("Expected a diff for a new file, got %+v", d)
+	}
+
+	d, err = store.Diff("conv", deleted)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if d.Exists || d.Before != "bye\n" || !strings.Contains(d.Unified, "+++ /dev/null\n") {
+		t.Errorf("Expected a diff for a deleted file, got %+v", d)
+	}
+
+	entries, err := store.List("conv")
+	if err != nil || len(entries) != 2 || entries[0].Path != created {
+		t.Errorf("Expected both files listed in path order, got %+v, %v", entries, err)
+	}
+
+	if err := store.Delete("conv"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if entries, _ := store.List("conv"); len(entries) != 0 {
+		t.Errorf("Expected no snapshots after Delete, got %d", len(entries))
+	}
+}
+
+func TestStore_BinaryFiles(t *testing.T) {
+	store, work := setupTestStore(t)
+	path := filepath.Join(work, "image.bin")
+	os.WriteFile(path, []byte{0x89, 0, 1, 2}, 0644)
+
+	store.Record("conv", path)
+	os.WriteFile(path, []byte{0x89, 0, 3}, 0644)
+
+	d, err := store.Diff("conv", path)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !d.Binary || d.Before != "" || d.Unified != "" {
+		t.Errorf("Expected a binary diff without contents, got %+v", d)
+	}
+}
+
+func TestStore_DiffLargeFile(t *testing.T) {
+	store, work := setupTestStore(t)
+	path := filepath.Join(work, "big.log")
+	var before strings.Builder
+	for i := 0; before.Len() <= InlineSize; i++ {
+		fmt.Fprintf(&before, "entry %d\n", i)
+	}
+	os.WriteFile(path, []byte(before.String()), 0644)
+	store.Record("conv", path)
+	os.WriteFile(path, []byte(strings.Replace(before.String(), "entry 50000\n", "entry 50000 (edited)\n", 1)), 0644)
+
+	d, err := store.Diff("conv", path)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !d.Omitted || d.Cut || d.Before != "" || d.After != "" {
+		t.Errorf("Expected only the diff of a large file, got omitted %v, cut %v, %d and %d bytes of contents", d.Omitted, d.Cut, len(d.Before), len(d.After))
+	}
+	if !strings.Contains(d.Unified, "\n-entry 50000\n+entry 50000 (edited)\n") || strings.Count(d.Unified, "\n@@ ") != 1 {
+		t.Errorf("Unexpected unified diff:\n%s", d.Unified)
+	}
+}
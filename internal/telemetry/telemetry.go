@@ -0,0 +1,320 @@
+// Package telemetry counts which features, tools, and providers are used
+// and which crashes happen, and reports the counts to a configured endpoint
+// once a day, to guide what to work on. It is off unless the user turns it
+// on. Reports hold counts and crash signatures only: never prompts, paths,
+// file contents, or tool output, and no identifier of the user or install.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"agent-desktop/internal/crash"
+)
+
+// interval is how often a report is sent.
+const interval = 24 * time.Hour
+
+// maxFrames is how many stack frames of a crash are reported.
+const maxFrames = 8
+
+// Report is everything sent in one report. GetTelemetryPreview shows it
+// to the user before it is sent.
+type Report struct {
+	AppVersion string         `json:"app_version"`
+	OS         string         `json:"os"`
+	Arch       string         `json:"arch"`
+	Since      string         `json:"since"`    // Day counting started, YYYY-MM-DD
+	Features   map[string]int `json:"features"` // Uses of each feature, tool, and provider kind
+	Crashes    []Crash        `json:"crashes"`  // Most frequent first
+}
+
+// Crash is a kind of crash and how often it happened. Only the names of
+// the functions on the stack are kept, not the panic message, which can
+// hold user data.
+type Crash struct {
+	Signature string   `json:"signature"` // Hash of the frames
+	Frames    []string `json:"frames"`    // Innermost first
+	Count     int      `json:"count"`
+}
+
+// state is what is saved between runs of the app.
+type state struct {
+	Since    time.Time         `json:"since"`
+	LastSent time.Time         `json:"last_sent,omitempty"`
+	Features map[string]int    `json:"features"`
+	Crashes  map[string]*Crash `json:"crashes"`
+}
+
+// Recorder counts usage while telemetry is enabled. A nil Recorder records
+// nothing.
+type Recorder struct {
+	mu      sync.Mutex
+	path    string
+	enabled bool
+	state   state
+}
+
+// now returns the current time. It is a variable so tests can fix it.
+var now = time.Now
+
+// Open loads the counts saved at path. The recorder starts disabled and is
+// usable even when the file can't be read, in which case it starts empty
+// and the error says why.
+func Open(path string) (*Recorder, error) {
+	r := &Recorder{path: path}
+	r.clear()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return r, err
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return r, fmt.Errorf("failed to read telemetry counts: %w", err)
+	}
+	if s.Features != nil {
+		r.state.Features = s.Features
+	}
+	if s.Crashes != nil {
+		r.state.Crashes = s.Crashes
+	}
+	r.state.Since, r.state.LastSent = s.Since, s.LastSent
+	return r, nil
+}
+
+// clear starts counting afresh (caller must hold lock).
+func (r *Recorder) clear() {
+	r.state.Since = now()
+	r.state.Features = map[string]int{}
+	r.state.Crashes = map[string]*Crash{}
+}
+
+// SetEnabled turns counting on or off. Turning it off forgets the counts
+// not yet sent and removes the saved file.
+func (r *Recorder) SetEnabled(on bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enabled = on
+	if !on {
+		r.clear()
+		if r.path != "" {
+			os.Remove(r.path)
+		}
+	}
+}
+
+// Count counts one use of feature, e.g. "run" or "tool.read_file".
+func (r *Recorder) Count(feature string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.enabled {
+		r.state.Features[feature]++
+	}
+}
+
+// RecordCrash counts a crash by the functions on its stack.
+func (r *Recorder) RecordCrash(report *crash.Report) {
+	if r == nil {
+		return
+	}
+	frames := Frames(report.Stack)
+	sum := sha256.Sum256([]byte(strings.Join(frames, "\n")))
+	sig := hex.EncodeToString(sum[:8])
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.enabled {
+		return
+	}
+	c, ok := r.state.Crashes[sig]
+	if !ok {
+		c = &Crash{Signature: sig, Frames: frames}
+		r.state.Crashes[sig] = c
+	}
+	c.Count++
+}
+
+// Preview returns the report that would be sent now.
+func (r *Recorder) Preview(version string) Report {
+	rep := Report{
+		AppVersion: version,
+		OS:         goruntime.GOOS,
+		Arch:       goruntime.GOARCH,
+		Features:   map[string]int{},
+		Crashes:    []Crash{},
+	}
+	if r == nil {
+		return rep
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep.Since = r.state.Since.Format("2006-01-02")
+	for feature, n := range r.state.Features {
+		rep.Features[feature] = n
+	}
+	for _, c := range r.state.Crashes {
+		rep.Crashes = append(rep.Crashes, *c)
+	}
+	sort.Slice(rep.Crashes, func(i, j int) bool {
+		if rep.Crashes[i].Count != rep.Crashes[j].Count {
+			return rep.Crashes[i].Count > rep.Crashes[j].Count
+		}
+		return rep.Crashes[i].Signature < rep.Crashes[j].Signature
+	})
+	return rep
+}
+
+// Due reports whether a day has passed since the last report, or since
+// counting started if none was sent.
+func (r *Recorder) Due() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := r.state.LastSent
+	if last.IsZero() {
+		last = r.state.Since
+	}
+	return r.enabled && now().Sub(last) >= interval
+}
+
+// Send posts the report to endpoint and starts counting afresh. If the
+// report can't be sent, the counts are kept for the next try.
+func (r *Recorder) Send(ctx context.Context, endpoint, version string) error {
+	if r == nil {
+		return nil
+	}
+	rep := r.Preview(version)
+	body, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("telemetry endpoint returned status %d", resp.StatusCode)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Keep what was counted while the report was being sent
+	for feature, n := range rep.Features {
+		if r.state.Features[feature] -= n; r.state.Features[feature] <= 0 {
+			delete(r.state.Features, feature)
+		}
+	}
+	for _, c := range rep.Crashes {
+		if kept := r.state.Crashes[c.Signature]; kept != nil {
+			if kept.Count -= c.Count; kept.Count <= 0 {
+				delete(r.state.Crashes, c.Signature)
+			}
+		}
+	}
+	r.state.Since = now()
+	r.state.LastSent = r.state.Since
+	return r.save()
+}
+
+// Save writes the counts so they survive a restart.
+func (r *Recorder) Save() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save()
+}
+
+// save writes the counts, if enabled (caller must hold lock).
+func (r *Recorder) save() error {
+	if r.path == "" || !r.enabled {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0644)
+}
+
+// Frames returns the names of the functions on a goroutine stack as
+// printed by runtime/debug.Stack, innermost first, leaving out file paths,
+// arguments, and the frames of the panic machinery itself.
+func Frames(stack string) []string {
+	var frames []string
+	for _, line := range strings.Split(stack, "\n") {
+		if line == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "goroutine ") || strings.HasPrefix(line, "created by ") {
+			continue
+		}
+		name := line
+		if i := strings.LastIndex(name, "("); i > 0 {
+			name = name[:i]
+		}
+		if strings.HasPrefix(name, "runtime/debug.") || name == "panic" || strings.HasPrefix(name, "runtime.") ||
+			strings.HasPrefix(name, "agent-desktop/internal/crash.") {
+			continue
+		}
+		frames = append(frames, name)
+		if len(frames) == maxFrames {
+			break
+		}
+	}
+	return frames
+}
+
+// ProviderKind names the kind of provider an endpoint belongs to without
+// revealing the endpoint: "openai", "openrouter", "anthropic", "local", or
+// "other".
+func ProviderKind(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "other"
+	}
+	host := u.Hostname()
+	switch {
+	case host == "api.openai.com":
+		return "openai"
+	case host == "openrouter.ai" || strings.HasSuffix(host, ".openrouter.ai"):
+		return "openrouter"
+	case host == "api.anthropic.com":
+		return "anthropic"
+	case host == "localhost" || host == "127.0.0.1" || host == "::1":
+		return "local"
+	}
+	return "other"
+}
@@ -0,0 +1,169 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/crash"
+)
+
+// fixNow sets the current time for the rest of the test.
+func fixNow(t *testing.T, at time.Time) {
+	t.Helper()
+	orig := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = orig })
+}
+
+const stack = `goroutine 42 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:26 +0x5e
+agent-desktop/internal/crash.Capture({0x1, 0xc}, {0x2, 0x3})
+	/home/alice/src/agent-desktop/internal/crash/crash.go:84 +0x65
+agent-desktop/internal/crash.Recover({0x1, 0xc}, 0xc000010000)
+	/home/alice/src/agent-desktop/internal/crash/crash.go:69 +0x4b
+panic({0x4, 0x5})
+	/usr/local/go/src/runtime/panic.go:785 +0x132
+agent-desktop/internal/tools.(*ShellSession).GetCWD(...)
+	/home/alice/src/agent-desktop/internal/tools/types.go:173
+agent-desktop/internal/tools.executeTool({0x6, 0x7}, 0xc000020000, 0x0)
+	/home/alice/src/agent-desktop/internal/tools/dispatcher.go:300 +0x1a
+created by agent-desktop/internal/agent.RunLoop in goroutine 1
+	/home/alice/src/agent-desktop/internal/agent/loop.go:33 +0x2c
+`
+
+func TestFrames(t *testing.T) {
+	got := Frames(stack)
+	want := []string{"agent-desktop/internal/tools.(*ShellSession).GetCWD", "agent-desktop/internal/tools.executeTool"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Frames() = %q, want %q", got, want)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	day := time.Date(2025, 5, 1, 9, 0, 0, 0, time.Local)
+	fixNow(t, day)
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	// Nothing is counted until the user opts in
+	r.Count("run")
+	r.RecordCrash(&crash.Report{Stack: stack, Panic: "secret /home/alice/notes.txt"})
+	if p := r.Preview("1.2.0"); len(p.Features) != 0 || len(p.Crashes) != 0 {
+		t.Errorf("counted while disabled: %+v", p)
+	}
+
+	r.SetEnabled(true)
+	r.Count("run")
+	r.Count("tool.read_file")
+	r.Count("tool.read_file")
+	r.RecordCrash(&crash.Report{Stack: stack, Panic: "secret /home/alice/notes.txt"})
+	r.RecordCrash(&crash.Report{Stack: stack, Panic: "another message"})
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Counts survive a restart
+	r, _ = Open(path)
+	r.SetEnabled(true)
+	p := r.Preview("1.2.0")
+	if p.AppVersion != "1.2.0" || p.Since != "2025-05-01" || p.Features["run"] != 1 || p.Features["tool.read_file"] != 2 {
+		t.Errorf("preview = %+v", p)
+	}
+	if len(p.Crashes) != 1 || p.Crashes[0].Count != 2 || len(p.Crashes[0].Frames) != 2 || len(p.Crashes[0].Signature) != 16 {
+		t.Errorf("crashes = %+v", p.Crashes)
+	}
+	data, _ := json.Marshal(p)
+	if strings.Contains(string(data), "alice") || strings.Contains(string(data), "secret") {
+		t.Errorf("report leaks user data: %s", data)
+	}
+
+	if r.Due() {
+		t.Error("report due before a day has passed")
+	}
+	fixNow(t, day.Add(25*time.Hour))
+	if !r.Due() {
+		t.Error("report not due after a day")
+	}
+
+	// Opting out forgets everything
+	r.SetEnabled(false)
+	if p := r.Preview("1.2.0"); len(p.Features) != 0 || len(p.Crashes) != 0 {
+		t.Errorf("counts kept after opting out: %+v", p)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("saved counts kept after opting out: %v", err)
+	}
+	if r.Due() {
+		t.Error("report due while disabled")
+	}
+}
+
+func TestRecorder_Send(t *testing.T) {
+	day := time.Date(2025, 5, 1, 9, 0, 0, 0, time.Local)
+	fixNow(t, day)
+
+	var received Report
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	r, _ := Open(filepath.Join(t.TempDir(), "telemetry.json"))
+	r.SetEnabled(true)
+	r.Count("run")
+
+	// A failed report keeps the counts for the next try
+	if err := r.Send(context.Background(), server.URL, "1.2.0"); err == nil {
+		t.Error("expected an error for a server error")
+	}
+	if r.Preview("1.2.0").Features["run"] != 1 {
+		t.Error("counts lost after a failed report")
+	}
+
+	status = http.StatusNoContent
+	fixNow(t, day.Add(25*time.Hour))
+	if err := r.Send(context.Background(), server.URL, "1.2.0"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if received.Features["run"] != 1 || received.AppVersion != "1.2.0" {
+		t.Errorf("server received %+v", received)
+	}
+	if p := r.Preview("1.2.0"); len(p.Features) != 0 || p.Since != "2025-05-02" {
+		t.Errorf("preview after sending = %+v", p)
+	}
+	if r.Due() {
+		t.Error("report due right after sending")
+	}
+}
+
+func TestProviderKind(t *testing.T) {
+	tests := map[string]string{
+		"https://api.openai.com/v1":           "openai",
+		"https://openrouter.ai/api/v1":        "openrouter",
+		"http://localhost:1234/v1":            "local",
+		"http://127.0.0.1:11434/v1":           "local",
+		"https://llm.internal.example.com/v1": "other",
+		"::not a url":                         "other",
+	}
+	for endpoint, want := range tests {
+		if got := ProviderKind(endpoint); got != want {
+			t.Errorf("ProviderKind(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	approvalMu   sync.Mutex
+	approvalHook func(callID, name string, args map[string]interface{}) (bool, string)
+)
+
+// SetApprovalHook sets a function that decides whether a tool call made
+// through ExecuteToolCall may run. It returns false and the reason to
+// refuse a call, and may block while a person decides. nil removes the
+// hook, so every call runs except those of tools that require approval.
+func SetApprovalHook(fn func(callID, name string, args map[string]interface{}) (bool, string)) {
+	approvalMu.Lock()
+	approvalHook = fn
+	approvalMu.Unlock()
+}
+
+// approve asks the approval hook, if any, about a tool call.
+func approve(callID, name string, args map[string]interface{}) (bool, string) {
+	approvalMu.Lock()
+	fn := approvalHook
+	approvalMu.Unlock()
+	if fn == nil {
+		if RequiresApproval(name) {
+			return false, name + " needs the user's approval, and there is no one to ask"
+		}
+		return true, ""
+	}
+	return fn(callID, name, args)
+}
+
+// IsDestructive reports whether a tool call can lose data: commands, which
+// can do anything, deletions and moves, writes or copies onto an existing
+// file, and bulk copies allowed to overwrite.
+func IsDestructive(name string, args map[string]interface{}) bool {
+	exists := func(key string) bool {
+		path, _ := args[key].(string)
+		if path == "" {
+			return false
+		}
+		_, err := os.Stat(ExpandPath(path, GetSession().GetCWD()))
+		return err == nil
+	}
+
+	switch name {
+	case "run_command", "delete_file", "move_file", "move_files":
+		return true
+	case "write_file":
+		appendMode, _ := args["append"].(bool)
+		return !appendMode && exists("path")
+	case "copy_file":
+		return exists("destination")
+	case "copy_files":
+		overwrite, _ := args["overwrite"].(bool)
+		return overwrite
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApprovalHook(t *testing.T) {
+	defer SetApprovalHook(nil)
+	path := filepath.Join(t.TempDir(), "kept.txt")
+
+	var asked []string
+	SetApprovalHook(func(callID, name string, args map[string]interface{}) (bool, string) {
+		asked = append(asked, callID+":"+name)
+		return false, "Denied in chat"
+	})
+	result := ExecuteToolCall("call-1", "write_file", map[string]interface{}{"path": path, "content": "x"})
+	if result.Success || result.Error != "Denied in chat" || result.Kind != KindNotApproved {
+		t.Errorf("result = %+v, want the refusal", result)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("expected the refused call not to run")
+	}
+	if len(asked) != 1 || asked[0] != "call-1:write_file" {
+		t.Errorf("asked = %v", asked)
+	}
+
+	SetApprovalHook(func(string, string, map[string]interface{}) (bool, string) { return true, "" })
+	if result := ExecuteToolCall("call-2", "write_file", map[string]interface{}{"path": path, "content": "x"}); !result.Success {
+		t.Errorf("approved call failed: %s", result.Error)
+	}
+}
+
+func TestIsDestructive(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	os.WriteFile(existing, []byte("data"), 0644)
+	missing := filepath.Join(dir, "new.txt")
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want bool
+	}{
+		{"run_command", map[string]interface{}{"command": "ls"}, true},
+		{"delete_file", map[string]interface{}{"path": existing}, true},
+		{"move_file", map[string]interface{}{"source": existing, "destination": missing}, true},
+		{"write_file", map[string]interface{}{"path": existing}, true},
+		{"write_file", map[string]interface{}{"path": existing, "append": true}, false},
+		{"write_file", map[string]interface{}{"path": missing}, false},
+		{"copy_file", map[string]interface{}{"source": existing, "destination": missing}, false},
+		{"copy_file", map[string]interface{}{"source": missing, "destination": existing}, true},
+		{"move_files", map[string]interface{}{"sources": []interface{}{existing}, "destination": dir}, true},
+		{"copy_files", map[string]interface{}{"sources": []interface{}{existing}, "destination": dir}, false},
+		{"copy_files", map[string]interface{}{"sources": []interface{}{existing}, "destination": dir, "overwrite": true}, true},
+		{"read_file", map[string]interface{}{"path": existing}, false},
+	}
+	for _, tt := range tests {
+		if got := IsDestructive(tt.name, tt.args); got != tt.want {
+			t.Errorf("IsDestructive(%s, %v) = %v, want %v", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestApprovalHook_RequiredApproval(t *testing.T) {
+	defer SetApprovalHook(nil)
+	defer SetExternalTools("test", nil)
+	SetExternalTools("test", []ExternalTool{{
+		Definition:      ToolDefinition{Type: "function", Function: ToolFunction{Name: "send_note"}},
+		Execute:         func(map[string]interface{}) ToolResult { return ToolResult{Success: true, Output: "sent"} },
+		RequireApproval: true,
+	}})
+
+	if !RequiresApproval("send_note") || RequiresApproval("write_file") {
+		t.Error("RequiresApproval should only be true for send_note")
+	}
+	if result := ExecuteToolCall("call-1", "send_note", nil); result.Success {
+		t.Error("expected the call to be refused without an approval hook")
+	}
+	SetApprovalHook(func(string, string, map[string]interface{}) (bool, string) { return true, "" })
+	if result := ExecuteToolCall("call-2", "send_note", nil); !result.Success {
+		t.Errorf("approved call failed: %s", result.Error)
+	}
+}
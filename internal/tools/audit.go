@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MaxAuditLogBytes is the size at which the audit log is rotated: the log
+// is renamed with a ".1" suffix, replacing the previous one, and a new log
+// is started.
+const MaxAuditLogBytes = 20 << 20
+
+var (
+	auditMu   sync.Mutex
+	auditPath string // "" = commands aren't audited
+)
+
+// SetAuditLog sets the file every command the sessions run is appended to
+// as a JSON line, keeping the full history that sessions cut at the
+// history limit. Empty stops auditing.
+func SetAuditLog(path string) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditPath = path
+}
+
+// audit appends record to the audit log, if one is set.
+func audit(record CommandRecord) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditPath == "" {
+		return
+	}
+	if record.StartedAt.IsZero() {
+		record.StartedAt = time.Now()
+	}
+	if err := appendAudit(auditPath, record); err != nil {
+		logger.Warn("failed to write command audit log", "path", auditPath, "error", err)
+	}
+}
+
+// appendAudit appends record to the audit log at path, rotating it first
+// if it is full (caller must hold auditMu).
+func appendAudit(path string, record CommandRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(data)) >= MaxAuditLogBytes {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadAuditLog returns up to the last n commands in the audit log, oldest
+// first, including those in the rotated log. n <= 0 returns them all.
+func ReadAuditLog(n int) ([]CommandRecord, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	records := []CommandRecord{}
+	if auditPath == "" {
+		return records, nil
+	}
+	for _, path := range []string{auditPath + ".1", auditPath} {
+		file, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+		for scanner.Scan() {
+			var record CommandRecord
+			if json.Unmarshal(scanner.Bytes(), &record) == nil {
+				records = append(records, record)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if n > 0 && len(records) > n {
+		records = records[len(records)-n:]
+	}
+	return records, nil
+}
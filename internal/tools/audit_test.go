@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "commands.jsonl")
+	SetAuditLog(path)
+	defer SetAuditLog("")
+	SetHistoryLimit(2)
+	defer SetHistoryLimit(0)
+
+	session := NewShellSession()
+	for i := range 4 {
+		session.RecordCommand(fmt.Sprintf("cmd %d", i), i)
+	}
+
+	records, err := ReadAuditLog(0)
+	if err != nil {
+		t.Fatalf("ReadAuditLog() error = %v", err)
+	}
+	if len(records) != 4 || records[0].Command != "cmd 0" || records[3].ExitCode != 3 || records[0].StartedAt.IsZero() {
+		t.Errorf("ReadAuditLog() = %+v, want all 4 commands", records)
+	}
+	if records, _ := ReadAuditLog(1); len(records) != 1 || records[0].Command != "cmd 3" {
+		t.Errorf("ReadAuditLog(1) = %+v, want the last command", records)
+	}
+
+	// A full log is rotated, and read along with the new one
+	if err := os.WriteFile(path, []byte(strings.Repeat("not a record\n", MaxAuditLogBytes/13+1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	session.RecordCommand("after rotation", 0)
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("audit log wasn't rotated: %v", err)
+	}
+	if records, _ := ReadAuditLog(0); len(records) != 1 || records[0].Command != "after rotation" {
+		t.Errorf("ReadAuditLog() after rotation = %+v", records)
+	}
+}
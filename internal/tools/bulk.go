@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"agent-desktop/internal/i18n"
+)
+
+const (
+	// MaxBulkFiles is the most files one copy_files or move_files call
+	// works on.
+	MaxBulkFiles = 10000
+
+	bulkWorkers = 8  // How many files a bulk call works on at once
+	bulkListed  = 50 // How many successful files a bulk result lists by name
+)
+
+// FileOp is one file of a bulk copy or move.
+type FileOp struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// fileResult is the outcome of one FileOp.
+type fileResult struct {
+	op      FileOp
+	skipped bool   // The destination existed and overwrite wasn't set
+	err     string // "" = done
+}
+
+// GlobFiles expands sources, each a path or a glob pattern as understood by
+// filepath.Match, into one FileOp per file, going into the folder
+// destination under its own name. Folders matched by a pattern are left
+// out; a source that matches nothing is returned as an error.
+func GlobFiles(sources []string, destination string) ([]FileOp, error) {
+	cwd := GetSession().GetCWD()
+	dstDir := ExpandPath(destination, cwd)
+	var ops []FileOp
+	for _, source := range sources {
+		pattern := ExpandPath(source, cwd)
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.New(i18n.T("file.bulk_bad_pattern", source, err))
+		}
+		if len(matches) == 0 {
+			return nil, errors.New(i18n.T("file.bulk_no_match", source))
+		}
+		isPattern := strings.ContainsAny(pattern, "*?[")
+		for _, match := range matches {
+			if isPattern {
+				if info, err := os.Stat(match); err == nil && info.IsDir() {
+					continue
+				}
+			}
+			ops = append(ops, FileOp{Source: match, Destination: filepath.Join(dstDir, filepath.Base(match))})
+		}
+	}
+	return ops, nil
+}
+
+// CopyFiles copies each file to its destination, several at a time. A file
+// whose destination exists is skipped unless overwrite is set. The result
+// lists what happened to each file and fails if any file failed.
+func CopyFiles(ops []FileOp, overwrite bool) ToolResult {
+	return bulkFiles(ops, overwrite, false, nil)
+}
+
+// MoveFiles is CopyFiles for moving files.
+func MoveFiles(ops []FileOp, overwrite bool) ToolResult {
+	return bulkFiles(ops, overwrite, true, nil)
+}
+
+// bulkFiles copies or moves files with a pool of workers, reporting the
+// files done to progress.
+func bulkFiles(ops []FileOp, overwrite, move bool, progress *progressReporter) ToolResult {
+	if len(ops) == 0 {
+		return ToolResult{Success: false, Error: i18n.T("file.bulk_no_files")}
+	}
+	if len(ops) > MaxBulkFiles {
+		return ToolResult{Success: false, Error: i18n.T("file.bulk_too_many", len(ops), MaxBulkFiles)}
+	}
+
+	// Resolve paths once, so the workers don't race on the session
+	cwd := GetSession().GetCWD()
+	results := make([]fileResult, len(ops))
+	seen := make(map[string]bool, len(ops))
+	var queue []int
+	for i, op := range ops {
+		op = FileOp{Source: ExpandPath(op.Source, cwd), Destination: ExpandPath(op.Destination, cwd)}
+		results[i].op = op
+		if seen[op.Destination] {
+			results[i].err = i18n.T("file.bulk_duplicate", op.Destination)
+			continue
+		}
+		seen[op.Destination] = true
+		queue = append(queue, i)
+	}
+
+	work := make(chan int)
+	finished := make(chan struct{})
+	var wg sync.WaitGroup
+	for range min(bulkWorkers, len(queue)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = bulkFile(results[i].op, overwrite, move)
+				finished <- struct{}{}
+			}
+		}()
+	}
+	go func() {
+		for _, i := range queue {
+			work <- i
+		}
+		close(work)
+		wg.Wait()
+		close(finished)
+	}()
+	done := int64(len(ops) - len(queue))
+	progress.report(done, int64(len(ops)))
+	for range finished {
+		done++
+		progress.report(done, int64(len(ops)))
+	}
+
+	return bulkResult(results, move)
+}
+
+// bulkArgs reads the files of a copy_files or move_files call: the pairs
+// in files, then those matched by sources going into destination.
+func bulkArgs(name string, args map[string]interface{}) ([]FileOp, error) {
+	var ops []FileOp
+	if files, ok := args["files"].([]interface{}); ok {
+		for _, f := range files {
+			pair, _ := f.(map[string]interface{})
+			source, _ := pair["source"].(string)
+			destination, _ := pair["destination"].(string)
+			if source == "" || destination == "" {
+				return nil, errors.New(i18n.T("file.bulk_bad_pair", name))
+			}
+			ops = append(ops, FileOp{Source: source, Destination: destination})
+		}
+	}
+	if raw, ok := args["sources"].([]interface{}); ok && len(raw) > 0 {
+		destination, ok := args["destination"].(string)
+		if !ok {
+			return nil, errors.New(i18n.T("tool.missing_argument", name, "destination"))
+		}
+		var sources []string
+		for _, s := range raw {
+			if s, ok := s.(string); ok {
+				sources = append(sources, s)
+			}
+		}
+		matched, err := GlobFiles(sources, destination)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, matched...)
+	}
+	return ops, nil
+}
+
+// bulkFile copies or moves one file.
+func bulkFile(op FileOp, overwrite, move bool) fileResult {
+	if !overwrite {
+		if _, err := os.Lstat(op.Destination); err == nil {
+			return fileResult{op: op, skipped: true}
+		}
+	}
+	var result ToolResult
+	if move {
+		result = MoveFile(op.Source, op.Destination)
+	} else {
+		result = copyFile(op.Source, op.Destination, nil)
+	}
+	return fileResult{op: op, err: result.Error}
+}
+
+// bulkResult summarizes the outcome of a bulk call: a count, then every
+// file skipped or failed, then the files done, up to bulkListed of them.
+func bulkResult(results []fileResult, move bool) ToolResult {
+	var problems, listed []string
+	done, skipped, failed := 0, 0, 0
+	for _, r := range results {
+		switch {
+		case r.err != "":
+			failed++
+			problems = append(problems, i18n.T("file.bulk_failed", r.op.Source, r.op.Destination, r.err))
+		case r.skipped:
+			skipped++
+			problems = append(problems, i18n.T("file.bulk_skipped", r.op.Source, r.op.Destination))
+		default:
+			done++
+			if len(listed) < bulkListed {
+				key := "file.copied"
+				if move {
+					key = "file.moved"
+				}
+				listed = append(listed, i18n.T(key, r.op.Source, r.op.Destination))
+			}
+		}
+	}
+	if done > len(listed) {
+		listed = append(listed, i18n.T("file.bulk_more", done-len(listed)))
+	}
+
+	key := "file.bulk_copied"
+	if move {
+		key = "file.bulk_moved"
+	}
+	summary := i18n.T(key, done, len(results), skipped, failed)
+	output := strings.Join(append(append([]string{summary}, problems...), listed...), "\n")
+	if failed > 0 {
+		return ToolResult{Success: false, Output: output, Error: summary}
+	}
+	return ToolResult{Success: true, Output: output}
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecuteTool_MoveFiles(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 100 {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("photo%03d.jpg", i)), []byte{byte(i)}, 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0644)
+	os.Mkdir(filepath.Join(dir, "album.jpg"), 0755)
+
+	var updates []Progress
+	SetProgressHook(func(p Progress) { updates = append(updates, p) })
+	defer SetProgressHook(nil)
+
+	result := ExecuteToolCall("call_1", "move_files", map[string]interface{}{
+		"sources":     []interface{}{filepath.Join(dir, "*.jpg")},
+		"destination": filepath.Join(dir, "sorted"),
+	})
+	if !result.Success {
+		t.Fatalf("move_files failed: %s\n%s", result.Error, result.Output)
+	}
+	if !strings.HasPrefix(result.Output, "Moved 100 of 100 files (0 skipped, 0 failed)\n") || !strings.HasSuffix(result.Output, "... and 50 more") {
+		t.Errorf("Output = %q", result.Output)
+	}
+	moved, _ := filepath.Glob(filepath.Join(dir, "sorted", "*.jpg"))
+	left, _ := filepath.Glob(filepath.Join(dir, "photo*.jpg"))
+	if len(moved) != 100 || len(left) != 0 {
+		t.Errorf("moved %d files and left %d, want 100 and 0", len(moved), len(left))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "album.jpg")); err != nil {
+		t.Error("a folder matched by the pattern was moved")
+	}
+	if last := updates[len(updates)-1]; last.Done != 100 || last.Total != 100 {
+		t.Errorf("last progress update = %+v, want 100 of 100 files", last)
+	}
+}
+
+func TestExecuteTool_CopyFilesPairs(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("a"), 0644)
+	os.WriteFile(b, []byte("b"), 0644)
+	existing := filepath.Join(dir, "2024", "b.txt")
+	os.MkdirAll(filepath.Dir(existing), 0755)
+	os.WriteFile(existing, []byte("old"), 0644)
+
+	files := []interface{}{
+		map[string]interface{}{"source": a, "destination": filepath.Join(dir, "2023", "a.txt")},
+		map[string]interface{}{"source": b, "destination": existing},
+		map[string]interface{}{"source": filepath.Join(dir, "missing.txt"), "destination": filepath.Join(dir, "2023", "missing.txt")},
+		map[string]interface{}{"source": b, "destination": filepath.Join(dir, "2023", "a.txt")},
+	}
+	result := ExecuteTool("copy_files", map[string]interface{}{"files": files})
+	if result.Success {
+		t.Fatal("copy_files succeeded with failing files")
+	}
+	if result.Error != "Copied 1 of 4 files (1 skipped, 2 failed)" {
+		t.Errorf("Error = %q", result.Error)
+	}
+	for _, want := range []string{"Skipped, the destination exists: " + b, "Failed: " + filepath.Join(dir, "missing.txt"), "More than one file would go to"} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("Output is missing %q:\n%s", want, result.Output)
+		}
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "old" {
+		t.Errorf("existing file = %q, want it left alone", data)
+	}
+
+	result = ExecuteTool("copy_files", map[string]interface{}{"files": files[1:2], "overwrite": true})
+	if data, _ := os.ReadFile(existing); !result.Success || string(data) != "b" {
+		t.Errorf("overwrite: %v %q, existing file = %q", result.Success, result.Error, data)
+	}
+}
+
+func TestExecuteTool_BulkArgs(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range []map[string]interface{}{
+		{},
+		{"sources": []interface{}{filepath.Join(dir, "*.none")}, "destination": dir},
+		{"sources": []interface{}{filepath.Join(dir, "[")}, "destination": dir},
+		{"sources": []interface{}{dir}},
+		{"files": []interface{}{map[string]interface{}{"source": "a.txt"}}},
+	} {
+		if result := ExecuteTool("copy_files", args); result.Success || result.Error == "" {
+			t.Errorf("copy_files(%v) = %+v, want an error", args, result)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,6 +11,9 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"agent-desktop/internal/elevate"
+	"agent-desktop/internal/i18n"
 )
 
 // RunCommand executes a shell command and returns the output.
@@ -17,7 +22,7 @@ func RunCommand(command string, workingDir string, timeout int) ToolResult {
 	// Check command safety first
 	safe, reason := CheckCommandSafety(command)
 	if !safe {
-		return ToolResult{Success: false, Error: reason}
+		return ToolResult{Success: false, Error: reason, Kind: KindSafetyBlock}
 	}
 
 	session := GetSession()
@@ -28,6 +33,13 @@ func RunCommand(command string, workingDir string, timeout int) ToolResult {
 		cwd = ExpandPath(workingDir, session.CWD)
 	}
 
+	// Installers that need administrator rights go through the system prompt
+	if plan, ok := elevate.Detect(command); ok && elevationEnabled {
+		if result, ok := runElevated(session, command, plan, cwd, timeout); ok {
+			return result
+		}
+	}
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	defer cancel()
@@ -49,8 +61,19 @@ func RunCommand(command string, workingDir string, timeout int) ToolResult {
 	}
 	cmd.Env = env
 
-	// Run command and capture output
-	output, err := cmd.CombinedOutput()
+	// Run command and capture output, tracking it while it runs
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	started := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		pid := cmd.Process.Pid
+		session.startRunning(RunningCommand{PID: pid, Command: command, CWD: cwd, StartedAt: started})
+		err = cmd.Wait()
+		session.finishRunning(pid)
+	}
+	output := buf.Bytes()
 
 	// Record in history
 	exitCode := 0
@@ -61,14 +84,21 @@ func RunCommand(command string, workingDir string, timeout int) ToolResult {
 			exitCode = -1
 		}
 	}
-	session.RecordCommand(command, exitCode)
+	session.recordRun(CommandRecord{
+		Command:    command,
+		CWD:        cwd,
+		ExitCode:   exitCode,
+		StartedAt:  started,
+		DurationMS: time.Since(started).Milliseconds(),
+	})
 
 	// Check for timeout
 	if ctx.Err() == context.DeadlineExceeded {
 		return ToolResult{
 			Success: false,
 			Output:  string(output),
-			Error:   fmt.Sprintf("Command timed out after %d seconds", timeout),
+			Error:   i18n.T("command.timed_out", timeout),
+			Kind:    KindTimeout,
 		}
 	}
 
@@ -77,7 +107,7 @@ func RunCommand(command string, workingDir string, timeout int) ToolResult {
 		return ToolResult{
 			Success: false,
 			Output:  string(output),
-			Error:   fmt.Sprintf("Command failed with exit code %d: %s", exitCode, err.Error()),
+			Error:   i18n.T("command.failed", exitCode, err.Error()),
 		}
 	}
 
@@ -87,6 +117,62 @@ func RunCommand(command string, workingDir string, timeout int) ToolResult {
 	}
 }
 
+// elevationEnabled routes installer commands that need administrator
+// rights through the operating system's elevation prompt.
+var elevationEnabled bool
+
+// elevationPromptTime is extra time given to elevated commands for the user
+// to answer the prompt.
+const elevationPromptTime = 5 * time.Minute
+
+// elevateRun runs an elevated command. It is a variable so tests can stub
+// the system prompt.
+var elevateRun = elevate.Run
+
+// SetElevation turns routing installer commands through the system's
+// administrator prompt on or off.
+func SetElevation(enabled bool) {
+	elevationEnabled = enabled
+}
+
+// runElevated runs an installer command with administrator rights. It
+// returns false if elevation isn't supported here, so the command runs
+// normally.
+func runElevated(session *ShellSession, command string, plan elevate.Plan, cwd string, timeout int) (ToolResult, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second+elevationPromptTime)
+	defer cancel()
+
+	started := time.Now()
+	res, err := elevateRun(ctx, plan, cwd)
+	if errors.Is(err, elevate.ErrUnsupported) {
+		return ToolResult{}, false
+	}
+	exitCode := res.ExitCode
+	if err != nil {
+		exitCode = -1
+	}
+	session.recordRun(CommandRecord{
+		Command:    command,
+		CWD:        cwd,
+		ExitCode:   exitCode,
+		StartedAt:  started,
+		DurationMS: time.Since(started).Milliseconds(),
+	})
+
+	output := strings.TrimRight(res.Output, "\r\n")
+	switch {
+	case errors.Is(err, elevate.ErrCancelled):
+		return ToolResult{Success: false, Error: i18n.T("command.elevation_cancelled")}, true
+	case ctx.Err() == context.DeadlineExceeded:
+		return ToolResult{Success: false, Output: output, Error: i18n.T("command.timed_out", timeout), Kind: KindTimeout}, true
+	case err != nil:
+		return ToolResult{Success: false, Error: err.Error()}, true
+	case res.ExitCode != 0:
+		return ToolResult{Success: false, Output: output, Error: i18n.T("command.elevated_failed", res.ExitCode)}, true
+	}
+	return ToolResult{Success: true, Output: strings.TrimSpace(i18n.T("command.elevated") + "\n\n" + output)}, true
+}
+
 // GetCurrentDirectory returns the current working directory of the session.
 func GetCurrentDirectory() ToolResult {
 	return ToolResult{
@@ -112,13 +198,13 @@ func ChangeDirectory(path string) ToolResult {
 	info, err := os.Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return ToolResult{Success: false, Error: fmt.Sprintf("Directory not found: %s", absPath)}
+			return ToolResult{Success: false, Error: i18n.T("directory.not_found", absPath)}
 		}
 		return ToolResult{Success: false, Error: err.Error()}
 	}
 
 	if !info.IsDir() {
-		return ToolResult{Success: false, Error: fmt.Sprintf("Not a directory: %s", absPath)}
+		return ToolResult{Success: false, Error: i18n.T("directory.not_a_directory", absPath)}
 	}
 
 	// Update session CWD
@@ -128,17 +214,17 @@ func ChangeDirectory(path string) ToolResult {
 
 	return ToolResult{
 		Success: true,
-		Output:  fmt.Sprintf("Changed directory to: %s", absPath),
+		Output:  i18n.T("directory.changed", absPath),
 	}
 }
 
 // TaskComplete signals that the agent has completed its task.
 // It returns a formatted summary of what was accomplished.
 func TaskComplete(summary string, filesModified []string) ToolResult {
-	output := fmt.Sprintf("✅ Task completed!\n\n%s", summary)
+	output := i18n.T("task.completed") + "\n\n" + summary
 
 	if len(filesModified) > 0 {
-		output += "\n\nFiles modified:\n"
+		output += "\n\n" + i18n.T("task.files_modified") + "\n"
 		for _, f := range filesModified {
 			output += fmt.Sprintf("  • %s\n", f)
 		}
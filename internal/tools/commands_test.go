@@ -1,11 +1,14 @@
 package tools
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+
+	"agent-desktop/internal/elevate"
 )
 
 func TestRunCommand_Success(t *testing.T) {
@@ -55,6 +58,9 @@ func TestRunCommand_Timeout(t *testing.T) {
 	if !strings.Contains(strings.ToLower(result.Error), "timed out") && !strings.Contains(strings.ToLower(result.Error), "timeout") {
 		t.Errorf("error should mention timeout, got: %q", result.Error)
 	}
+	if result.Kind != KindTimeout {
+		t.Errorf("Kind = %q, want %q", result.Kind, KindTimeout)
+	}
 }
 
 func TestRunCommand_BlockedCommand(t *testing.T) {
@@ -66,6 +72,9 @@ func TestRunCommand_BlockedCommand(t *testing.T) {
 	if !strings.Contains(strings.ToLower(result.Error), "blocked") {
 		t.Errorf("error should mention blocked, got: %q", result.Error)
 	}
+	if result.Kind != KindSafetyBlock {
+		t.Errorf("Kind = %q, want %q", result.Kind, KindSafetyBlock)
+	}
 }
 
 func TestRunCommand_WorkingDir(t *testing.T) {
@@ -182,3 +191,45 @@ func TestTaskComplete_NoFiles(t *testing.T) {
 		t.Error("output should contain summary")
 	}
 }
+
+func TestRunCommand_Elevated(t *testing.T) {
+	command := map[string]string{
+		"linux":   "sudo apt-get install -y jq",
+		"darwin":  "sudo installer -pkg jq.pkg -target /",
+		"windows": "choco install -y jq",
+	}[runtime.GOOS]
+	if command == "" {
+		t.Skip("no elevation on this system")
+	}
+
+	var ran elevate.Plan
+	elevateRun = func(ctx context.Context, plan elevate.Plan, dir string) (elevate.Result, error) {
+		ran = plan
+		return elevate.Result{Output: "jq installed\n"}, nil
+	}
+	SetElevation(true)
+	t.Cleanup(func() {
+		elevateRun = elevate.Run
+		SetElevation(false)
+	})
+	ResetSession()
+	defer ResetSession()
+
+	result := RunCommand(command, "", 10)
+	if !result.Success || !strings.Contains(result.Output, "jq installed") {
+		t.Fatalf("Expected the elevated command to succeed, got %+v", result)
+	}
+	if strings.HasPrefix(ran.Command, "sudo") {
+		t.Errorf("Expected sudo to be stripped, got %q", ran.Command)
+	}
+	if history := GetSession().Inspect().History; len(history) != 1 || history[0].Command != command {
+		t.Errorf("Expected the command in history, got %+v", history)
+	}
+
+	elevateRun = func(context.Context, elevate.Plan, string) (elevate.Result, error) {
+		return elevate.Result{}, elevate.ErrCancelled
+	}
+	if result := RunCommand(command, "", 10); result.Success || result.Error == "" {
+		t.Errorf("Expected a dismissed prompt to fail the call, got %+v", result)
+	}
+}
@@ -1,9 +1,17 @@
 package tools
 
 import (
-	"fmt"
+	"slices"
+	"time"
+
+	"agent-desktop/internal/crash"
+	"agent-desktop/internal/i18n"
+	"agent-desktop/internal/logging"
 )
 
+// logger records tool calls and problems the tools work around.
+var logger = logging.For("tools")
+
 // ToolFunction represents a function definition in OpenAI format.
 type ToolFunction struct {
 	Name        string                 `json:"name"`
@@ -49,7 +57,7 @@ var toolDefinitions = []ToolDefinition{
 		Type: "function",
 		Function: ToolFunction{
 			Name:        "read_file",
-			Description: "Read the contents of a file.",
+			Description: "Read the contents of a file. PDF (.pdf), Word (.docx), and Excel (.xlsx) files are returned as their text, with headings and tables in Markdown. Files over 10 MB must be read in parts with start_line and max_lines.",
 			Parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -57,6 +65,10 @@ var toolDefinitions = []ToolDefinition{
 						"type":        "string",
 						"description": "Path to the file to read",
 					},
+					"start_line": map[string]interface{}{
+						"type":        "integer",
+						"description": "Line to start reading at, counting from 1. Defaults to 1.",
+					},
 					"max_lines": map[string]interface{}{
 						"type":        "integer",
 						"description": "Maximum number of lines to read. If not specified, reads entire file.",
@@ -228,20 +240,145 @@ var toolDefinitions = []ToolDefinition{
 			},
 		},
 	},
+	{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        "copy_files",
+			Description: "Copy many files in one call, several at a time. Give sources (paths or glob patterns such as ~/Pictures/*.jpg) and a destination folder, or files, a list of source and destination pairs, e.g. to sort files into folders. Files whose destination exists are skipped unless overwrite is true. Reports what happened to each file.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"sources": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Paths or glob patterns of the files to copy into destination",
+					},
+					"destination": map[string]interface{}{
+						"type":        "string",
+						"description": "Folder the files in sources go into",
+					},
+					"files": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"source":      map[string]interface{}{"type": "string"},
+								"destination": map[string]interface{}{"type": "string"},
+							},
+							"required": []string{"source", "destination"},
+						},
+						"description": "Files to copy, each with its own destination path",
+					},
+					"overwrite": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace files that already exist at the destination (default false)",
+					},
+				},
+			},
+		},
+	},
+	{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        "move_files",
+			Description: "Move many files in one call, several at a time. Give sources (paths or glob patterns such as ~/Pictures/*.jpg) and a destination folder, or files, a list of source and destination pairs, e.g. to sort files into folders. Files whose destination exists are skipped unless overwrite is true. Reports what happened to each file.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"sources": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Paths or glob patterns of the files to move into destination",
+					},
+					"destination": map[string]interface{}{
+						"type":        "string",
+						"description": "Folder the files in sources go into",
+					},
+					"files": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"source":      map[string]interface{}{"type": "string"},
+								"destination": map[string]interface{}{"type": "string"},
+							},
+							"required": []string{"source", "destination"},
+						},
+						"description": "Files to move, each with its own destination path",
+					},
+					"overwrite": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace files that already exist at the destination (default false)",
+					},
+				},
+			},
+		},
+	},
 }
 
-// GetToolDefinitions returns all available tool definitions in OpenAI format.
+// GetToolDefinitions returns all available tool definitions in OpenAI
+// format: the built-in tools followed by any external tools. The same
+// slice is returned until the external tools change, so callers can cache
+// what they derive from it; it must not be modified.
 func GetToolDefinitions() []ToolDefinition {
-	return toolDefinitions
+	externalMu.Lock()
+	defer externalMu.Unlock()
+	if allDefinitions == nil {
+		allDefinitions = toolDefinitions
+		if external := externalDefinitions(); len(external) > 0 {
+			allDefinitions = append(slices.Clip(toolDefinitions), external...)
+		}
+	}
+	return allDefinitions
 }
 
-// ExecuteTool executes a tool by name with the given arguments.
+// maxOutputBytes caps the output of a single tool call (0 = MaxToolOutputBytes).
+var maxOutputBytes int
+
+// SetMaxOutputBytes sets the largest tool output, in bytes, returned to the
+// model. Longer output is truncated. Zero leaves only MaxToolOutputBytes.
+func SetMaxOutputBytes(n int) {
+	maxOutputBytes = n
+}
+
+// ExecuteTool executes a tool by name with the given arguments, truncating
+// its output to the configured limit and replacing binary output.
 func ExecuteTool(name string, args map[string]interface{}) ToolResult {
+	return ExecuteToolCall("", name, args)
+}
+
+// ExecuteToolCall is ExecuteTool for a model's tool call. Long-running tools
+// send progress updates keyed by callID to the hook set with
+// SetProgressHook.
+func ExecuteToolCall(callID, name string, args map[string]interface{}) (result ToolResult) {
+	// A crashing tool fails its call instead of the app
+	defer crash.Recover("tool "+name, func(r *crash.Report) {
+		result = ToolResult{Success: false, Error: r.Message()}
+	})
+
+	if ok, reason := approve(callID, name, args); !ok {
+		logger.Info("tool call not approved", "tool", name, "reason", reason)
+		return ToolResult{Success: false, Error: reason, Kind: KindNotApproved}
+	}
+
+	start := time.Now()
+	result = executeTool(name, args, newProgressReporter(callID, name))
+	if result.Success {
+		logger.Debug("tool call finished", "tool", name, "duration", time.Since(start))
+	} else {
+		logger.Info("tool call failed", "tool", name, "error", result.Error, "duration", time.Since(start))
+	}
+	result.Output = guardOutput(result.Output)
+	return result
+}
+
+// executeTool dispatches a tool call to its implementation.
+func executeTool(name string, args map[string]interface{}, progress *progressReporter) ToolResult {
 	switch name {
 	case "run_command":
 		command, ok := args["command"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "run_command requires 'command' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "run_command", "command")}
 		}
 		workingDir, _ := args["working_dir"].(string)
 		timeout := 60
@@ -255,7 +392,7 @@ func ExecuteTool(name string, args map[string]interface{}) ToolResult {
 	case "read_file":
 		path, ok := args["path"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "read_file requires 'path' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "read_file", "path")}
 		}
 		var maxLines *int
 		if ml, ok := args["max_lines"].(float64); ok {
@@ -264,16 +401,22 @@ func ExecuteTool(name string, args map[string]interface{}) ToolResult {
 		} else if ml, ok := args["max_lines"].(int); ok {
 			maxLines = &ml
 		}
-		return ReadFile(path, maxLines)
+		startLine := 1
+		if sl, ok := args["start_line"].(float64); ok {
+			startLine = int(sl)
+		} else if sl, ok := args["start_line"].(int); ok {
+			startLine = sl
+		}
+		return ReadFileRange(path, startLine, maxLines)
 
 	case "write_file":
 		path, ok := args["path"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "write_file requires 'path' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "write_file", "path")}
 		}
 		content, ok := args["content"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "write_file requires 'content' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "write_file", "content")}
 		}
 		appendFlag := false
 		if a, ok := args["append"].(bool); ok {
@@ -295,14 +438,14 @@ func ExecuteTool(name string, args map[string]interface{}) ToolResult {
 	case "change_directory":
 		path, ok := args["path"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "change_directory requires 'path' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "change_directory", "path")}
 		}
 		return ChangeDirectory(path)
 
 	case "task_complete":
 		summary, ok := args["summary"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "task_complete requires 'summary' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "task_complete", "summary")}
 		}
 		var filesModified []string
 		if fm, ok := args["files_modified"].([]interface{}); ok {
@@ -317,7 +460,7 @@ func ExecuteTool(name string, args map[string]interface{}) ToolResult {
 	case "delete_file":
 		path, ok := args["path"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "delete_file requires 'path' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "delete_file", "path")}
 		}
 		confirm := false
 		if c, ok := args["confirm"].(bool); ok {
@@ -328,26 +471,37 @@ func ExecuteTool(name string, args map[string]interface{}) ToolResult {
 	case "copy_file":
 		source, ok := args["source"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "copy_file requires 'source' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "copy_file", "source")}
 		}
 		destination, ok := args["destination"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "copy_file requires 'destination' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "copy_file", "destination")}
 		}
-		return CopyFile(source, destination)
+		return copyFile(source, destination, progress)
 
 	case "move_file":
 		source, ok := args["source"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "move_file requires 'source' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "move_file", "source")}
 		}
 		destination, ok := args["destination"].(string)
 		if !ok {
-			return ToolResult{Success: false, Error: "move_file requires 'destination' argument"}
+			return ToolResult{Success: false, Error: i18n.T("tool.missing_argument", "move_file", "destination")}
 		}
 		return MoveFile(source, destination)
 
+	case "copy_files", "move_files":
+		ops, err := bulkArgs(name, args)
+		if err != nil {
+			return ToolResult{Success: false, Error: err.Error()}
+		}
+		overwrite, _ := args["overwrite"].(bool)
+		return bulkFiles(ops, overwrite, name == "move_files", progress)
+
 	default:
-		return ToolResult{Success: false, Error: fmt.Sprintf("Unknown tool: %s", name)}
+		if tool, ok := findExternalTool(name); ok {
+			return tool.Execute(args)
+		}
+		return ToolResult{Success: false, Error: i18n.T("tool.unknown", name)}
 	}
 }
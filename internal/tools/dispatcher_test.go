@@ -1,12 +1,25 @@
 package tools
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"agent-desktop/internal/i18n"
 )
 
+func TestExecuteTool_Localized(t *testing.T) {
+	i18n.SetLocale("de")
+	defer i18n.SetLocale(i18n.DefaultLocale)
+
+	result := ExecuteTool("read_file", map[string]interface{}{})
+	if result.Error != "read_file erfordert das Argument 'path'" {
+		t.Errorf("Expected a German error, got %q", result.Error)
+	}
+}
+
 func TestExecuteTool_ValidTool(t *testing.T) {
 	// Test get_current_directory which is simple
 	result := ExecuteTool("get_current_directory", map[string]interface{}{})
@@ -128,6 +141,8 @@ func TestGetToolDefinitions(t *testing.T) {
 		"delete_file",
 		"copy_file",
 		"move_file",
+		"copy_files",
+		"move_files",
 	}
 
 	toolNames := make(map[string]bool)
@@ -189,3 +204,28 @@ func TestGetSessionInfo_ReturnsInfo(t *testing.T) {
 		t.Error("session info should have 'history_count'")
 	}
 }
+
+func TestExecuteTool_TruncatesOutput(t *testing.T) {
+	args := map[string]interface{}{"summary": strings.Repeat("x", 25)}
+	full := ExecuteTool("task_complete", args).Output
+
+	SetMaxOutputBytes(10)
+	defer SetMaxOutputBytes(0)
+	result := ExecuteTool("task_complete", args)
+
+	if !strings.HasPrefix(result.Output, full[:10]+"\n") {
+		t.Errorf("expected output truncated to 10 bytes, got %q", result.Output)
+	}
+	if want := fmt.Sprintf("%d more bytes", len(full)-10); !strings.Contains(result.Output, want) {
+		t.Errorf("expected truncation note %q, got %q", want, result.Output)
+	}
+}
+
+func TestIsBuiltinTool(t *testing.T) {
+	if !IsBuiltinTool("read_file") || !IsBuiltinTool("run_command") {
+		t.Error("expected built-in tools to be recognized")
+	}
+	if IsBuiltinTool("weather") || IsBuiltinTool("") {
+		t.Error("expected other names not to be built-in")
+	}
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ExternalTool is a tool implemented outside this package, such as one
+// provided by an MCP server.
+type ExternalTool struct {
+	Definition ToolDefinition
+	Execute    func(args map[string]interface{}) ToolResult
+
+	// RequireApproval makes every call wait for the user's approval,
+	// whatever the conversation's approval policy. Without an approval
+	// hook to ask, calls are refused.
+	RequireApproval bool
+}
+
+var (
+	externalMu sync.RWMutex
+	// externalTools maps a source, such as "mcp:github", to its tools
+	externalTools = map[string][]ExternalTool{}
+	// allDefinitions is what GetToolDefinitions returns until the external
+	// tools change (nil = not built yet)
+	allDefinitions []ToolDefinition
+)
+
+// SetExternalTools replaces the tools provided by source. An empty list
+// removes them. Each name means one tool, so tools named like a built-in
+// tool, a tool another source provides, or an earlier tool in the list are
+// left out, and the error returned names them.
+func SetExternalTools(source string, list []ExternalTool) error {
+	externalMu.Lock()
+	defer externalMu.Unlock()
+
+	allDefinitions = nil
+	delete(externalTools, source)
+
+	var errs []error
+	var kept []ExternalTool
+	seen := map[string]bool{}
+	for _, t := range list {
+		name := t.Definition.Function.Name
+		owner, taken := externalSource(name)
+		switch {
+		case IsBuiltinTool(name):
+			errs = append(errs, fmt.Errorf("%s is a built-in tool", name))
+		case taken:
+			errs = append(errs, fmt.Errorf("tool %s is already provided by %s", name, owner))
+		case seen[name]:
+			errs = append(errs, fmt.Errorf("tool %s is defined twice", name))
+		default:
+			seen[name] = true
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) > 0 {
+		externalTools[source] = kept
+	}
+	return errors.Join(errs...)
+}
+
+// ExternalSource returns the source providing the external tool called
+// name, if any.
+func ExternalSource(name string) (string, bool) {
+	externalMu.RLock()
+	defer externalMu.RUnlock()
+	return externalSource(name)
+}
+
+// externalSource is ExternalSource for callers holding externalMu.
+func externalSource(name string) (string, bool) {
+	for source, tools := range externalTools {
+		for _, t := range tools {
+			if t.Definition.Function.Name == name {
+				return source, true
+			}
+		}
+	}
+	return "", false
+}
+
+// externalDefinitions returns the definitions of every external tool,
+// ordered by source so the list sent to the model is stable (caller must
+// hold externalMu).
+func externalDefinitions() []ToolDefinition {
+	sources := make([]string, 0, len(externalTools))
+	for source := range externalTools {
+		sources = append(sources, source)
+	}
+	slices.Sort(sources)
+
+	var defs []ToolDefinition
+	for _, source := range sources {
+		for _, t := range externalTools[source] {
+			defs = append(defs, t.Definition)
+		}
+	}
+	return defs
+}
+
+// findExternalTool returns the external tool called name. Names are unique
+// across sources; see SetExternalTools.
+func findExternalTool(name string) (ExternalTool, bool) {
+	externalMu.RLock()
+	defer externalMu.RUnlock()
+
+	for _, tools := range externalTools {
+		for _, t := range tools {
+			if t.Definition.Function.Name == name {
+				return t, true
+			}
+		}
+	}
+	return ExternalTool{}, false
+}
+
+// RequiresApproval reports whether every call to the tool called name
+// must be approved by the user; see ExternalTool.RequireApproval.
+func RequiresApproval(name string) bool {
+	tool, ok := findExternalTool(name)
+	return ok && tool.RequireApproval
+}
+
+// IsBuiltinTool reports whether name is one of this package's tools.
+func IsBuiltinTool(name string) bool {
+	for _, def := range toolDefinitions {
+		if def.Function.Name == name {
+			return true
+		}
+	}
+	return false
+}
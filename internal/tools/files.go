@@ -1,49 +1,187 @@
 package tools
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+
+	"agent-desktop/internal/document"
+	"agent-desktop/internal/i18n"
+)
+
+var (
+	fileChangeMu   sync.Mutex
+	fileChangeHook func(path string)
 )
 
-// ReadFile reads the contents of a file.
+// SetFileChangeHook sets a function called with the absolute path of a file
+// just before a file tool overwrites, replaces, or deletes it, e.g. to keep
+// a snapshot of its contents. nil removes the hook. Changes made by shell
+// commands are not reported.
+func SetFileChangeHook(fn func(path string)) {
+	fileChangeMu.Lock()
+	fileChangeHook = fn
+	fileChangeMu.Unlock()
+}
+
+// beforeFileChange reports a file about to be changed to the hook.
+func beforeFileChange(path string) {
+	fileChangeMu.Lock()
+	fn := fileChangeHook
+	fileChangeMu.Unlock()
+	if fn != nil {
+		fn(path)
+	}
+}
+
+// MaxReadBytes is the most read_file returns at once. A file larger than
+// this can only be read a range of lines at a time.
+const MaxReadBytes = 10 << 20
+
+// ReadFile reads the contents of a file. PDF, Word, and Excel files are
+// read as their extracted text.
 // If maxLines is provided, it truncates the output to that many lines.
 func ReadFile(path string, maxLines *int) ToolResult {
+	return ReadFileRange(path, 1, maxLines)
+}
+
+// ReadFileRange reads the lines of a file from startLine, counting from 1,
+// stopping after maxLines lines if it is provided. Plain files are read
+// only as far as the range, so the start of a huge log is as cheap to read
+// as a small file. Reading all of a file larger than MaxReadBytes fails
+// with a hint to read it in parts instead, and binary files aren't read.
+func ReadFileRange(path string, startLine int, maxLines *int) ToolResult {
 	// Expand path relative to session CWD
 	expandedPath := ExpandPath(path, GetSession().CWD)
 
 	info, err := os.Stat(expandedPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return ToolResult{Success: false, Error: fmt.Sprintf("File not found: %s", expandedPath)}
+			return ToolResult{Success: false, Error: i18n.T("file.not_found", expandedPath)}
 		}
 		return ToolResult{Success: false, Error: err.Error()}
 	}
 
 	if info.IsDir() {
-		return ToolResult{Success: false, Error: fmt.Sprintf("Not a file: %s", expandedPath)}
+		return ToolResult{Success: false, Error: i18n.T("file.not_a_file", expandedPath)}
+	}
+
+	if startLine < 1 {
+		startLine = 1
+	}
+	limit := 0
+	if maxLines != nil && *maxLines > 0 {
+		limit = *maxLines
+	}
+
+	var r io.Reader
+	if document.IsDocument(expandedPath) {
+		// PDFs and Office files are read as their text
+		text, err := document.Extract(expandedPath)
+		if err != nil {
+			return ToolResult{Success: false, Error: i18n.T("file.extract_failed", expandedPath, err)}
+		}
+		r = strings.NewReader(text)
+	} else {
+		if startLine == 1 && limit == 0 && info.Size() > MaxReadBytes {
+			return ToolResult{Success: false, Error: i18n.T("file.too_large", expandedPath, formatSize(info.Size()), formatSize(MaxReadBytes))}
+		}
+		file, err := os.Open(expandedPath)
+		if err != nil {
+			return ToolResult{Success: false, Error: err.Error()}
+		}
+		defer file.Close()
+		br := bufio.NewReaderSize(file, 64<<10)
+		if head, _ := br.Peek(sniffBytes); looksBinary(string(head)) {
+			kind := http.DetectContentType(head)
+			return ToolResult{Success: false, Error: i18n.T("file.binary", expandedPath, kind, formatSize(info.Size()))}
+		}
+		r = br
 	}
 
-	content, err := os.ReadFile(expandedPath)
+	output, err := readLines(r, startLine, limit)
 	if err != nil {
 		return ToolResult{Success: false, Error: err.Error()}
 	}
+	if output == nil {
+		return ToolResult{Success: false, Error: i18n.T("file.past_end", expandedPath, startLine)}
+	}
+	return ToolResult{Success: true, Output: *output}
+}
 
-	output := string(content)
+// readLines reads the lines of r from startLine, at most limit of them if
+// limit is positive, and at most MaxReadBytes in all. What was left out is
+// noted at the end. It returns nil if r ends before startLine.
+func readLines(r io.Reader, startLine, limit int) (*string, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	for n := 1; n < startLine; n++ {
+		_, size, err := readLine(br, 0)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+	}
 
-	if maxLines != nil && *maxLines > 0 {
-		lines := strings.Split(output, "\n")
-		if len(lines) > *maxLines {
-			lines = lines[:*maxLines]
-			output = strings.Join(lines, "\n")
-			output += fmt.Sprintf("\n... (truncated, showing first %d lines)", *maxLines)
+	var b strings.Builder
+	read := 0
+	for limit == 0 || read < limit {
+		line, size, err := readLine(br, MaxReadBytes-b.Len())
+		if err != nil {
+			return nil, err
 		}
+		if size == 0 {
+			break
+		}
+		b.Write(line)
+		read++
+		if len(line) < size {
+			// Out of room partway through a line
+			fmt.Fprintf(&b, "\n... (truncated at %s, showing lines %d-%d; read on with start_line)", formatSize(MaxReadBytes), startLine, startLine+read-1)
+			output := b.String()
+			return &output, nil
+		}
+	}
+	if read == 0 && startLine > 1 {
+		return nil, nil
 	}
 
-	return ToolResult{Success: true, Output: output}
+	output := b.String()
+	if _, err := br.Peek(1); err == nil {
+		output = strings.TrimSuffix(output, "\n")
+		if startLine == 1 {
+			output += fmt.Sprintf("\n... (truncated, showing first %d lines)", read)
+		} else {
+			output += fmt.Sprintf("\n... (truncated, showing lines %d-%d)", startLine, startLine+read-1)
+		}
+	}
+	return &output, nil
+}
+
+// readLine reads the next line of br with its newline, keeping at most keep
+// bytes of it. size is the length of the whole line, 0 at the end of br.
+func readLine(br *bufio.Reader, keep int) (line []byte, size int, err error) {
+	for {
+		part, err := br.ReadSlice('\n')
+		size += len(part)
+		if n := min(len(part), keep-len(line)); n > 0 {
+			line = append(line, part[:n]...)
+		}
+		switch err {
+		case bufio.ErrBufferFull:
+			continue
+		case nil, io.EOF:
+			return line, size, nil
+		}
+		return line, size, err
+	}
 }
 
 // WriteFile writes content to a file.
@@ -56,7 +194,7 @@ func WriteFile(path string, content string, append bool) ToolResult {
 	// Create parent directories if needed
 	dir := filepath.Dir(expandedPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("Failed to create directory: %s", err)}
+		return ToolResult{Success: false, Error: i18n.T("file.create_directory_failed", err)}
 	}
 
 	var flag int
@@ -66,6 +204,7 @@ func WriteFile(path string, content string, append bool) ToolResult {
 		flag = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
 	}
 
+	beforeFileChange(expandedPath)
 	file, err := os.OpenFile(expandedPath, flag, 0644)
 	if err != nil {
 		return ToolResult{Success: false, Error: err.Error()}
@@ -77,14 +216,14 @@ func WriteFile(path string, content string, append bool) ToolResult {
 		return ToolResult{Success: false, Error: err.Error()}
 	}
 
-	action := "Wrote"
+	key := "file.wrote"
 	if append {
-		action = "Appended to"
+		key = "file.appended"
 	}
 
 	return ToolResult{
 		Success: true,
-		Output:  fmt.Sprintf("%s %s (%d bytes)", action, expandedPath, len(content)),
+		Output:  i18n.T(key, expandedPath, len(content)),
 	}
 }
 
@@ -102,13 +241,13 @@ func ListDirectory(path string, showHidden bool) ToolResult {
 	info, err := os.Stat(expandedPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return ToolResult{Success: false, Error: fmt.Sprintf("Directory not found: %s", expandedPath)}
+			return ToolResult{Success: false, Error: i18n.T("directory.not_found", expandedPath)}
 		}
 		return ToolResult{Success: false, Error: err.Error()}
 	}
 
 	if !info.IsDir() {
-		return ToolResult{Success: false, Error: fmt.Sprintf("Not a directory: %s", expandedPath)}
+		return ToolResult{Success: false, Error: i18n.T("directory.not_a_directory", expandedPath)}
 	}
 
 	entries, err := os.ReadDir(expandedPath)
@@ -142,7 +281,7 @@ func ListDirectory(path string, showHidden bool) ToolResult {
 		}
 	}
 
-	output := fmt.Sprintf("Directory: %s\n\n%s", expandedPath, strings.Join(lines, "\n"))
+	output := fmt.Sprintf("%s\n\n%s", i18n.T("directory.listing", expandedPath), strings.Join(lines, "\n"))
 	return ToolResult{Success: true, Output: output}
 }
 
@@ -152,7 +291,7 @@ func DeleteFile(path string, confirm bool) ToolResult {
 	if !confirm {
 		return ToolResult{
 			Success: false,
-			Error:   "Deletion not confirmed. Set confirm=true to delete the file.",
+			Error:   i18n.T("file.delete_not_confirmed"),
 		}
 	}
 
@@ -162,7 +301,7 @@ func DeleteFile(path string, confirm bool) ToolResult {
 	info, err := os.Stat(expandedPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return ToolResult{Success: false, Error: fmt.Sprintf("File not found: %s", expandedPath)}
+			return ToolResult{Success: false, Error: i18n.T("file.not_found", expandedPath)}
 		}
 		return ToolResult{Success: false, Error: err.Error()}
 	}
@@ -170,19 +309,25 @@ func DeleteFile(path string, confirm bool) ToolResult {
 	if info.IsDir() {
 		return ToolResult{
 			Success: false,
-			Error:   fmt.Sprintf("Cannot delete directory with delete_file. Use run_command for directories: %s", expandedPath),
+			Error:   i18n.T("file.delete_directory", expandedPath),
 		}
 	}
 
+	beforeFileChange(expandedPath)
 	if err := os.Remove(expandedPath); err != nil {
 		return ToolResult{Success: false, Error: err.Error()}
 	}
 
-	return ToolResult{Success: true, Output: fmt.Sprintf("Deleted: %s", expandedPath)}
+	return ToolResult{Success: true, Output: i18n.T("file.deleted", expandedPath)}
 }
 
 // CopyFile copies a file to a new location.
 func CopyFile(source string, destination string) ToolResult {
+	return copyFile(source, destination, nil)
+}
+
+// copyFile copies a file, reporting the bytes copied to progress.
+func copyFile(source string, destination string, progress *progressReporter) ToolResult {
 	// Expand paths relative to session CWD
 	srcPath := ExpandPath(source, GetSession().CWD)
 	dstPath := ExpandPath(destination, GetSession().CWD)
@@ -190,19 +335,19 @@ func CopyFile(source string, destination string) ToolResult {
 	srcInfo, err := os.Stat(srcPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return ToolResult{Success: false, Error: fmt.Sprintf("Source file not found: %s", srcPath)}
+			return ToolResult{Success: false, Error: i18n.T("file.source_not_found", srcPath)}
 		}
 		return ToolResult{Success: false, Error: err.Error()}
 	}
 
 	if srcInfo.IsDir() {
-		return ToolResult{Success: false, Error: fmt.Sprintf("Source is not a file: %s", srcPath)}
+		return ToolResult{Success: false, Error: i18n.T("file.source_not_a_file", srcPath)}
 	}
 
 	// Create parent directories if needed
 	dstDir := filepath.Dir(dstPath)
 	if err := os.MkdirAll(dstDir, 0755); err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("Failed to create directory: %s", err)}
+		return ToolResult{Success: false, Error: i18n.T("file.create_directory_failed", err)}
 	}
 
 	// Open source file
@@ -213,6 +358,7 @@ func CopyFile(source string, destination string) ToolResult {
 	defer srcFile.Close()
 
 	// Create destination file
+	beforeFileChange(dstPath)
 	dstFile, err := os.Create(dstPath)
 	if err != nil {
 		return ToolResult{Success: false, Error: err.Error()}
@@ -220,15 +366,17 @@ func CopyFile(source string, destination string) ToolResult {
 	defer dstFile.Close()
 
 	// Copy content
-	_, err = io.Copy(dstFile, srcFile)
+	_, err = io.Copy(progress.writer(dstFile, srcInfo.Size()), srcFile)
 	if err != nil {
 		return ToolResult{Success: false, Error: err.Error()}
 	}
 
 	// Preserve file mode
-	os.Chmod(dstPath, srcInfo.Mode())
+	if err := os.Chmod(dstPath, srcInfo.Mode()); err != nil {
+		logger.Warn("failed to preserve file mode", "path", dstPath, "error", err)
+	}
 
-	return ToolResult{Success: true, Output: fmt.Sprintf("Copied: %s -> %s", srcPath, dstPath)}
+	return ToolResult{Success: true, Output: i18n.T("file.copied", srcPath, dstPath)}
 }
 
 // MoveFile moves or renames a file.
@@ -239,7 +387,7 @@ func MoveFile(source string, destination string) ToolResult {
 
 	if _, err := os.Stat(srcPath); err != nil {
 		if os.IsNotExist(err) {
-			return ToolResult{Success: false, Error: fmt.Sprintf("Source file not found: %s", srcPath)}
+			return ToolResult{Success: false, Error: i18n.T("file.source_not_found", srcPath)}
 		}
 		return ToolResult{Success: false, Error: err.Error()}
 	}
@@ -247,14 +395,16 @@ func MoveFile(source string, destination string) ToolResult {
 	// Create parent directories if needed
 	dstDir := filepath.Dir(dstPath)
 	if err := os.MkdirAll(dstDir, 0755); err != nil {
-		return ToolResult{Success: false, Error: fmt.Sprintf("Failed to create directory: %s", err)}
+		return ToolResult{Success: false, Error: i18n.T("file.create_directory_failed", err)}
 	}
 
+	beforeFileChange(srcPath)
+	beforeFileChange(dstPath)
 	if err := os.Rename(srcPath, dstPath); err != nil {
 		return ToolResult{Success: false, Error: err.Error()}
 	}
 
-	return ToolResult{Success: true, Output: fmt.Sprintf("Moved: %s -> %s", srcPath, dstPath)}
+	return ToolResult{Success: true, Output: i18n.T("file.moved", srcPath, dstPath)}
 }
 
 // formatSize formats a file size in human-readable form.
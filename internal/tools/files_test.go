@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -51,6 +52,28 @@ func TestReadFile_NotExists(t *testing.T) {
 	}
 }
 
+func TestReadFile_Document(t *testing.T) {
+	tmpDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	// A PDF whose one page shows two lines of text
+	testFile := filepath.Join(tmpDir, "letter.pdf")
+	page := "BT 72 720 Td (Dear Sam,) Tj 0 -20 Td (See you soon.) Tj ET"
+	pdf := fmt.Sprintf("%%PDF-1.4\n1 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n%%%%EOF\n", len(page), page)
+	os.WriteFile(testFile, []byte(pdf), 0644)
+
+	result := ReadFile(testFile, nil)
+	if !result.Success || result.Output != "Dear Sam,\nSee you soon." {
+		t.Errorf("ReadFile() = %+v", result)
+	}
+
+	corrupt := filepath.Join(tmpDir, "broken.docx")
+	os.WriteFile(corrupt, []byte("not a zip"), 0644)
+	if result := ReadFile(corrupt, nil); result.Success || !strings.Contains(result.Error, "broken.docx") {
+		t.Errorf("ReadFile() of a corrupt document = %+v", result)
+	}
+}
+
 func TestReadFile_MaxLines(t *testing.T) {
 	tmpDir, cleanup := setupTestDir(t)
 	defer cleanup()
@@ -83,6 +106,71 @@ func TestReadFile_MaxLines(t *testing.T) {
 	}
 }
 
+func TestReadFileRange(t *testing.T) {
+	tmpDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	testFile := filepath.Join(tmpDir, "multiline.txt")
+	os.WriteFile(testFile, []byte("Line 1\nLine 2\nLine 3\nLine 4\nLine 5\n"), 0644)
+
+	two := 2
+	tests := []struct {
+		start    int
+		maxLines *int
+		want     string
+	}{
+		{1, nil, "Line 1\nLine 2\nLine 3\nLine 4\nLine 5\n"},
+		{2, &two, "Line 2\nLine 3\n... (truncated, showing lines 2-3)"},
+		{4, nil, "Line 4\nLine 5\n"},
+		{4, &two, "Line 4\nLine 5\n"},
+	}
+	for _, tt := range tests {
+		result := ReadFileRange(testFile, tt.start, tt.maxLines)
+		if !result.Success || result.Output != tt.want {
+			t.Errorf("ReadFileRange(%d) = %+v, want %q", tt.start, result, tt.want)
+		}
+	}
+
+	if result := ReadFileRange(testFile, 9, nil); result.Success || !strings.Contains(result.Error, "fewer than 9 lines") {
+		t.Errorf("ReadFileRange() past the end = %+v", result)
+	}
+}
+
+func TestReadFile_TooLarge(t *testing.T) {
+	tmpDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	testFile := filepath.Join(tmpDir, "huge.log")
+	content := "first\n" + strings.Repeat("more log\n", MaxReadBytes/9+1)
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := ReadFile(testFile, nil)
+	if result.Success || !strings.Contains(result.Error, "start_line") {
+		t.Errorf("ReadFile() of a huge file = %+v", result)
+	}
+
+	one := 1
+	result = ReadFile(testFile, &one)
+	if !result.Success || result.Output != "first\n... (truncated, showing first 1 lines)" {
+		t.Errorf("ReadFile() of the start of a huge file = %+v", result)
+	}
+}
+
+func TestReadFile_Binary(t *testing.T) {
+	tmpDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	testFile := filepath.Join(tmpDir, "image.png")
+	os.WriteFile(testFile, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644)
+
+	result := ReadFile(testFile, nil)
+	if result.Success || !strings.Contains(result.Error, "image/png") {
+		t.Errorf("ReadFile() of a binary file = %+v", result)
+	}
+}
+
 // WriteFile tests
 
 func TestWriteFile_Creates(t *testing.T) {
@@ -387,3 +475,30 @@ func TestMoveFile_Renames(t *testing.T) {
 		t.Errorf("new file content = %q, want %q", string(newData), content)
 	}
 }
+
+func TestFileChangeHook(t *testing.T) {
+	tmpDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	var changed []string
+	SetFileChangeHook(func(path string) {
+		// The file still has its old contents when the hook runs
+		data, _ := os.ReadFile(path)
+		changed = append(changed, filepath.Base(path)+"="+string(data))
+	})
+	defer SetFileChangeHook(nil)
+
+	a := filepath.Join(tmpDir, "a.txt")
+	b := filepath.Join(tmpDir, "b.txt")
+	os.WriteFile(a, []byte("old"), 0644)
+
+	WriteFile(a, "new", false)
+	CopyFile(a, b)
+	MoveFile(b, filepath.Join(tmpDir, "c.txt"))
+	DeleteFile(a, true)
+
+	want := []string{"a.txt=old", "b.txt=", "b.txt=new", "c.txt=", "a.txt=new"}
+	if strings.Join(changed, ",") != strings.Join(want, ",") {
+		t.Errorf("hook calls = %v, want %v", changed, want)
+	}
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxToolOutputBytes caps the output of a tool call returned to the
+	// model when no lower limit is set, so one runaway command can't fill
+	// the context window or the conversation file.
+	MaxToolOutputBytes = 1 << 20
+
+	// OutputRetention is how long full tool outputs are kept in the
+	// outputs folder.
+	OutputRetention = 7 * 24 * time.Hour
+
+	sniffBytes = 8 << 10 // How much of an output is looked at to tell if it is binary
+)
+
+// outputsDir keeps outputs too large or too binary to return in full
+// ("" = discard them).
+var outputsDir string
+
+// SetOutputsDir sets the folder that full tool outputs are saved in when
+// they are cut or replaced, so the model can still read them, and deletes
+// those older than OutputRetention. Empty discards them.
+func SetOutputsDir(dir string) {
+	outputsDir = dir
+	if dir == "" {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-OutputRetention)
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// guardOutput keeps output fit for the message history: binary output is
+// replaced with a description, and output over the limit is cut. Either
+// way the full output is saved and its path given, so it can be read.
+func guardOutput(output string) string {
+	if looksBinary(output) {
+		kind := http.DetectContentType([]byte(output[:min(len(output), 512)]))
+		placeholder := fmt.Sprintf("[binary output not shown: %s of %s", formatSize(int64(len(output))), kind)
+		if path := saveOutput(output, ".bin"); path != "" {
+			placeholder += "; saved to " + path
+		}
+		return placeholder + "]"
+	}
+
+	limit := maxOutputBytes
+	if limit <= 0 || limit > MaxToolOutputBytes {
+		limit = MaxToolOutputBytes
+	}
+	if len(output) <= limit {
+		return output
+	}
+	omitted := len(output) - limit
+	note := fmt.Sprintf("\n... (truncated, %d more bytes)", omitted)
+	if path := saveOutput(output, ".txt"); path != "" {
+		note = fmt.Sprintf("\n... (truncated, %d more bytes; the full output is in %s, readable with read_file and start_line)", omitted, path)
+	}
+	return strings.ToValidUTF8(output[:limit], "") + note
+}
+
+// saveOutput saves output in the outputs folder, named by its hash, and
+// returns its path, or "" if it couldn't be saved.
+func saveOutput(output, ext string) string {
+	if outputsDir == "" {
+		return ""
+	}
+	if err := os.MkdirAll(outputsDir, 0700); err != nil {
+		logger.Warn("failed to create outputs folder", "error", err)
+		return ""
+	}
+	sum := sha256.Sum256([]byte(output))
+	path := filepath.Join(outputsDir, hex.EncodeToString(sum[:8])+ext)
+	if err := os.WriteFile(path, []byte(output), 0600); err != nil {
+		logger.Warn("failed to save tool output", "error", err)
+		return ""
+	}
+	return path
+}
+
+// looksBinary reports whether s, judging by its start, is binary data
+// rather than text: it has NUL bytes, or more than one in ten of its
+// characters are invalid UTF-8 or control characters other than
+// whitespace and terminal escapes.
+func looksBinary(s string) bool {
+	sample := s[:min(len(s), sniffBytes)]
+	if strings.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	chars, bad := 0, 0
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRuneInString(sample[i:])
+		if r == utf8.RuneError && size == 1 && len(sample) < len(s) && len(sample)-i < utf8.UTFMax {
+			break // A character cut off by the sample
+		}
+		switch {
+		case r == utf8.RuneError && size == 1:
+			bad++
+		case r < 0x20 && !strings.ContainsRune("\n\r\t\f\b\x1b", r), r == 0x7f:
+			bad++
+		}
+		chars++
+		i += size
+	}
+	return bad*10 > chars
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLooksBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{"empty", "", false},
+		{"text", "hello\nworld\r\n\ttabbed", false},
+		{"colored", "\x1b[32mok\x1b[0m", false},
+		{"unicode", "Grüße, 世界", false},
+		{"nul", "abc\x00def", true},
+		{"garbage", "\xff\xfe\x01\x02\x03abc", true},
+		{"cut rune", strings.Repeat("é", sniffBytes), false},
+	}
+	for _, tt := range tests {
+		if got := looksBinary(tt.s); got != tt.want {
+			t.Errorf("looksBinary(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGuardOutput(t *testing.T) {
+	dir := t.TempDir()
+	SetOutputsDir(dir)
+	defer SetOutputsDir("")
+	SetMaxOutputBytes(10)
+	defer SetMaxOutputBytes(0)
+
+	if got := guardOutput("short"); got != "short" {
+		t.Errorf("guardOutput() = %q, want it unchanged", got)
+	}
+
+	long := strings.Repeat("0123456789", 5)
+	got := guardOutput(long)
+	m := regexp.MustCompile(`^0123456789\n\.\.\. \(truncated, 40 more bytes; the full output is in (\S+),`).FindStringSubmatch(got)
+	if m == nil {
+		t.Fatalf("guardOutput() = %q, want it cut with a path to the rest", got)
+	}
+	if saved, err := os.ReadFile(m[1]); err != nil || string(saved) != long {
+		t.Errorf("saved output = %q, %v; want the full output", saved, err)
+	}
+
+	binary := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	got = guardOutput(binary)
+	if !strings.HasPrefix(got, "[binary output not shown: 16 B of image/png; saved to "+dir) {
+		t.Errorf("guardOutput() of binary = %q", got)
+	}
+}
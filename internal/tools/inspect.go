@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"slices"
+	"strings"
+)
+
+// RedactedValue replaces the values of environment variables that look like
+// secrets.
+const RedactedValue = "[redacted]"
+
+// secretNameParts mark environment variable names whose values are secret.
+var secretNameParts = []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "PASSWD", "PASS", "CREDENTIAL", "AUTH", "PRIVATE", "COOKIE"}
+
+// SessionInspection is a detailed view of a shell session for the session
+// inspector.
+type SessionInspection struct {
+	CWD     string            `json:"cwd"`
+	Env     map[string]string `json:"env"` // Secret-looking values are redacted
+	History []CommandRecord   `json:"history"`
+	Running []RunningCommand  `json:"running"` // Oldest first
+}
+
+// Inspect returns the session's working directory, redacted environment,
+// full command history, and the commands still running.
+func (s *ShellSession) Inspect() SessionInspection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running := make([]RunningCommand, 0, len(s.running))
+	for _, cmd := range s.running {
+		running = append(running, cmd)
+	}
+	slices.SortFunc(running, func(a, b RunningCommand) int { return a.StartedAt.Compare(b.StartedAt) })
+
+	return SessionInspection{
+		CWD:     s.CWD,
+		Env:     RedactEnv(s.Env),
+		History: slices.Clone(s.History),
+		Running: running,
+	}
+}
+
+// RedactEnv returns a copy of env with the values of secret-looking
+// variables, such as API keys and tokens, replaced by RedactedValue.
+func RedactEnv(env map[string]string) map[string]string {
+	redacted := make(map[string]string, len(env))
+	for name, value := range env {
+		if isSecretName(name) && value != "" {
+			value = RedactedValue
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// isSecretName reports whether an environment variable name suggests its
+// value is a secret.
+func isSecretName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, part := range secretNameParts {
+		if strings.Contains(upper, part) {
+			return true
+		}
+	}
+	return false
+}
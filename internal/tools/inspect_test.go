@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRedactEnv(t *testing.T) {
+	env := RedactEnv(map[string]string{
+		"PATH":           "/usr/bin",
+		"OPENAI_API_KEY": "sk-123",
+		"GITHUB_TOKEN":   "ghp_abc",
+		"DB_PASSWORD":    "hunter2",
+		"EMPTY_SECRET":   "",
+	})
+	if env["PATH"] != "/usr/bin" {
+		t.Errorf("PATH = %q, want it unchanged", env["PATH"])
+	}
+	for _, name := range []string{"OPENAI_API_KEY", "GITHUB_TOKEN", "DB_PASSWORD"} {
+		if env[name] != RedactedValue {
+			t.Errorf("%s = %q, want it redacted", name, env[name])
+		}
+	}
+	if env["EMPTY_SECRET"] != "" {
+		t.Errorf("EMPTY_SECRET = %q, want it empty", env["EMPTY_SECRET"])
+	}
+}
+
+func TestShellSession_InspectRunning(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	ResetSession()
+	defer ResetSession()
+
+	done := make(chan ToolResult)
+	go func() { done <- RunCommand("sleep 0.5", "", 10) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	var running []RunningCommand
+	for time.Now().Before(deadline) {
+		if running = GetSession().Inspect().Running; len(running) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(running) != 1 || running[0].Command != "sleep 0.5" || running[0].PID == 0 {
+		t.Fatalf("Expected the sleep to be listed as running, got %+v", running)
+	}
+
+	<-done
+	info := GetSession().Inspect()
+	if len(info.Running) != 0 {
+		t.Errorf("Expected no running commands after the command finished, got %+v", info.Running)
+	}
+	if len(info.History) != 1 || info.History[0].DurationMS < 400 || info.History[0].StartedAt.IsZero() {
+		t.Errorf("Expected the command in history with its duration, got %+v", info.History)
+	}
+}
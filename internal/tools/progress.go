@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Progress is a progress update from a long-running tool call, such as a
+// large file copy.
+type Progress struct {
+	ToolCallID string  `json:"tool_call_id"`
+	Tool       string  `json:"tool"`
+	Done       int64   `json:"done"`            // Bytes processed so far; files, for bulk tools
+	Total      int64   `json:"total,omitempty"` // Total bytes or files; 0 if unknown
+	Percent    float64 `json:"percent"`         // 0-100; -1 if Total is unknown
+}
+
+// progressInterval is the shortest time between progress updates for one
+// tool call, so a fast copy doesn't flood the frontend with events.
+const progressInterval = 100 * time.Millisecond
+
+var (
+	progressMu   sync.Mutex
+	progressHook func(Progress)
+)
+
+// SetProgressHook sets a function called with progress updates from tool
+// calls that run through ExecuteToolCall. nil removes the hook.
+func SetProgressHook(fn func(Progress)) {
+	progressMu.Lock()
+	progressHook = fn
+	progressMu.Unlock()
+}
+
+// progressReporter sends throttled progress updates for one tool call. A
+// nil reporter discards them, so tools can report unconditionally.
+type progressReporter struct {
+	callID, tool string
+	fn           func(Progress)
+	last         time.Time
+}
+
+// newProgressReporter returns a reporter for the tool call, or nil if
+// there is no hook or no call ID to key updates by.
+func newProgressReporter(callID, tool string) *progressReporter {
+	progressMu.Lock()
+	fn := progressHook
+	progressMu.Unlock()
+	if fn == nil || callID == "" {
+		return nil
+	}
+	return &progressReporter{callID: callID, tool: tool, fn: fn}
+}
+
+// report sends an update unless one was sent less than progressInterval
+// ago. The first update and the final one (done == total) are always sent.
+func (r *progressReporter) report(done, total int64) {
+	if r == nil {
+		return
+	}
+	now := time.Now()
+	if !r.last.IsZero() && now.Sub(r.last) < progressInterval && (total <= 0 || done < total) {
+		return
+	}
+	r.last = now
+
+	percent := -1.0
+	if total > 0 {
+		percent = float64(done) * 100 / float64(total)
+	}
+	r.fn(Progress{ToolCallID: r.callID, Tool: r.tool, Done: done, Total: total, Percent: percent})
+}
+
+// writer wraps w so bytes written through it are reported as progress
+// towards total.
+func (r *progressReporter) writer(w io.Writer, total int64) io.Writer {
+	if r == nil {
+		return w
+	}
+	r.report(0, total)
+	return &progressWriter{w: w, r: r, total: total}
+}
+
+type progressWriter struct {
+	w     io.Writer
+	r     *progressReporter
+	done  int64
+	total int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	p.r.report(p.done, p.total)
+	return n, err
+}
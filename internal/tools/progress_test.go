@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecuteToolCall_CopyProgress(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "big.bin")
+	if err := os.WriteFile(src, []byte(strings.Repeat("x", 1<<20)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var updates []Progress
+	SetProgressHook(func(p Progress) { updates = append(updates, p) })
+	defer SetProgressHook(nil)
+
+	result := ExecuteToolCall("call_1", "copy_file", map[string]interface{}{
+		"source":      src,
+		"destination": filepath.Join(dir, "copy.bin"),
+	})
+	if !result.Success {
+		t.Fatalf("copy_file failed: %s", result.Error)
+	}
+
+	if len(updates) < 2 {
+		t.Fatalf("Expected start and end updates, got %+v", updates)
+	}
+	first, last := updates[0], updates[len(updates)-1]
+	if first.Done != 0 || first.ToolCallID != "call_1" || first.Tool != "copy_file" {
+		t.Errorf("Unexpected first update: %+v", first)
+	}
+	if last.Done != 1<<20 || last.Total != 1<<20 || last.Percent != 100 {
+		t.Errorf("Unexpected last update: %+v", last)
+	}
+}
+
+func TestExecuteTool_NoProgressWithoutCallID(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	os.WriteFile(src, []byte("hello"), 0644)
+
+	called := false
+	SetProgressHook(func(Progress) { called = true })
+	defer SetProgressHook(nil)
+
+	ExecuteTool("copy_file", map[string]interface{}{"source": src, "destination": filepath.Join(dir, "b.txt")})
+	if called {
+		t.Error("Expected no progress updates for a call without an ID")
+	}
+}
+
+func TestProgressReporter_UnknownTotal(t *testing.T) {
+	var got Progress
+	r := &progressReporter{callID: "c", tool: "t", fn: func(p Progress) { got = p }}
+	r.report(42, 0)
+	if got.Percent != -1 || got.Done != 42 {
+		t.Errorf("Unexpected update: %+v", got)
+	}
+}
@@ -5,6 +5,8 @@ package tools
 import (
 	"regexp"
 	"strings"
+
+	"agent-desktop/internal/i18n"
 )
 
 // blockedPatterns contains regex patterns for commands that should NEVER execute.
@@ -66,7 +68,7 @@ func CheckCommandSafety(command string) (bool, string) {
 
 	for i, re := range compiledPatterns {
 		if re.MatchString(normalized) {
-			return false, "Command blocked: matches dangerous pattern '" + blockedPatterns[i] + "'"
+			return false, i18n.T("command.blocked", blockedPatterns[i])
 		}
 	}
 
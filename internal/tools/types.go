@@ -1,8 +1,12 @@
 package tools
 
 import (
+	"fmt"
 	"os"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ToolResult represents the result of a tool execution.
@@ -10,29 +14,118 @@ type ToolResult struct {
 	Success bool   `json:"success"`
 	Output  string `json:"output"`
 	Error   string `json:"error,omitempty"`
+	Kind    string `json:"kind,omitempty"` // Why the call failed, when the UI can suggest a fix: one of the Kind constants
 }
 
+// Kinds of tool failure the UI suggests fixes for.
+const (
+	KindTimeout     = "tool_timeout" // The command ran past its timeout
+	KindSafetyBlock = "safety_block" // The safety checks refused the command
+	KindNotApproved = "not_approved" // The user or approval policy refused the call
+)
+
 // CommandRecord represents a recorded command in the session history.
 type CommandRecord struct {
-	Command  string `json:"command"`
-	CWD      string `json:"cwd"`
-	ExitCode int    `json:"exit_code"`
+	Command    string    `json:"command"`
+	CWD        string    `json:"cwd"`
+	ExitCode   int       `json:"exit_code"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// RunningCommand is a run_command call that hasn't finished.
+type RunningCommand struct {
+	PID       int       `json:"pid"`
+	Command   string    `json:"command"`
+	CWD       string    `json:"cwd"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// DefaultHistoryLimit is how many commands a session's history keeps
+// unless SetHistoryLimit says otherwise.
+const DefaultHistoryLimit = 1000
+
+// historyLimit caps the commands kept in a session's history.
+var historyLimit atomic.Int64
+
+func init() {
+	historyLimit.Store(DefaultHistoryLimit)
+}
+
+// SetHistoryLimit sets how many of their latest commands sessions keep in
+// their history; older ones remain only in the audit log. n <= 0 restores
+// DefaultHistoryLimit. Histories are cut when next changed.
+func SetHistoryLimit(n int) {
+	if n <= 0 {
+		n = DefaultHistoryLimit
+	}
+	historyLimit.Store(int64(n))
 }
 
 // ShellSession maintains state for shell command execution.
 type ShellSession struct {
 	CWD     string            `json:"cwd"`
 	Env     map[string]string `json:"env"`
-	History []CommandRecord   `json:"history"`
+	History []CommandRecord   `json:"history"` // Oldest first, at most the history limit
+	running map[int]RunningCommand
 	mu      sync.Mutex
 }
 
-// NewShellSession creates a new shell session with default values.
-func NewShellSession() *ShellSession {
+// defaultWorkingDir is the directory new and reset sessions start in.
+// Empty means the user's home directory.
+var (
+	defaultWorkingDir   string
+	defaultWorkingDirMu sync.RWMutex
+)
+
+// SetDefaultWorkingDir sets the directory new and reset sessions start in.
+// The path may use ~ and must be an existing directory. An empty path
+// restores the default of the user's home directory.
+func SetDefaultWorkingDir(path string) error {
+	if path != "" {
+		path = ExpandPath(path, homeDir())
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("default working directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("default working directory is not a directory: %s", path)
+		}
+	}
+
+	defaultWorkingDirMu.Lock()
+	defer defaultWorkingDirMu.Unlock()
+	defaultWorkingDir = path
+	return nil
+}
+
+// startingDir returns the directory sessions start in: the configured
+// default working directory if it still exists, otherwise the home directory.
+func startingDir() string {
+	defaultWorkingDirMu.RLock()
+	dir := defaultWorkingDir
+	defaultWorkingDirMu.RUnlock()
+
+	if dir != "" {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return homeDir()
+}
+
+// homeDir returns the user's home directory, or "." if it is unknown.
+func homeDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		home = "."
+		return "."
 	}
+	return home
+}
+
+// NewShellSession creates a new shell session with default values.
+func NewShellSession() *ShellSession {
+	home := startingDir()
 
 	// Copy current environment
 	env := make(map[string]string)
@@ -55,27 +148,98 @@ func NewShellSession() *ShellSession {
 // RecordCommand adds a command to the session history.
 func (s *ShellSession) RecordCommand(command string, exitCode int) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.History = append(s.History, CommandRecord{
+	record := CommandRecord{
 		Command:  command,
 		CWD:      s.CWD,
 		ExitCode: exitCode,
-	})
+	}
+	s.appendHistory(record)
+	s.mu.Unlock()
+	audit(record)
 }
 
-// Reset resets the shell session to its initial state.
+// recordRun adds a finished command with its timing to the session history.
+func (s *ShellSession) recordRun(record CommandRecord) {
+	s.mu.Lock()
+	s.appendHistory(record)
+	s.mu.Unlock()
+	audit(record)
+}
+
+// appendHistory adds record to the history, dropping the oldest command
+// once it holds the history limit (caller must hold lock).
+func (s *ShellSession) appendHistory(record CommandRecord) {
+	if over := len(s.History) + 1 - int(historyLimit.Load()); over > 0 {
+		s.History = s.History[:copy(s.History, s.History[min(over, len(s.History)):])]
+	}
+	s.History = append(s.History, record)
+}
+
+// startRunning tracks a started command until finishRunning is called.
+func (s *ShellSession) startRunning(cmd RunningCommand) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running == nil {
+		s.running = map[int]RunningCommand{}
+	}
+	s.running[cmd.PID] = cmd
+}
+
+// finishRunning stops tracking the command with the given process ID.
+func (s *ShellSession) finishRunning(pid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, pid)
+}
+
+// Reset resets the shell session to its initial state, in the default working directory.
 func (s *ShellSession) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	home, err := os.UserHomeDir()
+	s.CWD = startingDir()
+	s.History = make([]CommandRecord, 0)
+}
+
+// GetHistory returns a copy of the session's command history.
+func (s *ShellSession) GetHistory() []CommandRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.History)
+}
+
+// SetHistory replaces the session's command history, as when a saved
+// conversation is reopened, keeping the latest commands up to the limit.
+func (s *ShellSession) SetHistory(history []CommandRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit := int(historyLimit.Load()); len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	s.History = append(make([]CommandRecord, 0, len(history)), history...)
+}
+
+// GetCWD returns the session's current working directory.
+func (s *ShellSession) GetCWD() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.CWD
+}
+
+// SetCWD sets the session's working directory. The path must be an existing directory.
+func (s *ShellSession) SetCWD(path string) error {
+	info, err := os.Stat(path)
 	if err != nil {
-		home = "."
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", path)
 	}
 
-	s.CWD = home
-	s.History = make([]CommandRecord, 0)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.CWD = path
+	return nil
 }
 
 // GetInfo returns information about the current session.
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"fmt"
 	"os"
 	"testing"
 )
@@ -79,6 +80,29 @@ func TestShellSession_RecordCommand(t *testing.T) {
 	}
 }
 
+func TestShellSession_History(t *testing.T) {
+	session := NewShellSession()
+	session.RecordCommand("make", 2)
+
+	history := session.GetHistory()
+	history[0].Command = "changed"
+	if session.History[0].Command != "make" {
+		t.Error("GetHistory should return a copy")
+	}
+
+	saved := []CommandRecord{{Command: "go test ./...", CWD: "/src", ExitCode: 1}}
+	session.SetHistory(saved)
+	saved[0].Command = "changed"
+	if len(session.History) != 1 || session.History[0].Command != "go test ./..." || session.History[0].ExitCode != 1 {
+		t.Errorf("History after SetHistory = %+v", session.History)
+	}
+
+	session.SetHistory(nil)
+	if session.History == nil || len(session.History) != 0 {
+		t.Errorf("History after SetHistory(nil) = %#v", session.History)
+	}
+}
+
 func TestShellSession_Reset(t *testing.T) {
 	session := NewShellSession()
 
@@ -115,3 +139,71 @@ func TestCommandRecord(t *testing.T) {
 		t.Errorf("expected ExitCode=0, got %d", record.ExitCode)
 	}
 }
+
+func TestShellSession_SetCWD(t *testing.T) {
+	session := NewShellSession()
+	dir := t.TempDir()
+
+	if err := session.SetCWD(dir); err != nil {
+		t.Fatalf("SetCWD failed: %v", err)
+	}
+	if session.GetCWD() != dir {
+		t.Errorf("expected CWD=%q, got %q", dir, session.GetCWD())
+	}
+
+	if err := session.SetCWD(dir + "/does-not-exist"); err == nil {
+		t.Error("expected error for missing directory")
+	}
+	if session.GetCWD() != dir {
+		t.Error("CWD should not change when SetCWD fails")
+	}
+}
+
+func TestSetDefaultWorkingDir(t *testing.T) {
+	defer SetDefaultWorkingDir("")
+
+	dir := t.TempDir()
+	if err := SetDefaultWorkingDir(dir); err != nil {
+		t.Fatalf("SetDefaultWorkingDir failed: %v", err)
+	}
+
+	if cwd := NewShellSession().GetCWD(); cwd != dir {
+		t.Errorf("Expected new session to start in %s, got %s", dir, cwd)
+	}
+
+	session := NewShellSession()
+	session.SetCWD(os.TempDir())
+	session.Reset()
+	if session.GetCWD() != dir {
+		t.Errorf("Expected reset session to return to %s, got %s", dir, session.GetCWD())
+	}
+
+	if err := SetDefaultWorkingDir(dir + "/missing"); err == nil {
+		t.Error("Expected error for a missing directory")
+	}
+
+	SetDefaultWorkingDir("")
+	home, _ := os.UserHomeDir()
+	if cwd := NewShellSession().GetCWD(); cwd != home {
+		t.Errorf("Expected clearing the default to start in home, got %s", cwd)
+	}
+}
+
+func TestShellSessionHistoryLimit(t *testing.T) {
+	SetHistoryLimit(3)
+	defer SetHistoryLimit(0)
+
+	session := NewShellSession()
+	for i := range 5 {
+		session.RecordCommand(fmt.Sprintf("cmd %d", i), 0)
+	}
+	history := session.GetHistory()
+	if len(history) != 3 || history[0].Command != "cmd 2" || history[2].Command != "cmd 4" {
+		t.Errorf("history = %+v, want the last 3 commands", history)
+	}
+
+	session.SetHistory([]CommandRecord{{Command: "a"}, {Command: "b"}, {Command: "c"}, {Command: "d"}})
+	if history := session.GetHistory(); len(history) != 3 || history[0].Command != "b" {
+		t.Errorf("history after SetHistory = %+v, want the last 3 commands", history)
+	}
+}
@@ -0,0 +1,117 @@
+// Package tracing exports OpenTelemetry traces of agent runs to an OTLP
+// collector. A run is a span with a child for each model call and tool
+// call, so the trace shows where a long run spent its time. With tracing
+// off, spans cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"agent-desktop/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName names the tracer spans are started with.
+const instrumentationName = "agent-desktop"
+
+// shutdownTimeout bounds flushing spans when tracing is turned off or the
+// app exits.
+const shutdownTimeout = 5 * time.Second
+
+var (
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider // Exporting provider, or nil when tracing is off
+	current  config.TracingSettings
+)
+
+// Configure starts exporting traces as the settings say, replacing the
+// exporter set up before, or stops exporting if they turn tracing off.
+// version is reported as the service version.
+func Configure(s config.TracingSettings, version string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if provider != nil && sameSettings(s, current) {
+		return nil
+	}
+	shutdown()
+	if !s.Enabled() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(s.Endpoint),
+		otlptracehttp.WithHeaders(s.Headers),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set up trace export: %w", err)
+	}
+	sampler := sdktrace.AlwaysSample()
+	if s.SampleRatio > 0 && s.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(s.SampleRatio)
+	}
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "agent-desktop"),
+			attribute.String("service.version", version),
+		)),
+	)
+	current = s
+	otel.SetTracerProvider(provider)
+	return nil
+}
+
+// Shutdown flushes the spans not yet exported and stops exporting.
+func Shutdown() {
+	mu.Lock()
+	defer mu.Unlock()
+	shutdown()
+}
+
+// shutdown flushes and stops the exporting provider (caller must hold lock).
+func shutdown() {
+	if provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	provider.Shutdown(ctx)
+	provider = nil
+	otel.SetTracerProvider(noop.NewTracerProvider())
+}
+
+func sameSettings(a, b config.TracingSettings) bool {
+	if a.Endpoint != b.Endpoint || a.SampleRatio != b.SampleRatio || len(a.Headers) != len(b.Headers) {
+		return false
+	}
+	for k, v := range a.Headers {
+		if b.Headers[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Start starts a span as a child of any span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.GetTracerProvider().Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Fail marks a span as failed with msg.
+func Fail(span trace.Span, msg string) {
+	span.SetStatus(codes.Error, msg)
+}
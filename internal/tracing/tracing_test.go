@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"agent-desktop/internal/config"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestConfigure_ExportsSpans(t *testing.T) {
+	var exports atomic.Int32
+	var apiKey atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/traces" {
+			exports.Add(1)
+			apiKey.Store(r.Header.Get("X-Api-Key"))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	settings := config.TracingSettings{Endpoint: server.URL, Headers: map[string]string{"x-api-key": "secret"}}
+	if err := Configure(settings, "1.0.0"); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	_, span := Start(context.Background(), "agent.run", attribute.Int("agent.max_steps", 5))
+	if !span.SpanContext().IsSampled() {
+		t.Error("expected the span to be sampled")
+	}
+	span.End()
+	Shutdown()
+
+	if exports.Load() == 0 {
+		t.Fatal("expected the span to be exported when tracing shut down")
+	}
+	if apiKey.Load() != "secret" {
+		t.Errorf("x-api-key = %v, want the configured header", apiKey.Load())
+	}
+}
+
+func TestConfigure_Disabled(t *testing.T) {
+	if err := Configure(config.TracingSettings{}, "1.0.0"); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	_, span := Start(context.Background(), "agent.run")
+	defer span.End()
+	if span.SpanContext().IsValid() || span.IsRecording() {
+		t.Error("expected spans to do nothing with tracing off")
+	}
+}
+
+func TestSameSettings(t *testing.T) {
+	a := config.TracingSettings{Endpoint: "http://localhost:4318", Headers: map[string]string{"k": "v"}}
+	b := config.TracingSettings{Endpoint: "http://localhost:4318", Headers: map[string]string{"k": "v"}}
+	if !sameSettings(a, b) {
+		t.Error("expected equal settings to match")
+	}
+	b.Headers = map[string]string{"k": "other"}
+	if sameSettings(a, b) {
+		t.Error("expected different headers not to match")
+	}
+	b = a
+	b.SampleRatio = 0.5
+	if sameSettings(a, b) {
+		t.Error("expected different sample ratios not to match")
+	}
+}
@@ -0,0 +1,61 @@
+package tray
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+)
+
+// iconSize is the width and height of the generated tray icons in pixels.
+const iconSize = 32
+
+// statusColors gives each status its icon color.
+var statusColors = map[Status]color.RGBA{
+	StatusIdle:             {R: 107, G: 114, B: 128, A: 255}, // gray-500
+	StatusRunning:          {R: 34, G: 197, B: 94, A: 255},   // green-500
+	StatusAwaitingApproval: {R: 245, G: 158, B: 11, A: 255},  // amber-500
+}
+
+var (
+	iconMu    sync.Mutex
+	iconCache = map[Status][]byte{}
+)
+
+// statusIcon returns the tray icon for a status: a filled circle in the
+// status color, encoded for the platform's tray.
+func statusIcon(status Status) []byte {
+	iconMu.Lock()
+	defer iconMu.Unlock()
+
+	if icon, ok := iconCache[status]; ok {
+		return icon
+	}
+	c, ok := statusColors[status]
+	if !ok {
+		c = statusColors[StatusIdle]
+	}
+	icon := platformIcon(circlePNG(c))
+	iconCache[status] = icon
+	return icon
+}
+
+// circlePNG draws a filled circle on a transparent square.
+func circlePNG(c color.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, iconSize, iconSize))
+	center := float64(iconSize-1) / 2
+	radius := float64(iconSize)/2 - 2
+	for y := 0; y < iconSize; y++ {
+		for x := 0; x < iconSize; x++ {
+			dx, dy := float64(x)-center, float64(y)-center
+			if dx*dx+dy*dy <= radius*radius {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
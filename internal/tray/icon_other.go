@@ -0,0 +1,8 @@
+//go:build !windows
+
+package tray
+
+// platformIcon returns the PNG as is; macOS and Linux trays accept PNG.
+func platformIcon(pngData []byte) []byte {
+	return pngData
+}
@@ -0,0 +1,22 @@
+package tray
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// platformIcon wraps a PNG in an ICO container, which the Windows tray
+// requires. ICO files may hold PNG data directly.
+func platformIcon(pngData []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Reserved, Type, Count uint16
+	}{0, 1, 1})
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Width, Height, Colors, Reserved uint8
+		Planes, BitCount                uint16
+		Size, Offset                    uint32
+	}{iconSize, iconSize, 0, 0, 1, 32, uint32(len(pngData)), 22})
+	buf.Write(pngData)
+	return buf.Bytes()
+}
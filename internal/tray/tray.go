@@ -0,0 +1,217 @@
+// Package tray shows the app in the system tray with its current status and
+// quick actions: opening the window, starting a new task, stopping the
+// agent, and reopening recent conversations.
+package tray
+
+import (
+	"sync"
+
+	"fyne.io/systray"
+)
+
+// Status is the agent state shown by the tray icon.
+type Status string
+
+// Agent states shown in the tray.
+const (
+	StatusIdle             Status = "idle"
+	StatusRunning          Status = "running"
+	StatusAwaitingApproval Status = "awaiting_approval"
+)
+
+// MaxRecent is the number of recent conversations listed in the menu.
+const MaxRecent = 5
+
+// maxTitleLength keeps menu items short.
+const maxTitleLength = 40
+
+// Entry is a recent conversation listed in the tray menu.
+type Entry struct {
+	ID    string
+	Title string
+}
+
+// Actions are the callbacks run when tray menu items are clicked. They are
+// called from a tray goroutine.
+type Actions struct {
+	Show             func()
+	NewTask          func()
+	StopAgent        func()
+	OpenConversation func(id string)
+	Quit             func()
+}
+
+// Tray is a running tray icon. A nil *Tray ignores all calls, so callers
+// don't need to check whether the tray was started.
+type Tray struct {
+	mu      sync.Mutex
+	actions Actions
+	status  Status
+	recent  []Entry
+	ready   bool
+	end     func()
+
+	statusItem *systray.MenuItem
+	stopItem   *systray.MenuItem
+	recentMenu *systray.MenuItem
+	slots      [MaxRecent]*systray.MenuItem
+}
+
+// Start shows the tray icon alongside the app's own event loop.
+func Start(actions Actions) *Tray {
+	t := &Tray{actions: actions, status: StatusIdle}
+	start, end := systray.RunWithExternalLoop(t.onReady, nil)
+	t.end = end
+	start()
+	return t
+}
+
+// Stop removes the tray icon.
+func (t *Tray) Stop() {
+	if t == nil || t.end == nil {
+		return
+	}
+	t.end()
+	t.end = nil
+}
+
+// SetStatus updates the icon, tooltip, and menu for the agent state.
+func (t *Tray) SetStatus(status Status) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status = status
+	t.applyStatus()
+}
+
+// SetRecent replaces the recent conversations listed in the menu. Only the
+// first MaxRecent entries are shown.
+func (t *Tray) SetRecent(entries []Entry) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(entries) > MaxRecent {
+		entries = entries[:MaxRecent]
+	}
+	t.recent = append([]Entry(nil), entries...)
+	t.applyRecent()
+}
+
+// onReady builds the menu once the tray is up.
+func (t *Tray) onReady() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	systray.SetTitle("")
+	t.statusItem = systray.AddMenuItem("", "")
+	t.statusItem.Disable()
+	systray.AddSeparator()
+
+	show := systray.AddMenuItem("Show Agent Desktop", "Open the main window")
+	newTask := systray.AddMenuItem("New task", "Start a new conversation")
+	t.stopItem = systray.AddMenuItem("Stop agent", "Stop the running task")
+	t.recentMenu = systray.AddMenuItem("Recent conversations", "")
+	for i := range t.slots {
+		t.slots[i] = t.recentMenu.AddSubMenuItem("", "")
+		go t.handleRecent(i)
+	}
+	systray.AddSeparator()
+	quit := systray.AddMenuItem("Quit", "Quit Agent Desktop")
+
+	go handle(show, t.actions.Show)
+	go handle(newTask, t.actions.NewTask)
+	go handle(t.stopItem, t.actions.StopAgent)
+	go handle(quit, t.actions.Quit)
+
+	t.ready = true
+	t.applyStatus()
+	t.applyRecent()
+}
+
+// applyStatus pushes the current status to the tray. Callers hold t.mu.
+func (t *Tray) applyStatus() {
+	if !t.ready {
+		return
+	}
+	systray.SetIcon(statusIcon(t.status))
+	systray.SetTooltip("Agent Desktop: " + statusLabel(t.status))
+	t.statusItem.SetTitle("Status: " + statusLabel(t.status))
+	if t.status == StatusIdle {
+		t.stopItem.Disable()
+	} else {
+		t.stopItem.Enable()
+	}
+}
+
+// applyRecent pushes the recent conversations to the menu. Callers hold t.mu.
+func (t *Tray) applyRecent() {
+	if !t.ready {
+		return
+	}
+	for i, slot := range t.slots {
+		if i < len(t.recent) {
+			slot.SetTitle(menuTitle(t.recent[i].Title))
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+	if len(t.recent) == 0 {
+		t.recentMenu.Disable()
+	} else {
+		t.recentMenu.Enable()
+	}
+}
+
+// handleRecent opens the conversation in recent slot i when it is clicked.
+func (t *Tray) handleRecent(i int) {
+	for range t.slots[i].ClickedCh {
+		t.mu.Lock()
+		var id string
+		if i < len(t.recent) {
+			id = t.recent[i].ID
+		}
+		t.mu.Unlock()
+
+		if id != "" && t.actions.OpenConversation != nil {
+			t.actions.OpenConversation(id)
+		}
+	}
+}
+
+// handle runs action each time item is clicked.
+func handle(item *systray.MenuItem, action func()) {
+	for range item.ClickedCh {
+		if action != nil {
+			action()
+		}
+	}
+}
+
+// statusLabel returns the human-readable name of a status.
+func statusLabel(status Status) string {
+	switch status {
+	case StatusRunning:
+		return "Running"
+	case StatusAwaitingApproval:
+		return "Awaiting approval"
+	default:
+		return "Idle"
+	}
+}
+
+// menuTitle shortens a conversation title for the menu.
+func menuTitle(title string) string {
+	if title == "" {
+		return "Untitled conversation"
+	}
+	runes := []rune(title)
+	if len(runes) <= maxTitleLength {
+		return title
+	}
+	return string(runes[:maxTitleLength-1]) + "…"
+}
@@ -0,0 +1,63 @@
+package tray
+
+import (
+	"bytes"
+	"image/png"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStatusIcon(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("icons are wrapped in ICO on Windows")
+	}
+
+	for status, want := range statusColors {
+		img, err := png.Decode(bytes.NewReader(statusIcon(status)))
+		if err != nil {
+			t.Fatalf("%s icon is not a PNG: %v", status, err)
+		}
+		r, g, b, _ := img.At(iconSize/2, iconSize/2).RGBA()
+		if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+			t.Errorf("%s icon center = (%d,%d,%d), want %v", status, r>>8, g>>8, b>>8, want)
+		}
+		if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+			t.Errorf("%s icon corner should be transparent", status)
+		}
+	}
+}
+
+func TestStatusLabel(t *testing.T) {
+	tests := map[Status]string{
+		StatusIdle:             "Idle",
+		StatusRunning:          "Running",
+		StatusAwaitingApproval: "Awaiting approval",
+		"":                     "Idle",
+	}
+	for status, want := range tests {
+		if got := statusLabel(status); got != want {
+			t.Errorf("statusLabel(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestMenuTitle(t *testing.T) {
+	if got := menuTitle(""); got != "Untitled conversation" {
+		t.Errorf("menuTitle(\"\") = %q", got)
+	}
+	if got := menuTitle("List files"); got != "List files" {
+		t.Errorf("menuTitle() = %q, want unchanged", got)
+	}
+	got := []rune(menuTitle(strings.Repeat("a", 100)))
+	if len(got) != maxTitleLength || got[len(got)-1] != '…' {
+		t.Errorf("long title not shortened: %q", string(got))
+	}
+}
+
+func TestNilTray(t *testing.T) {
+	var tr *Tray
+	tr.SetStatus(StatusRunning)
+	tr.SetRecent([]Entry{{ID: "1", Title: "x"}})
+	tr.Stop()
+}
@@ -0,0 +1,416 @@
+// Package updater keeps the app up to date. It reads a JSON release feed,
+// downloads the build for this platform, checks its SHA-256 digest and the
+// Ed25519 signature of its version, platform, and digest, and stages it.
+// The staged build replaces the running executable when the app exits, so
+// it takes effect on the next launch.
+package updater
+
+import (
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Release channels. Beta includes stable releases, so beta users always
+// get the newest build.
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+// DefaultFeedURL is the release feed used when the config doesn't set one.
+const DefaultFeedURL = "https://github.com/pbarrett520/agent-desktop/releases/latest/download/releases.json"
+
+// maxFeedSize bounds the release feed download.
+const maxFeedSize = 1 << 20
+
+// maxBuildSize bounds a build download, so a bad mirror can't fill the disk
+// before the signature is checked. A variable so tests can lower it.
+var maxBuildSize int64 = 512 << 20
+
+// manifestFile records the staged build in the staging directory.
+const manifestFile = "pending.json"
+
+// PublicKey is the base64 Ed25519 key release builds are signed with. It is
+// set at build time with -ldflags "-X agent-desktop/internal/updater.PublicKey=...";
+// builds without it refuse to install updates.
+var PublicKey = ""
+
+// semver matches a semantic version, with an optional leading "v". The
+// feed isn't signed, so versions are checked before they name a file.
+var semver = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+// ErrNoPublicKey is returned when this build has no key to verify updates.
+var ErrNoPublicKey = errors.New("this build has no update signing key")
+
+// Feed is the release feed document.
+type Feed struct {
+	Releases []Release `json:"releases"`
+}
+
+// Release is a published version of the app.
+type Release struct {
+	Version     string    `json:"version"` // Semantic version, e.g. 1.4.0 or 1.5.0-beta.2
+	Channel     string    `json:"channel"` // ChannelStable or ChannelBeta
+	Notes       string    `json:"notes,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []Asset   `json:"assets"`
+}
+
+// Asset is a release build for one platform.
+type Asset struct {
+	OS        string `json:"os"`   // GOOS, e.g. linux
+	Arch      string `json:"arch"` // GOARCH, e.g. amd64
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`    // Hex digest of the file
+	Signature string `json:"signature"` // Base64 Ed25519 signature of SignedManifest for this build
+}
+
+// SignedManifest returns what a build's signature covers: its version,
+// platform, and SHA-256 digest, so a tampered feed can't pass an older
+// signed build off as a newer version or another platform's build. Release
+// tooling signs it with the private key matching PublicKey.
+func SignedManifest(version, goos, goarch, sha256Hex string) []byte {
+	return []byte("agent-desktop update\n" +
+		"version: " + version + "\n" +
+		"os: " + goos + "\n" +
+		"arch: " + goarch + "\n" +
+		"sha256: " + strings.ToLower(sha256Hex) + "\n")
+}
+
+// Pending is a verified build staged to replace the executable.
+type Pending struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// Updater checks for and stages updates.
+type Updater struct {
+	FeedURL        string
+	Channel        string // ChannelStable (default) or ChannelBeta
+	CurrentVersion string
+	Dir            string // Staging directory for downloaded builds
+	PublicKey      string // Base64 Ed25519 key; empty uses the package PublicKey
+	Client         *http.Client
+}
+
+// Check fetches the feed and returns the newest release for the channel
+// that is newer than CurrentVersion and has a build for this platform, or
+// nil if the app is up to date. Releases whose version isn't a semantic
+// version are ignored.
+func (u *Updater) Check(ctx context.Context) (*Release, error) {
+	feed, err := u.fetchFeed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *Release
+	for i := range feed.Releases {
+		r := &feed.Releases[i]
+		if !ValidVersion(r.Version) || !u.inChannel(r) || r.asset() == nil {
+			continue
+		}
+		if CompareVersions(r.Version, u.CurrentVersion) <= 0 {
+			continue
+		}
+		if newest == nil || CompareVersions(r.Version, newest.Version) > 0 {
+			newest = r
+		}
+	}
+	return newest, nil
+}
+
+// Download fetches the release's build for this platform, verifies it, and
+// stages it to be installed by Apply.
+func (u *Updater) Download(ctx context.Context, r *Release) (*Pending, error) {
+	if !ValidVersion(r.Version) {
+		return nil, fmt.Errorf("release version %q is not a semantic version", r.Version)
+	}
+	asset := r.asset()
+	if asset == nil {
+		return nil, fmt.Errorf("release %s has no build for %s/%s", r.Version, goruntime.GOOS, goruntime.GOARCH)
+	}
+	key, err := u.publicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(u.Dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(u.Dir, "agent-desktop-"+r.Version+exeSuffix())
+	if err := u.download(ctx, asset.URL, path); err != nil {
+		return nil, err
+	}
+	if err := verify(path, r.Version, asset, key); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	pending := &Pending{Version: r.Version, Path: path}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(u.Dir, manifestFile), data, 0644); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// Pending returns the staged build, or nil if there is none.
+func (u *Updater) Pending() *Pending {
+	data, err := os.ReadFile(filepath.Join(u.Dir, manifestFile))
+	if err != nil {
+		return nil
+	}
+	var p Pending
+	if json.Unmarshal(data, &p) != nil || p.Path == "" {
+		return nil
+	}
+	if _, err := os.Stat(p.Path); err != nil {
+		return nil
+	}
+	return &p
+}
+
+// Apply replaces executable with the staged build, keeping the old one as
+// executable.old until the next update. It returns the installed version,
+// or "" if nothing was staged.
+func (u *Updater) Apply(executable string) (string, error) {
+	p := u.Pending()
+	if p == nil {
+		return "", nil
+	}
+
+	backup := executable + ".old"
+	os.Remove(backup)
+	if err := os.Rename(executable, backup); err != nil {
+		return "", fmt.Errorf("failed to move current executable aside: %w", err)
+	}
+	if err := moveFile(p.Path, executable); err != nil {
+		// Put the current build back so the app still starts
+		os.Rename(backup, executable)
+		return "", fmt.Errorf("failed to install update: %w", err)
+	}
+	os.Remove(filepath.Join(u.Dir, manifestFile))
+	return p.Version, nil
+}
+
+func (u *Updater) fetchFeed(ctx context.Context) (*Feed, error) {
+	url := u.FeedURL
+	if url == "" {
+		url = DefaultFeedURL
+	}
+	resp, err := u.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var feed Feed
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFeedSize)).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to parse release feed: %w", err)
+	}
+	return &feed, nil
+}
+
+func (u *Updater) download(ctx context.Context, url, path string) error {
+	resp, err := u.get(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to download update: %w", err)
+	}
+	defer resp.Body.Close()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxBuildSize+1))
+	if err == nil && n > maxBuildSize {
+		err = fmt.Errorf("build is larger than %d bytes", maxBuildSize)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to download update: %w", err)
+	}
+	return f.Close()
+}
+
+func (u *Updater) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+func (u *Updater) inChannel(r *Release) bool {
+	if u.Channel == ChannelBeta {
+		return r.Channel == ChannelBeta || r.Channel == ChannelStable || r.Channel == ""
+	}
+	return r.Channel == ChannelStable || r.Channel == ""
+}
+
+func (u *Updater) publicKey() (ed25519.PublicKey, error) {
+	encoded := u.PublicKey
+	if encoded == "" {
+		encoded = PublicKey
+	}
+	if encoded == "" {
+		return nil, ErrNoPublicKey
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid update signing key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// asset returns the release's build for this platform, or nil.
+func (r *Release) asset() *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].OS == goruntime.GOOS && r.Assets[i].Arch == goruntime.GOARCH {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// verify checks the file's digest against the asset and the signature of
+// the release's manifest for it against key.
+func verify(path, version string, asset *Asset, key ed25519.PublicKey) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	digest := h.Sum(nil)
+
+	if !strings.EqualFold(hex.EncodeToString(digest), asset.SHA256) {
+		return errors.New("update checksum does not match the release feed")
+	}
+	manifest := SignedManifest(version, asset.OS, asset.Arch, hex.EncodeToString(digest))
+	sig, err := base64.StdEncoding.DecodeString(asset.Signature)
+	if err != nil || !ed25519.Verify(key, manifest, sig) {
+		return errors.New("update signature is not valid")
+	}
+	return nil
+}
+
+// moveFile renames src to dst, copying when they are on different volumes.
+func moveFile(src, dst string) error {
+	if os.Rename(src, dst) == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func exeSuffix() string {
+	if goruntime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// ValidVersion reports whether v is a semantic version such as 1.4.0 or
+// v1.5.0-beta.2.
+func ValidVersion(v string) bool {
+	return semver.MatchString(v)
+}
+
+// CompareVersions compares two semantic versions, ignoring a leading "v".
+// It returns -1, 0, or 1. A pre-release (1.2.0-beta.1) sorts before its
+// release (1.2.0).
+func CompareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bCore, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	if c := compareDotted(aCore, bCore); c != 0 {
+		return c
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return compareDotted(aPre, bPre)
+}
+
+// compareDotted compares dot-separated identifiers, numerically where both
+// are numbers.
+func compareDotted(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		switch {
+		case xErr == nil && yErr == nil:
+			if xn != yn {
+				return cmp.Compare(xn, yn)
+			}
+		case x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
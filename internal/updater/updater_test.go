@@ -0,0 +1,270 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"testing"
+)
+
+// testRelease is a signed build served by a fake release server.
+type testRelease struct {
+	server *httptest.Server
+	key    string
+	build  []byte
+}
+
+// setupReleaseServer serves a feed with the given releases, each with a
+// signed build for this platform. Versions ending in "-bad" are served with
+// a wrong signature, and those ending in "-replayed" with the signature of
+// an older version.
+func setupReleaseServer(t *testing.T, releases map[string]string) *testRelease {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tr := &testRelease{key: base64.StdEncoding.EncodeToString(pub), build: []byte("new build")}
+	digest := sha256.Sum256(tr.build)
+
+	mux := http.NewServeMux()
+	tr.server = httptest.NewServer(mux)
+	t.Cleanup(tr.server.Close)
+
+	var feed Feed
+	for version, channel := range releases {
+		// A "-replayed" release carries the signature of 1.0.0's build
+		signed := strings.TrimSuffix(version, "-replayed")
+		if signed != version {
+			signed = "1.0.0"
+		}
+		sig := ed25519.Sign(priv, SignedManifest(signed, goruntime.GOOS, goruntime.GOARCH, hex.EncodeToString(digest[:])))
+		signature := base64.StdEncoding.EncodeToString(sig)
+		if strings.HasSuffix(version, "-bad") {
+			signature = base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+		}
+		feed.Releases = append(feed.Releases, Release{
+			Version: version,
+			Channel: channel,
+			Assets: []Asset{{
+				OS:        goruntime.GOOS,
+				Arch:      goruntime.GOARCH,
+				URL:       tr.server.URL + "/build",
+				SHA256:    hex.EncodeToString(digest[:]),
+				Signature: signature,
+			}},
+		})
+	}
+
+	mux.HandleFunc("/feed.json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(feed)
+	})
+	mux.HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tr.build)
+	})
+	return tr
+}
+
+func (tr *testRelease) updater(t *testing.T, channel string) *Updater {
+	return &Updater{
+		FeedURL:        tr.server.URL + "/feed.json",
+		Channel:        channel,
+		CurrentVersion: "1.0.0",
+		Dir:            t.TempDir(),
+		PublicKey:      tr.key,
+	}
+}
+
+func TestCheck_PicksNewestInChannel(t *testing.T) {
+	tr := setupReleaseServer(t, map[string]string{
+		"0.9.0":        ChannelStable,
+		"1.1.0":        ChannelStable,
+		"1.2.0-beta.1": ChannelBeta,
+	})
+
+	release, err := tr.updater(t, ChannelStable).Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if release == nil || release.Version != "1.1.0" {
+		t.Errorf("Expected stable 1.1.0, got %+v", release)
+	}
+
+	release, err = tr.updater(t, ChannelBeta).Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if release == nil || release.Version != "1.2.0-beta.1" {
+		t.Errorf("Expected beta 1.2.0-beta.1, got %+v", release)
+	}
+}
+
+func TestCheck_UpToDate(t *testing.T) {
+	tr := setupReleaseServer(t, map[string]string{"1.0.0": ChannelStable})
+
+	release, err := tr.updater(t, ChannelStable).Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if release != nil {
+		t.Errorf("Expected no update, got %s", release.Version)
+	}
+}
+
+func TestDownloadAndApply(t *testing.T) {
+	tr := setupReleaseServer(t, map[string]string{"1.1.0": ChannelStable})
+	u := tr.updater(t, ChannelStable)
+
+	release, err := u.Check(context.Background())
+	if err != nil || release == nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	pending, err := u.Download(context.Background(), release)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if got := u.Pending(); got == nil || got.Version != "1.1.0" || got.Path != pending.Path {
+		t.Fatalf("Expected staged 1.1.0, got %+v", got)
+	}
+
+	exe := filepath.Join(t.TempDir(), "agent-desktop")
+	os.WriteFile(exe, []byte("old build"), 0755)
+
+	version, err := u.Apply(exe)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if version != "1.1.0" {
+		t.Errorf("Expected 1.1.0 to be installed, got %q", version)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new build" {
+		t.Errorf("Expected executable to be replaced, got %q", data)
+	}
+	if data, _ := os.ReadFile(exe + ".old"); string(data) != "old build" {
+		t.Errorf("Expected previous build to be kept, got %q", data)
+	}
+	if u.Pending() != nil {
+		t.Error("Expected nothing staged after Apply")
+	}
+
+	// Nothing left to apply
+	if version, err := u.Apply(exe); err != nil || version != "" {
+		t.Errorf("Expected no-op Apply, got %q, %v", version, err)
+	}
+}
+
+func TestDownload_RejectsBadSignature(t *testing.T) {
+	tr := setupReleaseServer(t, map[string]string{"1.1.0-bad": ChannelStable})
+	u := tr.updater(t, ChannelStable)
+
+	release, err := u.Check(context.Background())
+	if err != nil || release == nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if _, err := u.Download(context.Background(), release); err == nil {
+		t.Fatal("Expected signature error")
+	}
+	if u.Pending() != nil {
+		t.Error("Expected nothing staged for a bad build")
+	}
+}
+
+func TestDownload_RejectsReplayedSignature(t *testing.T) {
+	tr := setupReleaseServer(t, map[string]string{"2.0.0-replayed": ChannelStable})
+	u := tr.updater(t, ChannelStable)
+
+	release, err := u.Check(context.Background())
+	if err != nil || release == nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if _, err := u.Download(context.Background(), release); err == nil {
+		t.Fatal("Expected an older version's signature to be rejected")
+	}
+}
+
+func TestDownload_RejectsOversizedBuild(t *testing.T) {
+	tr := setupReleaseServer(t, map[string]string{"1.1.0": ChannelStable})
+	u := tr.updater(t, ChannelStable)
+	original := maxBuildSize
+	maxBuildSize = int64(len(tr.build)) - 1
+	t.Cleanup(func() { maxBuildSize = original })
+
+	release, err := u.Check(context.Background())
+	if err != nil || release == nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if _, err := u.Download(context.Background(), release); err == nil {
+		t.Fatal("Expected a build over the size limit to be rejected")
+	}
+	if entries, _ := os.ReadDir(u.Dir); len(entries) != 0 {
+		t.Errorf("Expected the partial download to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestDownload_RequiresKey(t *testing.T) {
+	tr := setupReleaseServer(t, map[string]string{"1.1.0": ChannelStable})
+	u := tr.updater(t, ChannelStable)
+	u.PublicKey = ""
+
+	release, _ := u.Check(context.Background())
+	if _, err := u.Download(context.Background(), release); err != ErrNoPublicKey {
+		t.Errorf("Expected ErrNoPublicKey, got %v", err)
+	}
+}
+
+func TestDownload_RejectsBadVersion(t *testing.T) {
+	tr := setupReleaseServer(t, map[string]string{"1.1.0/../../escape": ChannelStable})
+	u := tr.updater(t, ChannelStable)
+
+	if release, err := u.Check(context.Background()); err != nil || release != nil {
+		t.Errorf("Check = %+v, %v; want the release ignored", release, err)
+	}
+	release := &Release{Version: "../1.1.0", Assets: []Asset{{OS: goruntime.GOOS, Arch: goruntime.GOARCH, URL: tr.server.URL + "/build"}}}
+	if _, err := u.Download(context.Background(), release); err == nil {
+		t.Fatal("Expected an error for a version that isn't semver")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(u.Dir)); len(entries) != 1 {
+		t.Errorf("Expected nothing written beside the staging directory, found %d entries", len(entries))
+	}
+}
+
+func TestValidVersion(t *testing.T) {
+	for v, want := range map[string]bool{
+		"1.4.0": true, "v1.5.0-beta.2": true, "2.0.0+build.7": true,
+		"1.4": false, "01.4.0": false, "1.4.0/../x": false, "../1.4.0": false, "": false,
+	} {
+		if got := ValidVersion(v); got != want {
+			t.Errorf("ValidVersion(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2.0", "1.1.9", 1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.2.0-beta.1", "1.2.0", -1},
+		{"1.2.0-beta.2", "1.2.0-beta.10", -1},
+		{"2.0.0", "1.99.99", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
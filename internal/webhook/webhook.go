@@ -0,0 +1,166 @@
+// Package webhook delivers JSON notifications about finished agent runs to
+// user-configured URLs, such as Slack incoming webhooks or home-automation
+// endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Agent-Desktop-Event"     // The event name
+	HeaderDelivery  = "X-Agent-Desktop-Delivery"  // Unique per payload, the same across retries
+	HeaderSignature = "X-Agent-Desktop-Signature" // "sha256=<hex HMAC of the body>" if the webhook has a secret
+)
+
+// maxAttempts is how many times a delivery is tried before giving up.
+const maxAttempts = 3
+
+// retryDelay is the wait before the first retry; it doubles after each
+// attempt. It is a variable so tests don't wait.
+var retryDelay = 2 * time.Second
+
+// client sends deliveries; each attempt is bounded by its timeout.
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Payload describes a finished agent run.
+type Payload struct {
+	Event          string    `json:"event"` // config.WebhookComplete or config.WebhookError
+	Text           string    `json:"text"`  // One-line summary, shown by Slack-style webhooks
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Title          string    `json:"title,omitempty"` // Conversation title
+	Summary        string    `json:"summary"`         // The agent's final message, or the error
+	FilesModified  []string  `json:"files_modified"`  // Files the run changed
+	Cost           float64   `json:"cost"`            // Estimated cost of the run in USD
+	Tokens         int       `json:"tokens"`          // Prompt and completion tokens used by the run
+	DurationMS     int64     `json:"duration_ms"`     // Wall time of the run
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// Send delivers p to every webhook that wants its event, in the background.
+// Failures are passed to onError, if set, after the last attempt.
+func Send(hooks []config.Webhook, p Payload, onError func(config.Webhook, error)) {
+	if p.Timestamp.IsZero() {
+		p.Timestamp = time.Now()
+	}
+	if p.Text == "" {
+		p.Text = text(p)
+	}
+	if p.FilesModified == nil {
+		p.FilesModified = []string{}
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	delivery := newDeliveryID()
+
+	for _, hook := range hooks {
+		if !hook.Wants(p.Event) {
+			continue
+		}
+		go func(hook config.Webhook) {
+			if err := Deliver(context.Background(), hook, p.Event, delivery, body); err != nil && onError != nil {
+				onError(hook, err)
+			}
+		}(hook)
+	}
+}
+
+// Deliver posts body to hook, retrying network errors, rate limiting, and
+// server errors.
+func Deliver(ctx context.Context, hook config.Webhook, event, delivery string, body []byte) error {
+	delay := retryDelay
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var retry bool
+		if retry, err = post(ctx, hook, event, delivery, body); err == nil || !retry {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying.
+func post(ctx context.Context, hook config.Webhook, event, delivery string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "agent-desktop")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, delivery)
+	if hook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(hook.Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by
+// the hex HMAC-SHA256 of body keyed with secret. Receivers recompute it to
+// check that a payload came from this app.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// text summarizes p in one line.
+func text(p Payload) string {
+	subject := "Agent run"
+	if p.Title != "" {
+		subject = fmt.Sprintf("%q", p.Title)
+	}
+	summary := p.Summary
+	if r := []rune(summary); len(r) > 200 {
+		summary = string(r[:200]) + "…"
+	}
+	if p.Event == config.WebhookError {
+		return fmt.Sprintf("%s failed: %s", subject, summary)
+	}
+	return fmt.Sprintf("%s finished: %s", subject, summary)
+}
+
+// newDeliveryID returns a random ID for a payload.
+func newDeliveryID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"agent-desktop/internal/config"
+)
+
+func TestSend(t *testing.T) {
+	received := make(chan *http.Request, 2)
+	bodies := make(chan []byte, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	hooks := []config.Webhook{
+		{URL: server.URL + "/all", Secret: "s3cret"},
+		{URL: server.URL + "/errors", Events: []string{config.WebhookError}},
+	}
+	Send(hooks, Payload{
+		Event:          config.WebhookComplete,
+		ConversationID: "conv-1",
+		Title:          "Tidy downloads",
+		Summary:        "Moved 12 files",
+		FilesModified:  []string{"/home/me/a.txt"},
+		Cost:           0.01,
+	}, func(hook config.Webhook, err error) { t.Errorf("delivery to %s failed: %v", hook.URL, err) })
+
+	var req *http.Request
+	var body []byte
+	select {
+	case req = <-received:
+		body = <-bodies
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the webhook")
+	}
+	if req.URL.Path != "/all" {
+		t.Errorf("delivered to %s, want only the webhook for every event", req.URL.Path)
+	}
+	if got := req.Header.Get(HeaderEvent); got != config.WebhookComplete {
+		t.Errorf("event header = %q", got)
+	}
+	if got, want := req.Header.Get(HeaderSignature), Sign("s3cret", body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if p.ConversationID != "conv-1" || len(p.FilesModified) != 1 || p.Cost != 0.01 || p.Timestamp.IsZero() {
+		t.Errorf("payload = %+v", p)
+	}
+	if p.Text != `"Tidy downloads" finished: Moved 12 files` {
+		t.Errorf("text = %q", p.Text)
+	}
+
+	select {
+	case req := <-received:
+		t.Errorf("unexpected delivery to %s", req.URL.Path)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDeliver_Retries(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Millisecond
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	if err := Deliver(context.Background(), config.Webhook{URL: server.URL}, "complete", "id", []byte("{}")); err != nil {
+		t.Errorf("Deliver failed: %v", err)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("attempts = %d, want 3", n)
+	}
+}
+
+func TestDeliver_ClientErrorNotRetried(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Millisecond
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := Deliver(context.Background(), config.Webhook{URL: server.URL}, "error", "id", []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Deliver error = %v, want the 404", err)
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("attempts = %d, want 1", n)
+	}
+}
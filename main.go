@@ -2,37 +2,102 @@ package main
 
 import (
 	"embed"
+	"log/slog"
+	"os"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/deeplink"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/options/mac"
 )
 
 //go:embed all:frontend/dist
 var assets embed.FS
 
+// version is the application version, set for release builds with
+// -ldflags "-X main.version=1.2.3".
+var version = "0.1.0"
+
 func main() {
 	// Create an instance of the app structure
 	app := NewApp()
 
+	// --mcp offers the built-in tools to other agents over MCP
+	if hasFlag(os.Args[1:], "mcp") {
+		if err := runMCPServer(app); err != nil {
+			slog.Error("MCP server exited with an error", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// --jsonrpc lets editor extensions drive the agent over stdin and stdout
+	if hasFlag(os.Args[1:], "jsonrpc") {
+		if err := runJSONRPC(app); err != nil {
+			slog.Error("JSON-RPC server exited with an error", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// --serve drives the agent through a REST API, and optionally gRPC,
+	// instead of a window
+	if addr, grpcAddr, ok := serveOptions(os.Args[1:]); ok {
+		if err := runHeadless(app, addr, grpcAddr); err != nil {
+			slog.Error("server exited with an error", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Windows and Linux pass an agent-desktop:// link as an argument
+	if link, ok := deeplink.FromArgs(os.Args[1:]); ok {
+		app.openDeepLink(link)
+	}
+
+	// Reopen the window where the user left it
+	window := config.LoadWindowState()
+	app.window = &window
+	startState := options.Normal
+	if window.Maximised {
+		startState = options.Maximised
+	}
+
 	// Create application with options
 	err := wails.Run(&options.App{
 		Title:  "Agent Desktop",
-		Width:  1280,
-		Height: 800,
-		MinWidth: 900,
-		MinHeight: 600,
+		Width:  window.Width,
+		Height: window.Height,
+		MinWidth: config.MinWindowWidth,
+		MinHeight: config.MinWindowHeight,
+		WindowStartState: startState,
 		AssetServer: &assetserver.Options{
 			Assets: assets,
 		},
 		BackgroundColour: &options.RGBA{R: 248, G: 249, B: 250, A: 1}, // gray-50
+		DragAndDrop: &options.DragAndDrop{
+			EnableFileDrop: true,
+		},
 		OnStartup:        app.startup,
+		OnBeforeClose:    app.beforeClose,
+		OnShutdown:       app.shutdown,
+		// A second launch, e.g. from a deep link, is handed to this instance
+		SingleInstanceLock: &options.SingleInstanceLock{
+			UniqueId:               "com.pbarrett520.agent-desktop",
+			OnSecondInstanceLaunch: app.onSecondInstance,
+		},
+		Mac: &mac.Options{
+			OnUrlOpen: app.openDeepLink,
+		},
 		Bind: []interface{}{
 			app,
 		},
 	})
 
 	if err != nil {
-		println("Error:", err.Error())
+		slog.Error("application exited with an error", "error", err)
 	}
 }
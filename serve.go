@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"agent-desktop/internal/config"
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/grpcapi"
+	"agent-desktop/internal/i18n"
+	"agent-desktop/internal/jsonrpc"
+	"agent-desktop/internal/mcp"
+	"agent-desktop/internal/runqueue"
+	"agent-desktop/internal/server"
+	"agent-desktop/internal/tools"
+)
+
+// serveOptions reports whether the command line asks for headless server
+// mode (--serve), the address to listen on (--listen), and the address to
+// serve gRPC on as well (--grpc-listen, off if empty).
+func serveOptions(args []string) (addr, grpcAddr string, ok bool) {
+	flags := flag.NewFlagSet("agent-desktop", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	serve := flags.Bool("serve", false, "run without a window and serve the REST API")
+	listen := flags.String("listen", server.DefaultAddr, "address the REST API listens on")
+	grpcListen := flags.String("grpc-listen", "", "address the gRPC API listens on")
+	if err := flags.Parse(args); err != nil || !*serve {
+		return "", "", false
+	}
+	return *listen, *grpcListen, true
+}
+
+// hasFlag reports whether args contain the boolean flag --name (or -name).
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--"+name || arg == "-"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// runMCPServer serves the built-in tools over MCP on stdin and stdout until
+// the client disconnects or the process is interrupted. Only the tool
+// settings of the configuration apply; no conversations are opened, so it
+// can run alongside the window. Stdout carries the protocol, so logs go to
+// stderr and the log file.
+func runMCPServer(app *App) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.Config{ExecutionTimeout: 60}
+	}
+	app.config = cfg
+	app.configureLogging(cfg)
+	i18n.SetLocale(cfg.Locale)
+	if err != nil {
+		slog.Error("failed to load config, using defaults", "error", err)
+	}
+
+	if err := tools.SetDefaultWorkingDir(cfg.DefaultWorkingDir); err != nil {
+		slog.Warn("ignoring default working directory", "error", err)
+	}
+	tools.ResetSession()
+	tools.SetMaxOutputBytes(cfg.Limits.MaxOutputBytesPerTool)
+	tools.SetElevation(!cfg.DisableElevation)
+
+	slog.Info("serving tools over MCP on stdio")
+	return mcp.NewServer(version).Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// runJSONRPC starts the app without a window and answers JSON-RPC requests
+// on stdin and stdout until the client disconnects or the process is
+// interrupted, for editor extensions. App events are sent as notifications
+// once the client asks for them.
+func runJSONRPC(app *App) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rpc := jsonrpc.New(apiBackend{app})
+	app.headless = true
+	app.emitter = rpc.Publish
+	app.initBackend(ctx)
+	defer app.shutdown(ctx)
+	if !app.config.IsConfigured() {
+		slog.Warn("LLM is not configured; runs will fail until config.json or LLM_* variables are set")
+	}
+
+	if err := rpc.Serve(ctx, os.Stdin, os.Stdout); !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// runHeadless starts the app without a window and serves the REST API on
+// addr, and the gRPC API on grpcAddr if set, until interrupted. App events
+// go to the APIs' event streams.
+func runHeadless(app *App, addr, grpcAddr string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tokenPath := filepath.Join(config.Dir(), "api_token")
+	token, err := server.LoadToken(tokenPath)
+	if err != nil {
+		return err
+	}
+
+	api := server.New(apiBackend{app}, token)
+	app.headless = true
+	app.emitter = api.Publish
+	if grpcAddr != "" {
+		rpc := grpcapi.New(apiBackend{app}, token)
+		grpcListener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return err
+		}
+		grpcServer := rpc.GRPCServer()
+		defer grpcServer.Stop()
+		go grpcServer.Serve(grpcListener)
+		app.emitter = func(event string, data ...interface{}) {
+			api.Publish(event, data...)
+			rpc.Publish(event, data...)
+		}
+		fmt.Fprintf(os.Stderr, "Serving the gRPC API on %s\n", grpcListener.Addr())
+	}
+	app.initBackend(ctx)
+	defer app.shutdown(ctx)
+	if !app.config.IsConfigured() {
+		slog.Warn("LLM is not configured; runs will fail until config.json or LLM_* variables are set")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	httpServer := &http.Server{Handler: api.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving the API on http://%s (token in %s or $%s)\n", listener.Addr(), tokenPath, server.EnvToken)
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// apiBackend gives the REST API access to the app's conversations and run
+// queue. Messages run through the queue one at a time, after any run the
+// window started, and never change the conversation the window shows.
+type apiBackend struct {
+	app *App
+}
+
+func (b apiBackend) ListConversations() ([]conversation.Summary, error) {
+	return b.app.ListConversations()
+}
+
+func (b apiBackend) NewConversation() (*conversation.Conversation, error) {
+	if b.app.convManager == nil {
+		return nil, errors.New("conversation manager not initialized")
+	}
+	return b.app.convManager.Create()
+}
+
+func (b apiBackend) GetConversation(id string) (*conversation.Conversation, error) {
+	if b.app.convManager == nil {
+		return nil, errors.New("conversation manager not initialized")
+	}
+	return b.app.convManager.Get(id)
+}
+
+func (b apiBackend) SendMessage(conversationID, text string) (runqueue.Item, error) {
+	if b.app.readOnly {
+		return runqueue.Item{}, conversation.ErrReadOnly
+	}
+	return b.app.EnqueueTask(text, conversationID)
+}
+
+func (b apiBackend) ListRuns() []runqueue.Item {
+	return b.app.GetQueue()
+}
+
+func (b apiBackend) CancelRun(id string) error {
+	if b.app.queue == nil {
+		return server.ErrNotFound
+	}
+	return b.app.queue.Cancel(id)
+}
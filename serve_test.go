@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"agent-desktop/internal/conversation"
+	"agent-desktop/internal/runqueue"
+	"agent-desktop/internal/server"
+)
+
+func TestServeOptions(t *testing.T) {
+	tests := []struct {
+		args     []string
+		addr     string
+		grpcAddr string
+		wantOK   bool
+	}{
+		{nil, "", "", false},
+		{[]string{"agent-desktop://new"}, "", "", false},
+		{[]string{"--serve"}, server.DefaultAddr, "", true},
+		{[]string{"--serve", "--listen", "0.0.0.0:9000"}, "0.0.0.0:9000", "", true},
+		{[]string{"--listen=:9000", "--serve"}, ":9000", "", true},
+		{[]string{"--serve", "--grpc-listen", "127.0.0.1:8766"}, server.DefaultAddr, "127.0.0.1:8766", true},
+	}
+
+	for _, tt := range tests {
+		addr, grpcAddr, ok := serveOptions(tt.args)
+		if ok != tt.wantOK || addr != tt.addr || grpcAddr != tt.grpcAddr {
+			t.Errorf("serveOptions(%q) = %q, %q, %v; want %q, %q, %v", tt.args, addr, grpcAddr, ok, tt.addr, tt.grpcAddr, tt.wantOK)
+		}
+	}
+}
+
+func TestHasFlag(t *testing.T) {
+	if hasFlag(nil, "mcp") || hasFlag([]string{"--serve"}, "mcp") || hasFlag([]string{"--mcpx"}, "mcp") {
+		t.Error("hasFlag() = true without --mcp")
+	}
+	if !hasFlag([]string{"--mcp"}, "mcp") || !hasFlag([]string{"agent-desktop://x", "-jsonrpc"}, "jsonrpc") {
+		t.Error("hasFlag() = false with the flag")
+	}
+}
+
+func TestAPIBackend(t *testing.T) {
+	app, cleanup := setupTestApp(t)
+	defer cleanup()
+
+	ran := make(chan runqueue.Item, 1)
+	app.queue = runqueue.New(func(ctx context.Context, item runqueue.Item) error {
+		ran <- item
+		return nil
+	}, 1, nil)
+	backend := apiBackend{app}
+
+	active := app.convManager.New()
+	conv, err := backend.NewConversation()
+	if err != nil {
+		t.Fatalf("NewConversation failed: %v", err)
+	}
+	if app.convManager.GetActive().ID != active.ID {
+		t.Error("creating a conversation over the API should not switch the active one")
+	}
+	if _, err := backend.GetConversation("missing"); !errors.Is(err, conversation.ErrNotFound) {
+		t.Errorf("GetConversation(missing) error = %v, want ErrNotFound", err)
+	}
+
+	item, err := backend.SendMessage(conv.ID, "list files")
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if got := <-ran; got.ID != item.ID || got.ConversationID != conv.ID || got.Task != "list files" {
+		t.Errorf("ran %+v, want the queued message", got)
+	}
+
+	app.readOnly = true
+	if _, err := backend.SendMessage(conv.ID, "again"); !errors.Is(err, conversation.ErrReadOnly) {
+		t.Errorf("read-only SendMessage error = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestApp_EmitHeadless(t *testing.T) {
+	app := NewApp()
+	app.emit("agent:complete", "ignored before startup")
+
+	var got []string
+	app.emitter = func(event string, data ...interface{}) { got = append(got, event) }
+	app.queueChanged(nil)
+	if len(got) != 1 || got[0] != "queue:changed" {
+		t.Errorf("emitted %v, want queue:changed", got)
+	}
+}